@@ -3,17 +3,82 @@ package v1
 type CreditDebitRequest struct {
 	UserID string  `json:"user_id" example:"1"`
 	Asset  string  `json:"asset" example:"BTC"`
-	Amount float64 `json:"amount" example:"1"`
+	Amount Decimal `json:"amount" swaggertype:"string" example:"1.00000000"`
+
+	// DepositID (credits only) makes the credit idempotent: a credit repeating
+	// a deposit ID already applied for the user changes nothing.
+	DepositID string `json:"deposit_id,omitempty" example:"pix-7f3a91"`
 }
 
 type BalanceItem struct {
-	Asset     string  `json:"asset"`
-	Available float64 `json:"available"`
-	Locked    float64 `json:"locked"`
-	Total     float64 `json:"total"`
+	Asset     string       `json:"asset"`
+	Available FixedDecimal `json:"available" swaggertype:"string"`
+	Locked    FixedDecimal `json:"locked" swaggertype:"string"`
+	Total     FixedDecimal `json:"total" swaggertype:"string"`
 }
 
 type BalanceResponse struct {
 	UserID   string        `json:"user_id"`
 	Balances []BalanceItem `json:"balances"`
+
+	// Replayed is set when a credit repeated a deposit ID: nothing was
+	// credited and Balances are those returned by the original credit.
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+// BatchCreditResult reports the outcome of one entry of a batch credit.
+type BatchCreditResult struct {
+	Index   int     `json:"index"`
+	UserID  string  `json:"user_id"`
+	Asset   string  `json:"asset"`
+	Amount  Decimal `json:"amount" swaggertype:"string"`
+	Success bool    `json:"success"`
+	Error   string  `json:"error,omitempty"`
+	Code    string  `json:"code,omitempty"`
+}
+
+type BatchCreditResponse struct {
+	Results   []BatchCreditResult `json:"results"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+}
+
+// FeeStatusResponse is a user's place in the fee schedule. Rates are
+// fractions of the traded amount ("0.001" is 0.1%).
+type FeeStatusResponse struct {
+	UserID    string  `json:"user_id"`
+	Volume    Decimal `json:"volume" swaggertype:"string"` // quote volume traded in the last 30 days
+	Tier      int     `json:"tier"`                        // -1 when no fee schedule is configured
+	MakerRate Decimal `json:"maker_rate" swaggertype:"string"`
+	TakerRate Decimal `json:"taker_rate" swaggertype:"string"`
+}
+
+// PositionResponse is a user's position on a pair as built by their trades.
+// The entry price follows the average-cost method; fees are not included.
+type PositionResponse struct {
+	UserID        string        `json:"user_id"`
+	Pair          string        `json:"pair"`
+	NetPosition   FixedDecimal  `json:"net_position" swaggertype:"string"` // base; negative when short
+	Bought        FixedDecimal  `json:"bought" swaggertype:"string"`
+	Sold          FixedDecimal  `json:"sold" swaggertype:"string"`
+	QuoteSpent    FixedDecimal  `json:"quote_spent" swaggertype:"string"`
+	QuoteReceived FixedDecimal  `json:"quote_received" swaggertype:"string"`
+	AvgEntryPrice *FixedDecimal `json:"avg_entry_price" swaggertype:"string"` // null when flat
+	RealizedPnL   FixedDecimal  `json:"realized_pnl" swaggertype:"string"`
+	Trades        int           `json:"trades"`
+}
+
+// PnLResponse is a user's realized PnL on a pair on the FIFO method: sells
+// close the oldest open buys first, and selling without open buys opens a
+// short. Fees are not included.
+type PnLResponse struct {
+	UserID       string        `json:"user_id"`
+	Pair         string        `json:"pair"`
+	Method       string        `json:"method" example:"fifo"`
+	RealizedPnL  FixedDecimal  `json:"realized_pnl" swaggertype:"string"`
+	Closed       FixedDecimal  `json:"closed" swaggertype:"string"`         // base closed against earlier fills
+	OpenPosition FixedDecimal  `json:"open_position" swaggertype:"string"`  // base; negative when short
+	OpenCost     FixedDecimal  `json:"open_cost" swaggertype:"string"`      // quote basis of the open lots
+	AvgOpenPrice *FixedDecimal `json:"avg_open_price" swaggertype:"string"` // null when flat
+	Trades       int           `json:"trades"`
 }
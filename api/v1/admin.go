@@ -0,0 +1,145 @@
+package v1
+
+import "time"
+
+// AdminCancelOrderRequest cancels any resting order, whoever owns it.
+type AdminCancelOrderRequest struct {
+	Pair    string `json:"pair"`
+	OrderID int64  `json:"order_id"`
+	Reason  string `json:"reason,omitempty"` // recorded in the audit log
+}
+
+// AdminHaltRequest halts or resumes trading on every pair.
+type AdminHaltRequest struct {
+	Reason string `json:"reason,omitempty"` // recorded in the audit log
+}
+
+// HaltStatusResponse reports whether trading is halted engine-wide.
+type HaltStatusResponse struct {
+	Halted bool `json:"halted"`
+}
+
+// AdminPairModeRequest restricts a pair to maker or taker orders, or lifts
+// the restriction with an empty Mode.
+type AdminPairModeRequest struct {
+	Pair   string `json:"pair"`
+	Mode   string `json:"mode" enums:"maker_only,taker_only,auction"`
+	Reason string `json:"reason,omitempty"` // recorded in the audit log
+}
+
+// AdminAuctionRequest runs the call auction of a pair in auction mode.
+type AdminAuctionRequest struct {
+	Pair   string `json:"pair"`
+	Reason string `json:"reason,omitempty"` // recorded in the audit log
+}
+
+// AuctionResponse is the outcome of a call auction: every match executed at
+// Price. Imbalance is the bid volume at or above Price minus the ask volume
+// at or below it, so it is negative when sellers were left over.
+type AuctionResponse struct {
+	Pair      string       `json:"pair"`
+	Price     FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"` // 0 when nothing crossed
+	Volume    FixedDecimal `json:"volume" swaggertype:"string" example:"0.75000000"`
+	Imbalance FixedDecimal `json:"imbalance" swaggertype:"string" example:"-0.50000000"`
+	Trades    int          `json:"trades"`
+}
+
+// AdminCancelStaleRequest cancels every resting order on a pair placed more
+// than MaxAge ago, whoever owns it.
+type AdminCancelStaleRequest struct {
+	Pair   string `json:"pair"`
+	MaxAge string `json:"max_age" example:"24h"` // Go duration
+	Reason string `json:"reason,omitempty"`      // recorded in the audit log
+}
+
+// AdminCancelStaleResponse lists the orders a stale-order sweep cancelled,
+// oldest first.
+type AdminCancelStaleResponse struct {
+	Pair      string          `json:"pair"`
+	Cancelled []OrderResponse `json:"cancelled"`
+	Count     int             `json:"count"`
+}
+
+// AdminDelistRequest takes a pair off the exchange for good.
+type AdminDelistRequest struct {
+	Pair   string `json:"pair"`
+	Reason string `json:"reason,omitempty"` // recorded in the audit log
+}
+
+// AdminDelistResponse lists the resting orders a delisting cancelled, oldest
+// first.
+type AdminDelistResponse struct {
+	Pair       string          `json:"pair"`
+	DelistedAt time.Time       `json:"delisted_at"`
+	Cancelled  []OrderResponse `json:"cancelled"`
+	Count      int             `json:"count"`
+}
+
+// AdminStatsResponse is a snapshot of engine activity since start.
+type AdminStatsResponse struct {
+	OrdersPlaced   int64                   `json:"orders_placed"`
+	Matches        int64                   `json:"matches"`
+	Volume         map[string]FixedDecimal `json:"volume" swaggertype:"object,string"` // asset -> amount traded, both legs of each fill
+	ActivePairs    int                     `json:"active_pairs"`
+	OpenOrders     int                     `json:"open_orders"`
+	RebatesSkipped int64                   `json:"rebates_skipped"`
+	MatchLatency   []MatchLatency          `json:"match_latency"`
+}
+
+// MatchLatency is the histogram of time the engine spent matching orders of
+// one type on one pair, excluding HTTP handling, validation and settlement.
+type MatchLatency struct {
+	Pair      string          `json:"pair"`
+	OrderType string          `json:"order_type" enums:"limit,market"`
+	Count     int64           `json:"count"`
+	SumMicros float64         `json:"sum_us"`
+	Buckets   []LatencyBucket `json:"buckets"`
+}
+
+// LatencyBucket counts the samples above the previous bucket's bound and up
+// to Le, a Go duration, or "+Inf" for the last bucket.
+type LatencyBucket struct {
+	Le    string `json:"le" example:"25µs"`
+	Count int64  `json:"count"`
+}
+
+// AdminMatch is one trade between the two users of an AdminMatchesResponse,
+// unmasked.
+type AdminMatch struct {
+	Seq        uint64       `json:"seq"`
+	Pair       string       `json:"pair"`
+	Price      FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"`
+	Amount     FixedDecimal `json:"amount" swaggertype:"string" example:"0.50000000"`
+	BuyerID    string       `json:"buyer_id"`
+	SellerID   string       `json:"seller_id"`
+	BidOrderID int64        `json:"bid_order_id"`
+	AskOrderID int64        `json:"ask_order_id"`
+	TakerSide  string       `json:"taker_side" enums:"bid,ask"`
+	Timestamp  time.Time    `json:"timestamp"`
+}
+
+// AdminMatchesResponse is a page of the trades two users made with each
+// other, oldest first.
+type AdminMatchesResponse struct {
+	UserA   string       `json:"user_a"`
+	UserB   string       `json:"user_b"`
+	Matches []AdminMatch `json:"matches"`
+	Total   int          `json:"total"` // matching trades before paging
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+}
+
+// AdminAssetTotal is the sum of one asset's balances over every user.
+type AdminAssetTotal struct {
+	Asset     string       `json:"asset" example:"BRL"`
+	Available FixedDecimal `json:"available" swaggertype:"string" example:"950000.00"`
+	Locked    FixedDecimal `json:"locked" swaggertype:"string" example:"50000.00"`
+	Total     FixedDecimal `json:"total" swaggertype:"string" example:"1000000.00"`
+	Users     int          `json:"users"` // users holding a non-zero balance
+}
+
+// AdminAssetsResponse is an accounting snapshot of every asset, sorted by
+// asset, read at a single point in time.
+type AdminAssetsResponse struct {
+	Assets []AdminAssetTotal `json:"assets"`
+}
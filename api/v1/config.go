@@ -0,0 +1,36 @@
+package v1
+
+// FeeTierInfo is one entry of the fee schedule. Rates are fractions of the
+// traded amount.
+type FeeTierInfo struct {
+	MinVolume Decimal `json:"min_volume" swaggertype:"string"`
+	MakerRate Decimal `json:"maker_rate" swaggertype:"string"`
+	TakerRate Decimal `json:"taker_rate" swaggertype:"string"`
+}
+
+// ConfigResponse is the configuration the engine is enforcing. Secrets are
+// never included; AdminEnabled only tells whether an admin token is set.
+type ConfigResponse struct {
+	Pairs                []PairInfo         `json:"pairs"`
+	STPMode              string             `json:"stp_mode" enums:"cancel_newest,cancel_oldest,cancel_both"`
+	TickPolicy           string             `json:"tick_policy" enums:"floor,round,reject"`
+	MaxOpenOrdersPerUser int                `json:"max_open_orders_per_user"` // 0 means no cap
+	MaxLevelsPerOrder    int                `json:"max_levels_per_order"`     // 0 means no cap
+	SweepDust            bool               `json:"sweep_dust"`
+	AllowUnlistedPairs   bool               `json:"allow_unlisted_pairs"`
+	Deterministic        bool               `json:"deterministic"`
+	FeeTiers             []FeeTierInfo      `json:"fee_tiers"` // lowest volume first; empty means no fees
+	FeeWindow            string             `json:"fee_window" example:"720h0m0s"`
+	FeeAccount           string             `json:"fee_account"`
+	FeeRounding          string             `json:"fee_rounding" enums:"up,down,nearest"`
+	MinRefund            map[string]Decimal `json:"min_refund,omitempty" swaggertype:"object"` // per-asset overrides
+	AdminEnabled         bool               `json:"admin_enabled"`
+}
+
+// CapabilitiesResponse lists what this server accepts, so clients can adapt
+// without trial and error. Only enabled entries are listed.
+type CapabilitiesResponse struct {
+	OrderTypes  []string `json:"order_types" example:"limit,market"`
+	TimeInForce []string `json:"time_in_force" example:"gtc,gtd"`
+	Features    []string `json:"features" example:"fees,ws,hidden_orders"`
+}
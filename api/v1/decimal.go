@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Decimal is a price or amount that travels over JSON as a string
+// ("50000.00", "0.00000001") so clients never have to parse it as a binary
+// float. Plain JSON numbers are still accepted on input for compatibility.
+//
+// Output uses the shortest representation that parses back to the same
+// value, so a round trip through the API never drifts.
+type Decimal float64
+
+// Float64 returns d as a float64 for use with the engine.
+func (d Decimal) Float64() float64 {
+	return float64(d)
+}
+
+// IsFinite reports whether d is neither NaN nor ±Inf. UnmarshalJSON never
+// produces such values, but a Decimal built in Go code can hold them.
+func (d Decimal) IsFinite() bool {
+	f := float64(d)
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+func (d Decimal) String() string {
+	return strconv.FormatFloat(float64(d), 'f', -1, 64)
+}
+
+// Places returns the number of decimals d is written with, e.g. 2 for
+// 0.01 and 0 for 50000.
+func (d Decimal) Places() int {
+	s := d.String()
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	f := float64(d)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("decimal: cannot encode %v", f)
+	}
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return fmt.Errorf("decimal: invalid string %s", s)
+		}
+		s = unquoted
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("decimal: invalid value %q", s)
+	}
+
+	*d = Decimal(f)
+	return nil
+}
+
+const (
+	FiatPlaces   = 2 // BRL and other fiat: cents
+	CryptoPlaces = 8 // crypto quantities: satoshi-sized units
+)
+
+// fiatAssets lists the assets rendered with FiatPlaces.
+var fiatAssets = map[string]bool{"BRL": true}
+
+// FixedDecimal is a response value rendered as a plain decimal string with at
+// least Places decimals ("50000.00", "0.10000000"). Values that need more
+// digits keep them, so nothing is ever rounded away, and exponent notation
+// never appears.
+type FixedDecimal struct {
+	Value  float64
+	Places int
+}
+
+// Fiat renders v with FiatPlaces decimals.
+func Fiat(v float64) FixedDecimal {
+	return FixedDecimal{Value: v, Places: FiatPlaces}
+}
+
+// Crypto renders v with CryptoPlaces decimals.
+func Crypto(v float64) FixedDecimal {
+	return FixedDecimal{Value: v, Places: CryptoPlaces}
+}
+
+// AssetAmount renders an amount of asset with the decimals of its kind.
+func AssetAmount(asset string, v float64) FixedDecimal {
+	if fiatAssets[asset] {
+		return Fiat(v)
+	}
+	return Crypto(v)
+}
+
+// Float64 returns the value for use with the engine.
+func (f FixedDecimal) Float64() float64 {
+	return f.Value
+}
+
+func (f FixedDecimal) String() string {
+	s := strconv.FormatFloat(f.Value, 'f', -1, 64)
+
+	decimals := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		decimals = len(s) - i - 1
+	}
+	if decimals >= f.Places {
+		return s
+	}
+	return strconv.FormatFloat(f.Value, 'f', f.Places, 64)
+}
+
+func (f FixedDecimal) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(f.Value) || math.IsInf(f.Value, 0) {
+		return nil, fmt.Errorf("decimal: cannot encode %v", f.Value)
+	}
+	return []byte(strconv.Quote(f.String())), nil
+}
+
+// UnmarshalJSON accepts the same input as Decimal and keeps the number of
+// decimals it was written with.
+func (f *FixedDecimal) UnmarshalJSON(data []byte) error {
+	var d Decimal
+	if err := d.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	s := strings.Trim(string(data), `"`)
+	places := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		places = len(s) - i - 1
+	}
+
+	*f = FixedDecimal{Value: d.Float64(), Places: places}
+	return nil
+}
@@ -0,0 +1,119 @@
+package v1
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDecimal_RoundTrip_NoDrift(t *testing.T) {
+	values := []string{"0.00000001", "0.1", "50000.01", "0.00123456", "123456789.12345678"}
+
+	for _, s := range values {
+		var d Decimal
+		if err := json.Unmarshal([]byte(`"`+s+`"`), &d); err != nil {
+			t.Fatalf("unmarshal %s: %v", s, err)
+		}
+
+		out, err := json.Marshal(d)
+		if err != nil {
+			t.Fatalf("marshal %s: %v", s, err)
+		}
+		if string(out) != `"`+s+`"` {
+			t.Errorf("round trip of %s: got %s", s, out)
+		}
+	}
+}
+
+func TestDecimal_Unmarshal_AcceptsNumber(t *testing.T) {
+	var req PlaceOrderRequest
+	if err := json.Unmarshal([]byte(`{"price":50000.5,"amount":"0.00000001"}`), &req); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if req.Price != 50000.5 {
+		t.Errorf("price: expected 50000.5, got %v", req.Price)
+	}
+	if req.Amount != 0.00000001 {
+		t.Errorf("amount: expected 0.00000001, got %v", req.Amount)
+	}
+}
+
+func TestDecimal_Unmarshal_Invalid(t *testing.T) {
+	inputs := []string{`"abc"`, `""`, `"NaN"`, `"Inf"`, `1e400`, `true`}
+
+	for _, in := range inputs {
+		var d Decimal
+		if err := json.Unmarshal([]byte(in), &d); err == nil {
+			t.Errorf("expected error for %s, got %v", in, d)
+		}
+	}
+}
+
+func TestDecimal_Marshal_PlainNotation(t *testing.T) {
+	out, err := json.Marshal(Decimal(1e-8))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(out) != `"0.00000001"` {
+		t.Errorf("expected \"0.00000001\", got %s", out)
+	}
+}
+
+func TestFixedDecimal_Marshal(t *testing.T) {
+	tests := []struct {
+		value    FixedDecimal
+		expected string
+	}{
+		{Crypto(1e-8), `"0.00000001"`},
+		{Crypto(0.1), `"0.10000000"`},
+		{Crypto(2), `"2.00000000"`},
+		{Fiat(50_000), `"50000.00"`},
+		{Fiat(0.1), `"0.10"`},
+		{Fiat(0.0005), `"0.0005"`}, // finer than cents is kept, not rounded away
+		{AssetAmount("BRL", 10), `"10.00"`},
+		{AssetAmount("BTC", 10), `"10.00000000"`},
+	}
+
+	for _, tt := range tests {
+		out, err := json.Marshal(tt.value)
+		if err != nil {
+			t.Fatalf("marshal %v: %v", tt.value.Value, err)
+		}
+		if string(out) != tt.expected {
+			t.Errorf("marshal %v: expected %s, got %s", tt.value.Value, tt.expected, out)
+		}
+	}
+}
+
+func TestFixedDecimal_Marshal_NoExponent(t *testing.T) {
+	out, err := json.Marshal(MatchResponse{Price: Fiat(1e-2), SizeFilled: Crypto(1e-8)})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(out), "e-") {
+		t.Errorf("exponent notation in %s", out)
+	}
+	if !strings.Contains(string(out), `"size_filled":"0.00000001"`) {
+		t.Errorf("expected plain size_filled in %s", out)
+	}
+}
+
+func TestFixedDecimal_Unmarshal(t *testing.T) {
+	var f FixedDecimal
+	if err := json.Unmarshal([]byte(`"0.50000000"`), &f); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if f.Value != 0.5 || f.Places != 8 {
+		t.Errorf("expected 0.5 with 8 places, got %v with %d", f.Value, f.Places)
+	}
+}
+
+func TestDecimal_Places(t *testing.T) {
+	cases := map[Decimal]int{50000: 0, 0.01: 2, 0.5: 1, 1e-8: 8, 0.123456789: 9, 50000.019: 3}
+
+	for d, want := range cases {
+		if got := d.Places(); got != want {
+			t.Errorf("places of %s: expected %d, got %d", d, want, got)
+		}
+	}
+}
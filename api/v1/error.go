@@ -2,4 +2,5 @@ package v1
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code" example:"INSUFFICIENT_BALANCE"`
 }
@@ -7,38 +7,270 @@ type PlaceOrderRequest struct {
 	Pair   string  `json:"pair" example:"BTC/BRL"`
 	Side   string  `json:"side" enums:"bid,ask" example:"bid"`
 	Type   string  `json:"type" enums:"limit,market" example:"limit"`
-	Price  float64 `json:"price" example:"50000.00"` // 0 para market orders
-	Amount float64 `json:"amount" example:"1"`
+	Price  Decimal `json:"price" swaggertype:"string" example:"50000.00"` // 0 para market orders
+	Amount Decimal `json:"amount" swaggertype:"string" example:"0.00100000"`
+	Hidden bool    `json:"hidden,omitempty"` // limit only: rest without showing in depth
+
+	// AmountPct replaces Amount with a percent (above 0, at most 100) of the
+	// user's available balance: base for an ask, quote for a bid, converted
+	// at price or by walking the book for a market bid. The amount is floored
+	// to the pair's tick and returned as resolved_amount.
+	AmountPct Decimal `json:"amount_pct,omitempty" swaggertype:"string" example:"25"`
+
+	// RestRemainder (market only) rests the part the book cannot fill as a
+	// limit at the last execution price instead of cancelling it.
+	RestRemainder bool `json:"rest_remainder,omitempty"`
+
+	// Nonce is optional. When set it must be greater than the last nonce the
+	// user sent, otherwise the request is rejected as a replay.
+	Nonce uint64 `json:"nonce,omitempty"`
+
+	// ExpiresAt (limit only) makes the order good-till-date: whatever still
+	// rests at that time is removed with state "expired".
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// AllowSelfTrade lets the order match the user's own resting orders
+	// instead of being stopped by self-trade prevention. Self-matches pay no
+	// fees, so they only release the locked funds on both sides.
+	AllowSelfTrade bool `json:"allow_self_trade,omitempty"`
+
+	// OrderID is optional: an ID from GET /api/v1/orders/reserve-id to give
+	// the order instead of a fresh one. Each reserved ID works once.
+	OrderID int64 `json:"order_id,omitempty"`
+
+	// ImproveOnly (limit only) rejects the order with WOULD_NOT_IMPROVE
+	// unless it would rest as the new best visible price on its side,
+	// strictly better than the current touch and without crossing the
+	// other side.
+	ImproveOnly bool `json:"improve_only,omitempty"`
+
+	// Tag is an opaque string of at most 64 bytes, echoed back on the order,
+	// its fills and its history. It does not affect matching.
+	Tag string `json:"tag,omitempty" example:"grid-7"`
+
+	// IncludeBalances embeds the user's balances, read right after the order
+	// matched, in the response, saving a call to the balance endpoint.
+	IncludeBalances bool `json:"include_balances,omitempty"`
+
+	// WaitFor is optional, a Go duration of at most 30s. When set, the
+	// response is held until the order is filled or closed, and an order
+	// still resting after WaitFor is cancelled, so the response always
+	// carries a terminal order.
+	WaitFor string `json:"wait_for,omitempty" example:"2s"`
 }
 
 type OrderResponse struct {
-	ID           int64     `json:"id"`
-	UserID       string    `json:"user_id"`
-	Pair         string    `json:"pair"`
-	Side         string    `json:"side"`
-	Type         string    `json:"type"`
-	Price        float64   `json:"price"`
-	Amount       float64   `json:"amount"`
-	FilledAmount float64   `json:"filled_amount"`
-	State        string    `json:"state"`
-	Timestamp    time.Time `json:"timestamp"`
+	ID           int64        `json:"id"`
+	UserID       string       `json:"user_id"`
+	Pair         string       `json:"pair"`
+	Side         string       `json:"side"`
+	Type         string       `json:"type"`
+	Price        FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"`
+	Amount       FixedDecimal `json:"amount" swaggertype:"string" example:"0.50000000"`
+	FilledAmount FixedDecimal `json:"filled_amount" swaggertype:"string" example:"0.00000000"`
+	State        string       `json:"state"`
+	Hidden       bool         `json:"hidden,omitempty"`
+	Timestamp    time.Time    `json:"timestamp"`
+	ExpiresAt    *time.Time   `json:"expires_at,omitempty"`
+	Tag          string       `json:"tag,omitempty"`
+
+	// Set on placement when the order rests: its 1-based FIFO position at
+	// its price level and how many orders are queued ahead of it.
+	QueuePosition int `json:"queue_position,omitempty"`
+	OrdersAhead   int `json:"orders_ahead,omitempty"`
+
+	// Set on cancel: the asset and amount moved back from locked to
+	// available, so clients need not diff balances.
+	UnlockedAsset  string        `json:"unlocked_asset,omitempty"`
+	UnlockedAmount *FixedDecimal `json:"unlocked_amount,omitempty" swaggertype:"string" example:"0.50000000"`
 }
 
 type MatchResponse struct {
-	BidOrderID int64     `json:"bid_order_id"`
-	AskOrderID int64     `json:"ask_order_id"`
-	Price      float64   `json:"price"`
-	SizeFilled float64   `json:"size_filled"`
-	Timestamp  time.Time `json:"timestamp"`
+	BidOrderID int64        `json:"bid_order_id"`
+	AskOrderID int64        `json:"ask_order_id"`
+	Price      FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"`
+	SizeFilled FixedDecimal `json:"size_filled" swaggertype:"string" example:"0.50000000"`
+	TakerSide  string       `json:"taker_side" enums:"bid,ask"` // side of the aggressor (the incoming order); empty for auction trades
+	Role       string       `json:"role" enums:"maker,taker"`   // role of the requesting user in this fill
+	Timestamp  time.Time    `json:"timestamp"`
+	Seq        uint64       `json:"seq"`           // exchange-wide trade sequence, increasing across every pair
+	PairSeq    uint64       `json:"pair_seq"`      // trade sequence within the pair
+	Tag        string       `json:"tag,omitempty"` // tag of the placed order
 }
 
 type PlaceOrderResponse struct {
-	Order   OrderResponse   `json:"order"`
-	Matches []MatchResponse `json:"matches"`
+	Order           OrderResponse   `json:"order"`
+	Matches         []MatchResponse `json:"matches"`
+	RequestedPrice  Decimal         `json:"requested_price,omitempty" swaggertype:"string"`
+	RequestedAmount Decimal         `json:"requested_amount" swaggertype:"string"`          // order.amount holds the accepted (tick-normalized) amount
+	ResolvedAmount  Decimal         `json:"resolved_amount,omitempty" swaggertype:"string"` // only with amount_pct: the amount it came to
+	Balances        []BalanceItem   `json:"balances,omitempty"`                             // only with include_balances
 }
 
 type CancelOrderRequest struct {
 	UserID  string `json:"user_id"`
 	Pair    string `json:"pair"`
 	OrderID int64  `json:"order_id"`
+	Nonce   uint64 `json:"nonce,omitempty"` // optional, see PlaceOrderRequest.Nonce
+}
+
+// AmendOrderRequest reduces a resting order's amount, keeping its price and
+// queue position.
+type AmendOrderRequest struct {
+	UserID  string  `json:"user_id"`
+	Pair    string  `json:"pair"`
+	OrderID int64   `json:"order_id"`
+	Amount  Decimal `json:"amount" swaggertype:"string" example:"0.50000000"` // new total amount, fills included
+	Nonce   uint64  `json:"nonce,omitempty"`                                  // optional, see PlaceOrderRequest.Nonce
+}
+
+type CancelAllOrdersRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// CancelAllOrdersResponse lists the orders cancelled, across every pair.
+type CancelAllOrdersResponse struct {
+	UserID    string          `json:"user_id"`
+	Cancelled []OrderResponse `json:"cancelled"`
+	Count     int             `json:"count"`
+}
+
+// OrderHistoryItem is a terminal order with its aggregated fills.
+type OrderHistoryItem struct {
+	ID           int64     `json:"id"`
+	Pair         string    `json:"pair"`
+	Side         string    `json:"side"`
+	Type         string    `json:"type"`
+	Price        Decimal   `json:"price" swaggertype:"string"` // 0 for market orders
+	Amount       Decimal   `json:"amount" swaggertype:"string"`
+	FilledAmount Decimal   `json:"filled_amount" swaggertype:"string"`
+	AvgFillPrice Decimal   `json:"avg_fill_price" swaggertype:"string"` // 0 when nothing filled
+	State        string    `json:"state"`
+	Tag          string    `json:"tag,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	ClosedAt     time.Time `json:"closed_at"`
+}
+
+type OrderHistoryResponse struct {
+	UserID string             `json:"user_id"`
+	Orders []OrderHistoryItem `json:"orders"`
+	Total  int                `json:"total"` // matching orders before paging
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+}
+
+// FillResponse is one execution of an order. The counterparty's user ID is
+// masked.
+type FillResponse struct {
+	TradeSeq     uint64       `json:"trade_seq"`
+	Price        FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"`
+	Amount       FixedDecimal `json:"amount" swaggertype:"string" example:"0.50000000"`
+	Role         string       `json:"role" enums:"maker,taker"`
+	Counterparty string       `json:"counterparty" example:"us****"`
+	Tag          string       `json:"tag,omitempty"` // the order's tag
+	Timestamp    time.Time    `json:"timestamp"`
+}
+
+// OrderFillsResponse is a page of the trades that filled one order, oldest
+// first.
+type OrderFillsResponse struct {
+	OrderID int64          `json:"order_id"`
+	Pair    string         `json:"pair"`
+	Fills   []FillResponse `json:"fills"`
+	Total   int            `json:"total"` // fills before paging
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+}
+
+// OrderStandingResponse estimates how a resting order would fare right now.
+// FillableNow is what would execute if it were resubmitted as a market order,
+// at FillableAvgPrice; QueueAhead is the volume that fills before it on its
+// own side.
+type OrderStandingResponse struct {
+	OrderID          int64        `json:"order_id"`
+	Pair             string       `json:"pair"`
+	Side             string       `json:"side" enums:"bid,ask"`
+	Price            FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"`
+	Remaining        FixedDecimal `json:"remaining" swaggertype:"string" example:"0.50000000"`
+	QueueAhead       FixedDecimal `json:"queue_ahead" swaggertype:"string" example:"1.20000000"`
+	FillableNow      FixedDecimal `json:"fillable_now" swaggertype:"string" example:"0.30000000"`
+	FillableAvgPrice FixedDecimal `json:"fillable_avg_price" swaggertype:"string" example:"50100.00"` // 0 when nothing is fillable
+}
+
+// OpenOrderResponse is a resting order as seen by a maker polling its
+// orders: Remaining is always Amount - FilledAmount, and QueuePosition and
+// QueueAhead are where it stands right now.
+type OpenOrderResponse struct {
+	ID            int64        `json:"id"`
+	Side          string       `json:"side" enums:"bid,ask"`
+	Price         FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"`
+	Amount        FixedDecimal `json:"amount" swaggertype:"string" example:"1.00000000"`
+	FilledAmount  FixedDecimal `json:"filled_amount" swaggertype:"string" example:"0.25000000"`
+	Remaining     FixedDecimal `json:"remaining" swaggertype:"string" example:"0.75000000"`
+	State         string       `json:"state"`
+	QueuePosition int          `json:"queue_position"` // 1-based, within its price level
+	QueueAhead    FixedDecimal `json:"queue_ahead" swaggertype:"string" example:"1.20000000"`
+	Timestamp     time.Time    `json:"timestamp"`
+	Tag           string       `json:"tag,omitempty"`
+}
+
+// OpenOrdersResponse lists a user's resting orders on a pair, oldest first.
+// The response carries an ETag; sending it back in If-None-Match yields 304
+// while nothing in the list has changed.
+type OpenOrdersResponse struct {
+	Pair   string              `json:"pair"`
+	Orders []OpenOrderResponse `json:"orders"`
+}
+
+// RequiredFundsResponse is what placing an order would lock right now. For
+// market orders it is estimated from the current book.
+type RequiredFundsResponse struct {
+	Pair   string       `json:"pair"`
+	Side   string       `json:"side" enums:"bid,ask"`
+	Type   string       `json:"type" enums:"limit,market"`
+	Asset  string       `json:"asset" example:"BRL"`
+	Amount FixedDecimal `json:"amount" swaggertype:"string" example:"25000.00"`
+}
+
+type OrderDetailResponse struct {
+	Order        OrderResponse  `json:"order"`
+	Fills        []FillResponse `json:"fills"`      // most recent fills, oldest first
+	FillCount    int            `json:"fill_count"` // all fills, including those no longer listed
+	AvgFillPrice FixedDecimal   `json:"avg_fill_price" swaggertype:"string" example:"50000.00"`
+	ClosedAt     *time.Time     `json:"closed_at,omitempty"` // unset while the order rests
+}
+
+// RejectionEvent is an order placement the engine refused. Reason is the
+// error code the placement failed with, the same one its HTTP response
+// carried; Error is the human-readable message.
+type RejectionEvent struct {
+	Seq       uint64       `json:"seq"`
+	Pair      string       `json:"pair"`
+	Side      string       `json:"side" enums:"bid,ask"`
+	Type      string       `json:"type" enums:"limit,market"`
+	Price     FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"` // 0 for market orders
+	Amount    FixedDecimal `json:"amount" swaggertype:"string" example:"0.50000000"`
+	Tag       string       `json:"tag,omitempty"`
+	Reason    string       `json:"reason" example:"INSUFFICIENT_BALANCE"`
+	Error     string       `json:"error"`
+	Timestamp time.Time    `json:"timestamp"`
+}
+
+type ReserveOrderIDRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// ReserveOrderIDResponse carries an order ID reserved for the user, to send
+// as order_id when placing an order before ExpiresAt.
+type ReserveOrderIDResponse struct {
+	UserID    string    `json:"user_id"`
+	OrderID   int64     `json:"order_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RejectionsResponse lists a user's most recent rejected orders, newest
+// first.
+type RejectionsResponse struct {
+	UserID     string           `json:"user_id"`
+	Rejections []RejectionEvent `json:"rejections"`
 }
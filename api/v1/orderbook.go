@@ -1,14 +1,107 @@
 package v1
 
+import "time"
+
 type LimitLevel struct {
-	Price       float64 `json:"price"`
-	TotalVolume float64 `json:"total_volume"`
+	Price       FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"`
+	TotalVolume FixedDecimal `json:"total_volume" swaggertype:"string" example:"1.50000000"`
 }
 
+// OrderbookResponse is the visible depth of a pair. BestBid and BestAsk are
+// null when their side is empty, and Spread and SpreadBps are null unless both
+// sides exist, so a one-sided book is never reported with a zero spread.
+// SpreadBps is the spread relative to the mid price, in basis points.
 type OrderbookResponse struct {
-	Pair           string       `json:"pair"`
-	Bids           []LimitLevel `json:"bids"`
-	Asks           []LimitLevel `json:"asks"`
-	BidTotalVolume float64      `json:"bid_total_volume"`
-	AskTotalVolume float64      `json:"ask_total_volume"`
+	Pair           string        `json:"pair"`
+	Bids           []LimitLevel  `json:"bids"`
+	Asks           []LimitLevel  `json:"asks"`
+	BidTotalVolume FixedDecimal  `json:"bid_total_volume" swaggertype:"string"`
+	AskTotalVolume FixedDecimal  `json:"ask_total_volume" swaggertype:"string"`
+	BestBid        *FixedDecimal `json:"best_bid" swaggertype:"string" extensions:"x-nullable"`
+	BestAsk        *FixedDecimal `json:"best_ask" swaggertype:"string" extensions:"x-nullable"`
+	Spread         *FixedDecimal `json:"spread" swaggertype:"string" extensions:"x-nullable"`
+	SpreadBps      *float64      `json:"spread_bps" extensions:"x-nullable"`
+	OneSided       bool          `json:"one_sided"` // exactly one side has visible orders
+	Crossed        bool          `json:"crossed"`   // best bid at or above best ask
+	Checksum       uint32        `json:"checksum"`  // CRC32 of the best 25 levels per side, see BookChecksumEvent
+}
+
+// TopOfBookResponse carries only the best level of each side. Price and volume
+// are zero when the matching Has* flag is false; Spread requires both sides.
+// WeightedMid is the volume-weighted mid over the best Levels levels of each
+// side (the micro-price for 1), null unless both sides exist.
+type TopOfBookResponse struct {
+	Pair      string       `json:"pair"`
+	HasBid    bool         `json:"has_bid"`
+	BidPrice  FixedDecimal `json:"bid_price" swaggertype:"string"`
+	BidVolume FixedDecimal `json:"bid_volume" swaggertype:"string"`
+	HasAsk    bool         `json:"has_ask"`
+	AskPrice  FixedDecimal `json:"ask_price" swaggertype:"string"`
+	AskVolume FixedDecimal `json:"ask_volume" swaggertype:"string"`
+	Spread    FixedDecimal `json:"spread" swaggertype:"string"`
+
+	Levels      int           `json:"levels"`
+	WeightedMid *FixedDecimal `json:"weighted_mid" swaggertype:"string" extensions:"x-nullable"`
+}
+
+// DepthLevel is one price level of a depth ladder. CumulativeVolume and
+// CumulativeQuote add up the level and every better one on its side, from
+// the best price out, as a depth chart draws them.
+type DepthLevel struct {
+	Price            FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"`
+	Volume           FixedDecimal `json:"volume" swaggertype:"string" example:"1.50000000"`
+	CumulativeVolume FixedDecimal `json:"cumulative_volume" swaggertype:"string" example:"2.00000000"`
+	CumulativeQuote  FixedDecimal `json:"cumulative_quote" swaggertype:"string" example:"100010.00"`
+}
+
+// DepthResponse is the visible depth of a pair with running totals, best
+// price first on each side. Depth is the levels-per-side limit, 0 for all.
+type DepthResponse struct {
+	Pair  string       `json:"pair"`
+	Depth int          `json:"depth"`
+	Bids  []DepthLevel `json:"bids"`
+	Asks  []DepthLevel `json:"asks"`
+}
+
+// AuctionPreviewResponse is the indicative clearing of a pair in auction
+// mode: what its call auction would execute if it ran now. Imbalance is the
+// bid volume at or above Price minus the ask volume at or below it.
+type AuctionPreviewResponse struct {
+	Pair      string       `json:"pair"`
+	Price     FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"` // 0 when nothing crosses
+	Volume    FixedDecimal `json:"volume" swaggertype:"string" example:"0.75000000"`
+	Imbalance FixedDecimal `json:"imbalance" swaggertype:"string" example:"-0.50000000"`
+}
+
+// DepthInRangeResponse is the visible volume resting on one side between two
+// prices, both inclusive.
+type DepthInRangeResponse struct {
+	Pair   string       `json:"pair"`
+	Side   string       `json:"side" enums:"bid,ask"`
+	From   FixedDecimal `json:"from" swaggertype:"string" example:"50000.00"`
+	To     FixedDecimal `json:"to" swaggertype:"string" example:"51000.00"`
+	Volume FixedDecimal `json:"volume" swaggertype:"string" example:"1.50000000"`
+	Orders int          `json:"orders"`
+	Levels int          `json:"levels"`
+}
+
+// SpreadSample is the visible top of a book at one point in time. Prices are
+// zero when the matching Has* flag is false; Spread and Mid require both
+// sides.
+type SpreadSample struct {
+	Timestamp time.Time    `json:"timestamp"`
+	HasBid    bool         `json:"has_bid"`
+	BidPrice  FixedDecimal `json:"bid_price" swaggertype:"string" example:"49900.00"`
+	HasAsk    bool         `json:"has_ask"`
+	AskPrice  FixedDecimal `json:"ask_price" swaggertype:"string" example:"50100.00"`
+	Spread    FixedDecimal `json:"spread" swaggertype:"string" example:"200.00"`
+	Mid       FixedDecimal `json:"mid" swaggertype:"string" example:"50000.00"`
+}
+
+// SpreadHistoryResponse lists the latest spread samples of a pair, oldest
+// first, taken every Interval.
+type SpreadHistoryResponse struct {
+	Pair     string         `json:"pair"`
+	Interval string         `json:"interval" example:"1s"`
+	Samples  []SpreadSample `json:"samples"`
 }
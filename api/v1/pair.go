@@ -0,0 +1,15 @@
+package v1
+
+type PairInfo struct {
+	Symbol       string  `json:"symbol" example:"BTC/BRL"`
+	Base         string  `json:"base" example:"BTC"`
+	Quote        string  `json:"quote" example:"BRL"`
+	PriceTick    Decimal `json:"price_tick" swaggertype:"string" example:"0.01"`
+	AmountTick   Decimal `json:"amount_tick" swaggertype:"string" example:"0.00000001"`
+	MinOrderSize Decimal `json:"min_order_size" swaggertype:"string"`
+	MinNotional  Decimal `json:"min_notional" swaggertype:"string"`
+	LotSize      Decimal `json:"lot_size,omitempty" swaggertype:"string" example:"0.001"`      // amounts must be multiples of it
+	MaxMarketGap Decimal `json:"max_market_gap,omitempty" swaggertype:"string" example:"0.05"` // market orders stop at a wider gap between levels
+	Halted       bool    `json:"halted"`
+	Mode         string  `json:"mode,omitempty" enums:"maker_only,taker_only,auction"` // empty when makers and takers are both accepted
+}
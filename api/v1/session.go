@@ -0,0 +1,12 @@
+package v1
+
+// SessionMessage is exchanged over the order session WebSocket. The server
+// sends "session" once after connecting and answers every "ping" with "pong".
+// Each order of the user the engine rejects is pushed as "rejected".
+type SessionMessage struct {
+	Type               string          `json:"type" enums:"session,ping,pong,error,rejected"`
+	UserID             string          `json:"user_id,omitempty"`
+	CancelOnDisconnect bool            `json:"cancel_on_disconnect,omitempty"`
+	Error              string          `json:"error,omitempty"`
+	Rejection          *RejectionEvent `json:"rejection,omitempty"`
+}
@@ -0,0 +1,49 @@
+package v1
+
+import "time"
+
+// StreamControl is sent by a client on a market data WebSocket to change what
+// it receives. Pairs use the BASE/QUOTE form; an empty Events list means every
+// event type. Unsubscribe removes exactly the listed pair/event combinations.
+type StreamControl struct {
+	Op     string   `json:"op" enums:"subscribe,unsubscribe"`
+	Pairs  []string `json:"pairs" example:"BTC/BRL"`
+	Events []string `json:"events,omitempty" enums:"trades,orderbook"`
+}
+
+// StreamAck answers a StreamControl with the subscriptions now active on the
+// connection, or the reason the message was rejected.
+type StreamAck struct {
+	Type          string   `json:"type" enums:"subscribed,unsubscribed,error"`
+	Subscriptions []string `json:"subscriptions,omitempty"` // "PAIR:event", sorted
+	Error         string   `json:"error,omitempty"`
+}
+
+// StreamEvent is a published market data event.
+type StreamEvent struct {
+	Type string      `json:"type" enums:"trades,orderbook"`
+	Pair string      `json:"pair"`
+	Data interface{} `json:"data"`
+}
+
+// BookChecksumEvent is the Data of an "orderbook" StreamEvent: the checksum
+// of the book's public depth over the best Depth levels per side. It is the
+// CRC32 (IEEE) of the bids, best first, then "|", then the asks, best first,
+// each level written "price:volume" with 8 decimals and levels separated by
+// ",", e.g. "50000.00000000:1.50000000|50010.00000000:2.00000000".
+type BookChecksumEvent struct {
+	Depth     int       `json:"depth"`
+	Checksum  uint32    `json:"checksum"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// TradeEvent is the Data of a "trades" StreamEvent: one executed trade, with
+// no user or order IDs.
+type TradeEvent struct {
+	Seq       uint64       `json:"seq"`      // exchange-wide trade sequence
+	PairSeq   uint64       `json:"pair_seq"` // trade sequence within the pair
+	Price     FixedDecimal `json:"price" swaggertype:"string" example:"50000.00"`
+	Amount    FixedDecimal `json:"amount" swaggertype:"string" example:"0.50000000"`
+	TakerSide string       `json:"taker_side" enums:"bid,ask"` // side of the aggressor; empty for auction trades
+	Timestamp time.Time    `json:"timestamp"`
+}
@@ -1,7 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/moura95/crypto-exchange-challenge/config"
 	server "github.com/moura95/crypto-exchange-challenge/internal"
@@ -24,6 +31,10 @@ import (
 // @BasePath /
 // @schemes http https
 
+// shutdownTimeout bounds how long requests in flight get to finish on
+// shutdown.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -35,7 +46,25 @@ func main() {
 		log.Fatalf("Failed to create server: %v", err)
 	}
 
-	if err := srv.Start(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() { errs <- srv.Start() }()
+
+	select {
+	case err := <-errs:
 		log.Fatalf("Server failed to start: %v", err)
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := <-errs; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server failed: %v", err)
 	}
 }
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// Bootstrap is the startup state read from BOOTSTRAP_FILE: the pairs to list
+// and the balances to credit on a fresh engine.
+type Bootstrap struct {
+	Pairs    []BootstrapPair    `json:"pairs"`
+	Balances []BootstrapBalance `json:"balances"`
+}
+
+// BootstrapPair lists one pair, e.g. {"pair": "BTC/BRL", "price_tick": 0.01}.
+type BootstrapPair struct {
+	Pair         string  `json:"pair"`
+	PriceTick    float64 `json:"price_tick"`
+	AmountTick   float64 `json:"amount_tick"`
+	MinOrderSize float64 `json:"min_order_size"`
+	MinNotional  float64 `json:"min_notional"`
+	LotSize      float64 `json:"lot_size"`
+	MaxMarketGap float64 `json:"max_market_gap"`
+	Mode         string  `json:"mode"` // "maker_only", "taker_only", "auction" or empty for both
+}
+
+// BootstrapBalance is an initial available balance.
+type BootstrapBalance struct {
+	UserID string  `json:"user_id"`
+	Asset  string  `json:"asset"`
+	Amount float64 `json:"amount"`
+}
+
+// LoadBootstrap reads and validates the JSON bootstrap file at path. Unknown
+// fields are rejected so a typo cannot silently drop a setting.
+func LoadBootstrap(path string) (*Bootstrap, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+
+	var b Bootstrap
+	if err := dec.Decode(&b); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := b.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &b, nil
+}
+
+func (b *Bootstrap) validate() error {
+	seen := make(map[string]bool, len(b.Pairs))
+	for i, p := range b.Pairs {
+		base, quote, ok := strings.Cut(p.Pair, "/")
+		if !ok || base == "" || quote == "" {
+			return fmt.Errorf("pairs[%d]: %q must be BASE/QUOTE", i, p.Pair)
+		}
+		key := strings.ToUpper(p.Pair)
+		if seen[key] {
+			return fmt.Errorf("pairs[%d]: %s listed twice", i, p.Pair)
+		}
+		seen[key] = true
+
+		if !positive(p.PriceTick) || !positive(p.AmountTick) {
+			return fmt.Errorf("pairs[%d]: price_tick and amount_tick must be positive numbers", i)
+		}
+		if !nonNegative(p.MinOrderSize) || !nonNegative(p.MinNotional) || !nonNegative(p.LotSize) || !nonNegative(p.MaxMarketGap) {
+			return fmt.Errorf("pairs[%d]: min_order_size, min_notional, lot_size and max_market_gap must be non-negative numbers", i)
+		}
+		if p.Mode != "" && p.Mode != "maker_only" && p.Mode != "taker_only" && p.Mode != "auction" {
+			return fmt.Errorf("pairs[%d]: mode must be maker_only, taker_only, auction or empty", i)
+		}
+	}
+
+	for i, bal := range b.Balances {
+		if bal.UserID == "" || bal.Asset == "" {
+			return fmt.Errorf("balances[%d]: user_id and asset are required", i)
+		}
+		if !positive(bal.Amount) {
+			return fmt.Errorf("balances[%d]: amount must be a positive number", i)
+		}
+	}
+	return nil
+}
+
+func positive(v float64) bool {
+	return v > 0 && !math.IsInf(v, 0)
+}
+
+func nonNegative(v float64) bool {
+	return v >= 0 && !math.IsInf(v, 0)
+}
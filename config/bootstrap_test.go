@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeBootstrap(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bootstrap.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	return path
+}
+
+func TestLoadBootstrap_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"malformed JSON", `{"pairs": [`, "unexpected EOF"},
+		{"unknown field", `{"pairz": []}`, "unknown field"},
+		{"bad symbol", `{"pairs": [{"pair": "BTCBRL", "price_tick": 0.01, "amount_tick": 0.01}]}`, "BASE/QUOTE"},
+		{"duplicate pair", `{"pairs": [
+			{"pair": "BTC/BRL", "price_tick": 0.01, "amount_tick": 0.01},
+			{"pair": "btc/brl", "price_tick": 0.01, "amount_tick": 0.01}]}`, "listed twice"},
+		{"missing tick", `{"pairs": [{"pair": "BTC/BRL", "price_tick": 0.01}]}`, "must be positive"},
+		{"negative minimum", `{"pairs": [{"pair": "BTC/BRL", "price_tick": 0.01, "amount_tick": 0.01, "min_notional": -1}]}`, "non-negative"},
+		{"bad mode", `{"pairs": [{"pair": "BTC/BRL", "price_tick": 0.01, "amount_tick": 0.01, "mode": "batch"}]}`, "mode must be"},
+		{"missing user", `{"balances": [{"asset": "BRL", "amount": 10}]}`, "user_id and asset are required"},
+		{"zero amount", `{"balances": [{"user_id": "1", "asset": "BRL", "amount": 0}]}`, "positive number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadBootstrap(writeBootstrap(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadBootstrap_MissingFile(t *testing.T) {
+	if _, err := LoadBootstrap(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
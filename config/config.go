@@ -1,24 +1,270 @@
 package config
 
 import (
+	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	HTTPServerAddress string
+	TickPolicy        string
+
+	// MaxOpenOrdersPerUser caps resting orders per user per pair. 0 disables the cap.
+	MaxOpenOrdersPerUser int
+
+	// MaxLevelsPerOrder caps the price levels one order may match against. 0 disables the cap.
+	MaxLevelsPerOrder int
+
+	// SpreadSampleInterval is how often the top of every book is sampled for
+	// the spread history. 0 disables sampling.
+	SpreadSampleInterval time.Duration
+
+	// ReconcileInterval is how often level volumes are recomputed from the
+	// resting orders to undo float drift. 0 disables it.
+	ReconcileInterval time.Duration
+
+	// ChecksumInterval is how often book checksums are published to stream
+	// clients that asked for them. 0 disables them.
+	ChecksumInterval time.Duration
+
+	// SweepDust auto-cancels resting remainders below the pair's minimum order size.
+	SweepDust bool
+
+	// AllowUnlistedPairs lets orders create books for pairs that were never registered.
+	AllowUnlistedPairs bool
+
+	// DeterministicMatching makes match output reproducible from the order
+	// sequence alone, for auditing.
+	DeterministicMatching bool
+
+	// MaxBodyBytes caps the size of request bodies. 0 disables the cap.
+	MaxBodyBytes int64
+
+	// AdminToken guards the /api/v1/admin routes. Empty disables them.
+	AdminToken string
+
+	// FeeTiers is the fee schedule by 30-day quote volume. Empty means no fees.
+	FeeTiers []FeeTier
+
+	// FeeRounding is which way fees are rounded: nearest (the default), up
+	// (to the fee account) or down (to the user).
+	FeeRounding string
+
+	// MinRefund overrides, per asset, the smallest refund unlocked after a
+	// fill. Assets not listed use their smallest unit.
+	MinRefund map[string]float64
+
+	// TakerDelay, TakerLimit and TakerWindow throttle orders that cross the
+	// spread. Zero values leave takers unthrottled.
+	TakerDelay  time.Duration
+	TakerLimit  int
+	TakerWindow time.Duration
+
+	// Bootstrap is the content of BOOTSTRAP_FILE, or nil when it is unset.
+	Bootstrap *Bootstrap
+}
+
+// FeeTier is one entry of FEE_TIERS, written as min_volume:maker_rate:taker_rate.
+type FeeTier struct {
+	MinVolume float64
+	MakerRate float64
+	TakerRate float64
 }
 
 func Load() (*Config, error) {
 	cfg := &Config{
 		HTTPServerAddress: getEnv("HTTP_SERVER_ADDRESS", "0.0.0.0:8080"),
+		TickPolicy:        getEnv("TICK_POLICY", "floor"),
+		FeeRounding:       getEnv("FEE_ROUNDING", "nearest"),
+		AdminToken:        getEnv("ADMIN_TOKEN", ""),
 	}
 	if cfg.HTTPServerAddress == "" {
 		cfg.HTTPServerAddress = "0.0.0.0:8080"
 	}
 
+	switch cfg.TickPolicy {
+	case "floor", "round", "reject":
+	default:
+		return nil, fmt.Errorf("invalid TICK_POLICY %q: must be floor, round or reject", cfg.TickPolicy)
+	}
+
+	switch cfg.FeeRounding {
+	case "up", "down", "nearest":
+	default:
+		return nil, fmt.Errorf("invalid FEE_ROUNDING %q: must be up, down or nearest", cfg.FeeRounding)
+	}
+
+	maxOpen, err := strconv.Atoi(getEnv("MAX_OPEN_ORDERS_PER_USER", "0"))
+	if err != nil || maxOpen < 0 {
+		return nil, fmt.Errorf("invalid MAX_OPEN_ORDERS_PER_USER: must be a non-negative integer")
+	}
+	cfg.MaxOpenOrdersPerUser = maxOpen
+
+	maxLevels, err := strconv.Atoi(getEnv("MAX_LEVELS_PER_ORDER", "0"))
+	if err != nil || maxLevels < 0 {
+		return nil, fmt.Errorf("invalid MAX_LEVELS_PER_ORDER: must be a non-negative integer")
+	}
+	cfg.MaxLevelsPerOrder = maxLevels
+
+	spreadInterval, err := time.ParseDuration(getEnv("SPREAD_SAMPLE_INTERVAL", "1s"))
+	if err != nil || spreadInterval < 0 {
+		return nil, fmt.Errorf("invalid SPREAD_SAMPLE_INTERVAL: must be a non-negative duration")
+	}
+	cfg.SpreadSampleInterval = spreadInterval
+
+	reconcileInterval, err := time.ParseDuration(getEnv("RECONCILE_INTERVAL", "0s"))
+	if err != nil || reconcileInterval < 0 {
+		return nil, fmt.Errorf("invalid RECONCILE_INTERVAL: must be a non-negative duration")
+	}
+	cfg.ReconcileInterval = reconcileInterval
+
+	checksumInterval, err := time.ParseDuration(getEnv("CHECKSUM_INTERVAL", "5s"))
+	if err != nil || checksumInterval < 0 {
+		return nil, fmt.Errorf("invalid CHECKSUM_INTERVAL: must be a non-negative duration")
+	}
+	cfg.ChecksumInterval = checksumInterval
+
+	sweepDust, err := strconv.ParseBool(getEnv("SWEEP_DUST", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SWEEP_DUST: must be true or false")
+	}
+	cfg.SweepDust = sweepDust
+
+	allowUnlisted, err := strconv.ParseBool(getEnv("ALLOW_UNLISTED_PAIRS", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ALLOW_UNLISTED_PAIRS: must be true or false")
+	}
+	cfg.AllowUnlistedPairs = allowUnlisted
+
+	maxBody, err := strconv.ParseInt(getEnv("MAX_BODY_BYTES", "1048576"), 10, 64)
+	if err != nil || maxBody < 0 {
+		return nil, fmt.Errorf("invalid MAX_BODY_BYTES: must be a non-negative integer")
+	}
+	cfg.MaxBodyBytes = maxBody
+
+	deterministic, err := strconv.ParseBool(getEnv("DETERMINISTIC_MATCHING", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DETERMINISTIC_MATCHING: must be true or false")
+	}
+	cfg.DeterministicMatching = deterministic
+
+	feeTiers, err := parseFeeTiers(getEnv("FEE_TIERS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FEE_TIERS: %w", err)
+	}
+	cfg.FeeTiers = feeTiers
+
+	minRefund, err := parseMinRefund(getEnv("MIN_REFUND", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MIN_REFUND: %w", err)
+	}
+	cfg.MinRefund = minRefund
+
+	takerDelay, err := time.ParseDuration(getEnv("TAKER_DELAY", "0s"))
+	if err != nil || takerDelay < 0 {
+		return nil, fmt.Errorf("invalid TAKER_DELAY: must be a non-negative duration")
+	}
+	cfg.TakerDelay = takerDelay
+
+	cfg.TakerLimit, cfg.TakerWindow, err = parseRateLimit(getEnv("TAKER_RATE_LIMIT", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TAKER_RATE_LIMIT: %w", err)
+	}
+
+	if path := getEnv("BOOTSTRAP_FILE", ""); path != "" {
+		cfg.Bootstrap, err = LoadBootstrap(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BOOTSTRAP_FILE: %w", err)
+		}
+	}
+
 	return cfg, nil
 }
 
+// parseRateLimit parses a count/window limit, e.g. "10/1m".
+func parseRateLimit(raw string) (int, time.Duration, error) {
+	if raw == "" {
+		return 0, 0, nil
+	}
+
+	count, window, ok := strings.Cut(raw, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q must be count/window, e.g. 10/1m", raw)
+	}
+	limit, err := strconv.Atoi(count)
+	if err != nil || limit <= 0 {
+		return 0, 0, fmt.Errorf("%q: count must be a positive integer", raw)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return 0, 0, fmt.Errorf("%q: window must be a positive duration", raw)
+	}
+	return limit, d, nil
+}
+
+// parseFeeTiers parses a comma-separated list of min_volume:maker_rate:taker_rate
+// entries, e.g. "0:0.001:0.002,100000:-0.0001:0.001". A negative maker rate
+// is a rebate.
+func parseFeeTiers(raw string) ([]FeeTier, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var tiers []FeeTier
+	for _, entry := range strings.Split(raw, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("entry %q must be min_volume:maker_rate:taker_rate", entry)
+		}
+
+		var values [3]float64
+		for i, part := range parts {
+			v, err := strconv.ParseFloat(part, 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("entry %q: %q is not a number", entry, part)
+			}
+			// Only the maker rate may be negative: a rebate
+			if v < 0 && i != 1 {
+				return nil, fmt.Errorf("entry %q: %q must not be negative", entry, part)
+			}
+			values[i] = v
+		}
+		if values[1] <= -1 || values[1] >= 1 || values[2] >= 1 {
+			return nil, fmt.Errorf("entry %q: rates must be between -1 and 1", entry)
+		}
+
+		tiers = append(tiers, FeeTier{MinVolume: values[0], MakerRate: values[1], TakerRate: values[2]})
+	}
+	return tiers, nil
+}
+
+// parseMinRefund parses a comma-separated list of asset:threshold entries,
+// e.g. "BRL:0.01,USDT:0.000001".
+func parseMinRefund(raw string) (map[string]float64, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	thresholds := make(map[string]float64)
+	for _, entry := range strings.Split(raw, ",") {
+		asset, value, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || asset == "" {
+			return nil, fmt.Errorf("entry %q must be asset:threshold", entry)
+		}
+
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v <= 0 || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("entry %q: %q is not a positive number", entry, value)
+		}
+		thresholds[strings.ToUpper(asset)] = v
+	}
+	return thresholds, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,89 @@
+package account
+
+// MaxDepositsPerUser bounds the deposit IDs remembered per user. The oldest
+// are forgotten first, so a retry arriving after that many newer deposits of
+// the same user credits again.
+const MaxDepositsPerUser = 1000
+
+// Deposit is a credit applied under a deposit ID, as first recorded.
+type Deposit struct {
+	ID       string
+	Asset    string
+	Amount   float64            // rounded to the asset's precision
+	Balances map[string]Balance // every balance of the user right after the credit
+}
+
+// depositLog is the bounded set of deposits seen for one user.
+type depositLog struct {
+	byID  map[string]Deposit
+	order []string // oldest first
+}
+
+func (l *depositLog) add(deposit Deposit) {
+	if len(l.order) == MaxDepositsPerUser {
+		delete(l.byID, l.order[0])
+		l.order = l.order[1:]
+	}
+	l.byID[deposit.ID] = deposit
+	l.order = append(l.order, deposit.ID)
+}
+
+// CreditDeposit credits amount like Credit, once per depositID and user, so
+// a payment system may retry safely. A repeated depositID changes nothing and
+// returns the deposit first recorded under it, with replayed set; it fails
+// with ErrDepositConflict if the asset or amount differ from that deposit.
+// An empty depositID is a plain Credit.
+func (m *Manager) CreditDeposit(userID, depositID, asset string, amount float64) (deposit Deposit, replayed bool, err error) {
+	if err := m.validateInputs(userID, asset, amount); err != nil {
+		return Deposit{}, false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.creditDeposit(userID, depositID, asset, amount)
+}
+
+// creditDeposit is CreditDeposit on validated inputs. Must be called with
+// m.mu held.
+func (m *Manager) creditDeposit(userID, depositID, asset string, amount float64) (Deposit, bool, error) {
+	amount = m.round(asset, amount)
+
+	log := m.deposits[userID]
+	if log != nil && depositID != "" {
+		if prior, seen := log.byID[depositID]; seen {
+			if prior.Asset != asset || prior.Amount != amount {
+				return Deposit{}, false, ErrDepositConflict
+			}
+			return prior, true, nil
+		}
+	}
+
+	balance, err := m.load(userID, asset)
+	if err != nil {
+		return Deposit{}, false, err
+	}
+	balance.Available += amount
+	if err := m.save(userID, asset, balance); err != nil {
+		return Deposit{}, false, err
+	}
+
+	deposit := Deposit{ID: depositID, Asset: asset, Amount: amount, Balances: make(map[string]Balance)}
+	if depositID == "" {
+		return deposit, false, nil
+	}
+
+	// The credit is done, so it is recorded even if the snapshot cannot be
+	// read: a retry must not credit again.
+	if balances, err := m.store.Balances(userID); err == nil {
+		for a, b := range balances {
+			deposit.Balances[a] = b
+		}
+	}
+	if log == nil {
+		log = &depositLog{byID: make(map[string]Deposit)}
+		m.deposits[userID] = log
+	}
+	log.add(deposit)
+	return deposit, false, nil
+}
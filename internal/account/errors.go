@@ -6,6 +6,10 @@ var (
 	ErrInsufficientBalance = errors.New("insufficient balance")
 	ErrInsufficientLocked  = errors.New("insufficient locked balance")
 	ErrInvalidAmount       = errors.New("amount must be greater than 0")
+	ErrNonFiniteAmount     = errors.New("amount must be a finite number")
 	ErrInvalidAsset        = errors.New("asset cannot be empty")
 	ErrInvalidUserID       = errors.New("userID cannot be empty")
+	ErrInvalidPrecision    = errors.New("precision must be between 0 and 8 decimals")
+	ErrNegativeBalance     = errors.New("operation would leave a negative balance")
+	ErrDepositConflict     = errors.New("deposit_id already used for a different credit")
 )
@@ -1,16 +1,33 @@
 package account
 
-import "sync"
+import (
+	"math"
+	"sync"
+)
 
 type Manager struct {
-	accounts map[string]map[string]*Balance
-	mu       sync.RWMutex
+	store      Store
+	precisions map[string]int         // asset -> decimals
+	deposits   map[string]*depositLog // user ID -> deposits seen, see CreditDeposit
+	mu         sync.RWMutex
 }
 
+// NewManager returns a manager keeping balances in memory.
 func NewManager() *Manager {
-	return &Manager{
-		accounts: make(map[string]map[string]*Balance),
+	return NewManagerWithStore(NewMemoryStore())
+}
+
+// NewManagerWithStore returns a manager keeping balances in store.
+func NewManagerWithStore(store Store) *Manager {
+	m := &Manager{
+		store:      store,
+		precisions: make(map[string]int, len(DefaultPrecisions)),
+		deposits:   make(map[string]*depositLog),
 	}
+	for asset, decimals := range DefaultPrecisions {
+		m.precisions[asset] = decimals
+	}
+	return m
 }
 
 // Credit adds amount to available balance
@@ -24,10 +41,43 @@ func (m *Manager) Credit(userID, asset string, amount float64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	balance := m.getOrCreateBalance(userID, asset)
+	amount = m.round(asset, amount)
+	balance, err := m.load(userID, asset)
+	if err != nil {
+		return err
+	}
 	balance.Available += amount
+	return m.save(userID, asset, balance)
+}
 
-	return nil
+// CreditEntry is one item of a batch credit.
+type CreditEntry struct {
+	UserID    string
+	Asset     string
+	Amount    float64
+	DepositID string // optional, see CreditDeposit
+}
+
+// CreditBatch credits every entry independently under a single lock. An
+// invalid entry does not stop the others; the result holds one error (or
+// nil) per entry, in order. Entries with a deposit ID already seen are
+// skipped without error, as in CreditDeposit.
+func (m *Manager) CreditBatch(entries []CreditEntry) []error {
+	results := make([]error, len(entries))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, entry := range entries {
+		if err := m.validateInputs(entry.UserID, entry.Asset, entry.Amount); err != nil {
+			results[i] = err
+			continue
+		}
+
+		_, _, results[i] = m.creditDeposit(entry.UserID, entry.DepositID, entry.Asset, entry.Amount)
+	}
+
+	return results
 }
 
 // Debit remove amount from available balance
@@ -41,13 +91,17 @@ func (m *Manager) Debit(userID, asset string, amount float64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	balance := m.getOrCreateBalance(userID, asset)
+	amount = m.round(asset, amount)
+	balance, err := m.load(userID, asset)
+	if err != nil {
+		return err
+	}
 	if balance.Available < amount {
 		return ErrInsufficientBalance
 	}
 
 	balance.Available -= amount
-	return nil
+	return m.save(userID, asset, balance)
 }
 
 // Lock amount from available balance to locked
@@ -61,14 +115,18 @@ func (m *Manager) Lock(userID, asset string, amount float64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	balance := m.getOrCreateBalance(userID, asset)
+	amount = m.round(asset, amount)
+	balance, err := m.load(userID, asset)
+	if err != nil {
+		return err
+	}
 	if balance.Available < amount {
 		return ErrInsufficientBalance
 	}
 
 	balance.Available -= amount
 	balance.Locked += amount
-	return nil
+	return m.save(userID, asset, balance)
 }
 
 // Unlock amount from locked to available
@@ -82,14 +140,50 @@ func (m *Manager) Unlock(userID, asset string, amount float64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	balance := m.getOrCreateBalance(userID, asset)
+	amount = m.round(asset, amount)
+	balance, err := m.load(userID, asset)
+	if err != nil {
+		return err
+	}
 	if balance.Locked < amount {
 		return ErrInsufficientLocked
 	}
 
 	balance.Locked -= amount
 	balance.Available += amount
-	return nil
+	return m.save(userID, asset, balance)
+}
+
+// Relock swaps a lock of from on asset for a lock of to in one step, as when
+// a resting order is replaced by one that needs a different amount. Nothing
+// changes if from is not locked or the balance cannot cover to.
+func (m *Manager) Relock(userID, asset string, from, to float64) error {
+	if err := m.validateInputs(userID, asset, from); err != nil {
+		return err
+	}
+	if err := m.validateInputs(userID, asset, to); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from = m.round(asset, from)
+	to = m.round(asset, to)
+	balance, err := m.load(userID, asset)
+	if err != nil {
+		return err
+	}
+	if balance.Locked < from {
+		return ErrInsufficientLocked
+	}
+	if balance.Available+from < to {
+		return ErrInsufficientBalance
+	}
+
+	balance.Available += from - to
+	balance.Locked += to - from
+	return m.save(userID, asset, balance)
 }
 
 // DebitLocked remove amount from locked balance
@@ -103,59 +197,77 @@ func (m *Manager) DebitLocked(userID, asset string, amount float64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	balance := m.getOrCreateBalance(userID, asset)
+	amount = m.round(asset, amount)
+	balance, err := m.load(userID, asset)
+	if err != nil {
+		return err
+	}
 	if balance.Locked < amount {
 		return ErrInsufficientLocked
 	}
 
 	balance.Locked -= amount
-	return nil
+	return m.save(userID, asset, balance)
 }
 
+// GetBalance returns a copy of userID's balance of asset, or nil if there is
+// none or the store could not be read.
 func (m *Manager) GetBalance(userID, asset string) *Balance {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if userAssets, exists := m.accounts[userID]; exists {
-		if balance, exists := userAssets[asset]; exists {
-			// Return copy to avoid external modification
-			return &Balance{
-				Available: balance.Available,
-				Locked:    balance.Locked,
-			}
-		}
+	balance, exists, err := m.store.Balance(userID, asset)
+	if err != nil || !exists {
+		return nil
 	}
-	return nil
+	return &balance
 }
 
+// GetAllBalances returns a copy of every balance of userID, by asset. A store
+// that cannot be read yields no balances.
 func (m *Manager) GetAllBalances(userID string) map[string]*Balance {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	result := make(map[string]*Balance)
 
-	if userAssets, exists := m.accounts[userID]; exists {
-		for asset, balance := range userAssets {
-			result[asset] = &Balance{
-				Available: balance.Available,
-				Locked:    balance.Locked,
-			}
+	balances, err := m.store.Balances(userID)
+	if err != nil {
+		return result
+	}
+	for asset, balance := range balances {
+		result[asset] = &Balance{
+			Available: balance.Available,
+			Locked:    balance.Locked,
 		}
 	}
 
 	return result
 }
 
-func (m *Manager) getOrCreateBalance(userID, asset string) *Balance {
-	if _, exists := m.accounts[userID]; !exists {
-		m.accounts[userID] = make(map[string]*Balance)
-	}
+// load returns userID's balance of asset, zero if none was saved yet. Nothing
+// is stored until save, so an operation that fails its checks leaves no empty
+// record behind. Must be called with m.mu held.
+func (m *Manager) load(userID, asset string) (Balance, error) {
+	balance, _, err := m.store.Balance(userID, asset)
+	return balance, err
+}
 
-	if _, exists := m.accounts[userID][asset]; !exists {
-		m.accounts[userID][asset] = &Balance{}
+// negativeTolerance is how far below zero a balance may land from float
+// error before an operation is refused. It only matters for assets without
+// a registered precision; rounding clears such noise for the others.
+const negativeTolerance = 1e-9
+
+// save rounds balance to asset's precision and writes it back. It refuses,
+// leaving the stored balance untouched, when either side would end up
+// negative: every operation checks its own preconditions, so this only
+// fires on a caller bug or a corrupted store. Must be called with m.mu held.
+func (m *Manager) save(userID, asset string, balance Balance) error {
+	m.roundBalance(asset, &balance)
+	if balance.Available < -negativeTolerance || balance.Locked < -negativeTolerance {
+		return ErrNegativeBalance
 	}
-
-	return m.accounts[userID][asset]
+	return m.store.SetBalance(userID, asset, balance)
 }
 
 func (m *Manager) validateInputs(userID, asset string, amount float64) error {
@@ -165,6 +277,9 @@ func (m *Manager) validateInputs(userID, asset string, amount float64) error {
 	if asset == "" {
 		return ErrInvalidAsset
 	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return ErrNonFiniteAmount
+	}
 	if amount <= 0 {
 		return ErrInvalidAmount
 	}
@@ -1,6 +1,8 @@
 package account
 
 import (
+	"fmt"
+	"math"
 	"testing"
 )
 
@@ -14,13 +16,13 @@ func TestNewManager(t *testing.T) {
 	if m == nil {
 		t.Fatal("NewManager returned nil")
 	}
-	if m.accounts == nil {
-		t.Error("accounts map should be initialized")
+	if m.store == nil {
+		t.Error("store should be initialized")
 	}
 }
 
 func TestManager_Credit(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	// Credit new account
 	err := m.Credit("1", "BTC", 10.0)
@@ -39,7 +41,7 @@ func TestManager_Credit(t *testing.T) {
 }
 
 func TestManager_Credit_InvalidInputs(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	err := m.Credit("", "BTC", 10.0)
 	assertError(t, ErrInvalidUserID, err)
@@ -52,10 +54,20 @@ func TestManager_Credit_InvalidInputs(t *testing.T) {
 
 	err = m.Credit("1", "BTC", -10)
 	assertError(t, ErrInvalidAmount, err)
+
+	err = m.Credit("1", "BTC", math.NaN())
+	assertError(t, ErrNonFiniteAmount, err)
+
+	err = m.Credit("1", "BTC", math.Inf(1))
+	assertError(t, ErrNonFiniteAmount, err)
+
+	if m.GetBalance("1", "BTC") != nil {
+		t.Error("Rejected credits should not create a balance")
+	}
 }
 
 func TestManager_Debit(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	// Setup
 	m.Credit("1", "BTC", 100.0)
@@ -69,7 +81,7 @@ func TestManager_Debit(t *testing.T) {
 }
 
 func TestManager_Debit_InsufficientBalance(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	m.Credit("1", "BTC", 50.0)
 
@@ -82,7 +94,7 @@ func TestManager_Debit_InsufficientBalance(t *testing.T) {
 }
 
 func TestManager_Debit_InvalidInputs(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	err := m.Debit("", "BTC", 10.0)
 	assertError(t, ErrInvalidUserID, err)
@@ -95,7 +107,7 @@ func TestManager_Debit_InvalidInputs(t *testing.T) {
 }
 
 func TestManager_Lock(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	m.Credit("1", "BRL", 100_000)
 
@@ -110,7 +122,7 @@ func TestManager_Lock(t *testing.T) {
 }
 
 func TestManager_Lock_InsufficientBalance(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	m.Credit("1", "BRL", 50_000)
 
@@ -124,7 +136,7 @@ func TestManager_Lock_InsufficientBalance(t *testing.T) {
 }
 
 func TestManager_Lock_InvalidInputs(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	err := m.Lock("", "BTC", 10.0)
 	assertError(t, ErrInvalidUserID, err)
@@ -137,7 +149,7 @@ func TestManager_Lock_InvalidInputs(t *testing.T) {
 }
 
 func TestManager_Unlock(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	m.Credit("1", "BRL", 100_000)
 	m.Lock("1", "BRL", 60_000)
@@ -152,7 +164,7 @@ func TestManager_Unlock(t *testing.T) {
 }
 
 func TestManager_Unlock_InsufficientLocked(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	m.Credit("1", "BRL", 100_000)
 	m.Lock("1", "BRL", 30_000)
@@ -162,7 +174,7 @@ func TestManager_Unlock_InsufficientLocked(t *testing.T) {
 }
 
 func TestManager_Unlock_InvalidInputs(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	err := m.Unlock("", "BTC", 10.0)
 	assertError(t, ErrInvalidUserID, err)
@@ -174,8 +186,32 @@ func TestManager_Unlock_InvalidInputs(t *testing.T) {
 	assertError(t, ErrInvalidAmount, err)
 }
 
+func TestManager_Relock(t *testing.T) {
+	m := newManager()
+
+	m.Credit("1", "BRL", 100_000)
+	m.Lock("1", "BRL", 60_000)
+
+	// Grow the lock beyond what is available on its own
+	err := m.Relock("1", "BRL", 60_000, 90_000)
+	assertNoError(t, err)
+
+	balance := m.GetBalance("1", "BRL")
+	assertFloat(t, 10_000, balance.Available, "Available after relock")
+	assertFloat(t, 90_000, balance.Locked, "Locked after relock")
+
+	err = m.Relock("1", "BRL", 90_000, 100_001)
+	assertError(t, ErrInsufficientBalance, err)
+
+	err = m.Relock("1", "BRL", 95_000, 10_000)
+	assertError(t, ErrInsufficientLocked, err)
+
+	balance = m.GetBalance("1", "BRL")
+	assertFloat(t, 90_000, balance.Locked, "Failed relocks change nothing")
+}
+
 func TestManager_DebitLocked(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	m.Credit("1", "BRL", 100_000)
 	m.Lock("1", "BRL", 50_000)
@@ -191,7 +227,7 @@ func TestManager_DebitLocked(t *testing.T) {
 }
 
 func TestManager_DebitLocked_InsufficientLocked(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	m.Credit("1", "BRL", 100_000)
 	m.Lock("1", "BRL", 30_000)
@@ -201,7 +237,7 @@ func TestManager_DebitLocked_InsufficientLocked(t *testing.T) {
 }
 
 func TestManager_DebitLocked_InvalidInputs(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	err := m.DebitLocked("", "BTC", 10.0)
 	assertError(t, ErrInvalidUserID, err)
@@ -213,8 +249,63 @@ func TestManager_DebitLocked_InvalidInputs(t *testing.T) {
 	assertError(t, ErrInvalidAmount, err)
 }
 
+func TestManager_NegativeBalanceRejected(t *testing.T) {
+	// A corrupted store: the checks of each operation alone would let these
+	// balances go (or stay) negative.
+	store := NewMemoryStore()
+	_ = store.SetBalance("1", "BRL", Balance{Available: -100, Locked: 50})
+	_ = store.SetBalance("2", "BRL", Balance{Available: 100, Locked: -1})
+	m := NewManagerWithStore(store)
+
+	err := m.DebitLocked("1", "BRL", 10)
+	assertError(t, ErrNegativeBalance, err)
+	err = m.Credit("1", "BRL", 20)
+	assertError(t, ErrNegativeBalance, err)
+	err = m.Lock("2", "BRL", 0.5)
+	assertError(t, ErrNegativeBalance, err)
+
+	// Nothing was applied
+	balance := m.GetBalance("1", "BRL")
+	assertFloat(t, -100, balance.Available, "Available unchanged")
+	assertFloat(t, 50, balance.Locked, "Locked unchanged")
+
+	// An operation that brings the balance back above zero goes through
+	err = m.Credit("1", "BRL", 200)
+	assertNoError(t, err)
+	assertFloat(t, 100, m.GetBalance("1", "BRL").Available, "Available repaired")
+}
+
+func TestManager_NegativeBalance_FloatNoiseTolerated(t *testing.T) {
+	store := NewMemoryStore()
+	_ = store.SetBalance("1", "XYZ", Balance{Available: 1, Locked: -1e-12})
+	m := NewManagerWithStore(store)
+
+	// XYZ has no precision, so nothing rounds the noise away
+	err := m.Credit("1", "XYZ", 1)
+	assertNoError(t, err)
+}
+
+func TestManager_FailedOperationsCreateNoBalance(t *testing.T) {
+	m := newManager()
+	m.Credit("1", "BRL", 100)
+
+	// None of these can succeed on an asset the user never held
+	assertError(t, ErrInsufficientBalance, m.Lock("1", "BTC", 1))
+	assertError(t, ErrInsufficientBalance, m.Debit("1", "BTC", 1))
+	assertError(t, ErrInsufficientLocked, m.Unlock("1", "BTC", 1))
+	assertError(t, ErrInsufficientLocked, m.DebitLocked("1", "BTC", 1))
+
+	balances := m.GetAllBalances("1")
+	if len(balances) != 1 {
+		t.Errorf("expected only the BRL balance, got %v", balances)
+	}
+	if m.GetBalance("1", "BTC") != nil {
+		t.Error("expected no BTC balance after failed operations")
+	}
+}
+
 func TestManager_GetAllBalances(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	m.Credit("1", "BTC", 10.0)
 	m.Credit("1", "BRL", 100_000)
@@ -232,7 +323,7 @@ func TestManager_GetAllBalances(t *testing.T) {
 }
 
 func TestManager_FullOrder_Buy(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	// UserID:1 wants to buy 1 BTC @ 50000 BRL
 	// 1. Credit BRL
@@ -263,7 +354,7 @@ func TestManager_FullOrder_Buy(t *testing.T) {
 }
 
 func TestManager_FullOrder_Sell(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	// UserId:2 wants to sell 1 BTC @ 50000 BRL
 	// 1. Credit BTC
@@ -294,7 +385,7 @@ func TestManager_FullOrder_Sell(t *testing.T) {
 }
 
 func TestManager_FullOrder_Cancel(t *testing.T) {
-	m := NewManager()
+	m := newManager()
 
 	// OrderID:3 creates order then cancels
 	m.Credit("3", "BRL", 100_000)
@@ -308,3 +399,202 @@ func TestManager_FullOrder_Cancel(t *testing.T) {
 	assertFloat(t, 100_000, balance.Available, "Available after cancel")
 	assertFloat(t, 0.0, balance.Locked, "Locked after cancel")
 }
+
+func TestManager_CreditBatch(t *testing.T) {
+	m := newManager()
+
+	results := m.CreditBatch([]CreditEntry{
+		{UserID: "1", Asset: "BTC", Amount: 2},
+		{UserID: "", Asset: "BTC", Amount: 1},
+		{UserID: "2", Asset: "BRL", Amount: 1_000},
+		{UserID: "1", Asset: "", Amount: 1},
+		{UserID: "1", Asset: "BTC", Amount: -5},
+		{UserID: "1", Asset: "BTC", Amount: 0.5},
+	})
+
+	if len(results) != 6 {
+		t.Fatalf("expected 6 results, got %d", len(results))
+	}
+	assertNoError(t, results[0])
+	assertError(t, ErrInvalidUserID, results[1])
+	assertNoError(t, results[2])
+	assertError(t, ErrInvalidAsset, results[3])
+	assertError(t, ErrInvalidAmount, results[4])
+	assertNoError(t, results[5])
+
+	assertFloat(t, 2.5, m.GetBalance("1", "BTC").Available, "User 1 BTC")
+	assertFloat(t, 1_000, m.GetBalance("2", "BRL").Available, "User 2 BRL")
+	if m.GetBalance("1", "") != nil {
+		t.Error("Invalid entry should not create a balance")
+	}
+}
+
+func TestManager_CreditDeposit(t *testing.T) {
+	m := newManager()
+
+	first, replayed, err := m.CreditDeposit("1", "dep-1", "BRL", 100)
+	assertNoError(t, err)
+	if replayed {
+		t.Error("First credit should not be a replay")
+	}
+	assertFloat(t, 100, first.Balances["BRL"].Available, "Snapshot after the credit")
+
+	prior, replayed, err := m.CreditDeposit("1", "dep-1", "BRL", 100)
+	assertNoError(t, err)
+	if !replayed {
+		t.Error("Repeated deposit ID should be a replay")
+	}
+	assertFloat(t, 100, prior.Balances["BRL"].Available, "Prior result returned")
+	assertFloat(t, 100, m.GetBalance("1", "BRL").Available, "Credited once")
+
+	_, _, err = m.CreditDeposit("1", "dep-1", "BRL", 200)
+	assertError(t, ErrDepositConflict, err)
+
+	_, replayed, err = m.CreditDeposit("1", "dep-2", "BRL", 100)
+	assertNoError(t, err)
+	if replayed {
+		t.Error("New deposit ID should credit")
+	}
+	assertFloat(t, 200, m.GetBalance("1", "BRL").Available, "New deposit credited")
+
+	// Deposit IDs are per user
+	_, replayed, err = m.CreditDeposit("2", "dep-1", "BRL", 100)
+	assertNoError(t, err)
+	if replayed {
+		t.Error("Another user's deposit ID should credit")
+	}
+
+	// The oldest IDs are forgotten past the bound
+	for i := 0; i < MaxDepositsPerUser; i++ {
+		_, _, err := m.CreditDeposit("3", fmt.Sprintf("dep-%d", i), "BTC", 0.001)
+		assertNoError(t, err)
+	}
+	_, replayed, _ = m.CreditDeposit("3", fmt.Sprintf("dep-%d", MaxDepositsPerUser-1), "BTC", 0.001)
+	if !replayed {
+		t.Error("Recent deposit ID should still be remembered")
+	}
+	_, _, _ = m.CreditDeposit("3", "dep-extra", "BTC", 0.001)
+	_, replayed, _ = m.CreditDeposit("3", "dep-0", "BTC", 0.001)
+	if replayed {
+		t.Error("Oldest deposit ID should have been forgotten")
+	}
+}
+
+func TestManager_CreditBatch_DepositIDs(t *testing.T) {
+	m := newManager()
+
+	results := m.CreditBatch([]CreditEntry{
+		{UserID: "1", Asset: "BTC", Amount: 1, DepositID: "dep-1"},
+		{UserID: "1", Asset: "BTC", Amount: 1, DepositID: "dep-1"},
+		{UserID: "1", Asset: "BTC", Amount: 2, DepositID: "dep-1"},
+		{UserID: "1", Asset: "BTC", Amount: 1},
+	})
+	assertNoError(t, results[0])
+	assertNoError(t, results[1])
+	assertError(t, ErrDepositConflict, results[2])
+	assertNoError(t, results[3])
+
+	assertFloat(t, 2, m.GetBalance("1", "BTC").Available, "Repeated deposit skipped")
+}
+
+func TestManager_AssetTotals(t *testing.T) {
+	m := newManager()
+
+	assertNoError(t, m.Credit("1", "BRL", 1_000))
+	assertNoError(t, m.Credit("2", "BRL", 500.5))
+	assertNoError(t, m.Credit("2", "BTC", 1.5))
+	assertNoError(t, m.Credit("3", "BTC", 0.25))
+	assertNoError(t, m.Lock("1", "BRL", 300))
+	assertNoError(t, m.Lock("3", "BTC", 0.25))
+
+	// A user whose balance went back to zero is not counted
+	assertNoError(t, m.Credit("4", "BTC", 1))
+	assertNoError(t, m.Debit("4", "BTC", 1))
+
+	totals, err := m.AssetTotals()
+	assertNoError(t, err)
+	if len(totals) != 2 || totals[0].Asset != "BRL" || totals[1].Asset != "BTC" {
+		t.Fatalf("Expected BRL and BTC totals, got %+v", totals)
+	}
+
+	brl, btc := totals[0], totals[1]
+	assertFloat(t, 1_200.5, brl.Available, "BRL available")
+	assertFloat(t, 300, brl.Locked, "BRL locked")
+	assertFloat(t, 1_500.5, brl.Total(), "BRL total")
+	assertFloat(t, 1.5, btc.Available, "BTC available")
+	assertFloat(t, 0.25, btc.Locked, "BTC locked")
+	if brl.Users != 2 || btc.Users != 2 {
+		t.Errorf("Expected 2 holders of each asset, got BRL %d and BTC %d", brl.Users, btc.Users)
+	}
+
+	// Moving funds between users and between available and locked
+	// leaves the totals unchanged
+	assertNoError(t, m.DebitLocked("1", "BRL", 300))
+	assertNoError(t, m.Credit("3", "BRL", 300))
+	totals, err = m.AssetTotals()
+	assertNoError(t, err)
+	assertFloat(t, 1_500.5, totals[0].Total(), "BRL total conserved")
+	assertFloat(t, 0, totals[0].Locked, "BRL no longer locked")
+}
+
+func TestManager_Precision_BRLRoundsToCents(t *testing.T) {
+	m := newManager()
+
+	assertNoError(t, m.Credit("1", "BRL", 10.456))
+	assertFloat(t, 10.46, m.GetBalance("1", "BRL").Available, "Credit rounded to cents")
+
+	assertNoError(t, m.Credit("1", "BRL", 0.1))
+	assertNoError(t, m.Credit("1", "BRL", 0.2))
+	assertFloat(t, 10.76, m.GetBalance("1", "BRL").Available, "No float drift across credits")
+
+	assertNoError(t, m.Debit("1", "BRL", 0.764))
+	assertFloat(t, 10, m.GetBalance("1", "BRL").Available, "Debit rounded to cents")
+}
+
+func TestManager_Precision_BTCRoundsToEightDecimals(t *testing.T) {
+	m := newManager()
+
+	assertNoError(t, m.Credit("1", "BTC", 0.123456789))
+	assertFloat(t, 0.12345679, m.GetBalance("1", "BTC").Available, "Credit rounded to 8 decimals")
+}
+
+func TestManager_Precision_LockMatchesDebitLocked(t *testing.T) {
+	m := newManager()
+	m.Credit("1", "BRL", 100)
+
+	// The engine locks and later debits price*amount, which rarely lands
+	// on a whole cent. Both sides must round the same way.
+	assertNoError(t, m.Lock("1", "BRL", 33.333))
+	balance := m.GetBalance("1", "BRL")
+	assertFloat(t, 66.67, balance.Available, "Available after lock")
+	assertFloat(t, 33.33, balance.Locked, "Locked after lock")
+
+	assertNoError(t, m.DebitLocked("1", "BRL", 33.333))
+	balance = m.GetBalance("1", "BRL")
+	assertFloat(t, 0, balance.Locked, "Locked fully debited")
+
+	assertNoError(t, m.Lock("1", "BRL", 10.005))
+	assertNoError(t, m.Unlock("1", "BRL", 10.005))
+	assertFloat(t, 66.67, m.GetBalance("1", "BRL").Available, "Unlock returns what lock took")
+}
+
+func TestManager_SetPrecision(t *testing.T) {
+	m := newManager()
+
+	// Unregistered assets are not rounded
+	assertNoError(t, m.Credit("1", "DOGE", 1.123456789))
+	assertFloat(t, 1.123456789, m.GetBalance("1", "DOGE").Available, "Unregistered asset")
+
+	assertNoError(t, m.SetPrecision("DOGE", 4))
+	assertNoError(t, m.Credit("2", "DOGE", 1.123456789))
+	assertFloat(t, 1.1235, m.GetBalance("2", "DOGE").Available, "Registered asset")
+
+	decimals, ok := m.Precision("DOGE")
+	if !ok || decimals != 4 {
+		t.Errorf("Precision: expected 4, got %d (registered: %t)", decimals, ok)
+	}
+
+	assertError(t, ErrInvalidPrecision, m.SetPrecision("DOGE", -1))
+	assertError(t, ErrInvalidPrecision, m.SetPrecision("DOGE", MaxPrecision+1))
+	assertError(t, ErrInvalidAsset, m.SetPrecision("", 2))
+}
@@ -0,0 +1,61 @@
+package account
+
+import "math"
+
+// MaxPrecision is the largest number of decimals an asset can be registered
+// with. Beyond this a float64 can no longer hold typical balances exactly.
+const MaxPrecision = 8
+
+// DefaultPrecisions are the decimals registered on every new manager: fiat
+// settles in cents, crypto in satoshi-sized units.
+var DefaultPrecisions = map[string]int{
+	"BRL":  2,
+	"USDT": 6,
+	"BTC":  8,
+	"ETH":  8,
+}
+
+// SetPrecision registers the number of decimals amounts of asset are rounded
+// to. Assets without a registered precision are left unrounded. Amounts
+// smaller than half the asset's smallest unit round to zero and move nothing.
+func (m *Manager) SetPrecision(asset string, decimals int) error {
+	if asset == "" {
+		return ErrInvalidAsset
+	}
+	if decimals < 0 || decimals > MaxPrecision {
+		return ErrInvalidPrecision
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.precisions[asset] = decimals
+	return nil
+}
+
+// Precision returns the registered decimals of asset, if any.
+func (m *Manager) Precision(asset string) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	decimals, ok := m.precisions[asset]
+	return decimals, ok
+}
+
+// round rounds amount to asset's precision. Must be called with m.mu held.
+func (m *Manager) round(asset string, amount float64) float64 {
+	decimals, ok := m.precisions[asset]
+	if !ok {
+		return amount
+	}
+	scale := math.Pow10(decimals)
+	return math.Round(amount*scale) / scale
+}
+
+// roundBalance rounds both sides of balance to asset's precision so repeated
+// arithmetic never leaves float noise below the smallest unit. Must be called
+// with m.mu held.
+func (m *Manager) roundBalance(asset string, balance *Balance) {
+	balance.Available = m.round(asset, balance.Available)
+	balance.Locked = m.round(asset, balance.Locked)
+}
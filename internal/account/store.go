@@ -0,0 +1,57 @@
+package account
+
+// Store holds the balances a Manager works on. The in-memory MemoryStore is
+// the default; a persistent implementation can be passed to
+// NewManagerWithStore without changing any balance rule. Manager serialises
+// every call under its own lock, so implementations need not be safe for
+// concurrent use.
+type Store interface {
+	// Balance returns userID's balance of asset and whether one was saved.
+	Balance(userID, asset string) (Balance, bool, error)
+	// SetBalance saves userID's balance of asset.
+	SetBalance(userID, asset string, balance Balance) error
+	// Balances returns every saved balance of userID, by asset.
+	Balances(userID string) (map[string]Balance, error)
+	// Users returns the ID of every user with a saved balance, in any order.
+	Users() ([]string, error)
+}
+
+// MemoryStore is a Store backed by plain maps.
+type MemoryStore struct {
+	accounts map[string]map[string]Balance // user ID -> asset -> balance
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{accounts: make(map[string]map[string]Balance)}
+}
+
+func (s *MemoryStore) Balance(userID, asset string) (Balance, bool, error) {
+	balance, ok := s.accounts[userID][asset]
+	return balance, ok, nil
+}
+
+func (s *MemoryStore) SetBalance(userID, asset string, balance Balance) error {
+	userAssets, exists := s.accounts[userID]
+	if !exists {
+		userAssets = make(map[string]Balance)
+		s.accounts[userID] = userAssets
+	}
+	userAssets[asset] = balance
+	return nil
+}
+
+func (s *MemoryStore) Balances(userID string) (map[string]Balance, error) {
+	result := make(map[string]Balance, len(s.accounts[userID]))
+	for asset, balance := range s.accounts[userID] {
+		result[asset] = balance
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) Users() ([]string, error) {
+	result := make([]string, 0, len(s.accounts))
+	for userID := range s.accounts {
+		result = append(result, userID)
+	}
+	return result, nil
+}
@@ -0,0 +1,147 @@
+package account
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// keyedStore is a second Store, keyed by "user/asset", to run the manager
+// suite against something other than MemoryStore.
+type keyedStore struct {
+	balances map[string]Balance
+}
+
+func newKeyedStore() *keyedStore {
+	return &keyedStore{balances: make(map[string]Balance)}
+}
+
+func (s *keyedStore) Balance(userID, asset string) (Balance, bool, error) {
+	balance, ok := s.balances[userID+"/"+asset]
+	return balance, ok, nil
+}
+
+func (s *keyedStore) SetBalance(userID, asset string, balance Balance) error {
+	s.balances[userID+"/"+asset] = balance
+	return nil
+}
+
+func (s *keyedStore) Balances(userID string) (map[string]Balance, error) {
+	result := make(map[string]Balance)
+	for key, balance := range s.balances {
+		if asset, ok := strings.CutPrefix(key, userID+"/"); ok {
+			result[asset] = balance
+		}
+	}
+	return result, nil
+}
+
+func (s *keyedStore) Users() ([]string, error) {
+	seen := make(map[string]bool)
+	var result []string
+	for key := range s.balances {
+		userID, _, _ := strings.Cut(key, "/")
+		if !seen[userID] {
+			seen[userID] = true
+			result = append(result, userID)
+		}
+	}
+	return result, nil
+}
+
+var errStoreDown = errors.New("store down")
+
+// failingStore rejects every write.
+type failingStore struct {
+	*MemoryStore
+}
+
+func (s failingStore) SetBalance(string, string, Balance) error {
+	return errStoreDown
+}
+
+func TestManager_SuiteOnKeyedStore(t *testing.T) {
+	newManager = func() *Manager { return NewManagerWithStore(newKeyedStore()) }
+	defer func() { newManager = NewManager }()
+
+	suite := []struct {
+		name string
+		test func(*testing.T)
+	}{
+		{"Credit", TestManager_Credit},
+		{"Credit_InvalidInputs", TestManager_Credit_InvalidInputs},
+		{"Debit", TestManager_Debit},
+		{"Debit_InsufficientBalance", TestManager_Debit_InsufficientBalance},
+		{"Debit_InvalidInputs", TestManager_Debit_InvalidInputs},
+		{"Lock", TestManager_Lock},
+		{"Lock_InsufficientBalance", TestManager_Lock_InsufficientBalance},
+		{"Lock_InvalidInputs", TestManager_Lock_InvalidInputs},
+		{"Unlock", TestManager_Unlock},
+		{"Unlock_InsufficientLocked", TestManager_Unlock_InsufficientLocked},
+		{"Unlock_InvalidInputs", TestManager_Unlock_InvalidInputs},
+		{"Relock", TestManager_Relock},
+		{"DebitLocked", TestManager_DebitLocked},
+		{"DebitLocked_InsufficientLocked", TestManager_DebitLocked_InsufficientLocked},
+		{"DebitLocked_InvalidInputs", TestManager_DebitLocked_InvalidInputs},
+		{"FailedOperationsCreateNoBalance", TestManager_FailedOperationsCreateNoBalance},
+		{"GetAllBalances", TestManager_GetAllBalances},
+		{"FullOrder_Buy", TestManager_FullOrder_Buy},
+		{"FullOrder_Sell", TestManager_FullOrder_Sell},
+		{"FullOrder_Cancel", TestManager_FullOrder_Cancel},
+		{"CreditBatch", TestManager_CreditBatch},
+		{"AssetTotals", TestManager_AssetTotals},
+		{"Precision_BRLRoundsToCents", TestManager_Precision_BRLRoundsToCents},
+		{"Precision_BTCRoundsToEightDecimals", TestManager_Precision_BTCRoundsToEightDecimals},
+		{"Precision_LockMatchesDebitLocked", TestManager_Precision_LockMatchesDebitLocked},
+		{"SetPrecision", TestManager_SetPrecision},
+	}
+	for _, tc := range suite {
+		t.Run(tc.name, tc.test)
+	}
+}
+
+func TestManager_StoreWriteError(t *testing.T) {
+	store := NewMemoryStore()
+	assertNoError(t, store.SetBalance("1", "BRL", Balance{Available: 100}))
+	m := NewManagerWithStore(failingStore{store})
+
+	assertError(t, errStoreDown, m.Credit("1", "BRL", 50))
+	assertError(t, errStoreDown, m.Lock("1", "BRL", 50))
+
+	// Nothing was applied
+	balance := m.GetBalance("1", "BRL")
+	assertFloat(t, 100, balance.Available, "Available")
+	assertFloat(t, 0, balance.Locked, "Locked")
+
+	errs := m.CreditBatch([]CreditEntry{{UserID: "1", Asset: "BRL", Amount: 1}})
+	assertError(t, errStoreDown, errs[0])
+}
+
+func TestMemoryStore_ReturnsCopies(t *testing.T) {
+	store := NewMemoryStore()
+	assertNoError(t, store.SetBalance("1", "BTC", Balance{Available: 1}))
+
+	balances, err := store.Balances("1")
+	assertNoError(t, err)
+	balances["BTC"] = Balance{Available: 99}
+
+	balance, ok, err := store.Balance("1", "BTC")
+	assertNoError(t, err)
+	if !ok {
+		t.Fatal("balance should exist")
+	}
+	assertFloat(t, 1, balance.Available, "Stored balance unchanged")
+}
+
+func BenchmarkManager_LockDebitLocked(b *testing.B) {
+	m := NewManager()
+	_ = m.Credit("1", "BRL", 1e12)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = m.Lock("1", "BRL", 100)
+		_ = m.DebitLocked("1", "BRL", 60)
+		_ = m.Unlock("1", "BRL", 40)
+		_ = m.Credit("2", "BRL", 60)
+	}
+}
@@ -29,3 +29,7 @@ func assertNil(t *testing.T, actual interface{}, msg string) {
 		t.Errorf("%s: expected nil, got %v", msg, actual)
 	}
 }
+
+// newManager builds the manager each TestManager_* test runs against, so the
+// suite can be repeated on other Store implementations.
+var newManager = NewManager
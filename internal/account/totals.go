@@ -0,0 +1,63 @@
+package account
+
+import "sort"
+
+// AssetTotal is the sum of one asset's balances over every user.
+type AssetTotal struct {
+	Asset     string
+	Available float64
+	Locked    float64
+	Users     int // users holding a non-zero balance of the asset
+}
+
+// Total returns the available plus locked sum.
+func (t AssetTotal) Total() float64 {
+	return t.Available + t.Locked
+}
+
+// AssetTotals sums the balances of every user by asset, sorted by asset. It
+// reads all accounts under one read lock, so no single balance change shows
+// up half-applied. A trade is several such changes, though: only a caller
+// that keeps trades out meanwhile, as Engine.AssetTotals does, gets totals
+// that stay the same as value moves between users and between available and
+// locked.
+func (m *Manager) AssetTotals() ([]AssetTotal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users, err := m.store.Users()
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]*AssetTotal)
+	for _, userID := range users {
+		balances, err := m.store.Balances(userID)
+		if err != nil {
+			return nil, err
+		}
+		for asset, balance := range balances {
+			total, exists := totals[asset]
+			if !exists {
+				total = &AssetTotal{Asset: asset}
+				totals[asset] = total
+			}
+			total.Available += balance.Available
+			total.Locked += balance.Locked
+			if balance.Total() != 0 {
+				total.Users++
+			}
+		}
+	}
+
+	result := make([]AssetTotal, 0, len(totals))
+	for asset, total := range totals {
+		total.Available = m.round(asset, total.Available)
+		total.Locked = m.round(asset, total.Locked)
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Asset < result[j].Asset
+	})
+	return result, nil
+}
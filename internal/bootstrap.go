@@ -0,0 +1,42 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/moura95/crypto-exchange-challenge/config"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/pkg/logger"
+)
+
+// applyBootstrap lists b's pairs and credits its balances on eng. It stops at
+// the first entry the engine rejects, so a bad file keeps the server from
+// starting instead of running with part of its state.
+func applyBootstrap(eng *engine.Engine, b *config.Bootstrap) error {
+	for _, p := range b.Pairs {
+		base, quote, _ := strings.Cut(strings.ToUpper(p.Pair), "/")
+		err := eng.RegisterPair(engine.PairConfig{
+			Pair:         engine.Pair{Base: base, Quote: quote},
+			PriceTick:    p.PriceTick,
+			AmountTick:   p.AmountTick,
+			MinOrderSize: p.MinOrderSize,
+			MinNotional:  p.MinNotional,
+			LotSize:      p.LotSize,
+			MaxMarketGap: p.MaxMarketGap,
+			Mode:         engine.PairMode(p.Mode),
+		})
+		if err != nil {
+			return fmt.Errorf("pair %s: %w", p.Pair, err)
+		}
+	}
+
+	accounts := eng.GetAccountManager()
+	for _, bal := range b.Balances {
+		if err := accounts.Credit(bal.UserID, strings.ToUpper(bal.Asset), bal.Amount); err != nil {
+			return fmt.Errorf("balance %s %s: %w", bal.UserID, bal.Asset, err)
+		}
+	}
+
+	logger.Infof("Bootstrap applied - Pairs: %d - Balances: %d", len(b.Pairs), len(b.Balances))
+	return nil
+}
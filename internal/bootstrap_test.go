@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/config"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+)
+
+func TestApplyBootstrap_SampleFile(t *testing.T) {
+	b, err := config.LoadBootstrap("../bootstrap.example.json")
+	if err != nil {
+		t.Fatalf("load sample: %v", err)
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.SkipDefaultPairs = true
+	eng := engine.NewEngineWithConfig(cfg)
+	if err := applyBootstrap(eng, b); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	pairs := eng.ListPairs()
+	if len(pairs) != 2 {
+		t.Fatalf("expected 2 pairs, got %d", len(pairs))
+	}
+	btc := pairs[0]
+	if btc.Pair.String() != "BTC/BRL" || btc.MinOrderSize != 0.0001 || btc.MinNotional != 10 {
+		t.Errorf("unexpected BTC/BRL config: %+v", btc)
+	}
+	if pairs[1].Pair.String() != "ETH/BRL" {
+		t.Errorf("expected ETH/BRL, got %s", pairs[1].Pair)
+	}
+
+	accounts := eng.GetAccountManager()
+	for _, want := range []struct {
+		user, asset string
+		amount      float64
+	}{
+		{"1", "BRL", 100_000},
+		{"1", "BTC", 10},
+		{"2", "BRL", 100_000},
+		{"2", "ETH", 50},
+	} {
+		if got := accounts.GetBalance(want.user, want.asset).Available; got != want.amount {
+			t.Errorf("user %s %s: expected %v, got %v", want.user, want.asset, want.amount, got)
+		}
+	}
+}
+
+func TestApplyBootstrap_RejectedPairFailsFast(t *testing.T) {
+	b := &config.Bootstrap{
+		Pairs: []config.BootstrapPair{
+			{Pair: "BTC/USD", PriceTick: 0.01, AmountTick: 0.00000001},
+		},
+		Balances: []config.BootstrapBalance{{UserID: "1", Asset: "BRL", Amount: 100}},
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.SkipDefaultPairs = true
+	eng := engine.NewEngineWithConfig(cfg)
+
+	err := applyBootstrap(eng, b)
+	if !errors.Is(err, engine.ErrInvalidPair) {
+		t.Fatalf("expected ErrInvalidPair, got %v", err)
+	}
+	if balance := eng.GetAccountManager().GetBalance("1", "BRL"); balance != nil && balance.Available != 0 {
+		t.Errorf("balances must not be applied after a failure, got %v", balance.Available)
+	}
+}
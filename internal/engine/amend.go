@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
+)
+
+// AmendOrder reduces the amount of userID's resting order orderID to amount
+// and unlocks what the smaller remainder no longer needs. The price does not
+// change and the order keeps its queue position; raising the amount or moving
+// the price takes a cancel and a new order, which queues behind the level.
+// The pair's minimum size and notional apply to what is left to fill once
+// the amend shrinks it, not to the amount including the fills.
+func (e *Engine) AmendOrder(userID string, pair Pair, orderID int64, amount float64) (*orderbook.Order, error) {
+	return e.AmendOrderWithNonce(userID, pair, orderID, amount, 0)
+}
+
+// AmendOrderWithNonce is AmendOrder for a request carrying nonce, which must
+// be above the last one userID used; 0 means none. See CheckNonce.
+func (e *Engine) AmendOrderWithNonce(userID string, pair Pair, orderID int64, amount float64, nonce uint64) (*orderbook.Order, error) {
+	if !pair.IsValid() {
+		return nil, ErrInvalidPair
+	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return nil, orderbook.ErrNonFinite
+	}
+	if amount <= 0 {
+		return nil, orderbook.ErrInvalidAmount
+	}
+
+	cfg := e.pairConfig(pair)
+	amount, ok := e.normalizeToTick(amount, cfg.AmountTick)
+	if !ok {
+		return nil, ErrInvalidAmountTick
+	}
+	if !cfg.lotAligned(amount) {
+		return nil, ErrInvalidAmountLot
+	}
+	if err := e.CheckNonce(userID, nonce); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ob, exists := e.orderbooks[pair.String()]
+	if !exists {
+		return nil, ErrOrderNotFound
+	}
+	order, exists := ob.GetOrder(orderID)
+	if !exists {
+		return nil, ErrOrderNotFound
+	}
+	if order.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if remaining := utils.RoundToTick(amount-order.FilledAmount, cfg.AmountTick); remaining > 0 {
+		if remaining < cfg.MinOrderSize {
+			return nil, ErrBelowMinOrderSize
+		}
+		if order.Price*remaining < cfg.MinNotional {
+			return nil, ErrBelowMinNotional
+		}
+	}
+
+	before := order.RemainingAmount()
+	if _, err := ob.ReduceOrder(orderID, amount); err != nil {
+		return nil, err
+	}
+
+	var unlockAsset string
+	var unlockAmount float64
+	if order.Side == orderbook.Bid {
+		unlockAsset = pair.Quote
+		unlockAmount = e.roundAsset(pair.Quote,
+			e.bidReserve(pair, order.Price, before)-e.bidReserve(pair, order.Price, order.RemainingAmount()))
+	} else {
+		unlockAsset = pair.Base
+		unlockAmount = e.roundAsset(pair.Base, before-order.RemainingAmount())
+	}
+	if unlockAmount > 0 {
+		if err := e.accounts.Unlock(userID, unlockAsset, unlockAmount); err != nil {
+			return nil, fmt.Errorf("unlock failed: %w", err)
+		}
+	}
+
+	snapshot := *order
+	snapshot.Limit = nil
+	return &snapshot, nil
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_AmendOrder_KeepsQueuePosition(t *testing.T) {
+	e := setupEngine()
+
+	first, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	second, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	assertFloat(t, 1, e.accounts.GetBalance("2", "BTC").Locked, "Locked before amend")
+
+	amended, err := e.AmendOrder("2", btcBrl(), first.ID, 0.2)
+	assertNoError(t, err)
+	assertFloat(t, 0.2, amended.Amount, "New amount")
+
+	limit := first.Limit
+	assertEqual(t, first.ID, limit.Orders[0].ID, "Amended order still first in line")
+	assertEqual(t, second.ID, limit.Orders[1].ID, "Second order still behind")
+	assertFloat(t, 0.7, limit.TotalVolume, "Level volume reduced")
+	assertFloat(t, 0.7, e.accounts.GetBalance("2", "BTC").Locked, "Freed base unlocked")
+
+	// The amended order is still matched first
+	_, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.2)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "One match")
+	assertEqual(t, first.ID, matches[0].Ask.ID, "Amended order kept priority")
+	assertEqual(t, orderbook.OrderFilled, first.State, "Amended order filled")
+}
+
+func TestEngine_AmendOrder_BidAfterPartialFill(t *testing.T) {
+	e := setupEngine()
+
+	bid, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.3)
+	assertNoError(t, err)
+	assertFloat(t, 35_000, e.accounts.GetBalance("1", "BRL").Locked, "Locked for 0.7 remaining")
+
+	_, err = e.AmendOrder("1", btcBrl(), bid.ID, 0.3)
+	assertEqual(t, orderbook.ErrAmendBelowFilled, err, "Amount equal to filled")
+	_, err = e.AmendOrder("1", btcBrl(), bid.ID, 0.2)
+	assertEqual(t, orderbook.ErrAmendBelowFilled, err, "Amount below filled")
+	_, err = e.AmendOrder("1", btcBrl(), bid.ID, 2)
+	assertEqual(t, orderbook.ErrAmendIncrease, err, "Amount increase")
+	_, err = e.AmendOrder("2", btcBrl(), bid.ID, 0.5)
+	assertEqual(t, ErrUnauthorized, err, "Another user's order")
+	assertFloat(t, 35_000, e.accounts.GetBalance("1", "BRL").Locked, "Rejected amends change nothing")
+
+	amended, err := e.AmendOrder("1", btcBrl(), bid.ID, 0.5)
+	assertNoError(t, err)
+	assertFloat(t, 0.2, amended.RemainingAmount(), "Remaining after amend")
+	assertFalse(t, amended.IsFilled(), "Still open")
+
+	buyerBRL := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 10_000, buyerBRL.Locked, "Locked for 0.2 remaining")
+	assertFloat(t, 75_000, buyerBRL.Available, "Freed quote unlocked")
+
+	// Cancelling releases exactly what is left
+	_, err = e.CancelOrder("1", btcBrl(), bid.ID)
+	assertNoError(t, err)
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BRL").Locked, "Nothing stranded")
+}
+
+func TestEngine_AmendOrder_StaleNonce(t *testing.T) {
+	e := setupEngine()
+
+	order, _, err := e.PlaceOrderWithOptions("2", btcBrl(), orderbook.Ask, 50_000, 0.5, OrderOptions{Nonce: 4})
+	assertNoError(t, err)
+
+	_, err = e.AmendOrderWithNonce("2", btcBrl(), order.ID, 0.2, 4)
+	assertEqual(t, ErrStaleNonce, err, "Replayed nonce")
+	assertFloat(t, 0.5, order.Amount, "Amount unchanged")
+
+	_, err = e.AmendOrderWithNonce("2", btcBrl(), order.ID, 0.2, 5)
+	assertNoError(t, err)
+	assertFloat(t, 0.2, order.Amount, "Amended")
+}
+
+func TestEngine_AmendOrder_MinimumsApplyToRemaining(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "SOL", Quote: "BRL"}
+	assertNoError(t, e.RegisterPair(PairConfig{Pair: pair, PriceTick: 0.01, AmountTick: 0.0001, MinOrderSize: 0.1, MinNotional: 100}))
+	_ = e.accounts.Credit("2", "SOL", 10)
+
+	bid, _, err := e.PlaceOrder("1", pair, orderbook.Bid, 1_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", pair, orderbook.Ask, 1_000, 0.5)
+	assertNoError(t, err)
+
+	// 0.55 clears both minimums in total, but only 0.05 would be left to fill
+	_, err = e.AmendOrder("1", pair, bid.ID, 0.55)
+	assertEqual(t, ErrBelowMinOrderSize, err, "Remaining below the minimum size")
+
+	amended, err := e.AmendOrder("1", pair, bid.ID, 0.6)
+	assertNoError(t, err)
+	assertFloat(t, 0.6, amended.Amount, "Leaves exactly the minimum to fill")
+}
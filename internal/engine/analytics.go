@@ -0,0 +1,265 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
+)
+
+// RoundTripCost returns the quote cost of buying amount at market and
+// immediately selling it back at market: the effective spread plus slippage.
+// It is read-only and does not touch balances or the book.
+func (e *Engine) RoundTripCost(pair Pair, amount float64) (float64, error) {
+	if !pair.IsValid() {
+		return 0, ErrInvalidPair
+	}
+	if amount <= 0 {
+		return 0, orderbook.ErrInvalidAmount
+	}
+
+	cfg := e.pairConfig(pair)
+	ob := e.GetOrderbook(pair)
+	if ob == nil {
+		return 0, ErrInsufficientLiquidity
+	}
+
+	buy := ob.EstimateFill(orderbook.Bid, amount)
+	sell := ob.EstimateFill(orderbook.Ask, amount)
+
+	if !isFullyFilled(buy, amount, cfg.AmountTick) || !isFullyFilled(sell, amount, cfg.AmountTick) {
+		return 0, ErrInsufficientLiquidity
+	}
+
+	return utils.RoundToTick(buy.QuoteAmount-sell.QuoteAmount, cfg.PriceTick), nil
+}
+
+// isFullyFilled reports whether est covers amount to within half an amount tick.
+func isFullyFilled(est orderbook.FillEstimate, amount, amountTick float64) bool {
+	return amount-est.Filled < amountTick/2
+}
+
+// ImpactEstimate describes how far a market order would walk the book.
+type ImpactEstimate struct {
+	FirstPrice    float64 // price of the first level consumed
+	LastPrice     float64 // price of the last level consumed
+	AvgPrice      float64 // volume-weighted execution price
+	MidPrice      float64 // reference price the impact is measured from
+	ImpactPercent float64 // move from MidPrice to LastPrice, in percent, always >= 0
+	Levels        int     // price levels consumed
+}
+
+// MarketImpact estimates the price impact of a market order of amount on
+// side, without placing it. The impact is measured from the mid price, or
+// from the best opposite price when the other side of the book is empty. It
+// is read-only and fails with ErrInsufficientLiquidity when the book cannot
+// fill amount.
+func (e *Engine) MarketImpact(pair Pair, side orderbook.Side, amount float64) (ImpactEstimate, error) {
+	if !pair.IsValid() {
+		return ImpactEstimate{}, ErrInvalidPair
+	}
+	if side != orderbook.Bid && side != orderbook.Ask {
+		return ImpactEstimate{}, orderbook.ErrInvalidSide
+	}
+	if amount <= 0 {
+		return ImpactEstimate{}, orderbook.ErrInvalidAmount
+	}
+
+	cfg := e.pairConfig(pair)
+	ob := e.GetOrderbook(pair)
+	if ob == nil {
+		return ImpactEstimate{}, ErrInsufficientLiquidity
+	}
+
+	est := ob.EstimateFill(side, amount)
+	if est.Levels == 0 || !isFullyFilled(est, amount, cfg.AmountTick) {
+		return ImpactEstimate{}, ErrInsufficientLiquidity
+	}
+
+	mid := est.FirstPrice
+	bid, hasBid := ob.BestBid()
+	ask, hasAsk := ob.BestAsk()
+	if hasBid && hasAsk {
+		mid = (bid.Price(ob.PriceTick()) + ask.Price(ob.PriceTick())) / 2
+	}
+
+	return ImpactEstimate{
+		FirstPrice:    est.FirstPrice,
+		LastPrice:     est.LastPrice,
+		AvgPrice:      est.QuoteAmount / est.Filled,
+		MidPrice:      mid,
+		ImpactPercent: math.Abs(est.LastPrice-mid) / mid * 100,
+		Levels:        est.Levels,
+	}, nil
+}
+
+// Position is a user's exposure on a pair as built up by their trades, with
+// its cost basis tracked on the average-cost method. Fees are not included.
+type Position struct {
+	Bought        float64 // base bought
+	Sold          float64 // base sold
+	QuoteSpent    float64
+	QuoteReceived float64
+	AvgEntryPrice float64 // average price of the open position, 0 when flat
+	RealizedPnL   float64 // quote gained or lost closing position at prices away from the entry
+	Trades        int
+}
+
+// Net returns the open base position: positive long, negative short.
+func (p Position) Net() float64 {
+	return p.Bought - p.Sold
+}
+
+// Position replays userID's trades on pair from the tape, oldest first. A
+// user without trades gets a zero Position.
+func (e *Engine) Position(userID string, pair Pair) (Position, error) {
+	if !pair.IsValid() {
+		return Position{}, ErrInvalidPair
+	}
+	// Float residue of a closed position is below half an amount tick
+	dust := e.pairConfig(pair).AmountTick / 2
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var p Position
+	var open float64 // signed position the entry price applies to
+	for _, t := range e.trades {
+		if t.Pair != pair {
+			continue
+		}
+		if t.BuyerID == userID {
+			p.Bought += t.Amount
+			p.QuoteSpent += t.Price * t.Amount
+			open = p.apply(open, t.Amount, t.Price)
+			p.Trades++
+		}
+		if t.SellerID == userID {
+			p.Sold += t.Amount
+			p.QuoteReceived += t.Price * t.Amount
+			open = p.apply(open, -t.Amount, t.Price)
+			p.Trades++
+		}
+		if math.Abs(open) < dust {
+			open, p.AvgEntryPrice = 0, 0
+		}
+	}
+	return p, nil
+}
+
+// apply adds a signed fill of qty at price to the open position and returns
+// the new one. Fills that grow the position move the average entry; fills
+// that shrink it realize PnL against it, and any excess opens the other way
+// at price.
+func (p *Position) apply(open, qty, price float64) float64 {
+	if open == 0 || (open > 0) == (qty > 0) {
+		size := math.Abs(open) + math.Abs(qty)
+		p.AvgEntryPrice = (math.Abs(open)*p.AvgEntryPrice + math.Abs(qty)*price) / size
+		return open + qty
+	}
+
+	closed := math.Min(math.Abs(qty), math.Abs(open))
+	if open > 0 {
+		p.RealizedPnL += closed * (price - p.AvgEntryPrice)
+	} else {
+		p.RealizedPnL += closed * (p.AvgEntryPrice - price)
+	}
+
+	open += qty
+	switch {
+	case open == 0:
+		p.AvgEntryPrice = 0
+	case (open > 0) == (qty > 0):
+		p.AvgEntryPrice = price
+	}
+	return open
+}
+
+// PnL is a user's realized profit and loss on a pair on the FIFO method: each
+// sell closes the oldest open buys first, and each buy the oldest open sells.
+// Selling without open buys opens a short, realized when later buys close
+// it. Fees are not included.
+type PnL struct {
+	Realized     float64 // quote gained or lost on the closed amount
+	Closed       float64 // base closed against earlier fills
+	OpenPosition float64 // base still open: positive long, negative short
+	OpenCost     float64 // quote paid (long) or received (short) for the open base
+	Trades       int
+}
+
+// AvgOpenPrice returns the average price of the open lots, or 0 when flat.
+func (p PnL) AvgOpenPrice() float64 {
+	if p.OpenPosition == 0 {
+		return 0
+	}
+	return p.OpenCost / math.Abs(p.OpenPosition)
+}
+
+// pnlLot is an open FIFO lot: a signed base amount, positive long, filled at
+// price.
+type pnlLot struct {
+	amount float64
+	price  float64
+}
+
+// RealizedPnL replays userID's trades on pair from the tape, oldest first,
+// matching them into FIFO lots. A user without trades gets a zero PnL.
+func (e *Engine) RealizedPnL(userID string, pair Pair) (PnL, error) {
+	if !pair.IsValid() {
+		return PnL{}, ErrInvalidPair
+	}
+	// Float residue of a closed lot is below half an amount tick
+	dust := e.pairConfig(pair).AmountTick / 2
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var p PnL
+	var lots []pnlLot // open lots, oldest first, all on the same side
+	for _, t := range e.trades {
+		if t.Pair != pair {
+			continue
+		}
+		if t.BuyerID == userID {
+			lots = p.fill(lots, t.Amount, t.Price, dust)
+		}
+		if t.SellerID == userID {
+			lots = p.fill(lots, -t.Amount, t.Price, dust)
+		}
+	}
+
+	for _, lot := range lots {
+		p.OpenPosition += lot.amount
+		p.OpenCost += math.Abs(lot.amount) * lot.price
+	}
+	return p, nil
+}
+
+// fill applies a signed fill of qty at price: it closes open lots of the
+// other side, oldest first, and opens a lot with whatever is left.
+func (p *PnL) fill(lots []pnlLot, qty, price, dust float64) []pnlLot {
+	p.Trades++
+
+	for len(lots) > 0 && math.Abs(qty) >= dust && (lots[0].amount > 0) != (qty > 0) {
+		lot := &lots[0]
+		closed := math.Min(math.Abs(qty), math.Abs(lot.amount))
+		if lot.amount > 0 {
+			p.Realized += closed * (price - lot.price)
+			lot.amount -= closed
+			qty += closed
+		} else {
+			p.Realized += closed * (lot.price - price)
+			lot.amount += closed
+			qty -= closed
+		}
+		p.Closed += closed
+		if math.Abs(lot.amount) < dust {
+			lots = lots[1:]
+		}
+	}
+
+	if math.Abs(qty) >= dust {
+		lots = append(lots, pnlLot{amount: qty, price: price})
+	}
+	return lots
+}
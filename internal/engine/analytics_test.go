@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_RoundTripCost_SymmetricBook(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_900, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_100, 1)
+	assertNoError(t, err)
+
+	cost, err := e.RoundTripCost(btcBrl(), 1)
+	assertNoError(t, err)
+	assertFloat(t, 200, cost, "Round trip cost equals the spread")
+}
+
+func TestEngine_RoundTripCost_AsymmetricBook(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_900, 0.2)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_800, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_100, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_200, 1)
+	assertNoError(t, err)
+
+	// Buy 1: 0.5*50,100 + 0.5*50,200 = 50,150
+	// Sell 1: 0.2*49,900 + 0.8*49,800 = 49,820
+	cost, err := e.RoundTripCost(btcBrl(), 1)
+	assertNoError(t, err)
+	assertFloat(t, 330, cost, "Round trip cost includes slippage")
+
+	// Book must be untouched
+	ob := e.GetOrderbook(btcBrl())
+	assertFloat(t, 1.2, ob.BidTotalVolume(), "Bid volume unchanged")
+	assertFloat(t, 1.5, ob.AskTotalVolume(), "Ask volume unchanged")
+}
+
+func TestEngine_RoundTripCost_InsufficientLiquidity(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_100, 5)
+	assertNoError(t, err)
+
+	_, err = e.RoundTripCost(btcBrl(), 1)
+	assertEqual(t, ErrInsufficientLiquidity, err, "No bids to sell back into")
+}
+
+func TestEngine_MarketImpact_MultiLevel(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 51_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 52_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 55_000, 1)
+	assertNoError(t, err)
+
+	// Buy 1.5: 0.5*51,000 + 0.5*52,000 + 0.5*55,000 = 79,000
+	impact, err := e.MarketImpact(btcBrl(), orderbook.Bid, 1.5)
+	assertNoError(t, err)
+	assertFloat(t, 51_000, impact.FirstPrice, "First level")
+	assertFloat(t, 55_000, impact.LastPrice, "Last level")
+	assertEqual(t, 3, impact.Levels, "Levels consumed")
+	assertFloat(t, 79_000.0/1.5, impact.AvgPrice, "Average price")
+	assertFloat(t, 50_000, impact.MidPrice, "Mid price")
+	assertFloat(t, 10, impact.ImpactPercent, "55k is 10% above the 50k mid")
+
+	// Sell side walks the bids
+	impact, err = e.MarketImpact(btcBrl(), orderbook.Ask, 1)
+	assertNoError(t, err)
+	assertFloat(t, 49_000, impact.FirstPrice, "First bid level")
+	assertFloat(t, 49_000, impact.LastPrice, "Last bid level")
+	assertFloat(t, 2, impact.ImpactPercent, "49k is 2% below the 50k mid")
+
+	// Book must be untouched
+	ob := e.GetOrderbook(btcBrl())
+	assertFloat(t, 2, ob.AskTotalVolume(), "Ask volume unchanged")
+	assertFloat(t, 1, ob.BidTotalVolume(), "Bid volume unchanged")
+}
+
+func TestEngine_MarketImpact_OneSidedBook(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 51_000, 1)
+	assertNoError(t, err)
+
+	impact, err := e.MarketImpact(btcBrl(), orderbook.Bid, 2)
+	assertNoError(t, err)
+	assertFloat(t, 50_000, impact.MidPrice, "Best ask is the reference without bids")
+	assertFloat(t, 2, impact.ImpactPercent, "Impact from best ask")
+}
+
+func TestEngine_MarketImpact_InsufficientLiquidity(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+
+	_, err = e.MarketImpact(btcBrl(), orderbook.Bid, 2)
+	assertEqual(t, ErrInsufficientLiquidity, err, "Not enough asks")
+
+	_, err = e.MarketImpact(btcBrl(), orderbook.Ask, 0.5)
+	assertEqual(t, ErrInsufficientLiquidity, err, "No bids")
+}
+
+func TestEngine_Position_AverageEntry(t *testing.T) {
+	e := setupEngine()
+
+	// User 1 buys 0.25 @ 48,000 and 0.75 @ 52,000
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 48_000, 0.25)
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 52_000, 0.75)
+	_, _, err := e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 1)
+	assertNoError(t, err)
+
+	p, err := e.Position("1", btcBrl())
+	assertNoError(t, err)
+	assertEqual(t, 2, p.Trades, "Trades")
+	assertFloat(t, 1, p.Net(), "Net position")
+	assertFloat(t, 51_000, p.QuoteSpent, "Quote spent")
+	assertFloat(t, 51_000, p.AvgEntryPrice, "Weighted average entry")
+
+	// Selling half at 54,000 realizes 1,500 and keeps the entry
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 54_000, 0.5)
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Ask, 0.5)
+	assertNoError(t, err)
+
+	p, _ = e.Position("1", btcBrl())
+	assertFloat(t, 0.5, p.Net(), "Net after partial close")
+	assertFloat(t, 27_000, p.QuoteReceived, "Quote received")
+	assertFloat(t, 51_000, p.AvgEntryPrice, "Entry unchanged by a close")
+	assertFloat(t, 1_500, p.RealizedPnL, "Realized PnL")
+
+	// Selling 1 at 50,000 closes the rest and opens a short at that price
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_000, 1)
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Ask, 1)
+	assertNoError(t, err)
+
+	p, _ = e.Position("1", btcBrl())
+	assertFloat(t, -0.5, p.Net(), "Net short")
+	assertFloat(t, 50_000, p.AvgEntryPrice, "Short entry")
+	assertFloat(t, 1_000, p.RealizedPnL, "Realized PnL after flip")
+}
+
+func TestEngine_Position_NoTrades(t *testing.T) {
+	e := setupEngine()
+
+	p, err := e.Position("1", btcBrl())
+	assertNoError(t, err)
+	assertEqual(t, Position{}, p, "Empty position")
+
+	_, err = e.Position("1", Pair{Base: "BTC", Quote: "XYZ"})
+	assertEqual(t, ErrInvalidPair, err, "Invalid pair")
+}
+
+func TestEngine_RealizedPnL_FIFO(t *testing.T) {
+	e := setupEngine()
+
+	// User 1 buys 0.25 @ 48,000 and 0.75 @ 52,000
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 48_000, 0.25)
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 52_000, 0.75)
+	_, _, err := e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 1)
+	assertNoError(t, err)
+
+	// Selling 0.5 at 54,000 closes the 48,000 lot first: 1,500 + 500
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 54_000, 0.5)
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Ask, 0.5)
+	assertNoError(t, err)
+
+	pnl, err := e.RealizedPnL("1", btcBrl())
+	assertNoError(t, err)
+	assertFloat(t, 2_000, pnl.Realized, "Oldest lots closed first")
+	assertFloat(t, 0.5, pnl.Closed, "Closed amount")
+	assertFloat(t, 0.5, pnl.OpenPosition, "Half still open")
+	assertFloat(t, 52_000, pnl.AvgOpenPrice(), "Open lot from the second buy")
+	assertEqual(t, 3, pnl.Trades, "Trades")
+
+	// The seller had no buys: its sells opened shorts, closed at a loss
+	pnl, err = e.RealizedPnL("2", btcBrl())
+	assertNoError(t, err)
+	assertFloat(t, -2_000, pnl.Realized, "Short closed above its entry")
+	assertFloat(t, -0.5, pnl.OpenPosition, "Still short")
+
+	// Selling 1 at 50,000 closes the rest at a loss and opens a short
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_000, 1)
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Ask, 1)
+	assertNoError(t, err)
+	// Buying it back at 49,000 closes the short at a profit
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 49_000, 0.5)
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.5)
+	assertNoError(t, err)
+
+	pnl, _ = e.RealizedPnL("1", btcBrl())
+	assertFloat(t, 1_500, pnl.Realized, "2000 - 1000 + 500")
+	assertFloat(t, 0, pnl.OpenPosition, "Flat")
+	assertFloat(t, 0, pnl.AvgOpenPrice(), "No open price when flat")
+
+	pnl, err = e.RealizedPnL("3", btcBrl())
+	assertNoError(t, err)
+	assertEqual(t, PnL{}, pnl, "No trades")
+	_, err = e.RealizedPnL("1", Pair{Base: "BTC", Quote: "XYZ"})
+	assertEqual(t, ErrInvalidPair, err, "Invalid pair")
+}
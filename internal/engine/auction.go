@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+// AuctionResult is what one RunAuction executed.
+type AuctionResult struct {
+	orderbook.AuctionClearing
+	Trades int // matches executed at the clearing price
+}
+
+// AuctionPreview returns the indicative clearing of a pair in
+// PairModeAuction: the price, volume and imbalance RunAuction would execute
+// if it ran now. Nothing is matched or changed. Hidden orders are left out,
+// as in the public book, so with any resting the run may differ.
+func (e *Engine) AuctionPreview(pair Pair) (orderbook.AuctionClearing, error) {
+	if !pair.IsValid() {
+		return orderbook.AuctionClearing{}, ErrInvalidPair
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	cfg, exists := e.pairs[pair.String()]
+	if !exists {
+		return orderbook.AuctionClearing{}, ErrUnknownPair
+	}
+	if cfg.Mode != PairModeAuction {
+		return orderbook.AuctionClearing{}, ErrNotInAuction
+	}
+	ob, exists := e.orderbooks[pair.String()]
+	if !exists {
+		return orderbook.AuctionClearing{}, nil
+	}
+
+	return ob.VisibleAuctionClearing(cfg.AmountTick, e.lastTradePrice(pair)), nil
+}
+
+// RunAuction uncrosses a pair in PairModeAuction: the limit orders queued
+// since the last run trade at the single price that matches the most volume,
+// as orderbook.Orderbook.auctionClearing picks it with the pair's last trade
+// price as reference. Every bid at or above that price and every ask at or
+// below it fills, by price and then time priority, until the volume is
+// exhausted; what is left keeps resting for the next run.
+//
+// Neither side is the taker, so both pay maker fees, and a bid filled below
+// its limit price gets the difference unlocked. Orders of one user may
+// match each other, paying no fees, as self-trade prevention already ran
+// when they were placed.
+//
+// Each match is settled before it is filled in the book. If settling one
+// fails, the run stops there: the matches before it stand and the rest of
+// the book is left as it was, and the error is returned with what executed.
+func (e *Engine) RunAuction(pair Pair) (AuctionResult, error) {
+	if !pair.IsValid() {
+		return AuctionResult{}, ErrInvalidPair
+	}
+
+	defer e.serialize()()
+	defer e.repricePegs()
+	defer e.runTriggeredStops()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cfg, exists := e.pairs[pair.String()]
+	if !exists {
+		return AuctionResult{}, ErrUnknownPair
+	}
+	if cfg.Mode != PairModeAuction {
+		return AuctionResult{}, ErrNotInAuction
+	}
+	ob, exists := e.orderbooks[pair.String()]
+	if !exists {
+		return AuctionResult{}, nil
+	}
+
+	clearing, matches := ob.PlanAuction(cfg.AmountTick, e.lastTradePrice(pair), e.config.Clock.Now())
+
+	// A bid may fill in several matches; track its remainder from where it
+	// rests to price each match's share of its reserve.
+	bidRemaining := make(map[int64]float64)
+	var err error
+	settled := 0
+	for _, m := range matches {
+		before, ok := bidRemaining[m.Bid.ID]
+		if !ok {
+			before = m.Bid.RemainingAmount()
+		}
+		after := before - m.SizeFilled
+
+		consumed := e.roundAsset(pair.Quote, e.bidReserve(pair, m.Bid.Price, before)-e.bidReserve(pair, m.Bid.Price, after))
+		quote := min(e.roundAsset(pair.Quote, m.SizeFilled*m.Price), consumed)
+		if err = e.executeTransfer(pair, m, "", quote); err != nil {
+			err = fmt.Errorf("transfer failed: %w", err)
+			break
+		}
+		settled++
+		bidRemaining[m.Bid.ID] = after
+		if refund := e.roundAsset(pair.Quote, consumed-quote); refund > 0 {
+			if err = e.accounts.Unlock(m.Bid.UserID, pair.Quote, refund); err != nil {
+				err = fmt.Errorf("refund failed: %w", err)
+				break
+			}
+		}
+	}
+
+	// Fill only what settled, so the book agrees with the balances
+	matches = matches[:settled]
+	ob.FillAuction(matches, cfg.AmountTick)
+	for i := range matches {
+		e.recordTrade(pair, &matches[i], "")
+	}
+	e.stats.matches.Add(int64(len(matches)))
+
+	// Archive each filled order once, after its last match
+	archived := make(map[int64]bool)
+	for _, m := range matches {
+		for _, o := range []*orderbook.Order{m.Bid, m.Ask} {
+			if o.State != orderbook.OrderFilled || archived[o.ID] {
+				continue
+			}
+			archived[o.ID] = true
+			e.archiveOrder(pair, o)
+		}
+	}
+
+	return AuctionResult{AuctionClearing: clearing, Trades: len(matches)}, err
+}
@@ -0,0 +1,236 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_RunAuction(t *testing.T) {
+	e := setupEngine()
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeAuction))
+
+	// Demand/supply:  49800: 1.5/0.25  49900: 1.5/0.75  50000: 0.75/1.25  50100: 0.25/1.25
+	for _, o := range []struct {
+		user   string
+		side   orderbook.Side
+		price  float64
+		amount float64
+	}{
+		{"1", orderbook.Bid, 50_100, 0.25},
+		{"1", orderbook.Bid, 50_000, 0.5},
+		{"1", orderbook.Bid, 49_900, 0.75},
+		{"2", orderbook.Ask, 49_800, 0.25},
+		{"2", orderbook.Ask, 49_900, 0.5},
+		{"2", orderbook.Ask, 50_000, 0.5},
+		{"2", orderbook.Ask, 50_200, 0.25},
+	} {
+		order, matches, err := e.PlaceOrder(o.user, btcBrl(), o.side, o.price, o.amount)
+		assertNoError(t, err)
+		assertEqual(t, 0, len(matches), "Queued without matching")
+		assertEqual(t, orderbook.OrderOpen, order.State, "Resting")
+	}
+	assertEqual(t, 0, len(e.Trades(btcBrl())), "No trades before the auction")
+
+	_, _, err := e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.1)
+	assertEqual(t, ErrAuctionMode, err, "Market orders rejected")
+
+	result, err := e.RunAuction(btcBrl())
+	assertNoError(t, err)
+	assertFloat(t, 50_000, result.Price, "Clearing price")
+	assertFloat(t, 0.75, result.Volume, "Matched volume")
+	assertEqual(t, 2, result.Trades, "Matches")
+
+	trades := e.Trades(btcBrl())
+	assertEqual(t, 2, len(trades), "Trades recorded")
+	for _, tr := range trades {
+		assertFloat(t, 50_000, tr.Price, "Uniform price")
+		assertEqual(t, orderbook.Side(""), tr.TakerSide, "No taker in an auction")
+	}
+
+	// The bid at 50100 paid 50000 and got the difference back
+	brl := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 37_425, brl.Locked, "Unfilled bid keeps its reserve")
+	assertFloat(t, 25_075, brl.Available, "Paid the clearing price")
+	assertFloat(t, 10.75, e.accounts.GetBalance("1", "BTC").Available, "Bought")
+	assertFloat(t, 137_500, e.accounts.GetBalance("2", "BRL").Available, "Sold")
+	assertFloat(t, 0.75, e.accounts.GetBalance("2", "BTC").Locked, "Unfilled asks keep their lock")
+
+	history, _, err := e.OrderHistory("2", HistoryFilter{})
+	assertNoError(t, err)
+	assertEqual(t, 2, len(history), "Filled asks archived once each")
+
+	ob := e.GetOrderbook(btcBrl())
+	bid, _ := ob.BestBid()
+	ask, _ := ob.BestAsk()
+	assertTrue(t, bid.PriceTicks < ask.PriceTicks, "Book uncrossed")
+}
+
+func TestEngine_AuctionPreview(t *testing.T) {
+	e := setupEngine()
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeAuction))
+
+	preview, err := e.AuctionPreview(btcBrl())
+	assertNoError(t, err)
+	assertFloat(t, 0, preview.Volume, "Empty book")
+
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_200, 0.25)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.75)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 49_900, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_100, 0.5)
+	assertNoError(t, err)
+
+	preview, err = e.AuctionPreview(btcBrl())
+	assertNoError(t, err)
+	assertFloat(t, 50_000, preview.Price, "Indicative price")
+	assertFloat(t, 0.5, preview.Volume, "Indicative volume")
+	assertFloat(t, 0.5, preview.Imbalance, "Buyers left over")
+
+	// Nothing moved
+	again, err := e.AuctionPreview(btcBrl())
+	assertNoError(t, err)
+	assertEqual(t, preview, again, "Repeatable")
+	assertEqual(t, 0, len(e.Trades(btcBrl())), "No trades")
+	assertEqual(t, 2, len(e.GetOrderbook(btcBrl()).Bids()), "Bids still resting")
+	assertFloat(t, 1, e.accounts.GetBalance("2", "BTC").Locked, "Asks still locked")
+
+	// The auction executes what the preview showed
+	result, err := e.RunAuction(btcBrl())
+	assertNoError(t, err)
+	assertEqual(t, preview, result.AuctionClearing, "Preview matches the run")
+
+	filled := 0.0
+	for _, tr := range e.Trades(btcBrl()) {
+		assertFloat(t, preview.Price, tr.Price, "Traded at the indicative price")
+		filled += tr.Amount
+	}
+	assertFloat(t, preview.Volume, filled, "Traded the indicative volume")
+
+	_, err = e.AuctionPreview(Pair{Base: "DOGE", Quote: "BRL"})
+	assertEqual(t, ErrUnknownPair, err, "Unlisted pair")
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeNormal))
+	_, err = e.AuctionPreview(btcBrl())
+	assertEqual(t, ErrNotInAuction, err, "Continuous pair")
+}
+
+func TestEngine_AuctionPreview_LeavesOutHidden(t *testing.T) {
+	e := setupEngine()
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeAuction))
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrderWithOptions("2", btcBrl(), orderbook.Ask, 50_000, 0.5, OrderOptions{Hidden: true})
+	assertNoError(t, err)
+
+	preview, err := e.AuctionPreview(btcBrl())
+	assertNoError(t, err)
+	assertFloat(t, 0.5, preview.Volume, "Hidden ask not shown")
+	assertFloat(t, 0.5, preview.Imbalance, "Imbalance over visible orders")
+
+	result, err := e.RunAuction(btcBrl())
+	assertNoError(t, err)
+	assertFloat(t, 1, result.Volume, "The run still fills the hidden ask")
+}
+
+func TestEngine_RunAuction_ReferencePrice(t *testing.T) {
+	e := setupEngine()
+
+	// The last trade is the reference for a balanced tie
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_080, 0.25)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_080, 0.25)
+	assertNoError(t, err)
+
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeAuction))
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_100, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 49_900, 0.5)
+	assertNoError(t, err)
+
+	result, err := e.RunAuction(btcBrl())
+	assertNoError(t, err)
+	assertFloat(t, 50_100, result.Price, "Tied price closest to the last trade")
+	assertFloat(t, 0.5, result.Volume, "Matched volume")
+}
+
+func TestEngine_RunAuction_Errors(t *testing.T) {
+	e := setupEngine()
+
+	_, err := e.RunAuction(btcBrl())
+	assertEqual(t, ErrNotInAuction, err, "Continuous pair")
+	_, err = e.RunAuction(Pair{Base: "DOGE", Quote: "BRL"})
+	assertEqual(t, ErrUnknownPair, err, "Unlisted pair")
+
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeAuction))
+	result, err := e.RunAuction(btcBrl())
+	assertNoError(t, err)
+	assertEqual(t, 0, result.Trades, "Empty book")
+}
+
+func TestEngine_RunAuction_FailedSettlementLeavesRestUnfilled(t *testing.T) {
+	e := setupEngine()
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeAuction))
+
+	bid, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+	first, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 49_900, 0.25)
+	assertNoError(t, err)
+	second, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 49_900, 0.25)
+	assertNoError(t, err)
+
+	// Only the first ask's lock is left, so the second match cannot settle
+	assertNoError(t, e.accounts.Unlock("2", "BTC", 0.25))
+
+	result, err := e.RunAuction(btcBrl())
+	assertTrue(t, err != nil, "Settlement failure reported")
+	assertEqual(t, 1, result.Trades, "The settled match stands")
+	assertEqual(t, 1, len(e.Trades(btcBrl())), "One trade recorded")
+
+	ob := e.GetOrderbook(btcBrl())
+	_, resting := ob.GetOrder(first.ID)
+	assertFalse(t, resting, "First ask filled")
+	unfilled, resting := ob.GetOrder(second.ID)
+	assertTrue(t, resting, "Second ask still rests")
+	assertFloat(t, 0, unfilled.FilledAmount, "Second ask untouched")
+	resting2, _ := ob.GetOrder(bid.ID)
+	assertFloat(t, 0.25, resting2.RemainingAmount(), "Bid filled by the settled match only")
+	assertFloat(t, 10.25, e.accounts.GetBalance("1", "BTC").Available, "Buyer got the settled base")
+}
+
+func TestEngine_RunAuction_HoldsTrailingStops(t *testing.T) {
+	e := setupEngine()
+	_ = e.accounts.Credit("3", "BTC", 1)
+
+	// Liquidity for the stop's market sell, below the traded range
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 1)
+	assertNoError(t, err)
+	trade(t, e, 50_000)
+	_, err = e.PlaceTrailingStop("3", btcBrl(), orderbook.Ask, 1_000, 0.5)
+	assertNoError(t, err)
+
+	// The auction prints through the stop price while market orders are refused
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeAuction))
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 48_000, 0.1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 48_000, 0.1)
+	assertNoError(t, err)
+	result, err := e.RunAuction(btcBrl())
+	assertNoError(t, err)
+	assertFloat(t, 48_000, result.Price, "Auction price")
+
+	held := e.TrailingStops("3")[0]
+	assertEqual(t, TrailingActive, held.State, "Held, not failed")
+	assertFloat(t, 48_000, held.TriggerPrice, "Triggered by the auction print")
+
+	// Leaving the auction fires it
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeNormal))
+	fired := e.TrailingStops("3")[0]
+	assertEqual(t, TrailingTriggered, fired.State, "Fired after the auction")
+	assertTrue(t, fired.OrderID > 0, "Market order placed")
+	assertFloat(t, 20_000, e.accounts.GetBalance("3", "BRL").Available, "Sold at 40000")
+}
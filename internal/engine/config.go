@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+// STPMode selects how an incoming order that would cross the same user's
+// resting orders is handled.
+type STPMode string
+
+const (
+	STPCancelNewest STPMode = "cancel_newest" // reject the incoming order
+	STPCancelOldest STPMode = "cancel_oldest" // cancel the crossed resting orders, then match
+	STPCancelBoth   STPMode = "cancel_both"   // cancel the crossed resting orders and reject the incoming one
+)
+
+// TickPolicy selects how prices and amounts that are not aligned to the
+// pair's tick are normalized.
+type TickPolicy string
+
+const (
+	TickFloor  TickPolicy = "floor"  // round down to the tick
+	TickRound  TickPolicy = "round"  // round to the nearest tick
+	TickReject TickPolicy = "reject" // reject misaligned values
+)
+
+// Config holds engine-wide behavior switches.
+type Config struct {
+	STPMode    STPMode
+	TickPolicy TickPolicy
+
+	// MaxOpenOrdersPerUser caps resting orders per user per pair. 0 disables the cap.
+	MaxOpenOrdersPerUser int
+
+	// MaxLevelsPerOrder caps the price levels a single market or marketable
+	// limit order may match against, bounding how long one order holds the
+	// book. A market order stopped by the cap ends partially filled (or rests,
+	// with RestRemainder); a limit order is cancelled, since its remainder
+	// would cross the book. 0 disables the cap.
+	MaxLevelsPerOrder int
+
+	// PegRepriceInterval is the least time between two reprices of a pegged
+	// order, so a busy book cannot make it churn. 0 reprices on every book
+	// change.
+	PegRepriceInterval time.Duration
+
+	// SpreadSampleInterval is how often, on Clock, StartSpreadSampler
+	// records the top of every book for SpreadHistory. 0 leaves the sampler
+	// off.
+	SpreadSampleInterval time.Duration
+
+	// SweepDust cancels resting orders whose remainder drops below the pair's
+	// MinOrderSize after a fill, since they could never be fully matched.
+	SweepDust bool
+
+	// Clock stamps orders and matches. Nil means the wall clock.
+	Clock clock.Clock
+
+	// Deterministic makes match output a function of the input sequence
+	// alone, so runs of two engine versions can be diffed: placements are
+	// serialized, order IDs and sequences come from per-engine counters
+	// instead of process-wide ones, and a wall Clock is replaced by a fake
+	// one starting at DeterministicEpoch that moves 1ms per placement,
+	// cancel or other serialized operation. Expiry sweeps started with
+	// StartExpirySweeper still follow wall time; replays call ExpireOrders.
+	Deterministic bool
+
+	// FeeTiers is the fee schedule, selected by a user's traded quote volume
+	// over FeeWindow. No tiers means no fees.
+	FeeTiers  []FeeTier
+	FeeWindow time.Duration
+
+	// FeeAccount is the account collected fees are credited to. The ID is
+	// reserved: orders placed under it are rejected with ErrReservedUserID,
+	// so no trader can spend or share the fees.
+	FeeAccount string
+
+	// FeeRounding picks which way fees are rounded to their asset's
+	// precision. Empty means FeeRoundNearest, so a fee on a fill worth a
+	// unit or two is not rounded up to all of it.
+	FeeRounding FeeRounding
+
+	// AllowUnlistedPairs lets orders on pairs missing from the registry create
+	// their orderbook on first use. Without it they fail with ErrUnknownPair
+	// whenever at least one pair is registered.
+	AllowUnlistedPairs bool
+
+	// SkipDefaultPairs starts the engine with an empty registry instead of
+	// listing BTC/BRL, ETH/BRL and USDT/BRL, for callers that register their
+	// own pairs.
+	SkipDefaultPairs bool
+
+	// MinRefund is, per asset, the smallest price-improvement refund a
+	// resting bid gets back right away; smaller ones stay locked with the
+	// order and are released when it leaves the book. Assets not listed use
+	// one unit of their account precision.
+	MinRefund map[string]float64
+
+	// TakerThrottle delays or rate-limits orders that cross the spread, to
+	// favour makers. Off by default.
+	TakerThrottle TakerThrottle
+
+	// AccountStore and OrderStore hold balances and archived orders. Nil
+	// means in memory.
+	AccountStore account.Store
+	OrderStore   OrderStore
+}
+
+func DefaultConfig() Config {
+	return Config{
+		STPMode:    STPCancelNewest,
+		TickPolicy: TickFloor,
+		Clock:      clock.Real(),
+		FeeWindow:  DefaultFeeWindow,
+		FeeAccount: DefaultFeeAccount,
+
+		FeeRounding:          FeeRoundNearest,
+		PegRepriceInterval:   DefaultPegRepriceInterval,
+		SpreadSampleInterval: DefaultSpreadSampleInterval,
+	}
+}
+
+// Config returns a copy of the configuration the engine runs with, defaults
+// applied.
+func (e *Engine) Config() Config {
+	cfg := e.config
+	cfg.FeeTiers = append([]FeeTier(nil), e.config.FeeTiers...)
+	if e.config.MinRefund != nil {
+		cfg.MinRefund = make(map[string]float64, len(e.config.MinRefund))
+		for asset, threshold := range e.config.MinRefund {
+			cfg.MinRefund[asset] = threshold
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,77 @@
+package engine
+
+import "time"
+
+// DelistedPair records a pair taken off the registry by DelistPair.
+type DelistedPair struct {
+	Config          PairConfig // as it was when delisted, Halted set
+	DelistedAt      time.Time
+	CancelledOrders int // resting orders cancelled by the delisting
+}
+
+// DelistPair takes pair off the registry for good, unlike HaltPair, which
+// only pauses it: every resting order is cancelled and its funds unlocked,
+// active trailing stops are cancelled, and any further order on the pair
+// fails with ErrPairDelisted, even where unlisted pairs are allowed. The
+// pair leaves ListPairs and its book is no longer served, but its trades and
+// archived orders stay queryable. RegisterPair lists it again.
+//
+// The cancelled orders are returned oldest first. On error the orders
+// cancelled so far are returned with it and the pair stays listed, halted.
+func (e *Engine) DelistPair(pair Pair) ([]ArchivedOrder, error) {
+	if !pair.IsValid() {
+		return nil, ErrInvalidPair
+	}
+
+	defer e.serialize()()
+	defer e.repricePegs()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := pair.String()
+	cfg, exists := e.pairs[key]
+	if !exists {
+		if _, delisted := e.delisted[key]; delisted {
+			return nil, ErrPairDelisted
+		}
+		return nil, ErrUnknownPair
+	}
+	cfg.Halted = true
+
+	var cancelled []ArchivedOrder
+	if ob, exists := e.orderbooks[key]; exists {
+		for _, order := range ob.RestingOrders() {
+			archived, err := e.cancelResting(pair, ob, order.ID)
+			if err != nil {
+				return cancelled, err
+			}
+			cancelled = append(cancelled, archived)
+		}
+	}
+
+	for _, stop := range append(e.trailingStops[key], e.heldStops[key]...) {
+		stop.State = TrailingCancelled
+		stop.Err = ErrPairDelisted.Error()
+	}
+	delete(e.trailingStops, key)
+	delete(e.heldStops, key)
+
+	e.delisted[key] = DelistedPair{
+		Config:          *cfg,
+		DelistedAt:      e.config.Clock.Now(),
+		CancelledOrders: len(cancelled),
+	}
+	delete(e.pairs, key)
+
+	return cancelled, nil
+}
+
+// GetDelistedPair returns the record of pair's delisting, if it was
+// delisted and not listed again since.
+func (e *Engine) GetDelistedPair(pair Pair) (DelistedPair, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	d, ok := e.delisted[pair.String()]
+	return d, ok
+}
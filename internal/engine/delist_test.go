@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_DelistPair(t *testing.T) {
+	e := setupEngine()
+
+	// A trade before delisting stays in the history
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.25)
+	assertNoError(t, err)
+
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 51_000, 0.5)
+	assertNoError(t, err)
+	stop, err := e.PlaceTrailingStop("1", btcBrl(), orderbook.Ask, 1_000, 0.1)
+	assertNoError(t, err)
+
+	cancelled, err := e.DelistPair(btcBrl())
+	assertNoError(t, err)
+	assertEqual(t, 3, len(cancelled), "Every resting order cancelled")
+	for i, archived := range cancelled {
+		assertEqual(t, orderbook.OrderCancelled, archived.Order.State, "Cancelled")
+		if i > 0 {
+			assertTrue(t, archived.Order.Seq > cancelled[i-1].Order.Seq, "Oldest first")
+		}
+	}
+
+	// Locks released
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BRL").Locked, "Bid lock released")
+	assertFloat(t, 87_500, e.accounts.GetBalance("1", "BRL").Available, "Buyer keeps only the trade's cost")
+	assertFloat(t, 0, e.accounts.GetBalance("2", "BTC").Locked, "Ask lock released")
+	assertFloat(t, 9.75, e.accounts.GetBalance("2", "BTC").Available, "Seller keeps only the trade's sale")
+
+	assertEqual(t, TrailingCancelled, e.TrailingStops("1")[0].State, "Trailing stop cancelled")
+	assertEqual(t, stop.ID, e.TrailingStops("1")[0].ID, "Same stop")
+
+	// Gone from the registry, new orders rejected
+	for _, cfg := range e.ListPairs() {
+		assertTrue(t, cfg.Pair != btcBrl(), "Not listed")
+	}
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertEqual(t, ErrPairDelisted, err, "Limit order rejected")
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.1)
+	assertEqual(t, ErrPairDelisted, err, "Market order rejected")
+	_, err = e.PlaceTrailingStop("1", btcBrl(), orderbook.Ask, 1_000, 0.1)
+	assertEqual(t, ErrPairDelisted, err, "Trailing stop rejected")
+	assertTrue(t, e.GetOrderbook(btcBrl()) == nil, "Book no longer served")
+
+	// History kept
+	assertEqual(t, 1, len(e.Trades(btcBrl())), "Trades queryable")
+	record, ok := e.GetDelistedPair(btcBrl())
+	assertTrue(t, ok, "Delisting recorded")
+	assertEqual(t, 3, record.CancelledOrders, "Cancelled count recorded")
+	assertTrue(t, record.Config.Halted, "Recorded halted")
+
+	_, err = e.DelistPair(btcBrl())
+	assertEqual(t, ErrPairDelisted, err, "Already delisted")
+	_, err = e.DelistPair(Pair{Base: "DOGE", Quote: "BRL"})
+	assertEqual(t, ErrUnknownPair, err, "Never listed")
+}
+
+func TestEngine_DelistPair_Relist(t *testing.T) {
+	e := setupEngine()
+	_, err := e.DelistPair(btcBrl())
+	assertNoError(t, err)
+
+	assertNoError(t, e.RegisterPair(DefaultPairConfig(btcBrl())))
+	_, ok := e.GetDelistedPair(btcBrl())
+	assertTrue(t, !ok, "Record cleared")
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertNoError(t, err)
+}
+
+func TestEngine_DelistPair_LastPair(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SkipDefaultPairs = true
+	e := setupEngineWithConfig(cfg)
+	assertNoError(t, e.RegisterPair(DefaultPairConfig(btcBrl())))
+
+	_, err := e.DelistPair(btcBrl())
+	assertNoError(t, err)
+
+	// An emptied registry does not open the exchange to every pair
+	_, _, err = e.PlaceOrder("1", Pair{Base: "ETH", Quote: "BRL"}, orderbook.Bid, 10_000, 0.1)
+	assertEqual(t, ErrUnknownPair, err, "Unlisted pair still rejected")
+}
+
+func TestEngine_DelistPair_RacingPlacement(t *testing.T) {
+	e := setupEngine()
+
+	// An order validated before the delisting settles after it
+	order, cfg, err := e.newLimitOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1, OrderOptions{})
+	assertNoError(t, err)
+	lockAsset, lockAmount := e.orderLock(btcBrl(), order)
+	assertNoError(t, e.accounts.Lock("1", lockAsset, lockAmount))
+
+	_, err = e.DelistPair(btcBrl())
+	assertNoError(t, err)
+
+	e.mu.Lock()
+	_, _, err = e.submitLimitOrder(btcBrl(), cfg, order, lockAsset, lockAmount, 0)
+	e.mu.Unlock()
+	assertEqual(t, ErrPairDelisted, err, "Rejected once delisted")
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BRL").Locked, "Lock released")
+	assertEqual(t, 0, len(e.orderbooks[btcBrl().String()].RestingOrders()), "Nothing rests on the delisted book")
+}
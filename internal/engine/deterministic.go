@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+// DeterministicEpoch is where the clock of a deterministic engine starts when
+// Config.Clock is left as the wall clock.
+var DeterministicEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// deterministicStep is how far the clock of a deterministic engine moves
+// after each serialized operation.
+const deterministicStep = time.Millisecond
+
+// deterministicClock returns the clock a deterministic engine runs on: c
+// itself unless it is the wall clock, which would leak into timestamps. In
+// that case it returns a fake clock standing at DeterministicEpoch as both
+// values; reading it does not move it, serialize does.
+func deterministicClock(c clock.Clock) (clock.Clock, *clock.Fake) {
+	if c == nil || c == clock.Real() {
+		fake := clock.NewFake(DeterministicEpoch, 0)
+		return fake, fake
+	}
+	return c, nil
+}
+
+// serialize runs placements, and the cancels and peg reprices that follow
+// book changes, one at a time under Config.Deterministic, so concurrent
+// callers cannot interleave between the balance lock and the match. It
+// returns the function that ends the placement, which also moves the
+// engine's own deterministic clock forward by one step, so timestamps count
+// operations rather than how often each one reads the clock.
+func (e *Engine) serialize() func() {
+	if !e.config.Deterministic {
+		return func() {}
+	}
+	e.serial.Lock()
+	return func() {
+		if e.tick != nil {
+			e.tick.Advance(deterministicStep)
+		}
+		e.serial.Unlock()
+	}
+}
+
+// stampOrder replaces the process-wide ID and sequence of a new order with
+// the engine's own under Config.Deterministic, so two engines fed the same
+// input assign the same IDs regardless of what else ran in the process.
+func (e *Engine) stampOrder(order *orderbook.Order) {
+	if !e.config.Deterministic {
+		return
+	}
+	n := e.orderCounter.Add(1)
+	order.ID = int64(n)
+	order.Seq = n
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files under testdata")
+
+// replayFixedSequence feeds a fixed order sequence into a fresh deterministic
+// engine and renders every match it produced, one per line.
+func replayFixedSequence(t *testing.T) []byte {
+	t.Helper()
+
+	cfg := DefaultConfig()
+	cfg.Deterministic = true
+	e := setupEngineWithConfig(cfg)
+	_ = e.accounts.Credit("3", "BRL", 100_000)
+	_ = e.accounts.Credit("3", "BTC", 10)
+	_ = e.accounts.Credit("3", "ETH", 100)
+	_ = e.accounts.Credit("1", "ETH", 100)
+
+	eth := Pair{Base: "ETH", Quote: "BRL"}
+
+	var out bytes.Buffer
+	record := func(_ *orderbook.Order, matches []orderbook.Match, err error) {
+		t.Helper()
+		assertNoError(t, err)
+		for _, m := range matches {
+			fmt.Fprintf(&out, "seq=%d pair_seq=%d price=%.2f size=%.8f bid=%d/%s ask=%d/%s at=%s\n",
+				m.Seq, m.PairSeq, m.Price, m.SizeFilled, m.Bid.ID, m.Bid.UserID, m.Ask.ID, m.Ask.UserID,
+				m.Timestamp.Format("15:04:05.000"))
+		}
+	}
+
+	record(e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.3))
+	record(e.PlaceOrder("3", btcBrl(), orderbook.Ask, 50_000, 0.2))
+	record(e.PlaceOrderWithOptions("2", btcBrl(), orderbook.Ask, 50_100, 0.5, OrderOptions{Hidden: true}))
+	record(e.PlaceOrder("3", btcBrl(), orderbook.Ask, 50_100, 0.4))
+	record(e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_100, 0.7))
+	record(e.PlaceOrder("3", eth, orderbook.Ask, 3_000, 2))
+	record(e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.6))
+	record(e.PlaceOrder("2", eth, orderbook.Bid, 3_000, 1.5))
+	record(e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_900, 0.5))
+	record(e.PlaceMarketOrder("3", btcBrl(), orderbook.Ask, 0.25))
+	record(e.PlaceOrder("1", eth, orderbook.Ask, 2_990, 1))
+	return out.Bytes()
+}
+
+func TestEngine_Deterministic_GoldenMatches(t *testing.T) {
+	golden := filepath.Join("testdata", "deterministic_matches.golden")
+
+	first := replayFixedSequence(t)
+	second := replayFixedSequence(t)
+	if !bytes.Equal(first, second) {
+		t.Fatalf("two runs differ:\n%s\n---\n%s", first, second)
+	}
+
+	if *updateGolden {
+		assertNoError(t, os.WriteFile(golden, first, 0o644))
+	}
+	want, err := os.ReadFile(golden)
+	assertNoError(t, err)
+	if !bytes.Equal(want, first) {
+		t.Errorf("match output differs from %s:\n%s\n--- want\n%s", golden, first, want)
+	}
+}
+
+func TestEngine_Deterministic_PerEngineIDs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Deterministic = true
+
+	for i := 0; i < 2; i++ {
+		e := setupEngineWithConfig(cfg)
+		order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+		assertNoError(t, err)
+		assertEqual(t, int64(1), order.ID, "Each engine starts its own IDs")
+		assertTrue(t, order.Timestamp.Equal(DeterministicEpoch), "Fake clock starts at the epoch")
+	}
+}
+
+func TestEngine_Deterministic_ClockCountsOperations(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Deterministic = true
+	e := setupEngineWithConfig(cfg)
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.1)
+	assertNoError(t, err)
+
+	// Reading the clock, as samplers and snapshots do, must not move it
+	for i := 0; i < 5; i++ {
+		e.config.Clock.Now()
+	}
+
+	_, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Matched")
+	assertTrue(t, matches[0].Timestamp.Equal(DeterministicEpoch.Add(deterministicStep)), "Second operation, one step in")
+}
@@ -1,28 +1,142 @@
 package engine
 
 import (
+	"container/list"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/moura95/crypto-exchange-challenge/internal/account"
 	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
 	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
 )
 
 type Engine struct {
 	orderbooks map[string]*orderbook.Orderbook
+	pairs      map[string]*PairConfig
+	delisted   map[string]DelistedPair // pair -> how it left the registry
 	accounts   *account.Manager
+	config     Config
 	mu         sync.RWMutex
+
+	trades         []Trade
+	tradeListeners []func(Trade)         // see OnTrade
+	archive        OrderStore            // terminal orders of every user
+	unarchived     []ArchivedOrder       // archived orders archive has not accepted yet, oldest first
+	fillQuote      map[int64]float64     // order ID -> quote filled so far, until archived
+	fills          map[int64]*orderFills // order ID -> fill log, until archived
+
+	tradeSeq     atomic.Uint64     // exchange-wide, shared by every pair
+	pairTradeSeq map[string]uint64 // pair -> trades recorded on it
+
+	volumes map[string]*rollingVolume // user ID -> quote volume traded within the fee window
+
+	trailingStops map[string][]*TrailingStop // pair -> active trailing stops
+	allStops      []*TrailingStop            // every trailing stop, in placement order
+	triggered     []*TrailingStop            // stops waiting to fire once e.mu is released
+	heldStops     map[string][]*TrailingStop // pair -> triggered stops waiting for it to leave auction mode
+	trailingSeq   int64
+
+	activePegs []*PeggedOrder         // pegs following the book
+	hasPegs    atomic.Bool            // len(activePegs) > 0, readable without e.mu
+	allPegs    []*PeggedOrder         // every pegged order, in placement order
+	pegOrders  map[int64]*PeggedOrder // resting order ID -> its peg
+	pegSeq     int64
+
+	rejectMu        sync.Mutex               // guards the rejection fields below, never held with e.mu
+	rejections      map[string]*rejectionLog // user ID -> recent rejections
+	rejectUsers     *list.List               // user IDs with a rejection log, least recently rejected first
+	rejectListeners []func(Rejection)        // see OnReject
+	rejectSeq       uint64
+
+	expiries expiryHeap // resting orders with an ExpiresAt, soonest first
+
+	clientOrders map[clientOrderKey]*orderbook.Order // resting orders placed under a client order ID
+
+	waiters map[int64][]chan struct{} // resting order ID -> WaitForOrder calls, closed once it leaves the book
+
+	withheldRefunds map[int64]float64 // resting bid ID -> refund below minRefund, released when it leaves the book
+
+	spreadMu         sync.Mutex             // guards spreads and lastSpreadSample, taken before e.mu
+	spreads          map[string]*spreadRing // pair -> top of book samples
+	lastSpreadSample time.Time
+
+	nonces   sync.Map    // user ID -> *atomic.Uint64, last accepted nonce
+	reserved reservedIDs // order IDs handed out by ReserveOrderID
+	takers   takerLog    // recent taker orders, for Config.TakerThrottle
+
+	halted atomic.Bool // set by Halt, blocks placement on every pair
+
+	serial       sync.Mutex    // one placement at a time under Config.Deterministic
+	orderCounter atomic.Uint64 // order IDs under Config.Deterministic
+	tick         *clock.Fake   // the clock Config.Deterministic put in place of the wall clock, if any
+
+	stats counters
 }
 
 func NewEngine() *Engine {
+	return NewEngineWithConfig(DefaultConfig())
+}
+
+func NewEngineWithConfig(cfg Config) *Engine {
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real()
+	}
+	var tick *clock.Fake
+	if cfg.Deterministic {
+		cfg.Clock, tick = deterministicClock(cfg.Clock)
+	}
+	if cfg.FeeWindow <= 0 {
+		cfg.FeeWindow = DefaultFeeWindow
+	}
+	if cfg.FeeAccount == "" {
+		cfg.FeeAccount = DefaultFeeAccount
+	}
+	if cfg.FeeRounding == "" {
+		cfg.FeeRounding = FeeRoundNearest
+	}
+	cfg.FeeTiers = sortedFeeTiers(cfg.FeeTiers)
+	if cfg.AccountStore == nil {
+		cfg.AccountStore = account.NewMemoryStore()
+	}
+	if cfg.OrderStore == nil {
+		cfg.OrderStore = NewMemoryOrderStore()
+	}
+
 	e := &Engine{
 		orderbooks: make(map[string]*orderbook.Orderbook),
-		accounts:   account.NewManager(),
+		pairs:      make(map[string]*PairConfig),
+		delisted:   make(map[string]DelistedPair),
+		accounts:   account.NewManagerWithStore(cfg.AccountStore),
+		config:     cfg,
+		tick:       tick,
+		archive:    cfg.OrderStore,
+		fillQuote:  make(map[int64]float64),
+		fills:      make(map[int64]*orderFills),
+		volumes:    make(map[string]*rollingVolume),
+
+		pairTradeSeq:  make(map[string]uint64),
+		trailingStops: make(map[string][]*TrailingStop),
+		heldStops:     make(map[string][]*TrailingStop),
+		pegOrders:     make(map[int64]*PeggedOrder),
+		rejections:    make(map[string]*rejectionLog),
+		rejectUsers:   list.New(),
+		spreads:       make(map[string]*spreadRing),
+		clientOrders:  make(map[clientOrderKey]*orderbook.Order),
+
+		withheldRefunds: make(map[int64]float64),
 	}
 
 	// Pre-List orderbooks
+	if cfg.SkipDefaultPairs {
+		return e
+	}
 	preListPairs := []Pair{
 		{Base: "BTC", Quote: "BRL"},
 		{Base: "ETH", Quote: "BRL"},
@@ -30,7 +144,7 @@ func NewEngine() *Engine {
 	}
 
 	for _, pair := range preListPairs {
-		e.orderbooks[pair.String()] = orderbook.NewOrderbook()
+		_ = e.RegisterPair(DefaultPairConfig(pair))
 	}
 
 	return e
@@ -43,88 +157,294 @@ func (e *Engine) getOrCreateOrderbook(pair Pair) *orderbook.Orderbook {
 		return ob
 	}
 
-	ob := orderbook.NewOrderbook()
+	// The default tick is a positive constant, so this cannot fail.
+	ob, _ := orderbook.NewOrderbookWithClock(DefaultPairConfig(pair).PriceTick, e.config.Clock)
 	e.orderbooks[key] = ob
 	return ob
 }
 
 func (e *Engine) PlaceOrder(userID string, pair Pair, side orderbook.Side, price, amount float64) (*orderbook.Order, []orderbook.Match, error) {
+	return e.PlaceOrderWithOptions(userID, pair, side, price, amount, OrderOptions{})
+}
+
+// PlaceOrderWithOptions places a limit order with the given per-order flags.
+func (e *Engine) PlaceOrderWithOptions(userID string, pair Pair, side orderbook.Side, price, amount float64, opts OrderOptions) (order *orderbook.Order, matches []orderbook.Match, err error) {
+	defer e.serialize()()
+	defer func() {
+		if err != nil {
+			e.recordRejection(userID, pair, side, orderbook.OrderTypeLimit, price, amount, opts.Tag, err)
+		}
+	}()
+
+	// A halt refuses the order before it can spend the nonce
+	if e.halted.Load() {
+		return nil, nil, ErrEngineHalted
+	}
+	if err := e.CheckNonce(userID, opts.Nonce); err != nil {
+		return nil, nil, err
+	}
+
+	order, matches, err = e.placeLimitOrder(userID, pair, side, price, amount, opts)
+	e.runTriggeredStops()
+	e.repricePegs()
+	return order, matches, err
+}
+
+func (e *Engine) placeLimitOrder(userID string, pair Pair, side orderbook.Side, price, amount float64, opts OrderOptions) (*orderbook.Order, []orderbook.Match, error) {
+	order, cfg, err := e.newLimitOrder(userID, pair, side, price, amount, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	e.delayTaker(pair, side, order.Price)
+
+	// Lock funds
+	lockAsset, lockAmount := e.orderLock(pair, order)
+	if err := e.accounts.Lock(userID, lockAsset, lockAmount); err != nil {
+		return nil, nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if opts.ImproveOnly && !improvesBook(e.orderbooks[pair.String()], order, nil) {
+		_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
+		return nil, nil, ErrWouldNotImprove
+	}
+
+	return e.submitLimitOrder(pair, cfg, order, lockAsset, lockAmount, opts.OrderID)
+}
+
+// improvesBook reports whether order would rest on ob as the new best visible
+// price on its side: it must not cross the other side, and its price must be
+// strictly better than every visible order on its own, leaving out replaced,
+// the order it replaces if any. Must be called with e.mu held.
+func improvesBook(ob *orderbook.Orderbook, order, replaced *orderbook.Order) bool {
+	if ob == nil {
+		return true
+	}
+	if crossesBook(ob, order) {
+		return false
+	}
+
+	best, ok := ob.BestLimitExcluding(order.Side, func(o *orderbook.Order) bool {
+		return o.Hidden || o == replaced
+	})
+	if !ok {
+		return true
+	}
+
+	ticks := utils.PriceToTicks(order.Price, ob.PriceTick())
+	if order.Side == orderbook.Bid {
+		return ticks > best.PriceTicks
+	}
+	return ticks < best.PriceTicks
+}
+
+// newLimitOrder validates a limit order and creates it, without touching
+// funds or the book.
+func (e *Engine) newLimitOrder(userID string, pair Pair, side orderbook.Side, price, amount float64, opts OrderOptions) (*orderbook.Order, PairConfig, error) {
 
 	// 1. Basic validation
+	if e.halted.Load() {
+		return nil, PairConfig{}, ErrEngineHalted
+	}
 	if !pair.IsValid() {
-		return nil, nil, ErrInvalidPair
+		return nil, PairConfig{}, ErrInvalidPair
+	}
+	if userID == e.config.FeeAccount {
+		return nil, PairConfig{}, ErrReservedUserID
+	}
+	if len(opts.Tag) > MaxTagLength {
+		return nil, PairConfig{}, ErrTagTooLong
+	}
+
+	cfg, err := e.listedPairConfig(pair)
+	if err != nil {
+		return nil, PairConfig{}, err
+	}
+	if cfg.Halted {
+		return nil, PairConfig{}, ErrPairHalted
 	}
 
 	// Normalize and validate price
-	price = utils.FloorToTick(price, PriceTick)
-	if !utils.IsValidTick(price, PriceTick) {
-		return nil, nil, ErrInvalidPriceTick
+	price, ok := e.normalizeToTick(price, cfg.PriceTick)
+	if !ok {
+		return nil, PairConfig{}, ErrInvalidPriceTick
 	}
 
 	// Normalize and validate amount
-	amount = utils.FloorToTick(amount, AmountTick)
-	if !utils.IsValidTick(amount, AmountTick) {
-		return nil, nil, ErrInvalidAmountTick
+	amount, ok = e.normalizeToTick(amount, cfg.AmountTick)
+	if !ok {
+		return nil, PairConfig{}, ErrInvalidAmountTick
+	}
+	if !cfg.lotAligned(amount) {
+		return nil, PairConfig{}, ErrInvalidAmountLot
 	}
 
 	// 2. Create order
-	order, err := orderbook.NewOrder(userID, side, price, amount)
+	order, err := orderbook.NewOrderAt(userID, side, price, amount, e.config.Clock.Now())
 	if err != nil {
-		return nil, nil, err
+		return nil, PairConfig{}, err
+	}
+	e.stampOrder(order)
+	order.Hidden = opts.Hidden
+	order.MaxLevels = e.config.MaxLevelsPerOrder
+	order.Tag = opts.Tag
+	order.SelfTrade = opts.AllowSelfTrade
+
+	if !opts.ExpiresAt.IsZero() {
+		if !opts.ExpiresAt.After(order.Timestamp) {
+			return nil, PairConfig{}, ErrInvalidExpiry
+		}
+		order.ExpiresAt = opts.ExpiresAt
 	}
 
-	// 3. Decide which asset and how much to lock
-	var lockAsset string
-	var lockAmount float64
+	// Enforce pair minimums
+	if amount < cfg.MinOrderSize {
+		return nil, PairConfig{}, ErrBelowMinOrderSize
+	}
+	if price*amount < cfg.MinNotional {
+		return nil, PairConfig{}, ErrBelowMinNotional
+	}
 
-	if side == orderbook.Bid {
+	if err := e.checkReservedID(userID, opts.OrderID); err != nil {
+		return nil, PairConfig{}, err
+	}
+
+	return order, cfg, nil
+}
+
+// orderLock returns the asset and amount a new limit order must lock.
+func (e *Engine) orderLock(pair Pair, order *orderbook.Order) (string, float64) {
+	if order.Side == orderbook.Bid {
 		// BUY: lock quote currency (BRL)
-		lockAsset = pair.Quote
-		lockAmount = order.Price * order.Amount
-	} else {
-		// SELL: lock base currency (BTC)
-		lockAsset = pair.Base
-		lockAmount = order.Amount
+		return pair.Quote, e.bidReserve(pair, order.Price, order.Amount)
 	}
+	// SELL: lock base currency (BTC)
+	return pair.Base, order.Amount
+}
 
-	// Lock funds
-	if err := e.accounts.Lock(userID, lockAsset, lockAmount); err != nil {
+// submitLimitOrder places order, whose lockAmount of lockAsset is already
+// locked, and settles what it matches. The lock is released if the order is
+// refused. A non-zero reservedID, from ReserveOrderID, is given to the order
+// once it is accepted. Must be called with e.mu held.
+func (e *Engine) submitLimitOrder(pair Pair, cfg PairConfig, order *orderbook.Order, lockAsset string, lockAmount float64, reservedID int64) (*orderbook.Order, []orderbook.Match, error) {
+	userID := order.UserID
+
+	if err := e.checkTradable(pair); err != nil {
+		_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
 		return nil, nil, err
 	}
+	ob := e.getOrCreateOrderbook(pair)
 
-	e.mu.Lock()
-	defer e.mu.Unlock()
+	// Only the kind of order the pair's mode accepts
+	if err := checkPairMode(cfg, ob, order); err != nil {
+		_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
+		return nil, nil, err
+	}
 
-	ob := e.getOrCreateOrderbook(pair)
+	// 3. Self-trade prevention before matching against others
+	if err := e.preventSelfTrade(pair, ob, order); err != nil {
+		_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
+		return nil, nil, err
+	}
+
+	// 4. Enforce the open-order cap when the order is going to rest
+	if err := e.checkOpenOrderLimit(ob, order, cfg); err != nil {
+		_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
+		return nil, nil, err
+	}
+
+	// A call auction only queues orders, so nothing there takes
+	taker := cfg.Mode != PairModeAuction && crossesBook(ob, order)
+	if err := e.checkTakerLimit(userID, taker); err != nil {
+		_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
+		return nil, nil, err
+	}
 
-	// Place order and try to match
-	matches := ob.PlaceLimitOrder(order)
+	// Accepted: only now spend the reserved ID and the taker budget
+	if err := e.claimReservedID(order, reservedID); err != nil {
+		_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
+		return nil, nil, err
+	}
+	e.countTaker(userID, taker)
+
+	// Place order and try to match; a call auction only queues it until
+	// RunAuction
+	var matches []orderbook.Match
+	if cfg.Mode == PairModeAuction {
+		ob.RestLimitOrder(order)
+	} else {
+		start := time.Now()
+		matches = ob.PlaceLimitOrder(order)
+		e.stats.observeMatching(pair, orderbook.OrderTypeLimit, time.Since(start))
+	}
 
 	// 5. Execute balance transfers for each match
-	for _, match := range matches {
-		if err := e.executeTransfer(pair, match); err != nil {
+	quotes := e.matchQuotes(pair, order, matches)
+	for i, match := range matches {
+		if err := e.executeTransfer(pair, match, order.Side, quotes[i]); err != nil {
 			// Best-effort: unlock the initial lock so user won't get stuck
 			_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
 			return nil, nil, fmt.Errorf("transfer failed: %w", err)
 		}
 	}
+	e.recordFills(pair, order, matches)
 
 	// 6. Refund price improvement for BUY orders
-	if err := e.refundBidDifference(userID, pair, order, matches); err != nil {
+	if err := e.refundBidDifference(userID, pair, order, quotes); err != nil {
 		// Best-effort: unlock the initial lock so user won't get stuck
 		_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
 		return nil, nil, fmt.Errorf("refund failed: %w", err)
 	}
 
+	// 7. Release the remainder of an order the level cap stopped short
+	if order.State == orderbook.OrderCancelled {
+		if err := e.unlockRemaining(pair, order); err != nil {
+			return nil, nil, fmt.Errorf("unlock failed: %w", err)
+		}
+	}
+
+	// 8. Cancel unfillable remainders left behind by the matches
+	if err := e.sweepDust(pair, ob, cfg, order, matches); err != nil {
+		return nil, nil, fmt.Errorf("dust sweep failed: %w", err)
+	}
+
+	// 9. Nothing rests on a taker-only pair: cancel what did not fill
+	if cfg.Mode == PairModeTakerOnly && (order.State == orderbook.OrderOpen || order.State == orderbook.OrderPartiallyFilled) {
+		if _, err := e.cancelResting(pair, ob, order.ID); err != nil {
+			return nil, nil, fmt.Errorf("cancel remainder failed: %w", err)
+		}
+	}
+
+	e.scheduleExpiry(pair, order)
+
 	return order, matches, nil
 }
 
-func (e *Engine) CancelOrder(userID string, pair Pair, orderID int64) (*orderbook.Order, error) {
+// CancelOrder cancels a resting order of userID and unlocks what it still
+// held. It returns the archived order, which reports the asset and amount
+// unlocked.
+func (e *Engine) CancelOrder(userID string, pair Pair, orderID int64) (*ArchivedOrder, error) {
+	return e.CancelOrderWithNonce(userID, pair, orderID, 0)
+}
+
+// CancelOrderWithNonce is CancelOrder for a request carrying nonce, which
+// must be above the last one userID used; 0 means none. See CheckNonce.
+func (e *Engine) CancelOrderWithNonce(userID string, pair Pair, orderID int64, nonce uint64) (*ArchivedOrder, error) {
 	if !pair.IsValid() {
 		return nil, ErrInvalidPair
 	}
+	if err := e.CheckNonce(userID, nonce); err != nil {
+		return nil, err
+	}
 
 	// Use a single critical section to avoid races with PlaceOrder/matching.
+	// An order filled before we got the lock has already left the book (and
+	// released its funds through the fills), so it is reported as not found
+	// and nothing is unlocked twice. Pegs follow once the lock is released.
+	defer e.serialize()()
+	defer e.repricePegs()
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
@@ -144,59 +464,278 @@ func (e *Engine) CancelOrder(userID string, pair Pair, orderID int64) (*orderboo
 		return nil, ErrUnauthorized
 	}
 
-	// Cancel order in orderbook
-	cancelledOrder, err := ob.CancelOrder(orderID)
+	// Cancel order in orderbook and unlock remaining balance.
+	// For the challenge: fail-fast so we don't hide inconsistencies
+	archived, err := e.cancelResting(pair, ob, orderID)
 	if err != nil {
 		return nil, err
 	}
+	return &archived, nil
+}
 
-	// Unlock remaining balance
-	var unlockAsset string
-	var unlockAmount float64
+// ForceCancelOrder cancels a resting order whoever owns it, unlocking the
+// owner's remaining funds. It is meant for operators; user-facing cancels go
+// through CancelOrder.
+func (e *Engine) ForceCancelOrder(pair Pair, orderID int64) (*orderbook.Order, error) {
+	if !pair.IsValid() {
+		return nil, ErrInvalidPair
+	}
 
-	if cancelledOrder.Side == orderbook.Bid {
-		unlockAsset = pair.Quote
-		unlockAmount = cancelledOrder.RemainingAmount() * cancelledOrder.Price
-	} else {
-		unlockAsset = pair.Base
-		unlockAmount = cancelledOrder.RemainingAmount()
+	defer e.serialize()()
+	defer e.repricePegs()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ob, exists := e.orderbooks[pair.String()]
+	if !exists {
+		return nil, ErrOrderNotFound
+	}
+
+	// An order no longer in the book comes back as ErrOrderNotFound
+	archived, err := e.cancelResting(pair, ob, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return &archived.Order, nil
+}
+
+// CancelAllUserOrders cancels every resting order of userID across all pairs
+// and unlocks their remaining funds. Orders are cancelled pair by pair in
+// symbol order, oldest first, and returned as their archived snapshots. On
+// error the orders cancelled so far are returned with it.
+func (e *Engine) CancelAllUserOrders(userID string) ([]ArchivedOrder, error) {
+	defer e.serialize()()
+	defer e.repricePegs()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keys := make([]string, 0, len(e.orderbooks))
+	for key := range e.orderbooks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var cancelled []ArchivedOrder
+	for _, key := range keys {
+		ob := e.orderbooks[key]
+		base, quote, _ := strings.Cut(key, "/")
+		pair := Pair{Base: base, Quote: quote}
+
+		for _, order := range ob.UserOrders(userID) {
+			archived, err := e.cancelResting(pair, ob, order.ID)
+			if err != nil {
+				return cancelled, err
+			}
+			cancelled = append(cancelled, archived)
+		}
+	}
+
+	return cancelled, nil
+}
+
+// CancelOrdersOlderThan cancels every resting order on pair placed more than
+// age ago, whoever owns it, and unlocks their remaining funds. Orders are
+// cancelled oldest first and returned as their archived snapshots. On error
+// the orders cancelled so far are returned with it.
+func (e *Engine) CancelOrdersOlderThan(pair Pair, age time.Duration) ([]ArchivedOrder, error) {
+	if !pair.IsValid() {
+		return nil, ErrInvalidPair
+	}
+
+	defer e.serialize()()
+	defer e.repricePegs()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ob, exists := e.orderbooks[pair.String()]
+	if !exists {
+		return nil, nil
+	}
+
+	cutoff := e.config.Clock.Now().Add(-age)
+	var cancelled []ArchivedOrder
+	for _, order := range ob.OrdersPlacedBefore(cutoff) {
+		archived, err := e.cancelResting(pair, ob, order.ID)
+		if err != nil {
+			return cancelled, err
+		}
+		cancelled = append(cancelled, archived)
+	}
+	return cancelled, nil
+}
+
+// cancelResting removes a resting order from ob, unlocks what it still held
+// and archives it, returning the archived snapshot. Must be called with e.mu
+// held.
+func (e *Engine) cancelResting(pair Pair, ob *orderbook.Orderbook, orderID int64) (ArchivedOrder, error) {
+	return e.closeResting(pair, ob, orderID, orderbook.OrderCancelled)
+}
+
+// closeResting is cancelResting with the terminal state the order is archived
+// in. It fails with ErrOrderNotFound when orderID is not in the book. Must be
+// called with e.mu held.
+func (e *Engine) closeResting(pair Pair, ob *orderbook.Orderbook, orderID int64, state orderbook.OrderState) (ArchivedOrder, error) {
+	order, err := ob.CancelOrder(orderID)
+	if errors.Is(err, orderbook.ErrOrderNotFound) {
+		return ArchivedOrder{}, ErrOrderNotFound
+	}
+	if err != nil {
+		return ArchivedOrder{}, err
+	}
+	order.State = state
+	unlockAsset, unlockAmount := e.remainingLock(pair, order)
+	unlockAmount = e.roundAsset(unlockAsset, unlockAmount)
+	if err := e.unlockRemaining(pair, order); err != nil {
+		return ArchivedOrder{}, err
+	}
+
+	return e.archiveUnlocked(pair, order, unlockAsset, unlockAmount), nil
+}
+
+// unlockRemaining releases the funds still reserved by a cancelled order.
+func (e *Engine) unlockRemaining(pair Pair, order *orderbook.Order) error {
+	unlockAsset, unlockAmount := e.remainingLock(pair, order)
+	if unlockAmount <= 0 {
+		return nil
+	}
+	return e.accounts.Unlock(order.UserID, unlockAsset, unlockAmount)
+}
+
+// remainingLock returns the asset and amount order's unfilled remainder
+// keeps locked.
+func (e *Engine) remainingLock(pair Pair, order *orderbook.Order) (string, float64) {
+	if order.Side == orderbook.Bid {
+		return pair.Quote, e.bidReserve(pair, order.Price, order.RemainingAmount())
+	}
+	return pair.Base, order.RemainingAmount()
+}
+
+// preventSelfTrade applies the configured STP mode when order would cross
+// resting orders of the same user. Must be called with e.mu held.
+func (e *Engine) preventSelfTrade(pair Pair, ob *orderbook.Orderbook, order *orderbook.Order) error {
+	if order.SelfTrade {
+		return nil
+	}
+	crossing := ob.SelfCrossingOrders(order)
+	if len(crossing) == 0 {
+		return nil
 	}
 
-	if unlockAmount > 0 {
-		if err := e.accounts.Unlock(userID, unlockAsset, unlockAmount); err != nil {
-			// For the challenge: fail-fast so we don't hide inconsistencies
-			return nil, err
+	if e.config.STPMode == STPCancelOldest || e.config.STPMode == STPCancelBoth {
+		for _, resting := range crossing {
+			if _, err := e.cancelResting(pair, ob, resting.ID); err != nil {
+				return err
+			}
 		}
 	}
 
-	return cancelledOrder, nil
+	if e.config.STPMode == STPCancelOldest {
+		return nil
+	}
+	return ErrSelfTrade
 }
 
 func (e *Engine) PlaceMarketOrder(userID string, pair Pair, side orderbook.Side, amount float64) (*orderbook.Order, []orderbook.Match, error) {
+	return e.PlaceMarketOrderWithOptions(userID, pair, side, amount, OrderOptions{})
+}
+
+func (e *Engine) PlaceMarketOrderWithOptions(userID string, pair Pair, side orderbook.Side, amount float64, opts OrderOptions) (order *orderbook.Order, matches []orderbook.Match, err error) {
+	defer e.serialize()()
+	defer func() {
+		if err != nil {
+			e.recordRejection(userID, pair, side, orderbook.OrderTypeMarket, 0, amount, opts.Tag, err)
+		}
+	}()
+
+	// A halt refuses the order before it can spend the nonce
+	if e.halted.Load() {
+		return nil, nil, ErrEngineHalted
+	}
+	if err := e.CheckNonce(userID, opts.Nonce); err != nil {
+		return nil, nil, err
+	}
+
+	order, matches, err = e.placeMarketOrder(userID, pair, side, amount, opts)
+	e.runTriggeredStops()
+	e.repricePegs()
+	return order, matches, err
+}
+
+func (e *Engine) placeMarketOrder(userID string, pair Pair, side orderbook.Side, amount float64, opts OrderOptions) (*orderbook.Order, []orderbook.Match, error) {
+	if e.halted.Load() {
+		return nil, nil, ErrEngineHalted
+	}
 	if !pair.IsValid() {
 		return nil, nil, ErrInvalidPair
 	}
+	if userID == e.config.FeeAccount {
+		return nil, nil, ErrReservedUserID
+	}
+	if !opts.ExpiresAt.IsZero() {
+		return nil, nil, ErrInvalidExpiry
+	}
+	if len(opts.Tag) > MaxTagLength {
+		return nil, nil, ErrTagTooLong
+	}
+
+	cfg, err := e.listedPairConfig(pair)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Halted {
+		return nil, nil, ErrPairHalted
+	}
+	if cfg.Mode == PairModeMakerOnly {
+		return nil, nil, ErrMakerOnly
+	}
+	if cfg.Mode == PairModeTakerOnly && opts.RestRemainder {
+		return nil, nil, ErrTakerOnly
+	}
+	if cfg.Mode == PairModeAuction {
+		return nil, nil, ErrAuctionMode
+	}
 
 	// Normalize amount
-	amount = utils.FloorToTick(amount, AmountTick)
-	if !utils.IsValidTick(amount, AmountTick) {
+	amount, ok := e.normalizeToTick(amount, cfg.AmountTick)
+	if !ok {
 		return nil, nil, ErrInvalidAmountTick
 	}
+	if !cfg.lotAligned(amount) {
+		return nil, nil, ErrInvalidAmountLot
+	}
 
 	// 2. Create market order
-	order, err := orderbook.NewMarketOrder(userID, side, amount)
+	order, err := orderbook.NewMarketOrderAt(userID, side, amount, e.config.Clock.Now())
 	if err != nil {
 		return nil, nil, err
 	}
+	e.stampOrder(order)
+	order.MaxLevels = e.config.MaxLevelsPerOrder
+	order.MaxGap = cfg.MaxMarketGap
+	order.Tag = opts.Tag
+	order.SelfTrade = opts.AllowSelfTrade
+
+	if amount < cfg.MinOrderSize {
+		return nil, nil, ErrBelowMinOrderSize
+	}
+	if err := e.checkReservedID(userID, opts.OrderID); err != nil {
+		return nil, nil, err
+	}
+	e.delayTaker(pair, side, 0)
 
-	// 3. Estimate cost
+	// 3. Estimate cost, leaving out the user's own orders unless it may
+	// trade with them
+	liquidityFor := userID
+	if opts.AllowSelfTrade {
+		liquidityFor = ""
+	}
 	e.mu.RLock()
 	ob := e.getOrCreateOrderbook(pair)
-	estimatedCost := e.estimateMarketOrderCost(ob, side, amount)
+	estimatedCost := e.estimateMarketOrderCost(ob, liquidityFor, side, amount, cfg.AmountTick, cfg.MaxMarketGap, opts.RestRemainder)
 	e.mu.RUnlock()
 
 	if estimatedCost == 0 {
-		return nil, nil, errors.New("insufficient liquidity for market order")
+		return nil, nil, ErrInsufficientLiquidity
 	}
 
 	// 4. Decide which asset and how much to lock
@@ -222,68 +761,253 @@ func (e *Engine) PlaceMarketOrder(userID string, pair Pair, side orderbook.Side,
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	if err := e.checkTradable(pair); err != nil {
+		_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
+		return nil, nil, err
+	}
+	if err := e.checkTakerLimit(userID, true); err != nil {
+		_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
+		return nil, nil, err
+	}
+	if err := e.claimReservedID(order, opts.OrderID); err != nil {
+		_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
+		return nil, nil, err
+	}
 	ob = e.getOrCreateOrderbook(pair)
+	start := time.Now()
 	matches := ob.PlaceMarketOrder(order)
+	e.stats.observeMatching(pair, orderbook.OrderTypeMarket, time.Since(start))
+
+	// The book may have changed since the estimate; an order that executed
+	// nothing is rejected rather than archived open.
+	if len(matches) == 0 {
+		if err := e.accounts.Unlock(userID, lockAsset, lockAmount); err != nil {
+			return nil, nil, fmt.Errorf("unlock failed: %w", err)
+		}
+		return nil, nil, ErrInsufficientLiquidity
+	}
+	e.countTaker(userID, true)
 
 	// 7. Execute transfer
-	for _, match := range matches {
-		if err := e.executeTransfer(pair, match); err != nil {
+	quotes := e.matchQuotes(pair, order, matches)
+	for i, match := range matches {
+		if err := e.executeTransfer(pair, match, order.Side, quotes[i]); err != nil {
 			// Unlock for do not leave user lock
 			_ = e.accounts.Unlock(userID, lockAsset, lockAmount)
 			return nil, nil, fmt.Errorf("transfer failed: %w", err)
 		}
 	}
 
-	// 8. Refund/unlock unused amount
-	if side == orderbook.Bid {
-		// BUY market: unlock quote that was not spent
-		executedQuote := 0.0
-		for _, m := range matches {
-			executedQuote += m.SizeFilled * m.Price
+	// Optionally rest what the book could not fill
+	rested := false
+	if opts.RestRemainder {
+		rested = e.restRemainder(pair, ob, cfg, order, matches, quotes, lockAmount)
+	}
+	e.recordFills(pair, order, matches)
+
+	// 8. Refund/unlock unused amount. A rested remainder keeps its lock;
+	// restRemainder already settled it.
+	if !rested {
+		if side == orderbook.Bid {
+			// BUY market: unlock quote that was not spent
+			refund := e.roundAsset(pair.Quote, lockAmount-sumQuotes(quotes))
+
+			if refund > 0 {
+				if err := e.accounts.Unlock(userID, pair.Quote, refund); err != nil {
+					return nil, nil, fmt.Errorf("refund unlock failed: %w", err)
+				}
+			}
+		} else {
+			// SELL market: unlock base that was not sold
+			unfilledAmount := order.RemainingAmount()
+
+			if unfilledAmount > 0 {
+				if err := e.accounts.Unlock(userID, pair.Base, unfilledAmount); err != nil {
+					return nil, nil, fmt.Errorf("unlock unfilled failed: %w", err)
+				}
+			}
 		}
-		refund := lockAmount - executedQuote
+	}
+
+	// 9. Cancel unfillable maker remainders
+	if err := e.sweepDust(pair, ob, cfg, order, matches); err != nil {
+		return nil, nil, fmt.Errorf("dust sweep failed: %w", err)
+	}
 
-		const minRefundBRL = 0.01
-		if refund >= minRefundBRL {
-			if err := e.accounts.Unlock(userID, pair.Quote, refund); err != nil {
-				return nil, nil, fmt.Errorf("refund unlock failed: %w", err)
+	return order, matches, nil
+}
+
+// restRemainder turns the unfilled part of a market order into a resting
+// limit at its last execution price and adjusts the lock to what that limit
+// needs, holding back a refund too small to unlock as any resting bid does.
+// It reports false, leaving the order to be cancelled as usual, when nothing
+// executed, what is left falls below the pair's minimum size or notional,
+// the open-order cap is reached, the remainder would cross the user's own
+// resting orders (matching skipped them, so they can sit inside the last
+// execution price) or the user cannot cover the extra lock. Must be called
+// with e.mu held.
+func (e *Engine) restRemainder(pair Pair, ob *orderbook.Orderbook, cfg PairConfig, order *orderbook.Order, matches []orderbook.Match, quotes []float64, lockAmount float64) bool {
+	remaining := order.RemainingAmount()
+	if len(matches) == 0 || remaining < cfg.AmountTick {
+		return false
+	}
+	lastPrice := matches[len(matches)-1].Price
+	if remaining < cfg.MinOrderSize || lastPrice*remaining < cfg.MinNotional {
+		return false
+	}
+	if e.config.MaxOpenOrdersPerUser > 0 && ob.OpenOrderCount(order.UserID) >= e.config.MaxOpenOrdersPerUser {
+		return false
+	}
+
+	probe := *order
+	probe.Price = lastPrice
+	if len(ob.SelfCrossingOrders(&probe)) > 0 {
+		return false
+	}
+
+	// An ask locked its full amount up front, so the remainder is already
+	// covered. A bid keeps remaining*lastPrice locked and settles the rest.
+	if order.Side == orderbook.Bid {
+		spare := e.roundAsset(pair.Quote, lockAmount-sumQuotes(quotes))
+		needed := e.bidReserve(pair, lastPrice, remaining)
+
+		if spare < needed {
+			if err := e.accounts.Lock(order.UserID, pair.Quote, needed-spare); err != nil {
+				return false
 			}
+		} else if err := e.releaseRefund(pair, order, e.roundAsset(pair.Quote, spare-needed)); err != nil {
+			return false
 		}
-	} else {
-		// SELL market: unlock base that was not sold
-		unfilledAmount := order.RemainingAmount()
+	}
 
-		const minRefundBTC = 0.00000001
-		if unfilledAmount >= minRefundBTC {
-			if err := e.accounts.Unlock(userID, pair.Base, unfilledAmount); err != nil {
-				return nil, nil, fmt.Errorf("unlock unfilled failed: %w", err)
-			}
+	order.Type = orderbook.OrderTypeLimit
+	order.Price = lastPrice
+	ob.PlaceLimitOrder(order)
+	return true
+}
+
+// sweepDust cancels order and the makers it traded with when they are still
+// resting with a remainder below the pair's MinOrderSize, unlocking the funds
+// held for that remainder. Must be called with e.mu held.
+func (e *Engine) sweepDust(pair Pair, ob *orderbook.Orderbook, cfg PairConfig, order *orderbook.Order, matches []orderbook.Match) error {
+	if !e.config.SweepDust || cfg.MinOrderSize <= 0 || len(matches) == 0 {
+		return nil
+	}
+
+	candidates := make([]*orderbook.Order, 0, len(matches)+1)
+	for _, m := range matches {
+		if m.Bid == order {
+			candidates = append(candidates, m.Ask)
+		} else {
+			candidates = append(candidates, m.Bid)
 		}
 	}
+	candidates = append(candidates, order)
 
-	return order, matches, nil
+	for _, o := range candidates {
+		remaining := o.RemainingAmount()
+		if remaining <= 0 || remaining >= cfg.MinOrderSize {
+			continue
+		}
+		// Skip orders that are not resting: fully filled, market, or already swept
+		if _, exists := ob.GetOrder(o.ID); !exists {
+			continue
+		}
+
+		if _, err := e.cancelResting(pair, ob, o.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkOpenOrderLimit rejects order when it would rest in the book and its
+// owner already has the configured maximum of resting orders on this pair.
+// Must be called with e.mu held.
+func (e *Engine) checkOpenOrderLimit(ob *orderbook.Orderbook, order *orderbook.Order, cfg PairConfig) error {
+	if e.config.MaxOpenOrdersPerUser <= 0 {
+		return nil
+	}
+
+	est := ob.EstimateLimitFill(order.Side, order.Price, order.Amount)
+	if isFullyFilled(est, order.Amount, cfg.AmountTick) {
+		return nil
+	}
+
+	if ob.OpenOrderCount(order.UserID) >= e.config.MaxOpenOrdersPerUser {
+		return ErrTooManyOpenOrders
+	}
+	return nil
+}
+
+// normalizeToTick applies the configured tick policy to val and reports
+// whether the result is aligned to tick.
+func (e *Engine) normalizeToTick(val, tick float64) (float64, bool) {
+	if !validTick(tick) {
+		return val, false
+	}
+
+	switch e.config.TickPolicy {
+	case TickRound:
+		val = utils.RoundToTick(val, tick)
+	case TickReject:
+		// Keep the value as sent; misalignment is rejected below.
+	default:
+		val = utils.FloorToTick(val, tick)
+	}
+	return val, utils.IsValidTick(val, tick)
 }
 
-func (e *Engine) estimateMarketOrderCost(ob *orderbook.Orderbook, side orderbook.Side, amount float64) float64 {
+// estimateMarketOrderCost returns what a market order of amount must lock,
+// or 0 when the book cannot fill it. With partial set, a book that can fill
+// only part of it is enough: a bid then also covers the unfilled rest at the
+// worst price it reaches, since that rest will be resting there. Resting
+// orders of userID are left out, as matching skips them. maxGap is the pair's
+// MaxMarketGap.
+//
+// Volumes are counted in whole amountTicks rather than summed as floats, so a
+// book holding exactly amount is enough however large the numbers get.
+func (e *Engine) estimateMarketOrderCost(ob *orderbook.Orderbook, userID string, side orderbook.Side, amount, amountTick, maxGap float64, partial bool) float64 {
 	if ob == nil {
 		return 0
 	}
 
+	// Levels past the cap or a gap will not be reached, and the shortfall
+	// they leave is the cap's doing rather than missing liquidity.
+	maxLevels := e.config.MaxLevelsPerOrder
+	capped := false
+	prevPrice := 0.0
+	gapped := func(price float64) bool {
+		return maxGap > 0 && prevPrice > 0 && math.Abs(price-prevPrice)/prevPrice > maxGap
+	}
+
+	wanted := utils.AmountToTicks(amount, amountTick)
+
 	if side == orderbook.Ask {
 		// SELL market: validate liquidity enough
-		remaining := amount
+		remaining := wanted
 
-		for _, bidLimit := range ob.Bids() {
-			if remaining <= 0.00000001 {
+		for i, bidLimit := range ob.Bids() {
+			if remaining <= 0 {
 				break
 			}
+			if maxLevels > 0 && i == maxLevels {
+				capped = true
+				break
+			}
+			bidPrice := bidLimit.Price(ob.PriceTick())
+			if gapped(bidPrice) {
+				capped = true
+				break
+			}
+			prevPrice = bidPrice
 
-			fillQty := min(remaining, bidLimit.TotalVolume)
-			remaining -= fillQty
+			remaining -= min(remaining, utils.AmountToTicks(bidLimit.VolumeAgainst(userID), amountTick))
 		}
 
 		// if remaining > 0, means there is not enough liquidity
-		if remaining > 0.00000001 {
+		if remaining > 0 && (!(partial || capped) || remaining == wanted) {
 			return 0
 		}
 
@@ -292,39 +1016,146 @@ func (e *Engine) estimateMarketOrderCost(ob *orderbook.Orderbook, side orderbook
 
 	// BUY market: estimate Buy market order
 	cost := 0.0
-	remaining := amount
+	remaining := wanted
+	worstPrice := 0.0
 
-	for _, askLimit := range ob.Asks() {
-		if remaining <= 0.00000001 {
+	for i, askLimit := range ob.Asks() {
+		if remaining <= 0 {
+			break
+		}
+		if maxLevels > 0 && i == maxLevels {
+			capped = true
+			break
+		}
+		askPrice := askLimit.Price(ob.PriceTick())
+		if gapped(askPrice) {
+			capped = true
 			break
 		}
+		prevPrice = askPrice
 
-		askPrice := askLimit.Price(PriceTick)
-		fillQty := min(remaining, askLimit.TotalVolume)
+		fillTicks := min(remaining, utils.AmountToTicks(askLimit.VolumeAgainst(userID), amountTick))
+		if fillTicks <= 0 {
+			continue
+		}
 
-		cost += fillQty * askPrice
-		remaining -= fillQty
+		cost += utils.TicksToAmount(fillTicks, amountTick) * askPrice
+		remaining -= fillTicks
+		worstPrice = askPrice
 	}
 
 	// if have no enough liquidity, reject
-	if remaining > 0.00000001 {
-		return 0
+	if remaining > 0 {
+		if worstPrice == 0 || !(partial || capped) {
+			return 0
+		}
+		if partial {
+			cost += utils.TicksToAmount(remaining, amountTick) * worstPrice
+		}
 	}
 
-	return utils.RoundToTick(cost, PriceTick)
+	return utils.RoundToTick(cost, ob.PriceTick())
+}
+
+// roundAsset rounds amount to asset's registered precision, the rounding the
+// account manager applies to every balance move.
+func (e *Engine) roundAsset(asset string, amount float64) float64 {
+	decimals, ok := e.accounts.Precision(asset)
+	if !ok {
+		return amount
+	}
+	scale := math.Pow10(decimals)
+	return math.Round(amount*scale) / scale
+}
+
+// bidReserve returns the quote a bid at price keeps locked for remaining
+// units of base. A resting bid always holds exactly this much, so what it
+// locked when placed is bidReserve of its amount and what a cancel releases
+// is bidReserve of its remainder.
+func (e *Engine) bidReserve(pair Pair, price, remaining float64) float64 {
+	return e.roundAsset(pair.Quote, price*e.roundAsset(pair.Base, remaining))
 }
 
-func (e *Engine) executeTransfer(pair Pair, match orderbook.Match) error {
+// matchQuotes returns the quote each of taker's matches moves from the buyer
+// to the seller. For a limit bid that is the part of its reserve the match
+// consumes, so the reserve shrinks to bidReserve of the new remainder with no
+// rounding left over; a fill below the bid's price pays the rounded trade
+// value instead, capped at that part, and the difference is refunded.
+func (e *Engine) matchQuotes(pair Pair, taker *orderbook.Order, matches []orderbook.Match) []float64 {
+	quotes := make([]float64, len(matches))
+	takerRemaining := taker.Amount
+
+	for i, m := range matches {
+		takerRemaining -= m.SizeFilled
+
+		bid := m.Bid
+		quote := e.roundAsset(pair.Quote, m.SizeFilled*m.Price)
+		if bid.Type == orderbook.OrderTypeMarket {
+			quotes[i] = quote
+			continue
+		}
+
+		bidRemaining := bid.RemainingAmount()
+		if bid == taker {
+			bidRemaining = takerRemaining
+		}
+		consumed := e.roundAsset(pair.Quote,
+			e.bidReserve(pair, bid.Price, bidRemaining+m.SizeFilled)-e.bidReserve(pair, bid.Price, bidRemaining))
+
+		if m.Price == bid.Price || quote > consumed {
+			quote = consumed
+		}
+		quotes[i] = quote
+	}
+	return quotes
+}
+
+func sumQuotes(quotes []float64) float64 {
+	total := 0.0
+	for _, q := range quotes {
+		total += q
+	}
+	return total
+}
+
+// executeTransfer settles one match, moving quoteAmount (from matchQuotes,
+// already rounded to the quote's precision) from the buyer to the seller. The
+// buyer is debited exactly what the seller and the fee account are credited
+// between them. Each side pays its fee out of what it receives: the seller in
+// quote, the buyer in base. Fees are rounded once, by roundFee, so whatever
+// rounding leaves over goes to the side Config.FeeRounding picks and no
+// fraction of either asset is created or lost. A negative rate is a rebate,
+// paid in the same asset once the match's fees are collected. A self-trade
+// pays no fees, so it only moves each asset from locked to available.
+//
+// A match too small to be worth a unit of quote, so that quoteAmount is 0,
+// settles neither leg: the buyer pays and receives nothing, and the seller
+// keeps the base, which is unlocked as the match took it off the order. Must
+// be called with e.mu held.
+func (e *Engine) executeTransfer(pair Pair, match orderbook.Match, takerSide orderbook.Side, quoteAmount float64) error {
 	buyer := match.Bid.UserID
 	seller := match.Ask.UserID
 	baseAmount := match.SizeFilled
-	quoteAmount := match.SizeFilled * match.Price
+
+	if quoteAmount <= 0 {
+		if err := e.accounts.Unlock(seller, pair.Base, baseAmount); err != nil {
+			return fmt.Errorf("seller unlock failed: %w", err)
+		}
+		return nil
+	}
+
+	var sellerFee, buyerFee float64
+	if buyer != seller {
+		// A fee never takes more than the fill, however it rounds
+		sellerFee = min(e.roundFee(pair.Quote, quoteAmount*e.feeRate(seller, takerSide == orderbook.Ask)), quoteAmount)
+		buyerFee = min(e.roundFee(pair.Base, baseAmount*e.feeRate(buyer, takerSide == orderbook.Bid)), baseAmount)
+	}
 
 	// Seller: debit locked base (BTC), credit quote (BRL)
 	if err := e.accounts.DebitLocked(seller, pair.Base, baseAmount); err != nil {
 		return fmt.Errorf("seller debit locked failed: %w", err)
 	}
-	if err := e.accounts.Credit(seller, pair.Quote, quoteAmount); err != nil {
+	if err := e.accounts.Credit(seller, pair.Quote, quoteAmount-max(sellerFee, 0)); err != nil {
 		return fmt.Errorf("seller credit failed: %w", err)
 	}
 
@@ -332,53 +1163,97 @@ func (e *Engine) executeTransfer(pair Pair, match orderbook.Match) error {
 	if err := e.accounts.DebitLocked(buyer, pair.Quote, quoteAmount); err != nil {
 		return fmt.Errorf("buyer debit locked failed: %w", err)
 	}
-	if err := e.accounts.Credit(buyer, pair.Base, baseAmount); err != nil {
+	if err := e.accounts.Credit(buyer, pair.Base, baseAmount-max(buyerFee, 0)); err != nil {
 		return fmt.Errorf("buyer credit failed: %w", err)
 	}
 
-	return nil
+	if err := e.collectFee(pair.Quote, sellerFee); err != nil {
+		return err
+	}
+	if err := e.collectFee(pair.Base, buyerFee); err != nil {
+		return err
+	}
+
+	if err := e.payRebate(seller, pair.Quote, -sellerFee); err != nil {
+		return err
+	}
+	return e.payRebate(buyer, pair.Base, -buyerFee)
 }
 
-func (e *Engine) refundBidDifference(userID string, pair Pair, order *orderbook.Order, matches []orderbook.Match) error {
+func (e *Engine) refundBidDifference(userID string, pair Pair, order *orderbook.Order, quotes []float64) error {
 	// Refund applies only to BUY orders (BID)
 	// and only when at least one match happened
-	if order.Side != orderbook.Bid || len(matches) == 0 {
+	if order.Side != orderbook.Bid || len(quotes) == 0 {
 		return nil
 	}
 
 	// 1. Calculate how much money was really spent
-	executedQuote := 0.0
-	for _, m := range matches {
-		executedQuote += m.SizeFilled * m.Price
-	}
+	executedQuote := sumQuotes(quotes)
 
 	// 2. Amount locked when the order was created
-	initialLock := order.Price * order.Amount
+	initialLock := e.bidReserve(pair, order.Price, order.Amount)
 
 	// 3. Amount that must stay locked for the remaining order
-	stillLocked := order.Price * order.RemainingAmount()
+	stillLocked := e.bidReserve(pair, order.Price, order.RemainingAmount())
 
 	// 4. Money that must be returned to the user
-	refund := initialLock - executedQuote - stillLocked
+	refund := e.roundAsset(pair.Quote, initialLock-executedQuote-stillLocked)
 
-	// 5. Avoid unlocking very small values caused by float errors.
-	const minRefundBRL = 0.01
+	// 5. Release it, or hold it with the order while it rests
+	return e.releaseRefund(pair, order, refund)
+}
 
-	if refund >= minRefundBRL {
-		if err := e.accounts.Unlock(userID, pair.Quote, refund); err != nil {
-			return err
-		}
+// releaseRefund unlocks a quote refund owed to order's owner. While order
+// rests, a refund below minRefund is held back with it instead, as float
+// noise not worth an unlock of its own, and released when the order leaves
+// the book (see archiveUnlocked). Must be called with e.mu held.
+func (e *Engine) releaseRefund(pair Pair, order *orderbook.Order, refund float64) error {
+	if refund <= 0 {
+		return nil
 	}
+	resting := order.State == orderbook.OrderOpen || order.State == orderbook.OrderPartiallyFilled
+	if resting && refund < e.minRefund(pair.Quote) {
+		e.withheldRefunds[order.ID] += refund
+		return nil
+	}
+	return e.accounts.Unlock(order.UserID, pair.Quote, refund)
+}
 
-	return nil
+// minRefund returns the smallest amount of asset worth unlocking as a refund:
+// the configured Config.MinRefund, or else one unit of the asset's precision.
+func (e *Engine) minRefund(asset string) float64 {
+	if threshold, ok := e.config.MinRefund[asset]; ok {
+		return threshold
+	}
+
+	decimals, ok := e.accounts.Precision(asset)
+	if !ok {
+		decimals = account.MaxPrecision
+	}
+	return math.Pow10(-decimals)
 }
 
 func (e *Engine) GetAccountManager() *account.Manager {
 	return e.accounts
 }
 
+// Balances returns a copy of every balance of userID, by asset, read under
+// the engine lock so no trade is half settled in it.
+func (e *Engine) Balances(userID string) map[string]*account.Balance {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.accounts.GetAllBalances(userID)
+}
+
+// GetOrderbook returns the book of pair, or nil if pair has no book or is not
+// listed in the registry.
 func (e *Engine) GetOrderbook(pair Pair) *orderbook.Orderbook {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
-	return e.orderbooks[pair.String()]
+
+	key := pair.String()
+	if _, listed := e.pairs[key]; !listed && !e.allowsUnlisted() {
+		return nil
+	}
+	return e.orderbooks[key]
 }
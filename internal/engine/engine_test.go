@@ -3,8 +3,10 @@ package engine
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
 )
 
 func TestPair_String(t *testing.T) {
@@ -24,6 +26,15 @@ func TestPair_IsValid(t *testing.T) {
 
 	invalid3 := Pair{Base: "BTC", Quote: "USD"}
 	assertFalse(t, invalid3.IsValid(), "Invalid pair (quote must be BRL)")
+
+	invalid4 := Pair{Base: "BRL", Quote: "BRL"}
+	assertFalse(t, invalid4.IsValid(), "Invalid pair (same asset)")
+
+	invalid5 := Pair{Base: "  ", Quote: "BRL"}
+	assertFalse(t, invalid5.IsValid(), "Invalid pair (whitespace-only base)")
+
+	invalid6 := Pair{Base: " BTC", Quote: "BRL"}
+	assertFalse(t, invalid6.IsValid(), "Invalid pair (padded base)")
 }
 
 // =============================================================================
@@ -174,17 +185,77 @@ func TestEngine_PlaceOrder_SelfTradePrevention(t *testing.T) {
 	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 1)
 	assertNoError(t, err)
 
-	// UserId:1 tries to buy - should NOT match (self-trade prevention)
+	// UserId:1 tries to buy - rejected up front (default STP: cancel newest)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertEqual(t, ErrSelfTrade, err, "Should reject self-crossing order")
+
+	// Book must not be crossed: only the resting ask remains
+	ob := e.GetOrderbook(btcBrl())
+	assertEqual(t, 0, len(ob.Bids()), "Should have no bids")
+	assertEqual(t, 1, len(ob.Asks()), "Should have 1 ask")
+
+	// Lock taken for the rejected bid must be released
+	balance := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 100_000, balance.Available, "BRL available after rejection")
+	assertFloat(t, 0, balance.Locked, "BRL locked after rejection")
+}
+
+func TestEngine_PlaceOrder_STP_CancelOldest(t *testing.T) {
+	e := NewEngineWithConfig(Config{STPMode: STPCancelOldest})
+	_ = e.accounts.Credit("1", "BRL", 100_000)
+	_ = e.accounts.Credit("1", "BTC", 10)
+	_ = e.accounts.Credit("2", "BTC", 10)
+
+	// UserId:1 rests an ask @ 49k, UserId:2 rests an ask @ 50k
+	own, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 49_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+
+	// Aggressive bid cancels the own ask and matches UserId:2
 	order, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
 	assertNoError(t, err)
 
-	assertEqual(t, 0, len(matches), "Should have no matches (self-trade)")
-	assertEqual(t, orderbook.OrderOpen, order.State, "Order should be open")
+	assertEqual(t, orderbook.OrderCancelled, own.State, "Own resting ask should be cancelled")
+	assertEqual(t, 1, len(matches), "Should match the other user")
+	assertEqual(t, "2", matches[0].Ask.UserID, "Counterparty")
+	assertEqual(t, orderbook.OrderFilled, order.State, "Incoming bid should be filled")
+
+	btc := e.accounts.GetBalance("1", "BTC")
+	assertFloat(t, 11, btc.Available, "BTC available (own ask unlocked + bought 1)")
+	assertFloat(t, 0, btc.Locked, "BTC locked")
+}
+
+func TestEngine_PlaceOrder_STP_CancelBoth(t *testing.T) {
+	e := NewEngineWithConfig(Config{STPMode: STPCancelBoth})
+	_ = e.accounts.Credit("1", "BRL", 100_000)
+	_ = e.accounts.Credit("1", "BTC", 10)
+
+	own, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 51_000, 1)
+	assertEqual(t, ErrSelfTrade, err, "Incoming should be rejected")
+	assertEqual(t, orderbook.OrderCancelled, own.State, "Resting ask should be cancelled")
 
-	// Both orders should be in the book
 	ob := e.GetOrderbook(btcBrl())
-	assertEqual(t, 1, len(ob.Bids()), "Should have 1 bid")
-	assertEqual(t, 1, len(ob.Asks()), "Should have 1 ask")
+	assertEqual(t, 0, len(ob.Bids()), "No bids")
+	assertEqual(t, 0, len(ob.Asks()), "No asks")
+
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BTC").Locked, "BTC locked")
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BRL").Locked, "BRL locked")
+}
+
+func TestEngine_PlaceOrder_STP_NonCrossingOwnOrderAllowed(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 51_000, 1)
+	assertNoError(t, err)
+
+	// Bid below own ask does not cross, so it rests normally
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderOpen, order.State, "Order should rest")
 }
 
 // =============================================================================
@@ -206,7 +277,7 @@ func TestEngine_CancelOrder(t *testing.T) {
 	cancelled, err := e.CancelOrder("1", btcBrl(), order.ID)
 	assertNoError(t, err)
 
-	assertEqual(t, orderbook.OrderCancelled, cancelled.State, "Should be cancelled")
+	assertEqual(t, orderbook.OrderCancelled, cancelled.Order.State, "Should be cancelled")
 
 	// Balance should be unlocked
 	balanceAfter := e.accounts.GetBalance("1", "BRL")
@@ -233,6 +304,24 @@ func TestEngine_CancelOrder_Unauthorized(t *testing.T) {
 	assertEqual(t, ErrUnauthorized, err, "Should return unauthorized error")
 }
 
+func TestEngine_ForceCancelOrder(t *testing.T) {
+	e := setupEngine()
+
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 2)
+	assertNoError(t, err)
+
+	cancelled, err := e.ForceCancelOrder(btcBrl(), order.ID)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderCancelled, cancelled.State, "State")
+
+	balance := e.accounts.GetBalance("1", "BTC")
+	assertFloat(t, 10, balance.Available, "Owner's BTC unlocked")
+	assertFloat(t, 0, balance.Locked, "Nothing left locked")
+
+	_, err = e.ForceCancelOrder(btcBrl(), order.ID)
+	assertEqual(t, ErrOrderNotFound, err, "Already cancelled")
+}
+
 func TestEngine_CancelOrder_PartiallyFilled(t *testing.T) {
 	e := setupEngine()
 
@@ -248,8 +337,8 @@ func TestEngine_CancelOrder_PartiallyFilled(t *testing.T) {
 	cancelled, err := e.CancelOrder("2", btcBrl(), order.ID)
 	assertNoError(t, err)
 
-	assertEqual(t, orderbook.OrderCancelled, cancelled.State, "Should be cancelled")
-	assertFloat(t, 1, cancelled.FilledAmount, "Filled amount preserved")
+	assertEqual(t, orderbook.OrderCancelled, cancelled.Order.State, "Should be cancelled")
+	assertFloat(t, 1, cancelled.Order.FilledAmount, "Filled amount preserved")
 
 	// Only the remaining locked amount should be unlocked
 	balance := e.accounts.GetBalance("2", "BRL")
@@ -257,6 +346,45 @@ func TestEngine_CancelOrder_PartiallyFilled(t *testing.T) {
 	assertFloat(t, 0, balance.Locked, "Locked after cancel")
 }
 
+func TestEngine_CancelOrder_ReportsUnlocked(t *testing.T) {
+	e := setupEngine()
+
+	// Fully open ask: the whole amount comes back
+	ask, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 51_000, 2)
+	assertNoError(t, err)
+	before := e.accounts.GetBalance("1", "BTC").Available
+
+	cancelled, err := e.CancelOrder("1", btcBrl(), ask.ID)
+	assertNoError(t, err)
+	assertEqual(t, "BTC", cancelled.UnlockedAsset, "Ask unlocks base")
+	assertFloat(t, 2, cancelled.Unlocked, "Whole ask unlocked")
+	assertFloat(t, cancelled.Unlocked, e.accounts.GetBalance("1", "BTC").Available-before, "Matches the balance change")
+
+	// Partially filled bid: only the unfilled part comes back
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 0.4)
+	assertNoError(t, err)
+	bid, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+	before = e.accounts.GetBalance("2", "BRL").Available
+
+	cancelled, err = e.CancelOrder("2", btcBrl(), bid.ID)
+	assertNoError(t, err)
+	assertEqual(t, "BRL", cancelled.UnlockedAsset, "Bid unlocks quote")
+	assertFloat(t, 30_000, cancelled.Unlocked, "Unfilled 0.6 at 50000")
+	assertFloat(t, cancelled.Unlocked, e.accounts.GetBalance("2", "BRL").Available-before, "Matches the balance change")
+	assertFloat(t, 0, e.accounts.GetBalance("2", "BRL").Locked, "Nothing left locked")
+
+	// The amount is rounded to the asset's precision, without float drift
+	ask, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 52_000, 0.3)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 52_000, 0.1)
+	assertNoError(t, err)
+
+	cancelled, err = e.CancelOrder("1", btcBrl(), ask.ID)
+	assertNoError(t, err)
+	assertEqual(t, 0.2, cancelled.Unlocked, "0.3 less 0.1 filled, rounded")
+}
+
 // =============================================================================
 // PRICE/TIME PRIORITY (FIFO)
 // =============================================================================
@@ -313,6 +441,35 @@ func TestEngine_PlaceOrder_BuyPriceImprovement_ShouldRefundDifference(t *testing
 	assertFloat(t, 9, sellerBTC.Available, "Seller BTC after trade")
 }
 
+func TestEngine_PlaceOrder_RefundDownToSmallestUnit(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 49_999.99, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+
+	// The 0.01 BRL improvement is one cent, BRL's smallest unit
+	buyerBRL := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 50_000.01, buyerBRL.Available, "One-cent refund released")
+	assertFloat(t, 0, buyerBRL.Locked, "Nothing left locked")
+}
+
+func TestEngine_PlaceOrder_RefundThresholdConfigured(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MinRefund = map[string]float64{"BRL": 100}
+	e := setupEngineWithConfig(cfg)
+
+	// A filled bid gets a 50 BRL improvement back despite the threshold
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 49_950, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+	buyerBRL := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 0, buyerBRL.Locked, "Nothing left locked")
+	assertFloat(t, 100_000-49_950, buyerBRL.Available, "Whole difference refunded")
+}
+
 func TestEngine_CancelOrder_Twice_ShouldReturnNotFound(t *testing.T) {
 	e := setupEngine()
 
@@ -349,6 +506,85 @@ func TestEngine_ConcurrentPlaceOrders(t *testing.T) {
 	wg.Wait()
 }
 
+func TestEngine_CancelOrder_AfterFill(t *testing.T) {
+	e := setupEngine()
+
+	ask, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+
+	// The fill took the ask out of the book before the cancel got the lock
+	_, err = e.CancelOrder("2", btcBrl(), ask.ID)
+	assertEqual(t, ErrOrderNotFound, err, "Cancel of a filled order")
+	_, err = e.ForceCancelOrder(btcBrl(), ask.ID)
+	assertEqual(t, ErrOrderNotFound, err, "Force cancel of a filled order")
+
+	// Nothing was unlocked a second time
+	sellerBTC := e.accounts.GetBalance("2", "BTC")
+	assertFloat(t, 9.5, sellerBTC.Available, "Seller BTC available")
+	assertFloat(t, 0, sellerBTC.Locked, "Seller BTC locked")
+}
+
+func TestEngine_ConcurrentCancelAndMatch(t *testing.T) {
+	e := setupEngine()
+
+	const rounds = 200
+	placed := make(chan int64, rounds)
+	var wg sync.WaitGroup
+
+	// User 2 keeps resting asks that user 1's bids take out while the
+	// canceller races them for the same orders.
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		defer close(placed)
+		for i := 0; i < rounds; i++ {
+			order, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 1_000, 0.25)
+			if err == nil && order.State != orderbook.OrderFilled {
+				placed <- order.ID
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			_, _, _ = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 1_000, 0.25)
+		}
+	}()
+
+	var unexpected []error
+	go func() {
+		defer wg.Done()
+		for id := range placed {
+			if _, err := e.CancelOrder("2", btcBrl(), id); err != nil && err != ErrOrderNotFound {
+				unexpected = append(unexpected, err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	assertEqual(t, 0, len(unexpected), "Cancels failing with anything but not found")
+
+	_, err := e.CancelAllUserOrders("1")
+	assertNoError(t, err)
+	_, err = e.CancelAllUserOrders("2")
+	assertNoError(t, err)
+
+	// With the book empty every lock must be gone and no funds created
+	var totalBRL, totalBTC float64
+	for _, user := range []string{"1", "2"} {
+		for asset, balance := range e.accounts.GetAllBalances(user) {
+			assertTrue(t, balance.Available >= 0, user+" "+asset+" available not negative")
+			assertFloat(t, 0, balance.Locked, user+" "+asset+" locked")
+		}
+		totalBRL += e.accounts.GetBalance(user, "BRL").Total()
+		totalBTC += e.accounts.GetBalance(user, "BTC").Total()
+	}
+	assertFloat(t, 200_000, totalBRL, "BRL conserved")
+	assertFloat(t, 20, totalBTC, "BTC conserved")
+}
+
 func TestEngine_PlaceOrder_BuyPartialFill_WithPriceImprovement_ShouldRefundAndKeepCorrectLocked(t *testing.T) {
 	e := setupEngine()
 
@@ -392,6 +628,166 @@ func TestEngine_PlaceOrder_BuyPartialFill_WithPriceImprovement_ShouldRefundAndKe
 	assertFloat(t, 9.5, sellerBTC.Available, "Seller BTC after trade")
 }
 
+func TestEngine_PlaceOrder_MarketableBid_FillsThenRestsAtLimit(t *testing.T) {
+	e := setupEngine()
+	assertNoError(t, e.accounts.Credit("1", "BRL", 2_000))
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+
+	// BID 2 BTC @ 51,000 through an ask of 1 BTC @ 50,000
+	order, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 51_000, 2)
+	assertNoError(t, err)
+
+	assertEqual(t, 1, len(matches), "Should have 1 match")
+	assertFloat(t, 1, matches[0].SizeFilled, "Filled size")
+	assertFloat(t, 50_000, matches[0].Price, "Filled at the ask")
+	assertEqual(t, orderbook.OrderPartiallyFilled, order.State, "Order state")
+
+	// The remainder rests at the limit price
+	best, ok := e.GetOrderbook(btcBrl()).BestBid()
+	assertTrue(t, ok, "Remainder resting")
+	assertFloat(t, 51_000, best.Price(PriceTick), "Rests at its limit")
+	assertFloat(t, 1, best.TotalVolume, "Resting amount")
+
+	// Locked 102,000, paid 50,000, refunded 1,000, 51,000 still locked
+	buyerBRL := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 51_000, buyerBRL.Locked, "Locked for the resting remainder")
+	assertFloat(t, 1_000, buyerBRL.Available, "Available after refund")
+
+	// Cancelling releases exactly the rest
+	_, err = e.CancelOrder("1", btcBrl(), order.ID)
+	assertNoError(t, err)
+	buyerBRL = e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 0, buyerBRL.Locked, "Nothing locked after cancel")
+	assertFloat(t, 52_000, buyerBRL.Available, "Paid only the fill")
+}
+
+func TestEngine_PlaceOrder_MarketableBid_AcrossLevels(t *testing.T) {
+	e := setupEngine()
+	assertNoError(t, e.accounts.Credit("1", "BRL", 2_000))
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_500.5, 0.3)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 52_000, 1)
+	assertNoError(t, err)
+
+	// Takes both levels up to 51,000 and rests 1.2 BTC there
+	order, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 51_000, 2)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(matches), "Matches up to the limit")
+	assertFloat(t, 1.2, order.RemainingAmount(), "Remainder")
+
+	// Paid 25,000 + 15,150.15; 61,200 locked for 1.2 @ 51,000
+	buyerBRL := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 61_200, buyerBRL.Locked, "Locked for the resting remainder")
+	assertFloat(t, 649.85, buyerBRL.Available, "Available after refund")
+}
+
+func TestEngine_PlaceOrder_MarketableBid_RestingRefundHeldUntilCancel(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MinRefund = map[string]float64{"BRL": 100}
+	e := setupEngineWithConfig(cfg)
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_950, 1)
+	assertNoError(t, err)
+
+	// 50 BRL improvement is below the threshold, so it stays locked while
+	// the order rests and is released with the remainder on cancel
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 51_000, 1.5)
+	assertNoError(t, err)
+	assertFloat(t, 25_500+50, e.accounts.GetBalance("1", "BRL").Locked, "Locked for 0.5 @ 51,000 plus the held refund")
+
+	archived, err := e.CancelOrder("1", btcBrl(), order.ID)
+	assertNoError(t, err)
+	assertFloat(t, 25_550, archived.Unlocked, "Held refund reported with the cancel")
+	buyerBRL := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 0, buyerBRL.Locked, "Nothing stranded")
+	assertFloat(t, 100_000-50_950, buyerBRL.Available, "Paid only for the fill")
+}
+
+func TestEngine_PlaceMarketOrder_LevelCap_DeepBook(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxLevelsPerOrder = 3
+	e := setupEngineWithConfig(cfg)
+
+	// 1,000 levels of 0.001 BTC, 10 BRL apart
+	for i := 0; i < 1_000; i++ {
+		_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000+float64(i)*10, 0.001)
+		assertNoError(t, err)
+	}
+
+	order, matches, err := e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.5)
+	assertNoError(t, err)
+	assertEqual(t, 3, len(matches), "Stops at the cap")
+	assertEqual(t, orderbook.OrderPartiallyFilled, order.State, "Partial fill")
+	assertFloat(t, 0.003, order.FilledAmount, "Filled three levels")
+	assertEqual(t, 997, len(e.GetOrderbook(btcBrl()).Asks()), "Deeper levels untouched")
+
+	// Paid 50.00 + 50.01 + 50.02; the rest of the lock is released
+	buyerBRL := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 0, buyerBRL.Locked, "Nothing left locked")
+	assertFloat(t, 99_849.97, buyerBRL.Available, "Paid only the capped levels")
+}
+
+func TestEngine_PlaceMarketOrder_StopsAtGap(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "SOL", Quote: "BRL"}
+	assertNoError(t, e.RegisterPair(PairConfig{
+		Pair:         pair,
+		PriceTick:    0.01,
+		AmountTick:   0.001,
+		MaxMarketGap: 0.05,
+	}))
+	_ = e.accounts.Credit("2", "SOL", 10)
+
+	// 2% between the first two levels, then a 47% hole
+	for _, level := range []struct{ price, amount float64 }{{100, 0.25}, {102, 0.25}, {150, 0.5}} {
+		_, _, err := e.PlaceOrder("2", pair, orderbook.Ask, level.price, level.amount)
+		assertNoError(t, err)
+	}
+
+	order, matches, err := e.PlaceMarketOrder("1", pair, orderbook.Bid, 1)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(matches), "Stops before the gap")
+	assertEqual(t, orderbook.OrderPartiallyFilled, order.State, "Partial fill")
+	assertFloat(t, 0.5, order.FilledAmount, "Filled the levels before the gap")
+	assertEqual(t, 1, len(e.GetOrderbook(pair).Asks()), "Level past the gap untouched")
+
+	// Paid 25 + 25.50; the rest of the lock is released
+	buyerBRL := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 0, buyerBRL.Locked, "Nothing left locked")
+	assertFloat(t, 99_949.5, buyerBRL.Available, "Paid only up to the gap")
+}
+
+func TestEngine_PlaceOrder_LevelCap_MarketableLimitCancelled(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxLevelsPerOrder = 2
+	e := setupEngineWithConfig(cfg)
+
+	for _, price := range []float64{50_000, 50_010, 50_020} {
+		_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, price, 0.001)
+		assertNoError(t, err)
+	}
+
+	order, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 51_000, 1)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(matches), "Stops at the cap")
+	assertEqual(t, orderbook.OrderCancelled, order.State, "Remainder cancelled instead of crossing the book")
+	assertEqual(t, 0, len(e.GetOrderbook(btcBrl()).Bids()), "Nothing rests")
+
+	buyerBRL := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 0, buyerBRL.Locked, "Remainder unlocked")
+	assertFloat(t, 99_899.99, buyerBRL.Available, "Paid 50.00 + 50.01")
+
+	history, _, err := e.OrderHistory("1", HistoryFilter{})
+	assertNoError(t, err)
+	assertEqual(t, 1, len(history), "Archived")
+	assertEqual(t, orderbook.OrderCancelled, history[0].Order.State, "Archived as cancelled")
+}
+
 func TestEngine_CancelOrder_AfterBuyPartialFill_SamePrice_ShouldUnlockRemaining(t *testing.T) {
 	e := setupEngine()
 
@@ -416,8 +812,8 @@ func TestEngine_CancelOrder_AfterBuyPartialFill_SamePrice_ShouldUnlockRemaining(
 	cancelled, err := e.CancelOrder("1", btcBrl(), order.ID)
 	assertNoError(t, err)
 
-	assertEqual(t, orderbook.OrderCancelled, cancelled.State, "Order should be cancelled")
-	assertFloat(t, 0.5, cancelled.FilledAmount, "Filled amount should be preserved")
+	assertEqual(t, orderbook.OrderCancelled, cancelled.Order.State, "Order should be cancelled")
+	assertFloat(t, 0.5, cancelled.Order.FilledAmount, "Filled amount should be preserved")
 
 	// After cancel:
 	// Initial BRL: 100,000
@@ -432,6 +828,74 @@ func TestEngine_CancelOrder_AfterBuyPartialFill_SamePrice_ShouldUnlockRemaining(
 	assertFloat(t, 10.5, buyerBTC.Available, "Buyer BTC after partial fill and cancel")
 }
 
+func TestEngine_PlaceOrder_BuyPartialFills_LockedReconcilesToTheCent(t *testing.T) {
+	e := setupEngine()
+
+	// User 1 rests BID: 1 BTC @ 33,333.33, locking 33,333.33 BRL
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 33_333.33, 1)
+	assertNoError(t, err)
+	assertFloat(t, 33_333.33, e.accounts.GetBalance("1", "BRL").Locked, "Initial lock")
+
+	// User 2 sells into it in pieces whose quote values are not whole cents.
+	// The lock must follow the remainder exactly after every fill.
+	fills := []struct {
+		amount float64
+		locked float64
+	}{
+		{0.12345678, 29_218.10},
+		{0.3, 19_218.11},
+		{0.00000007, 19_218.10},
+		{0.17777777, 13_292.18},
+	}
+	for _, f := range fills {
+		_, matches, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 33_333.33, f.amount)
+		assertNoError(t, err)
+		assertEqual(t, 1, len(matches), "Should match the resting bid")
+		assertFloat(t, f.locked, e.accounts.GetBalance("1", "BRL").Locked, "Buyer BRL locked")
+	}
+
+	// What the buyer paid is exactly what the seller received
+	buyer := e.accounts.GetBalance("1", "BRL")
+	seller := e.accounts.GetBalance("2", "BRL")
+	assertFloat(t, 120_041.15, seller.Available, "Seller BRL after fills")
+	assertFloat(t, 200_000, buyer.Available+buyer.Locked+seller.Available, "BRL in circulation")
+
+	_, err = e.CancelOrder("1", btcBrl(), order.ID)
+	assertNoError(t, err)
+
+	buyer = e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 0, buyer.Locked, "Buyer BRL locked after cancel")
+	assertFloat(t, 79_958.85, buyer.Available, "Buyer BRL available after cancel")
+}
+
+func TestEngine_PlaceOrder_FillWorthNoQuoteSettlesNothing(t *testing.T) {
+	e := setupEngine()
+
+	// Leave one satoshi of the first ask resting, ahead of a second ask
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.50000001)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+
+	// One satoshi at 50000 is worth 0.0005 BRL, which rounds to nothing
+	_, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(matches), "Dust match, then the second ask")
+	assertFloat(t, 0.00000001, e.roundAsset("BTC", matches[0].SizeFilled), "Dust match")
+
+	// The dust match moves neither leg: the seller keeps that satoshi
+	seller := e.accounts.GetBalance("2", "BTC")
+	assertFloat(t, 0.90000001, seller.Locked, "Only the second ask's remainder locked")
+	assertFloat(t, 8.5, seller.Available, "Dust satoshi back to available")
+	assertFloat(t, 130_000, e.accounts.GetBalance("2", "BRL").Available, "Seller paid for what it delivered")
+	assertFloat(t, 10.59999999, e.accounts.GetBalance("1", "BTC").Available, "Buyer receives only what it paid for")
+	buyer := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 70_000, buyer.Available, "Buyer pays 30000")
+	assertFloat(t, 0, buyer.Locked, "Nothing left locked")
+}
+
 // =============================================================================
 // MARKET ORDER TESTS
 // =============================================================================
@@ -477,6 +941,46 @@ func TestEngine_PlaceMarketOrder_Buy_FullFill(t *testing.T) {
 	assertFloat(t, 9, sellerBTC.Available, "Seller BTC after trade")       // 10 - 1
 }
 
+func TestEngine_PlaceMarketOrder_OnlyOwnLiquidity(t *testing.T) {
+	e := setupEngine()
+
+	// The only asks and bids in the book are user 1's own
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 1.0)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 1.0)
+	assertNoError(t, err)
+
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.5)
+	assertEqual(t, ErrInsufficientLiquidity, err, "Market buy against own asks")
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Ask, 0.5)
+	assertEqual(t, ErrInsufficientLiquidity, err, "Market sell against own bids")
+
+	// Only the resting orders hold funds
+	assertFloat(t, 49_000, e.accounts.GetBalance("1", "BRL").Locked, "BRL locked by the bid only")
+	assertFloat(t, 1.0, e.accounts.GetBalance("1", "BTC").Locked, "BTC locked by the ask only")
+	assertEqual(t, 0, len(e.Trades(btcBrl())), "Nothing traded")
+}
+
+func TestEngine_PlaceMarketOrder_SkipsOwnLevelInEstimate(t *testing.T) {
+	e := setupEngine()
+
+	// User 1's cheaper ask must not lower what the market buy locks
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 51_000, 1.0)
+	assertNoError(t, err)
+
+	order, matches, err := e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.5)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderFilled, order.State, "Filled against user 2")
+	assertEqual(t, 1, len(matches), "One match")
+	assertFloat(t, 51_000, matches[0].Price, "Own level skipped")
+
+	buyerBRL := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 74_500, buyerBRL.Available, "Paid 25,500")
+	assertFloat(t, 0, buyerBRL.Locked, "Nothing left locked")
+}
+
 func TestEngine_PlaceMarketOrder_Sell_FullFill(t *testing.T) {
 	e := setupEngine()
 
@@ -518,6 +1022,29 @@ func TestEngine_PlaceMarketOrder_Sell_FullFill(t *testing.T) {
 	assertFloat(t, 11, buyerBTC.Available, "Buyer BTC after trade")     // 10 + 1
 }
 
+func TestEngine_PlaceMarketOrder_ExactLiquidity(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "SHIB", Quote: "BRL"}
+	assertNoError(t, e.RegisterPair(PairConfig{Pair: pair, PriceTick: 0.00000001, AmountTick: 0.01}))
+	_ = e.accounts.Credit("1", "BRL", 100_000)
+	_ = e.accounts.Credit("2", "SHIB", 1_000_000_000)
+
+	// At this size the two levels add up to the request only in whole ticks:
+	// subtracting them as floats leaves about 1e-7 over
+	_, _, err := e.PlaceOrder("2", pair, orderbook.Ask, 0.0001, 670_921_150.58)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", pair, orderbook.Ask, 0.0002, 187_476_767.35)
+	assertNoError(t, err)
+
+	order, matches, err := e.PlaceMarketOrder("1", pair, orderbook.Bid, 858_397_917.93)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(matches), "Both levels consumed")
+	assertEqual(t, orderbook.OrderFilled, order.State, "Order filled")
+
+	_, ok := e.GetOrderbook(pair).BestAsk()
+	assertFalse(t, ok, "Ask side emptied")
+}
+
 func TestEngine_PlaceMarketOrder_Buy_PartialFill_InsufficientLiquidity(t *testing.T) {
 	e := setupEngine()
 
@@ -630,3 +1157,447 @@ func TestEngine_PlaceMarketOrder_InvalidAmount(t *testing.T) {
 	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, -1)
 	assertError(t, err)
 }
+
+func TestEngine_PlaceMarketOrder_RestRemainder_Buy(t *testing.T) {
+	e := setupEngine()
+	opts := OrderOptions{RestRemainder: true}
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 51_000, 0.5)
+	assertNoError(t, err)
+
+	order, matches, err := e.PlaceMarketOrderWithOptions("1", btcBrl(), orderbook.Bid, 1.5, opts)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(matches), "Matches")
+
+	// The unfilled 0.5 rests as a limit at the last execution price
+	assertEqual(t, orderbook.OrderTypeLimit, order.Type, "Converted to limit")
+	assertEqual(t, orderbook.OrderPartiallyFilled, order.State, "State")
+	assertFloat(t, 51_000, order.Price, "Rests at last execution price")
+	assertFloat(t, 0.5, order.RemainingAmount(), "Remainder")
+
+	ob := e.GetOrderbook(btcBrl())
+	resting, exists := ob.GetOrder(order.ID)
+	assertTrue(t, exists, "Remainder is in the book")
+	assertEqual(t, orderbook.Bid, resting.Side, "Side")
+
+	// Spent 25000 + 25500, keeps 0.5 * 51000 locked for the remainder
+	balance := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 25_500, balance.Locked, "Locked for the remainder")
+	assertFloat(t, 24_000, balance.Available, "Available")
+	assertFloat(t, 11, e.accounts.GetBalance("1", "BTC").Available, "BTC received")
+
+	// Cancelling the remainder releases exactly what it holds
+	_, err = e.CancelOrder("1", btcBrl(), order.ID)
+	assertNoError(t, err)
+	balance = e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 0, balance.Locked, "Locked after cancel")
+	assertFloat(t, 49_500, balance.Available, "Available after cancel")
+}
+
+func TestEngine_PlaceMarketOrder_RestRemainder_Sell(t *testing.T) {
+	e := setupEngine()
+	opts := OrderOptions{RestRemainder: true}
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 0.5)
+	assertNoError(t, err)
+
+	order, _, err := e.PlaceMarketOrderWithOptions("2", btcBrl(), orderbook.Ask, 1.5, opts)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderTypeLimit, order.Type, "Converted to limit")
+	assertFloat(t, 49_000, order.Price, "Rests at last execution price")
+
+	btc := e.accounts.GetBalance("2", "BTC")
+	assertFloat(t, 0.5, btc.Locked, "Remainder stays locked")
+	assertFloat(t, 8.5, btc.Available, "Available")
+	assertFloat(t, 149_500, e.accounts.GetBalance("2", "BRL").Available, "Proceeds")
+
+	bestAsk := e.GetOrderbook(btcBrl()).Asks()[0]
+	assertFloat(t, 49_000, bestAsk.Price(PriceTick), "Remainder is the best ask")
+}
+
+func TestEngine_PlaceMarketOrder_RestRemainder_BelowPairMinimum(t *testing.T) {
+	e := setupDustEngine(false)
+	solBrl := Pair{Base: "SOL", Quote: "BRL"}
+
+	_, _, err := e.PlaceOrder("2", solBrl, orderbook.Ask, 1_000, 1)
+	assertNoError(t, err)
+
+	// 0.05 is left, below the pair's 0.1 minimum: a limit that small could
+	// not be placed, so it does not rest either
+	order, matches, err := e.PlaceMarketOrderWithOptions("1", solBrl, orderbook.Bid, 1.05, OrderOptions{RestRemainder: true})
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Filled what the book had")
+	assertEqual(t, orderbook.OrderTypeMarket, order.Type, "Not converted")
+	assertEqual(t, 0, e.GetOrderbook(solBrl).OpenOrderTotal(), "Nothing rests")
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BRL").Locked, "Nothing left locked")
+}
+
+func TestEngine_PlaceMarketOrder_RestRemainder_EmptyBook(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceMarketOrderWithOptions("1", btcBrl(), orderbook.Bid, 1, OrderOptions{RestRemainder: true})
+	assertEqual(t, ErrInsufficientLiquidity, err, "No last price to rest at")
+
+	balance := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 100_000, balance.Available, "Nothing locked")
+	assertFloat(t, 0, balance.Locked, "Nothing locked")
+}
+
+func TestEngine_PlaceMarketOrder_MixedLiquidity_RestBlockedByOwnAsk(t *testing.T) {
+	e := setupEngine()
+
+	// User 1's own ask sits between user 2's asks
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 49_500, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 49_000, 0.3)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.2)
+	assertNoError(t, err)
+
+	// Locks 0.3*49,000 + 0.2*50,000 + 0.5*50,000 = 49,700. Resting the 0.5
+	// left at 50,000 would cross the own ask at 49,500, so it is released.
+	order, matches, err := e.PlaceMarketOrderWithOptions("1", btcBrl(), orderbook.Bid, 1, OrderOptions{RestRemainder: true})
+	assertNoError(t, err)
+	assertEqual(t, 2, len(matches), "Filled by user 2 only")
+	assertEqual(t, orderbook.OrderPartiallyFilled, order.State, "Partially filled")
+	assertFloat(t, 0.5, order.FilledAmount, "Filled amount")
+	assertEqual(t, 0, len(e.GetOrderbook(btcBrl()).Bids()), "Remainder not rested")
+
+	brl := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 75_300, brl.Available, "Paid 24,700")
+	assertFloat(t, 0, brl.Locked, "Unfilled pre-lock released")
+	btc := e.accounts.GetBalance("1", "BTC")
+	assertFloat(t, 9.5, btc.Available, "Received 0.5")
+	assertFloat(t, 1, btc.Locked, "Own ask still locked")
+}
+
+func TestEngine_PlaceMarketOrder_MixedLiquidity_LevelCap(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxLevelsPerOrder = 2
+	e := setupEngineWithConfig(cfg)
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 49_000, 0.3)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 49_500, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+
+	// The own level uses up the second level of the cap without filling
+	order, matches, err := e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 1)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Only the first level filled")
+	assertFloat(t, 0.3, order.FilledAmount, "Filled amount")
+
+	brl := e.accounts.GetBalance("1", "BRL")
+	assertFloat(t, 85_300, brl.Available, "Paid 14,700")
+	assertFloat(t, 0, brl.Locked, "No stranded lock")
+	assertFloat(t, 1, e.accounts.GetBalance("1", "BTC").Locked, "Own ask still locked")
+
+	// A sell locks its whole amount and gets the unfilled part back
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 48_000, 0.4)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 47_500, 0.1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 47_000, 1)
+	assertNoError(t, err)
+
+	order, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Ask, 1)
+	assertNoError(t, err)
+	assertFloat(t, 0.4, order.FilledAmount, "Sell filled by the first level")
+	btc := e.accounts.GetBalance("1", "BTC")
+	assertFloat(t, 1, btc.Locked, "Only the own ask stays locked")
+	assertFloat(t, 8.9, btc.Available, "10.3 - 1 resting - 0.4 sold")
+}
+
+func TestEngine_PlaceMarketOrder_RestRemainder_Disabled(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+
+	// Without the flag thin liquidity still rejects the order
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 1.5)
+	assertEqual(t, ErrInsufficientLiquidity, err, "Rejected")
+}
+
+// =============================================================================
+// TICK POLICY
+// =============================================================================
+
+func TestEngine_PlaceOrder_TickPolicy_Floor(t *testing.T) {
+	e := setupEngine()
+
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000.019, 0.123456789)
+	assertNoError(t, err)
+
+	assertFloat(t, 50_000.01, order.Price, "Price floored to tick")
+	assertFloat(t, 0.12345678, order.Amount, "Amount floored to tick")
+}
+
+func TestEngine_PlaceOrder_TickPolicy_Round(t *testing.T) {
+	e := NewEngineWithConfig(Config{TickPolicy: TickRound})
+	_ = e.accounts.Credit("1", "BTC", 10)
+
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000.019, 0.123456789)
+	assertNoError(t, err)
+
+	assertFloat(t, 50_000.02, order.Price, "Price rounded to tick")
+	assertFloat(t, 0.12345679, order.Amount, "Amount rounded to tick")
+}
+
+func TestEngine_PlaceOrder_TickPolicy_Reject(t *testing.T) {
+	e := NewEngineWithConfig(Config{TickPolicy: TickReject})
+	_ = e.accounts.Credit("1", "BTC", 10)
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000.019, 1)
+	assertEqual(t, ErrInvalidPriceTick, err, "Misaligned price rejected")
+
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 0.123456789)
+	assertEqual(t, ErrInvalidAmountTick, err, "Misaligned amount rejected")
+
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Ask, 0.123456789)
+	assertEqual(t, ErrInvalidAmountTick, err, "Misaligned market amount rejected")
+
+	// Aligned values are accepted unchanged
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000.01, 0.12345678)
+	assertNoError(t, err)
+	assertFloat(t, 0.12345678, order.Amount, "Aligned amount kept")
+
+	balance := e.accounts.GetBalance("1", "BTC")
+	assertFloat(t, 0.12345678, balance.Locked, "Only the accepted order is locked")
+}
+
+// =============================================================================
+// OPEN ORDER CAP
+// =============================================================================
+
+func TestEngine_PlaceOrder_MaxOpenOrders(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxOpenOrdersPerUser = 2
+	e := setupEngineWithConfig(cfg)
+
+	first, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 0.1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 41_000, 0.1)
+	assertNoError(t, err)
+
+	// Third resting order is rejected and nothing stays locked for it
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 42_000, 0.1)
+	assertEqual(t, ErrTooManyOpenOrders, err, "Third order should be rejected")
+	assertFloat(t, 8_100, e.accounts.GetBalance("1", "BRL").Locked, "Locked only for resting orders")
+
+	// Another user is unaffected
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 42_000, 0.1)
+	assertNoError(t, err)
+
+	// Cancelling frees a slot
+	_, err = e.CancelOrder("1", btcBrl(), first.ID)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 42_000, 0.1)
+	assertNoError(t, err)
+}
+
+func TestEngine_PlaceOrder_MaxOpenOrders_FullyMatchingOrderAllowed(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxOpenOrdersPerUser = 1
+	e := setupEngineWithConfig(cfg)
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 0.1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+
+	// User 1 is at the cap, but this order fully matches and never rests
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderFilled, order.State, "Order should be filled")
+
+	// User 2's ask was filled, so their slot is free again
+	ob := e.GetOrderbook(btcBrl())
+	assertEqual(t, 0, ob.OpenOrderCount("2"), "Filled maker no longer counts")
+}
+
+func TestEngine_CancelAllUserOrders(t *testing.T) {
+	e := setupEngine()
+	ethBrl := Pair{Base: "ETH", Quote: "BRL"}
+	_ = e.accounts.Credit("1", "ETH", 5)
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 60_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", ethBrl, orderbook.Ask, 20_000, 2)
+	assertNoError(t, err)
+	other, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Bid, 39_000, 1)
+	assertNoError(t, err)
+
+	cancelled, err := e.CancelAllUserOrders("1")
+	assertNoError(t, err)
+	assertEqual(t, 3, len(cancelled), "Cancelled orders")
+	for _, o := range cancelled {
+		assertEqual(t, orderbook.OrderCancelled, o.Order.State, "Order state")
+	}
+	assertEqual(t, btcBrl(), cancelled[0].Pair, "BTC/BRL orders first")
+	assertEqual(t, ethBrl, cancelled[2].Pair, "Then ETH/BRL")
+	assertEqual(t, 0, e.GetOrderbook(btcBrl()).OpenOrderCount("1"), "No BTC/BRL orders left")
+	assertEqual(t, 0, e.GetOrderbook(ethBrl).OpenOrderCount("1"), "No ETH/BRL orders left")
+
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BRL").Locked, "BRL unlocked")
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BTC").Locked, "BTC unlocked")
+	assertFloat(t, 0, e.accounts.GetBalance("1", "ETH").Locked, "ETH unlocked")
+
+	// Other users keep their orders
+	_, exists := e.GetOrderbook(btcBrl()).GetOrder(other.ID)
+	assertTrue(t, exists, "Other user's order still rests")
+}
+
+// =============================================================================
+// DUST SWEEP
+// =============================================================================
+
+func setupDustEngine(sweep bool) *Engine {
+	cfg := DefaultConfig()
+	cfg.SweepDust = sweep
+	e := setupEngineWithConfig(cfg)
+
+	pair := Pair{Base: "SOL", Quote: "BRL"}
+	_ = e.RegisterPair(PairConfig{Pair: pair, PriceTick: 0.01, AmountTick: 0.0001, MinOrderSize: 0.1})
+	_ = e.accounts.Credit("1", "SOL", 10)
+	_ = e.accounts.Credit("2", "SOL", 10)
+	return e
+}
+
+func TestEngine_SweepDust_MakerRemainder(t *testing.T) {
+	e := setupDustEngine(true)
+	solBrl := Pair{Base: "SOL", Quote: "BRL"}
+
+	ask, _, err := e.PlaceOrder("1", solBrl, orderbook.Ask, 10_000, 1.0)
+	assertNoError(t, err)
+
+	// Fill leaves 0.0625 SOL resting, below the 0.1 minimum
+	_, matches, err := e.PlaceOrder("2", solBrl, orderbook.Bid, 10_000, 0.9375)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Matches")
+
+	assertEqual(t, orderbook.OrderCancelled, ask.State, "Dust remainder cancelled")
+	_, exists := e.GetOrderbook(solBrl).GetOrder(ask.ID)
+	assertFalse(t, exists, "Dust no longer rests")
+
+	balance := e.accounts.GetBalance("1", "SOL")
+	assertFloat(t, 0, balance.Locked, "Remainder unlocked")
+	assertFloat(t, 9.0625, balance.Available, "Seller keeps unsold SOL")
+}
+
+func TestEngine_SweepDust_TakerRemainder(t *testing.T) {
+	e := setupDustEngine(true)
+	solBrl := Pair{Base: "SOL", Quote: "BRL"}
+
+	_, _, err := e.PlaceOrder("1", solBrl, orderbook.Ask, 10_000, 0.5)
+	assertNoError(t, err)
+
+	// Taker buys 0.5625, 0.0625 would rest as dust
+	bid, _, err := e.PlaceOrder("2", solBrl, orderbook.Bid, 10_000, 0.5625)
+	assertNoError(t, err)
+
+	assertEqual(t, orderbook.OrderCancelled, bid.State, "Taker dust cancelled")
+	assertFloat(t, 0.5, bid.FilledAmount, "Filled amount kept")
+	assertFloat(t, 0, e.accounts.GetBalance("2", "BRL").Locked, "Bid remainder unlocked")
+	assertFloat(t, 95_000, e.accounts.GetBalance("2", "BRL").Available, "Buyer paid only for the fill")
+}
+
+func TestEngine_SweepDust_Disabled(t *testing.T) {
+	e := setupDustEngine(false)
+	solBrl := Pair{Base: "SOL", Quote: "BRL"}
+
+	ask, _, err := e.PlaceOrder("1", solBrl, orderbook.Ask, 10_000, 1.0)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", solBrl, orderbook.Bid, 10_000, 0.9375)
+	assertNoError(t, err)
+
+	assertEqual(t, orderbook.OrderPartiallyFilled, ask.State, "Dust stays when sweep is off")
+	assertFloat(t, 0.0625, e.accounts.GetBalance("1", "SOL").Locked, "Remainder still locked")
+}
+
+func TestEngine_SweepDust_MarketOrderMaker(t *testing.T) {
+	e := setupDustEngine(true)
+	solBrl := Pair{Base: "SOL", Quote: "BRL"}
+
+	ask, _, err := e.PlaceOrder("1", solBrl, orderbook.Ask, 10_000, 1.0)
+	assertNoError(t, err)
+	_, _, err = e.PlaceMarketOrder("2", solBrl, orderbook.Bid, 0.9375)
+	assertNoError(t, err)
+
+	assertEqual(t, orderbook.OrderCancelled, ask.State, "Dust remainder cancelled")
+	assertFloat(t, 0, e.accounts.GetBalance("1", "SOL").Locked, "Remainder unlocked")
+}
+
+func TestEngine_Clock_StampsOrdersAndMatches(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := DefaultConfig()
+	cfg.Clock = clock.NewFake(start, time.Second)
+	e := setupEngineWithConfig(cfg)
+
+	ask, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+	bid, matches, err := e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+
+	assertTrue(t, ask.Timestamp.Equal(start), "First order stamped at start")
+	assertTrue(t, bid.Timestamp.After(ask.Timestamp), "Later order stamped later")
+	assertEqual(t, 1, len(matches), "Matches")
+	assertTrue(t, matches[0].Timestamp.After(bid.Timestamp), "Match stamped after the taker was created")
+}
+
+// =============================================================================
+// IMPROVE-ONLY ORDERS
+// =============================================================================
+
+func TestEngine_PlaceOrder_ImproveOnly(t *testing.T) {
+	e := setupEngine()
+	improve := OrderOptions{ImproveOnly: true}
+
+	// Any price improves an empty side
+	_, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 49_000, 0.1, improve)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrderWithOptions("2", btcBrl(), orderbook.Ask, 51_000, 0.1, improve)
+	assertNoError(t, err)
+
+	// One tick better than the touch is accepted
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 49_000.01, 0.1, improve)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrderWithOptions("2", btcBrl(), orderbook.Ask, 50_999.99, 0.1, improve)
+	assertNoError(t, err)
+
+	// Joining or sitting behind the touch is rejected, and nothing stays locked
+	before := e.accounts.GetBalance("1", "BRL").Locked
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 49_000.01, 0.1, improve)
+	assertEqual(t, ErrWouldNotImprove, err, "Bid at the touch")
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 48_000, 0.1, improve)
+	assertEqual(t, ErrWouldNotImprove, err, "Bid behind the touch")
+	_, _, err = e.PlaceOrderWithOptions("2", btcBrl(), orderbook.Ask, 50_999.99, 0.1, improve)
+	assertEqual(t, ErrWouldNotImprove, err, "Ask at the touch")
+	assertFloat(t, before, e.accounts.GetBalance("1", "BRL").Locked, "Lock released")
+	assertEqual(t, 2, len(e.GetOrderbook(btcBrl()).Bids()), "Book unchanged")
+
+	// Crossing the spread would take, so it is rejected too
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 51_000, 0.1, improve)
+	assertEqual(t, ErrWouldNotImprove, err, "Bid crossing the asks")
+	_, _, err = e.PlaceOrderWithOptions("2", btcBrl(), orderbook.Ask, 49_000, 0.1, improve)
+	assertEqual(t, ErrWouldNotImprove, err, "Ask crossing the bids")
+	assertEqual(t, 2, len(e.GetOrderbook(btcBrl()).Asks()), "Nothing traded")
+
+	// Hidden orders are not part of the visible touch
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 49_500, 0.1, OrderOptions{Hidden: true})
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 49_200, 0.1, improve)
+	assertNoError(t, err)
+
+	// Without the flag the same order rests behind the touch
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 48_000, 0.1)
+	assertNoError(t, err)
+}
@@ -3,9 +3,43 @@ package engine
 import "errors"
 
 var (
-	ErrInvalidPair       = errors.New("invalid pair")
-	ErrInvalidPriceTick  = errors.New("price not aligned to tick")
-	ErrInvalidAmountTick = errors.New("amount not aligned to tick")
-	ErrOrderNotFound     = errors.New("order not found")
-	ErrUnauthorized      = errors.New("unauthorized: order belongs to another user")
+	ErrInvalidPair           = errors.New("invalid pair")
+	ErrSameAssetPair         = errors.New("base and quote must be different assets")
+	ErrInvalidPriceTick      = errors.New("price not aligned to tick")
+	ErrInvalidAmountTick     = errors.New("amount not aligned to tick")
+	ErrInvalidAmountLot      = errors.New("amount not a multiple of the lot size")
+	ErrOrderNotFound         = errors.New("order not found")
+	ErrUnauthorized          = errors.New("unauthorized: order belongs to another user")
+	ErrUnknownPair           = errors.New("unknown pair")
+	ErrPairAlreadyRegistered = errors.New("pair already registered")
+	ErrInvalidTickSize       = errors.New("tick sizes must be positive finite numbers")
+	ErrInvalidLotSize        = errors.New("lot size must be a positive multiple of the amount tick")
+	ErrInvalidMarketGap      = errors.New("max market gap must be a positive finite fraction")
+	ErrPairHalted            = errors.New("trading is halted for this pair")
+	ErrPairDelisted          = errors.New("pair has been delisted")
+	ErrEngineHalted          = errors.New("trading is halted on every pair")
+	ErrBelowMinOrderSize     = errors.New("amount below minimum order size")
+	ErrBelowMinNotional      = errors.New("order value below minimum notional")
+	ErrInsufficientLiquidity = errors.New("insufficient liquidity for market order")
+	ErrTooManyOpenOrders     = errors.New("too many open orders for this pair")
+	ErrSelfTrade             = errors.New("order would trade against your own resting order")
+	ErrInvalidTrailOffset    = errors.New("trail offset must be a positive finite number")
+	ErrInvalidPegOffset      = errors.New("peg offset must be a finite multiple of the price tick")
+	ErrNoPegReference        = errors.New("no price to peg to on this side of the book")
+	ErrStaleNonce            = errors.New("nonce must be greater than the last one used")
+	ErrInvalidExpiry         = errors.New("expires_at must be in the future and is only valid for limit orders")
+	ErrTakerThrottled        = errors.New("too many orders taking liquidity, try again later")
+	ErrTagTooLong            = errors.New("tag exceeds the maximum length")
+	ErrInvalidAmountPct      = errors.New("amount_pct must be above 0 and at most 100")
+	ErrInvalidClientOrderID  = errors.New("client order ID must be 1 to 64 bytes")
+	ErrReplaceSideMismatch   = errors.New("replacement must be on the same side as the order it replaces")
+	ErrOrderIDNotReserved    = errors.New("order ID was not reserved for this user or was already used")
+	ErrTooManyReservedIDs    = errors.New("too many unused reserved order IDs")
+	ErrWouldNotImprove       = errors.New("improve-only order would not be the new best price on its side")
+	ErrInvalidPairMode       = errors.New("pair mode must be maker_only, taker_only, auction or empty")
+	ErrMakerOnly             = errors.New("pair is maker-only: orders that cross the book are not accepted")
+	ErrTakerOnly             = errors.New("pair is taker-only: orders that would rest are not accepted")
+	ErrAuctionMode           = errors.New("pair is in a call auction: market orders are not accepted")
+	ErrNotInAuction          = errors.New("pair is not in auction mode")
+	ErrReservedUserID        = errors.New("user ID is reserved for the exchange's fee account")
 )
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/logger"
+)
+
+// expiryEntry schedules a resting order to expire at At.
+type expiryEntry struct {
+	At      time.Time
+	Pair    Pair
+	OrderID int64
+}
+
+// expiryHeap is a min-heap of expiry entries by At. Entries are never removed
+// when their order is cancelled or filled early; ExpireOrders skips them when
+// they surface.
+type expiryHeap []expiryEntry
+
+func (h expiryHeap) Len() int            { return len(h) }
+func (h expiryHeap) Less(i, j int) bool  { return h[i].At.Before(h[j].At) }
+func (h expiryHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap) Push(x interface{}) { *h = append(*h, x.(expiryEntry)) }
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	*h = old[:n-1]
+	return entry
+}
+
+// scheduleExpiry queues order for expiry if it has an ExpiresAt and is still
+// resting. Must be called with e.mu held.
+func (e *Engine) scheduleExpiry(pair Pair, order *orderbook.Order) {
+	if order.ExpiresAt.IsZero() || order.Limit == nil {
+		return
+	}
+	heap.Push(&e.expiries, expiryEntry{At: order.ExpiresAt, Pair: pair, OrderID: order.ID})
+}
+
+// ExpireOrders removes every resting order whose ExpiresAt has passed,
+// unlocks what it held and archives it as expired. It only visits orders that
+// are due, so a sweep costs O(k log n) for k expirations among n scheduled
+// orders.
+func (e *Engine) ExpireOrders() ([]ArchivedOrder, error) {
+	defer e.serialize()()
+	defer e.repricePegs()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.config.Clock.Now()
+
+	var expired []ArchivedOrder
+	for e.expiries.Len() > 0 && !e.expiries[0].At.After(now) {
+		entry := heap.Pop(&e.expiries).(expiryEntry)
+
+		// Cancelled or filled orders are no longer in the book
+		ob, exists := e.orderbooks[entry.Pair.String()]
+		if !exists {
+			continue
+		}
+		order, exists := ob.GetOrder(entry.OrderID)
+		if !exists {
+			continue
+		}
+
+		archived, err := e.closeResting(entry.Pair, ob, order.ID, orderbook.OrderExpired)
+		if err != nil {
+			return expired, err
+		}
+		expired = append(expired, archived)
+	}
+
+	return expired, nil
+}
+
+// StartExpirySweeper runs ExpireOrders every interval until the returned
+// stop function is called.
+func (e *Engine) StartExpirySweeper(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := e.ExpireOrders(); err != nil {
+					logger.Errorf("expiry sweep failed: %v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
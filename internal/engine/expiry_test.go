@@ -0,0 +1,199 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+func setupExpiryEngine() (*Engine, *clock.Fake) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+
+	cfg := DefaultConfig()
+	cfg.Clock = clk
+	return setupEngineWithConfig(cfg), clk
+}
+
+func TestEngine_ExpireOrders_OnlyDueOrders(t *testing.T) {
+	e, clk := setupExpiryEngine()
+	now := clk.Now()
+
+	soon, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 40_000, 0.5, OrderOptions{ExpiresAt: now.Add(time.Minute)})
+	assertNoError(t, err)
+	later, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 41_000, 0.5, OrderOptions{ExpiresAt: now.Add(time.Hour)})
+	assertNoError(t, err)
+	gtc, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 42_000, 0.5)
+	assertNoError(t, err)
+
+	expired, err := e.ExpireOrders()
+	assertNoError(t, err)
+	assertEqual(t, 0, len(expired), "Nothing due yet")
+
+	clk.Advance(time.Minute)
+	expired, err = e.ExpireOrders()
+	assertNoError(t, err)
+	assertEqual(t, 1, len(expired), "One order due")
+	assertEqual(t, soon.ID, expired[0].Order.ID, "Soonest order expired")
+	assertEqual(t, orderbook.OrderExpired, expired[0].Order.State, "Archived as expired")
+
+	ob := e.GetOrderbook(btcBrl())
+	_, exists := ob.GetOrder(soon.ID)
+	assertFalse(t, exists, "Expired order left the book")
+	_, exists = ob.GetOrder(later.ID)
+	assertTrue(t, exists, "Later order still rests")
+	_, exists = ob.GetOrder(gtc.ID)
+	assertTrue(t, exists, "Good-till-cancelled order still rests")
+
+	// 41,000*0.5 + 42,000*0.5 stay locked
+	assertFloat(t, 41_500, e.accounts.GetBalance("1", "BRL").Locked, "Expired order unlocked")
+}
+
+func TestEngine_ExpireOrders_SkipsCancelledAndFilled(t *testing.T) {
+	e, clk := setupExpiryEngine()
+	expiresAt := clk.Now().Add(time.Minute)
+
+	cancelled, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 40_000, 0.5, OrderOptions{ExpiresAt: expiresAt})
+	assertNoError(t, err)
+	_, err = e.CancelOrder("1", btcBrl(), cancelled.ID)
+	assertNoError(t, err)
+
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 41_000, 0.5, OrderOptions{ExpiresAt: expiresAt})
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 41_000, 0.5)
+	assertNoError(t, err)
+
+	clk.Advance(time.Hour)
+	expired, err := e.ExpireOrders()
+	assertNoError(t, err)
+	assertEqual(t, 0, len(expired), "Cancelled and filled orders are skipped")
+	assertEqual(t, 0, e.expiries.Len(), "Stale entries popped")
+}
+
+func TestEngine_PlaceOrder_InvalidExpiry(t *testing.T) {
+	e, clk := setupExpiryEngine()
+	now := clk.Now()
+
+	_, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 40_000, 0.5, OrderOptions{ExpiresAt: now})
+	assertEqual(t, ErrInvalidExpiry, err, "Expiry must be in the future")
+
+	_, _, err = e.PlaceMarketOrderWithOptions("1", btcBrl(), orderbook.Bid, 0.5, OrderOptions{ExpiresAt: now.Add(time.Hour)})
+	assertEqual(t, ErrInvalidExpiry, err, "Market orders cannot expire")
+}
+
+// expireByFullScan is the naive sweeper ExpireOrders replaces: it visits every
+// resting order on every sweep.
+func expireByFullScan(e *Engine) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.config.Clock.Now()
+	count := 0
+	for key, ob := range e.orderbooks {
+		pair := e.pairs[key].Pair
+		for _, order := range ob.Orders {
+			if order.ExpiresAt.IsZero() || order.ExpiresAt.After(now) {
+				continue
+			}
+			if _, err := e.closeResting(pair, ob, order.ID, orderbook.OrderExpired); err == nil {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+const (
+	benchRestingOrders = 10_000
+	benchExpiring      = 10
+)
+
+// setupExpiryBenchmark rests benchRestingOrders asks that expire far in the
+// future.
+func setupExpiryBenchmark(b *testing.B) (*Engine, *clock.Fake) {
+	e, clk := setupExpiryEngine()
+	_ = e.accounts.Credit("mm", "BTC", 1_000_000)
+
+	farFuture := clk.Now().AddDate(100, 0, 0)
+	for i := 0; i < benchRestingOrders; i++ {
+		price := 60_000 + float64(i%100)
+		if _, _, err := e.PlaceOrderWithOptions("mm", btcBrl(), orderbook.Ask, price, 0.001, OrderOptions{ExpiresAt: farFuture}); err != nil {
+			b.Fatalf("place: %v", err)
+		}
+	}
+	return e, clk
+}
+
+// placeExpiring rests benchExpiring asks due within the next second.
+func placeExpiring(b *testing.B, e *Engine, clk *clock.Fake) {
+	expiresAt := clk.Now().Add(time.Second)
+	for i := 0; i < benchExpiring; i++ {
+		if _, _, err := e.PlaceOrderWithOptions("mm", btcBrl(), orderbook.Ask, 70_000, 0.001, OrderOptions{ExpiresAt: expiresAt}); err != nil {
+			b.Fatalf("place: %v", err)
+		}
+	}
+	clk.Advance(2 * time.Second)
+}
+
+func BenchmarkExpiry_Heap(b *testing.B) {
+	e, clk := setupExpiryBenchmark(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		placeExpiring(b, e, clk)
+		b.StartTimer()
+
+		expired, err := e.ExpireOrders()
+		if err != nil || len(expired) != benchExpiring {
+			b.Fatalf("expired %d orders, err %v", len(expired), err)
+		}
+	}
+}
+
+func BenchmarkExpiry_FullScan(b *testing.B) {
+	e, clk := setupExpiryBenchmark(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		placeExpiring(b, e, clk)
+		b.StartTimer()
+
+		if n := expireByFullScan(e); n != benchExpiring {
+			b.Fatalf("expired %d orders", n)
+		}
+	}
+}
+
+func TestEngine_CancelOrdersOlderThan(t *testing.T) {
+	e, clk := setupExpiryEngine()
+
+	old, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 0.5)
+	assertNoError(t, err)
+	oldAsk, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 60_000, 1)
+	assertNoError(t, err)
+
+	clk.Advance(2 * time.Hour)
+	fresh, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 41_000, 0.5)
+	assertNoError(t, err)
+	clk.Advance(30 * time.Minute)
+
+	cancelled, err := e.CancelOrdersOlderThan(btcBrl(), time.Hour)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(cancelled), "Only the old orders cancelled")
+	assertEqual(t, old.ID, cancelled[0].Order.ID, "Oldest first")
+	assertEqual(t, oldAsk.ID, cancelled[1].Order.ID, "Other owner's old order")
+	assertEqual(t, orderbook.OrderCancelled, cancelled[0].Order.State, "Archived as cancelled")
+
+	// Only the fresh bid's 20,500 stays locked
+	assertFloat(t, 20_500, e.accounts.GetBalance("1", "BRL").Locked, "Buyer locked")
+	assertFloat(t, 0, e.accounts.GetBalance("2", "BTC").Locked, "Seller unlocked")
+	_, exists := e.GetOrderbook(btcBrl()).GetOrder(fresh.ID)
+	assertTrue(t, exists, "Fresh order still resting")
+
+	cancelled, err = e.CancelOrdersOlderThan(btcBrl(), time.Hour)
+	assertNoError(t, err)
+	assertEqual(t, 0, len(cancelled), "Nothing left to sweep")
+}
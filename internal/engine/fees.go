@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+)
+
+const (
+	// DefaultFeeWindow is the rolling period fee tiers are measured over.
+	DefaultFeeWindow = 30 * 24 * time.Hour
+
+	// DefaultFeeAccount receives the fees collected on every trade.
+	DefaultFeeAccount = "fees"
+)
+
+// FeeRounding selects which way a fee is rounded to its asset's precision,
+// and with it who keeps the sub-unit remainder: the fee account or the user.
+type FeeRounding string
+
+const (
+	FeeRoundUp      FeeRounding = "up"      // remainder to the fee account
+	FeeRoundDown    FeeRounding = "down"    // remainder to the user
+	FeeRoundNearest FeeRounding = "nearest" // whichever side is closer; the default
+)
+
+// FeeTier applies MakerRate and TakerRate to users whose traded quote volume
+// over the fee window is at least MinVolume. Rates are fractions: 0.001 is
+// 0.1%. A negative MakerRate is a rebate paid to makers out of the fee
+// account.
+type FeeTier struct {
+	MinVolume float64
+	MakerRate float64
+	TakerRate float64
+}
+
+// FeeStatus is a user's position in the fee schedule.
+type FeeStatus struct {
+	Volume float64 // quote volume traded within the fee window
+	Tier   int     // index into Config.FeeTiers; -1 when no tier applies
+	FeeTier
+}
+
+// FeeStatus returns userID's rolling volume and the tier it currently earns.
+func (e *Engine) FeeStatus(userID string) FeeStatus {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	volume := e.userVolume(userID)
+	tier := e.feeTier(volume)
+
+	status := FeeStatus{Volume: volume, Tier: tier}
+	if tier >= 0 {
+		status.FeeTier = e.config.FeeTiers[tier]
+	}
+	return status
+}
+
+// feeRate returns the rate userID pays on a fill, as taker or maker. Must be
+// called with e.mu held.
+func (e *Engine) feeRate(userID string, taker bool) float64 {
+	if len(e.config.FeeTiers) == 0 {
+		return 0
+	}
+
+	tier := e.feeTier(e.userVolume(userID))
+	if tier < 0 {
+		return 0
+	}
+	if taker {
+		return e.config.FeeTiers[tier].TakerRate
+	}
+	return e.config.FeeTiers[tier].MakerRate
+}
+
+// feeTier returns the index of the highest tier volume qualifies for, or -1.
+func (e *Engine) feeTier(volume float64) int {
+	tier := -1
+	for i, t := range e.config.FeeTiers {
+		if volume < t.MinVolume {
+			break
+		}
+		tier = i
+	}
+	return tier
+}
+
+// roundFee rounds fee, or a rebate given as a negative fee, to asset's
+// precision as Config.FeeRounding says. Rounding up always favours the fee
+// account: fees grow and rebates shrink. The user is then paid exactly what
+// they receive minus this value, so the two always add up to what the match
+// moved.
+func (e *Engine) roundFee(asset string, fee float64) float64 {
+	decimals, ok := e.accounts.Precision(asset)
+	if !ok || fee == 0 {
+		return fee
+	}
+	scale := math.Pow10(decimals)
+	units := fee * scale
+
+	switch e.config.FeeRounding {
+	case FeeRoundUp:
+		units = math.Ceil(units - 0.000000001)
+	case FeeRoundDown:
+		units = math.Floor(units + 0.000000001)
+	default:
+		units = math.Round(units)
+	}
+	return units / scale
+}
+
+// collectFee credits fee to the fee account. Must be called with e.mu held.
+func (e *Engine) collectFee(asset string, fee float64) error {
+	if fee <= 0 {
+		return nil
+	}
+	if err := e.accounts.Credit(e.config.FeeAccount, asset, fee); err != nil {
+		return fmt.Errorf("fee credit failed: %w", err)
+	}
+	return nil
+}
+
+// payRebate moves rebate from the fee account to userID. The fee account is
+// never overdrawn: it has to be pre-funded (or hold enough collected fees),
+// and a rebate it cannot cover is skipped so the fill still settles. Must be
+// called with e.mu held.
+func (e *Engine) payRebate(userID, asset string, rebate float64) error {
+	rebate = e.roundAsset(asset, rebate)
+	if rebate <= 0 {
+		return nil
+	}
+
+	if err := e.accounts.Debit(e.config.FeeAccount, asset, rebate); err != nil {
+		if errors.Is(err, account.ErrInsufficientBalance) {
+			e.stats.rebatesSkipped.Add(1)
+			return nil
+		}
+		return fmt.Errorf("rebate debit failed: %w", err)
+	}
+	if err := e.accounts.Credit(userID, asset, rebate); err != nil {
+		return fmt.Errorf("rebate credit failed: %w", err)
+	}
+	return nil
+}
+
+// userVolume returns userID's quote volume inside the fee window. Must be
+// called with e.mu held.
+func (e *Engine) userVolume(userID string) float64 {
+	v, ok := e.volumes[userID]
+	if !ok {
+		return 0
+	}
+	return v.since(e.config.Clock.Now().Add(-e.config.FeeWindow))
+}
+
+// addUserVolume records quote traded by userID at ts and drops entries that
+// fell out of the fee window. Must be called with e.mu held.
+func (e *Engine) addUserVolume(userID string, ts time.Time, quote float64) {
+	v, ok := e.volumes[userID]
+	if !ok {
+		v = &rollingVolume{}
+		e.volumes[userID] = v
+	}
+	v.add(ts, quote)
+	v.prune(e.config.Clock.Now().Add(-e.config.FeeWindow))
+}
+
+type volumeEntry struct {
+	at    time.Time
+	quote float64
+}
+
+// rollingVolume is a time-ordered list of traded amounts.
+type rollingVolume struct {
+	entries []volumeEntry
+}
+
+func (r *rollingVolume) add(at time.Time, quote float64) {
+	r.entries = append(r.entries, volumeEntry{at: at, quote: quote})
+}
+
+// prune drops entries older than cutoff.
+func (r *rollingVolume) prune(cutoff time.Time) {
+	i := 0
+	for i < len(r.entries) && r.entries[i].at.Before(cutoff) {
+		i++
+	}
+	r.entries = r.entries[i:]
+}
+
+// since sums the entries at or after cutoff.
+func (r *rollingVolume) since(cutoff time.Time) float64 {
+	total := 0.0
+	for i := len(r.entries) - 1; i >= 0 && !r.entries[i].at.Before(cutoff); i-- {
+		total += r.entries[i].quote
+	}
+	return total
+}
+
+// sortedFeeTiers returns a copy of tiers ordered by MinVolume.
+func sortedFeeTiers(tiers []FeeTier) []FeeTier {
+	if len(tiers) == 0 {
+		return nil
+	}
+	sorted := append([]FeeTier(nil), tiers...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].MinVolume < sorted[j].MinVolume
+	})
+	return sorted
+}
@@ -0,0 +1,290 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+func setupFeeEngine() (*Engine, *clock.Fake) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+
+	cfg := DefaultConfig()
+	cfg.Clock = clk
+	cfg.FeeTiers = []FeeTier{
+		{MinVolume: 50_000, MakerRate: 0.0005, TakerRate: 0.001},
+		{MinVolume: 0, MakerRate: 0.001, TakerRate: 0.002},
+	}
+	return setupEngineWithConfig(cfg), clk
+}
+
+// sellToUser1 has user 2 rest an ask that user 1 then takes.
+func sellToUser1(t *testing.T, e *Engine, amount float64) {
+	t.Helper()
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, amount)
+	assertNoError(t, err)
+	_, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, amount)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Matches")
+}
+
+func TestEngine_Fees_TierBoundary(t *testing.T) {
+	e, _ := setupFeeEngine()
+
+	status := e.FeeStatus("1")
+	assertEqual(t, 0, status.Tier, "Starts in the base tier")
+	assertFloat(t, 0.002, status.TakerRate, "Base taker rate")
+
+	// Two 25000 BRL trades at base rates: taker pays 0.2% in BTC, maker 0.1% in BRL
+	sellToUser1(t, e, 0.5)
+	assertFloat(t, 10.499, e.accounts.GetBalance("1", "BTC").Available, "Buyer BTC after taker fee")
+	assertFloat(t, 124_975, e.accounts.GetBalance("2", "BRL").Available, "Seller BRL after maker fee")
+
+	sellToUser1(t, e, 0.5)
+
+	// 50000 BRL traded crosses into the next tier
+	status = e.FeeStatus("1")
+	assertFloat(t, 50_000, status.Volume, "Rolling volume")
+	assertEqual(t, 1, status.Tier, "Tier after crossing the boundary")
+	assertFloat(t, 0.001, status.TakerRate, "Discounted taker rate")
+
+	sellToUser1(t, e, 0.5)
+	assertFloat(t, 11.4975, e.accounts.GetBalance("1", "BTC").Available, "Third fill charged 0.1%")
+	assertFloat(t, 174_937.5, e.accounts.GetBalance("2", "BRL").Available, "Third fill charged 0.05%")
+
+	// Everything withheld went to the fee account
+	assertFloat(t, 0.0025, e.accounts.GetBalance(DefaultFeeAccount, "BTC").Available, "BTC fees")
+	assertFloat(t, 62.5, e.accounts.GetBalance(DefaultFeeAccount, "BRL").Available, "BRL fees")
+}
+
+func TestEngine_AllowSelfTrade_NetNeutral(t *testing.T) {
+	e, _ := setupFeeEngine()
+
+	ask, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 0.6)
+	assertNoError(t, err)
+
+	order, matches, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_100, 1,
+		OrderOptions{AllowSelfTrade: true})
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Crossed the own ask")
+	assertEqual(t, "1", matches[0].Bid.UserID, "Buyer")
+	assertEqual(t, "1", matches[0].Ask.UserID, "Seller")
+	assertEqual(t, orderbook.OrderPartiallyFilled, order.State, "Rests the rest")
+
+	_, resting := e.GetOrderbook(btcBrl()).GetOrder(ask.ID)
+	assertFalse(t, resting, "Own ask consumed")
+
+	// Totals unchanged and no fees: only the resting 0.4 @ 50100 stays locked
+	brl := e.accounts.GetBalance("1", "BRL")
+	btc := e.accounts.GetBalance("1", "BTC")
+	assertFloat(t, 100_000, brl.Total(), "BRL total unchanged")
+	assertFloat(t, 10, btc.Total(), "BTC total unchanged")
+	assertFloat(t, 0.4*50_100, brl.Locked, "Only the resting bid locked")
+	assertFloat(t, 0, btc.Locked, "Ask lock released")
+	assertTrue(t, e.accounts.GetBalance(e.config.FeeAccount, "BRL") == nil, "No quote fee")
+	assertTrue(t, e.accounts.GetBalance(e.config.FeeAccount, "BTC") == nil, "No base fee")
+	assertFloat(t, 0, e.FeeStatus("1").Volume, "No fee-tier volume")
+
+	// Without the flag the default STP still applies
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 0.1)
+	assertEqual(t, ErrSelfTrade, err, "STP by default")
+}
+
+func TestEngine_AllowSelfTrade_KeptOffPublicData(t *testing.T) {
+	e := setupEngine()
+	pair := btcBrl()
+
+	trade(t, e, 50_000)
+	stop, err := e.PlaceTrailingStop("2", pair, orderbook.Ask, 1_000, 0.5)
+	assertNoError(t, err)
+
+	_, _, err = e.PlaceOrder("1", pair, orderbook.Ask, 45_000, 0.2)
+	assertNoError(t, err)
+	_, matches, err := e.PlaceOrderWithOptions("1", pair, orderbook.Bid, 45_000, 0.2,
+		OrderOptions{AllowSelfTrade: true})
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Self-matched")
+	assertEqual(t, uint64(0), matches[0].PairSeq, "No pair sequence number")
+
+	trades := e.Trades(pair)
+	assertEqual(t, 1, len(trades), "Only the public trade on the tape")
+	assertFloat(t, 50_000, e.lastTradePrice(pair), "Last price unchanged")
+	assertFloat(t, 0.1, e.Stats().Volume["BTC"], "Volume unchanged")
+	assertEqual(t, TrailingActive, e.TrailingStops("2")[0].State, "Stop not triggered")
+	assertFloat(t, 50_000, stop.Watermark, "Watermark unchanged")
+
+	own, total := e.TradesBetween("1", "1", 0, 0)
+	assertEqual(t, 1, total, "Admin view lists the self-trade")
+	assertTrue(t, own[0].SelfTrade, "Flagged")
+
+	trade(t, e, 50_000)
+	assertEqual(t, uint64(2), e.Trades(pair)[1].PairSeq, "Pair sequence has no gap")
+}
+
+func TestEngine_FeeAccount_CannotTrade(t *testing.T) {
+	e, _ := setupFeeEngine()
+	_ = e.accounts.Credit(DefaultFeeAccount, "BRL", 100_000)
+
+	_, _, err := e.PlaceOrder(DefaultFeeAccount, btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertEqual(t, ErrReservedUserID, err, "Limit order")
+	_, _, err = e.PlaceMarketOrder(DefaultFeeAccount, btcBrl(), orderbook.Bid, 0.1)
+	assertEqual(t, ErrReservedUserID, err, "Market order")
+	assertFloat(t, 0, e.accounts.GetBalance(DefaultFeeAccount, "BRL").Locked, "Nothing locked")
+}
+
+func TestEngine_Fees_VolumeExpires(t *testing.T) {
+	e, clk := setupFeeEngine()
+
+	sellToUser1(t, e, 1)
+	assertEqual(t, 1, e.FeeStatus("2").Tier, "Earned the discount")
+
+	clk.Advance(DefaultFeeWindow + time.Second)
+
+	status := e.FeeStatus("2")
+	assertFloat(t, 0, status.Volume, "Volume left the window")
+	assertEqual(t, 0, status.Tier, "Back to the base tier")
+}
+
+func TestEngine_Fees_NoTiers(t *testing.T) {
+	e := setupEngine()
+
+	sellToUser1(t, e, 0.5)
+	assertFloat(t, 10.5, e.accounts.GetBalance("1", "BTC").Available, "No fee charged")
+	assertEqual(t, -1, e.FeeStatus("1").Tier, "No tier")
+}
+
+func setupRebateEngine() *Engine {
+	cfg := DefaultConfig()
+	cfg.FeeTiers = []FeeTier{{MinVolume: 0, MakerRate: -0.0002, TakerRate: 0.001}}
+	return setupEngineWithConfig(cfg)
+}
+
+func TestEngine_Fees_MakerRebate(t *testing.T) {
+	e := setupRebateEngine()
+	assertNoError(t, e.accounts.Credit(DefaultFeeAccount, "BRL", 100))
+	assertNoError(t, e.accounts.Credit(DefaultFeeAccount, "BTC", 1))
+
+	// Ask maker: rebate of 0.02% of 25,000 BRL, paid in quote
+	sellToUser1(t, e, 0.5)
+	assertFloat(t, 125_005, e.accounts.GetBalance("2", "BRL").Available, "Maker credited the rebate")
+	assertFloat(t, 95, e.accounts.GetBalance(DefaultFeeAccount, "BRL").Available, "Collector debited the same amount")
+	assertFloat(t, 10.4995, e.accounts.GetBalance("1", "BTC").Available, "Taker still pays its fee")
+	assertFloat(t, 1.0005, e.accounts.GetBalance(DefaultFeeAccount, "BTC").Available, "Taker fee collected")
+
+	// Bid maker: rebate of 0.02% of 0.5 BTC, paid in base
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	assertFloat(t, 10.9996, e.accounts.GetBalance("1", "BTC").Available, "Maker credited the rebate in base")
+	assertFloat(t, 1.0004, e.accounts.GetBalance(DefaultFeeAccount, "BTC").Available, "Collector paid the base rebate")
+	assertFloat(t, 120, e.accounts.GetBalance(DefaultFeeAccount, "BRL").Available, "Taker fee collected in quote")
+	assertEqual(t, int64(0), e.Stats().RebatesSkipped, "Every rebate paid")
+}
+
+func TestEngine_Fees_MakerRebateUnfunded(t *testing.T) {
+	e := setupRebateEngine()
+
+	// The collector holds no BRL, so the rebate is skipped but the fill settles
+	sellToUser1(t, e, 0.5)
+	assertFloat(t, 125_000, e.accounts.GetBalance("2", "BRL").Available, "No rebate paid")
+	assertTrue(t, e.accounts.GetBalance(DefaultFeeAccount, "BRL") == nil, "Collector never overdrawn")
+	assertEqual(t, int64(1), e.Stats().RebatesSkipped, "Skipped rebate counted")
+}
+
+func TestEngine_Fees_RoundingDirection(t *testing.T) {
+	tests := []struct {
+		rounding  FeeRounding
+		fee       float64
+		sellerBRL float64
+	}{
+		{FeeRoundUp, 0.02, 100_009.98},
+		{FeeRoundDown, 0.01, 100_009.99},
+		{FeeRoundNearest, 0.01, 100_009.99},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.rounding), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.FeeRounding = tt.rounding
+			cfg.FeeTiers = []FeeTier{{MinVolume: 0, MakerRate: 0.0013, TakerRate: 0.0013}}
+			e := setupEngineWithConfig(cfg)
+
+			// 10 BRL traded: the maker's 0.013 BRL fee is not a whole cent
+			_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 1_000, 0.01)
+			assertNoError(t, err)
+			_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 1_000, 0.01)
+			assertNoError(t, err)
+
+			assertFloat(t, tt.fee, e.accounts.GetBalance(DefaultFeeAccount, "BRL").Available, "Fee collected")
+			assertFloat(t, tt.sellerBRL, e.accounts.GetBalance("2", "BRL").Available, "Seller credited the rest")
+			assertFloat(t, 99_990, e.accounts.GetBalance("1", "BRL").Available, "Buyer debited the trade value")
+		})
+	}
+}
+
+func TestEngine_Fees_DefaultRoundingSparesSmallFills(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.FeeTiers = []FeeTier{{MinVolume: 0, MakerRate: 0.0013, TakerRate: 0.0013}}
+	e := setupEngineWithConfig(cfg)
+
+	// A 0.01 BRL fill: rounding its 0.000013 fee up would take all of it
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 1_000, 0.00001)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 1_000, 0.00001)
+	assertNoError(t, err)
+
+	assertTrue(t, e.accounts.GetBalance(DefaultFeeAccount, "BRL") == nil, "No quote fee collected")
+	assertFloat(t, 100_000.01, e.accounts.GetBalance("2", "BRL").Available, "Seller keeps the fill")
+}
+
+func TestEngine_Fees_QuoteConserved(t *testing.T) {
+	for _, rounding := range []FeeRounding{FeeRoundUp, FeeRoundDown, FeeRoundNearest} {
+		t.Run(string(rounding), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.FeeRounding = rounding
+			cfg.FeeTiers = []FeeTier{{MinVolume: 0, MakerRate: 0.00137, TakerRate: 0.00251}}
+			e := setupEngineWithConfig(cfg)
+
+			before, err := e.accounts.AssetTotals()
+			assertNoError(t, err)
+
+			// Prices and amounts chosen so almost no trade is worth a whole
+			// number of cents, with makers on both sides and bids that fill
+			// below their limit
+			for i := 1; i <= 300; i++ {
+				price := 50_000 + float64(i)*0.37
+				amount := 0.00012345 + float64(i%7)*0.00000313
+				maker, taker := "1", "2"
+				makerSide, takerSide := orderbook.Bid, orderbook.Ask
+				takerPrice := price
+				if i%2 == 0 {
+					maker, taker = taker, maker
+					makerSide, takerSide = takerSide, makerSide
+					takerPrice = price + float64(i%5)*1.13
+				}
+
+				_, _, err := e.PlaceOrder(maker, btcBrl(), makerSide, price, amount)
+				assertNoError(t, err)
+				_, matches, err := e.PlaceOrder(taker, btcBrl(), takerSide, takerPrice, amount)
+				assertNoError(t, err)
+				assertEqual(t, 1, len(matches), "Matches")
+			}
+
+			after, err := e.accounts.AssetTotals()
+			assertNoError(t, err)
+			assertEqual(t, len(before), len(after), "Assets")
+			for i := range before {
+				if before[i].Total() != after[i].Total() {
+					t.Errorf("%s total changed from %.8f to %.8f", before[i].Asset, before[i].Total(), after[i].Total())
+				}
+				if after[i].Locked != 0 {
+					t.Errorf("%s left locked: %.8f", after[i].Asset, after[i].Locked)
+				}
+			}
+			assertTrue(t, e.accounts.GetBalance(DefaultFeeAccount, "BRL").Available > 0, "Quote fees collected")
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
+)
+
+// Funds is an amount of one asset.
+type Funds struct {
+	Asset  string
+	Amount float64
+}
+
+// RequiredFunds returns what placing an order would lock right now, without
+// locking anything or looking at balances. A limit bid locks its quote
+// reserve at price and an ask its amount of base. A market order locks the
+// estimate placement makes by walking the book, and fails with
+// ErrInsufficientLiquidity when the book cannot fill it; price is ignored.
+// Any other orderType is treated as a limit. price and amount are normalized
+// to the pair's ticks as on placement.
+func (e *Engine) RequiredFunds(pair Pair, side orderbook.Side, orderType orderbook.OrderType, price, amount float64) (Funds, error) {
+	if !pair.IsValid() {
+		return Funds{}, ErrInvalidPair
+	}
+	if side != orderbook.Bid && side != orderbook.Ask {
+		return Funds{}, orderbook.ErrInvalidSide
+	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) || math.IsNaN(price) || math.IsInf(price, 0) {
+		return Funds{}, orderbook.ErrNonFinite
+	}
+	if amount <= 0 {
+		return Funds{}, orderbook.ErrInvalidAmount
+	}
+
+	cfg, err := e.listedPairConfig(pair)
+	if err != nil {
+		return Funds{}, err
+	}
+
+	amount, ok := e.normalizeToTick(amount, cfg.AmountTick)
+	if !ok {
+		return Funds{}, ErrInvalidAmountTick
+	}
+	if !cfg.lotAligned(amount) {
+		return Funds{}, ErrInvalidAmountLot
+	}
+
+	if orderType == orderbook.OrderTypeMarket {
+		e.mu.RLock()
+		cost := e.estimateMarketOrderCost(e.orderbooks[pair.String()], "", side, amount, cfg.AmountTick, cfg.MaxMarketGap, false)
+		e.mu.RUnlock()
+
+		if cost == 0 {
+			return Funds{}, ErrInsufficientLiquidity
+		}
+		if side == orderbook.Bid {
+			return Funds{Asset: pair.Quote, Amount: cost}, nil
+		}
+		return Funds{Asset: pair.Base, Amount: cost}, nil
+	}
+
+	if price <= 0 {
+		return Funds{}, orderbook.ErrInvalidPrice
+	}
+	price, ok = e.normalizeToTick(price, cfg.PriceTick)
+	if !ok {
+		return Funds{}, ErrInvalidPriceTick
+	}
+
+	if side == orderbook.Bid {
+		return Funds{Asset: pair.Quote, Amount: e.bidReserve(pair, price, amount)}, nil
+	}
+	return Funds{Asset: pair.Base, Amount: amount}, nil
+}
+
+// AmountForBalancePct returns the order amount that spends pct percent (above
+// 0, at most 100) of userID's available balance. An ask sells that share of
+// the base. A limit bid buys what that share of the quote reserves at price;
+// a market bid buys what it pays for walking the book, leaving out userID's
+// own orders unless allowSelfTrade says the order may match them. The
+// amount is floored to the pair's amount tick, or its lot size when it has
+// one. It fails with account.ErrInsufficientBalance when the share does not
+// reach one step, a user without a balance in the asset included, and for a
+// market bid with ErrInsufficientLiquidity when the book has nothing to buy.
+//
+// Nothing is locked: the amount is only a quote of the balance right now,
+// read in one go with the book under the engine lock. Placing it locks the
+// funds again and fails if they have moved since.
+func (e *Engine) AmountForBalancePct(userID string, pair Pair, side orderbook.Side, orderType orderbook.OrderType, price, pct float64, allowSelfTrade bool) (float64, error) {
+	if !pair.IsValid() {
+		return 0, ErrInvalidPair
+	}
+	if side != orderbook.Bid && side != orderbook.Ask {
+		return 0, orderbook.ErrInvalidSide
+	}
+	if math.IsNaN(pct) || math.IsNaN(price) || math.IsInf(price, 0) {
+		return 0, orderbook.ErrNonFinite
+	}
+	if pct <= 0 || pct > 100 {
+		return 0, ErrInvalidAmountPct
+	}
+
+	cfg, err := e.listedPairConfig(pair)
+	if err != nil {
+		return 0, err
+	}
+	step := cfg.AmountTick
+	if cfg.LotSize > 0 {
+		step = cfg.LotSize
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var amount float64
+	switch {
+	case side == orderbook.Ask:
+		budget := e.available(userID, pair.Base) * pct / 100
+		amount = utils.FloorToTick(budget, step)
+
+	case orderType == orderbook.OrderTypeMarket:
+		liquidityFor := userID
+		if allowSelfTrade {
+			liquidityFor = ""
+		}
+		amount, err = e.marketBuyAmount(pair, cfg, liquidityFor, e.quoteBudget(userID, pair, pct), step)
+		if err != nil {
+			return 0, err
+		}
+
+	default:
+		if price <= 0 {
+			return 0, orderbook.ErrInvalidPrice
+		}
+		price, ok := e.normalizeToTick(price, cfg.PriceTick)
+		if !ok {
+			return 0, ErrInvalidPriceTick
+		}
+		budget := e.quoteBudget(userID, pair, pct)
+		amount = utils.FloorToTick(budget/price, step)
+		for amount > 0 && e.bidReserve(pair, price, amount) > budget {
+			amount = utils.FloorToTick(amount-step, step)
+		}
+	}
+
+	if amount <= 0 {
+		return 0, account.ErrInsufficientBalance
+	}
+	return amount, nil
+}
+
+// quoteBudget returns pct percent of userID's available quote, floored to
+// the quote's precision so no rounding of a reserve can exceed it.
+func (e *Engine) quoteBudget(userID string, pair Pair, pct float64) float64 {
+	budget := e.available(userID, pair.Quote) * pct / 100
+	if decimals, ok := e.accounts.Precision(pair.Quote); ok {
+		budget = utils.FloorToTick(budget, math.Pow10(-decimals))
+	}
+	return budget
+}
+
+// available returns userID's available balance of asset, 0 when the user
+// has never held it.
+func (e *Engine) available(userID, asset string) float64 {
+	if balance := e.accounts.GetBalance(userID, asset); balance != nil {
+		return balance.Available
+	}
+	return 0
+}
+
+// marketBuyAmount returns how much base a market bid can buy on pair for
+// budget, floored to step, checked against the estimate placement locks.
+// The resting asks of liquidityFor are left out; pass "" to count them all.
+// Must be called with e.mu held.
+func (e *Engine) marketBuyAmount(pair Pair, cfg PairConfig, liquidityFor string, budget, step float64) (float64, error) {
+	ob := e.orderbooks[pair.String()]
+	if ob == nil {
+		return 0, ErrInsufficientLiquidity
+	}
+
+	amount, left := 0.0, budget
+	for _, askLimit := range ob.Asks() {
+		price := askLimit.Price(ob.PriceTick())
+		volume := askLimit.VolumeAgainst(liquidityFor)
+		if volume*price <= left {
+			amount += volume
+			left -= volume * price
+			continue
+		}
+		amount += left / price
+		break
+	}
+	if amount == 0 {
+		return 0, ErrInsufficientLiquidity
+	}
+
+	amount = utils.FloorToTick(amount, step)
+	for amount > 0 {
+		cost := e.estimateMarketOrderCost(ob, liquidityFor, orderbook.Bid, amount, cfg.AmountTick, cfg.MaxMarketGap, false)
+		if cost == 0 {
+			return 0, ErrInsufficientLiquidity
+		}
+		if cost <= budget {
+			break
+		}
+		amount = utils.FloorToTick(amount-step, step)
+	}
+	return amount, nil
+}
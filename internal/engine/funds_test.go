@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_RequiredFunds(t *testing.T) {
+	e := setupEngine()
+
+	for _, o := range []struct {
+		userID string
+		side   orderbook.Side
+		price  float64
+		amount float64
+	}{
+		{"2", orderbook.Ask, 50_000, 0.5},
+		{"2", orderbook.Ask, 51_000, 0.5},
+		{"1", orderbook.Bid, 49_000, 0.5},
+		{"1", orderbook.Bid, 48_000, 1},
+	} {
+		_, _, err := e.PlaceOrder(o.userID, btcBrl(), o.side, o.price, o.amount)
+		assertNoError(t, err)
+	}
+	lockedBefore := e.accounts.GetBalance("1", "BRL").Locked
+
+	funds, err := e.RequiredFunds(btcBrl(), orderbook.Bid, orderbook.OrderTypeLimit, 50_000, 0.3)
+	assertNoError(t, err)
+	assertEqual(t, "BRL", funds.Asset, "Limit bid locks quote")
+	assertFloat(t, 15_000, funds.Amount, "Price times amount")
+
+	funds, err = e.RequiredFunds(btcBrl(), orderbook.Ask, orderbook.OrderTypeLimit, 60_000, 0.3)
+	assertNoError(t, err)
+	assertEqual(t, "BTC", funds.Asset, "Limit ask locks base")
+	assertFloat(t, 0.3, funds.Amount, "The amount")
+
+	funds, err = e.RequiredFunds(btcBrl(), orderbook.Bid, orderbook.OrderTypeMarket, 0, 0.8)
+	assertNoError(t, err)
+	assertEqual(t, "BRL", funds.Asset, "Market bid locks quote")
+	assertFloat(t, 40_300, funds.Amount, "0.5*50,000 + 0.3*51,000")
+
+	funds, err = e.RequiredFunds(btcBrl(), orderbook.Ask, orderbook.OrderTypeMarket, 0, 1)
+	assertNoError(t, err)
+	assertEqual(t, "BTC", funds.Asset, "Market ask locks base")
+	assertFloat(t, 1, funds.Amount, "The amount")
+
+	_, err = e.RequiredFunds(btcBrl(), orderbook.Bid, orderbook.OrderTypeMarket, 0, 2)
+	assertEqual(t, ErrInsufficientLiquidity, err, "Book too thin")
+
+	_, err = e.RequiredFunds(btcBrl(), orderbook.Bid, orderbook.OrderTypeLimit, 0, 1)
+	assertEqual(t, orderbook.ErrInvalidPrice, err, "Limit needs a price")
+
+	assertFloat(t, lockedBefore, e.accounts.GetBalance("1", "BRL").Locked, "Nothing locked")
+}
+
+func TestEngine_AmountForBalancePct_Sell(t *testing.T) {
+	e := setupEngine()
+
+	amount, err := e.AmountForBalancePct("2", btcBrl(), orderbook.Ask, orderbook.OrderTypeLimit, 50_000, 33.3, false)
+	assertNoError(t, err)
+	assertFloat(t, 3.33, amount, "A third of 10 BTC")
+
+	amount, err = e.AmountForBalancePct("2", btcBrl(), orderbook.Ask, orderbook.OrderTypeLimit, 50_000, 100, false)
+	assertNoError(t, err)
+	assertFloat(t, 10, amount, "All of it")
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, amount)
+	assertNoError(t, err)
+	assertFloat(t, 0, e.accounts.GetBalance("2", "BTC").Available, "Nothing left available")
+
+	_, err = e.AmountForBalancePct("2", btcBrl(), orderbook.Ask, orderbook.OrderTypeLimit, 50_000, 50, false)
+	assertEqual(t, account.ErrInsufficientBalance, err, "Nothing left to sell")
+}
+
+func TestEngine_AmountForBalancePct_NoBalance(t *testing.T) {
+	e := setupEngine()
+
+	_, err := e.AmountForBalancePct("3", btcBrl(), orderbook.Ask, orderbook.OrderTypeLimit, 50_000, 50, false)
+	assertEqual(t, account.ErrInsufficientBalance, err, "No base balance")
+
+	_, err = e.AmountForBalancePct("3", btcBrl(), orderbook.Bid, orderbook.OrderTypeLimit, 50_000, 50, false)
+	assertEqual(t, account.ErrInsufficientBalance, err, "No quote balance")
+}
+
+func TestEngine_AmountForBalancePct_Buy(t *testing.T) {
+	e := setupEngine()
+
+	amount, err := e.AmountForBalancePct("1", btcBrl(), orderbook.Bid, orderbook.OrderTypeLimit, 50_000, 12.5, false)
+	assertNoError(t, err)
+	assertFloat(t, 0.25, amount, "12,500 BRL at 50,000")
+
+	// 100,000 / 30,000 does not divide evenly; the reserve must still fit
+	amount, err = e.AmountForBalancePct("1", btcBrl(), orderbook.Bid, orderbook.OrderTypeLimit, 30_000, 100, false)
+	assertNoError(t, err)
+	assertFloat(t, 3.33333333, amount, "Floored to the amount tick")
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 30_000, amount)
+	assertNoError(t, err)
+}
+
+func TestEngine_AmountForBalancePct_MarketBuy(t *testing.T) {
+	e := setupEngine()
+
+	_, err := e.AmountForBalancePct("1", btcBrl(), orderbook.Bid, orderbook.OrderTypeMarket, 0, 100, false)
+	assertEqual(t, ErrInsufficientLiquidity, err, "Empty book")
+
+	for _, level := range []struct{ price, amount float64 }{{50_000, 0.5}, {51_000, 1}, {52_000, 2}} {
+		_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, level.price, level.amount)
+		assertNoError(t, err)
+	}
+
+	// 25,000 + 51,000 for the first two levels, 24,000 of the third
+	amount, err := e.AmountForBalancePct("1", btcBrl(), orderbook.Bid, orderbook.OrderTypeMarket, 0, 100, false)
+	assertNoError(t, err)
+	assertFloat(t, 1.96153846, amount, "Walks the book")
+
+	order, _, err := e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, amount)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderFilled, order.State, "Filled")
+
+	_, err = e.AmountForBalancePct("1", btcBrl(), orderbook.Bid, orderbook.OrderTypeMarket, 0, 0, false)
+	assertEqual(t, ErrInvalidAmountPct, err, "Zero percent")
+	_, err = e.AmountForBalancePct("1", btcBrl(), orderbook.Bid, orderbook.OrderTypeMarket, 0, 150, false)
+	assertEqual(t, ErrInvalidAmountPct, err, "Above 100 percent")
+}
+
+func TestEngine_AmountForBalancePct_MarketBuyAllowSelfTrade(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+
+	_, err = e.AmountForBalancePct("1", btcBrl(), orderbook.Bid, orderbook.OrderTypeMarket, 0, 10, false)
+	assertEqual(t, ErrInsufficientLiquidity, err, "Own ask left out")
+
+	amount, err := e.AmountForBalancePct("1", btcBrl(), orderbook.Bid, orderbook.OrderTypeMarket, 0, 10, true)
+	assertNoError(t, err)
+	assertFloat(t, 0.2, amount, "Own ask counted when self-trades are allowed")
+}
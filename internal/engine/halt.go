@@ -0,0 +1,18 @@
+package engine
+
+// Halt freezes order placement on every pair until Resume is called. Unlike
+// HaltPair it is not tied to the registry and also stops unlisted pairs and
+// trailing stops. Cancels keep working so users can pull their orders.
+func (e *Engine) Halt() {
+	e.halted.Store(true)
+}
+
+// Resume lifts a Halt. Pairs halted with HaltPair stay halted.
+func (e *Engine) Resume() {
+	e.halted.Store(false)
+}
+
+// Halted reports whether trading is halted engine-wide.
+func (e *Engine) Halted() bool {
+	return e.halted.Load()
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_Halt_BlocksPlacementButNotCancels(t *testing.T) {
+	e := setupEngine()
+	ethBrl := Pair{Base: "ETH", Quote: "BRL"}
+
+	resting, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+
+	e.Halt()
+	assertTrue(t, e.Halted(), "Engine reports halted")
+
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertEqual(t, ErrEngineHalted, err, "Limit order during halt")
+	_, _, err = e.PlaceMarketOrder("2", btcBrl(), orderbook.Ask, 1)
+	assertEqual(t, ErrEngineHalted, err, "Market order during halt")
+	_, _, err = e.PlaceOrder("1", ethBrl, orderbook.Bid, 10_000, 1)
+	assertEqual(t, ErrEngineHalted, err, "Other pair during halt")
+	_, err = e.PlaceTrailingStop("2", btcBrl(), orderbook.Ask, 1_000, 1)
+	assertEqual(t, ErrEngineHalted, err, "Trailing stop during halt")
+
+	cancelled, err := e.CancelOrder("1", btcBrl(), resting.ID)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderCancelled, cancelled.Order.State, "Cancel during halt")
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BRL").Locked, "Funds unlocked")
+	assertEqual(t, 0, len(e.Trades(btcBrl())), "Nothing traded")
+
+	e.Resume()
+	assertFalse(t, e.Halted(), "Engine resumed")
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+}
+
+func TestEngine_Resume_KeepsPairHalts(t *testing.T) {
+	e := setupEngine()
+	assertNoError(t, e.HaltPair(btcBrl()))
+
+	e.Halt()
+	e.Resume()
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertEqual(t, ErrPairHalted, err, "Pair halt survives engine resume")
+}
+
+func TestEngine_Halt_KeepsNonceUnspent(t *testing.T) {
+	e := setupEngine()
+	e.Halt()
+
+	opts := OrderOptions{Nonce: 7}
+	_, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 0.1, opts)
+	assertEqual(t, ErrEngineHalted, err, "Limit order during halt")
+	_, _, err = e.PlaceMarketOrderWithOptions("1", btcBrl(), orderbook.Bid, 0.1, opts)
+	assertEqual(t, ErrEngineHalted, err, "Market order during halt")
+	_, err = e.PlaceOrReplaceWithOptions("1", btcBrl(), "bid-1", orderbook.Bid, 50_000, 0.1, opts)
+	assertEqual(t, ErrEngineHalted, err, "Replace during halt")
+	assertEqual(t, uint64(0), e.LastNonce("1"), "Nonce not spent")
+
+	e.Resume()
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 0.1, opts)
+	assertNoError(t, err)
+}
@@ -0,0 +1,496 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+// Trade is a match as recorded by the engine. Seq is exchange-wide, so trades
+// of every pair merge into one totally ordered tape; PairSeq counts within the
+// pair.
+type Trade struct {
+	Seq        uint64
+	PairSeq    uint64
+	Pair       Pair
+	Price      float64
+	Amount     float64
+	BidOrderID int64
+	AskOrderID int64
+	BuyerID    string
+	SellerID   string
+	BidTag     string // client tags of the two orders, private to each owner
+	AskTag     string
+	TakerSide  orderbook.Side // empty for auction trades, where neither side took
+	SelfTrade  bool           // buyer and seller are the same user; kept off the public tape
+	Timestamp  time.Time
+}
+
+// MaxOrderFills bounds the fills kept per order. Older fills of very active
+// orders are dropped; FillCount and FilledQuote still cover all of them.
+const MaxOrderFills = 100
+
+// Fill is one execution of an order, seen from that order's side.
+type Fill struct {
+	TradeSeq       uint64
+	Price          float64
+	Amount         float64
+	Role           string // "maker" or "taker"
+	CounterpartyID string
+	Tag            string // the order's client tag
+	Timestamp      time.Time
+}
+
+// orderFills is the bounded fill log of one order.
+type orderFills struct {
+	fills []Fill // most recent MaxOrderFills, oldest first
+	count int    // every fill, including dropped ones
+}
+
+func (f *orderFills) add(fill Fill) {
+	f.count++
+	if len(f.fills) == MaxOrderFills {
+		copy(f.fills, f.fills[1:])
+		f.fills = f.fills[:MaxOrderFills-1]
+	}
+	f.fills = append(f.fills, fill)
+}
+
+// ArchivedOrder is a snapshot of an order taken when it left the book for
+// good (filled, cancelled, or a market order done executing). The engine also
+// uses it to describe a resting order, with a zero ClosedAt.
+type ArchivedOrder struct {
+	Order       orderbook.Order
+	Pair        Pair
+	FilledQuote float64 // sum of price * size over the order's fills
+	Fills       []Fill  // the most recent MaxOrderFills fills, oldest first
+	FillCount   int     // number of fills, including those beyond MaxOrderFills
+	ClosedAt    time.Time
+
+	// UnlockedAsset and Unlocked are the funds released back to the owner
+	// when the order was cancelled or expired off the book. Orders that
+	// filled or never rested release nothing here.
+	UnlockedAsset string
+	Unlocked      float64
+}
+
+// AvgFillPrice returns the volume-weighted fill price, or 0 if nothing filled.
+func (a ArchivedOrder) AvgFillPrice() float64 {
+	if a.Order.FilledAmount <= 0 {
+		return 0
+	}
+	return a.FilledQuote / a.Order.FilledAmount
+}
+
+// HistoryFilter selects a page of a user's archived orders. A nil Pair
+// matches every pair; Limit <= 0 means no limit.
+type HistoryFilter struct {
+	Pair   *Pair
+	Limit  int
+	Offset int
+}
+
+// OrderHistory returns userID's archived orders matching filter, newest
+// first, together with the total number of matches before paging.
+func (e *Engine) OrderHistory(userID string, filter HistoryFilter) ([]ArchivedOrder, int, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	archived, err := e.userArchive(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var matched []ArchivedOrder
+	for i := len(archived) - 1; i >= 0; i-- {
+		if filter.Pair != nil && archived[i].Pair != *filter.Pair {
+			continue
+		}
+		matched = append(matched, archived[i])
+	}
+
+	total := len(matched)
+	if filter.Offset >= total {
+		return []ArchivedOrder{}, total, nil
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(matched) {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total, nil
+}
+
+// Trades returns a copy of every trade executed on pair, oldest first,
+// leaving out self-trades.
+func (e *Engine) Trades(pair Pair) []Trade {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var result []Trade
+	for _, t := range e.trades {
+		if t.Pair == pair && !t.SelfTrade {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// OrderTrades returns a page of the trades that filled userID's order
+// orderID on pair, oldest first, together with the total number of such
+// trades. Unlike the order's own fill log it is not bounded by MaxOrderFills.
+// Limit <= 0 means no limit.
+func (e *Engine) OrderTrades(userID string, pair Pair, orderID int64, limit, offset int) ([]Trade, int, error) {
+	// Resolves ownership for resting and archived orders alike
+	if _, err := e.GetOrder(userID, pair, orderID); err != nil {
+		return nil, 0, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var matched []Trade
+	for _, t := range e.trades {
+		if t.Pair != pair {
+			continue
+		}
+		if (t.BidOrderID == orderID && t.BuyerID == userID) || (t.AskOrderID == orderID && t.SellerID == userID) {
+			matched = append(matched, t)
+		}
+	}
+
+	total := len(matched)
+	if offset >= total {
+		return []Trade{}, total, nil
+	}
+	matched = matched[offset:]
+	if limit > 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched, total, nil
+}
+
+// TradesBetween returns a page of the trades, on every pair, in which userA
+// and userB were counterparties in either direction, oldest first, together
+// with the total number of such trades. Passing the same user twice selects
+// its self-trades. Limit <= 0 means no limit.
+func (e *Engine) TradesBetween(userA, userB string, limit, offset int) ([]Trade, int) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var matched []Trade
+	for _, t := range e.trades {
+		if (t.BuyerID == userA && t.SellerID == userB) || (t.BuyerID == userB && t.SellerID == userA) {
+			matched = append(matched, t)
+		}
+	}
+
+	total := len(matched)
+	if offset >= total {
+		return []Trade{}, total
+	}
+	matched = matched[offset:]
+	if limit > 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched, total
+}
+
+// OnTrade registers fn to be called with every trade as it is recorded, in
+// sequence order, self-trades left out. fn runs with the engine lock held:
+// it must return quickly and must not call back into the engine.
+func (e *Engine) OnTrade(fn func(Trade)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tradeListeners = append(e.tradeListeners, fn)
+}
+
+// recordFills appends the trades of taker's matches, stamping each match
+// with its sequence numbers, updates the stats counters and archives every
+// order the matches took out of the book. It runs once per accepted order,
+// after the balances moved, so nothing in it can fail. Must be called with
+// e.mu held.
+func (e *Engine) recordFills(pair Pair, taker *orderbook.Order, matches []orderbook.Match) {
+	for i := range matches {
+		e.recordTrade(pair, &matches[i], taker.Side)
+
+		m := matches[i]
+		maker := m.Ask
+		if taker.Side == orderbook.Ask {
+			maker = m.Bid
+		}
+		if maker.State == orderbook.OrderFilled {
+			e.archiveOrder(pair, maker)
+		}
+	}
+
+	e.stats.ordersPlaced.Add(1)
+	e.stats.matches.Add(int64(len(matches)))
+
+	// A limit stopped by the level cap ends cancelled without resting.
+	if taker.State == orderbook.OrderFilled || taker.State == orderbook.OrderCancelled || taker.Type == orderbook.OrderTypeMarket {
+		e.archiveOrder(pair, taker)
+	}
+}
+
+// recordTrade stamps m with its sequence numbers, appends its trade and
+// credits the fills, volumes and trailing stops it moves. takerSide is the
+// side of the aggressor, or empty when neither side took, as in an auction.
+// A self-trade is kept for its owner's fills and the admin views only: it
+// takes no pair sequence number, is not published and moves no stats, last
+// price or trailing stop. Must be called with e.mu held.
+func (e *Engine) recordTrade(pair Pair, m *orderbook.Match, takerSide orderbook.Side) {
+	self := m.Bid.UserID == m.Ask.UserID
+	m.Seq = e.tradeSeq.Add(1)
+	if !self {
+		key := pair.String()
+		e.pairTradeSeq[key]++
+		m.PairSeq = e.pairTradeSeq[key]
+	}
+
+	trade := Trade{
+		Seq:        m.Seq,
+		PairSeq:    m.PairSeq,
+		Pair:       pair,
+		Price:      m.Price,
+		Amount:     m.SizeFilled,
+		BidOrderID: m.Bid.ID,
+		AskOrderID: m.Ask.ID,
+		BuyerID:    m.Bid.UserID,
+		SellerID:   m.Ask.UserID,
+		BidTag:     m.Bid.Tag,
+		AskTag:     m.Ask.Tag,
+		TakerSide:  takerSide,
+		SelfTrade:  self,
+		Timestamp:  m.Timestamp,
+	}
+	e.trades = append(e.trades, trade)
+
+	quote := m.Price * m.SizeFilled
+	e.fillQuote[m.Bid.ID] += quote
+	e.fillQuote[m.Ask.ID] += quote
+	e.addFill(m.Bid.ID, *m, m.Ask.UserID, takerSide == orderbook.Bid)
+	e.addFill(m.Ask.ID, *m, m.Bid.UserID, takerSide == orderbook.Ask)
+	if self {
+		return
+	}
+
+	for _, fn := range e.tradeListeners {
+		fn(trade)
+	}
+	e.stats.addVolume(pair.Base, m.SizeFilled)
+	e.stats.addVolume(pair.Quote, quote)
+	e.addUserVolume(m.Bid.UserID, m.Timestamp, quote)
+	e.addUserVolume(m.Ask.UserID, m.Timestamp, quote)
+	e.trackTrailingStops(pair, m.Price)
+}
+
+// archiveOrder snapshots order into its owner's history and returns the
+// snapshot. Must be called with e.mu held, once per order, after it has left
+// the book.
+func (e *Engine) archiveOrder(pair Pair, order *orderbook.Order) ArchivedOrder {
+	return e.archiveUnlocked(pair, order, "", 0)
+}
+
+// archiveUnlocked is archiveOrder for an order whose remaining lock of
+// amount in asset was just released. A refund held back while the order
+// rested is released here and reported with it; should that unlock fail,
+// the refund stays locked and is left out of the report.
+//
+// Archiving cannot fail, as it runs after the order's balances moved: what
+// the OrderStore refuses is queued and written, in order, by the next
+// archive (see flushArchive). Must be called with e.mu held.
+func (e *Engine) archiveUnlocked(pair Pair, order *orderbook.Order, asset string, amount float64) ArchivedOrder {
+	if withheld, ok := e.withheldRefunds[order.ID]; ok {
+		delete(e.withheldRefunds, order.ID)
+		if err := e.accounts.Unlock(order.UserID, pair.Quote, withheld); err != nil {
+			withheld = 0
+		}
+		if withheld > 0 && (asset == "" || asset == pair.Quote) {
+			asset = pair.Quote
+			amount = e.roundAsset(pair.Quote, amount+withheld)
+		}
+	}
+
+	snapshot := *order
+	snapshot.Limit = nil
+
+	archived := ArchivedOrder{
+		Order:         snapshot,
+		Pair:          pair,
+		FilledQuote:   e.fillQuote[order.ID],
+		ClosedAt:      e.config.Clock.Now(),
+		UnlockedAsset: asset,
+		Unlocked:      amount,
+	}
+	if log, ok := e.fills[order.ID]; ok {
+		archived.Fills = log.fills
+		archived.FillCount = log.count
+	}
+
+	e.unarchived = append(e.unarchived, archived)
+	e.flushArchive()
+	delete(e.fillQuote, order.ID)
+	delete(e.fills, order.ID)
+	e.forgetClientOrder(pair, order)
+	e.releaseWaiters(order.ID)
+	return archived
+}
+
+// flushArchive appends the queued archived orders to the OrderStore, oldest
+// first, stopping at the first it refuses. Must be called with e.mu held.
+func (e *Engine) flushArchive() {
+	for len(e.unarchived) > 0 {
+		if err := e.archive.Append(e.unarchived[0]); err != nil {
+			return
+		}
+		e.unarchived = e.unarchived[1:]
+	}
+}
+
+// userArchive returns userID's archived orders, oldest first: the ones in
+// the OrderStore, then any still queued for it. Must be called with e.mu
+// held.
+func (e *Engine) userArchive(userID string) ([]ArchivedOrder, error) {
+	archived, err := e.archive.UserOrders(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, queued := range e.unarchived {
+		if queued.Order.UserID == userID {
+			archived = append(archived, queued)
+		}
+	}
+	return archived, nil
+}
+
+// addFill appends m to the fill log of orderID. Must be called with e.mu
+// held, after m's trade was recorded.
+func (e *Engine) addFill(orderID int64, m orderbook.Match, counterpartyID string, taker bool) {
+	role := "maker"
+	if taker {
+		role = "taker"
+	}
+	tag := m.Ask.Tag
+	if orderID == m.Bid.ID {
+		tag = m.Bid.Tag
+	}
+
+	log, ok := e.fills[orderID]
+	if !ok {
+		log = &orderFills{}
+		e.fills[orderID] = log
+	}
+	log.add(Fill{
+		TradeSeq:       m.Seq,
+		Price:          m.Price,
+		Amount:         m.SizeFilled,
+		Role:           role,
+		CounterpartyID: counterpartyID,
+		Tag:            tag,
+		Timestamp:      m.Timestamp,
+	})
+}
+
+// OrderStanding returns the queue and liquidity standing of userID's resting
+// order orderID on pair. Closed orders have none and yield ErrOrderNotFound,
+// as does another user's order, so its existence does not leak.
+func (e *Engine) OrderStanding(userID string, pair Pair, orderID int64) (orderbook.OrderStanding, error) {
+	if !pair.IsValid() {
+		return orderbook.OrderStanding{}, ErrInvalidPair
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	ob, exists := e.orderbooks[pair.String()]
+	if !exists {
+		return orderbook.OrderStanding{}, ErrOrderNotFound
+	}
+	order, exists := ob.GetOrder(orderID)
+	if !exists {
+		return orderbook.OrderStanding{}, ErrOrderNotFound
+	}
+	if order.UserID != userID {
+		return orderbook.OrderStanding{}, ErrOrderNotFound
+	}
+
+	standing, _ := ob.Standing(orderID)
+	return standing, nil
+}
+
+// OpenOrder is a resting order together with its standing in the book.
+type OpenOrder struct {
+	Order    orderbook.Order
+	Standing orderbook.OrderStanding
+}
+
+// OpenOrders returns userID's resting orders on pair, oldest first, each with
+// its standing. Everything is read in one critical section, so filled and
+// remaining amounts and queue positions agree with each other.
+func (e *Engine) OpenOrders(userID string, pair Pair) ([]OpenOrder, error) {
+	if !pair.IsValid() {
+		return nil, ErrInvalidPair
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	ob, exists := e.orderbooks[pair.String()]
+	if !exists {
+		return []OpenOrder{}, nil
+	}
+
+	orders := ob.UserOrders(userID)
+	result := make([]OpenOrder, 0, len(orders))
+	for _, order := range orders {
+		standing, _ := ob.Standing(order.ID)
+		snapshot := *order
+		snapshot.Limit = nil
+		result = append(result, OpenOrder{Order: snapshot, Standing: standing})
+	}
+	return result, nil
+}
+
+// GetOrder returns userID's order orderID on pair, whether it is still
+// resting or already archived, together with its fills. Another user's order
+// is reported as ErrOrderNotFound, so its existence does not leak.
+func (e *Engine) GetOrder(userID string, pair Pair, orderID int64) (ArchivedOrder, error) {
+	if !pair.IsValid() {
+		return ArchivedOrder{}, ErrInvalidPair
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if ob, exists := e.orderbooks[pair.String()]; exists {
+		if order, exists := ob.GetOrder(orderID); exists {
+			if order.UserID != userID {
+				return ArchivedOrder{}, ErrOrderNotFound
+			}
+
+			snapshot := *order
+			snapshot.Limit = nil
+			result := ArchivedOrder{
+				Order:       snapshot,
+				Pair:        pair,
+				FilledQuote: e.fillQuote[orderID],
+			}
+			if log, ok := e.fills[orderID]; ok {
+				result.Fills = append([]Fill(nil), log.fills...)
+				result.FillCount = log.count
+			}
+			return result, nil
+		}
+	}
+
+	archive, err := e.userArchive(userID)
+	if err != nil {
+		return ArchivedOrder{}, err
+	}
+	for _, archived := range archive {
+		if archived.Order.ID == orderID && archived.Pair == pair {
+			return archived, nil
+		}
+	}
+	return ArchivedOrder{}, ErrOrderNotFound
+}
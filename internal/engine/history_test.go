@@ -0,0 +1,318 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_OrderHistory_FilledAndCancelled(t *testing.T) {
+	e := setupEngine()
+
+	// User 1 ask fills across two takers at 50k
+	ask, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 1.0)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_000, 0.25)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 51_000, 0.75)
+	assertNoError(t, err)
+
+	// User 1 bid partially fills, then is cancelled
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 40_000, 0.5)
+	assertNoError(t, err)
+	bid, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 2.0)
+	assertNoError(t, err)
+	_, err = e.CancelOrder("1", btcBrl(), bid.ID)
+	assertNoError(t, err)
+
+	// Still-resting orders are not history
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 30_000, 1.0)
+	assertNoError(t, err)
+
+	history, total, _ := e.OrderHistory("1", HistoryFilter{})
+	assertEqual(t, 2, total, "Total terminal orders")
+	assertEqual(t, 2, len(history), "History length")
+
+	// Newest first: the cancelled bid closed last
+	cancelled := history[0]
+	assertEqual(t, bid.ID, cancelled.Order.ID, "Newest first")
+	assertEqual(t, orderbook.OrderCancelled, cancelled.Order.State, "Cancelled state")
+	assertFloat(t, 0.5, cancelled.Order.FilledAmount, "Cancelled order filled amount")
+	assertFloat(t, 40_000, cancelled.AvgFillPrice(), "Cancelled order avg price")
+
+	filled := history[1]
+	assertEqual(t, ask.ID, filled.Order.ID, "Filled ask")
+	assertEqual(t, orderbook.OrderFilled, filled.Order.State, "Filled state")
+	assertFloat(t, 1.0, filled.Order.FilledAmount, "Filled amount")
+	assertFloat(t, 50_000, filled.AvgFillPrice(), "Maker fills at its own price")
+}
+
+func TestEngine_OrderHistory_MarketAndAvgPrice(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 52_000, 0.5)
+	assertNoError(t, err)
+
+	market, _, err := e.PlaceMarketOrder("2", btcBrl(), orderbook.Bid, 1.0)
+	assertNoError(t, err)
+
+	history, _, _ := e.OrderHistory("2", HistoryFilter{})
+	assertEqual(t, 1, len(history), "Market order archived")
+	assertEqual(t, market.ID, history[0].Order.ID, "Market order ID")
+	assertFloat(t, 51_000, history[0].AvgFillPrice(), "Volume-weighted average price")
+
+	trades := e.Trades(btcBrl())
+	assertEqual(t, 2, len(trades), "Trades recorded")
+	assertTrue(t, trades[0].Seq < trades[1].Seq, "Trade sequence increases")
+	assertEqual(t, orderbook.Bid, trades[0].TakerSide, "Taker side")
+}
+
+func TestEngine_TradeSeq_GlobalAcrossPairs(t *testing.T) {
+	e := setupEngine()
+	ethBrl := Pair{Base: "ETH", Quote: "BRL"}
+	assertNoError(t, e.accounts.Credit("2", "ETH", 10))
+
+	var seqs []uint64
+	for i := 0; i < 3; i++ {
+		for _, pair := range []Pair{btcBrl(), ethBrl, btcBrl()} {
+			_, _, err := e.PlaceOrder("2", pair, orderbook.Ask, 1_000, 0.1)
+			assertNoError(t, err)
+			_, matches, err := e.PlaceOrder("1", pair, orderbook.Bid, 1_000, 0.1)
+			assertNoError(t, err)
+			assertEqual(t, 1, len(matches), "One match per round")
+			seqs = append(seqs, matches[0].Seq)
+		}
+	}
+
+	for i := 1; i < len(seqs); i++ {
+		assertTrue(t, seqs[i] > seqs[i-1], "Global sequence strictly increasing across pairs")
+	}
+
+	btcTrades, ethTrades := e.Trades(btcBrl()), e.Trades(ethBrl)
+	assertEqual(t, 6, len(btcTrades), "BTC/BRL trades")
+	assertEqual(t, 3, len(ethTrades), "ETH/BRL trades")
+	for i, trade := range btcTrades {
+		assertEqual(t, uint64(i+1), trade.PairSeq, "BTC/BRL pair sequence")
+	}
+	for i, trade := range ethTrades {
+		assertEqual(t, uint64(i+1), trade.PairSeq, "ETH/BRL pair sequence")
+	}
+	assertEqual(t, seqs[1], ethTrades[0].Seq, "Trade keeps the match's global sequence")
+}
+
+func TestEngine_OrderHistory_Pagination(t *testing.T) {
+	e := setupEngine()
+	ethBrl := Pair{Base: "ETH", Quote: "BRL"}
+
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		pair := btcBrl()
+		if i%2 == 1 {
+			pair = ethBrl
+		}
+		o, _, err := e.PlaceOrder("1", pair, orderbook.Bid, 1_000, 1)
+		assertNoError(t, err)
+		_, err = e.CancelOrder("1", pair, o.ID)
+		assertNoError(t, err)
+		ids = append(ids, o.ID)
+	}
+
+	page, total, _ := e.OrderHistory("1", HistoryFilter{Limit: 2, Offset: 1})
+	assertEqual(t, 5, total, "Total")
+	assertEqual(t, 2, len(page), "Page size")
+	assertEqual(t, ids[3], page[0].Order.ID, "Offset skips the newest")
+	assertEqual(t, ids[2], page[1].Order.ID, "Then the next newest")
+
+	page, total, _ = e.OrderHistory("1", HistoryFilter{Pair: &ethBrl})
+	assertEqual(t, 2, total, "Pair filter total")
+	assertEqual(t, ids[3], page[0].Order.ID, "Newest ETH order")
+
+	page, _, _ = e.OrderHistory("1", HistoryFilter{Offset: 10})
+	assertEqual(t, 0, len(page), "Offset past the end")
+}
+
+func TestEngine_GetOrder_FillsAcrossTwoAsks(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "BTC", Quote: "BRL"}
+
+	_, _, err := e.PlaceOrder("2", pair, orderbook.Ask, 50_000, 0.4)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", pair, orderbook.Ask, 51_000, 0.4)
+	assertNoError(t, err)
+	bid, _, err := e.PlaceOrder("1", pair, orderbook.Bid, 51_000, 1.0)
+	assertNoError(t, err)
+
+	order, err := e.GetOrder("1", pair, bid.ID)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderPartiallyFilled, order.Order.State, "Bid still rests")
+	assertTrue(t, order.ClosedAt.IsZero(), "Resting order has no close time")
+	assertEqual(t, 2, order.FillCount, "Fill count")
+	assertEqual(t, 2, len(order.Fills), "Fills")
+
+	assertFloat(t, 50_000, order.Fills[0].Price, "First fill price")
+	assertFloat(t, 0.4, order.Fills[0].Amount, "First fill amount")
+	assertFloat(t, 51_000, order.Fills[1].Price, "Second fill price")
+	assertFloat(t, 0.4, order.Fills[1].Amount, "Second fill amount")
+	for _, f := range order.Fills {
+		assertEqual(t, "taker", f.Role, "Bid role")
+		assertEqual(t, "2", f.CounterpartyID, "Counterparty")
+	}
+}
+
+func TestEngine_GetOrder_ArchivedMaker(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "BTC", Quote: "BRL"}
+
+	ask, _, err := e.PlaceOrder("2", pair, orderbook.Ask, 50_000, 1.0)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", pair, orderbook.Bid, 50_000, 1.0)
+	assertNoError(t, err)
+
+	order, err := e.GetOrder("2", pair, ask.ID)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderFilled, order.Order.State, "Ask filled")
+	assertTrue(t, !order.ClosedAt.IsZero(), "Archived order has a close time")
+	assertEqual(t, 1, len(order.Fills), "Fills")
+	assertEqual(t, "maker", order.Fills[0].Role, "Ask role")
+	assertEqual(t, "1", order.Fills[0].CounterpartyID, "Counterparty")
+
+	_, err = e.GetOrder("2", pair, 999)
+	assertError(t, err)
+}
+
+func TestEngine_GetOrder_OtherUser(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "BTC", Quote: "BRL"}
+
+	ask, _, err := e.PlaceOrder("2", pair, orderbook.Ask, 50_000, 1.0)
+	assertNoError(t, err)
+
+	_, err = e.GetOrder("1", pair, ask.ID)
+	assertEqual(t, ErrOrderNotFound, err, "Other user's order is not revealed")
+}
+
+func TestEngine_OrderStanding_OtherUser(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "BTC", Quote: "BRL"}
+
+	ask, _, err := e.PlaceOrder("2", pair, orderbook.Ask, 50_000, 1.0)
+	assertNoError(t, err)
+
+	_, err = e.OrderStanding("2", pair, ask.ID)
+	assertNoError(t, err)
+	_, err = e.OrderStanding("1", pair, ask.ID)
+	assertEqual(t, ErrOrderNotFound, err, "Other user's order is not revealed")
+}
+
+func TestOrderFills_Bounded(t *testing.T) {
+	var log orderFills
+	for i := 1; i <= MaxOrderFills+5; i++ {
+		log.add(Fill{TradeSeq: uint64(i)})
+	}
+
+	assertEqual(t, MaxOrderFills+5, log.count, "Count covers every fill")
+	assertEqual(t, MaxOrderFills, len(log.fills), "List is bounded")
+	assertEqual(t, uint64(6), log.fills[0].TradeSeq, "Oldest fills dropped")
+	assertEqual(t, uint64(MaxOrderFills+5), log.fills[MaxOrderFills-1].TradeSeq, "Newest kept")
+}
+
+func TestEngine_OrderTrades_MultiLevel(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "BTC", Quote: "BRL"}
+
+	for _, price := range []float64{50_000, 50_500, 51_000} {
+		_, _, err := e.PlaceOrder("2", pair, orderbook.Ask, price, 0.25)
+		assertNoError(t, err)
+	}
+	bid, _, err := e.PlaceOrder("1", pair, orderbook.Bid, 51_000, 1.0)
+	assertNoError(t, err)
+	// An unrelated trade on the same pair
+	_, _, err = e.PlaceOrder("2", pair, orderbook.Ask, 51_000, 0.25)
+	assertNoError(t, err)
+
+	trades, total, err := e.OrderTrades("1", pair, bid.ID, 0, 0)
+	assertNoError(t, err)
+	assertEqual(t, 4, total, "Every fill of the bid")
+	assertEqual(t, 4, len(trades), "Unpaged")
+	assertFloat(t, 50_000, trades[0].Price, "First level")
+	assertFloat(t, 51_000, trades[3].Price, "Resting remainder filled last")
+	for _, tr := range trades {
+		assertEqual(t, bid.ID, tr.BidOrderID, "Trade filled the bid")
+	}
+
+	page, total, err := e.OrderTrades("1", pair, bid.ID, 2, 1)
+	assertNoError(t, err)
+	assertEqual(t, 4, total, "Total before paging")
+	assertEqual(t, 2, len(page), "Page size")
+	assertFloat(t, 50_500, page[0].Price, "Offset skips the first fill")
+
+	_, _, err = e.OrderTrades("2", pair, bid.ID, 0, 0)
+	assertEqual(t, ErrOrderNotFound, err, "Other user's closed order is not visible")
+	_, _, err = e.OrderTrades("1", pair, 999, 0, 0)
+	assertEqual(t, ErrOrderNotFound, err, "Unknown order")
+}
+
+func TestEngine_TradesBetween(t *testing.T) {
+	e := setupEngine()
+	_ = e.accounts.Credit("2", "ETH", 10)
+	_ = e.accounts.Credit("3", "BRL", 100_000)
+	_ = e.accounts.Credit("3", "BTC", 10)
+	eth := Pair{Base: "ETH", Quote: "BRL"}
+
+	trade := func(seller, buyer string, pair Pair, price float64) {
+		t.Helper()
+		_, _, err := e.PlaceOrder(seller, pair, orderbook.Ask, price, 0.1)
+		assertNoError(t, err)
+		_, matches, err := e.PlaceOrder(buyer, pair, orderbook.Bid, price, 0.1)
+		assertNoError(t, err)
+		assertEqual(t, 1, len(matches), "Trade executed")
+	}
+	trade("1", "2", btcBrl(), 50_000)
+	trade("1", "3", btcBrl(), 50_100)
+	trade("2", "1", btcBrl(), 50_200)
+	trade("3", "2", btcBrl(), 50_300)
+	trade("2", "1", eth, 3_000)
+
+	trades, total := e.TradesBetween("1", "2", 0, 0)
+	assertEqual(t, 3, total, "Only trades between 1 and 2")
+	assertEqual(t, 3, len(trades), "Unpaged")
+	assertFloat(t, 50_000, trades[0].Price, "1 sold to 2")
+	assertFloat(t, 50_200, trades[1].Price, "2 sold to 1")
+	assertEqual(t, eth, trades[2].Pair, "Across pairs")
+
+	reversed, total := e.TradesBetween("2", "1", 0, 0)
+	assertEqual(t, 3, total, "Order of the users does not matter")
+	assertEqual(t, trades[0].Seq, reversed[0].Seq, "Same trades")
+
+	page, total := e.TradesBetween("1", "2", 1, 1)
+	assertEqual(t, 3, total, "Total before paging")
+	assertEqual(t, 1, len(page), "Page size")
+	assertFloat(t, 50_200, page[0].Price, "Offset skips the first trade")
+
+	_, total = e.TradesBetween("1", "4", 0, 0)
+	assertEqual(t, 0, total, "No trades with an unknown user")
+}
+
+func TestEngine_Tag_DoesNotAffectMatching(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "BTC", Quote: "BRL"}
+
+	ask, _, err := e.PlaceOrderWithOptions("2", pair, orderbook.Ask, 50_000, 0.5, OrderOptions{Tag: "a"})
+	assertNoError(t, err)
+	bid, matches, err := e.PlaceOrderWithOptions("1", pair, orderbook.Bid, 50_000, 0.5, OrderOptions{Tag: "b"})
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Tagged orders match as usual")
+
+	trades := e.Trades(pair)
+	assertEqual(t, "b", trades[0].BidTag, "Bid tag on the trade")
+	assertEqual(t, "a", trades[0].AskTag, "Ask tag on the trade")
+
+	order, err := e.GetOrder("2", pair, ask.ID)
+	assertNoError(t, err)
+	assertEqual(t, "a", order.Fills[0].Tag, "Maker fill carries its own tag")
+	order, err = e.GetOrder("1", pair, bid.ID)
+	assertNoError(t, err)
+	assertEqual(t, "b", order.Order.Tag, "Tag archived with the order")
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
+)
+
+// PairMode restricts which kind of order a pair accepts, e.g. for the phases
+// of an opening or closing auction.
+type PairMode string
+
+const (
+	PairModeNormal    PairMode = ""           // makers and takers
+	PairModeMakerOnly PairMode = "maker_only" // only orders that rest without crossing
+	PairModeTakerOnly PairMode = "taker_only" // only orders that cross; nothing rests
+	PairModeAuction   PairMode = "auction"    // limit orders queue without matching until RunAuction
+)
+
+// Valid reports whether m is one of the known modes.
+func (m PairMode) Valid() bool {
+	return m == PairModeNormal || m == PairModeMakerOnly || m == PairModeTakerOnly || m == PairModeAuction
+}
+
+// SetPairMode switches a listed pair to mode. Orders already resting are
+// left alone, so a pair leaving PairModeAuction should be run through
+// RunAuction first or its book may stay crossed. Trailing stops that
+// triggered during the auction fire once it leaves it.
+func (e *Engine) SetPairMode(pair Pair, mode PairMode) error {
+	if !mode.Valid() {
+		return ErrInvalidPairMode
+	}
+
+	defer e.serialize()()
+	defer e.runTriggeredStops()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cfg, exists := e.pairs[pair.String()]
+	if !exists {
+		return ErrUnknownPair
+	}
+
+	if cfg.Mode == PairModeAuction && mode != PairModeAuction {
+		e.triggered = append(e.triggered, e.heldStops[pair.String()]...)
+		delete(e.heldStops, pair.String())
+	}
+	cfg.Mode = mode
+	return nil
+}
+
+// checkPairMode rejects a limit order of the kind cfg.Mode does not accept:
+// a crossing one on a maker-only pair, a non-crossing one on a taker-only
+// pair. Must be called with e.mu held.
+func checkPairMode(cfg PairConfig, ob *orderbook.Orderbook, order *orderbook.Order) error {
+	switch cfg.Mode {
+	case PairModeMakerOnly:
+		if crossesBook(ob, order) {
+			return ErrMakerOnly
+		}
+	case PairModeTakerOnly:
+		if !crossesBook(ob, order) {
+			return ErrTakerOnly
+		}
+	}
+	return nil
+}
+
+// crossesBook reports whether a limit order would match the best level of
+// the opposite side of ob. Must be called with e.mu held.
+func crossesBook(ob *orderbook.Orderbook, order *orderbook.Order) bool {
+	ticks := utils.PriceToTicks(order.Price, ob.PriceTick())
+	if order.Side == orderbook.Bid {
+		best, ok := ob.BestAsk()
+		return ok && ticks >= best.PriceTicks
+	}
+	best, ok := ob.BestBid()
+	return ok && ticks <= best.PriceTicks
+}
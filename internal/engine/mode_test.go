@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_PairMode_MakerOnly(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeMakerOnly))
+
+	// A crossing order is rejected and its lock released
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertEqual(t, ErrMakerOnly, err, "Crossing bid")
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BRL").Locked, "Nothing locked")
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.5)
+	assertEqual(t, ErrMakerOnly, err, "Market orders always take")
+
+	// A resting one is accepted
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 0.5)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderOpen, order.State, "Rests")
+
+	// Back to normal, the crossing order matches
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeNormal))
+	_, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Matched")
+}
+
+func TestEngine_PairMode_TakerOnly(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.4)
+	assertNoError(t, err)
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeTakerOnly))
+
+	// An order that would rest is rejected
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 0.5)
+	assertEqual(t, ErrTakerOnly, err, "Resting bid")
+	_, _, err = e.PlaceMarketOrderWithOptions("1", btcBrl(), orderbook.Bid, 0.5, OrderOptions{RestRemainder: true})
+	assertEqual(t, ErrTakerOnly, err, "Market order resting its remainder")
+
+	// A crossing one is accepted, and what it cannot fill is cancelled
+	order, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Matched")
+	assertEqual(t, orderbook.OrderCancelled, order.State, "Remainder cancelled")
+	assertEqual(t, 0, len(e.GetOrderbook(btcBrl()).Bids()), "Nothing rests")
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BRL").Locked, "Remainder unlocked")
+	assertFloat(t, 80_000, e.accounts.GetBalance("1", "BRL").Available, "Paid for the fill only")
+}
+
+func TestEngine_PairMode_RefusedReplaceKeepsOldOrder(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 1)
+	assertNoError(t, err)
+	first, err := e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 51_000, 1)
+	assertNoError(t, err)
+	assertNoError(t, e.SetPairMode(btcBrl(), PairModeMakerOnly))
+
+	_, err = e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 49_000, 1)
+	assertEqual(t, ErrMakerOnly, err, "Crossing replacement")
+	_, resting := e.GetOrderbook(btcBrl()).GetOrder(first.Order.ID)
+	assertTrue(t, resting, "Old order still rests")
+	assertFloat(t, 1, e.accounts.GetBalance("2", "BTC").Locked, "Its lock is untouched")
+}
+
+func TestEngine_PairMode_Invalid(t *testing.T) {
+	e := setupEngine()
+
+	assertEqual(t, ErrInvalidPairMode, e.SetPairMode(btcBrl(), "batch"), "Unknown mode")
+	assertEqual(t, ErrUnknownPair, e.SetPairMode(Pair{Base: "DOGE", Quote: "BRL"}, PairModeMakerOnly), "Unlisted pair")
+
+	cfg := DefaultPairConfig(Pair{Base: "SOL", Quote: "BRL"})
+	cfg.Mode = "batch"
+	assertEqual(t, ErrInvalidPairMode, e.RegisterPair(cfg), "Registered with an unknown mode")
+}
@@ -0,0 +1,40 @@
+package engine
+
+import "sync/atomic"
+
+// CheckNonce records nonce as the latest seen for userID, or fails with
+// ErrStaleNonce when it is not above the previous one. Nonces are opt-in: 0
+// means the request carries none and is always accepted.
+//
+// A nonce is consumed as soon as it is accepted, even if the request it came
+// with is rejected later on, so a client must never resend it.
+func (e *Engine) CheckNonce(userID string, nonce uint64) error {
+	if nonce == 0 {
+		return nil
+	}
+
+	v, ok := e.nonces.Load(userID)
+	if !ok {
+		v, _ = e.nonces.LoadOrStore(userID, new(atomic.Uint64))
+	}
+	last := v.(*atomic.Uint64)
+
+	for {
+		prev := last.Load()
+		if nonce <= prev {
+			return ErrStaleNonce
+		}
+		if last.CompareAndSwap(prev, nonce) {
+			return nil
+		}
+	}
+}
+
+// LastNonce returns the highest nonce accepted for userID, or 0.
+func (e *Engine) LastNonce(userID string) uint64 {
+	v, ok := e.nonces.Load(userID)
+	if !ok {
+		return 0
+	}
+	return v.(*atomic.Uint64).Load()
+}
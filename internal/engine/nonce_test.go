@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_CheckNonce_Increasing(t *testing.T) {
+	e := setupEngine()
+
+	assertNoError(t, e.CheckNonce("1", 1))
+	assertNoError(t, e.CheckNonce("1", 2))
+	assertNoError(t, e.CheckNonce("1", 10))
+	assertEqual(t, uint64(10), e.LastNonce("1"), "Last nonce")
+
+	// Nonces are tracked per user
+	assertNoError(t, e.CheckNonce("2", 1))
+}
+
+func TestEngine_CheckNonce_StaleRejected(t *testing.T) {
+	e := setupEngine()
+	assertNoError(t, e.CheckNonce("1", 5))
+
+	assertEqual(t, ErrStaleNonce, e.CheckNonce("1", 5), "Repeated nonce")
+	assertEqual(t, ErrStaleNonce, e.CheckNonce("1", 4), "Lower nonce")
+	assertEqual(t, uint64(5), e.LastNonce("1"), "Last nonce unchanged")
+}
+
+func TestEngine_CheckNonce_OptIn(t *testing.T) {
+	e := setupEngine()
+	assertNoError(t, e.CheckNonce("1", 5))
+
+	assertNoError(t, e.CheckNonce("1", 0))
+	assertNoError(t, e.CheckNonce("1", 0))
+	assertEqual(t, uint64(5), e.LastNonce("1"), "Zero nonce is not recorded")
+}
+
+func TestEngine_PlaceOrder_StaleNonce(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 0.1, OrderOptions{Nonce: 7})
+	assertNoError(t, err)
+
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 0.1, OrderOptions{Nonce: 7})
+	assertEqual(t, ErrStaleNonce, err, "Replayed limit order")
+
+	_, _, err = e.PlaceMarketOrderWithOptions("1", btcBrl(), orderbook.Bid, 0.1, OrderOptions{Nonce: 3})
+	assertEqual(t, ErrStaleNonce, err, "Out-of-order market order")
+
+	assertEqual(t, 1, e.GetOrderbook(btcBrl()).OpenOrderTotal(), "Only the first order rests")
+}
+
+func TestEngine_CancelOrder_StaleNonce(t *testing.T) {
+	e := setupEngine()
+
+	order, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 0.1, OrderOptions{Nonce: 7})
+	assertNoError(t, err)
+
+	_, err = e.CancelOrderWithNonce("1", btcBrl(), order.ID, 7)
+	assertEqual(t, ErrStaleNonce, err, "Replayed nonce")
+	assertEqual(t, 1, e.GetOrderbook(btcBrl()).OpenOrderTotal(), "Order still rests")
+
+	_, err = e.CancelOrderWithNonce("1", btcBrl(), order.ID, 8)
+	assertNoError(t, err)
+	assertEqual(t, uint64(8), e.LastNonce("1"), "Nonce consumed")
+}
+
+func TestEngine_CheckNonce_Concurrent(t *testing.T) {
+	e := setupEngine()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e.CheckNonce("1", 1) == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assertEqual(t, 1, accepted, "Same nonce accepted once")
+}
@@ -0,0 +1,332 @@
+package engine
+
+import (
+	"math"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
+)
+
+// DefaultPegRepriceInterval is the PegRepriceInterval of DefaultConfig.
+const DefaultPegRepriceInterval = 100 * time.Millisecond
+
+type PegState string
+
+const (
+	PegActive    PegState = "active"
+	PegFilled    PegState = "filled"
+	PegCancelled PegState = "cancelled" // its order was cancelled or expired
+	PegFailed    PegState = "failed"    // a reprice could not re-place the order
+)
+
+// PeggedOrder is a limit order that follows the best price on its own side
+// of the book. A pegged bid rests Offset below the best bid, a pegged ask
+// Offset above the best ask; a negative Offset improves on the best price.
+//
+// The reference price leaves out hidden orders and other pegged orders, so
+// pegs never chase each other. A peg that would cross the spread is held one
+// tick inside it instead, so it never takes liquidity when it moves.
+//
+// Each reprice cancels the resting order and places a new one for what is
+// left, so OrderID changes and the order goes to the back of the queue.
+// Cancelling the current order ends the peg.
+type PeggedOrder struct {
+	ID        int64
+	UserID    string
+	Pair      Pair
+	Side      orderbook.Side
+	Offset    float64
+	Amount    float64
+	State     PegState
+	CreatedAt time.Time
+
+	OrderID    int64   // order currently resting for the peg
+	Price      float64 // its limit price
+	Filled     float64 // filled by the peg's orders so far
+	Reprices   int
+	RepricedAt time.Time
+	Err        string // why the last reprice failed, if it did
+
+	order     *orderbook.Order // live order behind OrderID
+	repricing bool             // between cancelling the old order and placing the new one
+}
+
+// PlacePeggedOrder places a limit order for amount on pair that follows the
+// best price on side at offset. offset must be a multiple of the pair's price
+// tick. It fails with ErrNoPegReference while that side of the book has no
+// order to peg to.
+func (e *Engine) PlacePeggedOrder(userID string, pair Pair, side orderbook.Side, offset, amount float64) (*PeggedOrder, error) {
+	defer e.serialize()()
+
+	if userID == "" {
+		return nil, account.ErrInvalidUserID
+	}
+	if !pair.IsValid() {
+		return nil, ErrInvalidPair
+	}
+	if side != orderbook.Bid && side != orderbook.Ask {
+		return nil, orderbook.ErrInvalidSide
+	}
+
+	cfg, err := e.listedPairConfig(pair)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(offset) || math.IsInf(offset, 0) || !utils.IsValidTick(math.Abs(offset), cfg.PriceTick) {
+		return nil, ErrInvalidPegOffset
+	}
+
+	peg := &PeggedOrder{
+		UserID: userID,
+		Pair:   pair,
+		Side:   side,
+		Offset: offset,
+		Amount: amount,
+		State:  PegActive,
+	}
+
+	e.mu.RLock()
+	price, ok := e.pegPrice(peg)
+	e.mu.RUnlock()
+	if !ok {
+		return nil, ErrNoPegReference
+	}
+
+	order, _, err := e.placeLimitOrder(userID, pair, side, price, amount, OrderOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	e.pegSeq++
+	peg.ID = e.pegSeq
+	peg.CreatedAt = order.Timestamp
+	peg.RepricedAt = order.Timestamp
+	e.attachPegOrder(peg, order)
+	e.allPegs = append(e.allPegs, peg)
+	e.activePegs = append(e.activePegs, peg)
+	e.hasPegs.Store(true)
+	result := *peg
+	e.mu.Unlock()
+
+	e.runTriggeredStops()
+	e.repricePegs()
+	return &result, nil
+}
+
+// PeggedOrders returns a copy of every pegged order userID has placed, active
+// or not, in placement order.
+func (e *Engine) PeggedOrders(userID string) []PeggedOrder {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var result []PeggedOrder
+	for _, peg := range e.allPegs {
+		if peg.UserID == userID {
+			result = append(result, *peg)
+		}
+	}
+	return result
+}
+
+// repricePegs moves every active peg whose price no longer matches the book.
+// It runs after each book change, with e.mu released and under serialize,
+// and on StartPegRepricer's ticks. A peg repriced less than
+// Config.PegRepriceInterval ago, on a halted pair or without a reference
+// price is left where it is until a later run.
+func (e *Engine) repricePegs() {
+	type reprice struct {
+		peg    *PeggedOrder
+		price  float64
+		amount float64
+	}
+
+	// Most books have no pegs: skip the exclusive lock
+	if !e.hasPegs.Load() {
+		return
+	}
+
+	e.mu.Lock()
+	if len(e.activePegs) == 0 {
+		// A deterministic engine's clock ticks on every read
+		e.mu.Unlock()
+		return
+	}
+	now := e.config.Clock.Now()
+	var due []reprice
+	active := e.activePegs[:0]
+	for _, peg := range e.activePegs {
+		if peg.repricing {
+			active = append(active, peg)
+			continue
+		}
+
+		ob := e.orderbooks[peg.Pair.String()]
+		if _, resting := ob.GetOrder(peg.OrderID); !resting {
+			e.closePeg(peg)
+			continue
+		}
+		active = append(active, peg)
+
+		if now.Sub(peg.RepricedAt) < e.config.PegRepriceInterval || e.pegHalted(peg.Pair) {
+			continue
+		}
+		price, ok := e.pegPrice(peg)
+		if !ok || price == peg.Price {
+			continue
+		}
+
+		archived, err := e.cancelResting(peg.Pair, ob, peg.OrderID)
+		e.detachPegOrder(peg)
+		if err != nil {
+			peg.State = PegFailed
+			peg.Err = err.Error()
+			active = active[:len(active)-1]
+			continue
+		}
+		peg.repricing = true
+		due = append(due, reprice{peg: peg, price: price, amount: archived.Order.RemainingAmount()})
+	}
+	e.activePegs = active
+	e.hasPegs.Store(len(active) > 0)
+	e.mu.Unlock()
+
+	for _, r := range due {
+		order, _, err := e.placeLimitOrder(r.peg.UserID, r.peg.Pair, r.peg.Side, r.price, r.amount, OrderOptions{})
+
+		e.mu.Lock()
+		r.peg.repricing = false
+		if err != nil {
+			r.peg.State = PegFailed
+			r.peg.Err = err.Error()
+			e.removeActivePeg(r.peg)
+		} else {
+			r.peg.Reprices++
+			r.peg.RepricedAt = order.Timestamp
+			e.attachPegOrder(r.peg, order)
+		}
+		e.mu.Unlock()
+	}
+
+	// A re-placed order can still match if the book moved meanwhile
+	if len(due) > 0 {
+		e.runTriggeredStops()
+	}
+}
+
+// StartPegRepricer runs repricePegs every Config.PegRepriceInterval, or
+// DefaultPegRepriceInterval when that is 0, until the returned stop function
+// is called. Without it a peg skipped by a reprice would stay stale until
+// the next book change.
+func (e *Engine) StartPegRepricer() (stop func()) {
+	interval := e.config.PegRepriceInterval
+	if interval <= 0 {
+		interval = DefaultPegRepriceInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				release := e.serialize()
+				e.repricePegs()
+				release()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// pegPrice returns the price peg should rest at, or false when its side of
+// the book has nothing to peg to. Must be called with e.mu held.
+func (e *Engine) pegPrice(peg *PeggedOrder) (float64, bool) {
+	ob, exists := e.orderbooks[peg.Pair.String()]
+	if !exists {
+		return 0, false
+	}
+	ref, ok := ob.BestLimitExcluding(peg.Side, func(o *orderbook.Order) bool {
+		_, pegged := e.pegOrders[o.ID]
+		return pegged || o.Hidden
+	})
+	if !ok {
+		return 0, false
+	}
+
+	tick := ob.PriceTick()
+	offsetTicks := int64(math.Round(peg.Offset / tick))
+
+	var ticks int64
+	if peg.Side == orderbook.Bid {
+		ticks = ref.PriceTicks - offsetTicks
+		if ask, ok := ob.BestAsk(); ok && ticks >= ask.PriceTicks {
+			ticks = ask.PriceTicks - 1
+		}
+	} else {
+		ticks = ref.PriceTicks + offsetTicks
+		if bid, ok := ob.BestBid(); ok && ticks <= bid.PriceTicks {
+			ticks = bid.PriceTicks + 1
+		}
+	}
+	if ticks <= 0 {
+		return 0, false
+	}
+	return utils.TicksToPrice(ticks, tick), true
+}
+
+// pegHalted reports whether orders on pair cannot be placed right now, in
+// which case pegs keep their order rather than lose it. Must be called with
+// e.mu held.
+func (e *Engine) pegHalted(pair Pair) bool {
+	if e.halted.Load() {
+		return true
+	}
+	cfg, exists := e.pairs[pair.String()]
+	return exists && cfg.Halted
+}
+
+// closePeg ends a peg whose order left the book other than by a reprice.
+// Must be called with e.mu held.
+func (e *Engine) closePeg(peg *PeggedOrder) {
+	peg.State = PegCancelled
+	if peg.order.State == orderbook.OrderFilled {
+		peg.State = PegFilled
+	}
+	e.detachPegOrder(peg)
+}
+
+// attachPegOrder makes order the one resting for peg. Must be called with
+// e.mu held.
+func (e *Engine) attachPegOrder(peg *PeggedOrder, order *orderbook.Order) {
+	peg.order = order
+	peg.OrderID = order.ID
+	peg.Price = order.Price
+	e.pegOrders[order.ID] = peg
+}
+
+// detachPegOrder folds the fills of peg's current order into Filled and
+// forgets the order. Must be called with e.mu held.
+func (e *Engine) detachPegOrder(peg *PeggedOrder) {
+	peg.Filled += peg.order.FilledAmount
+	delete(e.pegOrders, peg.OrderID)
+}
+
+// removeActivePeg drops peg from the pegs that follow the book. Must be
+// called with e.mu held.
+func (e *Engine) removeActivePeg(peg *PeggedOrder) {
+	for i, p := range e.activePegs {
+		if p == peg {
+			e.activePegs = append(e.activePegs[:i], e.activePegs[i+1:]...)
+			e.hasPegs.Store(len(e.activePegs) > 0)
+			return
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+// setupPegEngine returns an engine on a fake clock, with user 3 funded for
+// pegs, and a function that moves the clock past the reprice interval.
+func setupPegEngine() (*Engine, func()) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+
+	cfg := DefaultConfig()
+	cfg.Clock = clk
+	e := setupEngineWithConfig(cfg)
+	_ = e.accounts.Credit("3", "BRL", 100_000)
+	_ = e.accounts.Credit("3", "BTC", 1)
+	return e, func() { clk.Advance(time.Second) }
+}
+
+func TestEngine_PeggedOrder_FollowsBestBid(t *testing.T) {
+	e, tick := setupPegEngine()
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+
+	peg, err := e.PlacePeggedOrder("3", btcBrl(), orderbook.Bid, 10, 0.1)
+	assertNoError(t, err)
+	assertFloat(t, 49_990, peg.Price, "Pegged below the best bid")
+	firstOrderID := peg.OrderID
+
+	// The best bid moves up
+	tick()
+	better, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_100, 0.5)
+	assertNoError(t, err)
+
+	moved := e.PeggedOrders("3")[0]
+	assertFloat(t, 50_090, moved.Price, "Peg follows the best bid up")
+	assertEqual(t, 1, moved.Reprices, "One reprice")
+	assertTrue(t, moved.OrderID != firstOrderID, "Re-placed as a new order")
+	_, resting := e.GetOrderbook(btcBrl()).GetOrder(firstOrderID)
+	assertFalse(t, resting, "Old order left the book")
+	assertFloat(t, 0.1*50_090, e.accounts.GetBalance("3", "BRL").Locked, "Lock follows the new price")
+
+	// And back down once it is cancelled
+	tick()
+	_, err = e.CancelOrder("2", btcBrl(), better.ID)
+	assertNoError(t, err)
+
+	moved = e.PeggedOrders("3")[0]
+	assertFloat(t, 49_990, moved.Price, "Peg follows the best bid down")
+	assertEqual(t, PegActive, moved.State, "Still active")
+}
+
+func TestEngine_PeggedOrder_HeldInsideSpread(t *testing.T) {
+	e, tick := setupPegEngine()
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+	tight, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_050, 1)
+	assertNoError(t, err)
+
+	// 100 above the best bid would cross the ask at 50050
+	peg, err := e.PlacePeggedOrder("3", btcBrl(), orderbook.Bid, -100, 0.1)
+	assertNoError(t, err)
+	assertFloat(t, 50_049.99, peg.Price, "Held one tick below the ask")
+	assertEqual(t, 0, len(e.Trades(btcBrl())), "Peg took no liquidity")
+
+	// The ask backs off, so the peg can reach its offset
+	tick()
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_200, 1)
+	assertNoError(t, err)
+	_, err = e.CancelOrder("2", btcBrl(), tight.ID)
+	assertNoError(t, err)
+
+	assertFloat(t, 50_100, e.PeggedOrders("3")[0].Price, "Full offset once the spread allows")
+}
+
+func TestEngine_PeggedOrder_RepriceInterval(t *testing.T) {
+	e, tick := setupPegEngine()
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 51_000, 1)
+	assertNoError(t, err)
+	peg, err := e.PlacePeggedOrder("3", btcBrl(), orderbook.Ask, 0, 0.1)
+	assertNoError(t, err)
+	assertFloat(t, 51_000, peg.Price, "Pegged at the best ask")
+
+	// Within the interval the book moves but the peg stays
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_900, 1)
+	assertNoError(t, err)
+	assertFloat(t, 51_000, e.PeggedOrders("3")[0].Price, "Not repriced within the interval")
+
+	// The next change after the interval catches up
+	tick()
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_800, 1)
+	assertNoError(t, err)
+	assertFloat(t, 50_800, e.PeggedOrders("3")[0].Price, "Repriced after the interval")
+	assertEqual(t, 1, e.PeggedOrders("3")[0].Reprices, "A single reprice")
+}
+
+func TestEngine_PeggedOrder_CatchesUpAfterHalt(t *testing.T) {
+	e, tick := setupPegEngine()
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+	better, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_100, 0.5)
+	assertNoError(t, err)
+	_, err = e.PlacePeggedOrder("3", btcBrl(), orderbook.Bid, 10, 0.1)
+	assertNoError(t, err)
+
+	// The best bid leaves while the pair is halted
+	tick()
+	assertNoError(t, e.HaltPair(btcBrl()))
+	_, err = e.CancelOrder("2", btcBrl(), better.ID)
+	assertNoError(t, err)
+	assertFloat(t, 50_090, e.PeggedOrders("3")[0].Price, "Kept while halted")
+
+	// Nothing else changes the book after the resume; a StartPegRepricer
+	// tick catches up
+	assertNoError(t, e.ResumePair(btcBrl()))
+	e.repricePegs()
+	assertFloat(t, 49_990, e.PeggedOrders("3")[0].Price, "Repriced after the resume")
+}
+
+func TestEngine_PeggedOrder_FilledAndCancelled(t *testing.T) {
+	e, _ := setupPegEngine()
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 51_000, 1)
+	assertNoError(t, err)
+	filled, err := e.PlacePeggedOrder("3", btcBrl(), orderbook.Ask, -10, 0.1)
+	assertNoError(t, err)
+	assertFloat(t, 50_990, filled.Price, "Improves on the best ask")
+
+	_, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_990, 0.1)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Peg traded")
+
+	pegs := e.PeggedOrders("3")
+	assertEqual(t, PegFilled, pegs[0].State, "Peg filled")
+	assertFloat(t, 0.1, pegs[0].Filled, "Filled amount")
+
+	cancelled, err := e.PlacePeggedOrder("3", btcBrl(), orderbook.Ask, 0, 0.1)
+	assertNoError(t, err)
+	_, err = e.CancelOrder("3", btcBrl(), cancelled.OrderID)
+	assertNoError(t, err)
+
+	pegs = e.PeggedOrders("3")
+	assertEqual(t, PegCancelled, pegs[1].State, "Cancelling the order ends the peg")
+	assertFloat(t, 0, e.accounts.GetBalance("3", "BTC").Locked, "Nothing left locked")
+}
+
+func TestEngine_PlacePeggedOrder_Invalid(t *testing.T) {
+	e, _ := setupPegEngine()
+
+	_, err := e.PlacePeggedOrder("3", btcBrl(), orderbook.Bid, 10, 0.1)
+	assertEqual(t, ErrNoPegReference, err, "Nothing to peg to")
+
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+
+	_, err = e.PlacePeggedOrder("3", btcBrl(), orderbook.Bid, 0.005, 0.1)
+	assertEqual(t, ErrInvalidPegOffset, err, "Offset off the price tick")
+
+	_, err = e.PlacePeggedOrder("3", btcBrl(), orderbook.Ask, 10, 0.1)
+	assertEqual(t, ErrNoPegReference, err, "Empty ask side")
+
+	assertEqual(t, 0, len(e.PeggedOrders("3")), "Nothing placed")
+}
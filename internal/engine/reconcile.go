@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+)
+
+// ReconcileBooks recomputes the volume of every price level of every book
+// from the orders resting there, and removes levels left without orders. It
+// returns how many levels it had to correct, which is 0 unless the running
+// totals had drifted.
+func (e *Engine) ReconcileBooks() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	fixed := 0
+	for _, ob := range e.orderbooks {
+		fixed += ob.Reconcile()
+	}
+	return fixed
+}
+
+// StartReconciler runs ReconcileBooks every interval until the returned stop
+// function is called. It does nothing when interval is not positive.
+func (e *Engine) StartReconciler(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.ReconcileBooks()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// AssetTotals is account.Manager.AssetTotals read while no trade settles, so
+// each trade is either in the totals entirely or not at all. Trading then
+// never changes an asset's Total: only credits and debits do.
+func (e *Engine) AssetTotals() ([]account.AssetTotal, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.accounts.AssetTotals()
+}
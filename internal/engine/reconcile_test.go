@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_ReconcileBooks(t *testing.T) {
+	e := setupEngine()
+
+	for _, amount := range []float64{0.7, 0.3, 1.1} {
+		_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, amount)
+		assertNoError(t, err)
+	}
+	for i := 0; i < 50; i++ {
+		_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.013)
+		assertNoError(t, err)
+	}
+
+	e.ReconcileBooks()
+
+	limit, ok := e.GetOrderbook(btcBrl()).BestAsk()
+	assertTrue(t, ok, "Level still rests")
+	sum := 0.0
+	for _, o := range limit.Orders {
+		sum += o.RemainingAmount()
+	}
+	assertTrue(t, sum == limit.TotalVolume, "Total volume is the sum of the remainders")
+	assertEqual(t, 0, e.ReconcileBooks(), "A reconciled book stays reconciled")
+}
+
+func TestEngine_AssetTotals_WhileTrading(t *testing.T) {
+	e := setupEngine()
+
+	before, err := e.AssetTotals()
+	assertNoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.01)
+			_, _, _ = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.01)
+		}
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+
+		totals, err := e.AssetTotals()
+		assertNoError(t, err)
+		for i := range before {
+			if before[i].Total() != totals[i].Total() {
+				t.Fatalf("%s total moved from %.8f to %.8f mid-trade", before[i].Asset, before[i].Total(), totals[i].Total())
+			}
+		}
+	}
+}
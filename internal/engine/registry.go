@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"math"
+	"sort"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
+)
+
+// PairConfig holds the trading parameters of a listed pair.
+type PairConfig struct {
+	Pair         Pair
+	PriceTick    float64
+	AmountTick   float64
+	MinOrderSize float64
+	MinNotional  float64
+	Halted       bool
+
+	// LotSize, when set, is the step order amounts must be a multiple of. It
+	// is coarser than AmountTick, which still governs fills: a partial match
+	// can leave a remainder that is not a whole number of lots.
+	LotSize float64
+
+	// MaxMarketGap, when set, stops a market order before a level priced
+	// more than this fraction (0.05 is 5%) away from the level it last
+	// matched at; the rest is cancelled as with the level cap.
+	MaxMarketGap float64
+
+	// Mode restricts the pair to maker or taker orders; see PairMode.
+	Mode PairMode
+}
+
+// DefaultPairConfig returns the config used for pairs listed without custom parameters.
+func DefaultPairConfig(pair Pair) PairConfig {
+	return PairConfig{
+		Pair:       pair,
+		PriceTick:  PriceTick,
+		AmountTick: AmountTick,
+	}
+}
+
+// RegisterPair lists a new pair, or relists a delisted one, and creates its
+// orderbook. Base and quote must be different assets, both tick sizes must
+// be positive finite numbers, a lot size, if any, a multiple of the amount
+// tick, a market gap, if any, positive and finite, and the mode a known
+// PairMode.
+func (e *Engine) RegisterPair(cfg PairConfig) error {
+	if cfg.Pair.SameAsset() {
+		return ErrSameAssetPair
+	}
+	if !cfg.Pair.IsValid() {
+		return ErrInvalidPair
+	}
+	if !validTick(cfg.PriceTick) || !validTick(cfg.AmountTick) {
+		return ErrInvalidTickSize
+	}
+	if cfg.LotSize != 0 && (!validTick(cfg.LotSize) || !utils.IsValidTick(cfg.LotSize, cfg.AmountTick)) {
+		return ErrInvalidLotSize
+	}
+	if cfg.MaxMarketGap != 0 && !validTick(cfg.MaxMarketGap) {
+		return ErrInvalidMarketGap
+	}
+	if !cfg.Mode.Valid() {
+		return ErrInvalidPairMode
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := cfg.Pair.String()
+	if _, exists := e.pairs[key]; exists {
+		return ErrPairAlreadyRegistered
+	}
+
+	if _, exists := e.orderbooks[key]; !exists {
+		ob, err := orderbook.NewOrderbookWithClock(cfg.PriceTick, e.config.Clock)
+		if err != nil {
+			return err
+		}
+		e.orderbooks[key] = ob
+	}
+	e.pairs[key] = &cfg
+	delete(e.delisted, key)
+
+	return nil
+}
+
+// validTick reports whether tick can space price or amount levels.
+func validTick(tick float64) bool {
+	return tick > 0 && !math.IsInf(tick, 0)
+}
+
+// lotAligned reports whether amount is a whole number of lots. Pairs without
+// a lot size accept any amount.
+func (cfg PairConfig) lotAligned(amount float64) bool {
+	return cfg.LotSize <= 0 || utils.IsValidTick(amount, cfg.LotSize)
+}
+
+// ListPairs returns a copy of every registered pair config, sorted by symbol.
+func (e *Engine) ListPairs() []PairConfig {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	result := make([]PairConfig, 0, len(e.pairs))
+	for _, cfg := range e.pairs {
+		result = append(result, *cfg)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Pair.String() < result[j].Pair.String()
+	})
+
+	return result
+}
+
+// HaltPair stops order placement on a pair. Cancels are still accepted.
+func (e *Engine) HaltPair(pair Pair) error {
+	return e.setHalted(pair, true)
+}
+
+// ResumePair re-enables order placement on a halted pair.
+func (e *Engine) ResumePair(pair Pair) error {
+	return e.setHalted(pair, false)
+}
+
+func (e *Engine) setHalted(pair Pair, halted bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cfg, exists := e.pairs[pair.String()]
+	if !exists {
+		return ErrUnknownPair
+	}
+
+	cfg.Halted = halted
+	return nil
+}
+
+// GetPairConfig returns the config orders on pair are placed under, or
+// ErrUnknownPair when the pair is not listed.
+func (e *Engine) GetPairConfig(pair Pair) (PairConfig, error) {
+	return e.listedPairConfig(pair)
+}
+
+// listedPairConfig returns the config for pair, ErrPairDelisted once it has
+// been delisted, or ErrUnknownPair when the registry is in use and pair is
+// not in it. Unregistered pairs get the defaults while the registry is empty
+// or Config.AllowUnlistedPairs is set.
+func (e *Engine) listedPairConfig(pair Pair) (PairConfig, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.lookupPairConfig(pair)
+}
+
+// lookupPairConfig is listedPairConfig for callers holding e.mu.
+func (e *Engine) lookupPairConfig(pair Pair) (PairConfig, error) {
+	if _, delisted := e.delisted[pair.String()]; delisted {
+		return PairConfig{}, ErrPairDelisted
+	}
+	if cfg, exists := e.pairs[pair.String()]; exists {
+		return *cfg, nil
+	}
+	if !e.allowsUnlisted() {
+		return PairConfig{}, ErrUnknownPair
+	}
+	return DefaultPairConfig(pair), nil
+}
+
+// checkTradable fails when orders cannot be placed on pair: trading is
+// halted engine-wide or on the pair, or the pair is not listed. Placements
+// check again with it under the lock that settles them, since the pair can
+// be halted or delisted after they were validated. Must be called with e.mu
+// held.
+func (e *Engine) checkTradable(pair Pair) error {
+	if e.halted.Load() {
+		return ErrEngineHalted
+	}
+	cfg, err := e.lookupPairConfig(pair)
+	if err != nil {
+		return err
+	}
+	if cfg.Halted {
+		return ErrPairHalted
+	}
+	return nil
+}
+
+// allowsUnlisted reports whether orders may create books for unregistered
+// pairs. A registry emptied by delisting stays in use. Must be called with
+// e.mu held.
+func (e *Engine) allowsUnlisted() bool {
+	return e.config.AllowUnlistedPairs || len(e.pairs) == 0 && len(e.delisted) == 0
+}
+
+// pairConfig returns the config for pair, falling back to the defaults for
+// pairs that were never registered.
+func (e *Engine) pairConfig(pair Pair) PairConfig {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if cfg, exists := e.pairs[pair.String()]; exists {
+		return *cfg
+	}
+	return DefaultPairConfig(pair)
+}
@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
+)
+
+func TestEngine_ListPairs_PreListed(t *testing.T) {
+	e := NewEngine()
+
+	pairs := e.ListPairs()
+	assertEqual(t, 3, len(pairs), "Pre-listed pairs")
+	assertEqual(t, "BTC/BRL", pairs[0].Pair.String(), "Pairs sorted by symbol")
+	assertFloat(t, PriceTick, pairs[0].PriceTick, "Default price tick")
+	assertFloat(t, AmountTick, pairs[0].AmountTick, "Default amount tick")
+}
+
+func TestEngine_RegisterPair_Duplicate(t *testing.T) {
+	e := NewEngine()
+
+	err := e.RegisterPair(DefaultPairConfig(btcBrl()))
+	assertEqual(t, ErrPairAlreadyRegistered, err, "Duplicate registration")
+}
+
+func TestEngine_PlaceOrder_BelowPairMinimums(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "SOL", Quote: "BRL"}
+	assertNoError(t, e.RegisterPair(PairConfig{
+		Pair:         pair,
+		PriceTick:    0.01,
+		AmountTick:   0.001,
+		MinOrderSize: 1,
+		MinNotional:  500,
+	}))
+	_ = e.accounts.Credit("1", "SOL", 10)
+
+	_, _, err := e.PlaceOrder("1", pair, orderbook.Ask, 1_000, 0.5)
+	assertEqual(t, ErrBelowMinOrderSize, err, "Below min order size")
+
+	_, _, err = e.PlaceOrder("1", pair, orderbook.Ask, 100, 2)
+	assertEqual(t, ErrBelowMinNotional, err, "Below min notional")
+
+	_, _, err = e.PlaceOrder("1", pair, orderbook.Ask, 1_000, 2)
+	assertNoError(t, err)
+}
+
+func TestEngine_HaltPair(t *testing.T) {
+	e := setupEngine()
+
+	assertNoError(t, e.HaltPair(btcBrl()))
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertEqual(t, ErrPairHalted, err, "Placement on halted pair")
+
+	assertNoError(t, e.ResumePair(btcBrl()))
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+}
+
+func TestEngine_PlaceOrder_UnlistedPairRejected(t *testing.T) {
+	e := setupEngine()
+	typo := Pair{Base: "BTX", Quote: "BRL"}
+	_ = e.accounts.Credit("1", "BTX", 10)
+
+	_, _, err := e.PlaceOrder("1", typo, orderbook.Ask, 50_000, 1.0)
+	assertEqual(t, ErrUnknownPair, err, "Limit order on unlisted pair")
+
+	_, _, err = e.PlaceMarketOrder("1", typo, orderbook.Bid, 1.0)
+	assertEqual(t, ErrUnknownPair, err, "Market order on unlisted pair")
+
+	assertTrue(t, e.GetOrderbook(typo) == nil, "No ghost orderbook")
+	assertFloat(t, 0, e.accounts.GetBalance("1", "BTX").Locked, "Nothing locked")
+	for _, cfg := range e.ListPairs() {
+		assertTrue(t, cfg.Pair != typo, "Unlisted pair not in ListPairs")
+	}
+}
+
+func TestEngine_PlaceOrder_AllowUnlistedPairs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AllowUnlistedPairs = true
+	e := setupEngineWithConfig(cfg)
+	pair := Pair{Base: "BTX", Quote: "BRL"}
+	_ = e.accounts.Credit("1", "BTX", 10)
+
+	_, _, err := e.PlaceOrder("1", pair, orderbook.Ask, 50_000, 1.0)
+	assertNoError(t, err)
+	assertTrue(t, e.GetOrderbook(pair) != nil, "Orderbook created on first use")
+	assertEqual(t, 3, len(e.ListPairs()), "Still not listed")
+}
+
+func TestEngine_RegisterPair_InvalidTicks(t *testing.T) {
+	e := NewEngine()
+	pair := Pair{Base: "SOL", Quote: "BRL"}
+
+	configs := []PairConfig{
+		{Pair: pair, PriceTick: 0, AmountTick: 0.001},
+		{Pair: pair, PriceTick: -0.01, AmountTick: 0.001},
+		{Pair: pair, PriceTick: 0.01, AmountTick: 0},
+		{Pair: pair, PriceTick: 0.01, AmountTick: -1},
+		{Pair: pair, PriceTick: math.Inf(1), AmountTick: 0.001},
+		{Pair: pair, PriceTick: math.NaN(), AmountTick: 0.001},
+	}
+	for _, cfg := range configs {
+		err := e.RegisterPair(cfg)
+		assertEqual(t, ErrInvalidTickSize, err, "Degenerate tick rejected")
+	}
+
+	assertEqual(t, 3, len(e.ListPairs()), "Nothing registered")
+	assertTrue(t, e.GetOrderbook(pair) == nil, "No orderbook created")
+}
+
+func TestEngine_RegisterPair_InvalidSymbols(t *testing.T) {
+	e := NewEngine()
+
+	err := e.RegisterPair(PairConfig{Pair: Pair{Base: "BRL", Quote: "BRL"}, PriceTick: 0.01, AmountTick: 0.001})
+	assertEqual(t, ErrSameAssetPair, err, "Same-asset pair rejected")
+
+	err = e.RegisterPair(PairConfig{Pair: Pair{Base: " brl ", Quote: "BRL"}, PriceTick: 0.01, AmountTick: 0.001})
+	assertEqual(t, ErrSameAssetPair, err, "Same asset after trim and case folding rejected")
+
+	err = e.RegisterPair(PairConfig{Pair: Pair{Base: " \t", Quote: "BRL"}, PriceTick: 0.01, AmountTick: 0.001})
+	assertEqual(t, ErrInvalidPair, err, "Whitespace-only base rejected")
+
+	assertEqual(t, 3, len(e.ListPairs()), "Nothing registered")
+}
+
+func TestEngine_RegisterPair_InvalidLotSize(t *testing.T) {
+	e := NewEngine()
+	pair := Pair{Base: "SOL", Quote: "BRL"}
+
+	for _, lot := range []float64{-0.01, 0.0015, math.Inf(1), math.NaN()} {
+		err := e.RegisterPair(PairConfig{Pair: pair, PriceTick: 0.01, AmountTick: 0.001, LotSize: lot})
+		assertEqual(t, ErrInvalidLotSize, err, "Lot size rejected")
+	}
+
+	assertNoError(t, e.RegisterPair(PairConfig{Pair: pair, PriceTick: 0.01, AmountTick: 0.001, LotSize: 0.01}))
+}
+
+func TestEngine_PlaceOrder_LotSize(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "SOL", Quote: "BRL"}
+	assertNoError(t, e.RegisterPair(PairConfig{
+		Pair:       pair,
+		PriceTick:  0.01,
+		AmountTick: 0.0001,
+		LotSize:    0.01,
+	}))
+	_ = e.accounts.Credit("2", "SOL", 10)
+
+	// On the amount tick but between lots
+	_, _, err := e.PlaceOrder("2", pair, orderbook.Ask, 1_000, 0.015)
+	assertEqual(t, ErrInvalidAmountLot, err, "Limit off the lot")
+	_, _, err = e.PlaceMarketOrder("1", pair, orderbook.Bid, 0.015)
+	assertEqual(t, ErrInvalidAmountLot, err, "Market off the lot")
+	_, err = e.PlaceTrailingStop("1", pair, orderbook.Bid, 10, 0.015)
+	assertEqual(t, ErrInvalidAmountLot, err, "Trailing stop off the lot")
+
+	ask, _, err := e.PlaceOrder("2", pair, orderbook.Ask, 1_000, 0.05)
+	assertNoError(t, err)
+
+	// Fills are whole lots too, so a partial match leaves whole lots resting
+	_, matches, err := e.PlaceMarketOrder("1", pair, orderbook.Bid, 0.02)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Market order matched")
+	resting, err := e.GetOrder("2", pair, ask.ID)
+	assertNoError(t, err)
+	assertFloat(t, 0.03, utils.RoundToTick(resting.Order.RemainingAmount(), 0.0001), "Remainder")
+
+	_, err = e.AmendOrder("2", pair, ask.ID, 0.045)
+	assertEqual(t, ErrInvalidAmountLot, err, "Amend off the lot")
+	_, err = e.AmendOrder("2", pair, ask.ID, 0.04)
+	assertNoError(t, err)
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+// MaxRejectionsPerUser bounds the rejections kept per user; the oldest are
+// dropped first.
+const MaxRejectionsPerUser = 100
+
+// MaxRejectionUsers bounds the users whose rejections are kept; past it, the
+// log of the user rejected least recently is dropped.
+const MaxRejectionUsers = 10_000
+
+// Rejection records an order placement the engine refused. Err is the error
+// the placement returned, so callers can map it to a reason code.
+type Rejection struct {
+	Seq       uint64
+	UserID    string
+	Pair      Pair
+	Side      orderbook.Side
+	Type      orderbook.OrderType
+	Price     float64 // as requested, zero for market orders
+	Amount    float64 // as requested
+	Tag       string
+	Err       error
+	Timestamp time.Time
+}
+
+// rejectionLog is the recent rejections of one user, oldest first, and its
+// element in Engine.rejectUsers.
+type rejectionLog struct {
+	entries []Rejection
+	user    *list.Element
+}
+
+// OnReject registers fn to be called with every rejected placement, in
+// sequence order. fn runs with the rejection log locked: it must return
+// quickly and must not call back into the engine.
+func (e *Engine) OnReject(fn func(Rejection)) {
+	e.rejectMu.Lock()
+	defer e.rejectMu.Unlock()
+	e.rejectListeners = append(e.rejectListeners, fn)
+}
+
+// Rejections returns the most recent rejected placements of userID, newest
+// first. At most MaxRejectionsPerUser are kept, for the MaxRejectionUsers
+// users rejected most recently.
+func (e *Engine) Rejections(userID string) []Rejection {
+	e.rejectMu.Lock()
+	defer e.rejectMu.Unlock()
+
+	log, exists := e.rejections[userID]
+	if !exists {
+		return []Rejection{}
+	}
+	result := make([]Rejection, len(log.entries))
+	for i, r := range log.entries {
+		result[len(log.entries)-1-i] = r
+	}
+	return result
+}
+
+// RecordRejection logs a placement refused before it reached the engine,
+// such as one whose amount could not be resolved, and notifies the reject
+// listeners as for the placements the engine refuses itself.
+func (e *Engine) RecordRejection(userID string, pair Pair, side orderbook.Side, orderType orderbook.OrderType, price, amount float64, tag string, err error) {
+	e.recordRejection(userID, pair, side, orderType, price, amount, tag, err)
+}
+
+// recordRejection logs a placement that failed with err and notifies the
+// reject listeners. It takes rejectMu rather than e.mu, so a refused order
+// does not stall matching.
+func (e *Engine) recordRejection(userID string, pair Pair, side orderbook.Side, orderType orderbook.OrderType, price, amount float64, tag string, err error) {
+	e.rejectMu.Lock()
+	defer e.rejectMu.Unlock()
+
+	e.rejectSeq++
+	r := Rejection{
+		Seq:       e.rejectSeq,
+		UserID:    userID,
+		Pair:      pair,
+		Side:      side,
+		Type:      orderType,
+		Price:     price,
+		Amount:    amount,
+		Tag:       tag,
+		Err:       err,
+		Timestamp: e.config.Clock.Now(),
+	}
+
+	log, exists := e.rejections[userID]
+	if !exists {
+		if e.rejectUsers.Len() == MaxRejectionUsers {
+			oldest := e.rejectUsers.Front()
+			delete(e.rejections, e.rejectUsers.Remove(oldest).(string))
+		}
+		log = &rejectionLog{user: e.rejectUsers.PushBack(userID)}
+		e.rejections[userID] = log
+	} else {
+		e.rejectUsers.MoveToBack(log.user)
+	}
+	if len(log.entries) == MaxRejectionsPerUser {
+		log.entries = append(log.entries[:0], log.entries[1:]...)
+	}
+	log.entries = append(log.entries, r)
+
+	for _, fn := range e.rejectListeners {
+		fn(r)
+	}
+}
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_Rejections_RecordReason(t *testing.T) {
+	e := NewEngineWithConfig(Config{TickPolicy: TickReject})
+	_ = e.accounts.Credit("1", "BRL", 100_000)
+	_ = e.accounts.Credit("2", "BTC", 10)
+
+	var heard []Rejection
+	e.OnReject(func(r Rejection) { heard = append(heard, r) })
+
+	_, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 100, OrderOptions{Tag: "grid-1"})
+	assertEqual(t, account.ErrInsufficientBalance, err, "Bid beyond the BRL balance")
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000.005, 1)
+	assertEqual(t, ErrInvalidPriceTick, err, "Price off the tick")
+	_, _, err = e.PlaceMarketOrder("2", btcBrl(), orderbook.Ask, 0.123456789)
+	assertEqual(t, ErrInvalidAmountTick, err, "Amount off the tick")
+
+	rejections := e.Rejections("1")
+	assertEqual(t, 2, len(rejections), "Rejections of user 1")
+	assertEqual(t, ErrInvalidPriceTick, rejections[0].Err, "Newest first")
+	assertEqual(t, account.ErrInsufficientBalance, rejections[1].Err, "Reason kept")
+	assertEqual(t, orderbook.OrderTypeLimit, rejections[1].Type, "Order type")
+	assertFloat(t, 100, rejections[1].Amount, "Requested amount")
+	assertEqual(t, "grid-1", rejections[1].Tag, "Tag")
+
+	market := e.Rejections("2")
+	assertEqual(t, 1, len(market), "Market rejection recorded")
+	assertEqual(t, orderbook.OrderTypeMarket, market[0].Type, "Market type")
+
+	assertEqual(t, 3, len(heard), "Listener heard every rejection")
+	assertEqual(t, uint64(3), heard[2].Seq, "Sequence numbers")
+
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(e.Rejections("1")), "Accepted orders are not recorded")
+}
+
+func TestEngine_Rejections_Bounded(t *testing.T) {
+	e := setupEngine()
+
+	for i := 0; i < MaxRejectionsPerUser+5; i++ {
+		_, _, _ = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 100)
+	}
+
+	rejections := e.Rejections("1")
+	assertEqual(t, MaxRejectionsPerUser, len(rejections), "Log is bounded")
+	assertEqual(t, uint64(MaxRejectionsPerUser+5), rejections[0].Seq, "Newest kept")
+	assertEqual(t, uint64(6), rejections[len(rejections)-1].Seq, "Oldest dropped")
+}
+
+func TestEngine_Rejections_BoundedUsers(t *testing.T) {
+	e := setupEngine()
+
+	_, _, _ = e.PlaceOrder("u0", btcBrl(), orderbook.Bid, 50_000, 1)
+	_, _, _ = e.PlaceOrder("u1", btcBrl(), orderbook.Bid, 50_000, 1)
+	_, _, _ = e.PlaceOrder("u0", btcBrl(), orderbook.Bid, 50_000, 1) // u1 is now the least recent
+	for i := 2; i <= MaxRejectionUsers; i++ {
+		_, _, _ = e.PlaceOrder(fmt.Sprintf("u%d", i), btcBrl(), orderbook.Bid, 50_000, 1)
+	}
+
+	assertEqual(t, 2, len(e.Rejections("u0")), "Recently rejected user kept")
+	assertEqual(t, 0, len(e.Rejections("u1")), "Least recently rejected user dropped")
+	assertEqual(t, 1, len(e.Rejections(fmt.Sprintf("u%d", MaxRejectionUsers))), "Newest user kept")
+}
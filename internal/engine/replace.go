@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+// MaxClientOrderIDLength bounds the client order IDs of PlaceOrReplace, in
+// bytes.
+const MaxClientOrderIDLength = 64
+
+// clientOrderKey identifies a client order ID. Each user has their own on
+// each pair.
+type clientOrderKey struct {
+	pair   string
+	userID string
+	id     string
+}
+
+// ReplaceResult is what PlaceOrReplace did.
+type ReplaceResult struct {
+	Order    *orderbook.Order  // the order now resting (or filled) under the client order ID
+	Matches  []orderbook.Match // what Order matched when it was placed
+	Replaced *orderbook.Order  // the order it cancelled, as archived; nil when none rested under the ID
+}
+
+// PlaceOrReplace places a limit order under clientOrderID, the way a market
+// maker updates a quote. If userID already has an order resting on pair
+// under that ID, it is cancelled and the new one placed in the same critical
+// section: no other order, cancel or fill sees the book with both orders or
+// with neither. The new order queues behind its level even at an unchanged
+// price.
+//
+// amount is the size of the new order, whatever the old one filled. Fills
+// the old order got before the replace stand and only its remainder is
+// released, within the same step that locks the funds of the new order. An
+// old order that filled completely has already left the book, so the call
+// then places a fresh order and Replaced is nil.
+//
+// The new order must be on the side of the one it replaces. When it is
+// refused, the old order keeps resting untouched.
+func (e *Engine) PlaceOrReplace(userID string, pair Pair, clientOrderID string, side orderbook.Side, price, amount float64) (*ReplaceResult, error) {
+	return e.PlaceOrReplaceWithOptions(userID, pair, clientOrderID, side, price, amount, OrderOptions{})
+}
+
+// PlaceOrReplaceWithOptions is PlaceOrReplace with options for the new
+// order. An improve-only replacement is compared against the book without
+// the order it replaces, and when refused leaves that order resting.
+func (e *Engine) PlaceOrReplaceWithOptions(userID string, pair Pair, clientOrderID string, side orderbook.Side, price, amount float64, opts OrderOptions) (result *ReplaceResult, err error) {
+	defer e.serialize()()
+	defer func() {
+		if err != nil {
+			e.recordRejection(userID, pair, side, orderbook.OrderTypeLimit, price, amount, opts.Tag, err)
+		}
+	}()
+
+	// A halt refuses the order before it can spend the nonce
+	if e.halted.Load() {
+		return nil, ErrEngineHalted
+	}
+	if clientOrderID == "" || len(clientOrderID) > MaxClientOrderIDLength {
+		return nil, ErrInvalidClientOrderID
+	}
+	if err := e.CheckNonce(userID, opts.Nonce); err != nil {
+		return nil, err
+	}
+	order, cfg, err := e.newLimitOrder(userID, pair, side, price, amount, opts)
+	if err != nil {
+		return nil, err
+	}
+	order.ClientID = clientOrderID
+	e.delayTaker(pair, side, order.Price)
+
+	result, err = e.replaceLimitOrder(pair, cfg, order, opts)
+	e.runTriggeredStops()
+	e.repricePegs()
+	return result, err
+}
+
+// replaceLimitOrder places order in place of whatever rests under its
+// client order ID.
+func (e *Engine) replaceLimitOrder(pair Pair, cfg PairConfig, order *orderbook.Order, opts OrderOptions) (*ReplaceResult, error) {
+	lockAsset, lockAmount := e.orderLock(pair, order)
+	key := clientOrderKey{pair: pair.String(), userID: order.UserID, id: order.ClientID}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkTradable(pair); err != nil {
+		return nil, err
+	}
+	ob := e.getOrCreateOrderbook(pair)
+	old, resting := e.clientOrders[key]
+	if resting {
+		_, resting = ob.GetOrder(old.ID)
+	}
+	if !resting {
+		if opts.ImproveOnly && !improvesBook(ob, order, nil) {
+			return nil, ErrWouldNotImprove
+		}
+		if err := e.accounts.Lock(order.UserID, lockAsset, lockAmount); err != nil {
+			return nil, err
+		}
+		return e.submitClientOrder(pair, cfg, order, lockAsset, lockAmount, opts.OrderID, nil)
+	}
+	if old.Side != order.Side {
+		return nil, ErrReplaceSideMismatch
+	}
+	if opts.ImproveOnly && !improvesBook(ob, order, old) {
+		return nil, ErrWouldNotImprove
+	}
+
+	// Run the checks that could refuse the new order while the old one still
+	// rests, before self-trade prevention may cancel other orders: the pair's
+	// mode, the taker cap and the reserved ID, which may have expired or been
+	// spent since it was checked outside the lock, then the funds, handing the
+	// old order's lock over to the new one.
+	if err := checkPairMode(cfg, ob, order); err != nil {
+		return nil, err
+	}
+	if err := e.checkTakerLimit(order.UserID, cfg.Mode != PairModeAuction && crossesBook(ob, order)); err != nil {
+		return nil, err
+	}
+	if err := e.checkReservedID(order.UserID, opts.OrderID); err != nil {
+		return nil, err
+	}
+	_, release := e.remainingLock(pair, old)
+	if err := e.accounts.Relock(order.UserID, lockAsset, release, lockAmount); err != nil {
+		return nil, err
+	}
+	if err := e.preventSelfTrade(pair, ob, order); err != nil {
+		_ = e.accounts.Relock(order.UserID, lockAsset, lockAmount, release)
+		return nil, err
+	}
+
+	// Spend the reserved ID while the old order can still be left resting
+	if err := e.claimReservedID(order, opts.OrderID); err != nil {
+		_ = e.accounts.Relock(order.UserID, lockAsset, lockAmount, release)
+		return nil, err
+	}
+
+	// Take the old order off the book
+	if _, err := ob.CancelOrder(old.ID); err != nil {
+		return nil, fmt.Errorf("replace failed: %w", err)
+	}
+	old.State = orderbook.OrderCancelled
+	archived := e.archiveOrder(pair, old)
+
+	return e.submitClientOrder(pair, cfg, order, lockAsset, lockAmount, 0, &archived.Order)
+}
+
+// submitClientOrder is submitLimitOrder for an order placed under a client
+// order ID, which it files the order under while it rests. Must be called
+// with e.mu held.
+func (e *Engine) submitClientOrder(pair Pair, cfg PairConfig, order *orderbook.Order, lockAsset string, lockAmount float64, reservedID int64, replaced *orderbook.Order) (*ReplaceResult, error) {
+	placed, matches, err := e.submitLimitOrder(pair, cfg, order, lockAsset, lockAmount, reservedID)
+	if err != nil {
+		return nil, err
+	}
+	if _, resting := e.orderbooks[pair.String()].GetOrder(placed.ID); resting {
+		e.clientOrders[clientOrderKey{pair: pair.String(), userID: placed.UserID, id: placed.ClientID}] = placed
+	}
+	return &ReplaceResult{Order: placed, Matches: matches, Replaced: replaced}, nil
+}
+
+// forgetClientOrder drops order from the client order IDs once it leaves the
+// book. Must be called with e.mu held.
+func (e *Engine) forgetClientOrder(pair Pair, order *orderbook.Order) {
+	if order.ClientID == "" {
+		return
+	}
+	key := clientOrderKey{pair: pair.String(), userID: order.UserID, id: order.ClientID}
+	if e.clientOrders[key] == order {
+		delete(e.clientOrders, key)
+	}
+}
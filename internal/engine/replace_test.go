@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+func TestEngine_PlaceOrReplace_PlacesNew(t *testing.T) {
+	e := setupEngine()
+
+	result, err := e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 51_000, 1)
+	assertNoError(t, err)
+	assertTrue(t, result.Replaced == nil, "Nothing to replace")
+	assertEqual(t, "ask-1", result.Order.ClientID, "Placed under the client order ID")
+
+	_, resting := e.GetOrderbook(btcBrl()).GetOrder(result.Order.ID)
+	assertTrue(t, resting, "New order rests")
+	assertFloat(t, 1, e.accounts.GetBalance("2", "BTC").Locked, "Funds locked")
+
+	// Another user's order under the same ID is a separate order
+	other, err := e.PlaceOrReplace("1", btcBrl(), "ask-1", orderbook.Ask, 52_000, 1)
+	assertNoError(t, err)
+	assertTrue(t, other.Replaced == nil, "Client order IDs are per user")
+}
+
+func TestEngine_PlaceOrReplace_Replaces(t *testing.T) {
+	e := setupEngine()
+
+	first, err := e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 51_000, 6)
+	assertNoError(t, err)
+
+	// 9 BTC is more than is available next to the first order, but not once
+	// its lock is handed over
+	second, err := e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 50_500, 9)
+	assertNoError(t, err)
+	assertTrue(t, second.Replaced != nil, "Replaced the resting order")
+	assertEqual(t, first.Order.ID, second.Replaced.ID, "Replaced the first order")
+	assertEqual(t, orderbook.OrderCancelled, second.Replaced.State, "Old order cancelled")
+
+	ob := e.GetOrderbook(btcBrl())
+	_, resting := ob.GetOrder(first.Order.ID)
+	assertFalse(t, resting, "Old order left the book")
+	_, resting = ob.GetOrder(second.Order.ID)
+	assertTrue(t, resting, "New order rests")
+	assertEqual(t, 1, ob.OpenOrderCount("2"), "One live order")
+
+	balance := e.accounts.GetBalance("2", "BTC")
+	assertFloat(t, 9, balance.Locked, "Lock follows the new amount")
+	assertFloat(t, 1, balance.Available, "The rest is available")
+
+	archived, err := e.GetOrder("2", btcBrl(), first.Order.ID)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderCancelled, archived.Order.State, "Old order archived")
+}
+
+func TestEngine_PlaceOrReplace_ImproveOnly(t *testing.T) {
+	e := setupEngine()
+	improve := OrderOptions{ImproveOnly: true}
+
+	first, err := e.PlaceOrReplaceWithOptions("2", btcBrl(), "ask-1", orderbook.Ask, 51_000, 1, improve)
+	assertNoError(t, err)
+
+	// The order being replaced does not count as the touch
+	second, err := e.PlaceOrReplaceWithOptions("2", btcBrl(), "ask-1", orderbook.Ask, 51_000, 1, improve)
+	assertNoError(t, err)
+	assertEqual(t, first.Order.ID, second.Replaced.ID, "Replaced at the same price")
+
+	// Behind another user's ask it is refused, and the old order keeps resting
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_500, 1)
+	assertNoError(t, err)
+	_, err = e.PlaceOrReplaceWithOptions("2", btcBrl(), "ask-1", orderbook.Ask, 50_800, 1, improve)
+	assertEqual(t, ErrWouldNotImprove, err, "Behind the touch")
+	_, resting := e.GetOrderbook(btcBrl()).GetOrder(second.Order.ID)
+	assertTrue(t, resting, "Old order still rests")
+	assertFloat(t, 1, e.accounts.GetBalance("2", "BTC").Locked, "Lock unchanged")
+}
+
+func TestEngine_PlaceOrReplace_RefusedKeepsOldOrder(t *testing.T) {
+	e := setupEngine()
+
+	first, err := e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 51_000, 6)
+	assertNoError(t, err)
+
+	_, err = e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 51_000, 11)
+	assertTrue(t, err != nil, "Not enough BTC for the replacement")
+
+	_, err = e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Bid, 49_000, 1)
+	assertEqual(t, ErrReplaceSideMismatch, err, "Side cannot change")
+
+	_, err = e.PlaceOrReplace("2", btcBrl(), "", orderbook.Ask, 51_000, 1)
+	assertEqual(t, ErrInvalidClientOrderID, err, "Empty client order ID")
+
+	_, resting := e.GetOrderbook(btcBrl()).GetOrder(first.Order.ID)
+	assertTrue(t, resting, "Old order still rests")
+	assertFloat(t, 6, e.accounts.GetBalance("2", "BTC").Locked, "Its lock is untouched")
+}
+
+func TestEngine_PlaceOrReplace_FilledBeforeReplace(t *testing.T) {
+	e := setupEngine()
+
+	first, err := e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+
+	// Part of the quote trades before the update reaches the engine
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.4)
+	assertNoError(t, err)
+
+	partial, err := e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 50_100, 1)
+	assertNoError(t, err)
+	assertEqual(t, first.Order.ID, partial.Replaced.ID, "Partly filled order replaced")
+	assertFloat(t, 0.4, partial.Replaced.FilledAmount, "Its fills stand")
+	assertFloat(t, 1, e.accounts.GetBalance("2", "BTC").Locked, "Only its remainder was released")
+	assertFloat(t, 8.6, e.accounts.GetBalance("2", "BTC").Available, "Sold BTC is gone")
+
+	// All of it trades
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_100, 1)
+	assertNoError(t, err)
+
+	fresh, err := e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 50_200, 1)
+	assertNoError(t, err)
+	assertTrue(t, fresh.Replaced == nil, "A filled order is not replaced")
+	assertEqual(t, 1, e.GetOrderbook(btcBrl()).OpenOrderCount("2"), "One live order")
+	assertFloat(t, 1, e.accounts.GetBalance("2", "BTC").Locked, "Nothing unlocked twice")
+	assertFloat(t, 7.6, e.accounts.GetBalance("2", "BTC").Available, "Balance adds up")
+}
+
+func TestEngine_PlaceOrReplace_InterleavedFills(t *testing.T) {
+	e := setupEngine()
+	ob := e.GetOrderbook(btcBrl())
+
+	// Replaces landing between fills of every size: none, part, all of it
+	for i := 0; i < 60; i++ {
+		_, err := e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 50_000, 0.05)
+		assertNoError(t, err)
+		for j := 0; j < i%6; j++ {
+			_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.01)
+			assertNoError(t, err)
+		}
+
+		asks := ob.UserOrders("2")
+		assertTrue(t, len(asks) <= 1, "Never more than one live order")
+		var remaining float64
+		for _, ask := range asks {
+			remaining += ask.RemainingAmount()
+		}
+		assertFloat(t, e.roundAsset("BTC", remaining), e.accounts.GetBalance("2", "BTC").Locked, "Locked matches what rests")
+	}
+}
+
+func TestEngine_PlaceOrReplace_RefusedBeforeSelfTradeCancels(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.STPMode = STPCancelOldest
+	e := setupEngineWithConfig(cfg)
+
+	bid, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertNoError(t, err)
+	first, err := e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 51_000, 6)
+	assertNoError(t, err)
+
+	// The replacement would cross the user's own bid, but there is not
+	// enough BTC for it
+	_, err = e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 49_000, 11)
+	assertEqual(t, account.ErrInsufficientBalance, err, "Not enough BTC")
+
+	ob := e.GetOrderbook(btcBrl())
+	_, resting := ob.GetOrder(bid.ID)
+	assertTrue(t, resting, "Crossed bid not cancelled")
+	_, resting = ob.GetOrder(first.Order.ID)
+	assertTrue(t, resting, "Old order still rests")
+}
+
+func TestEngine_PlaceOrReplace_ExpiredReservedIDKeepsOld(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+	cfg := DefaultConfig()
+	cfg.Clock = clk
+	// A taker waits long enough for the reservation to expire past the
+	// check made outside the lock
+	cfg.TakerThrottle = TakerThrottle{Delay: ReservedOrderIDTTL}
+	e := setupEngineWithConfig(cfg)
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+	first, err := e.PlaceOrReplace("2", btcBrl(), "ask-1", orderbook.Ask, 51_000, 1)
+	assertNoError(t, err)
+
+	id, _, err := e.ReserveOrderID("2")
+	assertNoError(t, err)
+	_, err = e.PlaceOrReplaceWithOptions("2", btcBrl(), "ask-1", orderbook.Ask, 50_000, 1, OrderOptions{OrderID: id, Tag: "quote"})
+	assertEqual(t, ErrOrderIDNotReserved, err, "Reservation expired during the delay")
+
+	_, resting := e.GetOrderbook(btcBrl()).GetOrder(first.Order.ID)
+	assertTrue(t, resting, "Old order still rests")
+	assertFloat(t, 1, e.accounts.GetBalance("2", "BTC").Locked, "Old order keeps its lock")
+	assertEqual(t, 0, len(e.Trades(btcBrl())), "Nothing matched")
+
+	rejections := e.Rejections("2")
+	assertEqual(t, 1, len(rejections), "Refusal logged")
+	assertEqual(t, "quote", rejections[0].Tag, "Tag kept")
+}
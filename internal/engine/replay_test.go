@@ -0,0 +1,82 @@
+package engine_test
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/internal/testutil"
+)
+
+func TestEngine_Replay_PartialFills(t *testing.T) {
+	e := engine.NewEngine()
+	result := testutil.Replay(t, e, filepath.Join("testdata", "replay_partial_fills.json"))
+
+	assertFloat := func(expected, actual float64, msg string) {
+		t.Helper()
+		if math.Abs(expected-actual) > 1e-9 {
+			t.Errorf("%s: expected %.8f, got %.8f", msg, expected, actual)
+		}
+	}
+
+	if len(result.Matches) != 3 {
+		t.Fatalf("Expected 3 matches, got %d", len(result.Matches))
+	}
+	assertFloat(50_000, result.Matches[0].Price, "First fill at the best ask")
+	assertFloat(0.2, result.Matches[1].SizeFilled, "Second fill takes part of the next level")
+	assertFloat(49_000, result.Matches[2].Price, "Market sell hits the resting bid")
+
+	if state := result.Orders["sweep"].State; state != orderbook.OrderFilled {
+		t.Errorf("Sweeping bid: expected filled, got %s", state)
+	}
+	if resting := result.Orders["resting"]; resting.Tag != "grid-1" || resting.State != orderbook.OrderPartiallyFilled {
+		t.Errorf("Resting bid: expected partially filled with tag grid-1, got %s %q", resting.State, resting.Tag)
+	}
+	if state := result.Orders["ask2"].State; state != orderbook.OrderCancelled {
+		t.Errorf("Cancelled ask: expected cancelled, got %s", state)
+	}
+
+	// Only the rest of the tagged bid is left on the book
+	book := result.Books["BTC/BRL"]
+	if len(book.Bids) != 1 || book.Bids[0].Orders != 1 {
+		t.Fatalf("Expected 1 resting bid, got %+v", book.Bids)
+	}
+	assertFloat(49_000, book.Bids[0].Price, "Best bid")
+	assertFloat(0.2, book.Bids[0].Volume, "Volume left at the best bid")
+	if len(book.Asks) != 0 {
+		t.Errorf("Expected no asks left, got %+v", book.Asks)
+	}
+
+	balances := result.Balances
+	assertFloat(100_000-25_000-10_020-4_900-9_800, balances["1"]["BRL"].Available, "Buyer BRL available")
+	assertFloat(9_800, balances["1"]["BRL"].Locked, "Buyer BRL locked for the resting bid")
+	assertFloat(0.8, balances["1"]["BTC"].Available, "Buyer BTC")
+	assertFloat(1.4, balances["2"]["BTC"].Available, "Seller 2 BTC")
+	assertFloat(29_900, balances["2"]["BRL"].Available, "Seller 2 BRL")
+	assertFloat(0.8, balances["3"]["BTC"].Available, "Seller 3 BTC after the cancel")
+	assertFloat(0, balances["3"]["BTC"].Locked, "Seller 3 nothing locked")
+	assertFloat(10_020, balances["3"]["BRL"].Available, "Seller 3 BRL")
+}
+
+func TestEngine_Replay_ResultIsACopy(t *testing.T) {
+	e := engine.NewEngine()
+	result := testutil.Replay(t, e, filepath.Join("testdata", "replay_partial_fills.json"))
+	resting := result.Orders["resting"]
+
+	btcBrl := engine.Pair{Base: "BTC", Quote: "BRL"}
+	if _, err := e.CancelOrder("1", btcBrl, resting.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	if state := result.Orders["resting"].State; state != orderbook.OrderPartiallyFilled {
+		t.Errorf("Result order: expected partially filled, got %s", state)
+	}
+	if len(result.Books["BTC/BRL"].Bids) != 1 {
+		t.Error("Result book changed with the engine")
+	}
+	if locked := result.Balances["1"]["BRL"].Locked; locked != 9_800 {
+		t.Errorf("Result balance changed with the engine: locked %.2f", locked)
+	}
+}
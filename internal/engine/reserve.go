@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+// MaxReservedOrderIDs bounds the IDs a user may hold reserved and not yet
+// used.
+const MaxReservedOrderIDs = 100
+
+// ReservedOrderIDTTL is how long a reserved order ID stays usable.
+const ReservedOrderIDTTL = 10 * time.Minute
+
+// reservation is an order ID held for a user until it expires.
+type reservation struct {
+	userID    string
+	expiresAt time.Time
+}
+
+// reservedIDs holds the order IDs handed out by ReserveOrderID until an
+// order uses them or they expire.
+type reservedIDs struct {
+	mu      sync.Mutex
+	owners  map[int64]reservation // reserved ID -> who it is held for
+	counts  map[string]int        // user ID -> IDs held
+	pruneAt time.Time             // next sweep of expired IDs
+}
+
+// ReserveOrderID allocates an order ID for userID to place an order under
+// before expiresAt, through OrderOptions.OrderID. It comes from the same
+// sequence as every other order ID, so it never collides with one. A user
+// may hold at most MaxReservedOrderIDs unused IDs.
+func (e *Engine) ReserveOrderID(userID string) (id int64, expiresAt time.Time, err error) {
+	if userID == "" {
+		return 0, time.Time{}, account.ErrInvalidUserID
+	}
+
+	r := &e.reserved
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := e.config.Clock.Now()
+	if r.owners == nil {
+		r.owners = make(map[int64]reservation)
+		r.counts = make(map[string]int)
+	}
+	if r.counts[userID] >= MaxReservedOrderIDs || !now.Before(r.pruneAt) {
+		r.prune(now)
+	}
+	if r.counts[userID] >= MaxReservedOrderIDs {
+		return 0, time.Time{}, ErrTooManyReservedIDs
+	}
+
+	id = e.newOrderID()
+	expiresAt = now.Add(ReservedOrderIDTTL)
+	r.owners[id] = reservation{userID: userID, expiresAt: expiresAt}
+	r.counts[userID]++
+	return id, expiresAt, nil
+}
+
+// newOrderID draws the next ID from the sequence new orders are given.
+func (e *Engine) newOrderID() int64 {
+	if e.config.Deterministic {
+		return int64(e.orderCounter.Add(1))
+	}
+	return orderbook.NextOrderID()
+}
+
+// checkReservedID fails unless id is 0 or reserved for userID and still
+// usable. It leaves the ID reserved: only claimReservedID spends it.
+func (e *Engine) checkReservedID(userID string, id int64) error {
+	if id == 0 {
+		return nil
+	}
+
+	r := &e.reserved
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err := r.lookup(userID, id, e.config.Clock.Now())
+	return err
+}
+
+// claimReservedID spends the ID reserved for order's owner and gives it to
+// order, or leaves the order as created when id is 0. It runs once the
+// order has passed every check, so a rejected order leaves the ID reserved.
+// Must be called with e.mu held.
+func (e *Engine) claimReservedID(order *orderbook.Order, id int64) error {
+	if id == 0 {
+		return nil
+	}
+
+	r := &e.reserved
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.lookup(order.UserID, id, e.config.Clock.Now()); err != nil {
+		return err
+	}
+	r.remove(id)
+
+	order.ID = id
+	return nil
+}
+
+// lookup returns the reservation of id if it is held for userID and not
+// expired at now, dropping it once expired. Must be called with r.mu held.
+func (r *reservedIDs) lookup(userID string, id int64, now time.Time) (reservation, error) {
+	res, ok := r.owners[id]
+	if !ok || res.userID != userID {
+		return reservation{}, ErrOrderIDNotReserved
+	}
+	if !now.Before(res.expiresAt) {
+		r.remove(id)
+		return reservation{}, ErrOrderIDNotReserved
+	}
+	return res, nil
+}
+
+// prune drops every reservation expired at now and schedules the next
+// sweep. Must be called with r.mu held.
+func (r *reservedIDs) prune(now time.Time) {
+	for id, res := range r.owners {
+		if !now.Before(res.expiresAt) {
+			r.remove(id)
+		}
+	}
+	r.pruneAt = now.Add(ReservedOrderIDTTL)
+}
+
+// remove drops the reservation of id. Must be called with r.mu held.
+func (r *reservedIDs) remove(id int64) {
+	userID := r.owners[id].userID
+	delete(r.owners, id)
+	r.counts[userID]--
+	if r.counts[userID] == 0 {
+		delete(r.counts, userID)
+	}
+}
@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+func TestEngine_ReserveOrderID_ThenPlace(t *testing.T) {
+	e := setupEngine()
+
+	id, _, err := e.ReserveOrderID("1")
+	assertNoError(t, err)
+
+	// Orders placed in between do not take the reserved ID
+	other, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 52_000, 1)
+	assertNoError(t, err)
+	assertTrue(t, other.ID != id, "Fresh ID for an unreserved order")
+
+	order, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 1, OrderOptions{OrderID: id})
+	assertNoError(t, err)
+	assertEqual(t, id, order.ID, "Placed under the reserved ID")
+
+	resting, ok := e.GetOrderbook(btcBrl()).GetOrder(id)
+	assertTrue(t, ok, "Resting under the reserved ID")
+	assertEqual(t, "1", resting.UserID, "Owner")
+
+	// Its ID predates an order placed earlier; listings still go by placement
+	earlier, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 0.1)
+	assertNoError(t, err)
+	id, _, err = e.ReserveOrderID("1")
+	assertNoError(t, err)
+	early, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 48_000, 0.1)
+	assertNoError(t, err)
+	late, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 47_000, 0.1, OrderOptions{OrderID: id})
+	assertNoError(t, err)
+	orders := e.GetOrderbook(btcBrl()).UserOrders("1")
+	assertEqual(t, 4, len(orders), "Resting orders")
+	assertEqual(t, earlier.ID, orders[1].ID, "Placed second")
+	assertEqual(t, early.ID, orders[2].ID, "Placed third")
+	assertEqual(t, late.ID, orders[3].ID, "Placed last, under the older ID")
+
+	// Market orders take reserved IDs too
+	id, _, err = e.ReserveOrderID("1")
+	assertNoError(t, err)
+	market, _, err := e.PlaceMarketOrderWithOptions("1", btcBrl(), orderbook.Bid, 0.5, OrderOptions{OrderID: id})
+	assertNoError(t, err)
+	assertEqual(t, id, market.ID, "Market order under the reserved ID")
+}
+
+func TestEngine_ReserveOrderID_Reuse(t *testing.T) {
+	e := setupEngine()
+
+	id, _, err := e.ReserveOrderID("1")
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 0.1, OrderOptions{OrderID: id})
+	assertNoError(t, err)
+
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 49_000, 0.1, OrderOptions{OrderID: id})
+	assertEqual(t, ErrOrderIDNotReserved, err, "Used IDs cannot be reused")
+
+	// A rejected order leaves it reserved, whichever check refused it
+	id, _, err = e.ReserveOrderID("1")
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 1_000, OrderOptions{OrderID: id})
+	assertTrue(t, err != nil, "Insufficient balance")
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 0.1, OrderOptions{OrderID: id, ImproveOnly: true})
+	assertEqual(t, ErrWouldNotImprove, err, "Joins the best bid")
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_010, 0.1, OrderOptions{OrderID: id, ImproveOnly: true})
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_020, 0.1, OrderOptions{OrderID: id})
+	assertEqual(t, ErrOrderIDNotReserved, err, "Used by the accepted order")
+
+	// Only the user it was reserved for can use it
+	id, _, err = e.ReserveOrderID("1")
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrderWithOptions("2", btcBrl(), orderbook.Ask, 51_000, 0.1, OrderOptions{OrderID: id})
+	assertEqual(t, ErrOrderIDNotReserved, err, "Reserved for another user")
+
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 0.1, OrderOptions{OrderID: 1 << 40})
+	assertEqual(t, ErrOrderIDNotReserved, err, "Never reserved")
+}
+
+func TestEngine_ReserveOrderID_Limit(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.ReserveOrderID("")
+	assertTrue(t, err != nil, "User required")
+
+	var last int64
+	for i := 0; i < MaxReservedOrderIDs; i++ {
+		id, _, err := e.ReserveOrderID("1")
+		assertNoError(t, err)
+		assertTrue(t, id > last, "IDs increase")
+		last = id
+	}
+	_, _, err = e.ReserveOrderID("1")
+	assertEqual(t, ErrTooManyReservedIDs, err, "Limit reached")
+
+	// Using one frees a slot
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 0.1, OrderOptions{OrderID: last})
+	assertNoError(t, err)
+	_, _, err = e.ReserveOrderID("1")
+	assertNoError(t, err)
+}
+
+func TestEngine_ReserveOrderID_Expires(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+	cfg := DefaultConfig()
+	cfg.Clock = clk
+	e := setupEngineWithConfig(cfg)
+
+	id, expiresAt, err := e.ReserveOrderID("1")
+	assertNoError(t, err)
+	assertEqual(t, clk.Now().Add(ReservedOrderIDTTL), expiresAt, "Expiry")
+
+	clk.Advance(ReservedOrderIDTTL)
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 50_000, 0.1, OrderOptions{OrderID: id})
+	assertEqual(t, ErrOrderIDNotReserved, err, "Expired")
+
+	// Expired IDs free their slots
+	for i := 0; i < MaxReservedOrderIDs; i++ {
+		_, _, err := e.ReserveOrderID("1")
+		assertNoError(t, err)
+	}
+	clk.Advance(ReservedOrderIDTTL)
+	_, _, err = e.ReserveOrderID("1")
+	assertNoError(t, err)
+}
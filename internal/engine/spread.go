@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
+)
+
+const (
+	// DefaultSpreadSampleInterval is the SpreadSampleInterval of DefaultConfig.
+	DefaultSpreadSampleInterval = time.Second
+
+	// MaxSpreadSamples bounds the samples kept per pair; once full, each new
+	// sample replaces the oldest.
+	MaxSpreadSamples = 3600
+)
+
+// SpreadSample is the visible top of one pair's book at a point in time.
+// Hidden orders are left out, as in the public book.
+type SpreadSample struct {
+	Time   time.Time
+	HasBid bool
+	Bid    float64 // best bid, 0 without one
+	HasAsk bool
+	Ask    float64 // best ask, 0 without one
+	Spread float64 // Ask - Bid, 0 unless both sides exist
+	Mid    float64 // (Bid + Ask) / 2, 0 unless both sides exist
+}
+
+// spreadRing holds the latest MaxSpreadSamples samples of a pair.
+type spreadRing struct {
+	samples []SpreadSample
+	next    int // where the next sample goes once samples is full
+}
+
+func (r *spreadRing) add(s SpreadSample) {
+	if len(r.samples) < MaxSpreadSamples {
+		r.samples = append(r.samples, s)
+		return
+	}
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % MaxSpreadSamples
+}
+
+// last returns up to n samples, oldest first.
+func (r *spreadRing) last(n int) []SpreadSample {
+	ordered := append(append([]SpreadSample(nil), r.samples[r.next:]...), r.samples[:r.next]...)
+	if n > 0 && n < len(ordered) {
+		ordered = ordered[len(ordered)-n:]
+	}
+	return ordered
+}
+
+// SampleSpreads records the top of every book, unless the last sample was
+// taken less than Config.SpreadSampleInterval ago by the engine clock. It
+// reports whether it sampled. Books are only read, under the read lock, so
+// sampling never holds up matching for longer than reading two price levels
+// per pair.
+func (e *Engine) SampleSpreads() bool {
+	now := e.config.Clock.Now()
+
+	e.spreadMu.Lock()
+	defer e.spreadMu.Unlock()
+
+	if !e.lastSpreadSample.IsZero() && now.Sub(e.lastSpreadSample) < e.config.SpreadSampleInterval {
+		return false
+	}
+
+	e.mu.RLock()
+	samples := make(map[string]SpreadSample, len(e.orderbooks))
+	for key, ob := range e.orderbooks {
+		samples[key] = topSample(ob, now)
+	}
+	e.mu.RUnlock()
+
+	for key, sample := range samples {
+		ring, exists := e.spreads[key]
+		if !exists {
+			ring = &spreadRing{}
+			e.spreads[key] = ring
+		}
+		ring.add(sample)
+	}
+	e.lastSpreadSample = now
+	return true
+}
+
+// topSample reads ob's best visible prices. Must be called with e.mu held.
+func topSample(ob *orderbook.Orderbook, now time.Time) SpreadSample {
+	hidden := func(o *orderbook.Order) bool { return o.Hidden }
+	tick := ob.PriceTick()
+
+	sample := SpreadSample{Time: now}
+	if bid, ok := ob.BestLimitExcluding(orderbook.Bid, hidden); ok {
+		sample.HasBid = true
+		sample.Bid = bid.Price(tick)
+	}
+	if ask, ok := ob.BestLimitExcluding(orderbook.Ask, hidden); ok {
+		sample.HasAsk = true
+		sample.Ask = ask.Price(tick)
+	}
+	if sample.HasBid && sample.HasAsk {
+		sample.Spread = utils.RoundToTick(sample.Ask-sample.Bid, tick)
+		sample.Mid = (sample.Bid + sample.Ask) / 2
+	}
+	return sample
+}
+
+// SpreadHistory returns the latest limit samples of pair, oldest first; a
+// limit of 0 returns every sample kept.
+func (e *Engine) SpreadHistory(pair Pair, limit int) ([]SpreadSample, error) {
+	if _, err := e.listedPairConfig(pair); err != nil {
+		return nil, err
+	}
+
+	e.spreadMu.Lock()
+	defer e.spreadMu.Unlock()
+
+	ring, exists := e.spreads[pair.String()]
+	if !exists {
+		return []SpreadSample{}, nil
+	}
+	return ring.last(limit), nil
+}
+
+// StartSpreadSampler runs SampleSpreads every Config.SpreadSampleInterval,
+// measured on Config.Clock, until the returned stop function is called. It
+// does nothing when the interval is not positive.
+func (e *Engine) StartSpreadSampler() (stop func()) {
+	interval := e.config.SpreadSampleInterval
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-clock.After(e.config.Clock, interval):
+				e.SampleSpreads()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+func setupSpreadEngine() (*Engine, *clock.Fake) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+
+	cfg := DefaultConfig()
+	cfg.Clock = clk
+	return setupEngineWithConfig(cfg), clk
+}
+
+func TestEngine_SampleSpreads_FollowsClock(t *testing.T) {
+	e, clk := setupSpreadEngine()
+	start := clk.Now()
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_900, 0.5)
+	assertNoError(t, err)
+	assertTrue(t, e.SampleSpreads(), "First sample taken")
+
+	// Within the interval nothing is recorded
+	clk.Advance(DefaultSpreadSampleInterval / 2)
+	assertFalse(t, e.SampleSpreads(), "Too soon for another sample")
+
+	clk.Advance(DefaultSpreadSampleInterval / 2)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_100, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrderWithOptions("2", btcBrl(), orderbook.Ask, 50_000, 0.5, OrderOptions{Hidden: true})
+	assertNoError(t, err)
+	assertTrue(t, e.SampleSpreads(), "Sampled once the interval passed")
+
+	samples, err := e.SpreadHistory(btcBrl(), 0)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(samples), "Samples")
+
+	first, second := samples[0], samples[1]
+	assertTrue(t, first.Time.Equal(start), "First sample time")
+	assertTrue(t, first.HasBid, "Bid side sampled")
+	assertFalse(t, first.HasAsk, "No ask yet")
+	assertFloat(t, 0, first.Spread, "No spread with one side")
+
+	assertTrue(t, second.Time.Equal(start.Add(DefaultSpreadSampleInterval)), "Second sample time")
+	assertFloat(t, 50_100, second.Ask, "Hidden ask left out")
+	assertFloat(t, 200, second.Spread, "Spread")
+	assertFloat(t, 50_000, second.Mid, "Mid")
+
+	latest, err := e.SpreadHistory(btcBrl(), 1)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(latest), "Limited")
+	assertTrue(t, latest[0].Time.Equal(second.Time), "Latest sample kept")
+}
+
+func TestEngine_SpreadHistory_Bounded(t *testing.T) {
+	e, clk := setupSpreadEngine()
+
+	for i := 0; i < MaxSpreadSamples+10; i++ {
+		assertTrue(t, e.SampleSpreads(), "Sampled")
+		clk.Advance(time.Second)
+	}
+
+	samples, err := e.SpreadHistory(btcBrl(), 0)
+	assertNoError(t, err)
+	assertEqual(t, MaxSpreadSamples, len(samples), "Ring is bounded")
+	for i := 1; i < len(samples); i++ {
+		if !samples[i].Time.After(samples[i-1].Time) {
+			t.Fatalf("Samples out of order at %d", i)
+		}
+	}
+
+	_, err = e.SpreadHistory(Pair{Base: "DOGE", Quote: "BRL"}, 0)
+	assertEqual(t, ErrUnknownPair, err, "Unlisted pair")
+}
+
+func TestEngine_StartSpreadSampler_FollowsClock(t *testing.T) {
+	e, clk := setupSpreadEngine()
+	stop := e.StartSpreadSampler()
+	defer stop()
+
+	// The sampler only fires once the engine clock moves, however long it
+	// waits in wall time
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		samples, err := e.SpreadHistory(btcBrl(), 0)
+		assertNoError(t, err)
+		if len(samples) > 0 {
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			assertFalse(t, samples[0].Time.Before(start.Add(DefaultSpreadSampleInterval)), "Stamped on the engine clock")
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Sampler never fired on the engine clock")
+		}
+		clk.Advance(DefaultSpreadSampleInterval)
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+// Stats is a point-in-time snapshot of engine activity.
+type Stats struct {
+	OrdersPlaced int64              // limit and market orders accepted
+	Matches      int64              // fills executed
+	Volume       map[string]float64 // asset -> amount traded, counting both legs of each fill
+	ActivePairs  int                // registered pairs that are not halted
+	OpenOrders   int                // orders resting across all books
+
+	RebatesSkipped int64 // maker rebates the fee account could not cover
+
+	// MatchLatency has one histogram per pair and order type that has
+	// matched, sorted by pair and then type.
+	MatchLatency []MatchLatency
+}
+
+// MatchLatencyBuckets are the upper bounds of the MatchLatency buckets.
+// Samples slower than the last bound land in a final, unbounded bucket.
+var MatchLatencyBuckets = [...]time.Duration{
+	time.Microsecond,
+	5 * time.Microsecond,
+	10 * time.Microsecond,
+	25 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+}
+
+// MatchLatency is the histogram of time spent in the orderbook matching
+// incoming orders of one type on one pair, from the moment the order reaches
+// the book until its matches are returned. Validation, locking funds and
+// settling the matches are not included.
+type MatchLatency struct {
+	Pair      Pair
+	OrderType orderbook.OrderType
+	Count     int64
+	Sum       time.Duration
+
+	// Buckets[i] counts the samples over MatchLatencyBuckets[i-1] and up to
+	// MatchLatencyBuckets[i]; the extra last bucket counts the slower ones.
+	Buckets []int64
+}
+
+// counters holds the hot-path statistics. They are updated with atomics so
+// reading them for Stats never contends with matching.
+type counters struct {
+	ordersPlaced   atomic.Int64
+	matches        atomic.Int64
+	rebatesSkipped atomic.Int64
+	volume         sync.Map // asset -> *atomicFloat
+	matchLatency   sync.Map // latencyKey -> *latencyHistogram
+}
+
+type latencyKey struct {
+	pair      Pair
+	orderType orderbook.OrderType
+}
+
+// latencyHistogram counts samples into MatchLatencyBuckets with atomics.
+type latencyHistogram struct {
+	count   atomic.Int64
+	sum     atomic.Int64 // nanoseconds
+	buckets [len(MatchLatencyBuckets) + 1]atomic.Int64
+}
+
+// observeMatching records that matching an order of orderType on pair took d.
+func (c *counters) observeMatching(pair Pair, orderType orderbook.OrderType, d time.Duration) {
+	key := latencyKey{pair: pair, orderType: orderType}
+	h, ok := c.matchLatency.Load(key)
+	if !ok {
+		h, _ = c.matchLatency.LoadOrStore(key, new(latencyHistogram))
+	}
+	hist := h.(*latencyHistogram)
+
+	i := 0
+	for i < len(MatchLatencyBuckets) && d > MatchLatencyBuckets[i] {
+		i++
+	}
+	hist.buckets[i].Add(1)
+	hist.count.Add(1)
+	hist.sum.Add(int64(d))
+}
+
+func (c *counters) addVolume(asset string, amount float64) {
+	v, ok := c.volume.Load(asset)
+	if !ok {
+		v, _ = c.volume.LoadOrStore(asset, new(atomicFloat))
+	}
+	v.(*atomicFloat).add(amount)
+}
+
+// atomicFloat is a float64 updated with compare-and-swap on its bits.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) add(delta float64) {
+	for {
+		old := f.bits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if f.bits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// Stats returns a snapshot of the engine counters plus the current number of
+// active pairs and resting orders.
+func (e *Engine) Stats() Stats {
+	stats := Stats{
+		OrdersPlaced: e.stats.ordersPlaced.Load(),
+		Matches:      e.stats.matches.Load(),
+		Volume:       make(map[string]float64),
+
+		RebatesSkipped: e.stats.rebatesSkipped.Load(),
+	}
+
+	e.stats.volume.Range(func(asset, v any) bool {
+		stats.Volume[asset.(string)] = v.(*atomicFloat).load()
+		return true
+	})
+
+	e.stats.matchLatency.Range(func(k, v any) bool {
+		key, hist := k.(latencyKey), v.(*latencyHistogram)
+		latency := MatchLatency{
+			Pair:      key.pair,
+			OrderType: key.orderType,
+			Count:     hist.count.Load(),
+			Sum:       time.Duration(hist.sum.Load()),
+			Buckets:   make([]int64, len(hist.buckets)),
+		}
+		for i := range hist.buckets {
+			latency.Buckets[i] = hist.buckets[i].Load()
+		}
+		stats.MatchLatency = append(stats.MatchLatency, latency)
+		return true
+	})
+	sort.Slice(stats.MatchLatency, func(i, j int) bool {
+		a, b := stats.MatchLatency[i], stats.MatchLatency[j]
+		if a.Pair != b.Pair {
+			return a.Pair.String() < b.Pair.String()
+		}
+		return a.OrderType < b.OrderType
+	})
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, cfg := range e.pairs {
+		if !cfg.Halted {
+			stats.ActivePairs++
+		}
+	}
+	for _, ob := range e.orderbooks {
+		stats.OpenOrders += ob.OpenOrderTotal()
+	}
+
+	return stats
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestEngine_Stats(t *testing.T) {
+	e := setupEngine()
+
+	stats := e.Stats()
+	assertEqual(t, int64(0), stats.OrdersPlaced, "No orders yet")
+	assertEqual(t, 3, stats.ActivePairs, "Pre-listed pairs")
+
+	// Two resting asks, one taker that fills both, one extra resting bid
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 52_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceMarketOrder("2", btcBrl(), orderbook.Bid, 1.0)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 40_000, 0.25)
+	assertNoError(t, err)
+
+	// Rejected orders are not counted
+	_, _, err = e.PlaceOrder("3", btcBrl(), orderbook.Bid, 40_000, 1)
+	assertTrue(t, err != nil, "Unfunded order rejected")
+
+	assertNoError(t, e.HaltPair(Pair{Base: "USDT", Quote: "BRL"}))
+
+	stats = e.Stats()
+	assertEqual(t, int64(4), stats.OrdersPlaced, "Orders placed")
+	assertEqual(t, int64(2), stats.Matches, "Matches")
+	assertFloat(t, 1.0, stats.Volume["BTC"], "BTC volume")
+	assertFloat(t, 51_000, stats.Volume["BRL"], "BRL volume")
+	assertEqual(t, 2, stats.ActivePairs, "Halted pair is not active")
+	assertEqual(t, 1, stats.OpenOrders, "Only the 40k bid rests")
+}
+
+func TestEngine_Stats_MatchLatency(t *testing.T) {
+	e := setupEngine()
+	assertEqual(t, 0, len(e.Stats().MatchLatency), "No samples yet")
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_100, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceMarketOrder("2", btcBrl(), orderbook.Bid, 0.25)
+	assertNoError(t, err)
+
+	// Rejected before reaching the book: no sample
+	_, _, err = e.PlaceOrder("3", btcBrl(), orderbook.Bid, 40_000, 1)
+	assertTrue(t, err != nil, "Unfunded order rejected")
+
+	latency := e.Stats().MatchLatency
+	assertEqual(t, 2, len(latency), "One histogram per pair and order type")
+	for i, want := range []struct {
+		orderType orderbook.OrderType
+		count     int64
+	}{
+		{orderbook.OrderTypeLimit, 2},
+		{orderbook.OrderTypeMarket, 1},
+	} {
+		h := latency[i]
+		assertEqual(t, btcBrl(), h.Pair, "Pair label")
+		assertEqual(t, want.orderType, h.OrderType, "Order type label")
+		assertEqual(t, want.count, h.Count, "Samples")
+		assertEqual(t, len(MatchLatencyBuckets)+1, len(h.Buckets), "Bucket count")
+
+		total := int64(0)
+		for _, n := range h.Buckets {
+			total += n
+		}
+		assertEqual(t, h.Count, total, "Every sample in a bucket")
+		assertTrue(t, h.Sum >= 0, "Sum recorded")
+	}
+}
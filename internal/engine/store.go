@@ -0,0 +1,34 @@
+package engine
+
+// OrderStore holds the orders the engine archived. The in-memory
+// MemoryOrderStore is the default; a persistent implementation can be set in
+// Config.OrderStore. The engine serialises every call under its own lock, so
+// implementations need not be safe for concurrent use.
+type OrderStore interface {
+	// Append saves order at the end of its owner's history.
+	Append(order ArchivedOrder) error
+	// UserOrders returns userID's archived orders, oldest first.
+	UserOrders(userID string) ([]ArchivedOrder, error)
+}
+
+// MemoryOrderStore is an OrderStore backed by a map of slices.
+type MemoryOrderStore struct {
+	orders map[string][]ArchivedOrder // user ID -> archived orders, oldest first
+}
+
+func NewMemoryOrderStore() *MemoryOrderStore {
+	return &MemoryOrderStore{orders: make(map[string][]ArchivedOrder)}
+}
+
+func (s *MemoryOrderStore) Append(order ArchivedOrder) error {
+	userID := order.Order.UserID
+	s.orders[userID] = append(s.orders[userID], order)
+	return nil
+}
+
+// UserOrders returns the stored slice itself, capped so appending to it
+// cannot write into the store; callers must not modify its elements.
+func (s *MemoryOrderStore) UserOrders(userID string) ([]ArchivedOrder, error) {
+	orders := s.orders[userID]
+	return orders[:len(orders):len(orders)], nil
+}
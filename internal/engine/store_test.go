@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+// countingOrderStore records how many orders the engine archived through it.
+type countingOrderStore struct {
+	*MemoryOrderStore
+	appended int
+}
+
+func (s *countingOrderStore) Append(order ArchivedOrder) error {
+	s.appended++
+	return s.MemoryOrderStore.Append(order)
+}
+
+// flakyOrderStore refuses every append while down.
+type flakyOrderStore struct {
+	*MemoryOrderStore
+	down bool
+}
+
+func (s *flakyOrderStore) Append(order ArchivedOrder) error {
+	if s.down {
+		return errors.New("store down")
+	}
+	return s.MemoryOrderStore.Append(order)
+}
+func TestEngine_CustomStores(t *testing.T) {
+	accounts := account.NewMemoryStore()
+	orders := &countingOrderStore{MemoryOrderStore: NewMemoryOrderStore()}
+
+	cfg := DefaultConfig()
+	cfg.AccountStore = accounts
+	cfg.OrderStore = orders
+	e := setupEngineWithConfig(cfg)
+
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+
+	// Balances live in the given account store
+	balance, ok, err := accounts.Balance("1", "BRL")
+	assertNoError(t, err)
+	assertTrue(t, ok, "Buyer balance stored")
+	assertFloat(t, 50_000, balance.Locked, "Locked in store")
+
+	_, err = e.CancelOrder("1", btcBrl(), order.ID)
+	assertNoError(t, err)
+
+	assertEqual(t, 1, orders.appended, "Archived through the order store")
+	history, total, err := e.OrderHistory("1", HistoryFilter{})
+	assertNoError(t, err)
+	assertEqual(t, 1, total, "History total")
+	assertEqual(t, order.ID, history[0].Order.ID, "History read from the order store")
+}
+
+func TestEngine_OrderStoreError(t *testing.T) {
+	store := &flakyOrderStore{MemoryOrderStore: NewMemoryOrderStore(), down: true}
+	cfg := DefaultConfig()
+	cfg.OrderStore = store
+	e := setupEngineWithConfig(cfg)
+
+	// A trade settles even though neither filled order can be stored yet
+	ask, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+	bid, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Trade executed")
+	assertFloat(t, 11, e.accounts.GetBalance("1", "BTC").Available, "Buyer credited")
+	assertFloat(t, 150_000, e.accounts.GetBalance("2", "BRL").Available, "Seller credited")
+
+	// The queued orders are already in the history
+	stored, err := store.UserOrders("2")
+	assertNoError(t, err)
+	assertEqual(t, 0, len(stored), "Nothing stored while down")
+	history, _, err := e.OrderHistory("2", HistoryFilter{})
+	assertNoError(t, err)
+	assertEqual(t, ask.ID, history[0].Order.ID, "Queued order in the history")
+
+	// The next archive once the store is back writes the queue in order
+	store.down = false
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 0.1)
+	assertNoError(t, err)
+	_, err = e.CancelOrder("1", btcBrl(), order.ID)
+	assertNoError(t, err)
+
+	stored, err = store.UserOrders("1")
+	assertNoError(t, err)
+	assertEqual(t, 2, len(stored), "Queue flushed")
+	assertEqual(t, bid.ID, stored[0].Order.ID, "Oldest first")
+	assertEqual(t, order.ID, stored[1].Order.ID, "Then the cancel")
+	history, total, err := e.OrderHistory("1", HistoryFilter{})
+	assertNoError(t, err)
+	assertEqual(t, 2, total, "Each order once in the history")
+	assertEqual(t, order.ID, history[0].Order.ID, "Newest first")
+}
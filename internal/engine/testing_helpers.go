@@ -51,7 +51,11 @@ func btcBrl() Pair {
 }
 
 func setupEngine() *Engine {
-	e := NewEngine()
+	return setupEngineWithConfig(DefaultConfig())
+}
+
+func setupEngineWithConfig(cfg Config) *Engine {
+	e := NewEngineWithConfig(cfg)
 	// Give users some balance
 	_ = e.accounts.Credit("1", "BRL", 100_000)
 	_ = e.accounts.Credit("1", "BTC", 10)
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+// Liquidity tells whether an order adds resting liquidity or takes it.
+type Liquidity string
+
+const (
+	LiquidityMaker Liquidity = "maker" // rests in the book without crossing the spread
+	LiquidityTaker Liquidity = "taker" // crosses the spread and matches on arrival
+)
+
+// TakerThrottle holds back orders that take liquidity so makers get ahead of
+// them. The zero value disables it.
+type TakerThrottle struct {
+	// Delay is waited, on the engine clock, before a taker order is
+	// matched.
+	Delay time.Duration
+
+	// Limit caps the taker orders a user may place per Window. Only orders
+	// the engine accepts count. 0 disables the cap.
+	Limit  int
+	Window time.Duration
+}
+
+// Enabled reports whether the throttle delays or caps anything.
+func (t TakerThrottle) Enabled() bool {
+	return t.Delay > 0 || (t.Limit > 0 && t.Window > 0)
+}
+
+// takerLog tracks recent taker orders per user for TakerThrottle.Limit. It
+// is guarded by e.mu.
+type takerLog struct {
+	recent map[string][]time.Time // user ID -> taker orders inside the window, oldest first
+}
+
+// ClassifyOrder reports whether a limit order at price would cross the
+// spread of pair as it stands. A price of 0 stands for a market order, which
+// always takes.
+func (e *Engine) ClassifyOrder(pair Pair, side orderbook.Side, price float64) Liquidity {
+	if price == 0 {
+		return LiquidityTaker
+	}
+	if normalized, ok := e.normalizeToTick(price, e.pairConfig(pair).PriceTick); ok {
+		price = normalized
+	}
+
+	ob := e.GetOrderbook(pair)
+	if ob == nil {
+		return LiquidityMaker
+	}
+
+	if side == orderbook.Bid {
+		if best, ok := ob.BestAsk(); ok && price >= best.Price(ob.PriceTick()) {
+			return LiquidityTaker
+		}
+		return LiquidityMaker
+	}
+	if best, ok := ob.BestBid(); ok && price <= best.Price(ob.PriceTick()) {
+		return LiquidityTaker
+	}
+	return LiquidityMaker
+}
+
+// delayTaker waits out Config.TakerThrottle.Delay on the engine clock when
+// an order of side at price would take on pair; a price of 0 stands for a
+// market order. It runs once the order passed validation and must not be
+// called with e.mu held.
+func (e *Engine) delayTaker(pair Pair, side orderbook.Side, price float64) {
+	delay := e.config.TakerThrottle.Delay
+	if delay <= 0 || e.ClassifyOrder(pair, side, price) == LiquidityMaker {
+		return
+	}
+	clock.Sleep(e.config.Clock, delay)
+}
+
+// checkTakerLimit refuses a taker order of userID with ErrTakerThrottled
+// once the user has Config.TakerThrottle.Limit accepted takers inside the
+// window. Makers always pass. Must be called with e.mu held.
+func (e *Engine) checkTakerLimit(userID string, taker bool) error {
+	policy := e.config.TakerThrottle
+	if !taker || policy.Limit <= 0 || policy.Window <= 0 {
+		return nil
+	}
+	if e.takers.inWindow(userID, e.config.Clock.Now(), policy.Window) >= policy.Limit {
+		return ErrTakerThrottled
+	}
+	return nil
+}
+
+// countTaker counts an accepted taker order of userID against
+// Config.TakerThrottle.Limit. Must be called with e.mu held.
+func (e *Engine) countTaker(userID string, taker bool) {
+	policy := e.config.TakerThrottle
+	if !taker || policy.Limit <= 0 || policy.Window <= 0 {
+		return
+	}
+	if e.takers.recent == nil {
+		e.takers.recent = make(map[string][]time.Time)
+	}
+	e.takers.recent[userID] = append(e.takers.recent[userID], e.config.Clock.Now())
+}
+
+// inWindow drops userID's taker orders older than window before now and
+// returns how many are left.
+func (l *takerLog) inWindow(userID string, now time.Time, window time.Duration) int {
+	cutoff := now.Add(-window)
+	recent := l.recent[userID]
+	i := 0
+	for i < len(recent) && !recent[i].After(cutoff) {
+		i++
+	}
+	if i > 0 {
+		l.recent[userID] = recent[i:]
+	}
+	return len(recent) - i
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+func TestEngine_ClassifyOrder(t *testing.T) {
+	e := setupEngine()
+
+	// Empty book: nothing to take
+	assertEqual(t, LiquidityMaker, e.ClassifyOrder(btcBrl(), orderbook.Bid, 50_000), "Bid on empty book")
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 49_000, 1)
+	assertNoError(t, err)
+
+	assertEqual(t, LiquidityTaker, e.ClassifyOrder(btcBrl(), orderbook.Bid, 50_000), "Bid at best ask")
+	assertEqual(t, LiquidityTaker, e.ClassifyOrder(btcBrl(), orderbook.Bid, 51_000), "Bid through best ask")
+	assertEqual(t, LiquidityMaker, e.ClassifyOrder(btcBrl(), orderbook.Bid, 49_999.99), "Bid inside the spread")
+	assertEqual(t, LiquidityTaker, e.ClassifyOrder(btcBrl(), orderbook.Ask, 49_000), "Ask at best bid")
+	assertEqual(t, LiquidityMaker, e.ClassifyOrder(btcBrl(), orderbook.Ask, 49_500), "Ask inside the spread")
+	assertEqual(t, LiquidityTaker, e.ClassifyOrder(btcBrl(), orderbook.Ask, 0), "Market order")
+}
+
+func TestEngine_TakerThrottle_LimitsTakersOnly(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+	cfg := DefaultConfig()
+	cfg.Clock = clk
+	cfg.TakerThrottle = TakerThrottle{Limit: 2, Window: time.Minute}
+	e := setupEngineWithConfig(cfg)
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+
+	// Two takers fit in the window, the third is refused
+	_, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "First taker matched")
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertEqual(t, ErrTakerThrottled, err, "Third taker in the window")
+
+	// Makers are never throttled, and other users have their own budget
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 0.1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("3", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertTrue(t, err != ErrTakerThrottled, "Another user's taker")
+
+	// Once the window has passed the user may take again
+	clk.Advance(time.Minute)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertNoError(t, err)
+}
+
+func TestEngine_TakerThrottle_RejectedOrdersDoNotCount(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+	cfg := DefaultConfig()
+	cfg.Clock = clk
+	cfg.TakerThrottle = TakerThrottle{Limit: 1, Window: time.Minute}
+	e := setupEngineWithConfig(cfg)
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+
+	// Takers refused by validation or for funds leave the budget alone
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0)
+	assertEqual(t, orderbook.ErrInvalidAmount, err, "Invalid amount")
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 5)
+	assertTrue(t, err != nil && err != ErrTakerThrottled, "Insufficient funds")
+
+	_, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "First accepted taker")
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.1)
+	assertEqual(t, ErrTakerThrottled, err, "Budget spent by the accepted taker")
+}
+
+func TestEngine_TakerThrottle_DelayOnEngineClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start, 0)
+	cfg := DefaultConfig()
+	cfg.Clock = clk
+	cfg.TakerThrottle = TakerThrottle{Delay: 5 * time.Second}
+	e := setupEngineWithConfig(cfg)
+
+	// Makers are not held back
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+	assertTrue(t, clk.Now().Equal(start), "No delay for a maker")
+
+	// Takers wait the delay on the engine clock, then match
+	_, matches, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Limit taker matched")
+	assertTrue(t, clk.Now().Equal(start.Add(5*time.Second)), "Limit taker delayed")
+	assertTrue(t, matches[0].Timestamp.Equal(start.Add(5*time.Second)), "Matched after the delay")
+
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.1)
+	assertNoError(t, err)
+	assertTrue(t, clk.Now().Equal(start.Add(10*time.Second)), "Market order delayed")
+
+	// An order refused by validation is not delayed
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0)
+	assertEqual(t, orderbook.ErrInvalidAmount, err, "Invalid amount")
+	assertTrue(t, clk.Now().Equal(start.Add(10*time.Second)), "No delay for a rejected order")
+}
+
+func TestEngine_TakerThrottle_OffByDefault(t *testing.T) {
+	e := setupEngine()
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.1)
+		assertNoError(t, err)
+	}
+}
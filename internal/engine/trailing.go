@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"math"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+type TrailingState string
+
+const (
+	TrailingActive    TrailingState = "active"
+	TrailingTriggered TrailingState = "triggered" // the market order was placed
+	TrailingFailed    TrailingState = "failed"    // triggered, but the market order was rejected
+	TrailingCancelled TrailingState = "cancelled" // dropped before it fired, e.g. when its pair was delisted
+)
+
+// TrailingStop follows the market by TrailOffset and fires a market order of
+// Side once the price retraces that far from its extreme. A trailing sell
+// (Side Ask) tracks the highest trade price, a trailing buy (Side Bid) the
+// lowest.
+//
+// Funds are not reserved while the stop is active; the market order is
+// checked against the user's balance when it fires.
+type TrailingStop struct {
+	ID          int64
+	UserID      string
+	Pair        Pair
+	Side        orderbook.Side
+	TrailOffset float64
+	Amount      float64
+	Watermark   float64 // best trade price seen since placement; 0 until the first trade
+	State       TrailingState
+	CreatedAt   time.Time
+
+	TriggerPrice float64 // trade price that fired the stop
+	TriggeredAt  time.Time
+	OrderID      int64  // market order placed on trigger
+	Err          string // why the market order was rejected, if it was
+}
+
+// StopPrice returns the trade price at which the stop fires, or 0 while no
+// trade has set the watermark.
+func (s *TrailingStop) StopPrice() float64 {
+	if s.Watermark == 0 {
+		return 0
+	}
+	if s.Side == orderbook.Ask {
+		return s.Watermark - s.TrailOffset
+	}
+	return s.Watermark + s.TrailOffset
+}
+
+// PlaceTrailingStop registers a trailing stop on pair. The watermark starts
+// at the pair's last trade price, if there is one.
+func (e *Engine) PlaceTrailingStop(userID string, pair Pair, side orderbook.Side, trailOffset, amount float64) (*TrailingStop, error) {
+	if userID == "" {
+		return nil, account.ErrInvalidUserID
+	}
+	if e.halted.Load() {
+		return nil, ErrEngineHalted
+	}
+	if !pair.IsValid() {
+		return nil, ErrInvalidPair
+	}
+	if side != orderbook.Bid && side != orderbook.Ask {
+		return nil, orderbook.ErrInvalidSide
+	}
+	if math.IsNaN(trailOffset) || math.IsInf(trailOffset, 0) || trailOffset <= 0 {
+		return nil, ErrInvalidTrailOffset
+	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return nil, orderbook.ErrNonFinite
+	}
+	if amount <= 0 {
+		return nil, orderbook.ErrInvalidAmount
+	}
+
+	cfg, err := e.listedPairConfig(pair)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Halted {
+		return nil, ErrPairHalted
+	}
+
+	amount, ok := e.normalizeToTick(amount, cfg.AmountTick)
+	if !ok {
+		return nil, ErrInvalidAmountTick
+	}
+	if !cfg.lotAligned(amount) {
+		return nil, ErrInvalidAmountLot
+	}
+	if amount < cfg.MinOrderSize {
+		return nil, ErrBelowMinOrderSize
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.trailingSeq++
+	stop := &TrailingStop{
+		ID:          e.trailingSeq,
+		UserID:      userID,
+		Pair:        pair,
+		Side:        side,
+		TrailOffset: trailOffset,
+		Amount:      amount,
+		Watermark:   e.lastTradePrice(pair),
+		State:       TrailingActive,
+		CreatedAt:   e.config.Clock.Now(),
+	}
+
+	key := pair.String()
+	e.trailingStops[key] = append(e.trailingStops[key], stop)
+	e.allStops = append(e.allStops, stop)
+
+	result := *stop
+	return &result, nil
+}
+
+// TrailingStops returns a copy of every trailing stop userID has placed,
+// active or not, in placement order.
+func (e *Engine) TrailingStops(userID string) []TrailingStop {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var result []TrailingStop
+	for _, stop := range e.allStops {
+		if stop.UserID == userID {
+			result = append(result, *stop)
+		}
+	}
+	return result
+}
+
+// trackTrailingStops moves the watermarks of pair's active stops to price
+// and queues the ones it crosses. Must be called with e.mu held.
+func (e *Engine) trackTrailingStops(pair Pair, price float64) {
+	key := pair.String()
+	stops := e.trailingStops[key]
+	if len(stops) == 0 {
+		return
+	}
+
+	active := stops[:0]
+	for _, stop := range stops {
+		if stop.crossed(price) {
+			stop.TriggerPrice = price
+			stop.TriggeredAt = e.config.Clock.Now()
+			e.triggered = append(e.triggered, stop)
+			continue
+		}
+		active = append(active, stop)
+	}
+	e.trailingStops[key] = active
+}
+
+// crossed updates the watermark with price and reports whether price has
+// retraced TrailOffset from it.
+func (s *TrailingStop) crossed(price float64) bool {
+	if s.Side == orderbook.Ask {
+		if price > s.Watermark {
+			s.Watermark = price
+			return false
+		}
+		return price <= s.Watermark-s.TrailOffset
+	}
+
+	if s.Watermark == 0 || price < s.Watermark {
+		s.Watermark = price
+		return false
+	}
+	return price >= s.Watermark+s.TrailOffset
+}
+
+// runTriggeredStops places the market orders of the stops queued by
+// trackTrailingStops. It runs after e.mu is released; the trades these
+// orders make can queue further stops, which are drained in the same loop.
+// A stop whose pair is in PairModeAuction, where market orders are refused,
+// is held until SetPairMode takes the pair out of it.
+func (e *Engine) runTriggeredStops() {
+	for {
+		e.mu.Lock()
+		if len(e.triggered) == 0 {
+			e.mu.Unlock()
+			return
+		}
+		stop := e.triggered[0]
+		e.triggered = e.triggered[1:]
+		if cfg, ok := e.pairs[stop.Pair.String()]; ok && cfg.Mode == PairModeAuction {
+			e.heldStops[stop.Pair.String()] = append(e.heldStops[stop.Pair.String()], stop)
+			e.mu.Unlock()
+			continue
+		}
+		e.mu.Unlock()
+
+		order, _, err := e.placeMarketOrder(stop.UserID, stop.Pair, stop.Side, stop.Amount, OrderOptions{})
+
+		e.mu.Lock()
+		if err != nil {
+			stop.State = TrailingFailed
+			stop.Err = err.Error()
+		} else {
+			stop.State = TrailingTriggered
+			stop.OrderID = order.ID
+		}
+		e.mu.Unlock()
+	}
+}
+
+// lastTradePrice returns the price of pair's most recent trade, self-trades
+// aside, or 0. Must be called with e.mu held.
+func (e *Engine) lastTradePrice(pair Pair) float64 {
+	for i := len(e.trades) - 1; i >= 0; i-- {
+		if e.trades[i].Pair == pair && !e.trades[i].SelfTrade {
+			return e.trades[i].Price
+		}
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+// trade executes a 0.1 BTC trade at price between users 2 (maker) and 1 (taker).
+func trade(t *testing.T, e *Engine, price float64) {
+	t.Helper()
+	pair := Pair{Base: "BTC", Quote: "BRL"}
+
+	_, _, err := e.PlaceOrder("2", pair, orderbook.Ask, price, 0.1)
+	assertNoError(t, err)
+	_, matches, err := e.PlaceOrder("1", pair, orderbook.Bid, price, 0.1)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(matches), "Trade at price")
+}
+
+func TestEngine_TrailingStop_Sell(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "BTC", Quote: "BRL"}
+	_ = e.accounts.Credit("3", "BTC", 1)
+
+	// Liquidity for the stop's market sell, below the traded range
+	_, _, err := e.PlaceOrder("1", pair, orderbook.Bid, 40_000, 1)
+	assertNoError(t, err)
+
+	trade(t, e, 50_000)
+
+	stop, err := e.PlaceTrailingStop("3", pair, orderbook.Ask, 1_000, 0.5)
+	assertNoError(t, err)
+	assertFloat(t, 50_000, stop.Watermark, "Watermark starts at last trade")
+	assertFloat(t, 49_000, stop.StopPrice(), "Initial stop price")
+
+	// Ratchet up, then retrace less than the offset
+	for _, price := range []float64{51_000, 52_000, 51_500} {
+		trade(t, e, price)
+		assertEqual(t, TrailingActive, e.TrailingStops("3")[0].State, "Still active")
+	}
+	assertFloat(t, 52_000, e.TrailingStops("3")[0].Watermark, "Watermark follows the high")
+	assertFloat(t, 51_000, e.TrailingStops("3")[0].StopPrice(), "Stop price trails the high")
+
+	// Retrace by the full offset
+	trade(t, e, 51_000)
+
+	fired := e.TrailingStops("3")[0]
+	assertEqual(t, TrailingTriggered, fired.State, "Triggered")
+	assertFloat(t, 51_000, fired.TriggerPrice, "Trigger price")
+	assertTrue(t, fired.OrderID > 0, "Market order placed")
+
+	btc := e.accounts.GetBalance("3", "BTC")
+	assertFloat(t, 0.5, btc.Available, "BTC left after the stop sold")
+	assertFloat(t, 20_000, e.accounts.GetBalance("3", "BRL").Available, "Proceeds at 40000")
+}
+
+func TestEngine_TrailingStop_Buy(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "BTC", Quote: "BRL"}
+	_ = e.accounts.Credit("3", "BRL", 100_000)
+
+	// Liquidity for the stop's market buy, above the traded range
+	_, _, err := e.PlaceOrder("2", pair, orderbook.Ask, 60_000, 1)
+	assertNoError(t, err)
+
+	stop, err := e.PlaceTrailingStop("3", pair, orderbook.Bid, 1_000, 0.5)
+	assertNoError(t, err)
+	assertFloat(t, 0, stop.Watermark, "No trade yet")
+
+	for _, price := range []float64{50_000, 49_000, 48_000, 48_500} {
+		trade(t, e, price)
+		assertEqual(t, TrailingActive, e.TrailingStops("3")[0].State, "Still active")
+	}
+	assertFloat(t, 48_000, e.TrailingStops("3")[0].Watermark, "Watermark follows the low")
+
+	trade(t, e, 49_000)
+
+	fired := e.TrailingStops("3")[0]
+	assertEqual(t, TrailingTriggered, fired.State, "Triggered")
+	assertFloat(t, 49_000, fired.TriggerPrice, "Trigger price")
+	assertFloat(t, 0.5, e.accounts.GetBalance("3", "BTC").Available, "BTC bought by the stop")
+	assertFloat(t, 70_000, e.accounts.GetBalance("3", "BRL").Available, "Paid at 60000")
+}
+
+func TestEngine_TrailingStop_FailsWithoutFunds(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "BTC", Quote: "BRL"}
+
+	_, _, err := e.PlaceOrder("1", pair, orderbook.Bid, 40_000, 1)
+	assertNoError(t, err)
+
+	_, err = e.PlaceTrailingStop("3", pair, orderbook.Ask, 1_000, 0.5)
+	assertNoError(t, err)
+
+	trade(t, e, 50_000)
+	trade(t, e, 49_000)
+
+	fired := e.TrailingStops("3")[0]
+	assertEqual(t, TrailingFailed, fired.State, "Market order rejected")
+	assertTrue(t, fired.Err != "", "Rejection reason recorded")
+}
+
+func TestEngine_TrailingStop_Validation(t *testing.T) {
+	e := setupEngine()
+	pair := Pair{Base: "BTC", Quote: "BRL"}
+
+	_, err := e.PlaceTrailingStop("1", pair, orderbook.Ask, 0, 1)
+	assertEqual(t, ErrInvalidTrailOffset, err, "Zero offset")
+
+	_, err = e.PlaceTrailingStop("1", pair, orderbook.Ask, 100, 0)
+	assertEqual(t, orderbook.ErrInvalidAmount, err, "Zero amount")
+
+	_, err = e.PlaceTrailingStop("1", Pair{Base: "BTC", Quote: "USD"}, orderbook.Ask, 100, 1)
+	assertEqual(t, ErrInvalidPair, err, "Invalid pair")
+}
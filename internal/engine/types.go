@@ -1,5 +1,10 @@
 package engine
 
+import (
+	"strings"
+	"time"
+)
+
 const (
 	PriceTick  = 0.01
 	AmountTick = 0.00000001
@@ -14,6 +19,56 @@ func (p Pair) String() string {
 	return p.Base + "/" + p.Quote
 }
 
+// IsValid reports whether p is quoted in BRL and names two different,
+// non-blank assets without surrounding whitespace.
 func (p Pair) IsValid() bool {
-	return p.Base != "" && p.Quote != "" && p.Quote == "BRL"
+	base := strings.TrimSpace(p.Base)
+	return base != "" && base == p.Base && p.Quote == "BRL" && !p.SameAsset()
+}
+
+// SameAsset reports whether base and quote name the same asset, ignoring
+// case and surrounding whitespace.
+func (p Pair) SameAsset() bool {
+	return strings.EqualFold(strings.TrimSpace(p.Base), strings.TrimSpace(p.Quote))
+}
+
+// OrderOptions carries optional per-order flags.
+type OrderOptions struct {
+	Hidden bool // limit only: rest without showing in public depth
+
+	// RestRemainder (market only) turns the part of a market order the book
+	// could not fill into a resting limit at the last execution price,
+	// instead of cancelling it.
+	RestRemainder bool
+
+	// Nonce, when non-zero, must be above the last nonce seen for the user;
+	// see Engine.CheckNonce.
+	Nonce uint64
+
+	// ExpiresAt (limit only) makes the order good-till-date: once it passes,
+	// whatever still rests is removed and archived as expired. Zero means
+	// good-till-cancelled.
+	ExpiresAt time.Time
+
+	// Tag is an opaque client string stored on the order and echoed back on
+	// it, its fills and its history. At most MaxTagLength bytes.
+	Tag string
+
+	// AllowSelfTrade lets the order match the same user's resting orders
+	// instead of applying Config.STPMode. Such matches pay no fees.
+	AllowSelfTrade bool
+
+	// OrderID, when non-zero, is an ID the user got from ReserveOrderID,
+	// given to the order instead of a fresh one. Each works once.
+	OrderID int64
+
+	// ImproveOnly (limit only) rejects the order with ErrWouldNotImprove
+	// unless it would rest as the new best visible price on its side: its
+	// price must be strictly better than every visible order there, hidden
+	// ones aside, and it must not cross the other side, so it never takes.
+	// Any price that does not cross improves an empty side.
+	ImproveOnly bool
 }
+
+// MaxTagLength bounds OrderOptions.Tag, in bytes.
+const MaxTagLength = 64
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+// MaxOrderWait bounds how long WaitForOrder may wait.
+const MaxOrderWait = 30 * time.Second
+
+// WaitForOrder waits until userID's order leaves the book, filled or closed,
+// timeout passes or ctx is done, and returns its final state. An order still
+// resting when the wait ends early is cancelled, so the result is always
+// terminal, as if the order had been immediate-or-cancel with a grace
+// period. The timeout runs on Config.Clock; under Config.Deterministic that
+// clock only moves with operations, so ctx is what bounds the wait in real
+// time. The engine lock is not held while waiting.
+func (e *Engine) WaitForOrder(ctx context.Context, userID string, pair Pair, orderID int64, timeout time.Duration) (ArchivedOrder, error) {
+	if !pair.IsValid() {
+		return ArchivedOrder{}, ErrInvalidPair
+	}
+	if timeout > MaxOrderWait {
+		timeout = MaxOrderWait
+	}
+
+	done, err := e.addWaiter(userID, pair, orderID)
+	if err != nil {
+		return ArchivedOrder{}, err
+	}
+	if done == nil {
+		// Already terminal
+		return e.GetOrder(userID, pair, orderID)
+	}
+
+	select {
+	case <-done:
+		return e.GetOrder(userID, pair, orderID)
+	case <-clock.After(e.config.Clock, timeout):
+	case <-ctx.Done():
+	}
+
+	e.removeWaiter(orderID, done)
+	cancelled, err := e.CancelOrder(userID, pair, orderID)
+	if errors.Is(err, ErrOrderNotFound) {
+		// Filled between the end of the wait and the cancel
+		return e.GetOrder(userID, pair, orderID)
+	}
+	if err != nil {
+		return ArchivedOrder{}, err
+	}
+	return *cancelled, nil
+}
+
+// addWaiter registers a channel closed once the order leaves the book, or
+// returns nil if it is not resting.
+func (e *Engine) addWaiter(userID string, pair Pair, orderID int64) (chan struct{}, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ob, exists := e.orderbooks[pair.String()]
+	if !exists {
+		return nil, nil
+	}
+	order, exists := ob.GetOrder(orderID)
+	if !exists {
+		return nil, nil
+	}
+	if order.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	if e.waiters == nil {
+		e.waiters = make(map[int64][]chan struct{})
+	}
+	done := make(chan struct{})
+	e.waiters[orderID] = append(e.waiters[orderID], done)
+	return done, nil
+}
+
+func (e *Engine) removeWaiter(orderID int64, done chan struct{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	waiting := e.waiters[orderID]
+	for i, ch := range waiting {
+		if ch == done {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(waiting) == 0 {
+		delete(e.waiters, orderID)
+	} else {
+		e.waiters[orderID] = waiting
+	}
+}
+
+// releaseWaiters wakes every WaitForOrder call on orderID. Must be called
+// with e.mu held.
+func (e *Engine) releaseWaiters(orderID int64) {
+	for _, done := range e.waiters[orderID] {
+		close(done)
+	}
+	delete(e.waiters, orderID)
+}
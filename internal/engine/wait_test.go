@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+func setupWaitEngine() (*Engine, *clock.Fake) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+
+	cfg := DefaultConfig()
+	cfg.Clock = clk
+	return setupEngineWithConfig(cfg), clk
+}
+
+// awaitWaiter blocks until a WaitForOrder call waits on orderID, giving up
+// after a few seconds.
+func awaitWaiter(e *Engine, orderID int64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		e.mu.RLock()
+		waiting := len(e.waiters[orderID]) > 0
+		e.mu.RUnlock()
+		if waiting {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEngine_WaitForOrder_ImmediateFill(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+
+	final, err := e.WaitForOrder(context.Background(), "1", btcBrl(), order.ID, time.Second)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderFilled, final.Order.State, "Filled on placement")
+}
+
+func TestEngine_WaitForOrder_FilledWhileWaiting(t *testing.T) {
+	e, _ := setupWaitEngine()
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+
+	go func() {
+		awaitWaiter(e, order.ID)
+		_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1)
+	}()
+
+	// The engine clock does not move, so only the fill ends the wait
+	final, err := e.WaitForOrder(context.Background(), "1", btcBrl(), order.ID, time.Second)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderFilled, final.Order.State, "Filled by a later taker")
+}
+
+func TestEngine_WaitForOrder_TimeoutCancels(t *testing.T) {
+	e, clk := setupWaitEngine()
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.4)
+	assertNoError(t, err)
+
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderPartiallyFilled, order.State, "Rests partially filled")
+
+	// Move the engine clock until the wait times out on it, however short
+	// that is in wall time
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				clk.Advance(time.Minute)
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+	final, err := e.WaitForOrder(context.Background(), "1", btcBrl(), order.ID, time.Minute)
+	close(stop)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderCancelled, final.Order.State, "Cancelled at the timeout")
+	assertFloat(t, 0.4, final.Order.FilledAmount, "Fills kept")
+	assertFloat(t, 30_000, final.Unlocked, "Remaining quote unlocked")
+
+	_, ok := e.GetOrderbook(btcBrl()).GetOrder(order.ID)
+	assertTrue(t, !ok, "Off the book")
+	assertEqual(t, 0, len(e.waiters), "No waiter left behind")
+}
+
+func TestEngine_WaitForOrder_OtherUser(t *testing.T) {
+	e := setupEngine()
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+
+	_, err = e.WaitForOrder(context.Background(), "2", btcBrl(), order.ID, time.Millisecond)
+	assertEqual(t, ErrUnauthorized, err, "Not the owner")
+}
+
+func TestEngine_WaitForOrder_ContextDoneCancels(t *testing.T) {
+	e := setupEngine()
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	final, err := e.WaitForOrder(ctx, "1", btcBrl(), order.ID, MaxOrderWait)
+	assertNoError(t, err)
+	assertEqual(t, orderbook.OrderCancelled, final.Order.State, "Cancelled once the caller is gone")
+	assertEqual(t, 0, len(e.waiters), "No waiter left behind")
+}
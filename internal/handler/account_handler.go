@@ -1,7 +1,7 @@
 package handler
 
 import (
-	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -29,50 +29,133 @@ func NewAccountHandler(manager *account.Manager) *AccountHandler {
 // @Param request body v1.CreditDebitRequest true "Credit details (includes user_id)"
 // @Success 200 {object} v1.BalanceResponse "Credit successful"
 // @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 409 {object} v1.ErrorResponse "Deposit ID already used for a different credit"
 // @Router /api/v1/accounts/credit [post]
 func (h *AccountHandler) Credit(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
 	var req v1.CreditDebitRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
 		logger.Warningf("Credit - invalid JSON - Duration: %v - Error: %v", time.Since(start), err)
 		return
 	}
 
 	// Validate
 	if req.UserID == "" {
-		h.sendError(w, "user_id is required", http.StatusBadRequest)
+		writeError(w, "user_id is required", http.StatusBadRequest)
 		logger.Warningf("Credit - missing user_id - Duration: %v", time.Since(start))
 		return
 	}
 	if req.Asset == "" {
-		h.sendError(w, "asset is required", http.StatusBadRequest)
+		writeError(w, "asset is required", http.StatusBadRequest)
 		logger.Warningf("Credit - missing asset - Duration: %v", time.Since(start))
 		return
 	}
+	if !req.Amount.IsFinite() {
+		writeError(w, "amount must be a finite number", http.StatusBadRequest)
+		logger.Warningf("Credit - non-finite amount - Duration: %v", time.Since(start))
+		return
+	}
 	if req.Amount <= 0 {
-		h.sendError(w, "amount must be greater than 0", http.StatusBadRequest)
+		writeError(w, "amount must be greater than 0", http.StatusBadRequest)
 		logger.Warningf("Credit - invalid amount - Duration: %v", time.Since(start))
 		return
 	}
 
 	// Credit
-	if err := h.manager.Credit(req.UserID, req.Asset, req.Amount); err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
-		logger.Warningf("Credit failed - User: %s - Asset: %s - Amount: %.8f - Duration: %v - Error: %v",
-			req.UserID, req.Asset, req.Amount, time.Since(start), err)
+	deposit, replayed, err := h.manager.CreditDeposit(req.UserID, req.DepositID, req.Asset, req.Amount.Float64())
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Credit failed - User: %s - Asset: %s - Amount: %.8f - DepositID: %q - Duration: %v - Error: %v",
+			req.UserID, req.Asset, req.Amount, req.DepositID, time.Since(start), err)
+		return
+	}
+	if replayed {
+		response := v1.BalanceResponse{UserID: req.UserID, Balances: make([]v1.BalanceItem, 0, len(deposit.Balances)), Replayed: true}
+		for asset, balance := range deposit.Balances {
+			response.Balances = append(response.Balances, balanceItem(asset, balance))
+		}
+		writeJSON(w, response, http.StatusOK)
+
+		logger.Infof("Credit replayed - User: %s - DepositID: %q - Status: 200 - Duration: %v",
+			req.UserID, req.DepositID, time.Since(start))
 		return
 	}
 
 	// Get updated balance
 	response := h.getBalanceResponse(req.UserID)
-	h.sendJSON(w, response, http.StatusOK)
+	writeJSON(w, response, http.StatusOK)
 
 	logger.Infof("Credit success - User: %s - Asset: %s - Amount: %.8f - Status: 200 - Duration: %v",
 		req.UserID, req.Asset, req.Amount, time.Since(start))
 }
 
+// maxBatchCredit bounds the number of entries in one batch credit request.
+const maxBatchCredit = 1000
+
+// CreditBatch godoc
+// @Summary Credit many accounts at once
+// @Description Apply a list of credits independently. Invalid entries fail on their own without affecting the others
+// @Tags Accounts
+// @Accept json
+// @Produce json
+// @Param request body []v1.CreditDebitRequest true "Credits to apply"
+// @Success 200 {object} v1.BatchCreditResponse "Per-entry results"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Router /api/v1/accounts/credit/batch [post]
+func (h *AccountHandler) CreditBatch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req []v1.CreditDebitRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		logger.Warningf("Credit batch - invalid JSON - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+	if len(req) == 0 {
+		writeError(w, "at least one credit is required", http.StatusBadRequest)
+		logger.Warningf("Credit batch - empty - Duration: %v", time.Since(start))
+		return
+	}
+	if len(req) > maxBatchCredit {
+		writeError(w, fmt.Sprintf("at most %d credits per batch", maxBatchCredit), http.StatusBadRequest)
+		logger.Warningf("Credit batch - too large: %d - Duration: %v", len(req), time.Since(start))
+		return
+	}
+
+	entries := make([]account.CreditEntry, len(req))
+	for i, item := range req {
+		entries[i] = account.CreditEntry{UserID: item.UserID, Asset: item.Asset, Amount: item.Amount.Float64(), DepositID: item.DepositID}
+	}
+
+	errs := h.manager.CreditBatch(entries)
+
+	response := v1.BatchCreditResponse{Results: make([]v1.BatchCreditResult, len(req))}
+	for i, item := range req {
+		result := v1.BatchCreditResult{
+			Index:   i,
+			UserID:  item.UserID,
+			Asset:   item.Asset,
+			Amount:  item.Amount,
+			Success: errs[i] == nil,
+		}
+		if errs[i] != nil {
+			result.Error = errs[i].Error()
+			result.Code, _ = errorCode(errs[i])
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+		response.Results[i] = result
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Credit batch - Entries: %d - Succeeded: %d - Failed: %d - Status: 200 - Duration: %v",
+		len(req), response.Succeeded, response.Failed, time.Since(start))
+}
+
 // Debit godoc
 // @Summary Debit asset from account
 // @Description Remove balance from a user's account
@@ -87,32 +170,42 @@ func (h *AccountHandler) Debit(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
 	var req v1.CreditDebitRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
 		logger.Warningf("Debit - invalid JSON - Duration: %v - Error: %v", time.Since(start), err)
 		return
 	}
 
 	// Validate
 	if req.UserID == "" {
-		h.sendError(w, "user_id is required", http.StatusBadRequest)
+		writeError(w, "user_id is required", http.StatusBadRequest)
 		logger.Warningf("Debit - missing user_id - Duration: %v", time.Since(start))
 		return
 	}
 	if req.Asset == "" {
-		h.sendError(w, "asset is required", http.StatusBadRequest)
+		writeError(w, "asset is required", http.StatusBadRequest)
 		logger.Warningf("Debit - missing asset - Duration: %v", time.Since(start))
 		return
 	}
+	if !req.Amount.IsFinite() {
+		writeError(w, "amount must be a finite number", http.StatusBadRequest)
+		logger.Warningf("Debit - non-finite amount - Duration: %v", time.Since(start))
+		return
+	}
 	if req.Amount <= 0 {
-		h.sendError(w, "amount must be greater than 0", http.StatusBadRequest)
+		writeError(w, "amount must be greater than 0", http.StatusBadRequest)
 		logger.Warningf("Debit - invalid amount - Duration: %v", time.Since(start))
 		return
 	}
+	if req.DepositID != "" {
+		writeError(w, "deposit_id is only valid for credits", http.StatusBadRequest)
+		logger.Warningf("Debit - deposit_id given - Duration: %v", time.Since(start))
+		return
+	}
 
 	// Debit
-	if err := h.manager.Debit(req.UserID, req.Asset, req.Amount); err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+	if err := h.manager.Debit(req.UserID, req.Asset, req.Amount.Float64()); err != nil {
+		writeDomainError(w, err)
 		logger.Warningf("Debit failed - User: %s - Asset: %s - Amount: %.8f - Duration: %v - Error: %v",
 			req.UserID, req.Asset, req.Amount, time.Since(start), err)
 		return
@@ -120,7 +213,7 @@ func (h *AccountHandler) Debit(w http.ResponseWriter, r *http.Request) {
 
 	// Get updated balance
 	response := h.getBalanceResponse(req.UserID)
-	h.sendJSON(w, response, http.StatusOK)
+	writeJSON(w, response, http.StatusOK)
 
 	logger.Infof("Debit success - User: %s - Asset: %s - Amount: %.8f - Status: 200 - Duration: %v",
 		req.UserID, req.Asset, req.Amount, time.Since(start))
@@ -128,10 +221,11 @@ func (h *AccountHandler) Debit(w http.ResponseWriter, r *http.Request) {
 
 // GetBalance godoc
 // @Summary Get account balance
-// @Description Get all balances for a user's account
+// @Description Get all balances for a user's account, or only the given asset's. An asset the user never held is returned zeroed
 // @Tags Accounts
 // @Produce json
 // @Param user_id query string true "User ID"
+// @Param asset query string false "Only return this asset (e.g., BTC)"
 // @Success 200 {object} v1.BalanceResponse "Balance retrieved successfully"
 // @Failure 400 {object} v1.ErrorResponse "Invalid request"
 // @Router /api/v1/accounts/balance [get]
@@ -140,13 +234,22 @@ func (h *AccountHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
-		h.sendError(w, "user_id query parameter is required", http.StatusBadRequest)
+		writeError(w, "user_id query parameter is required", http.StatusBadRequest)
 		logger.Warningf("Get balance - missing user_id - Duration: %v", time.Since(start))
 		return
 	}
 
+	if asset := r.URL.Query().Get("asset"); asset != "" {
+		response := h.getAssetBalanceResponse(userID, asset)
+		writeJSON(w, response, http.StatusOK)
+
+		logger.Infof("Get balance success - User: %s - Asset: %s - Status: 200 - Duration: %v",
+			userID, asset, time.Since(start))
+		return
+	}
+
 	response := h.getBalanceResponse(userID)
-	h.sendJSON(w, response, http.StatusOK)
+	writeJSON(w, response, http.StatusOK)
 
 	logger.Infof("Get balance success - User: %s - Assets: %d - Status: 200 - Duration: %v",
 		userID, len(response.Balances), time.Since(start))
@@ -155,33 +258,45 @@ func (h *AccountHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 // Helper methods
 
 func (h *AccountHandler) getBalanceResponse(userID string) v1.BalanceResponse {
-	balances := h.manager.GetAllBalances(userID)
+	return v1.BalanceResponse{
+		UserID:   userID,
+		Balances: balanceItems(h.manager.GetAllBalances(userID)),
+	}
+}
 
+// balanceItems lists balances, keyed by asset, as response items.
+func balanceItems(balances map[string]*account.Balance) []v1.BalanceItem {
 	items := make([]v1.BalanceItem, 0, len(balances))
 	for asset, balance := range balances {
-		items = append(items, v1.BalanceItem{
-			Asset:     asset,
-			Available: balance.Available,
-			Locked:    balance.Locked,
-			Total:     balance.Total(),
-		})
+		items = append(items, balanceItem(asset, *balance))
 	}
+	return items
+}
 
-	return v1.BalanceResponse{
-		UserID:   userID,
-		Balances: items,
+func balanceItem(asset string, balance account.Balance) v1.BalanceItem {
+	return v1.BalanceItem{
+		Asset:     asset,
+		Available: v1.AssetAmount(asset, balance.Available),
+		Locked:    v1.AssetAmount(asset, balance.Locked),
+		Total:     v1.AssetAmount(asset, balance.Total()),
 	}
 }
 
-func (h *AccountHandler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		logger.Errorf("Error encoding JSON response: %v", err)
+// getAssetBalanceResponse returns userID's balance of asset as the only item,
+// zeroed when the user holds none.
+func (h *AccountHandler) getAssetBalanceResponse(userID, asset string) v1.BalanceResponse {
+	var balance account.Balance
+	if b := h.manager.GetBalance(userID, asset); b != nil {
+		balance = *b
 	}
-}
 
-func (h *AccountHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
-	h.sendJSON(w, v1.ErrorResponse{Error: message}, statusCode)
+	return v1.BalanceResponse{
+		UserID: userID,
+		Balances: []v1.BalanceItem{{
+			Asset:     asset,
+			Available: v1.AssetAmount(asset, balance.Available),
+			Locked:    v1.AssetAmount(asset, balance.Locked),
+			Total:     v1.AssetAmount(asset, balance.Total()),
+		}},
+	}
 }
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+)
+
+func TestAccountHandler_CreditBatch(t *testing.T) {
+	manager := account.NewManager()
+	h := NewAccountHandler(manager)
+
+	body := json.RawMessage(`[
+		{"user_id": "10", "asset": "BRL", "amount": "5000.00"},
+		{"user_id": "", "asset": "BRL", "amount": "1"},
+		{"user_id": "11", "asset": "BTC", "amount": "0.5"},
+		{"user_id": "10", "asset": "BTC", "amount": "-1"},
+		{"user_id": "10", "asset": "BRL", "amount": 250}
+	]`)
+	rec := doRequest(h.CreditBatch, http.MethodPost, "/api/v1/accounts/credit/batch", body)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.BatchCreditResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 3, resp.Succeeded, "Succeeded")
+	assertEqual(t, 2, resp.Failed, "Failed")
+	assertEqual(t, 5, len(resp.Results), "One result per entry")
+
+	assertTrue(t, resp.Results[0].Success, "Entry 0")
+	assertEqual(t, false, resp.Results[1].Success, "Entry 1")
+	assertEqual(t, CodeInvalidUserID, resp.Results[1].Code, "Entry 1 code")
+	assertTrue(t, resp.Results[2].Success, "Entry 2")
+	assertEqual(t, CodeInvalidAmount, resp.Results[3].Code, "Entry 3 code")
+	assertTrue(t, resp.Results[4].Success, "Entry 4")
+
+	assertFloat(t, 5_250, manager.GetBalance("10", "BRL").Available, "User 10 BRL")
+	assertFloat(t, 0.5, manager.GetBalance("11", "BTC").Available, "User 11 BTC")
+	assertTrue(t, manager.GetBalance("10", "BTC") == nil, "Failed entry left no balance")
+}
+
+func TestAccountHandler_Credit_DepositID(t *testing.T) {
+	manager := account.NewManager()
+	h := NewAccountHandler(manager)
+
+	credit := func(depositID, amount string) *httptest.ResponseRecorder {
+		body := json.RawMessage(`{"user_id":"10","asset":"BRL","amount":"` + amount + `","deposit_id":"` + depositID + `"}`)
+		return doRequest(h.Credit, http.MethodPost, "/api/v1/accounts/credit", body)
+	}
+
+	rec := credit("pix-1", "100")
+	assertEqual(t, http.StatusOK, rec.Code, "First credit")
+	var first v1.BalanceResponse
+	decodeBody(t, rec, &first)
+	assertEqual(t, false, first.Replayed, "Not a replay")
+
+	// A payment system retry
+	rec = credit("pix-1", "100")
+	assertEqual(t, http.StatusOK, rec.Code, "Retry accepted")
+	var retry v1.BalanceResponse
+	decodeBody(t, rec, &retry)
+	assertTrue(t, retry.Replayed, "Replay flagged")
+	assertEqual(t, 1, len(retry.Balances), "Prior balances")
+	assertEqual(t, "100.00", retry.Balances[0].Available.String(), "Balance as first returned")
+	assertFloat(t, 100, manager.GetBalance("10", "BRL").Available, "Credited once")
+
+	rec = credit("pix-1", "250")
+	assertEqual(t, http.StatusConflict, rec.Code, "Same ID, different amount")
+
+	rec = credit("pix-2", "100")
+	assertEqual(t, http.StatusOK, rec.Code, "New deposit")
+	assertFloat(t, 200, manager.GetBalance("10", "BRL").Available, "Credited again")
+
+	rec = doRequest(h.Debit, http.MethodPost, "/api/v1/accounts/debit",
+		json.RawMessage(`{"user_id":"10","asset":"BRL","amount":"1","deposit_id":"pix-3"}`))
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Debits take no deposit ID")
+}
+
+func TestAccountHandler_CreditBatch_Empty(t *testing.T) {
+	h := NewAccountHandler(account.NewManager())
+
+	rec := doRequest(h.CreditBatch, http.MethodPost, "/api/v1/accounts/credit/batch", json.RawMessage(`[]`))
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Status code")
+}
+
+func TestAccountHandler_UnknownField(t *testing.T) {
+	manager := account.NewManager()
+	h := NewAccountHandler(manager)
+
+	rec := doRequest(h.Credit, http.MethodPost, "/api/v1/accounts/credit",
+		json.RawMessage(`{"user_id":"10","asset":"BRL","amount":"100","memo":"x"}`))
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Unknown field rejected")
+	var errResp v1.ErrorResponse
+	decodeBody(t, rec, &errResp)
+	assertEqual(t, `Invalid request body: unknown field "memo"`, errResp.Error, "Message names the field")
+	assertTrue(t, manager.GetBalance("10", "BRL") == nil, "Nothing credited")
+
+	// Batch entries are checked too
+	rec = doRequest(h.CreditBatch, http.MethodPost, "/api/v1/accounts/credit/batch",
+		json.RawMessage(`[{"user_id":"10","asset":"BRL","amout":"1"}]`))
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Unknown field in a batch entry")
+}
+
+func TestAccountHandler_NonFiniteAmount(t *testing.T) {
+	bodies := []string{
+		`{"user_id":"10","asset":"BRL","amount":1e400}`,
+		`{"user_id":"10","asset":"BRL","amount":"Inf"}`,
+		`{"user_id":"10","asset":"BRL","amount":"NaN"}`,
+	}
+
+	for _, body := range bodies {
+		manager := account.NewManager()
+		_ = manager.Credit("10", "BRL", 100)
+		h := NewAccountHandler(manager)
+
+		rec := doRequest(h.Credit, http.MethodPost, "/api/v1/accounts/credit", json.RawMessage(body))
+		assertEqual(t, http.StatusBadRequest, rec.Code, "Credit status for "+body)
+
+		rec = doRequest(h.Debit, http.MethodPost, "/api/v1/accounts/debit", json.RawMessage(body))
+		assertEqual(t, http.StatusBadRequest, rec.Code, "Debit status for "+body)
+
+		assertFloat(t, 100, manager.GetBalance("10", "BRL").Available, "Balance unchanged for "+body)
+	}
+}
+
+func TestAccountHandler_GetBalance_FixedDecimals(t *testing.T) {
+	manager := account.NewManager()
+	_ = manager.Credit("10", "BRL", 50_000)
+	_ = manager.Credit("10", "BTC", 0.00000001)
+	h := NewAccountHandler(manager)
+
+	rec := doRequest(h.GetBalance, http.MethodGet, "/api/v1/accounts/balance?user_id=10", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	raw := rec.Body.String()
+	assertTrue(t, !strings.Contains(raw, "e-"), "No exponent notation in "+raw)
+	assertTrue(t, strings.Contains(raw, `"available":"50000.00"`), "BRL with 2 decimals")
+	assertTrue(t, strings.Contains(raw, `"available":"0.00000001"`), "BTC with 8 decimals")
+	assertTrue(t, strings.Contains(raw, `"locked":"0.00000000"`), "Zero BTC with 8 decimals")
+}
+
+func TestAccountHandler_GetBalance_AssetFilter(t *testing.T) {
+	manager := account.NewManager()
+	_ = manager.Credit("10", "BRL", 50_000)
+	_ = manager.Credit("10", "BTC", 1.5)
+	_ = manager.Lock("10", "BTC", 0.5)
+	h := NewAccountHandler(manager)
+
+	rec := doRequest(h.GetBalance, http.MethodGet, "/api/v1/accounts/balance?user_id=10&asset=BTC", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	var resp v1.BalanceResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 1, len(resp.Balances), "Only the requested asset")
+	assertEqual(t, "BTC", resp.Balances[0].Asset, "Asset")
+	assertFloat(t, 1, resp.Balances[0].Available.Float64(), "Available")
+	assertFloat(t, 0.5, resp.Balances[0].Locked.Float64(), "Locked")
+	assertFloat(t, 1.5, resp.Balances[0].Total.Float64(), "Total")
+
+	rec = doRequest(h.GetBalance, http.MethodGet, "/api/v1/accounts/balance?user_id=10&asset=ETH", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code for an asset never held")
+	resp = v1.BalanceResponse{}
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 1, len(resp.Balances), "Zeroed entry")
+	assertEqual(t, "ETH", resp.Balances[0].Asset, "Asset")
+	assertFloat(t, 0, resp.Balances[0].Total.Float64(), "Zero total")
+
+	rec = doRequest(h.GetBalance, http.MethodGet, "/api/v1/accounts/balance?user_id=10", nil)
+	resp = v1.BalanceResponse{}
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 2, len(resp.Balances), "Every asset without the filter")
+}
@@ -0,0 +1,541 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/pkg/logger"
+)
+
+// AdminTokenHeader carries the operator token on admin requests.
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminHandler serves operator endpoints. Every route must be wrapped with
+// RequireAdmin.
+type AdminHandler struct {
+	engine *engine.Engine
+	orders *OrderHandler
+}
+
+func NewAdminHandler(engine *engine.Engine) *AdminHandler {
+	return &AdminHandler{
+		engine: engine,
+		orders: NewOrderHandler(engine),
+	}
+}
+
+// RequireAdmin only lets requests through that carry token in the
+// X-Admin-Token header. With an empty token the admin API is disabled.
+func RequireAdmin(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeError(w, "admin API is disabled", http.StatusForbidden)
+			logger.Warningf("Admin request rejected - API disabled - Path: %s", r.URL.Path)
+			return
+		}
+
+		given := r.Header.Get(AdminTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeError(w, "invalid admin token", http.StatusUnauthorized)
+			logger.Warningf("Admin request rejected - invalid token - Path: %s - Remote: %s", r.URL.Path, r.RemoteAddr)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// CancelOrder godoc
+// @Summary Force-cancel an order
+// @Description Cancel any resting order regardless of owner and unlock the owner's funds. Requires the X-Admin-Token header
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param request body v1.AdminCancelOrderRequest true "Order to cancel"
+// @Success 200 {object} v1.OrderResponse "Order cancelled successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 401 {object} v1.ErrorResponse "Invalid admin token"
+// @Failure 403 {object} v1.ErrorResponse "Admin API disabled"
+// @Failure 404 {object} v1.ErrorResponse "Order not found"
+// @Router /api/v1/admin/orders/cancel [post]
+func (h *AdminHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req v1.AdminCancelOrderRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		logger.Warningf("Admin cancel - invalid JSON - Duration: %v", time.Since(start))
+		return
+	}
+
+	if req.Pair == "" {
+		writeError(w, "pair is required", http.StatusBadRequest)
+		logger.Warningf("Admin cancel - missing pair - Duration: %v", time.Since(start))
+		return
+	}
+	if req.OrderID <= 0 {
+		writeError(w, "order_id must be greater than 0", http.StatusBadRequest)
+		logger.Warningf("Admin cancel - invalid order_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.orders.parsePair(req.Pair)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Admin cancel - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	cancelledOrder, err := h.engine.ForceCancelOrder(pair, req.OrderID)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("AUDIT admin cancel failed - Pair: %s - OrderID: %d - Reason: %q - Remote: %s - Duration: %v - Error: %v",
+			req.Pair, req.OrderID, req.Reason, r.RemoteAddr, time.Since(start), err)
+		return
+	}
+
+	writeJSON(w, h.orders.orderToResponse(cancelledOrder, req.Pair), http.StatusOK)
+
+	logger.Warningf("AUDIT admin cancel - Pair: %s - OrderID: %d - Owner: %s - Remaining: %.8f - Reason: %q - Remote: %s - Status: 200 - Duration: %v",
+		req.Pair, req.OrderID, cancelledOrder.UserID, cancelledOrder.RemainingAmount(), req.Reason, r.RemoteAddr, time.Since(start))
+}
+
+// CancelStaleOrders godoc
+// @Summary Cancel stale orders
+// @Description Cancel every resting order on a pair placed more than max_age ago, whoever owns it, and unlock the owners' funds. Requires the X-Admin-Token header
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param request body v1.AdminCancelStaleRequest true "Pair and maximum order age"
+// @Success 200 {object} v1.AdminCancelStaleResponse "Stale orders cancelled"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 401 {object} v1.ErrorResponse "Invalid admin token"
+// @Failure 403 {object} v1.ErrorResponse "Admin API disabled"
+// @Router /api/v1/admin/orders/cancel-stale [post]
+func (h *AdminHandler) CancelStaleOrders(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req v1.AdminCancelStaleRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		logger.Warningf("Admin cancel stale - invalid JSON - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.orders.parsePair(req.Pair)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Admin cancel stale - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	maxAge, err := time.ParseDuration(req.MaxAge)
+	if err != nil || maxAge <= 0 {
+		writeError(w, "max_age must be a positive duration (e.g. 24h)", http.StatusBadRequest)
+		logger.Warningf("Admin cancel stale - invalid max_age %q - Duration: %v", req.MaxAge, time.Since(start))
+		return
+	}
+
+	cancelled, err := h.engine.CancelOrdersOlderThan(pair, maxAge)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("AUDIT admin cancel stale failed - Pair: %s - MaxAge: %v - Cancelled: %d - Reason: %q - Remote: %s - Duration: %v - Error: %v",
+			req.Pair, maxAge, len(cancelled), req.Reason, r.RemoteAddr, time.Since(start), err)
+		return
+	}
+
+	response := v1.AdminCancelStaleResponse{
+		Pair:      pair.String(),
+		Cancelled: make([]v1.OrderResponse, len(cancelled)),
+		Count:     len(cancelled),
+	}
+	for i := range cancelled {
+		response.Cancelled[i] = h.orders.orderToResponse(&cancelled[i].Order, pair.String())
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Warningf("AUDIT admin cancel stale - Pair: %s - MaxAge: %v - Cancelled: %d - Reason: %q - Remote: %s - Status: 200 - Duration: %v",
+		req.Pair, maxAge, len(cancelled), req.Reason, r.RemoteAddr, time.Since(start))
+}
+
+// DelistPair godoc
+// @Summary Delist a pair
+// @Description Take a pair off the exchange for good, unlike a halt: every resting order is cancelled and its funds unlocked, trailing stops are cancelled, the pair leaves /pairs and new orders on it fail with 410 PAIR_DELISTED. Its trades and archived orders stay queryable. Requires the X-Admin-Token header
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param request body v1.AdminDelistRequest true "Pair to delist"
+// @Success 200 {object} v1.AdminDelistResponse "Pair delisted"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 401 {object} v1.ErrorResponse "Invalid admin token"
+// @Failure 403 {object} v1.ErrorResponse "Admin API disabled"
+// @Failure 404 {object} v1.ErrorResponse "Pair not listed"
+// @Failure 410 {object} v1.ErrorResponse "Pair already delisted"
+// @Router /api/v1/admin/pairs/delist [post]
+func (h *AdminHandler) DelistPair(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req v1.AdminDelistRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		logger.Warningf("Admin delist - invalid JSON - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.orders.parsePair(req.Pair)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Admin delist - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	cancelled, err := h.engine.DelistPair(pair)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("AUDIT admin delist failed - Pair: %s - Cancelled: %d - Reason: %q - Remote: %s - Duration: %v - Error: %v",
+			req.Pair, len(cancelled), req.Reason, r.RemoteAddr, time.Since(start), err)
+		return
+	}
+
+	response := v1.AdminDelistResponse{
+		Pair:      pair.String(),
+		Cancelled: make([]v1.OrderResponse, len(cancelled)),
+		Count:     len(cancelled),
+	}
+	for i := range cancelled {
+		response.Cancelled[i] = h.orders.orderToResponse(&cancelled[i].Order, pair.String())
+	}
+	if d, ok := h.engine.GetDelistedPair(pair); ok {
+		response.DelistedAt = d.DelistedAt
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Warningf("AUDIT admin delist - Pair: %s - Cancelled: %d - Reason: %q - Remote: %s - Status: 200 - Duration: %v",
+		req.Pair, len(cancelled), req.Reason, r.RemoteAddr, time.Since(start))
+}
+
+// Halt godoc
+// @Summary Halt all trading
+// @Description Emergency brake: reject every new order on every pair until resumed. Cancels are still accepted. Requires the X-Admin-Token header
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param request body v1.AdminHaltRequest false "Reason for the halt"
+// @Success 200 {object} v1.HaltStatusResponse "Trading halted"
+// @Failure 401 {object} v1.ErrorResponse "Invalid admin token"
+// @Failure 403 {object} v1.ErrorResponse "Admin API disabled"
+// @Router /api/v1/admin/halt [post]
+func (h *AdminHandler) Halt(w http.ResponseWriter, r *http.Request) {
+	h.setHalted(w, r, true)
+}
+
+// Resume godoc
+// @Summary Resume trading
+// @Description Lift an engine-wide halt. Pairs halted individually stay halted. Requires the X-Admin-Token header
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param request body v1.AdminHaltRequest false "Reason for resuming"
+// @Success 200 {object} v1.HaltStatusResponse "Trading resumed"
+// @Failure 401 {object} v1.ErrorResponse "Invalid admin token"
+// @Failure 403 {object} v1.ErrorResponse "Admin API disabled"
+// @Router /api/v1/admin/resume [post]
+func (h *AdminHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	h.setHalted(w, r, false)
+}
+
+func (h *AdminHandler) setHalted(w http.ResponseWriter, r *http.Request, halted bool) {
+	start := time.Now()
+
+	action := "resume"
+	if halted {
+		action = "halt"
+	}
+
+	// The body only carries an optional reason, so an empty one is fine.
+	var req v1.AdminHaltRequest
+	if err := decodeJSON(r, &req); err != nil && err != io.EOF {
+		writeBodyError(w, err)
+		logger.Warningf("Admin %s - invalid JSON - Duration: %v", action, time.Since(start))
+		return
+	}
+
+	if halted {
+		h.engine.Halt()
+	} else {
+		h.engine.Resume()
+	}
+
+	writeJSON(w, v1.HaltStatusResponse{Halted: h.engine.Halted()}, http.StatusOK)
+
+	logger.Warningf("AUDIT admin %s - Reason: %q - Remote: %s - Status: 200 - Duration: %v",
+		action, req.Reason, r.RemoteAddr, time.Since(start))
+}
+
+// SetPairMode godoc
+// @Summary Set a pair's market mode
+// @Description Restrict a pair to maker-only orders (nothing that crosses the book is accepted) or taker-only orders (only crossing orders, whose unfilled rest is cancelled), e.g. for auction phases, or put it in auction mode, where limit orders queue without matching until the pair's call auction runs. An empty mode accepts both again. Resting orders are left alone. Requires the X-Admin-Token header
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param request body v1.AdminPairModeRequest true "Pair and mode"
+// @Success 200 {object} v1.PairInfo "Mode set"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 401 {object} v1.ErrorResponse "Invalid admin token"
+// @Failure 403 {object} v1.ErrorResponse "Admin API disabled"
+// @Router /api/v1/admin/pairs/mode [post]
+func (h *AdminHandler) SetPairMode(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req v1.AdminPairModeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		logger.Warningf("Admin pair mode - invalid JSON - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.orders.parsePair(req.Pair)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Admin pair mode - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	if err := h.engine.SetPairMode(pair, engine.PairMode(req.Mode)); err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Admin pair mode failed - Pair: %s - Mode: %q - Duration: %v - Error: %v",
+			req.Pair, req.Mode, time.Since(start), err)
+		return
+	}
+
+	cfg, err := h.engine.GetPairConfig(pair)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Errorf("Admin pair mode - config not read - Pair: %s - Duration: %v - Error: %v", req.Pair, time.Since(start), err)
+		return
+	}
+	writeJSON(w, pairInfos([]engine.PairConfig{cfg})[0], http.StatusOK)
+
+	logger.Warningf("AUDIT admin pair mode - Pair: %s - Mode: %q - Reason: %q - Remote: %s - Status: 200 - Duration: %v",
+		req.Pair, req.Mode, req.Reason, r.RemoteAddr, time.Since(start))
+}
+
+// RunAuction godoc
+// @Summary Run a pair's call auction
+// @Description Uncross a pair in auction mode at the single price that matches the most volume; every bid at or above it and ask at or below it trades at that price, and the rest keeps resting. Requires the X-Admin-Token header
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param request body v1.AdminAuctionRequest true "Pair"
+// @Success 200 {object} v1.AuctionResponse "Auction run"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 401 {object} v1.ErrorResponse "Invalid admin token"
+// @Failure 403 {object} v1.ErrorResponse "Admin API disabled"
+// @Failure 409 {object} v1.ErrorResponse "Pair not in auction mode"
+// @Router /api/v1/admin/pairs/auction [post]
+func (h *AdminHandler) RunAuction(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req v1.AdminAuctionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		logger.Warningf("Admin auction - invalid JSON - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.orders.parsePair(req.Pair)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Admin auction - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	result, err := h.engine.RunAuction(pair)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Admin auction failed - Pair: %s - Duration: %v - Error: %v", req.Pair, time.Since(start), err)
+		return
+	}
+
+	writeJSON(w, v1.AuctionResponse{
+		Pair:      pair.String(),
+		Price:     v1.AssetAmount(pair.Quote, result.Price),
+		Volume:    v1.AssetAmount(pair.Base, result.Volume),
+		Imbalance: v1.AssetAmount(pair.Base, result.Imbalance),
+		Trades:    result.Trades,
+	}, http.StatusOK)
+
+	logger.Warningf("AUDIT admin auction - Pair: %s - Price: %v - Volume: %v - Trades: %d - Reason: %q - Remote: %s - Status: 200 - Duration: %v",
+		req.Pair, result.Price, result.Volume, result.Trades, req.Reason, r.RemoteAddr, time.Since(start))
+}
+
+// Stats godoc
+// @Summary Engine statistics
+// @Description Orders placed, matches and volume since start, active pairs, resting orders, and per pair and order type a histogram of the time spent in the matching loop alone. Requires the X-Admin-Token header
+// @Tags Admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} v1.AdminStatsResponse "Engine statistics"
+// @Failure 401 {object} v1.ErrorResponse "Invalid admin token"
+// @Failure 403 {object} v1.ErrorResponse "Admin API disabled"
+// @Router /api/v1/admin/stats [get]
+func (h *AdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	stats := h.engine.Stats()
+	response := v1.AdminStatsResponse{
+		OrdersPlaced:   stats.OrdersPlaced,
+		Matches:        stats.Matches,
+		Volume:         make(map[string]v1.FixedDecimal, len(stats.Volume)),
+		ActivePairs:    stats.ActivePairs,
+		OpenOrders:     stats.OpenOrders,
+		RebatesSkipped: stats.RebatesSkipped,
+		MatchLatency:   make([]v1.MatchLatency, len(stats.MatchLatency)),
+	}
+	for asset, volume := range stats.Volume {
+		response.Volume[asset] = v1.AssetAmount(asset, volume)
+	}
+	for i, latency := range stats.MatchLatency {
+		buckets := make([]v1.LatencyBucket, len(latency.Buckets))
+		for j, count := range latency.Buckets {
+			le := "+Inf"
+			if j < len(engine.MatchLatencyBuckets) {
+				le = engine.MatchLatencyBuckets[j].String()
+			}
+			buckets[j] = v1.LatencyBucket{Le: le, Count: count}
+		}
+		response.MatchLatency[i] = v1.MatchLatency{
+			Pair:      latency.Pair.String(),
+			OrderType: string(latency.OrderType),
+			Count:     latency.Count,
+			SumMicros: float64(latency.Sum) / float64(time.Microsecond),
+			Buckets:   buckets,
+		}
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Admin stats success - Status: 200 - Duration: %v", time.Since(start))
+}
+
+// AssetTotals godoc
+// @Summary Sum balances by asset
+// @Description Accounting snapshot for reconciliation: per asset, the available and locked balances summed over every user, and how many users hold it. Requires the X-Admin-Token header
+// @Tags Admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} v1.AdminAssetsResponse "Asset totals"
+// @Failure 401 {object} v1.ErrorResponse "Invalid admin token"
+// @Failure 403 {object} v1.ErrorResponse "Admin API disabled"
+// @Failure 500 {object} v1.ErrorResponse "Balances could not be read"
+// @Router /api/v1/admin/assets [get]
+func (h *AdminHandler) AssetTotals(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	totals, err := h.engine.AssetTotals()
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Errorf("Admin asset totals failed - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	response := v1.AdminAssetsResponse{Assets: make([]v1.AdminAssetTotal, 0, len(totals))}
+	for _, total := range totals {
+		response.Assets = append(response.Assets, v1.AdminAssetTotal{
+			Asset:     total.Asset,
+			Available: v1.AssetAmount(total.Asset, total.Available),
+			Locked:    v1.AssetAmount(total.Asset, total.Locked),
+			Total:     v1.AssetAmount(total.Asset, total.Total()),
+			Users:     total.Users,
+		})
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Admin asset totals success - Assets: %d - Remote: %s - Status: 200 - Duration: %v",
+		len(totals), r.RemoteAddr, time.Since(start))
+}
+
+// MatchesBetween godoc
+// @Summary List trades between two users
+// @Description Surveillance view for wash-trading investigations: every trade, on any pair, in which the two users were counterparties in either direction, oldest first and unmasked. Requires the X-Admin-Token header
+// @Tags Admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param userA query string true "First user ID"
+// @Param userB query string true "Second user ID"
+// @Param limit query int false "Trades to return (1-500, default 50)"
+// @Param offset query int false "Trades to skip"
+// @Success 200 {object} v1.AdminMatchesResponse "Trades between the users"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 401 {object} v1.ErrorResponse "Invalid admin token"
+// @Failure 403 {object} v1.ErrorResponse "Admin API disabled"
+// @Router /api/v1/admin/matches [get]
+func (h *AdminHandler) MatchesBetween(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	userA, userB := query.Get("userA"), query.Get("userB")
+	if userA == "" || userB == "" {
+		writeError(w, "userA and userB are required", http.StatusBadRequest)
+		logger.Warningf("Admin matches - missing user - Duration: %v", time.Since(start))
+		return
+	}
+
+	limit, err := parseIntParam(query.Get("limit"), defaultHistoryLimit, 1, maxHistoryLimit)
+	if err != nil {
+		writeError(w, "limit "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Admin matches - invalid limit - Duration: %v", time.Since(start))
+		return
+	}
+	offset, err := parseIntParam(query.Get("offset"), 0, 0, math.MaxInt)
+	if err != nil {
+		writeError(w, "offset "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Admin matches - invalid offset - Duration: %v", time.Since(start))
+		return
+	}
+
+	trades, total := h.engine.TradesBetween(userA, userB, limit, offset)
+
+	matches := make([]v1.AdminMatch, len(trades))
+	for i, t := range trades {
+		matches[i] = v1.AdminMatch{
+			Seq:        t.Seq,
+			Pair:       t.Pair.String(),
+			Price:      v1.Fiat(t.Price),
+			Amount:     v1.Crypto(t.Amount),
+			BuyerID:    t.BuyerID,
+			SellerID:   t.SellerID,
+			BidOrderID: t.BidOrderID,
+			AskOrderID: t.AskOrderID,
+			TakerSide:  string(t.TakerSide),
+			Timestamp:  t.Timestamp,
+		}
+	}
+
+	writeJSON(w, v1.AdminMatchesResponse{
+		UserA:   userA,
+		UserB:   userB,
+		Matches: matches,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	}, http.StatusOK)
+
+	logger.Infof("Admin matches success - Users: %s/%s - Matches: %d/%d - Remote: %s - Status: 200 - Duration: %v",
+		userA, userB, len(matches), total, r.RemoteAddr, time.Since(start))
+}
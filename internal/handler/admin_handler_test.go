@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
+)
+
+const testAdminToken = "s3cret"
+
+func doAdminRequest(handlerFunc http.HandlerFunc, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	_ = json.NewEncoder(&buf).Encode(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/orders/cancel", &buf)
+	if token != "" {
+		req.Header.Set(AdminTokenHeader, token)
+	}
+	rec := httptest.NewRecorder()
+	handlerFunc(rec, req)
+	return rec
+}
+
+func TestAdminHandler_CancelOrder_AnyOwner(t *testing.T) {
+	e := setupEngine()
+	h := NewAdminHandler(e)
+	cancel := RequireAdmin(testAdminToken, h.CancelOrder)
+
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	if err != nil {
+		t.Fatalf("place order: %v", err)
+	}
+	assertFloat(t, 50_000, e.GetAccountManager().GetBalance("1", "BRL").Locked, "Locked before cancel")
+
+	rec := doAdminRequest(cancel, testAdminToken, v1.AdminCancelOrderRequest{
+		Pair: "BTC/BRL", OrderID: order.ID, Reason: "compliance hold",
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.OrderResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "1", resp.UserID, "Owner")
+	assertEqual(t, string(orderbook.OrderCancelled), resp.State, "State")
+
+	balance := e.GetAccountManager().GetBalance("1", "BRL")
+	assertFloat(t, 100_000, balance.Available, "Owner's funds unlocked")
+	assertFloat(t, 0, balance.Locked, "Nothing left locked")
+	assertEqual(t, 0, e.GetOrderbook(btcBrl()).OpenOrderTotal(), "Order removed from book")
+}
+
+func TestAdminHandler_CancelOrder_Gate(t *testing.T) {
+	e := setupEngine()
+	h := NewAdminHandler(e)
+
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	if err != nil {
+		t.Fatalf("place order: %v", err)
+	}
+	body := v1.AdminCancelOrderRequest{Pair: "BTC/BRL", OrderID: order.ID}
+
+	rec := doAdminRequest(RequireAdmin(testAdminToken, h.CancelOrder), "", body)
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Missing token")
+
+	rec = doAdminRequest(RequireAdmin(testAdminToken, h.CancelOrder), "wrong", body)
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Wrong token")
+
+	rec = doAdminRequest(RequireAdmin("", h.CancelOrder), "", body)
+	assertEqual(t, http.StatusForbidden, rec.Code, "Admin API disabled")
+
+	assertEqual(t, 1, e.GetOrderbook(btcBrl()).OpenOrderTotal(), "Order still resting")
+}
+
+func TestAdminHandler_CancelOrder_NotFound(t *testing.T) {
+	h := NewAdminHandler(setupEngine())
+
+	rec := doAdminRequest(RequireAdmin(testAdminToken, h.CancelOrder), testAdminToken,
+		v1.AdminCancelOrderRequest{Pair: "BTC/BRL", OrderID: 999_999})
+	assertEqual(t, http.StatusNotFound, rec.Code, "Status code")
+}
+
+func TestAdminHandler_HaltAndResume(t *testing.T) {
+	e := setupEngine()
+	h := NewAdminHandler(e)
+	orders := NewOrderHandler(e)
+
+	resting, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	if err != nil {
+		t.Fatalf("place order: %v", err)
+	}
+
+	rec := doAdminRequest(RequireAdmin(testAdminToken, h.Halt), testAdminToken, v1.AdminHaltRequest{Reason: "incident"})
+	assertEqual(t, http.StatusOK, rec.Code, "Halt status code")
+	var status v1.HaltStatusResponse
+	decodeBody(t, rec, &status)
+	assertTrue(t, status.Halted, "Halted")
+
+	rec = doRequest(orders.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "2", Pair: "BTC/BRL", Side: "ask", Type: "limit", Price: 50_000, Amount: 1,
+	})
+	assertEqual(t, http.StatusServiceUnavailable, rec.Code, "Placement during halt")
+	var errResp v1.ErrorResponse
+	decodeBody(t, rec, &errResp)
+	assertEqual(t, CodeEngineHalted, errResp.Code, "Error code")
+
+	rec = doRequest(orders.CancelOrder, http.MethodPost, "/api/v1/orders/cancel", v1.CancelOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", OrderID: resting.ID,
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Cancel during halt")
+
+	rec = doAdminRequest(RequireAdmin(testAdminToken, h.Resume), testAdminToken, nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Resume status code")
+	decodeBody(t, rec, &status)
+	assertTrue(t, !status.Halted, "Resumed")
+}
+
+func TestAdminHandler_SetPairMode(t *testing.T) {
+	e := setupEngine()
+	h := NewAdminHandler(e)
+	setMode := RequireAdmin(testAdminToken, h.SetPairMode)
+
+	rec := doAdminRequest(setMode, testAdminToken, v1.AdminPairModeRequest{Pair: "BTC/BRL", Mode: "maker_only", Reason: "opening auction"})
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	var resp v1.PairInfo
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "maker_only", resp.Mode, "Mode reported")
+
+	_, _, err := e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.1)
+	assertEqual(t, engine.ErrMakerOnly, err, "Mode enforced")
+
+	rec = doAdminRequest(setMode, testAdminToken, v1.AdminPairModeRequest{Pair: "BTC/BRL", Mode: "batch"})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Unknown mode")
+	rec = doAdminRequest(setMode, "", v1.AdminPairModeRequest{Pair: "BTC/BRL"})
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Admin token required")
+
+	rec = doAdminRequest(setMode, testAdminToken, v1.AdminPairModeRequest{Pair: "BTC/BRL"})
+	assertEqual(t, http.StatusOK, rec.Code, "Mode lifted")
+	resp = v1.PairInfo{}
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "", resp.Mode, "Normal mode")
+}
+
+func TestAdminHandler_RunAuction(t *testing.T) {
+	e := setupEngine()
+	h := NewAdminHandler(e)
+	run := RequireAdmin(testAdminToken, h.RunAuction)
+
+	rec := doAdminRequest(run, testAdminToken, v1.AdminAuctionRequest{Pair: "BTC/BRL"})
+	assertEqual(t, http.StatusConflict, rec.Code, "Pair not in auction mode")
+
+	assertNoError(t, e.SetPairMode(btcBrl(), engine.PairModeAuction))
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_100, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.75)
+	assertNoError(t, err)
+
+	rec = doAdminRequest(run, testAdminToken, v1.AdminAuctionRequest{Pair: "BTC/BRL", Reason: "open"})
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	var resp v1.AuctionResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "50000.00", resp.Price.String(), "Clearing price")
+	assertEqual(t, "0.50000000", resp.Volume.String(), "Matched volume")
+	assertEqual(t, "-0.25000000", resp.Imbalance.String(), "Sellers left over")
+	assertEqual(t, 1, resp.Trades, "Trades")
+
+	rec = doAdminRequest(run, "", v1.AdminAuctionRequest{Pair: "BTC/BRL"})
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Admin token required")
+}
+
+func TestAdminHandler_DelistPair(t *testing.T) {
+	e := setupEngine()
+	h := NewAdminHandler(e)
+	delist := RequireAdmin(testAdminToken, h.DelistPair)
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 0.5)
+	assertNoError(t, err)
+
+	rec := doAdminRequest(delist, testAdminToken, v1.AdminDelistRequest{Pair: "BTC/BRL", Reason: "sunset"})
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	var resp v1.AdminDelistResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "BTC/BRL", resp.Pair, "Pair")
+	assertEqual(t, 1, resp.Count, "Resting order cancelled")
+	assertEqual(t, "cancelled", resp.Cancelled[0].State, "Reported cancelled")
+	assertTrue(t, !resp.DelistedAt.IsZero(), "Delisting time")
+
+	rec = doAdminRequest(delist, testAdminToken, v1.AdminDelistRequest{Pair: "BTC/BRL"})
+	assertEqual(t, http.StatusGone, rec.Code, "Already delisted")
+	var errResp v1.ErrorResponse
+	decodeBody(t, rec, &errResp)
+	assertEqual(t, CodePairDelisted, errResp.Code, "Error code")
+
+	rec = doAdminRequest(delist, "", v1.AdminDelistRequest{Pair: "ETH/BRL"})
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Admin token required")
+}
+
+func TestAdminHandler_CancelStaleOrders(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+	cfg := engine.DefaultConfig()
+	cfg.Clock = clk
+	e := engine.NewEngineWithConfig(cfg)
+	_ = e.GetAccountManager().Credit("1", "BRL", 100_000)
+	h := NewAdminHandler(e)
+	sweep := RequireAdmin(testAdminToken, h.CancelStaleOrders)
+
+	stale, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 1)
+	if err != nil {
+		t.Fatalf("place order: %v", err)
+	}
+	clk.Advance(25 * time.Hour)
+	if _, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 41_000, 1); err != nil {
+		t.Fatalf("place order: %v", err)
+	}
+
+	rec := doAdminRequest(sweep, testAdminToken, v1.AdminCancelStaleRequest{Pair: "BTC/BRL", MaxAge: "24h", Reason: "cleanup"})
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.AdminCancelStaleResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 1, resp.Count, "One stale order")
+	assertEqual(t, stale.ID, resp.Cancelled[0].ID, "The stale order")
+	assertFloat(t, 41_000, e.GetAccountManager().GetBalance("1", "BRL").Locked, "Fresh order still locked")
+
+	rec = doAdminRequest(sweep, testAdminToken, v1.AdminCancelStaleRequest{Pair: "BTC/BRL", MaxAge: "-1h"})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Negative max_age")
+	rec = doAdminRequest(sweep, "", v1.AdminCancelStaleRequest{Pair: "BTC/BRL", MaxAge: "1h"})
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Admin token required")
+}
+
+func TestAdminHandler_Stats(t *testing.T) {
+	e := setupEngine()
+	h := NewAdminHandler(e)
+	stats := RequireAdmin(testAdminToken, h.Stats)
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 0.25)
+	assertNoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/stats", nil)
+	req.Header.Set(AdminTokenHeader, testAdminToken)
+	rec := httptest.NewRecorder()
+	stats(rec, req)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.AdminStatsResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, int64(2), resp.OrdersPlaced, "Orders placed")
+	assertEqual(t, int64(1), resp.Matches, "Matches")
+	assertEqual(t, "0.25000000", resp.Volume["BTC"].String(), "BTC volume")
+	assertEqual(t, 1, resp.OpenOrders, "Ask still resting")
+
+	assertEqual(t, 2, len(resp.MatchLatency), "Limit and market histograms")
+	market := resp.MatchLatency[1]
+	assertEqual(t, "BTC/BRL", market.Pair, "Pair label")
+	assertEqual(t, "market", market.OrderType, "Order type label")
+	assertEqual(t, int64(1), market.Count, "One sample")
+	assertEqual(t, len(engine.MatchLatencyBuckets)+1, len(market.Buckets), "Buckets")
+	assertEqual(t, "1µs", market.Buckets[0].Le, "First bound")
+	assertEqual(t, "+Inf", market.Buckets[len(market.Buckets)-1].Le, "Unbounded last bucket")
+
+	rec = doAdminRequest(stats, "", nil)
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Admin token required")
+}
+
+func TestAdminHandler_AssetTotals(t *testing.T) {
+	e := setupEngine()
+	h := NewAdminHandler(e)
+	totals := RequireAdmin(testAdminToken, h.AssetTotals)
+	_ = e.GetAccountManager().Credit("3", "BRL", 250.75)
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 51_000, 2)
+	assertNoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/assets", nil)
+	req.Header.Set(AdminTokenHeader, testAdminToken)
+	rec := httptest.NewRecorder()
+	totals(rec, req)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.AdminAssetsResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 2, len(resp.Assets), "Assets")
+
+	brl, btc := resp.Assets[0], resp.Assets[1]
+	assertEqual(t, "BRL", brl.Asset, "Sorted by asset")
+	assertFloat(t, 175_250.75, brl.Available.Float64(), "BRL available")
+	assertFloat(t, 25_000, brl.Locked.Float64(), "BRL locked")
+	assertFloat(t, 200_250.75, brl.Total.Float64(), "BRL total")
+	assertEqual(t, 3, brl.Users, "BRL holders")
+	assertFloat(t, 18, btc.Available.Float64(), "BTC available")
+	assertFloat(t, 2, btc.Locked.Float64(), "BTC locked")
+	assertEqual(t, 2, btc.Users, "BTC holders")
+
+	rec = doAdminRequest(totals, "", nil)
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Token required")
+}
+
+func TestAdminHandler_MatchesBetween(t *testing.T) {
+	e := setupEngine()
+	_ = e.GetAccountManager().Credit("3", "BRL", 100_000)
+	h := NewAdminHandler(e)
+	matches := RequireAdmin(testAdminToken, h.MatchesBetween)
+
+	for _, tr := range []struct{ seller, buyer string }{{"1", "2"}, {"1", "3"}, {"2", "1"}} {
+		_, _, err := e.PlaceOrder(tr.seller, btcBrl(), orderbook.Ask, 50_000, 0.1)
+		assertNoError(t, err)
+		_, _, err = e.PlaceOrder(tr.buyer, btcBrl(), orderbook.Bid, 50_000, 0.1)
+		assertNoError(t, err)
+	}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(AdminTokenHeader, testAdminToken)
+		rec := httptest.NewRecorder()
+		matches(rec, req)
+		return rec
+	}
+
+	rec := get("/api/v1/admin/matches?userA=2&userB=1")
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	var resp v1.AdminMatchesResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 2, resp.Total, "Only trades between 1 and 2")
+	assertEqual(t, "2", resp.Matches[0].BuyerID, "Unmasked buyer")
+	assertEqual(t, "1", resp.Matches[0].SellerID, "Unmasked seller")
+	assertEqual(t, "1", resp.Matches[1].BuyerID, "Either direction")
+
+	rec = get("/api/v1/admin/matches?userA=1&userB=2&limit=1&offset=1")
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 1, len(resp.Matches), "Paged")
+	assertEqual(t, 2, resp.Total, "Total before paging")
+
+	rec = get("/api/v1/admin/matches?userA=1")
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Missing userB")
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/pkg/logger"
+)
+
+type ConfigHandler struct {
+	engine       *engine.Engine
+	adminEnabled bool
+}
+
+// NewConfigHandler serves the engine's effective configuration. adminEnabled
+// reports whether admin routes are reachable; the token itself is never
+// passed in.
+func NewConfigHandler(engine *engine.Engine, adminEnabled bool) *ConfigHandler {
+	return &ConfigHandler{
+		engine:       engine,
+		adminEnabled: adminEnabled,
+	}
+}
+
+// GetConfig godoc
+// @Summary Get runtime configuration
+// @Description Get the configuration the engine is enforcing: pair parameters, fee schedule, limits and enabled features. Secrets are redacted
+// @Tags Health
+// @Produce json
+// @Success 200 {object} v1.ConfigResponse "Configuration retrieved successfully"
+// @Router /api/v1/config [get]
+func (h *ConfigHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	cfg := h.engine.Config()
+
+	tiers := make([]v1.FeeTierInfo, len(cfg.FeeTiers))
+	for i, tier := range cfg.FeeTiers {
+		tiers[i] = v1.FeeTierInfo{
+			MinVolume: v1.Decimal(tier.MinVolume),
+			MakerRate: v1.Decimal(tier.MakerRate),
+			TakerRate: v1.Decimal(tier.TakerRate),
+		}
+	}
+
+	response := v1.ConfigResponse{
+		Pairs:                pairInfos(h.engine.ListPairs()),
+		STPMode:              string(cfg.STPMode),
+		TickPolicy:           string(cfg.TickPolicy),
+		MaxOpenOrdersPerUser: cfg.MaxOpenOrdersPerUser,
+		MaxLevelsPerOrder:    cfg.MaxLevelsPerOrder,
+		SweepDust:            cfg.SweepDust,
+		AllowUnlistedPairs:   cfg.AllowUnlistedPairs,
+		Deterministic:        cfg.Deterministic,
+		FeeTiers:             tiers,
+		FeeWindow:            cfg.FeeWindow.String(),
+		FeeAccount:           cfg.FeeAccount,
+		FeeRounding:          string(cfg.FeeRounding),
+		AdminEnabled:         h.adminEnabled,
+	}
+	if len(cfg.MinRefund) > 0 {
+		response.MinRefund = make(map[string]v1.Decimal, len(cfg.MinRefund))
+		for asset, threshold := range cfg.MinRefund {
+			response.MinRefund[asset] = v1.Decimal(threshold)
+		}
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Get config success - Status: 200 - Duration: %v", time.Since(start))
+}
+
+// GetCapabilities godoc
+// @Summary Get supported order types and features
+// @Description List the order types, time-in-force options and features this server has enabled, derived from its configuration. Order types it does not support, such as stop, stop-limit, OCO and iceberg, are left out
+// @Tags Health
+// @Produce json
+// @Success 200 {object} v1.CapabilitiesResponse "Capabilities retrieved successfully"
+// @Router /api/v1/capabilities [get]
+func (h *ConfigHandler) GetCapabilities(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	cfg := h.engine.Config()
+
+	features := []string{"ws", "hidden_orders", "nonces", "tags"}
+	for _, tier := range cfg.FeeTiers {
+		if tier.MakerRate != 0 || tier.TakerRate != 0 {
+			features = append(features, "fees")
+			break
+		}
+	}
+	if h.adminEnabled {
+		features = append(features, "admin_auth")
+	}
+	if cfg.SweepDust {
+		features = append(features, "sweep_dust")
+	}
+	if cfg.TakerThrottle.Enabled() {
+		features = append(features, "taker_throttle")
+	}
+	if cfg.Deterministic {
+		features = append(features, "deterministic")
+	}
+
+	writeJSON(w, v1.CapabilitiesResponse{
+		OrderTypes:  []string{"limit", "market"},
+		TimeInForce: []string{"gtc", "gtd"},
+		Features:    features,
+	}, http.StatusOK)
+
+	logger.Infof("Get capabilities success - Status: 200 - Duration: %v", time.Since(start))
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+)
+
+func TestConfigHandler_GetConfig(t *testing.T) {
+	cfg := engine.DefaultConfig()
+	cfg.STPMode = engine.STPCancelBoth
+	cfg.MaxOpenOrdersPerUser = 50
+	cfg.FeeTiers = []engine.FeeTier{
+		{MinVolume: 0, MakerRate: 0.001, TakerRate: 0.002},
+		{MinVolume: 100_000, MakerRate: 0.0005, TakerRate: 0.001},
+	}
+	cfg.MinRefund = map[string]float64{"BRL": 0.05}
+	e := engine.NewEngineWithConfig(cfg)
+	assertNoError(t, e.RegisterPair(engine.PairConfig{
+		Pair:         engine.Pair{Base: "SOL", Quote: "BRL"},
+		PriceTick:    0.5,
+		AmountTick:   0.001,
+		MinOrderSize: 0.1,
+		MinNotional:  10,
+	}))
+
+	h := NewConfigHandler(e, true)
+	rec := doRequest(h.GetConfig, http.MethodGet, "/api/v1/config", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.ConfigResponse
+	decodeBody(t, rec, &resp)
+
+	var sol *v1.PairInfo
+	for i := range resp.Pairs {
+		if resp.Pairs[i].Symbol == "SOL/BRL" {
+			sol = &resp.Pairs[i]
+		}
+	}
+	assertTrue(t, sol != nil, "Registered pair listed")
+	assertFloat(t, 0.5, sol.PriceTick.Float64(), "Price tick")
+	assertFloat(t, 0.001, sol.AmountTick.Float64(), "Amount tick")
+	assertFloat(t, 0.1, sol.MinOrderSize.Float64(), "Min order size")
+	assertFloat(t, 10, sol.MinNotional.Float64(), "Min notional")
+
+	assertEqual(t, "cancel_both", resp.STPMode, "STP mode")
+	assertEqual(t, "floor", resp.TickPolicy, "Tick policy")
+	assertEqual(t, 50, resp.MaxOpenOrdersPerUser, "Open order cap")
+	assertEqual(t, 2, len(resp.FeeTiers), "Fee tiers")
+	assertFloat(t, 0.002, resp.FeeTiers[0].TakerRate.Float64(), "Base taker rate")
+	assertFloat(t, 100_000, resp.FeeTiers[1].MinVolume.Float64(), "Tier volume")
+	assertFloat(t, 0.0005, resp.FeeTiers[1].MakerRate.Float64(), "Tier maker rate")
+	assertEqual(t, "720h0m0s", resp.FeeWindow, "Fee window")
+	assertEqual(t, "fees", resp.FeeAccount, "Fee account")
+	assertEqual(t, "nearest", resp.FeeRounding, "Fee rounding")
+	assertFloat(t, 0.05, resp.MinRefund["BRL"].Float64(), "Refund override")
+	assertTrue(t, resp.AdminEnabled, "Admin enabled")
+}
+
+func TestConfigHandler_GetConfig_NoSecrets(t *testing.T) {
+	h := NewConfigHandler(engine.NewEngine(), false)
+	rec := doRequest(h.GetConfig, http.MethodGet, "/api/v1/config", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	raw := rec.Body.String()
+	assertTrue(t, !strings.Contains(strings.ToLower(raw), "token"), "No token field in "+raw)
+	assertTrue(t, strings.Contains(raw, `"admin_enabled":false`), "Admin disabled")
+	assertTrue(t, strings.Contains(raw, `"fee_tiers":[]`), "No fee tiers")
+}
+
+func TestConfigHandler_GetCapabilities(t *testing.T) {
+	cfg := engine.DefaultConfig()
+	cfg.FeeTiers = []engine.FeeTier{{MakerRate: 0.001, TakerRate: 0.002}}
+	cfg.SweepDust = true
+
+	h := NewConfigHandler(engine.NewEngineWithConfig(cfg), true)
+	rec := doRequest(h.GetCapabilities, http.MethodGet, "/api/v1/capabilities", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.CapabilitiesResponse
+	decodeBody(t, rec, &resp)
+
+	assertEqual(t, "limit,market", strings.Join(resp.OrderTypes, ","), "Order types")
+	assertEqual(t, "gtc,gtd", strings.Join(resp.TimeInForce, ","), "Time in force")
+	features := strings.Join(resp.Features, ",")
+	for _, want := range []string{"fees", "admin_auth", "ws", "sweep_dust"} {
+		assertTrue(t, slices.Contains(resp.Features, want), want+" enabled in "+features)
+	}
+	for _, unwanted := range []string{"taker_throttle", "deterministic"} {
+		assertTrue(t, !slices.Contains(resp.Features, unwanted), unwanted+" disabled in "+features)
+	}
+
+	// A default engine charges no fees, and no admin token is set
+	h = NewConfigHandler(engine.NewEngine(), false)
+	rec = doRequest(h.GetCapabilities, http.MethodGet, "/api/v1/capabilities", nil)
+	decodeBody(t, rec, &resp)
+	assertTrue(t, !slices.Contains(resp.Features, "fees"), "No fees")
+	assertTrue(t, !slices.Contains(resp.Features, "admin_auth"), "No admin auth")
+	assertTrue(t, !slices.Contains(resp.OrderTypes, "stop"), "Stop orders unsupported")
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/pkg/logger"
+)
+
+type FeeHandler struct {
+	engine *engine.Engine
+}
+
+func NewFeeHandler(engine *engine.Engine) *FeeHandler {
+	return &FeeHandler{
+		engine: engine,
+	}
+}
+
+// GetFeeStatus godoc
+// @Summary Get fee tier
+// @Description Get a user's rolling 30-day traded volume and the fee tier it earns
+// @Tags Accounts
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Success 200 {object} v1.FeeStatusResponse "Fee status retrieved successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Router /api/v1/accounts/fees [get]
+func (h *FeeHandler) GetFeeStatus(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("Fee status - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	status := h.engine.FeeStatus(userID)
+	response := v1.FeeStatusResponse{
+		UserID:    userID,
+		Volume:    v1.Decimal(status.Volume),
+		Tier:      status.Tier,
+		MakerRate: v1.Decimal(status.MakerRate),
+		TakerRate: v1.Decimal(status.TakerRate),
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Fee status - User: %s - Tier: %d - Status: 200 - Duration: %v", userID, status.Tier, time.Since(start))
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestFeeHandler_GetFeeStatus(t *testing.T) {
+	cfg := engine.DefaultConfig()
+	cfg.FeeTiers = []engine.FeeTier{
+		{MinVolume: 0, MakerRate: 0.001, TakerRate: 0.002},
+		{MinVolume: 25_000, MakerRate: 0.0005, TakerRate: 0.001},
+	}
+	e := engine.NewEngineWithConfig(cfg)
+	_ = e.GetAccountManager().Credit("1", "BRL", 100_000)
+	_ = e.GetAccountManager().Credit("2", "BTC", 10)
+	h := NewFeeHandler(e)
+
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	_, _, _ = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+
+	rec := doRequest(h.GetFeeStatus, http.MethodGet, "/api/v1/accounts/fees?user_id=1", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.FeeStatusResponse
+	decodeBody(t, rec, &resp)
+	assertFloat(t, 25_000, resp.Volume.Float64(), "Volume")
+	assertEqual(t, 1, resp.Tier, "Tier")
+	assertFloat(t, 0.0005, resp.MakerRate.Float64(), "Maker rate")
+	assertFloat(t, 0.001, resp.TakerRate.Float64(), "Taker rate")
+}
+
+func TestFeeHandler_GetFeeStatus_MissingUser(t *testing.T) {
+	h := NewFeeHandler(setupEngine())
+
+	rec := doRequest(h.GetFeeStatus, http.MethodGet, "/api/v1/accounts/fees", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Status code")
+}
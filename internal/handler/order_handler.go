@@ -3,7 +3,11 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,6 +15,7 @@ import (
 	"github.com/moura95/crypto-exchange-challenge/internal/engine"
 	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
 	"github.com/moura95/crypto-exchange-challenge/pkg/logger"
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
 )
 
 type OrderHandler struct {
@@ -25,28 +30,29 @@ func NewOrderHandler(engine *engine.Engine) *OrderHandler {
 
 // PlaceOrder godoc
 // @Summary Place a new order
-// @Description Create a limit or market order
+// @Description Create a limit or market order. With wait_for, the response is held until the order is filled or closed, cancelling it if it still rests when wait_for runs out
 // @Tags Orders
 // @Accept json
 // @Produce json
 // @Param order body v1.PlaceOrderRequest true "Order details"
 // @Success 200 {object} v1.PlaceOrderResponse "Order placed successfully"
 // @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 409 {object} v1.ErrorResponse "Nonce not above the last one used"
 // @Failure 500 {object} v1.ErrorResponse "Internal server error"
 // @Router /api/v1/orders [post]
 func (h *OrderHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
 	var req v1.PlaceOrderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
 		logger.Warningf("Place order - invalid JSON - Duration: %v - Error: %v", time.Since(start), err)
 		return
 	}
 
 	// Validação
 	if err := h.validatePlaceOrderRequest(req); err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err.Error(), http.StatusBadRequest)
 		logger.Warningf("Place order - validation failed - Duration: %v - Error: %v", time.Since(start), err)
 		return
 	}
@@ -54,7 +60,7 @@ func (h *OrderHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
 	// Parse pair (BTC/BRL -> Base: BTC, Quote: BRL)
 	pair, err := h.parsePair(req.Pair)
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err.Error(), http.StatusBadRequest)
 		logger.Warningf("Place order - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
 		return
 	}
@@ -62,38 +68,108 @@ func (h *OrderHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
 	// Parse side
 	side, err := h.parseSide(req.Side)
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err.Error(), http.StatusBadRequest)
 		logger.Warningf("Place order - invalid side - Duration: %v - Error: %v", time.Since(start), err)
 		return
 	}
 
+	if err := h.checkPrecision(pair, req.Price, req.Amount); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Place order - too many decimals - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	// Resolve a percent of the balance into a concrete amount
+	amount := req.Amount.Float64()
+	if req.AmountPct != 0 {
+		orderType := orderbook.OrderTypeLimit
+		if req.Type == "market" {
+			orderType = orderbook.OrderTypeMarket
+		}
+		amount, err = h.engine.AmountForBalancePct(req.UserID, pair, side, orderType, req.Price.Float64(), req.AmountPct.Float64(), req.AllowSelfTrade)
+		if err != nil {
+			h.engine.RecordRejection(req.UserID, pair, side, orderType, req.Price.Float64(), 0, req.Tag, err)
+			writeDomainError(w, err)
+			logger.Warningf("Place order - amount_pct not resolved - User: %s - Duration: %v - Error: %v", req.UserID, time.Since(start), err)
+			return
+		}
+	}
+
 	var order *orderbook.Order
 	var matches []orderbook.Match
 
 	// Place order based on type
 	if req.Type == "market" {
-		order, matches, err = h.engine.PlaceMarketOrder(req.UserID, pair, side, req.Amount)
+		opts := engine.OrderOptions{RestRemainder: req.RestRemainder, Nonce: req.Nonce, Tag: req.Tag,
+			OrderID: req.OrderID, AllowSelfTrade: req.AllowSelfTrade}
+		order, matches, err = h.engine.PlaceMarketOrderWithOptions(req.UserID, pair, side, amount, opts)
 	} else {
-		order, matches, err = h.engine.PlaceOrder(req.UserID, pair, side, req.Price, req.Amount)
+		opts := engine.OrderOptions{Hidden: req.Hidden, Nonce: req.Nonce, Tag: req.Tag,
+			OrderID: req.OrderID, AllowSelfTrade: req.AllowSelfTrade, ImproveOnly: req.ImproveOnly}
+		if req.ExpiresAt != nil {
+			opts.ExpiresAt = *req.ExpiresAt
+		}
+		order, matches, err = h.engine.PlaceOrderWithOptions(req.UserID, pair, side, req.Price.Float64(), amount, opts)
 	}
 
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+		writeDomainError(w, err)
 		logger.Warningf("Place order failed - User: %s - Pair: %s - Duration: %v - Error: %v",
 			req.UserID, req.Pair, time.Since(start), err)
 		return
 	}
 
-	// Convert to response
+	// Hold the response until the order is done, cancelling it at the timeout
+	var waited *engine.ArchivedOrder
+	if req.WaitFor != "" && (order.State == orderbook.OrderOpen || order.State == orderbook.OrderPartiallyFilled) {
+		wait, _ := time.ParseDuration(req.WaitFor)
+		final, err := h.engine.WaitForOrder(r.Context(), req.UserID, pair, order.ID, wait)
+		if err != nil {
+			logger.Warningf("Place order - wait failed, returning the order as placed - User: %s - Order: %d - Error: %v",
+				req.UserID, order.ID, err)
+		} else {
+			order = &final.Order
+			waited = &final
+		}
+	}
+
+	// Convert to response; a waited order reports every trade that filled
+	// it, not only those matched on placement
 	response := v1.PlaceOrderResponse{
-		Order:   h.orderToResponse(order, req.Pair),
-		Matches: h.matchesToResponse(matches),
+		Order:           h.orderToResponse(order, req.Pair),
+		Matches:         h.matchesToResponse(order, matches),
+		RequestedPrice:  req.Price,
+		RequestedAmount: req.Amount,
+	}
+	if waited != nil {
+		trades, _, err := h.engine.OrderTrades(req.UserID, pair, order.ID, 0, 0)
+		if err != nil {
+			logger.Warningf("Place order - trades of the waited order not read, returning the placement matches - User: %s - Order: %d - Error: %v",
+				req.UserID, order.ID, err)
+		} else {
+			response.Matches = h.tradesToMatches(order, trades)
+		}
+	}
+	if req.AmountPct != 0 {
+		response.ResolvedAmount = v1.Decimal(amount)
+	}
+	if waited != nil && waited.UnlockedAsset != "" {
+		released := v1.AssetAmount(waited.UnlockedAsset, waited.Unlocked)
+		response.Order.UnlockedAsset = waited.UnlockedAsset
+		response.Order.UnlockedAmount = &released
+	}
+	if order.QueuePos > 0 && (order.State == orderbook.OrderOpen || order.State == orderbook.OrderPartiallyFilled) {
+		response.Order.QueuePosition = order.QueuePos
+		response.Order.OrdersAhead = order.QueuePos - 1
+	}
+	if req.IncludeBalances {
+		response.Balances = balanceItems(h.engine.Balances(req.UserID))
 	}
 
-	h.sendJSON(w, response, http.StatusOK)
+	writeJSON(w, response, http.StatusOK)
 
 	logger.Infof("Place order success - User: %s - Pair: %s - Type: %s - Side: %s - Price: %.2f - Amount: %.8f - Matches: %d - Status: 200 - Duration: %v",
-		req.UserID, req.Pair, req.Type, req.Side, req.Price, req.Amount, len(matches), time.Since(start))
+		req.UserID, req.Pair, req.Type, req.Side, req.Price, amount, len(response.Matches), time.Since(start))
 }
 
 // CancelOrder godoc
@@ -106,30 +182,31 @@ func (h *OrderHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} v1.OrderResponse "Order cancelled successfully"
 // @Failure 400 {object} v1.ErrorResponse "Invalid request"
 // @Failure 404 {object} v1.ErrorResponse "Order not found"
+// @Failure 409 {object} v1.ErrorResponse "Nonce not above the last one used"
 // @Router /api/v1/orders/cancel [post]
 func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
 	var req v1.CancelOrderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
 		logger.Warningf("Cancel order - invalid JSON - Duration: %v", time.Since(start))
 		return
 	}
 
 	// Validate
 	if req.UserID == "" {
-		h.sendError(w, "user_id is required", http.StatusBadRequest)
+		writeError(w, "user_id is required", http.StatusBadRequest)
 		logger.Warningf("Cancel order - missing user_id - Duration: %v", time.Since(start))
 		return
 	}
 	if req.Pair == "" {
-		h.sendError(w, "pair is required", http.StatusBadRequest)
+		writeError(w, "pair is required", http.StatusBadRequest)
 		logger.Warningf("Cancel order - missing pair - Duration: %v", time.Since(start))
 		return
 	}
 	if req.OrderID <= 0 {
-		h.sendError(w, "order_id must be greater than 0", http.StatusBadRequest)
+		writeError(w, "order_id must be greater than 0", http.StatusBadRequest)
 		logger.Warningf("Cancel order - invalid order_id - Duration: %v", time.Since(start))
 		return
 	}
@@ -137,36 +214,886 @@ func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	// Parse pair
 	pair, err := h.parsePair(req.Pair)
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err.Error(), http.StatusBadRequest)
 		logger.Warningf("Cancel order - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
 		return
 	}
 
 	// Cancel order
-	cancelledOrder, err := h.engine.CancelOrder(req.UserID, pair, req.OrderID)
+	cancelled, err := h.engine.CancelOrderWithNonce(req.UserID, pair, req.OrderID, req.Nonce)
 	if err != nil {
-		statusCode := http.StatusBadRequest
-		if errors.Is(err, engine.ErrOrderNotFound) {
-			statusCode = http.StatusNotFound
-		} else if errors.Is(err, engine.ErrUnauthorized) {
-			statusCode = http.StatusUnauthorized
-		}
-
-		h.sendError(w, err.Error(), statusCode)
-		logger.Warningf("Cancel order failed - User: %s - OrderID: %d - Duration: %v - Error: %v",
-			req.UserID, req.OrderID, time.Since(start), err)
+		writeDomainError(w, err)
+		logger.Warningf("Cancel order failed - User: %s - OrderID: %d - Nonce: %d - Duration: %v - Error: %v",
+			req.UserID, req.OrderID, req.Nonce, time.Since(start), err)
 		return
 	}
 
-	response := h.orderToResponse(cancelledOrder, req.Pair)
-	h.sendJSON(w, response, http.StatusOK)
+	response := h.orderToResponse(&cancelled.Order, req.Pair)
+	unlocked := v1.AssetAmount(cancelled.UnlockedAsset, cancelled.Unlocked)
+	response.UnlockedAsset = cancelled.UnlockedAsset
+	response.UnlockedAmount = &unlocked
+	writeJSON(w, response, http.StatusOK)
 
 	logger.Infof("Cancel order success - User: %s - OrderID: %d - Status: 200 - Duration: %v",
 		req.UserID, req.OrderID, time.Since(start))
 }
 
+// AmendOrder godoc
+// @Summary Reduce an order's amount
+// @Description Lower the amount of a resting order, keeping its price and queue position, and unlock the funds it no longer needs. The new amount must be below the current one and above what has already filled
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param request body v1.AmendOrderRequest true "Amend order details"
+// @Success 200 {object} v1.OrderResponse "Order amended successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 404 {object} v1.ErrorResponse "Order not found"
+// @Failure 409 {object} v1.ErrorResponse "Nonce not above the last one used"
+// @Router /api/v1/orders/amend [post]
+func (h *OrderHandler) AmendOrder(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req v1.AmendOrderRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		logger.Warningf("Amend order - invalid JSON - Duration: %v", time.Since(start))
+		return
+	}
+
+	if req.UserID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("Amend order - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+	if req.Pair == "" {
+		writeError(w, "pair is required", http.StatusBadRequest)
+		logger.Warningf("Amend order - missing pair - Duration: %v", time.Since(start))
+		return
+	}
+	if req.OrderID <= 0 {
+		writeError(w, "order_id must be greater than 0", http.StatusBadRequest)
+		logger.Warningf("Amend order - invalid order_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.parsePair(req.Pair)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Amend order - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	if err := h.checkPrecision(pair, 0, req.Amount); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Amend order - too many decimals - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	amended, err := h.engine.AmendOrderWithNonce(req.UserID, pair, req.OrderID, req.Amount.Float64(), req.Nonce)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Amend order failed - User: %s - OrderID: %d - Nonce: %d - Duration: %v - Error: %v",
+			req.UserID, req.OrderID, req.Nonce, time.Since(start), err)
+		return
+	}
+
+	writeJSON(w, h.orderToResponse(amended, req.Pair), http.StatusOK)
+
+	logger.Infof("Amend order success - User: %s - OrderID: %d - Amount: %.8f - Status: 200 - Duration: %v",
+		req.UserID, req.OrderID, amended.Amount, time.Since(start))
+}
+
+// CancelAllOrdersGlobal godoc
+// @Summary Cancel all of a user's orders
+// @Description Cancel every resting order of a user on every pair and unlock the funds they held
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param request body v1.CancelAllOrdersRequest true "User whose orders are cancelled"
+// @Success 200 {object} v1.CancelAllOrdersResponse "Orders cancelled successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 500 {object} v1.ErrorResponse "Internal server error"
+// @Router /api/v1/orders/cancel-all-global [post]
+func (h *OrderHandler) CancelAllOrdersGlobal(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req v1.CancelAllOrdersRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		logger.Warningf("Cancel all orders - invalid JSON - Duration: %v", time.Since(start))
+		return
+	}
+	if req.UserID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("Cancel all orders - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	cancelled, err := h.engine.CancelAllUserOrders(req.UserID)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Errorf("Cancel all orders failed - User: %s - Cancelled: %d - Duration: %v - Error: %v",
+			req.UserID, len(cancelled), time.Since(start), err)
+		return
+	}
+
+	orders := make([]v1.OrderResponse, len(cancelled))
+	for i, a := range cancelled {
+		orders[i] = h.orderToResponse(&a.Order, a.Pair.String())
+	}
+
+	writeJSON(w, v1.CancelAllOrdersResponse{
+		UserID:    req.UserID,
+		Cancelled: orders,
+		Count:     len(orders),
+	}, http.StatusOK)
+
+	logger.Infof("Cancel all orders success - User: %s - Cancelled: %d - Status: 200 - Duration: %v",
+		req.UserID, len(orders), time.Since(start))
+}
+
+// GetOrderHistory godoc
+// @Summary Get order history
+// @Description Get a user's filled and cancelled orders with fill totals, newest first
+// @Tags Orders
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param pair query string false "Trading pair filter (e.g., BTC/BRL)"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param offset query int false "Orders to skip"
+// @Success 200 {object} v1.OrderHistoryResponse "History retrieved successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Router /api/v1/orders/history [get]
+func (h *OrderHandler) GetOrderHistory(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	userID := query.Get("user_id")
+	if userID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("Order history - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	filter := engine.HistoryFilter{Limit: defaultHistoryLimit}
+
+	if pairStr := query.Get("pair"); pairStr != "" {
+		pair, err := h.parsePair(pairStr)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			logger.Warningf("Order history - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+			return
+		}
+		filter.Pair = &pair
+	}
+
+	var err error
+	if filter.Limit, err = parseIntParam(query.Get("limit"), defaultHistoryLimit, 1, maxHistoryLimit); err != nil {
+		writeError(w, "limit "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Order history - invalid limit - Duration: %v", time.Since(start))
+		return
+	}
+	if filter.Offset, err = parseIntParam(query.Get("offset"), 0, 0, math.MaxInt); err != nil {
+		writeError(w, "offset "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Order history - invalid offset - Duration: %v", time.Since(start))
+		return
+	}
+
+	archived, total, err := h.engine.OrderHistory(userID, filter)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Errorf("Order history failed - User: %s - Duration: %v - Error: %v", userID, time.Since(start), err)
+		return
+	}
+
+	items := make([]v1.OrderHistoryItem, len(archived))
+	for i, a := range archived {
+		items[i] = v1.OrderHistoryItem{
+			ID:           a.Order.ID,
+			Pair:         a.Pair.String(),
+			Side:         string(a.Order.Side),
+			Type:         string(a.Order.Type),
+			Price:        v1.Decimal(a.Order.Price),
+			Amount:       v1.Decimal(a.Order.Amount),
+			FilledAmount: v1.Decimal(a.Order.FilledAmount),
+			AvgFillPrice: v1.Decimal(a.AvgFillPrice()),
+			State:        string(a.Order.State),
+			Tag:          a.Order.Tag,
+			CreatedAt:    a.Order.Timestamp,
+			ClosedAt:     a.ClosedAt,
+		}
+	}
+
+	writeJSON(w, v1.OrderHistoryResponse{
+		UserID: userID,
+		Orders: items,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, http.StatusOK)
+
+	logger.Infof("Order history success - User: %s - Orders: %d/%d - Status: 200 - Duration: %v",
+		userID, len(items), total, time.Since(start))
+}
+
+// GetOrder godoc
+// @Summary Get an order
+// @Description Get one of a user's orders, resting or closed, with its fills. Only the most recent fills of very active orders are listed; counterparties are masked
+// @Tags Orders
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Param order_id query int true "Order ID"
+// @Success 200 {object} v1.OrderDetailResponse "Order retrieved successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 404 {object} v1.ErrorResponse "Order not found or owned by another user"
+// @Router /api/v1/orders/detail [get]
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	userID := query.Get("user_id")
+	if userID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("Get order - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	pairStr := query.Get("pair")
+	pair, err := h.parsePair(pairStr)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Get order - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	orderID, err := strconv.ParseInt(query.Get("order_id"), 10, 64)
+	if err != nil || orderID <= 0 {
+		writeError(w, "order_id must be a positive integer", http.StatusBadRequest)
+		logger.Warningf("Get order - invalid order_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	order, err := h.engine.GetOrder(userID, pair, orderID)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Get order failed - User: %s - OrderID: %d - Duration: %v - Error: %v",
+			userID, orderID, time.Since(start), err)
+		return
+	}
+
+	fills := make([]v1.FillResponse, len(order.Fills))
+	for i, f := range order.Fills {
+		fills[i] = v1.FillResponse{
+			TradeSeq:     f.TradeSeq,
+			Price:        v1.Fiat(f.Price),
+			Amount:       v1.Crypto(f.Amount),
+			Role:         f.Role,
+			Counterparty: maskUserID(f.CounterpartyID),
+			Tag:          f.Tag,
+			Timestamp:    f.Timestamp,
+		}
+	}
+
+	response := v1.OrderDetailResponse{
+		Order:        h.orderToResponse(&order.Order, pair.String()),
+		Fills:        fills,
+		FillCount:    order.FillCount,
+		AvgFillPrice: v1.Fiat(order.AvgFillPrice()),
+	}
+	if !order.ClosedAt.IsZero() {
+		response.ClosedAt = &order.ClosedAt
+	}
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Get order success - User: %s - OrderID: %d - Fills: %d - Status: 200 - Duration: %v",
+		userID, orderID, order.FillCount, time.Since(start))
+}
+
+// GetPosition godoc
+// @Summary Get a user's position
+// @Description Get a user's net position on a pair from their trades: base bought and sold, quote spent and received, average entry price (average-cost method) and realized PnL. Fees are not included
+// @Tags Accounts
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Success 200 {object} v1.PositionResponse "Position retrieved successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Router /api/v1/accounts/position [get]
+func (h *OrderHandler) GetPosition(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	userID := query.Get("user_id")
+	if userID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("Position - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.parsePair(query.Get("pair"))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Position - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	position, err := h.engine.Position(userID, pair)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Position failed - User: %s - Pair: %s - Duration: %v - Error: %v",
+			userID, pair, time.Since(start), err)
+		return
+	}
+
+	// A delisted pair keeps its trades but no longer has a listed config
+	cfg, err := h.engine.GetPairConfig(pair)
+	if err != nil {
+		cfg = engine.DefaultPairConfig(pair)
+	}
+
+	response := v1.PositionResponse{
+		UserID:        userID,
+		Pair:          pair.String(),
+		NetPosition:   v1.Crypto(utils.RoundToTick(position.Net(), cfg.AmountTick)),
+		Bought:        v1.Crypto(position.Bought),
+		Sold:          v1.Crypto(position.Sold),
+		QuoteSpent:    v1.Fiat(position.QuoteSpent),
+		QuoteReceived: v1.Fiat(position.QuoteReceived),
+		RealizedPnL:   v1.Fiat(utils.RoundToTick(position.RealizedPnL, cfg.PriceTick)),
+		Trades:        position.Trades,
+	}
+	if position.AvgEntryPrice != 0 {
+		entry := v1.Fiat(position.AvgEntryPrice)
+		response.AvgEntryPrice = &entry
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Position success - User: %s - Pair: %s - Trades: %d - Status: 200 - Duration: %v",
+		userID, pair, position.Trades, time.Since(start))
+}
+
+// GetPnL godoc
+// @Summary Get a user's realized PnL
+// @Description Get a user's realized PnL on a pair on the FIFO method: each sell closes the oldest open buys first, and selling without open buys opens a short that later buys close. Also reports the lots still open. Fees are not included
+// @Tags Accounts
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Success 200 {object} v1.PnLResponse "PnL retrieved successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Router /api/v1/accounts/pnl [get]
+func (h *OrderHandler) GetPnL(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	userID := query.Get("user_id")
+	if userID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("PnL - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.parsePair(query.Get("pair"))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("PnL - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	pnl, err := h.engine.RealizedPnL(userID, pair)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("PnL failed - User: %s - Pair: %s - Duration: %v - Error: %v",
+			userID, pair, time.Since(start), err)
+		return
+	}
+
+	response := v1.PnLResponse{
+		UserID:       userID,
+		Pair:         pair.String(),
+		Method:       "fifo",
+		RealizedPnL:  v1.Fiat(utils.RoundToTick(pnl.Realized, engine.PriceTick)),
+		Closed:       v1.Crypto(utils.RoundToTick(pnl.Closed, engine.AmountTick)),
+		OpenPosition: v1.Crypto(utils.RoundToTick(pnl.OpenPosition, engine.AmountTick)),
+		OpenCost:     v1.Fiat(utils.RoundToTick(pnl.OpenCost, engine.PriceTick)),
+		Trades:       pnl.Trades,
+	}
+	if avg := pnl.AvgOpenPrice(); avg != 0 {
+		price := v1.Fiat(avg)
+		response.AvgOpenPrice = &price
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("PnL success - User: %s - Pair: %s - Trades: %d - Status: 200 - Duration: %v",
+		userID, pair, pnl.Trades, time.Since(start))
+}
+
+// GetOrderFills godoc
+// @Summary Get an order's fills
+// @Description Get the trades that filled one of a user's orders, oldest first. Unlike /orders/detail every fill is kept; counterparties are masked
+// @Tags Orders
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Param id path int true "Order ID"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param offset query int false "Fills to skip"
+// @Success 200 {object} v1.OrderFillsResponse "Fills retrieved successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 404 {object} v1.ErrorResponse "Order not found"
+// @Router /api/v1/orders/{id}/fills [get]
+func (h *OrderHandler) GetOrderFills(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	userID := query.Get("user_id")
+	if userID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("Order fills - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.parsePair(query.Get("pair"))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Order fills - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	orderID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || orderID <= 0 {
+		writeError(w, "order id must be a positive integer", http.StatusBadRequest)
+		logger.Warningf("Order fills - invalid order id - Duration: %v", time.Since(start))
+		return
+	}
+
+	limit, err := parseIntParam(query.Get("limit"), defaultHistoryLimit, 1, maxHistoryLimit)
+	if err != nil {
+		writeError(w, "limit "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Order fills - invalid limit - Duration: %v", time.Since(start))
+		return
+	}
+	offset, err := parseIntParam(query.Get("offset"), 0, 0, math.MaxInt)
+	if err != nil {
+		writeError(w, "offset "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Order fills - invalid offset - Duration: %v", time.Since(start))
+		return
+	}
+
+	trades, total, err := h.engine.OrderTrades(userID, pair, orderID, limit, offset)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Order fills failed - User: %s - OrderID: %d - Duration: %v - Error: %v",
+			userID, orderID, time.Since(start), err)
+		return
+	}
+
+	fills := make([]v1.FillResponse, len(trades))
+	for i, t := range trades {
+		side, counterparty, tag := orderbook.Bid, t.SellerID, t.BidTag
+		if t.AskOrderID == orderID && t.SellerID == userID {
+			side, counterparty, tag = orderbook.Ask, t.BuyerID, t.AskTag
+		}
+		role := "maker"
+		if t.TakerSide == side {
+			role = "taker"
+		}
+		fills[i] = v1.FillResponse{
+			TradeSeq:     t.Seq,
+			Price:        v1.Fiat(t.Price),
+			Amount:       v1.Crypto(t.Amount),
+			Role:         role,
+			Counterparty: maskUserID(counterparty),
+			Tag:          tag,
+			Timestamp:    t.Timestamp,
+		}
+	}
+
+	writeJSON(w, v1.OrderFillsResponse{
+		OrderID: orderID,
+		Pair:    pair.String(),
+		Fills:   fills,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	}, http.StatusOK)
+
+	logger.Infof("Order fills success - User: %s - OrderID: %d - Fills: %d/%d - Status: 200 - Duration: %v",
+		userID, orderID, len(fills), total, time.Since(start))
+}
+
+// GetOrderStanding godoc
+// @Summary Estimate a resting order's completion
+// @Description Estimate how a resting order would fare against the book as it is now: the volume queued ahead of it on its side and how much would fill if it were resubmitted as a market order
+// @Tags Orders
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Param order_id query int true "Order ID"
+// @Success 200 {object} v1.OrderStandingResponse "Estimate computed successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 404 {object} v1.ErrorResponse "Order not resting"
+// @Router /api/v1/orders/standing [get]
+func (h *OrderHandler) GetOrderStanding(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	userID := query.Get("user_id")
+	if userID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("Order standing - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.parsePair(query.Get("pair"))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Order standing - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	orderID, err := strconv.ParseInt(query.Get("order_id"), 10, 64)
+	if err != nil || orderID <= 0 {
+		writeError(w, "order_id must be a positive integer", http.StatusBadRequest)
+		logger.Warningf("Order standing - invalid order_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	standing, err := h.engine.OrderStanding(userID, pair, orderID)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Order standing failed - User: %s - OrderID: %d - Duration: %v - Error: %v",
+			userID, orderID, time.Since(start), err)
+		return
+	}
+
+	avgPrice := 0.0
+	if standing.Fillable > 0 {
+		avgPrice = standing.FillableQuote / standing.Fillable
+	}
+
+	writeJSON(w, v1.OrderStandingResponse{
+		OrderID:          orderID,
+		Pair:             pair.String(),
+		Side:             string(standing.Side),
+		Price:            v1.Fiat(standing.Price),
+		Remaining:        v1.Crypto(standing.Remaining),
+		QueueAhead:       v1.Crypto(standing.QueueAhead),
+		FillableNow:      v1.Crypto(standing.Fillable),
+		FillableAvgPrice: v1.Fiat(avgPrice),
+	}, http.StatusOK)
+
+	logger.Infof("Order standing success - User: %s - OrderID: %d - Fillable: %.8f/%.8f - Status: 200 - Duration: %v",
+		userID, orderID, standing.Fillable, standing.Remaining, time.Since(start))
+}
+
+// GetOpenOrders godoc
+// @Summary List open orders for polling
+// @Description Resting orders of a user on a pair with filled and remaining amounts and their current queue position, all read at once. Send the returned ETag in If-None-Match to get 304 while nothing changed
+// @Tags Orders
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {object} v1.OpenOrdersResponse "Open orders retrieved successfully"
+// @Success 304 {string} string "Nothing changed since the given ETag"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Router /api/v1/orders/open [get]
+func (h *OrderHandler) GetOpenOrders(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	userID := query.Get("user_id")
+	if userID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("Open orders - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.parsePair(query.Get("pair"))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Open orders - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	open, err := h.engine.OpenOrders(userID, pair)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Open orders failed - User: %s - Pair: %s - Duration: %v - Error: %v",
+			userID, pair, time.Since(start), err)
+		return
+	}
+
+	response := v1.OpenOrdersResponse{Pair: pair.String(), Orders: make([]v1.OpenOrderResponse, 0, len(open))}
+	for _, o := range open {
+		response.Orders = append(response.Orders, v1.OpenOrderResponse{
+			ID:            o.Order.ID,
+			Side:          string(o.Order.Side),
+			Price:         v1.Fiat(o.Order.Price),
+			Amount:        v1.Crypto(o.Order.Amount),
+			FilledAmount:  v1.Crypto(o.Order.FilledAmount),
+			Remaining:     v1.Crypto(o.Standing.Remaining),
+			State:         string(o.Order.State),
+			QueuePosition: o.Standing.Position,
+			QueueAhead:    v1.Crypto(o.Standing.QueueAhead),
+			Timestamp:     o.Order.Timestamp,
+			Tag:           o.Order.Tag,
+		})
+	}
+
+	etag, err := responseETag(response)
+	if err != nil {
+		writeError(w, "failed to encode response", http.StatusInternalServerError)
+		logger.Errorf("Open orders - encode failed - User: %s - Error: %v", userID, err)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		logger.Infof("Open orders unchanged - User: %s - Pair: %s - Status: 304 - Duration: %v",
+			userID, pair, time.Since(start))
+		return
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Open orders success - User: %s - Pair: %s - Orders: %d - Status: 200 - Duration: %v",
+		userID, pair, len(open), time.Since(start))
+}
+
+// GetRequiredFunds godoc
+// @Summary Estimate the funds an order would lock
+// @Description Asset and amount that placing the order would lock right now: quote at price for limit bids, the amount for asks, and a book-walk estimate for market orders. Balances are not checked and nothing is locked
+// @Tags Orders
+// @Produce json
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Param side query string true "Order side (bid or ask)"
+// @Param type query string false "Order type (limit or market), default limit"
+// @Param price query string false "Limit price, required for limit orders"
+// @Param amount query string true "Order amount"
+// @Success 200 {object} v1.RequiredFundsResponse "Funds estimated successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request or not enough liquidity for a market order"
+// @Router /api/v1/orders/required-funds [get]
+func (h *OrderHandler) GetRequiredFunds(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	pair, err := h.parsePair(query.Get("pair"))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Required funds - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	side, err := h.parseSide(query.Get("side"))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Required funds - invalid side - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	orderType := orderbook.OrderType(query.Get("type"))
+	if orderType == "" {
+		orderType = orderbook.OrderTypeLimit
+	}
+	if orderType != orderbook.OrderTypeLimit && orderType != orderbook.OrderTypeMarket {
+		writeError(w, "type must be 'limit' or 'market'", http.StatusBadRequest)
+		logger.Warningf("Required funds - invalid type: %q - Duration: %v", orderType, time.Since(start))
+		return
+	}
+
+	amount, err := parsePriceParam(query.Get("amount"))
+	if err != nil {
+		writeError(w, "amount "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Required funds - invalid amount - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	price := 0.0
+	if orderType == orderbook.OrderTypeLimit {
+		if price, err = parsePriceParam(query.Get("price")); err != nil {
+			writeError(w, "price "+err.Error(), http.StatusBadRequest)
+			logger.Warningf("Required funds - invalid price - Duration: %v - Error: %v", time.Since(start), err)
+			return
+		}
+	}
+
+	funds, err := h.engine.RequiredFunds(pair, side, orderType, price, amount)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Required funds failed - Pair: %s - Side: %s - Type: %s - Duration: %v - Error: %v",
+			pair, side, orderType, time.Since(start), err)
+		return
+	}
+
+	writeJSON(w, v1.RequiredFundsResponse{
+		Pair:   pair.String(),
+		Side:   string(side),
+		Type:   string(orderType),
+		Asset:  funds.Asset,
+		Amount: v1.AssetAmount(funds.Asset, funds.Amount),
+	}, http.StatusOK)
+
+	logger.Infof("Required funds success - Pair: %s - Side: %s - Type: %s - %s: %.8f - Status: 200 - Duration: %v",
+		pair, side, orderType, funds.Asset, funds.Amount, time.Since(start))
+}
+
+// GetRejections godoc
+// @Summary List rejected orders
+// @Description Most recent orders of a user the engine refused, newest first, each with the error code it failed with. The same events are pushed to the user's order sessions
+// @Tags Orders
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param limit query int false "Rejections to return (1-100, default 100)"
+// @Success 200 {object} v1.RejectionsResponse "Rejections retrieved successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Router /api/v1/orders/rejections [get]
+func (h *OrderHandler) GetRejections(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	userID := query.Get("user_id")
+	if userID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("Rejections - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	limit, err := parseIntParam(query.Get("limit"), engine.MaxRejectionsPerUser, 1, engine.MaxRejectionsPerUser)
+	if err != nil {
+		writeError(w, "limit "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Rejections - invalid limit - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	rejections := h.engine.Rejections(userID)
+	if len(rejections) > limit {
+		rejections = rejections[:limit]
+	}
+
+	response := v1.RejectionsResponse{UserID: userID, Rejections: make([]v1.RejectionEvent, 0, len(rejections))}
+	for _, rejection := range rejections {
+		response.Rejections = append(response.Rejections, rejectionEvent(rejection))
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Rejections success - User: %s - Rejections: %d - Status: 200 - Duration: %v",
+		userID, len(rejections), time.Since(start))
+}
+
+// ReserveOrderID godoc
+// @Summary Reserve an order ID
+// @Description Allocate an order ID ahead of placement, to send as order_id on a later order before expires_at. Each ID can be used once, only by the user it was reserved for; an order rejected with it leaves it reserved
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param request body v1.ReserveOrderIDRequest true "User to reserve the ID for"
+// @Success 200 {object} v1.ReserveOrderIDResponse "Order ID reserved"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 429 {object} v1.ErrorResponse "Too many unused reserved IDs"
+// @Router /api/v1/orders/reserve-id [post]
+func (h *OrderHandler) ReserveOrderID(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var req v1.ReserveOrderIDRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeBodyError(w, err)
+		logger.Warningf("Reserve order ID - invalid JSON - Duration: %v", time.Since(start))
+		return
+	}
+	if req.UserID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warningf("Reserve order ID - missing user_id - Duration: %v", time.Since(start))
+		return
+	}
+
+	orderID, expiresAt, err := h.engine.ReserveOrderID(req.UserID)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Reserve order ID failed - User: %s - Duration: %v - Error: %v", req.UserID, time.Since(start), err)
+		return
+	}
+
+	writeJSON(w, v1.ReserveOrderIDResponse{UserID: req.UserID, OrderID: orderID, ExpiresAt: expiresAt}, http.StatusOK)
+
+	logger.Infof("Reserve order ID success - User: %s - OrderID: %d - Status: 200 - Duration: %v",
+		req.UserID, orderID, time.Since(start))
+}
+
+// rejectionEvent converts an engine rejection for the API. The requested
+// price and amount come from the caller unchecked, so values JSON cannot
+// carry are reported as zero.
+func rejectionEvent(r engine.Rejection) v1.RejectionEvent {
+	finite := func(v float64) float64 {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0
+		}
+		return v
+	}
+
+	reason, _ := errorCode(r.Err)
+	return v1.RejectionEvent{
+		Seq:       r.Seq,
+		Pair:      r.Pair.String(),
+		Side:      string(r.Side),
+		Type:      string(r.Type),
+		Price:     v1.Fiat(finite(r.Price)),
+		Amount:    v1.Crypto(finite(r.Amount)),
+		Tag:       r.Tag,
+		Reason:    reason,
+		Error:     r.Err.Error(),
+		Timestamp: r.Timestamp,
+	}
+}
+
 // Helper methods
 
+// responseETag returns a strong ETag for the JSON encoding of data.
+func responseETag(data interface{}) (string, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write(body)
+	return fmt.Sprintf("\"%016x\"", hash.Sum64()), nil
+}
+
+// maskUserID hides all but the first two characters of a user ID, or all of
+// it when it is too short for that to hide anything.
+func maskUserID(userID string) string {
+	if len(userID) <= 4 {
+		return "****"
+	}
+	return userID[:2] + strings.Repeat("*", len(userID)-2)
+}
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// parseIntParam parses an optional integer query parameter, returning def
+// when raw is empty.
+func parseIntParam(raw string, def, lo, hi int) (int, error) {
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, errors.New("must be an integer")
+	}
+	if v < lo || v > hi {
+		return 0, fmt.Errorf("must be between %d and %d", lo, hi)
+	}
+	return v, nil
+}
+
 func (h *OrderHandler) validatePlaceOrderRequest(req v1.PlaceOrderRequest) error {
 	if req.UserID == "" {
 		return errors.New("user_id is required")
@@ -183,12 +1110,58 @@ func (h *OrderHandler) validatePlaceOrderRequest(req v1.PlaceOrderRequest) error
 	if req.Type != "limit" && req.Type != "market" {
 		return errors.New("type must be 'limit' or 'market'")
 	}
-	if req.Amount <= 0 {
+	if !req.Amount.IsFinite() || !req.Price.IsFinite() || !req.AmountPct.IsFinite() {
+		return errors.New("price, amount and amount_pct must be finite numbers")
+	}
+	if req.AmountPct != 0 && req.Amount != 0 {
+		return errors.New("amount and amount_pct cannot both be set")
+	}
+	if req.AmountPct == 0 && req.Amount <= 0 {
 		return errors.New("amount must be greater than 0")
 	}
 	if req.Type == "limit" && req.Price <= 0 {
 		return errors.New("price must be greater than 0 for limit orders")
 	}
+	if req.Type == "market" && req.Hidden {
+		return errors.New("hidden is only supported for limit orders")
+	}
+	if req.Type == "limit" && req.RestRemainder {
+		return errors.New("rest_remainder is only supported for market orders")
+	}
+	if req.Type == "market" && req.ExpiresAt != nil {
+		return errors.New("expires_at is only supported for limit orders")
+	}
+	if req.Type == "market" && req.ImproveOnly {
+		return errors.New("improve_only is only supported for limit orders")
+	}
+	if req.WaitFor != "" {
+		wait, err := time.ParseDuration(req.WaitFor)
+		if err != nil || wait <= 0 || wait > engine.MaxOrderWait {
+			return fmt.Errorf("wait_for must be a positive duration of at most %v", engine.MaxOrderWait)
+		}
+	}
+	return nil
+}
+
+// checkPrecision rejects a price or amount written with more decimals than
+// the pair's tick, which the engine would otherwise round or reject without
+// saying why. Under the floor tick policy extra decimals are expected to be
+// floored away, so they are let through. A request without a price passes 0.
+func (h *OrderHandler) checkPrecision(pair engine.Pair, price, amount v1.Decimal) error {
+	if policy := h.engine.Config().TickPolicy; policy == "" || policy == engine.TickFloor {
+		return nil
+	}
+	cfg, err := h.engine.GetPairConfig(pair)
+	if err != nil {
+		return nil // the engine reports the unknown pair
+	}
+
+	if places := v1.Decimal(cfg.AmountTick).Places(); amount.Places() > places {
+		return fmt.Errorf("amount %s has more than the %d decimal places %s accepts", amount, places, pair)
+	}
+	if places := v1.Decimal(cfg.PriceTick).Places(); price.Places() > places {
+		return fmt.Errorf("price %s has more than the %d decimal places %s accepts", price, places, pair)
+	}
 	return nil
 }
 
@@ -203,8 +1176,11 @@ func (h *OrderHandler) parsePair(pairStr string) (engine.Pair, error) {
 		Quote: strings.ToUpper(parts[1]),
 	}
 
+	if pair.SameAsset() {
+		return engine.Pair{}, errors.New("invalid pair: base and quote must be different assets")
+	}
 	if !pair.IsValid() {
-		return engine.Pair{}, errors.New("invalid pair: quote must be BRL")
+		return engine.Pair{}, errors.New("invalid pair: base is required and quote must be BRL")
 	}
 
 	return pair, nil
@@ -219,43 +1195,81 @@ func (h *OrderHandler) parseSide(sideStr string) (orderbook.Side, error) {
 }
 
 func (h *OrderHandler) orderToResponse(order *orderbook.Order, pairStr string) v1.OrderResponse {
-	return v1.OrderResponse{
+	response := v1.OrderResponse{
 		ID:           order.ID,
 		UserID:       order.UserID,
 		Pair:         pairStr,
 		Side:         string(order.Side),
 		Type:         string(order.Type),
-		Price:        order.Price,
-		Amount:       order.Amount,
-		FilledAmount: order.FilledAmount,
+		Price:        v1.Fiat(order.Price),
+		Amount:       v1.Crypto(order.Amount),
+		FilledAmount: v1.Crypto(order.FilledAmount),
 		State:        string(order.State),
+		Hidden:       order.Hidden,
 		Timestamp:    order.Timestamp,
+		Tag:          order.Tag,
 	}
+	if !order.ExpiresAt.IsZero() {
+		expiresAt := order.ExpiresAt
+		response.ExpiresAt = &expiresAt
+	}
+	return response
 }
 
-func (h *OrderHandler) matchesToResponse(matches []orderbook.Match) []v1.MatchResponse {
+// matchesToResponse converts the fills of taker. The incoming order is always
+// the aggressor in this engine, so its side is the taker side of every fill.
+func (h *OrderHandler) matchesToResponse(taker *orderbook.Order, matches []orderbook.Match) []v1.MatchResponse {
 	result := make([]v1.MatchResponse, len(matches))
 	for i, m := range matches {
 		result[i] = v1.MatchResponse{
 			BidOrderID: m.Bid.ID,
 			AskOrderID: m.Ask.ID,
-			Price:      m.Price,
-			SizeFilled: m.SizeFilled,
+			Price:      v1.Fiat(m.Price),
+			SizeFilled: v1.Crypto(m.SizeFilled),
+			TakerSide:  string(taker.Side),
+			Role:       matchRole(m, taker.Side, taker.UserID),
 			Timestamp:  m.Timestamp,
+			Seq:        m.Seq,
+			PairSeq:    m.PairSeq,
+			Tag:        taker.Tag,
 		}
 	}
 	return result
 }
 
-func (h *OrderHandler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		logger.Errorf("Error encoding JSON response: %v", err)
+// tradesToMatches is matchesToResponse for the trades that filled order, as
+// OrderTrades returns them.
+func (h *OrderHandler) tradesToMatches(order *orderbook.Order, trades []engine.Trade) []v1.MatchResponse {
+	result := make([]v1.MatchResponse, len(trades))
+	for i, t := range trades {
+		role := "maker"
+		if t.TakerSide == order.Side {
+			role = "taker"
+		}
+		result[i] = v1.MatchResponse{
+			BidOrderID: t.BidOrderID,
+			AskOrderID: t.AskOrderID,
+			Price:      v1.Fiat(t.Price),
+			SizeFilled: v1.Crypto(t.Amount),
+			TakerSide:  string(t.TakerSide),
+			Role:       role,
+			Timestamp:  t.Timestamp,
+			Seq:        t.Seq,
+			PairSeq:    t.PairSeq,
+			Tag:        order.Tag,
+		}
 	}
+	return result
 }
 
-func (h *OrderHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
-	h.sendJSON(w, v1.ErrorResponse{Error: message}, statusCode)
+// matchRole reports whether userID was the taker or the maker of m.
+func matchRole(m orderbook.Match, takerSide orderbook.Side, userID string) string {
+	takerOrder := m.Bid
+	if takerSide == orderbook.Ask {
+		takerOrder = m.Ask
+	}
+	if takerOrder.UserID == userID {
+		return "taker"
+	}
+	return "maker"
 }
@@ -0,0 +1,1018 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func TestOrderHandler_PlaceOrder_DecimalStrings(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	body := json.RawMessage(`{"user_id":"2","pair":"BTC/BRL","side":"ask","type":"limit","price":"50000.01","amount":"0.00000001"}`)
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	raw := rec.Body.String()
+	assertTrue(t, strings.Contains(raw, `"price":"50000.01"`), "Price returned as exact string")
+	assertTrue(t, strings.Contains(raw, `"amount":"0.00000001"`), "Amount returned as exact string")
+
+	var resp v1.PlaceOrderResponse
+	decodeBody(t, rec, &resp)
+	assertFloat(t, 0.00000001, resp.Order.Amount.Float64(), "Decoded amount")
+
+	locked := e.GetAccountManager().GetBalance("2", "BTC").Locked
+	assertFloat(t, 0.00000001, locked, "Locked amount matches request")
+}
+
+func TestOrderHandler_PlaceOrder_NumericInputStillAccepted(t *testing.T) {
+	h := NewOrderHandler(setupEngine())
+
+	body := json.RawMessage(`{"user_id":"1","pair":"BTC/BRL","side":"bid","type":"limit","price":50000,"amount":0.5}`)
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.PlaceOrderResponse
+	decodeBody(t, rec, &resp)
+	assertFloat(t, 50_000, resp.Order.Price.Float64(), "Price")
+	assertFloat(t, 0.5, resp.Order.Amount.Float64(), "Amount")
+}
+
+func TestOrderHandler_PlaceOrder_InvalidDecimal(t *testing.T) {
+	h := NewOrderHandler(setupEngine())
+
+	body := json.RawMessage(`{"user_id":"1","pair":"BTC/BRL","side":"bid","type":"limit","price":"fifty","amount":"1"}`)
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Status code")
+}
+
+func TestOrderHandler_PlaceOrder_MatchRole(t *testing.T) {
+	tests := []struct {
+		name      string
+		makerSide orderbook.Side
+		takerSide string
+	}{
+		{"buy aggressor", orderbook.Ask, "bid"},
+		{"sell aggressor", orderbook.Bid, "ask"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := setupEngine()
+			_, _, err := e.PlaceOrder("1", btcBrl(), tt.makerSide, 50_000, 0.5)
+			assertNoError(t, err)
+
+			h := NewOrderHandler(e)
+			req := v1.PlaceOrderRequest{UserID: "2", Pair: "BTC/BRL", Side: tt.takerSide, Type: "limit", Price: 50_000, Amount: 0.5}
+			rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", req)
+			assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+			var resp v1.PlaceOrderResponse
+			decodeBody(t, rec, &resp)
+			assertEqual(t, 1, len(resp.Matches), "Matches")
+			assertEqual(t, tt.takerSide, resp.Matches[0].TakerSide, "Taker side")
+			assertEqual(t, "taker", resp.Matches[0].Role, "Role of requesting user")
+			assertEqual(t, uint64(1), resp.Matches[0].Seq, "Global trade sequence")
+			assertEqual(t, uint64(1), resp.Matches[0].PairSeq, "Pair trade sequence")
+		})
+	}
+}
+
+func TestMatchRole(t *testing.T) {
+	bid, _ := orderbook.NewOrder("1", orderbook.Bid, 50_000, 1)
+	ask, _ := orderbook.NewOrder("2", orderbook.Ask, 50_000, 1)
+	m := orderbook.Match{Bid: bid, Ask: ask, Price: 50_000, SizeFilled: 1}
+
+	assertEqual(t, "taker", matchRole(m, orderbook.Bid, "1"), "Bid owner as aggressor")
+	assertEqual(t, "maker", matchRole(m, orderbook.Bid, "2"), "Resting ask owner")
+	assertEqual(t, "taker", matchRole(m, orderbook.Ask, "2"), "Ask owner as aggressor")
+	assertEqual(t, "maker", matchRole(m, orderbook.Ask, "1"), "Resting bid owner")
+}
+
+func TestOrderHandler_CancelAllOrdersGlobal(t *testing.T) {
+	e := setupEngine()
+	ethBrl := engine.Pair{Base: "ETH", Quote: "BRL"}
+	accounts := e.GetAccountManager()
+	_ = accounts.Credit("1", "ETH", 5)
+
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", ethBrl, orderbook.Ask, 20_000, 2)
+	assertNoError(t, err)
+
+	h := NewOrderHandler(e)
+	rec := doRequest(h.CancelAllOrdersGlobal, http.MethodPost, "/api/v1/orders/cancel-all-global", v1.CancelAllOrdersRequest{UserID: "1"})
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.CancelAllOrdersResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 2, resp.Count, "Cancelled count")
+	assertEqual(t, "BTC/BRL", resp.Cancelled[0].Pair, "First pair")
+	assertEqual(t, "ETH/BRL", resp.Cancelled[1].Pair, "Second pair")
+	for _, o := range resp.Cancelled {
+		assertEqual(t, "cancelled", o.State, "Order state")
+	}
+
+	assertFloat(t, 0, accounts.GetBalance("1", "BRL").Locked, "BRL unlocked")
+	assertFloat(t, 0, accounts.GetBalance("1", "ETH").Locked, "ETH unlocked")
+
+	rec = doRequest(h.CancelAllOrdersGlobal, http.MethodPost, "/api/v1/orders/cancel-all-global", v1.CancelAllOrdersRequest{})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Missing user_id")
+}
+
+func TestOrderHandler_GetOrderHistory(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 1.0)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_000, 1.0)
+	assertNoError(t, err)
+	bid, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 0.5)
+	assertNoError(t, err)
+	_, err = e.CancelOrder("1", btcBrl(), bid.ID)
+	assertNoError(t, err)
+
+	h := NewOrderHandler(e)
+	rec := doRequest(h.GetOrderHistory, http.MethodGet, "/api/v1/orders/history?user_id=1&pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.OrderHistoryResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 2, resp.Total, "Total")
+	assertEqual(t, 2, len(resp.Orders), "Orders")
+
+	assertEqual(t, "cancelled", resp.Orders[0].State, "Newest is the cancelled bid")
+	assertFloat(t, 0, resp.Orders[0].FilledAmount.Float64(), "Cancelled filled amount")
+	assertFloat(t, 0, resp.Orders[0].AvgFillPrice.Float64(), "No fills, no average")
+
+	assertEqual(t, "filled", resp.Orders[1].State, "Then the filled ask")
+	assertFloat(t, 1.0, resp.Orders[1].FilledAmount.Float64(), "Filled amount")
+	assertFloat(t, 50_000, resp.Orders[1].AvgFillPrice.Float64(), "Average fill price")
+}
+
+func TestOrderHandler_GetOrderHistory_InvalidParams(t *testing.T) {
+	h := NewOrderHandler(setupEngine())
+
+	targets := []string{
+		"/api/v1/orders/history",
+		"/api/v1/orders/history?user_id=1&limit=0",
+		"/api/v1/orders/history?user_id=1&limit=abc",
+		"/api/v1/orders/history?user_id=1&offset=-1",
+		"/api/v1/orders/history?user_id=1&pair=BTC",
+	}
+	for _, target := range targets {
+		rec := doRequest(h.GetOrderHistory, http.MethodGet, target, nil)
+		assertEqual(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
+
+func TestOrderHandler_GetOrder_Fills(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.4)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 51_000, 0.6)
+	assertNoError(t, err)
+	bid, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 51_000, 1.0)
+	assertNoError(t, err)
+
+	h := NewOrderHandler(e)
+	target := fmt.Sprintf("/api/v1/orders/detail?user_id=1&pair=BTC/BRL&order_id=%d", bid.ID)
+	rec := doRequest(h.GetOrder, http.MethodGet, target, nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.OrderDetailResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "filled", resp.Order.State, "State")
+	assertTrue(t, resp.ClosedAt != nil, "Closed at set")
+	assertEqual(t, 2, resp.FillCount, "Fill count")
+	assertEqual(t, 2, len(resp.Fills), "Fills")
+	assertFloat(t, 50_000, resp.Fills[0].Price.Float64(), "First fill price")
+	assertFloat(t, 51_000, resp.Fills[1].Price.Float64(), "Second fill price")
+	assertEqual(t, "****", resp.Fills[0].Counterparty, "Counterparty masked")
+	assertEqual(t, "taker", resp.Fills[0].Role, "Role")
+}
+
+func TestOrderHandler_GetOrder_Errors(t *testing.T) {
+	e := setupEngine()
+	ask, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 1.0)
+	assertNoError(t, err)
+	h := NewOrderHandler(e)
+
+	cases := []struct {
+		target string
+		status int
+	}{
+		{"/api/v1/orders/detail?pair=BTC/BRL&order_id=1", http.StatusBadRequest},
+		{"/api/v1/orders/detail?user_id=1&pair=BTC&order_id=1", http.StatusBadRequest},
+		{"/api/v1/orders/detail?user_id=1&pair=BTC/BRL&order_id=abc", http.StatusBadRequest},
+		{"/api/v1/orders/detail?user_id=1&pair=BTC/BRL&order_id=999", http.StatusNotFound},
+		{fmt.Sprintf("/api/v1/orders/detail?user_id=1&pair=BTC/BRL&order_id=%d", ask.ID), http.StatusNotFound},
+	}
+	for _, c := range cases {
+		rec := doRequest(h.GetOrder, http.MethodGet, c.target, nil)
+		assertEqual(t, c.status, rec.Code, c.target)
+	}
+}
+
+func TestMaskUserID(t *testing.T) {
+	assertEqual(t, "****", maskUserID("1"), "Short ID")
+	assertEqual(t, "****", maskUserID("abcd"), "Four characters")
+	assertEqual(t, "al***", maskUserID("alice"), "Long ID")
+}
+
+func TestOrderHandler_Nonce(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	body := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 50_000, Amount: 0.1, Nonce: 2}
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusOK, rec.Code, "First nonce accepted")
+
+	var placed v1.PlaceOrderResponse
+	decodeBody(t, rec, &placed)
+
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusConflict, rec.Code, "Replay rejected")
+	assertTrue(t, strings.Contains(rec.Body.String(), "STALE_NONCE"), "Stale nonce code")
+
+	cancel := v1.CancelOrderRequest{UserID: "1", Pair: "BTC/BRL", OrderID: placed.Order.ID, Nonce: 1}
+	rec = doRequest(h.CancelOrder, http.MethodPost, "/api/v1/orders/cancel", cancel)
+	assertEqual(t, http.StatusConflict, rec.Code, "Lower nonce on cancel rejected")
+
+	cancel.Nonce = 3
+	rec = doRequest(h.CancelOrder, http.MethodPost, "/api/v1/orders/cancel", cancel)
+	assertEqual(t, http.StatusOK, rec.Code, "Higher nonce on cancel accepted")
+}
+
+func TestOrderHandler_CancelOrder_ReportsUnlocked(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 0.5)
+	assertNoError(t, err)
+
+	rec := doRequest(h.CancelOrder, http.MethodPost, "/api/v1/orders/cancel", v1.CancelOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", OrderID: order.ID,
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	assertTrue(t, strings.Contains(rec.Body.String(), `"unlocked_amount":"20000.00"`), "Fiat amount rendered")
+
+	var resp v1.OrderResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "cancelled", resp.State, "State")
+	assertEqual(t, "BRL", resp.UnlockedAsset, "Unlocked asset")
+	assertFloat(t, 20_000, resp.UnlockedAmount.Float64(), "Unlocked amount")
+}
+
+func TestOrderHandler_PlaceOrder_Precision(t *testing.T) {
+	overAmount := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "ask", Type: "limit", Price: 50_000, Amount: 0.123456789}
+	overPrice := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "ask", Type: "limit", Price: 50_000.019, Amount: 0.1}
+
+	// Floor: extra decimals are floored away
+	h := NewOrderHandler(setupEngine())
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", overAmount)
+	assertEqual(t, http.StatusOK, rec.Code, "Floored amount accepted")
+	var resp v1.PlaceOrderResponse
+	decodeBody(t, rec, &resp)
+	assertFloat(t, 0.12345678, resp.Order.Amount.Float64(), "Amount floored")
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", overPrice)
+	assertEqual(t, http.StatusOK, rec.Code, "Floored price accepted")
+
+	for _, policy := range []engine.TickPolicy{engine.TickRound, engine.TickReject} {
+		e := engine.NewEngineWithConfig(engine.Config{TickPolicy: policy})
+		_ = e.GetAccountManager().Credit("1", "BTC", 10)
+		h := NewOrderHandler(e)
+
+		rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", overAmount)
+		assertEqual(t, http.StatusBadRequest, rec.Code, string(policy)+": over-precise amount rejected")
+		assertTrue(t, strings.Contains(rec.Body.String(), "amount 0.123456789 has more than the 8 decimal places"), string(policy)+": amount message")
+
+		rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", overPrice)
+		assertEqual(t, http.StatusBadRequest, rec.Code, string(policy)+": over-precise price rejected")
+		assertTrue(t, strings.Contains(rec.Body.String(), "price 50000.019 has more than the 2 decimal places"), string(policy)+": price message")
+
+		exact := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "ask", Type: "limit", Price: 50_000.01, Amount: 0.12345678}
+		rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", exact)
+		assertEqual(t, http.StatusOK, rec.Code, string(policy)+": values within the tick accepted")
+	}
+}
+
+func TestOrderHandler_PlaceOrder_WaitFor(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+
+	// Filled on placement: nothing to wait for
+	body := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 50_000, Amount: 0.25, WaitFor: "5s"}
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	var resp v1.PlaceOrderResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "filled", resp.Order.State, "Filled at once")
+
+	// Partially filled, the rest cancelled at the timeout
+	body.Amount, body.WaitFor = 1, "20ms"
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	resp = v1.PlaceOrderResponse{}
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "cancelled", resp.Order.State, "Cancelled at the timeout")
+	assertFloat(t, 0.25, resp.Order.FilledAmount.Float64(), "Fills kept")
+	assertEqual(t, 1, len(resp.Matches), "Placement matches")
+	assertFloat(t, 37_500, resp.Order.UnlockedAmount.Float64(), "Remaining quote unlocked")
+	assertEqual(t, 0, len(e.GetOrderbook(btcBrl()).Bids()), "Nothing left resting")
+
+	for _, waitFor := range []string{"soon", "-1s", "1m"} {
+		body.WaitFor = waitFor
+		rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+		assertEqual(t, http.StatusBadRequest, rec.Code, "Invalid wait_for "+waitFor)
+	}
+}
+
+func TestOrderHandler_PlaceOrder_WaitFor_FilledWhileWaiting(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.25)
+	assertNoError(t, err)
+
+	// Fill the rest once the bid rests and the handler is waiting on it
+	go func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for len(e.GetOrderbook(btcBrl()).Bids()) == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.75)
+	}()
+
+	body := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 50_000, Amount: 1, WaitFor: "5s"}
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	var resp v1.PlaceOrderResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "filled", resp.Order.State, "Filled while waiting")
+	assertEqual(t, 2, len(resp.Matches), "Placement and later matches")
+
+	filled := 0.0
+	for _, m := range resp.Matches {
+		filled += m.SizeFilled.Float64()
+	}
+	assertFloat(t, resp.Order.FilledAmount.Float64(), filled, "Matches add up to the filled amount")
+	assertEqual(t, "taker", resp.Matches[0].Role, "Took on placement")
+	assertEqual(t, "maker", resp.Matches[1].Role, "Made while resting")
+}
+
+func TestOrderHandler_PlaceOrder_ImproveOnly(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 0.1)
+	assertNoError(t, err)
+
+	body := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 49_000, Amount: 0.1, ImproveOnly: true}
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusConflict, rec.Code, "At the touch")
+	assertTrue(t, strings.Contains(rec.Body.String(), CodeWouldNotImprove), "Error code")
+
+	body.Price = 49_000.01
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusOK, rec.Code, "One tick better")
+
+	body = v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "market", Amount: 0.1, ImproveOnly: true}
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Limit only")
+}
+
+func TestOrderHandler_PlaceOrder_ExpiresAt(t *testing.T) {
+	h := NewOrderHandler(setupEngine())
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	body := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 40_000, Amount: 0.1, ExpiresAt: &expiresAt}
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.PlaceOrderResponse
+	decodeBody(t, rec, &resp)
+	assertTrue(t, resp.Order.ExpiresAt != nil && resp.Order.ExpiresAt.Equal(expiresAt), "Expiry echoed")
+
+	past := time.Now().Add(-time.Hour)
+	body.ExpiresAt = &past
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Past expiry rejected")
+	assertTrue(t, strings.Contains(rec.Body.String(), "INVALID_EXPIRY"), "Invalid expiry code")
+
+	body = v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "market", Amount: 0.1, ExpiresAt: &expiresAt}
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Market order with expiry rejected")
+}
+
+func TestOrderHandler_PlaceOrder_NonFinite(t *testing.T) {
+	bodies := []string{
+		`{"user_id":"1","pair":"BTC/BRL","side":"bid","type":"limit","price":1e400,"amount":"1"}`,
+		`{"user_id":"1","pair":"BTC/BRL","side":"bid","type":"limit","price":"1e400","amount":"1"}`,
+		`{"user_id":"1","pair":"BTC/BRL","side":"bid","type":"limit","price":"50000","amount":"NaN"}`,
+		`{"user_id":"1","pair":"BTC/BRL","side":"bid","type":"market","amount":"-Inf"}`,
+	}
+
+	for _, body := range bodies {
+		e := setupEngine()
+		h := NewOrderHandler(e)
+
+		rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", json.RawMessage(body))
+		assertEqual(t, http.StatusBadRequest, rec.Code, "Status code for "+body)
+
+		balance := e.GetAccountManager().GetBalance("1", "BRL")
+		assertFloat(t, 100_000, balance.Available, "Available unchanged for "+body)
+		assertFloat(t, 0, balance.Locked, "Nothing locked for "+body)
+		assertEqual(t, 0, e.GetOrderbook(btcBrl()).OpenOrderTotal(), "Book unchanged for "+body)
+	}
+}
+
+func TestValidatePlaceOrderRequest_NonFinite(t *testing.T) {
+	h := NewOrderHandler(setupEngine())
+
+	req := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 50_000, Amount: 1}
+
+	nan := req
+	nan.Amount = v1.Decimal(math.NaN())
+	assertTrue(t, h.validatePlaceOrderRequest(nan) != nil, "NaN amount rejected")
+
+	inf := req
+	inf.Price = v1.Decimal(math.Inf(1))
+	assertTrue(t, h.validatePlaceOrderRequest(inf) != nil, "Inf price rejected")
+}
+
+func TestOrderHandler_PlaceOrder_QueuePosition(t *testing.T) {
+	e := setupEngine()
+	_ = e.GetAccountManager().Credit("3", "BTC", 10)
+	_ = e.GetAccountManager().Credit("4", "BRL", 100_000)
+	h := NewOrderHandler(e)
+
+	var resp v1.PlaceOrderResponse
+	for i, user := range []string{"1", "2", "3"} {
+		rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+			UserID: user, Pair: "BTC/BRL", Side: "ask", Type: "limit", Price: 50_000, Amount: 1,
+		})
+		assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+		resp = v1.PlaceOrderResponse{}
+		decodeBody(t, rec, &resp)
+		assertEqual(t, i+1, resp.Order.QueuePosition, "Queue position of order "+user)
+	}
+	assertEqual(t, 2, resp.Order.OrdersAhead, "Orders ahead of the third order")
+
+	// A fully matched order never rests and reports no position
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "4", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 50_000, Amount: 0.5,
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	assertTrue(t, !strings.Contains(rec.Body.String(), "queue_position"), "No queue position when fully matched")
+}
+
+func TestOrderHandler_PlaceOrder_RestRemainder(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	if err != nil {
+		t.Fatalf("place order: %v", err)
+	}
+
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "market", Amount: 1, RestRemainder: true,
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.PlaceOrderResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "limit", resp.Order.Type, "Remainder rests as a limit")
+	assertFloat(t, 50_000, resp.Order.Price.Float64(), "At the last execution price")
+	assertEqual(t, 1, resp.Order.QueuePosition, "Resting remainder reports its position")
+
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 50_000, Amount: 1, RestRemainder: true,
+	})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "rest_remainder rejected on limit orders")
+}
+
+func TestOrderHandler_AmendOrder(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+
+	rec := doRequest(h.AmendOrder, http.MethodPost, "/api/v1/orders/amend", v1.AmendOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", OrderID: order.ID, Amount: 0.4,
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.OrderResponse
+	decodeBody(t, rec, &resp)
+	assertFloat(t, 0.4, resp.Amount.Float64(), "Amended amount")
+	assertFloat(t, 20_000, e.GetAccountManager().GetBalance("1", "BRL").Locked, "Lock reduced")
+
+	rec = doRequest(h.AmendOrder, http.MethodPost, "/api/v1/orders/amend", v1.AmendOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", OrderID: order.ID, Amount: 0.8,
+	})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Increase rejected")
+	var errResp v1.ErrorResponse
+	decodeBody(t, rec, &errResp)
+	assertEqual(t, CodeInvalidAmend, errResp.Code, "Error code")
+}
+
+func TestOrderHandler_AmendOrder_Precision(t *testing.T) {
+	e := engine.NewEngineWithConfig(engine.Config{TickPolicy: engine.TickReject})
+	_ = e.GetAccountManager().Credit("1", "BTC", 10)
+	h := NewOrderHandler(e)
+
+	order, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+
+	rec := doRequest(h.AmendOrder, http.MethodPost, "/api/v1/orders/amend", v1.AmendOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", OrderID: order.ID, Amount: 0.123456789,
+	})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Over-precise amount rejected")
+	assertTrue(t, strings.Contains(rec.Body.String(), "amount 0.123456789 has more than the 8 decimal places"), "Amount message")
+	assertFloat(t, 1, order.Amount, "Order untouched")
+}
+
+// orderFillsRoute is the pattern the server serves GetOrderFills at.
+const orderFillsRoute = "/api/v1/orders/{id}/fills"
+
+func TestOrderHandler_GetOrderFills(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 51_000, 0.5)
+	assertNoError(t, err)
+	bid, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 51_000, 1)
+	assertNoError(t, err)
+
+	target := fmt.Sprintf("/api/v1/orders/%d/fills?user_id=1&pair=BTC/BRL&limit=1&offset=1", bid.ID)
+	rec := doRoutedRequest(orderFillsRoute, h.GetOrderFills, http.MethodGet, target, nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.OrderFillsResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 2, resp.Total, "Total fills")
+	assertEqual(t, 1, len(resp.Fills), "Page size")
+	assertFloat(t, 51_000, resp.Fills[0].Price.Float64(), "Second level")
+	assertEqual(t, "taker", resp.Fills[0].Role, "Role")
+	assertEqual(t, "****", resp.Fills[0].Counterparty, "Masked counterparty")
+
+	target = fmt.Sprintf("/api/v1/orders/%d/fills?user_id=2&pair=BTC/BRL", bid.ID)
+	rec = doRoutedRequest(orderFillsRoute, h.GetOrderFills, http.MethodGet, target, nil)
+	assertEqual(t, http.StatusNotFound, rec.Code, "Other user's closed order")
+
+	rec = doRoutedRequest(orderFillsRoute, h.GetOrderFills, http.MethodGet, "/api/v1/orders/x/fills?user_id=1&pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Invalid order id")
+}
+
+func TestOrderHandler_GetOrderStanding(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+	bid, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 51_000, 0.25)
+	assertNoError(t, err)
+
+	target := fmt.Sprintf("/api/v1/orders/standing?user_id=1&pair=BTC/BRL&order_id=%d", bid.ID)
+	rec := doRequest(h.GetOrderStanding, http.MethodGet, target, nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.OrderStandingResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "bid", resp.Side, "Side")
+	assertFloat(t, 1, resp.Remaining.Float64(), "Remaining")
+	assertFloat(t, 0.5, resp.QueueAhead.Float64(), "Queue ahead")
+	assertFloat(t, 0.25, resp.FillableNow.Float64(), "Fillable now")
+	assertFloat(t, 51_000, resp.FillableAvgPrice.Float64(), "Fillable average price")
+
+	target = fmt.Sprintf("/api/v1/orders/standing?user_id=2&pair=BTC/BRL&order_id=%d", bid.ID)
+	rec = doRequest(h.GetOrderStanding, http.MethodGet, target, nil)
+	assertEqual(t, http.StatusNotFound, rec.Code, "Other user's order not revealed")
+
+	rec = doRequest(h.GetOrderStanding, http.MethodGet, "/api/v1/orders/standing?user_id=1&pair=BTC/BRL&order_id=999", nil)
+	assertEqual(t, http.StatusNotFound, rec.Code, "Not resting")
+}
+
+func TestOrderHandler_GetOpenOrders_AfterPartialFills(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+	_ = e.GetAccountManager().Credit("3", "BRL", 100_000)
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	target, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.25)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_100, 0.5)
+	assertNoError(t, err)
+	second, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 50_100, 0.125)
+	assertNoError(t, err)
+
+	// Takes all of user 2's first ask and a quarter of the target
+	_, _, err = e.PlaceOrder("3", btcBrl(), orderbook.Bid, 50_000, 0.75)
+	assertNoError(t, err)
+
+	path := "/api/v1/orders/open?user_id=1&pair=BTC/BRL"
+	rec := doRequest(h.GetOpenOrders, http.MethodGet, path, nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	etag := rec.Header().Get("ETag")
+	assertTrue(t, etag != "", "ETag set")
+
+	var resp v1.OpenOrdersResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 2, len(resp.Orders), "Both orders open")
+
+	first := resp.Orders[0]
+	assertEqual(t, target.ID, first.ID, "Oldest first")
+	assertFloat(t, 0.25, first.FilledAmount.Float64(), "Filled")
+	assertFloat(t, 0.75, first.Remaining.Float64(), "Remaining")
+	assertEqual(t, string(orderbook.OrderPartiallyFilled), first.State, "State")
+	assertEqual(t, 1, first.QueuePosition, "Moved to the front of its level")
+	assertFloat(t, 0, first.QueueAhead.Float64(), "Nothing ahead")
+
+	other := resp.Orders[1]
+	assertEqual(t, second.ID, other.ID, "Second order")
+	assertFloat(t, 0.125, other.Remaining.Float64(), "Untouched")
+	assertEqual(t, 2, other.QueuePosition, "Behind user 2 at 50100")
+	assertFloat(t, 1.5, other.QueueAhead.Float64(), "Level 50000 plus user 2 at 50100")
+
+	conditional := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("If-None-Match", etag)
+		rec := httptest.NewRecorder()
+		h.GetOpenOrders(rec, req)
+		return rec
+	}
+
+	rec = conditional()
+	assertEqual(t, http.StatusNotModified, rec.Code, "Unchanged since the ETag")
+	assertEqual(t, 0, rec.Body.Len(), "No body")
+
+	_, _, err = e.PlaceOrder("3", btcBrl(), orderbook.Bid, 50_000, 0.25)
+	assertNoError(t, err)
+
+	rec = conditional()
+	assertEqual(t, http.StatusOK, rec.Code, "Changed after another fill")
+	assertTrue(t, rec.Header().Get("ETag") != etag, "New ETag")
+
+	rec = doRequest(h.GetOpenOrders, http.MethodGet, "/api/v1/orders/open?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Missing user_id")
+}
+
+func TestOrderHandler_GetRequiredFunds(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 0.5)
+	assertNoError(t, err)
+
+	cases := []struct {
+		query  string
+		asset  string
+		amount string
+	}{
+		{"side=bid&price=50000&amount=0.25", "BRL", "12500.00"},
+		{"side=ask&type=limit&price=52000&amount=0.25", "BTC", "0.25000000"},
+		{"side=bid&type=market&amount=0.25", "BRL", "12500.00"},
+		{"side=ask&type=market&amount=0.25", "BTC", "0.25000000"},
+	}
+	for _, c := range cases {
+		rec := doRequest(h.GetRequiredFunds, http.MethodGet, "/api/v1/orders/required-funds?pair=BTC/BRL&"+c.query, nil)
+		assertEqual(t, http.StatusOK, rec.Code, c.query)
+
+		var resp v1.RequiredFundsResponse
+		decodeBody(t, rec, &resp)
+		assertEqual(t, c.asset, resp.Asset, c.query+" asset")
+		assertEqual(t, c.amount, resp.Amount.String(), c.query+" amount")
+	}
+
+	rec := doRequest(h.GetRequiredFunds, http.MethodGet, "/api/v1/orders/required-funds?pair=BTC/BRL&side=bid&type=market&amount=1", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Not enough liquidity")
+	var errResp v1.ErrorResponse
+	decodeBody(t, rec, &errResp)
+	assertEqual(t, CodeInsufficientLiquidity, errResp.Code, "Error code")
+
+	rec = doRequest(h.GetRequiredFunds, http.MethodGet, "/api/v1/orders/required-funds?pair=BTC/BRL&side=bid&amount=1", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Limit without price")
+
+	rec = doRequest(h.GetRequiredFunds, http.MethodGet, "/api/v1/orders/required-funds?pair=BTC/BRL&side=bid&type=stop&amount=1", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Unknown type")
+}
+
+func TestOrderHandler_PlaceOrder_TagRoundTrip(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "2", Pair: "BTC/BRL", Side: "ask", Type: "limit", Price: 50_000, Amount: 0.5, Tag: "maker-grid-3",
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Maker placed")
+	var maker v1.PlaceOrderResponse
+	decodeBody(t, rec, &maker)
+	assertEqual(t, "maker-grid-3", maker.Order.Tag, "Tag echoed on the order")
+
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "market", Amount: 0.5, Tag: "hedge-42",
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Taker placed")
+	var taker v1.PlaceOrderResponse
+	decodeBody(t, rec, &taker)
+	assertEqual(t, "hedge-42", taker.Order.Tag, "Taker tag")
+	assertEqual(t, 1, len(taker.Matches), "One match")
+	assertEqual(t, "hedge-42", taker.Matches[0].Tag, "Tag on the fill")
+
+	// Each side sees its own tag on its fills, never the counterparty's
+	rec = doRequest(h.GetOrder, http.MethodGet,
+		fmt.Sprintf("/api/v1/orders/detail?user_id=2&pair=BTC/BRL&order_id=%d", maker.Order.ID), nil)
+	var detail v1.OrderDetailResponse
+	decodeBody(t, rec, &detail)
+	assertEqual(t, "maker-grid-3", detail.Order.Tag, "Tag on the archived order")
+	assertEqual(t, "maker-grid-3", detail.Fills[0].Tag, "Maker fill tag")
+
+	rec = doRoutedRequest(orderFillsRoute, h.GetOrderFills, http.MethodGet,
+		fmt.Sprintf("/api/v1/orders/%d/fills?user_id=1&pair=BTC/BRL", taker.Order.ID), nil)
+	var fills v1.OrderFillsResponse
+	decodeBody(t, rec, &fills)
+	assertEqual(t, "hedge-42", fills.Fills[0].Tag, "Taker fill tag")
+
+	rec = doRequest(h.GetOrderHistory, http.MethodGet, "/api/v1/orders/history?user_id=1", nil)
+	var history v1.OrderHistoryResponse
+	decodeBody(t, rec, &history)
+	assertEqual(t, "hedge-42", history.Orders[0].Tag, "Tag in the history")
+
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 40_000, Amount: 0.1,
+		Tag: strings.Repeat("x", engine.MaxTagLength+1),
+	})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Tag too long")
+	var errResp v1.ErrorResponse
+	decodeBody(t, rec, &errResp)
+	assertEqual(t, CodeTagTooLong, errResp.Code, "Error code")
+}
+
+func TestOrderHandler_PlaceOrder_AmountPct(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+	base := e.GetAccountManager().GetBalance("2", "BTC").Available
+
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "2", Pair: "BTC/BRL", Side: "ask", Type: "limit", Price: 50_000, AmountPct: 100,
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Sell everything")
+	var sell v1.PlaceOrderResponse
+	decodeBody(t, rec, &sell)
+	assertFloat(t, base, sell.ResolvedAmount.Float64(), "Resolved to the whole balance")
+	assertFloat(t, base, sell.Order.Amount.Float64(), "Order for that amount")
+	assertFloat(t, 0, e.GetAccountManager().GetBalance("2", "BTC").Available, "Nothing left available")
+
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 40_000, AmountPct: 2.5,
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Buy with a fraction")
+	var buy v1.PlaceOrderResponse
+	decodeBody(t, rec, &buy)
+	assertFloat(t, 0.0625, buy.ResolvedAmount.Float64(), "2.5% of 100,000 BRL at 40,000")
+
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 40_000, Amount: 0.1, AmountPct: 50,
+	})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Both amount and amount_pct")
+
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 40_000, AmountPct: 150,
+	})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Above 100 percent")
+	var errResp v1.ErrorResponse
+	decodeBody(t, rec, &errResp)
+	assertEqual(t, CodeInvalidAmountPct, errResp.Code, "Error code")
+
+	rejections := e.Rejections("1")
+	assertEqual(t, 1, len(rejections), "Unresolved amount_pct logged")
+	assertEqual(t, engine.ErrInvalidAmountPct, rejections[0].Err, "Reason kept")
+}
+
+func TestOrderHandler_PlaceOrder_UnknownField(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", map[string]interface{}{
+		"user_id": "1", "pair": "BTC/BRL", "side": "bid", "type": "limit", "price": "50000", "ammount": "0.5",
+	})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Typo rejected")
+
+	var errResp v1.ErrorResponse
+	decodeBody(t, rec, &errResp)
+	assertEqual(t, CodeInvalidRequest, errResp.Code, "Error code")
+	assertTrue(t, strings.Contains(errResp.Error, `unknown field "ammount"`), "Message names the field")
+	assertFloat(t, 0, e.GetAccountManager().GetBalance("1", "BRL").Locked, "Nothing placed")
+}
+
+func TestOrderHandler_PlaceOrder_IncludeBalances(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+	accounts := NewAccountHandler(e.GetAccountManager())
+
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "2", Pair: "BTC/BRL", Side: "ask", Type: "limit", Price: 50_000, Amount: 1,
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Maker placed")
+	var maker v1.PlaceOrderResponse
+	decodeBody(t, rec, &maker)
+	assertEqual(t, 0, len(maker.Balances), "Balances are opt-in")
+
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 50_000, Amount: 1.5, IncludeBalances: true,
+	})
+	assertEqual(t, http.StatusOK, rec.Code, "Taker placed")
+	var taker v1.PlaceOrderResponse
+	decodeBody(t, rec, &taker)
+	assertEqual(t, 1, len(taker.Matches), "Taker matched")
+
+	rec = doRequest(accounts.GetBalance, http.MethodGet, "/api/v1/accounts/balance?user_id=1", nil)
+	var balance v1.BalanceResponse
+	decodeBody(t, rec, &balance)
+
+	expected := make(map[string]v1.BalanceItem)
+	for _, item := range balance.Balances {
+		expected[item.Asset] = item
+	}
+	assertEqual(t, len(expected), len(taker.Balances), "Same assets as GetBalance")
+	for _, item := range taker.Balances {
+		assertEqual(t, expected[item.Asset], item, "Embedded balance of "+item.Asset)
+	}
+
+	// 1 BTC bought, 0.5 BTC still resting at 50,000
+	btc := expected["BTC"]
+	assertEqual(t, "11.00000000", btc.Available.String(), "BTC received")
+	brl := expected["BRL"]
+	assertEqual(t, "25000.00", brl.Locked.String(), "BRL locked for the rest")
+}
+
+func TestOrderHandler_GetPosition_AverageEntry(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	// User 1 buys 0.25 @ 48,000 and 0.75 @ 52,000
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 48_000, 0.25)
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 52_000, 0.75)
+	_, _, err := e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 1)
+	assertNoError(t, err)
+
+	rec := doRequest(h.GetPosition, http.MethodGet, "/api/v1/accounts/position?user_id=1&pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.PositionResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 2, resp.Trades, "Trades")
+	assertEqual(t, "1.00000000", resp.NetPosition.String(), "Net position")
+	assertEqual(t, "51000.00", resp.QuoteSpent.String(), "Quote spent")
+	assertTrue(t, resp.AvgEntryPrice != nil, "Entry price set")
+	assertEqual(t, "51000.00", resp.AvgEntryPrice.String(), "Weighted average entry")
+}
+
+func TestOrderHandler_GetPosition_PairTicks(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+	pair := engine.Pair{Base: "SOL", Quote: "BRL"}
+	assertNoError(t, e.RegisterPair(engine.PairConfig{Pair: pair, PriceTick: 1, AmountTick: 0.1}))
+	_ = e.GetAccountManager().Credit("2", "SOL", 10)
+
+	// User 1 buys 0.5 @ 100 and sells it @ 103: 1.5 realized, on a whole-unit price tick
+	_, _, _ = e.PlaceOrder("2", pair, orderbook.Ask, 100, 0.5)
+	_, _, err := e.PlaceMarketOrder("1", pair, orderbook.Bid, 0.5)
+	assertNoError(t, err)
+	_, _, _ = e.PlaceOrder("2", pair, orderbook.Bid, 103, 0.5)
+	_, _, err = e.PlaceMarketOrder("1", pair, orderbook.Ask, 0.5)
+	assertNoError(t, err)
+
+	rec := doRequest(h.GetPosition, http.MethodGet, "/api/v1/accounts/position?user_id=1&pair=SOL/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.PositionResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "2.00", resp.RealizedPnL.String(), "Rounded to the pair's price tick")
+}
+
+func TestOrderHandler_GetPosition_NoTrades(t *testing.T) {
+	h := NewOrderHandler(setupEngine())
+
+	rec := doRequest(h.GetPosition, http.MethodGet, "/api/v1/accounts/position?user_id=9&pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.PositionResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 0, resp.Trades, "No trades")
+	assertEqual(t, "0.00000000", resp.NetPosition.String(), "Flat")
+	assertTrue(t, resp.AvgEntryPrice == nil, "No entry price while flat")
+
+	rec = doRequest(h.GetPosition, http.MethodGet, "/api/v1/accounts/position?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Missing user_id")
+}
+
+func TestOrderHandler_GetPnL(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	// User 1 buys 0.25 @ 48,000 and 0.75 @ 52,000, then sells 0.5 @ 54,000
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 48_000, 0.25)
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 52_000, 0.75)
+	_, _, err := e.PlaceMarketOrder("1", btcBrl(), orderbook.Bid, 1)
+	assertNoError(t, err)
+	_, _, _ = e.PlaceOrder("2", btcBrl(), orderbook.Bid, 54_000, 0.5)
+	_, _, err = e.PlaceMarketOrder("1", btcBrl(), orderbook.Ask, 0.5)
+	assertNoError(t, err)
+
+	rec := doRequest(h.GetPnL, http.MethodGet, "/api/v1/accounts/pnl?user_id=1&pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.PnLResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "fifo", resp.Method, "Method")
+	assertEqual(t, "2000.00", resp.RealizedPnL.String(), "Oldest lot closed first")
+	assertEqual(t, "0.50000000", resp.OpenPosition.String(), "Open position")
+	assertTrue(t, resp.AvgOpenPrice != nil, "Open price set")
+	assertEqual(t, "52000.00", resp.AvgOpenPrice.String(), "Open lot price")
+
+	rec = doRequest(h.GetPnL, http.MethodGet, "/api/v1/accounts/pnl?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Missing user_id")
+}
+
+func TestOrderHandler_GetRejections(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderHandler(e)
+
+	req := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 50_000, Amount: 100}
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", req)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Order rejected")
+
+	req = v1.PlaceOrderRequest{UserID: "1", Pair: "ETH/BRL", Side: "ask", Type: "market", Amount: 1}
+	_ = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", req)
+
+	rec = doRequest(h.GetRejections, http.MethodGet, "/api/v1/orders/rejections?user_id=1", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.RejectionsResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 2, len(resp.Rejections), "Rejections")
+	assertEqual(t, "market", resp.Rejections[0].Type, "Newest first")
+	assertEqual(t, CodeInsufficientBalance, resp.Rejections[1].Reason, "Reason code")
+	assertEqual(t, "insufficient balance", resp.Rejections[1].Error, "Message")
+
+	rec = doRequest(h.GetRejections, http.MethodGet, "/api/v1/orders/rejections?user_id=1&limit=1", nil)
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 1, len(resp.Rejections), "Limited")
+
+	rec = doRequest(h.GetRejections, http.MethodGet, "/api/v1/orders/rejections?user_id=2", nil)
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 0, len(resp.Rejections), "Other user has none")
+
+	rec = doRequest(h.GetRejections, http.MethodGet, "/api/v1/orders/rejections", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Missing user_id")
+}
+
+func TestOrderHandler_ReserveOrderID(t *testing.T) {
+	h := NewOrderHandler(setupEngine())
+
+	rec := doRequest(h.ReserveOrderID, http.MethodPost, "/api/v1/orders/reserve-id", v1.ReserveOrderIDRequest{UserID: "1"})
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	var reserved v1.ReserveOrderIDResponse
+	decodeBody(t, rec, &reserved)
+	assertTrue(t, reserved.OrderID > 0, "ID allocated")
+	assertTrue(t, !reserved.ExpiresAt.IsZero(), "Expiry reported")
+
+	body := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 50_000, Amount: 0.1, OrderID: reserved.OrderID}
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusOK, rec.Code, "Placed with the reserved ID")
+	var placed v1.PlaceOrderResponse
+	decodeBody(t, rec, &placed)
+	assertEqual(t, reserved.OrderID, placed.Order.ID, "Order carries the reserved ID")
+
+	rec = doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusConflict, rec.Code, "Reuse rejected")
+	var errResp v1.ErrorResponse
+	decodeBody(t, rec, &errResp)
+	assertEqual(t, CodeOrderIDNotReserved, errResp.Code, "Error code")
+
+	rec = doRequest(h.ReserveOrderID, http.MethodPost, "/api/v1/orders/reserve-id", v1.ReserveOrderIDRequest{})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Missing user_id")
+}
@@ -1,8 +1,10 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/moura95/crypto-exchange-challenge/internal/engine"
 	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
 	"github.com/moura95/crypto-exchange-challenge/pkg/logger"
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
 )
 
 type OrderbookHandler struct {
@@ -37,7 +40,7 @@ func (h *OrderbookHandler) GetOrderbook(w http.ResponseWriter, r *http.Request)
 
 	pairStr := r.URL.Query().Get("pair")
 	if pairStr == "" {
-		h.sendError(w, "pair query parameter is required (e.g., BTC/BRL)", http.StatusBadRequest)
+		writeError(w, "pair query parameter is required (e.g., BTC/BRL)", http.StatusBadRequest)
 		logger.Warningf("Get orderbook - missing pair - Duration: %v", time.Since(start))
 		return
 	}
@@ -45,7 +48,7 @@ func (h *OrderbookHandler) GetOrderbook(w http.ResponseWriter, r *http.Request)
 	// Parse pair
 	pair, err := h.parsePair(pairStr)
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err.Error(), http.StatusBadRequest)
 		logger.Warningf("Get orderbook - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
 		return
 	}
@@ -53,7 +56,7 @@ func (h *OrderbookHandler) GetOrderbook(w http.ResponseWriter, r *http.Request)
 	// Get orderbook
 	ob := h.engine.GetOrderbook(pair)
 	if ob == nil {
-		h.sendError(w, "Orderbook not found", http.StatusNotFound)
+		writeError(w, "Orderbook not found", http.StatusNotFound)
 		logger.Infof("Get orderbook - not found - Pair: %s - Status: 404 - Duration: %v",
 			pairStr, time.Since(start))
 		return
@@ -61,14 +64,347 @@ func (h *OrderbookHandler) GetOrderbook(w http.ResponseWriter, r *http.Request)
 
 	// Convert to response
 	response := h.orderbookToResponse(pair, ob)
-	h.sendJSON(w, response, http.StatusOK)
+	writeJSON(w, response, http.StatusOK)
 
 	logger.Infof("Get orderbook success - Pair: %s - Bids: %d - Asks: %d - Status: 200 - Duration: %v",
 		pairStr, len(response.Bids), len(response.Asks), time.Since(start))
 }
 
+// maxWeightedMidLevels bounds the levels param of GetTopOfBook.
+const maxWeightedMidLevels = 50
+
+// GetTopOfBook godoc
+// @Summary Get top of book
+// @Description Get the best bid/ask price and volume, the spread and the volume-weighted mid over the top levels of each side for a trading pair
+// @Tags Orderbook
+// @Produce json
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Param levels query int false "Levels per side for weighted_mid (default 1, the micro-price; max 50)"
+// @Success 200 {object} v1.TopOfBookResponse "Top of book retrieved successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 404 {object} v1.ErrorResponse "Orderbook not found"
+// @Router /api/v1/top [get]
+func (h *OrderbookHandler) GetTopOfBook(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	pairStr := r.URL.Query().Get("pair")
+	if pairStr == "" {
+		writeError(w, "pair query parameter is required (e.g., BTC/BRL)", http.StatusBadRequest)
+		logger.Warningf("Get top of book - missing pair - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.parsePair(pairStr)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Get top of book - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	levels, err := parseIntParam(r.URL.Query().Get("levels"), 1, 1, maxWeightedMidLevels)
+	if err != nil {
+		writeError(w, "levels "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Get top of book - invalid levels - Duration: %v", time.Since(start))
+		return
+	}
+
+	ob := h.engine.GetOrderbook(pair)
+	if ob == nil {
+		writeError(w, "Orderbook not found", http.StatusNotFound)
+		logger.Infof("Get top of book - not found - Pair: %s - Status: 404 - Duration: %v",
+			pairStr, time.Since(start))
+		return
+	}
+
+	response := v1.TopOfBookResponse{
+		Pair:      pair.String(),
+		BidPrice:  v1.Fiat(0),
+		BidVolume: v1.Crypto(0),
+		AskPrice:  v1.Fiat(0),
+		AskVolume: v1.Crypto(0),
+		Spread:    v1.Fiat(0),
+		Levels:    levels,
+	}
+
+	if bid, ok := bestVisibleLevel(ob.Bids(), ob.PriceTick()); ok {
+		response.HasBid = true
+		response.BidPrice = bid.Price
+		response.BidVolume = bid.TotalVolume
+	}
+	if ask, ok := bestVisibleLevel(ob.Asks(), ob.PriceTick()); ok {
+		response.HasAsk = true
+		response.AskPrice = ask.Price
+		response.AskVolume = ask.TotalVolume
+	}
+	if response.HasBid && response.HasAsk {
+		response.Spread = v1.Fiat(utils.RoundToTick(response.AskPrice.Float64()-response.BidPrice.Float64(), ob.PriceTick()))
+	}
+	if mid, ok := ob.WeightedMid(levels); ok {
+		weighted := v1.Fiat(utils.RoundToTick(mid, ob.PriceTick()))
+		response.WeightedMid = &weighted
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Get top of book success - Pair: %s - Status: 200 - Duration: %v", pairStr, time.Since(start))
+}
+
+// maxDepthLevels bounds the depth param of GetDepth.
+const maxDepthLevels = 500
+
+// GetDepth godoc
+// @Summary Get cumulative depth
+// @Description Get the visible levels of both sides, best price first, each with the volume and quote value resting from the best price down to it, for depth charts
+// @Tags Orderbook
+// @Produce json
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Param depth query int false "Levels per side (default all, max 500)"
+// @Success 200 {object} v1.DepthResponse "Depth retrieved successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 404 {object} v1.ErrorResponse "Orderbook not found"
+// @Router /api/v1/depth [get]
+func (h *OrderbookHandler) GetDepth(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	pairStr := r.URL.Query().Get("pair")
+	if pairStr == "" {
+		writeError(w, "pair query parameter is required (e.g., BTC/BRL)", http.StatusBadRequest)
+		logger.Warningf("Get depth - missing pair - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.parsePair(pairStr)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Get depth - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	depth, err := parseIntParam(r.URL.Query().Get("depth"), 0, 1, maxDepthLevels)
+	if err != nil {
+		writeError(w, "depth "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Get depth - invalid depth - Duration: %v", time.Since(start))
+		return
+	}
+
+	ob := h.engine.GetOrderbook(pair)
+	if ob == nil {
+		writeError(w, "Orderbook not found", http.StatusNotFound)
+		logger.Infof("Get depth - not found - Pair: %s - Status: 404 - Duration: %v",
+			pairStr, time.Since(start))
+		return
+	}
+
+	response := v1.DepthResponse{
+		Pair:  pair.String(),
+		Depth: depth,
+		Bids:  depthLadder(visibleLevels(ob.Bids(), ob.PriceTick()), depth),
+		Asks:  depthLadder(visibleLevels(ob.Asks(), ob.PriceTick()), depth),
+	}
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Get depth success - Pair: %s - Bids: %d - Asks: %d - Status: 200 - Duration: %v",
+		pairStr, len(response.Bids), len(response.Asks), time.Since(start))
+}
+
+// GetAuctionPreview godoc
+// @Summary Get the indicative auction price
+// @Description Get the clearing price, matched volume and imbalance the call auction of a pair in auction mode would execute if it ran now, over the visible orders. Nothing is matched
+// @Tags Orderbook
+// @Produce json
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Success 200 {object} v1.AuctionPreviewResponse "Preview computed"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request or unknown pair"
+// @Failure 409 {object} v1.ErrorResponse "Pair not in auction mode"
+// @Router /api/v1/auction [get]
+func (h *OrderbookHandler) GetAuctionPreview(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	pairStr := r.URL.Query().Get("pair")
+	if pairStr == "" {
+		writeError(w, "pair query parameter is required (e.g., BTC/BRL)", http.StatusBadRequest)
+		logger.Warningf("Get auction preview - missing pair - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.parsePair(pairStr)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Get auction preview - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	preview, err := h.engine.AuctionPreview(pair)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Get auction preview failed - Pair: %s - Duration: %v - Error: %v", pairStr, time.Since(start), err)
+		return
+	}
+
+	writeJSON(w, v1.AuctionPreviewResponse{
+		Pair:      pair.String(),
+		Price:     v1.AssetAmount(pair.Quote, preview.Price),
+		Volume:    v1.AssetAmount(pair.Base, preview.Volume),
+		Imbalance: v1.AssetAmount(pair.Base, preview.Imbalance),
+	}, http.StatusOK)
+
+	logger.Infof("Get auction preview success - Pair: %s - Price: %v - Volume: %v - Status: 200 - Duration: %v",
+		pairStr, preview.Price, preview.Volume, time.Since(start))
+}
+
+// GetDepthInRange godoc
+// @Summary Get depth within a price range
+// @Description Get the visible volume and number of orders resting on one side between two prices, both inclusive. An empty range returns zeros
+// @Tags Orderbook
+// @Produce json
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Param side query string true "Book side (bid or ask)"
+// @Param from query string true "Lower price"
+// @Param to query string true "Upper price"
+// @Success 200 {object} v1.DepthInRangeResponse "Depth retrieved successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Failure 404 {object} v1.ErrorResponse "Orderbook not found"
+// @Router /api/v1/depth-in-range [get]
+func (h *OrderbookHandler) GetDepthInRange(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	pairStr := query.Get("pair")
+	if pairStr == "" {
+		writeError(w, "pair query parameter is required (e.g., BTC/BRL)", http.StatusBadRequest)
+		logger.Warningf("Get depth in range - missing pair - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.parsePair(pairStr)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Get depth in range - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	side := orderbook.Side(strings.ToLower(query.Get("side")))
+	if side != orderbook.Bid && side != orderbook.Ask {
+		writeError(w, "side must be 'bid' or 'ask'", http.StatusBadRequest)
+		logger.Warningf("Get depth in range - invalid side - Duration: %v", time.Since(start))
+		return
+	}
+
+	from, err := parsePriceParam(query.Get("from"))
+	if err != nil {
+		writeError(w, "from "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Get depth in range - invalid from - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+	to, err := parsePriceParam(query.Get("to"))
+	if err != nil {
+		writeError(w, "to "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Get depth in range - invalid to - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	ob := h.engine.GetOrderbook(pair)
+	if ob == nil {
+		writeError(w, "Orderbook not found", http.StatusNotFound)
+		logger.Infof("Get depth in range - not found - Pair: %s - Status: 404 - Duration: %v",
+			pairStr, time.Since(start))
+		return
+	}
+
+	depth := ob.DepthInRange(side, from, to)
+	writeJSON(w, v1.DepthInRangeResponse{
+		Pair:   pair.String(),
+		Side:   string(side),
+		From:   v1.Fiat(from),
+		To:     v1.Fiat(to),
+		Volume: v1.Crypto(depth.Volume),
+		Orders: depth.Orders,
+		Levels: depth.Levels,
+	}, http.StatusOK)
+
+	logger.Infof("Get depth in range success - Pair: %s - Side: %s - Orders: %d - Status: 200 - Duration: %v",
+		pairStr, side, depth.Orders, time.Since(start))
+}
+
+// GetSpreadHistory godoc
+// @Summary Get spread history
+// @Description Get the latest periodic samples of the best visible bid and ask of a trading pair, with the spread and mid, oldest first
+// @Tags Orderbook
+// @Produce json
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Param limit query int false "Samples to return (1-3600, default 100)"
+// @Success 200 {object} v1.SpreadHistoryResponse "Spread history retrieved successfully"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request or unknown pair"
+// @Router /api/v1/spread-history [get]
+func (h *OrderbookHandler) GetSpreadHistory(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	query := r.URL.Query()
+
+	pairStr := query.Get("pair")
+	if pairStr == "" {
+		writeError(w, "pair query parameter is required (e.g., BTC/BRL)", http.StatusBadRequest)
+		logger.Warningf("Get spread history - missing pair - Duration: %v", time.Since(start))
+		return
+	}
+
+	pair, err := h.parsePair(pairStr)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		logger.Warningf("Get spread history - invalid pair - Duration: %v - Error: %v", time.Since(start), err)
+		return
+	}
+
+	limit, err := parseIntParam(query.Get("limit"), 100, 1, engine.MaxSpreadSamples)
+	if err != nil {
+		writeError(w, "limit "+err.Error(), http.StatusBadRequest)
+		logger.Warningf("Get spread history - invalid limit - Duration: %v", time.Since(start))
+		return
+	}
+
+	samples, err := h.engine.SpreadHistory(pair, limit)
+	if err != nil {
+		writeDomainError(w, err)
+		logger.Warningf("Get spread history failed - Pair: %s - Duration: %v - Error: %v", pairStr, time.Since(start), err)
+		return
+	}
+
+	response := v1.SpreadHistoryResponse{
+		Pair:     pair.String(),
+		Interval: h.engine.Config().SpreadSampleInterval.String(),
+		Samples:  make([]v1.SpreadSample, len(samples)),
+	}
+	for i, s := range samples {
+		response.Samples[i] = v1.SpreadSample{
+			Timestamp: s.Time,
+			HasBid:    s.HasBid,
+			BidPrice:  v1.Fiat(s.Bid),
+			HasAsk:    s.HasAsk,
+			AskPrice:  v1.Fiat(s.Ask),
+			Spread:    v1.Fiat(s.Spread),
+			Mid:       v1.Fiat(s.Mid),
+		}
+	}
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("Get spread history success - Pair: %s - Samples: %d - Status: 200 - Duration: %v",
+		pairStr, len(samples), time.Since(start))
+}
+
 // Helper methods
 
+// parsePriceParam parses a required, non-negative price query parameter.
+func parsePriceParam(raw string) (float64, error) {
+	if raw == "" {
+		return 0, errors.New("is required")
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+		return 0, errors.New("must be a non-negative number")
+	}
+	return v, nil
+}
+
 func (h *OrderbookHandler) parsePair(pairStr string) (engine.Pair, error) {
 	parts := strings.Split(pairStr, "/")
 	if len(parts) != 2 {
@@ -88,45 +424,97 @@ func (h *OrderbookHandler) parsePair(pairStr string) (engine.Pair, error) {
 }
 
 func (h *OrderbookHandler) orderbookToResponse(pair engine.Pair, ob *orderbook.Orderbook) v1.OrderbookResponse {
-	bids := ob.Bids()
-	asks := ob.Asks()
-
-	bidLevels := make([]v1.LimitLevel, len(bids))
-	for i, limit := range bids {
-		bidLevels[i] = v1.LimitLevel{
-			Price:       limit.Price(engine.PriceTick),
-			TotalVolume: limit.TotalVolume,
-		}
+	bids := visibleLevels(ob.Bids(), ob.PriceTick())
+	asks := visibleLevels(ob.Asks(), ob.PriceTick())
+
+	response := v1.OrderbookResponse{
+		Pair:           pair.String(),
+		Bids:           bids,
+		Asks:           asks,
+		BidTotalVolume: v1.Crypto(ob.BidVisibleVolume()),
+		AskTotalVolume: v1.Crypto(ob.AskVisibleVolume()),
 	}
 
-	askLevels := make([]v1.LimitLevel, len(asks))
-	for i, limit := range asks {
-		askLevels[i] = v1.LimitLevel{
-			Price:       limit.Price(engine.PriceTick),
-			TotalVolume: limit.TotalVolume,
-		}
+	hasBid, hasAsk := len(bids) > 0, len(asks) > 0
+	if hasBid {
+		response.BestBid = &bids[0].Price
+	}
+	if hasAsk {
+		response.BestAsk = &asks[0].Price
 	}
+	response.OneSided = hasBid != hasAsk
 
-	return v1.OrderbookResponse{
-		Pair:           pair.String(),
-		Bids:           bidLevels,
-		Asks:           askLevels,
-		BidTotalVolume: ob.BidTotalVolume(),
-		AskTotalVolume: ob.AskTotalVolume(),
+	if hasBid && hasAsk {
+		spread := v1.Fiat(utils.RoundToTick(asks[0].Price.Float64()-bids[0].Price.Float64(), ob.PriceTick()))
+		response.Spread = &spread
+		response.Crossed = bids[0].Price.Float64() >= asks[0].Price.Float64()
+	}
+	if bps, ok := ob.SpreadBps(); ok {
+		response.SpreadBps = &bps
+	}
+	// From the levels served, which the book may have moved away from since
+	response.Checksum = orderbook.ChecksumLevels(checksumLevels(bids), checksumLevels(asks), orderbook.ChecksumDepth)
+
+	return response
+}
+
+// checksumLevels converts public depth back to what orderbook.ChecksumLevels
+// reads.
+func checksumLevels(levels []v1.LimitLevel) []orderbook.ChecksumLevel {
+	result := make([]orderbook.ChecksumLevel, len(levels))
+	for i, level := range levels {
+		result[i] = orderbook.ChecksumLevel{Price: level.Price.Float64(), Volume: level.TotalVolume.Float64()}
 	}
+	return result
 }
 
-func (h *OrderbookHandler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+// visibleLevels converts limits to public depth, hiding hidden order volume
+// and skipping levels that only hold hidden orders.
+func visibleLevels(limits []*orderbook.Limit, priceTick float64) []v1.LimitLevel {
+	levels := make([]v1.LimitLevel, 0, len(limits))
+	for _, limit := range limits {
+		volume := limit.VisibleVolume()
+		if volume <= 0 {
+			continue
+		}
+		levels = append(levels, v1.LimitLevel{
+			Price:       v1.Fiat(limit.Price(priceTick)),
+			TotalVolume: v1.Crypto(volume),
+		})
+	}
+	return levels
+}
 
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		logger.Errorf("Error encoding JSON response: %v", err)
+// depthLadder adds running volume and quote totals to levels, which must be
+// sorted best price first, keeping at most depth of them (all for 0).
+func depthLadder(levels []v1.LimitLevel, depth int) []v1.DepthLevel {
+	if depth > 0 && len(levels) > depth {
+		levels = levels[:depth]
 	}
+
+	ladder := make([]v1.DepthLevel, len(levels))
+	volume, quote := 0.0, 0.0
+	for i, level := range levels {
+		volume += level.TotalVolume.Float64()
+		quote += level.Price.Float64() * level.TotalVolume.Float64()
+		ladder[i] = v1.DepthLevel{
+			Price:            level.Price,
+			Volume:           level.TotalVolume,
+			CumulativeVolume: v1.Crypto(volume),
+			CumulativeQuote:  v1.Fiat(quote),
+		}
+	}
+	return ladder
 }
 
-func (h *OrderbookHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
-	h.sendJSON(w, v1.ErrorResponse{Error: message}, statusCode)
+// bestVisibleLevel returns the first level with visible volume.
+func bestVisibleLevel(limits []*orderbook.Limit, priceTick float64) (v1.LimitLevel, bool) {
+	for _, limit := range limits {
+		if volume := limit.VisibleVolume(); volume > 0 {
+			return v1.LimitLevel{Price: v1.Fiat(limit.Price(priceTick)), TotalVolume: v1.Crypto(volume)}, true
+		}
+	}
+	return v1.LimitLevel{}, false
 }
 
 // Custom error type
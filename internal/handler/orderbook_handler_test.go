@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+func setupEngine() *engine.Engine {
+	e := engine.NewEngine()
+	accounts := e.GetAccountManager()
+	_ = accounts.Credit("1", "BRL", 100_000)
+	_ = accounts.Credit("1", "BTC", 10)
+	_ = accounts.Credit("2", "BRL", 100_000)
+	_ = accounts.Credit("2", "BTC", 10)
+	return e
+}
+
+func btcBrl() engine.Pair {
+	return engine.Pair{Base: "BTC", Quote: "BRL"}
+}
+
+func TestOrderbookHandler_GetTopOfBook_Populated(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 48_000, 1)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.3)
+	assertNoError(t, err)
+
+	h := NewOrderbookHandler(e)
+	rec := doRequest(h.GetTopOfBook, http.MethodGet, "/api/v1/top?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.TopOfBookResponse
+	decodeBody(t, rec, &resp)
+
+	assertTrue(t, resp.HasBid, "Has bid")
+	assertFloat(t, 49_000, resp.BidPrice.Float64(), "Best bid price")
+	assertFloat(t, 0.5, resp.BidVolume.Float64(), "Best bid volume")
+	assertTrue(t, resp.HasAsk, "Has ask")
+	assertFloat(t, 50_000, resp.AskPrice.Float64(), "Best ask price")
+	assertFloat(t, 0.3, resp.AskVolume.Float64(), "Best ask volume")
+	assertFloat(t, 1_000, resp.Spread.Float64(), "Spread")
+	assertEqual(t, 1, resp.Levels, "Default levels")
+	// Micro-price: (49,000*0.3 + 50,000*0.5) / 0.8
+	assertFloat(t, 49_625, resp.WeightedMid.Float64(), "Weighted mid over 1 level")
+
+	// Bids 72,500/1.5 over 1.5, asks 50,000 over 0.3:
+	// (48,333.33*0.3 + 50,000*1.5) / 1.8 = 49,722.22
+	rec = doRequest(h.GetTopOfBook, http.MethodGet, "/api/v1/top?pair=BTC/BRL&levels=2", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	decodeBody(t, rec, &resp)
+	assertFloat(t, 49_722.22, resp.WeightedMid.Float64(), "Weighted mid over 2 levels")
+
+	rec = doRequest(h.GetTopOfBook, http.MethodGet, "/api/v1/top?pair=BTC/BRL&levels=0", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Invalid levels")
+}
+
+func TestOrderbookHandler_GetTopOfBook_Empty(t *testing.T) {
+	h := NewOrderbookHandler(engine.NewEngine())
+
+	rec := doRequest(h.GetTopOfBook, http.MethodGet, "/api/v1/top?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.TopOfBookResponse
+	decodeBody(t, rec, &resp)
+
+	assertEqual(t, false, resp.HasBid, "Has bid")
+	assertEqual(t, false, resp.HasAsk, "Has ask")
+	assertFloat(t, 0, resp.BidPrice.Float64(), "Bid price")
+	assertFloat(t, 0, resp.AskPrice.Float64(), "Ask price")
+	assertFloat(t, 0, resp.Spread.Float64(), "Spread")
+	assertTrue(t, resp.WeightedMid == nil, "No weighted mid without both sides")
+}
+
+func TestOrderbookHandler_GetDepth(t *testing.T) {
+	e := setupEngine()
+	for _, o := range []struct {
+		side          orderbook.Side
+		price, amount float64
+	}{
+		{orderbook.Bid, 50_000, 0.5}, {orderbook.Bid, 49_990, 0.25}, {orderbook.Bid, 49_900, 1},
+		{orderbook.Ask, 50_100, 0.5}, {orderbook.Ask, 50_200, 1.5},
+	} {
+		user := "1"
+		if o.side == orderbook.Ask {
+			user = "2"
+		}
+		_, _, err := e.PlaceOrder(user, btcBrl(), o.side, o.price, o.amount)
+		assertNoError(t, err)
+	}
+	_, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Bid, 49_950, 0.1, engine.OrderOptions{Hidden: true})
+	assertNoError(t, err)
+
+	h := NewOrderbookHandler(e)
+	rec := doRequest(h.GetDepth, http.MethodGet, "/api/v1/depth?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.DepthResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 3, len(resp.Bids), "Hidden level left out")
+	assertEqual(t, 2, len(resp.Asks), "Ask levels")
+
+	bidVolumes, bidQuotes := []float64{0.5, 0.75, 1.75}, []float64{25_000, 37_497.5, 87_397.5}
+	for i, level := range resp.Bids {
+		assertFloat(t, bidVolumes[i], level.CumulativeVolume.Float64(), "Cumulative bid volume")
+		assertFloat(t, bidQuotes[i], level.CumulativeQuote.Float64(), "Cumulative bid quote")
+	}
+	assertFloat(t, 50_000, resp.Bids[0].Price.Float64(), "Best bid first")
+	assertFloat(t, 100_350, resp.Asks[1].CumulativeQuote.Float64(), "Cumulative ask quote")
+
+	for _, side := range [][]v1.DepthLevel{resp.Bids, resp.Asks} {
+		for i := 1; i < len(side); i++ {
+			assertTrue(t, side[i].CumulativeVolume.Float64() > side[i-1].CumulativeVolume.Float64(), "Volume grows outwards")
+			assertTrue(t, side[i].CumulativeQuote.Float64() > side[i-1].CumulativeQuote.Float64(), "Quote grows outwards")
+		}
+		last := side[len(side)-1]
+		total := 0.0
+		for _, level := range side {
+			total += level.Volume.Float64()
+		}
+		assertFloat(t, total, last.CumulativeVolume.Float64(), "Last level holds the side's total")
+	}
+
+	rec = doRequest(h.GetDepth, http.MethodGet, "/api/v1/depth?pair=BTC/BRL&depth=2", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	resp = v1.DepthResponse{}
+	decodeBody(t, rec, &resp)
+	assertEqual(t, 2, len(resp.Bids), "Bids limited to depth")
+	assertFloat(t, 0.75, resp.Bids[1].CumulativeVolume.Float64(), "Totals within the limit")
+
+	rec = doRequest(h.GetDepth, http.MethodGet, "/api/v1/depth?pair=BTC/BRL&depth=0", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Depth below 1 rejected")
+}
+
+func TestOrderbookHandler_GetAuctionPreview(t *testing.T) {
+	e := setupEngine()
+	h := NewOrderbookHandler(e)
+
+	rec := doRequest(h.GetAuctionPreview, http.MethodGet, "/api/v1/auction?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusConflict, rec.Code, "Pair not in auction mode")
+
+	assertNoError(t, e.SetPairMode(btcBrl(), engine.PairModeAuction))
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_100, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.75)
+	assertNoError(t, err)
+
+	rec = doRequest(h.GetAuctionPreview, http.MethodGet, "/api/v1/auction?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+	var resp v1.AuctionPreviewResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "BTC/BRL", resp.Pair, "Pair")
+	assertEqual(t, "50000.00", resp.Price.String(), "Indicative price")
+	assertEqual(t, "0.50000000", resp.Volume.String(), "Indicative volume")
+	assertEqual(t, "-0.25000000", resp.Imbalance.String(), "Sellers left over")
+	assertEqual(t, 0, len(e.Trades(btcBrl())), "Nothing executed")
+
+	rec = doRequest(h.GetAuctionPreview, http.MethodGet, "/api/v1/auction", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Pair required")
+}
+
+func TestOrderbookHandler_GetDepthInRange(t *testing.T) {
+	e := setupEngine()
+	for _, price := range []float64{49_000, 50_000, 50_500, 52_000} {
+		_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, price, 0.5)
+		assertNoError(t, err)
+	}
+
+	h := NewOrderbookHandler(e)
+	rec := doRequest(h.GetDepthInRange, http.MethodGet,
+		"/api/v1/depth-in-range?pair=BTC/BRL&side=ask&from=50000&to=51000", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.DepthInRangeResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "ask", resp.Side, "Side")
+	assertFloat(t, 1.0, resp.Volume.Float64(), "Volume")
+	assertEqual(t, 2, resp.Orders, "Orders")
+	assertEqual(t, 2, resp.Levels, "Levels")
+
+	for _, target := range []string{
+		"/api/v1/depth-in-range?pair=BTC/BRL&side=ask&from=50000",
+		"/api/v1/depth-in-range?pair=BTC/BRL&side=up&from=50000&to=51000",
+		"/api/v1/depth-in-range?pair=BTC/BRL&side=bid&from=-1&to=51000",
+	} {
+		rec = doRequest(h.GetDepthInRange, http.MethodGet, target, nil)
+		assertEqual(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
+
+func TestOrderbookHandler_UnlistedPairNotFound(t *testing.T) {
+	e := setupEngine()
+	oh := NewOrderHandler(e)
+	body := v1.PlaceOrderRequest{UserID: "1", Pair: "BTX/BRL", Side: "bid", Type: "limit", Price: 50_000, Amount: 0.1}
+	rec := doRequest(oh.PlaceOrder, http.MethodPost, "/api/v1/orders", body)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Order on unlisted pair")
+	assertTrue(t, strings.Contains(rec.Body.String(), "UNKNOWN_PAIR"), "Unknown pair code")
+
+	h := NewOrderbookHandler(e)
+	rec = doRequest(h.GetOrderbook, http.MethodGet, "/api/v1/orderbook?pair=BTX/BRL", nil)
+	assertEqual(t, http.StatusNotFound, rec.Code, "Orderbook of unlisted pair")
+}
+
+func TestOrderbookHandler_GetOrderbook_ExcludesHidden(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Ask, 50_000, 1, engine.OrderOptions{Hidden: true})
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.4)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrderWithOptions("1", btcBrl(), orderbook.Ask, 51_000, 2, engine.OrderOptions{Hidden: true})
+	assertNoError(t, err)
+
+	h := NewOrderbookHandler(e)
+	rec := doRequest(h.GetOrderbook, http.MethodGet, "/api/v1/orderbook?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.OrderbookResponse
+	decodeBody(t, rec, &resp)
+
+	assertEqual(t, 1, len(resp.Asks), "Hidden-only level must not be shown")
+	assertFloat(t, 50_000, resp.Asks[0].Price.Float64(), "Ask price")
+	assertFloat(t, 0.4, resp.Asks[0].TotalVolume.Float64(), "Only visible volume is shown")
+	assertFloat(t, 0.4, resp.AskTotalVolume.Float64(), "Ask total volume excludes hidden")
+
+	// Hidden liquidity is still matchable
+	_ = e.GetAccountManager().Credit("3", "BRL", 200_000)
+	_, matches, err := e.PlaceOrder("3", btcBrl(), orderbook.Bid, 51_000, 3)
+	assertNoError(t, err)
+	assertEqual(t, 3, len(matches), "Should match hidden orders after the visible one")
+	assertEqual(t, "2", matches[0].Ask.UserID, "Visible order at 50k fills first")
+}
+
+func TestOrderbookHandler_GetOrderbook_FixedDecimals(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.00000001)
+	assertNoError(t, err)
+
+	h := NewOrderbookHandler(e)
+	rec := doRequest(h.GetOrderbook, http.MethodGet, "/api/v1/orderbook?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	raw := rec.Body.String()
+	assertTrue(t, !strings.Contains(raw, "e-"), "No exponent notation in "+raw)
+	assertTrue(t, strings.Contains(raw, `"price":"50000.00"`), "Price with 2 decimals")
+	assertTrue(t, strings.Contains(raw, `"total_volume":"0.00000001"`), "Volume with 8 decimals")
+}
+
+func TestOrderbookHandler_GetOrderbook_Empty(t *testing.T) {
+	h := NewOrderbookHandler(setupEngine())
+	rec := doRequest(h.GetOrderbook, http.MethodGet, "/api/v1/orderbook?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	raw := rec.Body.String()
+	assertTrue(t, strings.Contains(raw, `"best_bid":null`), "Best bid null in "+raw)
+	assertTrue(t, strings.Contains(raw, `"best_ask":null`), "Best ask null in "+raw)
+	assertTrue(t, strings.Contains(raw, `"spread":null`), "Spread null in "+raw)
+
+	var resp v1.OrderbookResponse
+	decodeBody(t, rec, &resp)
+	assertTrue(t, !resp.OneSided, "Empty book is not one-sided")
+	assertTrue(t, !resp.Crossed, "Empty book is not crossed")
+}
+
+func TestOrderbookHandler_GetOrderbook_OneSided(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 0.5)
+	assertNoError(t, err)
+
+	h := NewOrderbookHandler(e)
+	rec := doRequest(h.GetOrderbook, http.MethodGet, "/api/v1/orderbook?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.OrderbookResponse
+	decodeBody(t, rec, &resp)
+	assertTrue(t, resp.OneSided, "Only bids")
+	assertTrue(t, resp.BestBid != nil, "Best bid set")
+	assertFloat(t, 49_000, resp.BestBid.Float64(), "Best bid")
+	assertTrue(t, resp.BestAsk == nil, "No best ask")
+	assertTrue(t, resp.Spread == nil, "No spread without asks")
+	assertTrue(t, resp.SpreadBps == nil, "No bps spread without asks")
+}
+
+func TestOrderbookHandler_GetOrderbook_TwoSided(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.3)
+	assertNoError(t, err)
+
+	h := NewOrderbookHandler(e)
+	rec := doRequest(h.GetOrderbook, http.MethodGet, "/api/v1/orderbook?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.OrderbookResponse
+	decodeBody(t, rec, &resp)
+	assertTrue(t, !resp.OneSided, "Both sides")
+	assertTrue(t, !resp.Crossed, "Not crossed")
+	assertFloat(t, 49_000, resp.BestBid.Float64(), "Best bid")
+	assertFloat(t, 50_000, resp.BestAsk.Float64(), "Best ask")
+	assertFloat(t, 1_000, resp.Spread.Float64(), "Spread")
+	assertFloat(t, 20_000_000.0/99_000, *resp.SpreadBps, "Spread in bps")
+	assertEqual(t, e.GetOrderbook(btcBrl()).Checksum(orderbook.ChecksumDepth), resp.Checksum, "Checksum")
+}
+
+func TestOrderbookHandler_GetSpreadHistory(t *testing.T) {
+	e := setupEngine()
+	_, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 49_900, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_100, 0.5)
+	assertNoError(t, err)
+	assertTrue(t, e.SampleSpreads(), "Sampled")
+
+	h := NewOrderbookHandler(e)
+	rec := doRequest(h.GetSpreadHistory, http.MethodGet, "/api/v1/spread-history?pair=BTC/BRL", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var resp v1.SpreadHistoryResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, "1s", resp.Interval, "Sample interval")
+	assertEqual(t, 1, len(resp.Samples), "Samples")
+	assertEqual(t, "200.00", resp.Samples[0].Spread.String(), "Spread")
+	assertEqual(t, "50000.00", resp.Samples[0].Mid.String(), "Mid")
+
+	rec = doRequest(h.GetSpreadHistory, http.MethodGet, "/api/v1/spread-history?pair=DOGE/BRL", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Unlisted pair")
+
+	rec = doRequest(h.GetSpreadHistory, http.MethodGet, "/api/v1/spread-history?pair=BTC/BRL&limit=0", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Invalid limit")
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/pkg/logger"
+)
+
+type PairHandler struct {
+	engine *engine.Engine
+}
+
+func NewPairHandler(engine *engine.Engine) *PairHandler {
+	return &PairHandler{
+		engine: engine,
+	}
+}
+
+// ListPairs godoc
+// @Summary List trading pairs
+// @Description Get every registered trading pair with its tick sizes and limits
+// @Tags Pairs
+// @Produce json
+// @Success 200 {array} v1.PairInfo "Pairs retrieved successfully"
+// @Router /api/v1/pairs [get]
+func (h *PairHandler) ListPairs(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	pairs := h.engine.ListPairs()
+
+	response := pairInfos(pairs)
+
+	writeJSON(w, response, http.StatusOK)
+
+	logger.Infof("List pairs success - Pairs: %d - Status: 200 - Duration: %v", len(response), time.Since(start))
+}
+
+func pairInfos(pairs []engine.PairConfig) []v1.PairInfo {
+	result := make([]v1.PairInfo, len(pairs))
+	for i, cfg := range pairs {
+		result[i] = v1.PairInfo{
+			Symbol:       cfg.Pair.String(),
+			Base:         cfg.Pair.Base,
+			Quote:        cfg.Pair.Quote,
+			PriceTick:    v1.Decimal(cfg.PriceTick),
+			AmountTick:   v1.Decimal(cfg.AmountTick),
+			MinOrderSize: v1.Decimal(cfg.MinOrderSize),
+			MinNotional:  v1.Decimal(cfg.MinNotional),
+			LotSize:      v1.Decimal(cfg.LotSize),
+			MaxMarketGap: v1.Decimal(cfg.MaxMarketGap),
+			Halted:       cfg.Halted,
+			Mode:         string(cfg.Mode),
+		}
+	}
+	return result
+}
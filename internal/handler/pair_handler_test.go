@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+)
+
+func TestPairHandler_ListPairs_RegisteredPair(t *testing.T) {
+	e := engine.NewEngine()
+	err := e.RegisterPair(engine.PairConfig{
+		Pair:         engine.Pair{Base: "SOL", Quote: "BRL"},
+		PriceTick:    0.05,
+		AmountTick:   0.001,
+		MinOrderSize: 0.1,
+		MinNotional:  10,
+	})
+	assertNoError(t, err)
+
+	h := NewPairHandler(e)
+	rec := doRequest(h.ListPairs, http.MethodGet, "/api/v1/pairs", nil)
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+
+	var pairs []v1.PairInfo
+	decodeBody(t, rec, &pairs)
+
+	var found *v1.PairInfo
+	for i := range pairs {
+		if pairs[i].Symbol == "SOL/BRL" {
+			found = &pairs[i]
+		}
+	}
+	if found == nil {
+		t.Fatal("SOL/BRL should be listed")
+	}
+
+	assertEqual(t, "SOL", found.Base, "Base")
+	assertEqual(t, "BRL", found.Quote, "Quote")
+	assertFloat(t, 0.05, found.PriceTick.Float64(), "Price tick")
+	assertFloat(t, 0.001, found.AmountTick.Float64(), "Amount tick")
+	assertFloat(t, 0.1, found.MinOrderSize.Float64(), "Min order size")
+	assertFloat(t, 10, found.MinNotional.Float64(), "Min notional")
+	assertEqual(t, false, found.Halted, "Halted")
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/logger"
+)
+
+// Machine-readable error codes returned in v1.ErrorResponse.Code.
+const (
+	CodeInvalidRequest        = "INVALID_REQUEST"
+	CodeNotFound              = "NOT_FOUND"
+	CodeInternalError         = "INTERNAL_ERROR"
+	CodeInvalidPair           = "INVALID_PAIR"
+	CodeSameAssetPair         = "SAME_ASSET_PAIR"
+	CodeUnknownPair           = "UNKNOWN_PAIR"
+	CodePairAlreadyRegistered = "PAIR_ALREADY_REGISTERED"
+	CodeInvalidTickSize       = "INVALID_TICK_SIZE"
+	CodeTradingHalted         = "TRADING_HALTED"
+	CodePairDelisted          = "PAIR_DELISTED"
+	CodeEngineHalted          = "ENGINE_HALTED"
+	CodeInvalidPriceTick      = "INVALID_PRICE_TICK"
+	CodeInvalidAmountTick     = "INVALID_AMOUNT_TICK"
+	CodeInvalidAmountLot      = "INVALID_AMOUNT_LOT"
+	CodeInvalidLotSize        = "INVALID_LOT_SIZE"
+	CodeInvalidMarketGap      = "INVALID_MARKET_GAP"
+	CodeBelowMinOrderSize     = "BELOW_MIN_ORDER_SIZE"
+	CodeBelowMinNotional      = "BELOW_MIN_NOTIONAL"
+	CodeSelfTrade             = "SELF_TRADE"
+	CodeTooManyOpenOrders     = "TOO_MANY_OPEN_ORDERS"
+	CodeInsufficientLiquidity = "INSUFFICIENT_LIQUIDITY"
+	CodeOrderNotFound         = "ORDER_NOT_FOUND"
+	CodeUnauthorized          = "UNAUTHORIZED"
+	CodeForbidden             = "FORBIDDEN"
+	CodeInsufficientBalance   = "INSUFFICIENT_BALANCE"
+	CodeInsufficientLocked    = "INSUFFICIENT_LOCKED"
+	CodeInvalidPrice          = "INVALID_PRICE"
+	CodeInvalidAmount         = "INVALID_AMOUNT"
+	CodeNonFiniteNumber       = "NON_FINITE_NUMBER"
+	CodeInvalidSide           = "INVALID_SIDE"
+	CodeInvalidAsset          = "INVALID_ASSET"
+	CodeInvalidUserID         = "INVALID_USER_ID"
+	CodeStaleNonce            = "STALE_NONCE"
+	CodeInvalidExpiry         = "INVALID_EXPIRY"
+	CodeTakerThrottled        = "TAKER_THROTTLED"
+	CodeInvalidAmend          = "INVALID_AMEND"
+	CodeTagTooLong            = "TAG_TOO_LONG"
+	CodeInvalidAmountPct      = "INVALID_AMOUNT_PCT"
+	CodeBodyTooLarge          = "BODY_TOO_LARGE"
+	CodeDepositConflict       = "DEPOSIT_CONFLICT"
+	CodeOrderIDNotReserved    = "ORDER_ID_NOT_RESERVED"
+	CodeTooManyReservedIDs    = "TOO_MANY_RESERVED_IDS"
+	CodeWouldNotImprove       = "WOULD_NOT_IMPROVE"
+	CodeInvalidPairMode       = "INVALID_PAIR_MODE"
+	CodeMakerOnly             = "MAKER_ONLY"
+	CodeTakerOnly             = "TAKER_ONLY"
+	CodeAuctionMode           = "AUCTION_MODE"
+	CodeNotInAuction          = "NOT_IN_AUCTION"
+	CodeReservedUserID        = "RESERVED_USER_ID"
+)
+
+type errorMapping struct {
+	err    error
+	code   string
+	status int
+}
+
+// errorMappings translates engine, orderbook and account sentinel errors into
+// API codes. Wrapped errors are matched with errors.Is.
+var errorMappings = []errorMapping{
+	{engine.ErrInvalidPair, CodeInvalidPair, http.StatusBadRequest},
+	{engine.ErrSameAssetPair, CodeSameAssetPair, http.StatusBadRequest},
+	{engine.ErrUnknownPair, CodeUnknownPair, http.StatusBadRequest},
+	{engine.ErrPairAlreadyRegistered, CodePairAlreadyRegistered, http.StatusConflict},
+	{engine.ErrInvalidTickSize, CodeInvalidTickSize, http.StatusBadRequest},
+	{engine.ErrInvalidLotSize, CodeInvalidLotSize, http.StatusBadRequest},
+	{engine.ErrInvalidMarketGap, CodeInvalidMarketGap, http.StatusBadRequest},
+	{engine.ErrPairHalted, CodeTradingHalted, http.StatusConflict},
+	{engine.ErrPairDelisted, CodePairDelisted, http.StatusGone},
+	{engine.ErrEngineHalted, CodeEngineHalted, http.StatusServiceUnavailable},
+	{engine.ErrInvalidPriceTick, CodeInvalidPriceTick, http.StatusBadRequest},
+	{engine.ErrInvalidAmountTick, CodeInvalidAmountTick, http.StatusBadRequest},
+	{engine.ErrInvalidAmountLot, CodeInvalidAmountLot, http.StatusBadRequest},
+	{engine.ErrBelowMinOrderSize, CodeBelowMinOrderSize, http.StatusBadRequest},
+	{engine.ErrBelowMinNotional, CodeBelowMinNotional, http.StatusBadRequest},
+	{engine.ErrSelfTrade, CodeSelfTrade, http.StatusConflict},
+	{engine.ErrTooManyOpenOrders, CodeTooManyOpenOrders, http.StatusConflict},
+	{engine.ErrInsufficientLiquidity, CodeInsufficientLiquidity, http.StatusBadRequest},
+	{engine.ErrOrderNotFound, CodeOrderNotFound, http.StatusNotFound},
+	{engine.ErrUnauthorized, CodeUnauthorized, http.StatusUnauthorized},
+	{engine.ErrStaleNonce, CodeStaleNonce, http.StatusConflict},
+	{engine.ErrInvalidExpiry, CodeInvalidExpiry, http.StatusBadRequest},
+	{engine.ErrTakerThrottled, CodeTakerThrottled, http.StatusTooManyRequests},
+	{engine.ErrTagTooLong, CodeTagTooLong, http.StatusBadRequest},
+	{engine.ErrInvalidAmountPct, CodeInvalidAmountPct, http.StatusBadRequest},
+	{engine.ErrOrderIDNotReserved, CodeOrderIDNotReserved, http.StatusConflict},
+	{engine.ErrTooManyReservedIDs, CodeTooManyReservedIDs, http.StatusTooManyRequests},
+	{engine.ErrWouldNotImprove, CodeWouldNotImprove, http.StatusConflict},
+	{engine.ErrInvalidPairMode, CodeInvalidPairMode, http.StatusBadRequest},
+	{engine.ErrMakerOnly, CodeMakerOnly, http.StatusConflict},
+	{engine.ErrTakerOnly, CodeTakerOnly, http.StatusConflict},
+	{engine.ErrAuctionMode, CodeAuctionMode, http.StatusConflict},
+	{engine.ErrNotInAuction, CodeNotInAuction, http.StatusConflict},
+	{engine.ErrReservedUserID, CodeReservedUserID, http.StatusForbidden},
+	{orderbook.ErrOrderNotFound, CodeOrderNotFound, http.StatusNotFound},
+	{orderbook.ErrInvalidPrice, CodeInvalidPrice, http.StatusBadRequest},
+	{orderbook.ErrInvalidAmount, CodeInvalidAmount, http.StatusBadRequest},
+	{orderbook.ErrInvalidSide, CodeInvalidSide, http.StatusBadRequest},
+	{orderbook.ErrNonFinite, CodeNonFiniteNumber, http.StatusBadRequest},
+	{orderbook.ErrAmendIncrease, CodeInvalidAmend, http.StatusBadRequest},
+	{orderbook.ErrAmendBelowFilled, CodeInvalidAmend, http.StatusBadRequest},
+	{account.ErrInsufficientBalance, CodeInsufficientBalance, http.StatusBadRequest},
+	{account.ErrInsufficientLocked, CodeInsufficientLocked, http.StatusBadRequest},
+	{account.ErrInvalidAmount, CodeInvalidAmount, http.StatusBadRequest},
+	{account.ErrNonFiniteAmount, CodeNonFiniteNumber, http.StatusBadRequest},
+	{account.ErrInvalidAsset, CodeInvalidAsset, http.StatusBadRequest},
+	{account.ErrInvalidUserID, CodeInvalidUserID, http.StatusBadRequest},
+	{account.ErrDepositConflict, CodeDepositConflict, http.StatusConflict},
+}
+
+// errorCode returns the API code and HTTP status for a domain error.
+// Unknown errors are reported as internal errors.
+func errorCode(err error) (string, int) {
+	for _, m := range errorMappings {
+		if errors.Is(err, m.err) {
+			return m.code, m.status
+		}
+	}
+	return CodeInternalError, http.StatusInternalServerError
+}
+
+// codeForStatus returns the generic code used for handler-level validation errors.
+func codeForStatus(statusCode int) string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusRequestEntityTooLarge:
+		return CodeBodyTooLarge
+	case http.StatusInternalServerError:
+		return CodeInternalError
+	default:
+		return CodeInvalidRequest
+	}
+}
+
+// decodeJSON decodes the request body into v. Fields v does not declare are
+// rejected, so a client typo fails loudly instead of being silently dropped.
+func decodeJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// LimitBody caps every request body at maxBytes; reading past it fails and
+// the decoding handler answers 413. maxBytes <= 0 leaves bodies unbounded.
+func LimitBody(maxBytes int64, next http.Handler) http.Handler {
+	if maxBytes <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeBodyError answers a decodeJSON error: 413 when the body went over the
+// LimitBody cap, 400 otherwise.
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	writeError(w, bodyErrorMessage(err), http.StatusBadRequest)
+}
+
+// bodyErrorMessage is the client-facing message for a decodeJSON error,
+// naming the offending field when there is one.
+func bodyErrorMessage(err error) string {
+	// encoding/json has no typed error for this one
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return "Invalid request body: unknown field " + field
+	}
+	return "Invalid request body"
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.Errorf("Error encoding JSON response: %v", err)
+	}
+}
+
+// writeError sends a handler-level validation error.
+func writeError(w http.ResponseWriter, message string, statusCode int) {
+	writeJSON(w, v1.ErrorResponse{Error: message, Code: codeForStatus(statusCode)}, statusCode)
+}
+
+// writeDomainError sends an error returned by the engine or account manager,
+// mapping it to its code and HTTP status.
+func writeDomainError(w http.ResponseWriter, err error) {
+	code, statusCode := errorCode(err)
+	writeJSON(w, v1.ErrorResponse{Error: err.Error(), Code: code}, statusCode)
+}
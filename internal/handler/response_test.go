@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+)
+
+func TestErrorCode_Mappings(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   string
+		status int
+	}{
+		{engine.ErrInvalidPair, CodeInvalidPair, http.StatusBadRequest},
+		{engine.ErrOrderNotFound, CodeOrderNotFound, http.StatusNotFound},
+		{engine.ErrUnauthorized, CodeUnauthorized, http.StatusUnauthorized},
+		{engine.ErrSelfTrade, CodeSelfTrade, http.StatusConflict},
+		{account.ErrInsufficientBalance, CodeInsufficientBalance, http.StatusBadRequest},
+		{fmt.Errorf("transfer failed: %w", account.ErrInsufficientLocked), CodeInsufficientLocked, http.StatusBadRequest},
+		{fmt.Errorf("something unexpected"), CodeInternalError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		code, status := errorCode(tt.err)
+		assertEqual(t, tt.code, code, "Code for "+tt.err.Error())
+		assertEqual(t, tt.status, status, "Status for "+tt.err.Error())
+	}
+}
+
+func TestOrderHandler_PlaceOrder_InsufficientBalanceCode(t *testing.T) {
+	e := engine.NewEngine()
+	h := NewOrderHandler(e)
+
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{
+		UserID: "1",
+		Pair:   "BTC/BRL",
+		Side:   "bid",
+		Type:   "limit",
+		Price:  50_000,
+		Amount: 1,
+	})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Status code")
+
+	var resp v1.ErrorResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, CodeInsufficientBalance, resp.Code, "Error code")
+	assertEqual(t, account.ErrInsufficientBalance.Error(), resp.Error, "Error message")
+}
+
+func TestOrderHandler_CancelOrder_NotFoundCode(t *testing.T) {
+	e := engine.NewEngine()
+	h := NewOrderHandler(e)
+
+	rec := doRequest(h.CancelOrder, http.MethodPost, "/api/v1/orders/cancel", v1.CancelOrderRequest{
+		UserID:  "1",
+		Pair:    "BTC/BRL",
+		OrderID: 999,
+	})
+	assertEqual(t, http.StatusNotFound, rec.Code, "Status code")
+
+	var resp v1.ErrorResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, CodeOrderNotFound, resp.Code, "Error code")
+}
+
+func TestOrderHandler_PlaceOrder_ValidationCode(t *testing.T) {
+	h := NewOrderHandler(engine.NewEngine())
+
+	rec := doRequest(h.PlaceOrder, http.MethodPost, "/api/v1/orders", v1.PlaceOrderRequest{Pair: "BTC/BRL"})
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Status code")
+
+	var resp v1.ErrorResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, CodeInvalidRequest, resp.Code, "Error code")
+}
+
+// endlessBody streams an unterminated JSON string forever, counting what the
+// server pulls from it.
+type endlessBody struct {
+	prefix io.Reader
+	read   int64
+}
+
+func (b *endlessBody) Read(p []byte) (int, error) {
+	n, _ := b.prefix.Read(p)
+	for i := n; i < len(p); i++ {
+		p[i] = 'a'
+	}
+	b.read += int64(len(p))
+	return len(p), nil
+}
+
+func TestLimitBody_OversizedBody(t *testing.T) {
+	const limit = 1024
+	h := NewAccountHandler(engine.NewEngine().GetAccountManager())
+	limited := LimitBody(limit, http.HandlerFunc(h.CreditBatch))
+
+	body := &endlessBody{prefix: strings.NewReader(`{"entries":[{"user_id":"`)}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/credit/batch", body)
+	rec := httptest.NewRecorder()
+	limited.ServeHTTP(rec, req)
+
+	assertEqual(t, http.StatusRequestEntityTooLarge, rec.Code, "Status code")
+	var resp v1.ErrorResponse
+	decodeBody(t, rec, &resp)
+	assertEqual(t, CodeBodyTooLarge, resp.Code, "Error code")
+	// The reader stops one byte past the limit, plus at most one buffer the
+	// decoder asked for before the limit was hit.
+	assertTrue(t, body.read <= 2*limit, fmt.Sprintf("read %d bytes of an endless body", body.read))
+}
+
+func TestLimitBody_WithinLimit(t *testing.T) {
+	h := NewAccountHandler(engine.NewEngine().GetAccountManager())
+	limited := LimitBody(1024, http.HandlerFunc(h.Credit))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/credit",
+		strings.NewReader(`{"user_id":"1","asset":"BRL","amount":100}`))
+	rec := httptest.NewRecorder()
+	limited.ServeHTTP(rec, req)
+
+	assertEqual(t, http.StatusOK, rec.Code, "Status code")
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/pkg/logger"
+	"golang.org/x/net/websocket"
+)
+
+// SessionHandler serves the order session WebSocket. A session is bound to a
+// single user; with cancel_on_disconnect=true every resting order of that user
+// is cancelled as soon as the connection drops. Orders of the user the engine
+// rejects are pushed to each of its sessions.
+type SessionHandler struct {
+	engine *engine.Engine
+
+	mu       sync.Mutex
+	sessions map[*websocket.Conn]*session
+}
+
+// session is one open order session. events buffers the messages pushed to
+// it, so a slow client loses them instead of holding up the engine.
+type session struct {
+	userID string
+	events chan v1.SessionMessage
+}
+
+func NewSessionHandler(engine *engine.Engine) *SessionHandler {
+	h := &SessionHandler{
+		engine:   engine,
+		sessions: make(map[*websocket.Conn]*session),
+	}
+	engine.OnReject(h.publishRejection)
+	return h
+}
+
+// OrderSession godoc
+// @Summary Open an order session
+// @Description WebSocket control connection for a user. With cancel_on_disconnect=true all of the user's resting orders are cancelled when the connection drops
+// @Tags Orders
+// @Param user_id query string true "User ID"
+// @Param cancel_on_disconnect query bool false "Cancel all resting orders on disconnect"
+// @Success 101 {object} v1.SessionMessage "Switching protocols"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Router /api/v1/ws/orders [get]
+func (h *SessionHandler) OrderSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, "user_id is required", http.StatusBadRequest)
+		logger.Warning("Order session - missing user_id")
+		return
+	}
+
+	cancelOnDisconnect := false
+	if raw := r.URL.Query().Get("cancel_on_disconnect"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, "cancel_on_disconnect must be a boolean", http.StatusBadRequest)
+			logger.Warningf("Order session - invalid cancel_on_disconnect: %q", raw)
+			return
+		}
+		cancelOnDisconnect = v
+	}
+
+	websocket.Server{
+		Handler: func(conn *websocket.Conn) {
+			h.serve(conn, userID, cancelOnDisconnect)
+		},
+	}.ServeHTTP(w, r)
+}
+
+// ActiveSessions returns how many sessions userID currently has open.
+func (h *SessionHandler) ActiveSessions(userID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := 0
+	for _, s := range h.sessions {
+		if s.userID == userID {
+			count++
+		}
+	}
+	return count
+}
+
+// publishRejection queues rejection on every session of its user without
+// blocking. It runs with the engine lock held.
+func (h *SessionHandler) publishRejection(rejection engine.Rejection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	event := rejectionEvent(rejection)
+	for _, s := range h.sessions {
+		if s.userID != rejection.UserID {
+			continue
+		}
+		select {
+		case s.events <- v1.SessionMessage{Type: "rejected", Rejection: &event}:
+		default:
+			logger.Warningf("Order session too slow, dropping rejection - User: %s - Seq: %d",
+				s.userID, rejection.Seq)
+		}
+	}
+}
+
+func (h *SessionHandler) serve(conn *websocket.Conn, userID string, cancelOnDisconnect bool) {
+	start := time.Now()
+
+	s := &session{userID: userID, events: make(chan v1.SessionMessage, streamBuffer)}
+	h.mu.Lock()
+	h.sessions[conn] = s
+	h.mu.Unlock()
+
+	logger.Infof("Order session opened - User: %s - CancelOnDisconnect: %t", userID, cancelOnDisconnect)
+
+	defer h.disconnect(conn, userID, cancelOnDisconnect, start)
+
+	hello := v1.SessionMessage{Type: "session", UserID: userID, CancelOnDisconnect: cancelOnDisconnect}
+	if err := websocket.JSON.Send(conn, hello); err != nil {
+		return
+	}
+
+	// Pushed events are written alongside the replies below; the connection
+	// serializes whole frames.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case msg := <-s.events:
+				if err := websocket.JSON.Send(conn, msg); err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		var msg v1.SessionMessage
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			return
+		}
+
+		reply := v1.SessionMessage{Type: "pong"}
+		if msg.Type != "ping" {
+			reply = v1.SessionMessage{Type: "error", Error: "unsupported message type"}
+		}
+		if err := websocket.JSON.Send(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// disconnect runs once the session's connection is gone. The session stays
+// registered until its orders are cancelled so ActiveSessions never reports a
+// closed session whose cancels are still in flight.
+func (h *SessionHandler) disconnect(conn *websocket.Conn, userID string, cancelOnDisconnect bool, start time.Time) {
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, conn)
+		h.mu.Unlock()
+	}()
+
+	_ = conn.Close()
+
+	if !cancelOnDisconnect {
+		logger.Infof("Order session closed - User: %s - Duration: %v", userID, time.Since(start))
+		return
+	}
+
+	cancelled, err := h.engine.CancelAllUserOrders(userID)
+	if err != nil {
+		logger.Errorf("Order session closed - User: %s - Cancel on disconnect failed after %d orders - Error: %v",
+			userID, len(cancelled), err)
+		return
+	}
+
+	logger.Infof("Order session closed - User: %s - Cancelled: %d - Duration: %v",
+		userID, len(cancelled), time.Since(start))
+}
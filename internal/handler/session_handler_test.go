@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"golang.org/x/net/websocket"
+)
+
+// openSession dials the session endpoint and waits for the server greeting.
+func openSession(t *testing.T, h *SessionHandler, query string) *websocket.Conn {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(h.OrderSession))
+	t.Cleanup(srv.Close)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/ws/orders?" + query
+	conn, err := websocket.Dial(url, "", srv.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	var hello v1.SessionMessage
+	if err := websocket.JSON.Receive(conn, &hello); err != nil {
+		t.Fatalf("receive greeting: %v", err)
+	}
+	assertEqual(t, "session", hello.Type, "Greeting type")
+	return conn
+}
+
+// waitForDisconnect blocks until the server has dropped every session of userID.
+func waitForDisconnect(t *testing.T, h *SessionHandler, userID string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for h.ActiveSessions(userID) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("session was not closed by the server")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func placeRestingOrders(t *testing.T, e *engine.Engine) (*orderbook.Order, *orderbook.Order) {
+	t.Helper()
+
+	bid, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Bid, 40_000, 0.5)
+	assertNoError(t, err)
+	ask, _, err := e.PlaceOrder("1", btcBrl(), orderbook.Ask, 60_000, 1)
+	assertNoError(t, err)
+	return bid, ask
+}
+
+func TestSessionHandler_CancelOnDisconnect(t *testing.T) {
+	e := setupEngine()
+	h := NewSessionHandler(e)
+	bid, ask := placeRestingOrders(t, e)
+	other, _, err := e.PlaceOrder("2", btcBrl(), orderbook.Bid, 39_000, 1)
+	assertNoError(t, err)
+
+	conn := openSession(t, h, "user_id=1&cancel_on_disconnect=true")
+	assertEqual(t, 1, h.ActiveSessions("1"), "Active sessions")
+
+	_ = conn.Close()
+	waitForDisconnect(t, h, "1")
+
+	assertEqual(t, orderbook.OrderCancelled, bid.State, "Bid state")
+	assertEqual(t, orderbook.OrderCancelled, ask.State, "Ask state")
+	assertFloat(t, 0, e.GetAccountManager().GetBalance("1", "BRL").Locked, "BRL unlocked")
+	assertFloat(t, 0, e.GetAccountManager().GetBalance("1", "BTC").Locked, "BTC unlocked")
+	assertEqual(t, orderbook.OrderOpen, other.State, "Other user's order untouched")
+}
+
+func TestSessionHandler_NoCancelWithoutOptIn(t *testing.T) {
+	e := setupEngine()
+	h := NewSessionHandler(e)
+	bid, ask := placeRestingOrders(t, e)
+
+	conn := openSession(t, h, "user_id=1")
+	_ = conn.Close()
+	waitForDisconnect(t, h, "1")
+
+	assertEqual(t, orderbook.OrderOpen, bid.State, "Bid state")
+	assertEqual(t, orderbook.OrderOpen, ask.State, "Ask state")
+}
+
+func TestSessionHandler_Ping(t *testing.T) {
+	h := NewSessionHandler(setupEngine())
+	conn := openSession(t, h, "user_id=1")
+	defer conn.Close()
+
+	assertNoError(t, websocket.JSON.Send(conn, v1.SessionMessage{Type: "ping"}))
+
+	var reply v1.SessionMessage
+	assertNoError(t, websocket.JSON.Receive(conn, &reply))
+	assertEqual(t, "pong", reply.Type, "Reply type")
+}
+
+func TestSessionHandler_MissingUserID(t *testing.T) {
+	h := NewSessionHandler(setupEngine())
+
+	rec := doRequest(h.OrderSession, http.MethodGet, "/api/v1/ws/orders", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Status code")
+}
+
+func TestSessionHandler_PushesRejections(t *testing.T) {
+	e := setupEngine()
+	h := NewSessionHandler(e)
+	orders := NewOrderHandler(e)
+
+	conn := openSession(t, h, "user_id=1")
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	req := v1.PlaceOrderRequest{UserID: "1", Pair: "BTC/BRL", Side: "bid", Type: "limit", Price: 50_000, Amount: 100, Tag: "grid-1"}
+	rec := doRequest(orders.PlaceOrder, http.MethodPost, "/api/v1/orders", req)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Order rejected")
+
+	var msg v1.SessionMessage
+	if err := websocket.JSON.Receive(conn, &msg); err != nil {
+		t.Fatalf("receive rejection: %v", err)
+	}
+	assertEqual(t, "rejected", msg.Type, "Message type")
+	assertTrue(t, msg.Rejection != nil, "Rejection attached")
+	assertEqual(t, CodeInsufficientBalance, msg.Rejection.Reason, "Reason code")
+	assertEqual(t, "grid-1", msg.Rejection.Tag, "Tag")
+	assertFloat(t, 100, msg.Rejection.Amount.Float64(), "Requested amount")
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"github.com/moura95/crypto-exchange-challenge/pkg/logger"
+	"golang.org/x/net/websocket"
+)
+
+// streamBuffer is how many events a stream connection may fall behind before
+// further events are dropped for it.
+const streamBuffer = 256
+
+// StreamHandler serves the public market data WebSockets. Trades are
+// published as the engine records them; each connection has its own buffer,
+// so a slow client loses events instead of holding up matching.
+type StreamHandler struct {
+	engine *engine.Engine
+	subs   *SubscriptionManager
+}
+
+func NewStreamHandler(engine *engine.Engine) *StreamHandler {
+	h := &StreamHandler{engine: engine, subs: NewSubscriptionManager()}
+	engine.OnTrade(h.publishTrade)
+	return h
+}
+
+// TradeStream godoc
+// @Summary Stream trades
+// @Description WebSocket feed of every trade executed on a pair, in sequence order. The server first sends a "subscribed" acknowledgement, then one "trades" event per trade. With checksums=true the feed also carries periodic "orderbook" events holding the book checksum. Events are dropped for clients that fall too far behind
+// @Tags Orderbook
+// @Param pair query string true "Trading pair (e.g., BTC/BRL)"
+// @Param checksums query bool false "Also send periodic book checksums"
+// @Success 101 {object} v1.TradeEvent "Switching protocols"
+// @Failure 400 {object} v1.ErrorResponse "Invalid request"
+// @Router /api/v1/ws/trades [get]
+func (h *StreamHandler) TradeStream(w http.ResponseWriter, r *http.Request) {
+	pair := r.URL.Query().Get("pair")
+	if pair == "" {
+		writeError(w, "pair is required", http.StatusBadRequest)
+		logger.Warning("Trade stream - missing pair")
+		return
+	}
+
+	events := []string{EventTrades}
+	if raw := r.URL.Query().Get("checksums"); raw != "" {
+		checksums, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, "checksums must be true or false", http.StatusBadRequest)
+			logger.Warningf("Trade stream - invalid checksums %q", raw)
+			return
+		}
+		if checksums {
+			events = append(events, EventOrderbook)
+		}
+	}
+
+	sub := &streamConn{events: make(chan v1.StreamEvent, streamBuffer), remote: r.RemoteAddr}
+	ack := h.subs.Apply(sub, v1.StreamControl{Op: "subscribe", Pairs: []string{pair}, Events: events})
+	if ack.Type == "error" {
+		writeError(w, ack.Error, http.StatusBadRequest)
+		logger.Warningf("Trade stream - invalid pair %q: %s", pair, ack.Error)
+		return
+	}
+	defer h.subs.Remove(sub)
+
+	websocket.Server{
+		Handler: func(conn *websocket.Conn) {
+			h.serveTrades(conn, sub, ack, pair)
+		},
+	}.ServeHTTP(w, r)
+}
+
+func (h *StreamHandler) serveTrades(conn *websocket.Conn, sub *streamConn, ack v1.StreamAck, pair string) {
+	start := time.Now()
+	logger.Infof("Trade stream opened - Pair: %s - Remote: %s", pair, sub.remote)
+
+	defer func() {
+		_ = conn.Close()
+		logger.Infof("Trade stream closed - Pair: %s - Remote: %s - Dropped: %d - Duration: %v",
+			pair, sub.remote, sub.dropped.Load(), time.Since(start))
+	}()
+
+	if err := websocket.JSON.Send(conn, ack); err != nil {
+		return
+	}
+
+	// The feed is one-way; reading only notices the client going away.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var discard []byte
+		for websocket.Message.Receive(conn, &discard) == nil {
+		}
+	}()
+
+	for {
+		select {
+		case event := <-sub.events:
+			if err := websocket.JSON.Send(conn, event); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (h *StreamHandler) publishTrade(t engine.Trade) {
+	h.subs.Publish(v1.StreamEvent{
+		Type: EventTrades,
+		Pair: t.Pair.String(),
+		Data: v1.TradeEvent{
+			Seq:       t.Seq,
+			PairSeq:   t.PairSeq,
+			Price:     v1.Fiat(t.Price),
+			Amount:    v1.Crypto(t.Amount),
+			TakerSide: string(t.TakerSide),
+			Timestamp: t.Timestamp,
+		},
+	})
+}
+
+// StartChecksums publishes the checksum of every listed pair's book to its
+// "orderbook" subscribers each interval, so clients keeping a local copy of
+// the book can detect drift. It returns a function that stops it. A
+// non-positive interval disables it.
+func (h *StreamHandler) StartChecksums(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.publishChecksums()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func (h *StreamHandler) publishChecksums() {
+	clock := h.engine.Config().Clock
+	for _, cfg := range h.engine.ListPairs() {
+		ob := h.engine.GetOrderbook(cfg.Pair)
+		if ob == nil {
+			continue
+		}
+		h.subs.Publish(v1.StreamEvent{
+			Type: EventOrderbook,
+			Pair: cfg.Pair.String(),
+			Data: v1.BookChecksumEvent{
+				Depth:     orderbook.ChecksumDepth,
+				Checksum:  ob.Checksum(orderbook.ChecksumDepth),
+				Timestamp: clock.Now(),
+			},
+		})
+	}
+}
+
+// streamConn is the Subscriber of one stream connection.
+type streamConn struct {
+	events  chan v1.StreamEvent
+	remote  string
+	dropped atomic.Int64
+	lagging atomic.Bool // set from the first drop until an event fits again
+}
+
+// Deliver queues event without blocking. When the buffer is full the event is
+// dropped; the first drop of each streak is logged.
+func (c *streamConn) Deliver(event v1.StreamEvent) {
+	select {
+	case c.events <- event:
+		c.lagging.Store(false)
+	default:
+		c.dropped.Add(1)
+		if c.lagging.CompareAndSwap(false, true) {
+			logger.Warningf("Stream consumer too slow, dropping events - Remote: %s - Type: %s - Pair: %s",
+				c.remote, event.Type, event.Pair)
+		}
+	}
+}
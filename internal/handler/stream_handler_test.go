@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+	"golang.org/x/net/websocket"
+)
+
+// tradeMessage is a StreamEvent carrying a trade.
+type tradeMessage struct {
+	Type string        `json:"type"`
+	Pair string        `json:"pair"`
+	Data v1.TradeEvent `json:"data"`
+}
+
+func TestStreamHandler_TradeStream_PushesTrades(t *testing.T) {
+	e := setupEngine()
+	h := NewStreamHandler(e)
+
+	srv := httptest.NewServer(http.HandlerFunc(h.TradeStream))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/ws/trades?pair=btc/brl"
+	conn, err := websocket.Dial(url, "", srv.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var ack v1.StreamAck
+	if err := websocket.JSON.Receive(conn, &ack); err != nil {
+		t.Fatalf("receive ack: %v", err)
+	}
+	assertEqual(t, "subscribed", ack.Type, "Ack type")
+	assertEqual(t, "BTC/BRL:trades", strings.Join(ack.Subscriptions, ","), "Subscriptions")
+
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	_, _, err = e.PlaceOrder("1", btcBrl(), orderbook.Bid, 50_000, 0.5)
+	assertNoError(t, err)
+
+	var msg tradeMessage
+	if err := websocket.JSON.Receive(conn, &msg); err != nil {
+		t.Fatalf("receive trade: %v", err)
+	}
+	assertEqual(t, EventTrades, msg.Type, "Event type")
+	assertEqual(t, "BTC/BRL", msg.Pair, "Pair")
+	assertEqual(t, "50000.00", msg.Data.Price.String(), "Price")
+	assertEqual(t, "0.50000000", msg.Data.Amount.String(), "Amount")
+	assertEqual(t, "bid", msg.Data.TakerSide, "Aggressor side")
+	assertEqual(t, uint64(1), msg.Data.PairSeq, "Pair sequence")
+}
+
+// checksumMessage is a StreamEvent carrying a book checksum.
+type checksumMessage struct {
+	Type string               `json:"type"`
+	Pair string               `json:"pair"`
+	Data v1.BookChecksumEvent `json:"data"`
+}
+
+func TestStreamHandler_TradeStream_Checksums(t *testing.T) {
+	e := setupEngine()
+	h := NewStreamHandler(e)
+
+	srv := httptest.NewServer(http.HandlerFunc(h.TradeStream))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/ws/trades?pair=BTC/BRL&checksums=true"
+	conn, err := websocket.Dial(url, "", srv.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var ack v1.StreamAck
+	if err := websocket.JSON.Receive(conn, &ack); err != nil {
+		t.Fatalf("receive ack: %v", err)
+	}
+	assertEqual(t, "BTC/BRL:orderbook,BTC/BRL:trades", strings.Join(ack.Subscriptions, ","), "Subscriptions")
+
+	_, _, err = e.PlaceOrder("2", btcBrl(), orderbook.Ask, 50_000, 0.5)
+	assertNoError(t, err)
+	h.publishChecksums()
+
+	var msg checksumMessage
+	if err := websocket.JSON.Receive(conn, &msg); err != nil {
+		t.Fatalf("receive checksum: %v", err)
+	}
+	assertEqual(t, EventOrderbook, msg.Type, "Event type")
+	assertEqual(t, "BTC/BRL", msg.Pair, "Pair")
+	assertEqual(t, orderbook.ChecksumDepth, msg.Data.Depth, "Depth")
+	assertEqual(t, e.GetOrderbook(btcBrl()).Checksum(orderbook.ChecksumDepth), msg.Data.Checksum, "Checksum")
+}
+
+func TestStreamHandler_TradeStream_MissingPair(t *testing.T) {
+	h := NewStreamHandler(setupEngine())
+
+	rec := doRequest(h.TradeStream, http.MethodGet, "/api/v1/ws/trades", nil)
+	assertEqual(t, http.StatusBadRequest, rec.Code, "Status code")
+}
+
+func TestStreamConn_DropsWhenFull(t *testing.T) {
+	c := &streamConn{events: make(chan v1.StreamEvent, 1)}
+	event := v1.StreamEvent{Type: EventTrades, Pair: "BTC/BRL"}
+
+	c.Deliver(event)
+	c.Deliver(event)
+	c.Deliver(event)
+	assertEqual(t, int64(2), c.dropped.Load(), "Events dropped")
+	assertTrue(t, c.lagging.Load(), "Consumer lagging")
+
+	<-c.events
+	c.Deliver(event)
+	assertEqual(t, int64(2), c.dropped.Load(), "Delivered once there is room")
+	assertTrue(t, !c.lagging.Load(), "Caught up")
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+)
+
+// Stream event types a connection can subscribe to.
+const (
+	EventTrades    = "trades"
+	EventOrderbook = "orderbook"
+)
+
+var streamEvents = []string{EventTrades, EventOrderbook}
+
+var (
+	errUnknownStreamOp    = errors.New("op must be subscribe or unsubscribe")
+	errNoStreamPairs      = errors.New("pairs is required")
+	errUnknownStreamEvent = errors.New("events must be trades or orderbook")
+)
+
+// Subscriber receives the events a SubscriptionManager routes to it. Deliver
+// must not block: a slow subscriber should drop events rather than stall
+// publishing.
+type Subscriber interface {
+	Deliver(event v1.StreamEvent)
+}
+
+type subscription struct {
+	pair  string
+	event string
+}
+
+// SubscriptionManager tracks which pairs and event types each stream
+// connection wants, so a single connection can follow several pairs and
+// published events only reach interested connections.
+type SubscriptionManager struct {
+	mu    sync.RWMutex
+	subs  map[Subscriber]map[subscription]struct{}
+	route map[subscription]map[Subscriber]struct{}
+}
+
+func NewSubscriptionManager() *SubscriptionManager {
+	return &SubscriptionManager{
+		subs:  make(map[Subscriber]map[subscription]struct{}),
+		route: make(map[subscription]map[Subscriber]struct{}),
+	}
+}
+
+// Apply handles a control message from sub and returns the acknowledgement
+// to send back. Nothing changes when the message is invalid.
+func (m *SubscriptionManager) Apply(sub Subscriber, msg v1.StreamControl) v1.StreamAck {
+	keys, err := parseStreamControl(msg)
+	if err != nil {
+		return v1.StreamAck{Type: "error", Error: err.Error()}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if msg.Op == "subscribe" {
+			m.add(sub, key)
+		} else {
+			m.remove(sub, key)
+		}
+	}
+
+	return v1.StreamAck{Type: msg.Op + "d", Subscriptions: m.list(sub)}
+}
+
+// Remove drops every subscription of sub, e.g. once its connection closes.
+func (m *SubscriptionManager) Remove(sub Subscriber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.subs[sub] {
+		m.remove(sub, key)
+	}
+	delete(m.subs, sub)
+}
+
+// Publish delivers event to every subscriber of its pair and type and
+// returns how many received it.
+func (m *SubscriptionManager) Publish(event v1.StreamEvent) int {
+	m.mu.RLock()
+	targets := make([]Subscriber, 0, len(m.route[subscription{event.Pair, event.Type}]))
+	for sub := range m.route[subscription{event.Pair, event.Type}] {
+		targets = append(targets, sub)
+	}
+	m.mu.RUnlock()
+
+	for _, sub := range targets {
+		sub.Deliver(event)
+	}
+	return len(targets)
+}
+
+// Subscriptions returns sub's active subscriptions as sorted "PAIR:event"
+// strings.
+func (m *SubscriptionManager) Subscriptions(sub Subscriber) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.list(sub)
+}
+
+// add, remove and list must be called with m.mu held.
+func (m *SubscriptionManager) add(sub Subscriber, key subscription) {
+	if m.subs[sub] == nil {
+		m.subs[sub] = make(map[subscription]struct{})
+	}
+	m.subs[sub][key] = struct{}{}
+
+	if m.route[key] == nil {
+		m.route[key] = make(map[Subscriber]struct{})
+	}
+	m.route[key][sub] = struct{}{}
+}
+
+func (m *SubscriptionManager) remove(sub Subscriber, key subscription) {
+	delete(m.subs[sub], key)
+	delete(m.route[key], sub)
+	if len(m.route[key]) == 0 {
+		delete(m.route, key)
+	}
+}
+
+func (m *SubscriptionManager) list(sub Subscriber) []string {
+	result := make([]string, 0, len(m.subs[sub]))
+	for key := range m.subs[sub] {
+		result = append(result, key.pair+":"+key.event)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// parseStreamControl validates msg and expands it into pair/event keys.
+func parseStreamControl(msg v1.StreamControl) ([]subscription, error) {
+	if msg.Op != "subscribe" && msg.Op != "unsubscribe" {
+		return nil, errUnknownStreamOp
+	}
+	if len(msg.Pairs) == 0 {
+		return nil, errNoStreamPairs
+	}
+
+	events := msg.Events
+	if len(events) == 0 {
+		events = streamEvents
+	}
+	for _, event := range events {
+		if event != EventTrades && event != EventOrderbook {
+			return nil, errUnknownStreamEvent
+		}
+	}
+
+	keys := make([]subscription, 0, len(msg.Pairs)*len(events))
+	for _, raw := range msg.Pairs {
+		base, quote, ok := strings.Cut(raw, "/")
+		if !ok || base == "" || quote == "" {
+			return nil, &PairError{raw}
+		}
+		pair := strings.ToUpper(base) + "/" + strings.ToUpper(quote)
+		for _, event := range events {
+			keys = append(keys, subscription{pair: pair, event: event})
+		}
+	}
+	return keys, nil
+}
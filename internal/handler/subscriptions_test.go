@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"testing"
+
+	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
+)
+
+// recorder is a Subscriber that keeps every delivered event.
+type recorder struct {
+	events []v1.StreamEvent
+}
+
+func (r *recorder) Deliver(event v1.StreamEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestSubscriptionManager_OnlySubscribedPair(t *testing.T) {
+	m := NewSubscriptionManager()
+	sub := &recorder{}
+
+	ack := m.Apply(sub, v1.StreamControl{Op: "subscribe", Pairs: []string{"BTC/BRL"}, Events: []string{EventTrades}})
+	assertEqual(t, "subscribed", ack.Type, "Ack type")
+	assertEqual(t, 1, len(ack.Subscriptions), "Subscriptions")
+	assertEqual(t, "BTC/BRL:trades", ack.Subscriptions[0], "Subscription key")
+
+	m.Publish(v1.StreamEvent{Type: EventTrades, Pair: "ETH/BRL"})
+	m.Publish(v1.StreamEvent{Type: EventOrderbook, Pair: "BTC/BRL"})
+	assertEqual(t, 0, len(sub.events), "Other pair and other event type not delivered")
+
+	delivered := m.Publish(v1.StreamEvent{Type: EventTrades, Pair: "BTC/BRL"})
+	assertEqual(t, 1, delivered, "Delivered to one subscriber")
+	assertEqual(t, 1, len(sub.events), "Subscribed event delivered")
+	assertEqual(t, "BTC/BRL", sub.events[0].Pair, "Event pair")
+}
+
+func TestSubscriptionManager_MultiplePairsOneConnection(t *testing.T) {
+	m := NewSubscriptionManager()
+	sub := &recorder{}
+
+	ack := m.Apply(sub, v1.StreamControl{Op: "subscribe", Pairs: []string{"btc/brl", "ETH/BRL"}})
+	assertEqual(t, 4, len(ack.Subscriptions), "Both event types on both pairs")
+
+	m.Publish(v1.StreamEvent{Type: EventTrades, Pair: "BTC/BRL"})
+	m.Publish(v1.StreamEvent{Type: EventOrderbook, Pair: "ETH/BRL"})
+	assertEqual(t, 2, len(sub.events), "Events of both pairs delivered")
+
+	ack = m.Apply(sub, v1.StreamControl{Op: "unsubscribe", Pairs: []string{"BTC/BRL"}})
+	assertEqual(t, "unsubscribed", ack.Type, "Ack type")
+	assertEqual(t, 2, len(ack.Subscriptions), "Only ETH/BRL left")
+
+	m.Publish(v1.StreamEvent{Type: EventTrades, Pair: "BTC/BRL"})
+	assertEqual(t, 2, len(sub.events), "Unsubscribed pair no longer delivered")
+}
+
+func TestSubscriptionManager_Remove(t *testing.T) {
+	m := NewSubscriptionManager()
+	a, b := &recorder{}, &recorder{}
+	m.Apply(a, v1.StreamControl{Op: "subscribe", Pairs: []string{"BTC/BRL"}})
+	m.Apply(b, v1.StreamControl{Op: "subscribe", Pairs: []string{"BTC/BRL"}})
+
+	m.Remove(a)
+	delivered := m.Publish(v1.StreamEvent{Type: EventTrades, Pair: "BTC/BRL"})
+	assertEqual(t, 1, delivered, "Only the remaining subscriber")
+	assertEqual(t, 0, len(a.events), "Removed subscriber gets nothing")
+	assertEqual(t, 0, len(m.Subscriptions(a)), "No subscriptions left")
+}
+
+func TestSubscriptionManager_InvalidControl(t *testing.T) {
+	m := NewSubscriptionManager()
+	sub := &recorder{}
+
+	invalid := []v1.StreamControl{
+		{Op: "listen", Pairs: []string{"BTC/BRL"}},
+		{Op: "subscribe"},
+		{Op: "subscribe", Pairs: []string{"BTC"}},
+		{Op: "subscribe", Pairs: []string{"BTC/BRL"}, Events: []string{"quotes"}},
+	}
+	for _, msg := range invalid {
+		ack := m.Apply(sub, msg)
+		assertEqual(t, "error", ack.Type, "Rejected control message")
+	}
+	assertEqual(t, 0, len(m.Subscriptions(sub)), "Nothing subscribed")
+}
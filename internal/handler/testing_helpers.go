@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func assertEqual(t *testing.T, expected, actual interface{}, msg string) {
+	t.Helper()
+	if expected != actual {
+		t.Errorf("%s: expected %v, got %v", msg, expected, actual)
+	}
+}
+
+func assertFloat(t *testing.T, expected, actual float64, msg string) {
+	t.Helper()
+	if expected != actual {
+		t.Errorf("%s: expected %.8f, got %.8f", msg, expected, actual)
+	}
+}
+
+func assertTrue(t *testing.T, condition bool, msg string) {
+	t.Helper()
+	if !condition {
+		t.Errorf("%s: expected true", msg)
+	}
+}
+
+func doRequest(handlerFunc http.HandlerFunc, method, target string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+
+	req := httptest.NewRequest(method, target, &buf)
+	rec := httptest.NewRecorder()
+	handlerFunc(rec, req)
+	return rec
+}
+
+// doRoutedRequest is doRequest through a mux serving handlerFunc at
+// pattern, so the handler sees the request's path values.
+func doRoutedRequest(pattern string, handlerFunc http.HandlerFunc, method, target string, body interface{}) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, handlerFunc)
+	return doRequest(mux.ServeHTTP, method, target, body)
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package orderbook
+
+import (
+	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
+)
+
+// AuctionClearing is the price a call auction would uncross the book at and
+// what would trade there.
+type AuctionClearing struct {
+	Price     float64 // uniform price every match executes at; 0 when nothing crosses
+	Volume    float64 // base amount that trades at Price
+	Imbalance float64 // bid volume at or above Price minus ask volume at or below it
+}
+
+// RestLimitOrder adds order to the book without matching it, so it may rest
+// across the opposite side, as orders do while a call auction collects them.
+func (ob *Orderbook) RestLimitOrder(order *Order) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	ob.addOrderToBook(order, utils.PriceToTicks(order.Price, ob.priceTick))
+}
+
+// AuctionClearing computes the clearing price of a call auction over the
+// orders resting now, measuring volume in whole multiples of amountTick.
+// See auctionClearing for how the price is chosen.
+func (ob *Orderbook) AuctionClearing(amountTick, refPrice float64) AuctionClearing {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	clearing, _ := ob.auctionClearing(amountTick, refPrice, false)
+	return clearing
+}
+
+// VisibleAuctionClearing is AuctionClearing over the visible orders alone:
+// hidden orders are left out of the volumes and their levels, as in the
+// public depth, so the result may differ from what RunAuction executes.
+func (ob *Orderbook) VisibleAuctionClearing(amountTick, refPrice float64) AuctionClearing {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	clearing, _ := ob.auctionClearing(amountTick, refPrice, true)
+	return clearing
+}
+
+// auctionClearing picks the clearing price among the prices of the resting
+// levels. At a price p, demand is the bid volume priced at or above p and
+// supply the ask volume priced at or below it; min(demand, supply) trades.
+// The price chosen is, in order:
+//
+//  1. the one that trades the most volume;
+//  2. among those, the one leaving the smallest imbalance |demand - supply|;
+//  3. if every remaining candidate has more demand than supply, the highest
+//     of them, and if every one has more supply, the lowest;
+//  4. otherwise the one closest to refPrice (usually the last trade), or,
+//     with no refPrice or two equally close, the lower.
+//
+// The candidates are swept once in ascending price, keeping demand and
+// supply as running sums, so the cost is linear in the number of levels.
+// With visibleOnly, hidden orders count for nothing and a level holding
+// only hidden orders is no candidate. It also returns the price in ticks.
+// Must be called with ob.mu held.
+func (ob *Orderbook) auctionClearing(amountTick, refPrice float64, visibleOnly bool) (AuctionClearing, int64) {
+	type candidate struct {
+		ticks          int64
+		demand, supply int64
+	}
+
+	volume := func(l *Limit) int64 {
+		if visibleOnly {
+			return utils.AmountToTicks(l.VisibleVolume(), amountTick)
+		}
+		return utils.AmountToTicks(l.TotalVolume, amountTick)
+	}
+
+	// Every bid is demand at the lowest price; the sweep drops each bid
+	// level once the price passes it and adds each ask level once reached.
+	var demand, supply int64
+	for _, bid := range ob.bids {
+		demand += volume(bid)
+	}
+
+	var candidates []candidate
+	bi, ai := len(ob.bids)-1, 0 // bids are sorted descending, asks ascending
+	for bi >= 0 || ai < len(ob.asks) {
+		var ticks int64
+		switch {
+		case ai == len(ob.asks):
+			ticks = ob.bids[bi].PriceTicks
+		case bi < 0:
+			ticks = ob.asks[ai].PriceTicks
+		default:
+			ticks = minTicks(ob.bids[bi].PriceTicks, ob.asks[ai].PriceTicks)
+		}
+
+		shown := !visibleOnly
+		for ; ai < len(ob.asks) && ob.asks[ai].PriceTicks == ticks; ai++ {
+			v := volume(ob.asks[ai])
+			supply += v
+			shown = shown || v > 0
+		}
+		c := candidate{ticks: ticks, demand: demand, supply: supply}
+		for ; bi >= 0 && ob.bids[bi].PriceTicks == ticks; bi-- {
+			v := volume(ob.bids[bi])
+			demand -= v
+			shown = shown || v > 0
+		}
+
+		if shown && minTicks(c.demand, c.supply) > 0 {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		return AuctionClearing{}, 0
+	}
+
+	imbalance := func(c candidate) int64 {
+		if c.demand > c.supply {
+			return c.demand - c.supply
+		}
+		return c.supply - c.demand
+	}
+
+	// 1. Maximum volume, then 2. minimum imbalance
+	var best []candidate
+	for _, c := range candidates {
+		if len(best) == 0 {
+			best = append(best, c)
+			continue
+		}
+		volume, bestVolume := minTicks(c.demand, c.supply), minTicks(best[0].demand, best[0].supply)
+		switch {
+		case volume > bestVolume, volume == bestVolume && imbalance(c) < imbalance(best[0]):
+			best = append(best[:0], c)
+		case volume == bestVolume && imbalance(c) == imbalance(best[0]):
+			best = append(best, c)
+		}
+	}
+
+	// 3. Market pressure
+	lowest, highest := best[0], best[0]
+	buyers, sellers := true, true
+	for _, c := range best {
+		if c.ticks < lowest.ticks {
+			lowest = c
+		}
+		if c.ticks > highest.ticks {
+			highest = c
+		}
+		buyers = buyers && c.demand > c.supply
+		sellers = sellers && c.supply > c.demand
+	}
+
+	chosen := lowest
+	switch {
+	case buyers:
+		chosen = highest
+	case sellers:
+		chosen = lowest
+	case refPrice > 0:
+		// 4. Reference price
+		ref := utils.PriceToTicks(refPrice, ob.priceTick)
+		distance := func(c candidate) int64 {
+			if c.ticks > ref {
+				return c.ticks - ref
+			}
+			return ref - c.ticks
+		}
+		for _, c := range best {
+			if d, dc := distance(c), distance(chosen); d < dc || d == dc && c.ticks < chosen.ticks {
+				chosen = c
+			}
+		}
+	}
+
+	return AuctionClearing{
+		Price:     utils.TicksToPrice(chosen.ticks, ob.priceTick),
+		Volume:    utils.TicksToAmount(minTicks(chosen.demand, chosen.supply), amountTick),
+		Imbalance: utils.TicksToAmount(chosen.demand-chosen.supply, amountTick),
+	}, chosen.ticks
+}
+
+// RunAuction uncrosses the book at the price auctionClearing picks: it
+// fills every match PlanAuction returns. It returns the clearing and the
+// matches, stamped at now.
+func (ob *Orderbook) RunAuction(amountTick, refPrice float64, now time.Time) (AuctionClearing, []Match) {
+	clearing, matches := ob.PlanAuction(amountTick, refPrice, now)
+	ob.FillAuction(matches, amountTick)
+	return clearing, matches
+}
+
+// PlanAuction returns the matches a call auction would execute now, without
+// filling any: bids at or above the price auctionClearing picks fill
+// against asks at or below it, best price first and then time priority, and
+// every match executes at that one price. Resting orders of the same user
+// may match each other. The matches are stamped at now; FillAuction applies
+// them, or a prefix of them, as long as the book did not change in between.
+func (ob *Orderbook) PlanAuction(amountTick, refPrice float64, now time.Time) (AuctionClearing, []Match) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	clearing, priceTicks := ob.auctionClearing(amountTick, refPrice, false)
+	if clearing.Volume == 0 {
+		return clearing, nil
+	}
+
+	var bids, asks []*Order
+	for _, limit := range ob.bids {
+		if limit.PriceTicks < priceTicks {
+			break
+		}
+		bids = append(bids, limit.Orders...)
+	}
+	for _, limit := range ob.asks {
+		if limit.PriceTicks > priceTicks {
+			break
+		}
+		asks = append(asks, limit.Orders...)
+	}
+
+	var matches []Match
+	left := utils.AmountToTicks(clearing.Volume, amountTick)
+	bidLeft := utils.AmountToTicks(bids[0].RemainingAmount(), amountTick)
+	askLeft := utils.AmountToTicks(asks[0].RemainingAmount(), amountTick)
+	for i, j := 0, 0; left > 0 && i < len(bids) && j < len(asks); {
+		size := minTicks(minTicks(bidLeft, askLeft), left)
+		if size > 0 {
+			left -= size
+			bidLeft -= size
+			askLeft -= size
+			matches = append(matches, Match{
+				Bid:        bids[i],
+				Ask:        asks[j],
+				Price:      clearing.Price,
+				SizeFilled: utils.TicksToAmount(size, amountTick),
+				Timestamp:  now,
+			})
+		}
+
+		if bidLeft == 0 {
+			if i++; i < len(bids) {
+				bidLeft = utils.AmountToTicks(bids[i].RemainingAmount(), amountTick)
+			}
+		}
+		if askLeft == 0 {
+			if j++; j < len(asks) {
+				askLeft = utils.AmountToTicks(asks[j].RemainingAmount(), amountTick)
+			}
+		}
+	}
+
+	return clearing, matches
+}
+
+// FillAuction fills both orders of each of matches, as PlanAuction
+// returned them, taking the orders nothing is left of out of the book.
+func (ob *Orderbook) FillAuction(matches []Match, amountTick float64) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	for _, m := range matches {
+		ob.fillAuctionOrder(m.Bid, m.SizeFilled, amountTick)
+		ob.fillAuctionOrder(m.Ask, m.SizeFilled, amountTick)
+	}
+}
+
+// fillAuctionOrder fills size of a resting order and takes it out of the
+// book once nothing of it is left. Must be called with ob.mu held.
+func (ob *Orderbook) fillAuctionOrder(order *Order, size, amountTick float64) {
+	limit := order.Limit
+	order.FilledAmount += size
+	limit.reduce(order, size)
+
+	// Whatever float drift leaves below one tick is not fillable
+	if utils.AmountToTicks(order.RemainingAmount(), amountTick) == 0 {
+		order.FilledAmount = order.Amount
+	}
+	if !order.IsFilled() {
+		order.State = OrderPartiallyFilled
+		return
+	}
+
+	order.State = OrderFilled
+	limit.DeleteOrder(order)
+	ob.removeOrder(order)
+	if len(limit.Orders) == 0 {
+		ob.clearLimit(order.Side == Bid, limit)
+	}
+}
+
+func minTicks(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,190 @@
+package orderbook
+
+import "testing"
+
+const auctionAmountTick = 0.01
+
+func setupAuctionBook(t *testing.T) (*Orderbook, func(user string, side Side, price, amount float64) *Order) {
+	t.Helper()
+	clk := newTestClock()
+	ob, err := NewOrderbookWithClock(priceTick, clk)
+	assertNoError(t, err)
+
+	rest := func(user string, side Side, price, amount float64) *Order {
+		o, err := NewOrderAt(user, side, price, amount, clk.Now())
+		assertNoError(t, err)
+		ob.RestLimitOrder(o)
+		return o
+	}
+	return ob, rest
+}
+
+func TestOrderbook_RunAuction(t *testing.T) {
+	ob, rest := setupAuctionBook(t)
+
+	// Demand/supply:  49800: 6/1  49900: 6/3  50000: 3/5  50100: 1/5  50200: 0/6
+	b1 := rest("1", Bid, 50_100, 1)
+	b2 := rest("1", Bid, 50_000, 2)
+	b3 := rest("1", Bid, 49_900, 3)
+	a1 := rest("2", Ask, 49_800, 1)
+	a2 := rest("2", Ask, 49_900, 2)
+	a3 := rest("2", Ask, 50_000, 2)
+	rest("2", Ask, 50_200, 1)
+
+	bid, _ := ob.BestBid()
+	ask, _ := ob.BestAsk()
+	assertTrue(t, bid.PriceTicks > ask.PriceTicks, "Orders rest across the book")
+
+	// 49900 and 50000 both trade 3; 50000 leaves the smaller imbalance
+	clearing := ob.AuctionClearing(auctionAmountTick, 0)
+	assertFloat(t, 50_000, clearing.Price, "Clearing price")
+	assertFloat(t, 3, clearing.Volume, "Matched volume")
+	assertFloat(t, -2, clearing.Imbalance, "Excess supply")
+
+	ran, matches := ob.RunAuction(auctionAmountTick, 0, newTestClock().Now())
+	assertEqual(t, clearing, ran, "Executed at the computed clearing")
+
+	filled := 0.0
+	for _, m := range matches {
+		assertFloat(t, 50_000, m.Price, "Uniform price")
+		filled += m.SizeFilled
+	}
+	assertFloat(t, 3, filled, "Volume executed")
+
+	// Best prices fill first; the ask at the clearing price is not needed
+	for _, o := range []*Order{b1, b2, a1, a2} {
+		assertEqual(t, OrderFilled, o.State, "Crossing order filled")
+		_, ok := ob.GetOrder(o.ID)
+		assertFalse(t, ok, "Filled order left the book")
+	}
+	assertEqual(t, OrderOpen, b3.State, "Bid below the clearing price")
+	assertEqual(t, OrderOpen, a3.State, "Ask at the clearing price, past the volume")
+
+	// The book is uncrossed
+	bid, _ = ob.BestBid()
+	ask, _ = ob.BestAsk()
+	assertEqual(t, int64(4_990_000), bid.PriceTicks, "Best bid left")
+	assertEqual(t, int64(5_000_000), ask.PriceTicks, "Best ask left")
+	assertFloat(t, 2, ask.TotalVolume, "Ask level volume")
+
+	again, matches := ob.RunAuction(auctionAmountTick, 0, newTestClock().Now())
+	assertFloat(t, 0, again.Volume, "Nothing crosses any more")
+	assertEqual(t, 0, len(matches), "No matches")
+}
+
+func TestOrderbook_RunAuction_PartialFill(t *testing.T) {
+	ob, rest := setupAuctionBook(t)
+
+	bid := rest("1", Bid, 50_000, 1)
+	first := rest("2", Ask, 49_900, 0.25)
+	second := rest("3", Ask, 49_900, 0.5)
+
+	_, matches := ob.RunAuction(auctionAmountTick, 0, newTestClock().Now())
+	assertEqual(t, 2, len(matches), "Filled against both asks, in time order")
+	assertEqual(t, first, matches[0].Ask, "Earlier ask first")
+	assertEqual(t, second, matches[1].Ask, "Then the later one")
+	assertEqual(t, OrderPartiallyFilled, bid.State, "Bid partially filled")
+	assertFloat(t, 0.25, bid.RemainingAmount(), "Bid remainder")
+
+	resting, ok := ob.GetOrder(bid.ID)
+	assertTrue(t, ok, "Remainder keeps resting")
+	assertFloat(t, 0.25, resting.Limit.TotalVolume, "Level volume follows the fill")
+}
+
+func TestOrderbook_PlanAuction(t *testing.T) {
+	ob, rest := setupAuctionBook(t)
+
+	bid := rest("1", Bid, 50_000, 1)
+	first := rest("2", Ask, 49_900, 0.25)
+	second := rest("3", Ask, 49_900, 0.5)
+
+	// Planning leaves the book as it is
+	planned, matches := ob.PlanAuction(auctionAmountTick, 0, newTestClock().Now())
+	assertEqual(t, 2, len(matches), "Two matches planned")
+	assertEqual(t, ob.AuctionClearing(auctionAmountTick, 0), planned, "Same clearing")
+	assertEqual(t, OrderOpen, bid.State, "Bid not filled yet")
+	assertFloat(t, 0, first.FilledAmount, "Ask not filled yet")
+
+	// Filling a prefix applies only that match
+	ob.FillAuction(matches[:1], auctionAmountTick)
+	_, ok := ob.GetOrder(first.ID)
+	assertFalse(t, ok, "First ask filled")
+	assertFloat(t, 0.75, bid.RemainingAmount(), "Bid filled by the first match")
+	assertEqual(t, OrderOpen, second.State, "Second ask untouched")
+	ask, _ := ob.BestAsk()
+	assertFloat(t, 0.5, ask.TotalVolume, "Level keeps the second ask")
+}
+
+func TestOrderbook_AuctionClearing_Ties(t *testing.T) {
+	t.Run("nothing crosses", func(t *testing.T) {
+		ob, rest := setupAuctionBook(t)
+		rest("1", Bid, 49_900, 1)
+		rest("2", Ask, 50_000, 1)
+
+		clearing := ob.AuctionClearing(auctionAmountTick, 0)
+		assertEqual(t, AuctionClearing{}, clearing, "No clearing price")
+	})
+
+	t.Run("buy pressure takes the highest price", func(t *testing.T) {
+		ob, rest := setupAuctionBook(t)
+		rest("1", Bid, 50_100, 2)
+		rest("2", Ask, 49_900, 1)
+
+		clearing := ob.AuctionClearing(auctionAmountTick, 0)
+		assertFloat(t, 50_100, clearing.Price, "Highest tied price")
+		assertFloat(t, 1, clearing.Imbalance, "Excess demand")
+	})
+
+	t.Run("sell pressure takes the lowest price", func(t *testing.T) {
+		ob, rest := setupAuctionBook(t)
+		rest("1", Bid, 50_100, 1)
+		rest("2", Ask, 49_900, 2)
+
+		clearing := ob.AuctionClearing(auctionAmountTick, 0)
+		assertFloat(t, 49_900, clearing.Price, "Lowest tied price")
+		assertFloat(t, -1, clearing.Imbalance, "Excess supply")
+	})
+
+	t.Run("balanced ties follow the reference price", func(t *testing.T) {
+		ob, rest := setupAuctionBook(t)
+		rest("1", Bid, 50_100, 1)
+		rest("2", Ask, 49_900, 1)
+
+		assertFloat(t, 49_900, ob.AuctionClearing(auctionAmountTick, 0).Price, "No reference: lower price")
+		assertFloat(t, 50_100, ob.AuctionClearing(auctionAmountTick, 50_080).Price, "Closest to the reference")
+		assertFloat(t, 49_900, ob.AuctionClearing(auctionAmountTick, 50_000).Price, "Equally close: lower price")
+		assertFloat(t, 1, ob.AuctionClearing(auctionAmountTick, 0).Volume, "Volume at either price")
+	})
+}
+
+func TestOrderbook_VisibleAuctionClearing(t *testing.T) {
+	ob, rest := setupAuctionBook(t)
+
+	rest("1", Bid, 50_000, 1)
+	rest("2", Ask, 49_900, 0.5)
+	hidden, err := NewOrderAt("3", Ask, 49_800, 0.5, newTestClock().Now())
+	assertNoError(t, err)
+	hidden.Hidden = true
+	ob.RestLimitOrder(hidden)
+
+	full := ob.AuctionClearing(auctionAmountTick, 0)
+	assertFloat(t, 49_900, full.Price, "Run clears with the hidden ask")
+	assertFloat(t, 1, full.Volume, "Hidden ask trades in the run")
+
+	visible := ob.VisibleAuctionClearing(auctionAmountTick, 0)
+	assertFloat(t, 50_000, visible.Price, "Buyers left over: highest visible price")
+	assertFloat(t, 0.5, visible.Volume, "Hidden volume left out")
+	assertFloat(t, 0.5, visible.Imbalance, "Imbalance over visible orders")
+
+	// A level holding only hidden orders is no candidate, though it would
+	// tie the visible ones and sit closest to the reference
+	ob, rest = setupAuctionBook(t)
+	rest("1", Bid, 50_000, 0.5)
+	rest("2", Ask, 49_800, 0.5)
+	hidden, err = NewOrderAt("3", Ask, 49_900, 0.5, newTestClock().Now())
+	assertNoError(t, err)
+	hidden.Hidden = true
+	ob.RestLimitOrder(hidden)
+
+	assertFloat(t, 49_800, ob.VisibleAuctionClearing(auctionAmountTick, 49_900).Price, "Equally close visible levels: lower")
+}
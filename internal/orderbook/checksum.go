@@ -0,0 +1,81 @@
+package orderbook
+
+import (
+	"hash/crc32"
+	"strconv"
+	"strings"
+
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
+)
+
+// ChecksumDepth is the number of levels per side the API checksums.
+const ChecksumDepth = 25
+
+// Checksum returns the CRC32 (IEEE) of the public depth of the best depth
+// levels of each side, so a client can check its copy of the book against
+// the server's. depth <= 0 covers every level.
+//
+// The checksummed text lists the bids, best first, then "|", then the asks,
+// best first. Each level is "price:volume" with both numbers written with
+// exactly 8 decimals, and levels are separated by ",":
+//
+//	50000.00000000:1.50000000,49990.00000000:0.25000000|50010.00000000:2.00000000
+//
+// Volume is the visible volume, and levels holding only hidden orders are
+// skipped, as in the public depth.
+func (ob *Orderbook) Checksum(depth int) uint32 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	return ChecksumLevels(ob.checksumLevels(ob.bids), ob.checksumLevels(ob.asks), depth)
+}
+
+// ChecksumLevel is one price level of public depth as Checksum reads it.
+type ChecksumLevel struct {
+	Price  float64
+	Volume float64 // visible volume
+}
+
+// ChecksumLevels is Checksum over levels already read from the book, best
+// first on each side, so a caller serving depth can checksum exactly what it
+// serves. Levels without volume are skipped.
+func ChecksumLevels(bids, asks []ChecksumLevel, depth int) uint32 {
+	var b strings.Builder
+	writeChecksumLevels(&b, bids, depth)
+	b.WriteByte('|')
+	writeChecksumLevels(&b, asks, depth)
+	return crc32.ChecksumIEEE([]byte(b.String()))
+}
+
+// checksumLevels reads the visible depth of one side. Must be called with
+// ob.mu held.
+func (ob *Orderbook) checksumLevels(limits []*Limit) []ChecksumLevel {
+	levels := make([]ChecksumLevel, 0, len(limits))
+	for _, limit := range limits {
+		levels = append(levels, ChecksumLevel{
+			Price:  utils.TicksToPrice(limit.PriceTicks, ob.priceTick),
+			Volume: limit.VisibleVolume(),
+		})
+	}
+	return levels
+}
+
+// writeChecksumLevels writes up to depth levels of one side.
+func writeChecksumLevels(b *strings.Builder, levels []ChecksumLevel, depth int) {
+	written := 0
+	for _, level := range levels {
+		if depth > 0 && written == depth {
+			return
+		}
+		if level.Volume <= 0 {
+			continue
+		}
+		if written > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(level.Price, 'f', 8, 64))
+		b.WriteByte(':')
+		b.WriteString(strconv.FormatFloat(level.Volume, 'f', 8, 64))
+		written++
+	}
+}
@@ -0,0 +1,54 @@
+package orderbook
+
+import (
+	"hash/crc32"
+	"testing"
+)
+
+func TestOrderbook_Checksum(t *testing.T) {
+	clk := newTestClock()
+	ob, err := NewOrderbookWithClock(priceTick, clk)
+	assertNoError(t, err)
+
+	place := func(user string, side Side, price, amount float64, hidden bool) *Order {
+		o, err := NewOrderAt(user, side, price, amount, clk.Now())
+		assertNoError(t, err)
+		o.Hidden = hidden
+		ob.PlaceLimitOrder(o)
+		return o
+	}
+
+	empty := ob.Checksum(ChecksumDepth)
+	assertEqual(t, crc32.ChecksumIEEE([]byte("|")), empty, "Empty book")
+
+	place("1", Bid, 50_000, 1.5, false)
+	place("1", Bid, 49_990, 0.25, false)
+	ask := place("2", Ask, 50_010, 2, false)
+
+	sum := ob.Checksum(ChecksumDepth)
+	want := crc32.ChecksumIEEE([]byte("50000.00000000:1.50000000,49990.00000000:0.25000000|50010.00000000:2.00000000"))
+	assertEqual(t, want, sum, "Documented format")
+	assertEqual(t, sum, ob.Checksum(ChecksumDepth), "Stable while the book is unchanged")
+
+	// Hidden volume is not public depth
+	place("3", Ask, 50_020, 1, true)
+	assertEqual(t, sum, ob.Checksum(ChecksumDepth), "Hidden-only level skipped")
+
+	// Depth bounds each side
+	top := crc32.ChecksumIEEE([]byte("50000.00000000:1.50000000|50010.00000000:2.00000000"))
+	assertEqual(t, top, ob.Checksum(1), "Best level only")
+
+	// A partial fill changes a level's volume
+	place("1", Bid, 50_010, 0.5, false)
+	filled := ob.Checksum(ChecksumDepth)
+	assertTrue(t, filled != sum, "Volume change detected")
+
+	// So do new and removed levels
+	place("2", Ask, 50_030, 1, false)
+	added := ob.Checksum(ChecksumDepth)
+	assertTrue(t, added != filled, "New level detected")
+
+	_, err = ob.CancelOrder(ask.ID)
+	assertNoError(t, err)
+	assertTrue(t, ob.Checksum(ChecksumDepth) != added, "Removed level detected")
+}
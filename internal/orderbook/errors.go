@@ -7,4 +7,12 @@ var (
 	ErrInvalidPrice  = errors.New("price must be greater than 0")
 	ErrInvalidAmount = errors.New("amount must be greater than 0")
 	ErrInvalidSide   = errors.New("invalid side")
+	ErrNonFinite     = errors.New("price and amount must be finite numbers")
+	ErrInvalidTick   = errors.New("tick size must be a positive finite number")
+	ErrBookNotEmpty  = errors.New("orderbook must be empty to import orders")
+	ErrPriceOffTick  = errors.New("price is not aligned to the book's tick")
+	ErrDuplicateID   = errors.New("duplicate order ID")
+
+	ErrAmendIncrease    = errors.New("amend can only reduce the amount")
+	ErrAmendBelowFilled = errors.New("amended amount must be above the filled amount")
 )
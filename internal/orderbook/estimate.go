@@ -0,0 +1,230 @@
+package orderbook
+
+import "github.com/moura95/crypto-exchange-challenge/pkg/utils"
+
+// FillEstimate describes how an incoming order would execute against the book.
+type FillEstimate struct {
+	Filled      float64 // base amount that would execute
+	QuoteAmount float64 // quote value of the executed amount
+	Levels      int     // price levels touched
+	FirstPrice  float64 // price of the first level touched
+	LastPrice   float64 // price of the last level touched
+}
+
+// EstimateFill walks the side opposite to side, without mutating the book,
+// and reports how a market order of amount would execute.
+func (ob *Orderbook) EstimateFill(side Side, amount float64) FillEstimate {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.estimate(side, amount, 0, false)
+}
+
+// EstimateLimitFill is EstimateFill for a limit order: levels beyond price are
+// not touched.
+func (ob *Orderbook) EstimateLimitFill(side Side, price, amount float64) FillEstimate {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.estimate(side, amount, utils.PriceToTicks(price, ob.priceTick), true)
+}
+
+func (ob *Orderbook) estimate(side Side, amount float64, limitTicks int64, hasLimit bool) FillEstimate {
+	limits := ob.asks
+	if side == Ask {
+		limits = ob.bids
+	}
+
+	var est FillEstimate
+	remaining := amount
+
+	for _, limit := range limits {
+		if remaining <= 0 {
+			break
+		}
+		if hasLimit && crossesLimit(side, limit.PriceTicks, limitTicks) {
+			break
+		}
+
+		price := limit.Price(ob.priceTick)
+		fillQty := min(remaining, limit.TotalVolume)
+
+		if est.Levels == 0 {
+			est.FirstPrice = price
+		}
+		est.LastPrice = price
+		est.Levels++
+		est.Filled += fillQty
+		est.QuoteAmount += fillQty * price
+		remaining -= fillQty
+	}
+
+	return est
+}
+
+// crossesLimit reports whether a resting level at levelTicks is beyond the
+// limit price of an incoming order on side.
+func crossesLimit(side Side, levelTicks, limitTicks int64) bool {
+	if side == Bid {
+		return levelTicks > limitTicks
+	}
+	return levelTicks < limitTicks
+}
+
+// RangeDepth is the visible liquidity resting on one side between two prices.
+type RangeDepth struct {
+	Volume float64 // visible base volume
+	Orders int     // visible orders
+	Levels int     // levels with visible volume
+}
+
+// DepthInRange sums the visible orders resting on side at prices from through
+// to, both rounded to the book's tick. Hidden orders are left out, as in the
+// public depth. An empty or inverted range yields zeros.
+func (ob *Orderbook) DepthInRange(side Side, from, to float64) RangeDepth {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	fromTicks := utils.PriceToTicks(from, ob.priceTick)
+	toTicks := utils.PriceToTicks(to, ob.priceTick)
+
+	var depth RangeDepth
+	if fromTicks > toTicks {
+		return depth
+	}
+
+	// Bids are sorted best (highest) first, asks lowest first, so each side
+	// is done once it walks past the far end of the range.
+	limits := ob.asks
+	if side == Bid {
+		limits = ob.bids
+	}
+	for _, limit := range limits {
+		if side == Bid && limit.PriceTicks < fromTicks || side != Bid && limit.PriceTicks > toTicks {
+			break
+		}
+		if limit.PriceTicks < fromTicks || limit.PriceTicks > toTicks {
+			continue
+		}
+
+		visible := 0
+		for _, o := range limit.Orders {
+			if !o.Hidden {
+				visible++
+			}
+		}
+		if visible == 0 {
+			continue
+		}
+		depth.Volume += limit.VisibleVolume()
+		depth.Orders += visible
+		depth.Levels++
+	}
+	return depth
+}
+
+// WeightedMid estimates fair value from the best levels visible price levels
+// of each side: each side's volume-weighted price, weighted in turn by the
+// opposite side's volume, so the mid leans toward the thinner side as the
+// micro-price does. With levels == 1 it is the micro-price. Sides with fewer
+// levels use what they have; ok is false when either side is empty or levels
+// is not positive. Hidden orders are left out, as in the public depth.
+func (ob *Orderbook) WeightedMid(levels int) (mid float64, ok bool) {
+	if levels <= 0 {
+		return 0, false
+	}
+
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	bidPrice, bidVolume := ob.visibleVWAP(ob.bids, levels)
+	askPrice, askVolume := ob.visibleVWAP(ob.asks, levels)
+	if bidVolume <= 0 || askVolume <= 0 {
+		return 0, false
+	}
+	return (bidPrice*askVolume + askPrice*bidVolume) / (bidVolume + askVolume), true
+}
+
+// visibleVWAP returns the volume-weighted price and the total visible volume
+// of the first levels limits that have visible volume. Must be called with
+// ob.mu held.
+func (ob *Orderbook) visibleVWAP(limits []*Limit, levels int) (price, volume float64) {
+	var quote float64
+	for _, limit := range limits {
+		if levels == 0 {
+			break
+		}
+		visible := limit.VisibleVolume()
+		if visible <= 0 {
+			continue
+		}
+		quote += visible * limit.Price(ob.priceTick)
+		volume += visible
+		levels--
+	}
+	if volume <= 0 {
+		return 0, 0
+	}
+	return quote / volume, volume
+}
+
+// OrderStanding describes where a resting order stands against the book as it
+// is now, to help its owner decide whether to reprice.
+type OrderStanding struct {
+	Side          Side
+	Price         float64
+	Remaining     float64 // unfilled amount
+	QueueAhead    float64 // resting volume on its side that fills before it: better prices, then earlier in its level
+	Position      int     // 1-based place in its level's queue right now
+	Fillable      float64 // amount that would execute now if resubmitted as a market order
+	FillableQuote float64 // quote value of Fillable
+}
+
+// Standing reports the standing of the resting order orderID, or false when it
+// is not in the book. Only visible volume counts, as in the public depth, so
+// hidden orders are left out of QueueAhead, Position and Fillable; so are the
+// order owner's resting orders from Fillable, as matching skips them.
+func (ob *Orderbook) Standing(orderID int64) (OrderStanding, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	order, exists := ob.Orders[orderID]
+	if !exists || order.Limit == nil {
+		return OrderStanding{}, false
+	}
+
+	standing := OrderStanding{Side: order.Side, Price: order.Price, Remaining: order.RemainingAmount()}
+
+	same, opposite := ob.bids, ob.asks
+	if order.Side == Ask {
+		same, opposite = ob.asks, ob.bids
+	}
+
+	for _, limit := range same {
+		if limit == order.Limit {
+			standing.Position = 1
+			for _, o := range limit.Orders {
+				if o == order {
+					break
+				}
+				if !o.Hidden {
+					standing.QueueAhead += o.RemainingAmount()
+					standing.Position++
+				}
+			}
+			break
+		}
+		standing.QueueAhead += limit.VisibleVolume()
+	}
+
+	remaining := standing.Remaining
+	for _, limit := range opposite {
+		if remaining <= 0 {
+			break
+		}
+		fillQty := min(remaining, limit.visibleVolumeAgainst(order.UserID))
+		standing.Fillable += fillQty
+		standing.FillableQuote += fillQty * limit.Price(ob.priceTick)
+		remaining -= fillQty
+	}
+
+	return standing, true
+}
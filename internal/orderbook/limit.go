@@ -7,9 +7,10 @@ import (
 )
 
 type Limit struct {
-	PriceTicks  int64
-	Orders      []*Order
-	TotalVolume float64
+	PriceTicks   int64
+	Orders       []*Order
+	TotalVolume  float64 // all resting volume, hidden included
+	HiddenVolume float64 // portion of TotalVolume from hidden orders
 }
 
 func NewLimit(priceTicks int64) *Limit {
@@ -24,10 +25,98 @@ func (l *Limit) Price(priceTick float64) float64 {
 	return utils.TicksToPrice(l.PriceTicks, priceTick)
 }
 
+// VisibleVolume is the volume shown in public depth. When hidden orders are
+// present it is summed from the visible orders to avoid subtraction drift.
+func (l *Limit) VisibleVolume() float64 {
+	if l.HiddenVolume == 0 {
+		return l.TotalVolume
+	}
+
+	visible := 0.0
+	for _, o := range l.Orders {
+		if !o.Hidden {
+			visible += o.RemainingAmount()
+		}
+	}
+	return visible
+}
+
+// VolumeAgainst is the volume userID can trade at this level: matching
+// skips resting orders of the same user.
+func (l *Limit) VolumeAgainst(userID string) float64 {
+	volume := l.TotalVolume
+	for _, o := range l.Orders {
+		if o.UserID == userID {
+			volume -= o.RemainingAmount()
+		}
+	}
+	return max(volume, 0)
+}
+
+// visibleVolumeAgainst is VisibleVolume without userID's own orders.
+func (l *Limit) visibleVolumeAgainst(userID string) float64 {
+	volume := 0.0
+	for _, o := range l.Orders {
+		if !o.Hidden && o.UserID != userID {
+			volume += o.RemainingAmount()
+		}
+	}
+	return volume
+}
+
+// AddOrder queues o at this level. Priority is FIFO by Seq, with hidden
+// orders yielding to visible ones: visible orders come first, each group in
+// Seq order.
 func (l *Limit) AddOrder(o *Order) {
 	o.Limit = l
-	l.Orders = append(l.Orders, o)
 	l.TotalVolume += o.RemainingAmount()
+	if o.Hidden {
+		l.HiddenVolume += o.RemainingAmount()
+	}
+
+	i := len(l.Orders)
+	for i > 0 && queuedBefore(o, l.Orders[i-1]) {
+		i--
+	}
+	l.Orders = append(l.Orders, nil)
+	copy(l.Orders[i+1:], l.Orders[i:])
+	l.Orders[i] = o
+	o.QueuePos = i + 1
+}
+
+// reduce takes delta off the volume of o, which rests at this level, without
+// moving it in the queue.
+func (l *Limit) reduce(o *Order, delta float64) {
+	l.TotalVolume -= delta
+	if o.Hidden {
+		l.HiddenVolume -= delta
+	}
+}
+
+// reconcile recomputes TotalVolume and HiddenVolume from the orders resting
+// at this level, dropping the float drift the running totals pick up over
+// many fills, and reports whether either had drifted.
+func (l *Limit) reconcile() bool {
+	total, hidden := 0.0, 0.0
+	for _, o := range l.Orders {
+		total += o.RemainingAmount()
+		if o.Hidden {
+			hidden += o.RemainingAmount()
+		}
+	}
+
+	drifted := total != l.TotalVolume || hidden != l.HiddenVolume
+	l.TotalVolume = total
+	l.HiddenVolume = hidden
+	return drifted
+}
+
+// queuedBefore reports whether a has priority over b at the same price.
+func queuedBefore(a, b *Order) bool {
+	if a.Hidden != b.Hidden {
+		return !a.Hidden
+	}
+	return a.Seq < b.Seq
 }
 
 func (l *Limit) DeleteOrder(o *Order) {
@@ -35,6 +124,9 @@ func (l *Limit) DeleteOrder(o *Order) {
 		if l.Orders[i].ID == o.ID {
 			l.Orders = append(l.Orders[:i], l.Orders[i+1:]...)
 			l.TotalVolume -= o.RemainingAmount()
+			if o.Hidden {
+				l.HiddenVolume -= o.RemainingAmount()
+			}
 			o.Limit = nil
 			return
 		}
@@ -44,6 +136,11 @@ func (l *Limit) DeleteOrder(o *Order) {
 // Fill fills incomingOrder against this price level.
 // Self-trade prevention: skip resting orders from same user.
 func (l *Limit) Fill(incomingOrder *Order, priceTick float64) []Match {
+	return l.FillAt(incomingOrder, priceTick, time.Now())
+}
+
+// FillAt is Fill with the matches stamped at now.
+func (l *Limit) FillAt(incomingOrder *Order, priceTick float64, now time.Time) []Match {
 	var matches []Match
 	var ordersToDelete []*Order
 
@@ -53,7 +150,7 @@ func (l *Limit) Fill(incomingOrder *Order, priceTick float64) []Match {
 		if incomingOrder.IsFilled() {
 			break
 		}
-		if existingOrder.UserID == incomingOrder.UserID {
+		if existingOrder.UserID == incomingOrder.UserID && !incomingOrder.SelfTrade {
 			continue
 		}
 
@@ -78,6 +175,9 @@ func (l *Limit) Fill(incomingOrder *Order, priceTick float64) []Match {
 		}
 
 		l.TotalVolume -= fillSize
+		if existingOrder.Hidden {
+			l.HiddenVolume -= fillSize
+		}
 
 		var bid, ask *Order
 		if incomingOrder.Side == Bid {
@@ -93,7 +193,7 @@ func (l *Limit) Fill(incomingOrder *Order, priceTick float64) []Match {
 			Ask:        ask,
 			Price:      levelPrice,
 			SizeFilled: fillSize,
-			Timestamp:  time.Now(),
+			Timestamp:  now,
 		}
 
 		matches = append(matches, match)
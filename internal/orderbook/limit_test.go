@@ -1,9 +1,6 @@
 package orderbook
 
-import (
-	"testing"
-	"time"
-)
+import "testing"
 
 func TestLimit_AddOrder(t *testing.T) {
 	limit := NewLimit(priceToTicks(50_000))
@@ -22,6 +19,9 @@ func TestLimit_AddOrder(t *testing.T) {
 	limit.AddOrder(order2)
 	assertEqual(t, 2, len(limit.Orders), "Orders count after second add")
 	assertFloat(t, 3.0, limit.TotalVolume, "TotalVolume after second add")
+
+	assertEqual(t, 1, order1.QueuePos, "First order queue position")
+	assertEqual(t, 2, order2.QueuePos, "Second order queue position")
 }
 
 func TestLimit_DeleteOrder(t *testing.T) {
@@ -116,22 +116,22 @@ func TestLimit_Fill_PartialMatch_ExistingLarger(t *testing.T) {
 
 func TestLimit_Fill_MultipleOrders_FIFO(t *testing.T) {
 	limit := NewLimit(priceToTicks(50_000))
+	clk := newTestClock()
 
-	ask1, err := NewOrder("1", Ask, 50_000, 1.0)
+	ask1, err := NewOrderAt("1", Ask, 50_000, 1.0, clk.Now())
 	assertNoError(t, err)
-
-	time.Sleep(1 * time.Millisecond)
-
-	ask2, err := NewOrder("2", Ask, 50_000, 1.0)
+	ask2, err := NewOrderAt("2", Ask, 50_000, 1.0, clk.Now())
 	assertNoError(t, err)
+	assertTrue(t, ask1.Timestamp.Before(ask2.Timestamp), "Timestamps strictly increasing")
 
 	limit.AddOrder(ask1)
 	limit.AddOrder(ask2)
 
-	bidOrder, err := NewOrder("3", Bid, 50_000, 1.5)
+	bidOrder, err := NewOrderAt("3", Bid, 50_000, 1.5, clk.Now())
 	assertNoError(t, err)
 
-	matches := limit.Fill(bidOrder, priceTick)
+	matchTime := clk.Now()
+	matches := limit.FillAt(bidOrder, priceTick, matchTime)
 
 	assertEqual(t, 2, len(matches), "Should have 2 matches")
 	assertFloat(t, 1.0, matches[0].SizeFilled, "First match size")
@@ -143,6 +143,7 @@ func TestLimit_Fill_MultipleOrders_FIFO(t *testing.T) {
 	assertTrue(t, ask1.IsFilled(), "Ask1 should be filled")
 	assertFalse(t, ask2.IsFilled(), "Ask2 should NOT be filled")
 	assertFloat(t, 0.5, ask2.RemainingAmount(), "Ask2 remaining")
+	assertTrue(t, matches[0].Timestamp.Equal(matchTime), "Match stamped by the clock")
 }
 
 func TestLimit_Fill_SelfTradePrevention(t *testing.T) {
@@ -163,9 +164,128 @@ func TestLimit_Fill_SelfTradePrevention(t *testing.T) {
 	assertEqual(t, 1, len(limit.Orders), "Ask should still be in limit")
 }
 
+func TestLimit_Fill_SelfTradeAllowed(t *testing.T) {
+	limit := NewLimit(priceToTicks(50_000))
+
+	askOrder, err := NewOrder("1", Ask, 50_000, 1.0)
+	assertNoError(t, err)
+	limit.AddOrder(askOrder)
+
+	bidOrder, err := NewOrder("1", Bid, 50_000, 1.0)
+	assertNoError(t, err)
+	bidOrder.SelfTrade = true
+
+	matches := limit.Fill(bidOrder, priceTick)
+
+	assertEqual(t, 1, len(matches), "Matched the own ask")
+	assertTrue(t, askOrder.IsFilled(), "Ask filled")
+	assertTrue(t, bidOrder.IsFilled(), "Bid filled")
+	assertEqual(t, 0, len(limit.Orders), "Level emptied")
+}
+
 func TestLimit_Price(t *testing.T) {
 	limit := NewLimit(priceToTicks(50_000))
 
 	price := limit.Price(priceTick)
 	assertFloat(t, 50_000, price, "Limit price should match")
 }
+
+func TestLimit_AddOrder_HiddenVolume(t *testing.T) {
+	limit := NewLimit(priceToTicks(50_000))
+
+	visible, _ := NewOrder("1", Ask, 50_000, 1.0)
+	hidden, _ := NewOrder("2", Ask, 50_000, 2.0)
+	hidden.Hidden = true
+
+	limit.AddOrder(visible)
+	limit.AddOrder(hidden)
+
+	assertFloat(t, 3.0, limit.TotalVolume, "TotalVolume includes hidden")
+	assertFloat(t, 2.0, limit.HiddenVolume, "HiddenVolume")
+	assertFloat(t, 1.0, limit.VisibleVolume(), "VisibleVolume")
+
+	limit.DeleteOrder(hidden)
+	assertFloat(t, 0.0, limit.HiddenVolume, "HiddenVolume after delete")
+	assertFloat(t, 1.0, limit.VisibleVolume(), "VisibleVolume after delete")
+}
+
+func TestLimit_Fill_HiddenYieldsToVisible(t *testing.T) {
+	limit := NewLimit(priceToTicks(50_000))
+
+	// Hidden order arrives first, visible one second
+	hidden, _ := NewOrder("1", Ask, 50_000, 1.0)
+	hidden.Hidden = true
+	limit.AddOrder(hidden)
+
+	visible, _ := NewOrder("2", Ask, 50_000, 1.0)
+	limit.AddOrder(visible)
+
+	assertEqual(t, visible.ID, limit.Orders[0].ID, "Visible order should be queued first")
+
+	bid, _ := NewOrder("3", Bid, 50_000, 1.5)
+	matches := limit.Fill(bid, priceTick)
+
+	assertEqual(t, 2, len(matches), "Should have 2 matches")
+	assertEqual(t, "2", matches[0].Ask.UserID, "Visible order fills first")
+	assertFloat(t, 1.0, matches[0].SizeFilled, "Visible fill size")
+	assertEqual(t, "1", matches[1].Ask.UserID, "Hidden order fills second")
+	assertFloat(t, 0.5, matches[1].SizeFilled, "Hidden fill size")
+
+	assertFloat(t, 0.5, limit.TotalVolume, "TotalVolume after fill")
+	assertFloat(t, 0.5, limit.HiddenVolume, "HiddenVolume after fill")
+	assertFloat(t, 0.0, limit.VisibleVolume(), "VisibleVolume after fill")
+}
+
+func TestLimit_FIFO_BySequenceNotTimestamp(t *testing.T) {
+	limit := NewLimit(priceToTicks(50_000))
+	ts := newTestClock().Now()
+
+	// Same timestamp for all three: only Seq can order them
+	ask1, _ := NewOrderAt("1", Ask, 50_000, 1.0, ts)
+	ask2, _ := NewOrderAt("2", Ask, 50_000, 1.0, ts)
+	ask3, _ := NewOrderAt("3", Ask, 50_000, 1.0, ts)
+	assertTrue(t, ask1.Seq < ask2.Seq && ask2.Seq < ask3.Seq, "Sequence is monotonic")
+
+	// Queued out of creation order
+	limit.AddOrder(ask2)
+	limit.AddOrder(ask3)
+	limit.AddOrder(ask1)
+
+	bid, _ := NewOrderAt("4", Bid, 50_000, 2.5, ts)
+	matches := limit.FillAt(bid, priceTick, ts)
+
+	assertEqual(t, 3, len(matches), "Should have 3 matches")
+	assertEqual(t, "1", matches[0].Ask.UserID, "Lowest sequence first")
+	assertEqual(t, "2", matches[1].Ask.UserID, "Then second")
+	assertEqual(t, "3", matches[2].Ask.UserID, "Then third")
+	assertFloat(t, 0.5, matches[2].SizeFilled, "Last match partial")
+}
+
+func TestLimit_FIFO_HiddenStillYieldsToVisible(t *testing.T) {
+	limit := NewLimit(priceToTicks(50_000))
+
+	hidden, _ := NewOrder("1", Ask, 50_000, 1.0)
+	hidden.Hidden = true
+	visible, _ := NewOrder("2", Ask, 50_000, 1.0)
+
+	limit.AddOrder(hidden)
+	limit.AddOrder(visible)
+
+	assertEqual(t, visible.ID, limit.Orders[0].ID, "Visible order first despite later sequence")
+	assertEqual(t, hidden.ID, limit.Orders[1].ID, "Hidden order last")
+}
+
+func TestLimit_VolumeAgainst(t *testing.T) {
+	limit := NewLimit(priceToTicks(50_000))
+
+	own, err := NewOrder("1", Ask, 50_000, 1.0)
+	assertNoError(t, err)
+	other, err := NewOrder("2", Ask, 50_000, 2.0)
+	assertNoError(t, err)
+	limit.AddOrder(own)
+	limit.AddOrder(other)
+
+	assertFloat(t, 2.0, limit.VolumeAgainst("1"), "Own order excluded")
+	assertFloat(t, 1.0, limit.VolumeAgainst("2"), "Other user sees the rest")
+	assertFloat(t, 3.0, limit.VolumeAgainst("3"), "Third party sees everything")
+}
@@ -11,6 +11,11 @@ type Match struct {
 	Price      float64
 	SizeFilled float64
 	Timestamp  time.Time
+
+	// Seq orders trades across every pair and PairSeq within this match's
+	// pair. The engine sets both when it records the trade.
+	Seq     uint64
+	PairSeq uint64
 }
 
 func (m Match) String() string {
@@ -3,6 +3,7 @@ package orderbook
 import (
 	"errors"
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -15,17 +16,34 @@ type Order struct {
 	Amount       float64
 	FilledAmount float64
 	State        OrderState
+	Seq          uint64 // creation sequence; FIFO priority within a level
 	Timestamp    time.Time
+	Hidden       bool      // rests and matches, but is excluded from displayed depth
+	QueuePos     int       // 1-based place in its level's queue when it came to rest; 0 if it never rested
+	ExpiresAt    time.Time // good-till-date expiry; zero means good-till-cancelled
+	MaxLevels    int       // price levels the order may match against before it stops; 0 means no cap
+	MaxGap       float64   // market only: largest fraction the next level may sit from the last one; 0 means no limit
+	Tag          string    // opaque client tag, echoed back and never used for matching
+	ClientID     string    // client order ID it was placed under, if any; see Engine.PlaceOrReplace
+	SelfTrade    bool      // may match resting orders of the same user, which are otherwise skipped
 	Limit        *Limit
 }
 
 func NewOrder(userID string, side Side, price, amount float64) (*Order, error) {
+	return NewOrderAt(userID, side, price, amount, time.Now())
+}
+
+// NewOrderAt is NewOrder with an explicit creation timestamp.
+func NewOrderAt(userID string, side Side, price, amount float64, ts time.Time) (*Order, error) {
 	if userID == "" {
 		return nil, errors.New("userID cannot be empty")
 	}
 	if side != Bid && side != Ask {
 		return nil, ErrInvalidSide
 	}
+	if !isFinite(price) || !isFinite(amount) {
+		return nil, ErrNonFinite
+	}
 	if price <= 0 {
 		return nil, ErrInvalidPrice
 	}
@@ -34,7 +52,8 @@ func NewOrder(userID string, side Side, price, amount float64) (*Order, error) {
 	}
 
 	return &Order{
-		ID:           nextOrderID(),
+		ID:           NextOrderID(),
+		Seq:          nextSeq(),
 		UserID:       userID,
 		Side:         side,
 		Type:         OrderTypeLimit,
@@ -42,23 +61,32 @@ func NewOrder(userID string, side Side, price, amount float64) (*Order, error) {
 		Amount:       amount,
 		FilledAmount: 0,
 		State:        OrderOpen,
-		Timestamp:    time.Now(),
+		Timestamp:    ts,
 	}, nil
 }
 
 func NewMarketOrder(userID string, side Side, amount float64) (*Order, error) {
+	return NewMarketOrderAt(userID, side, amount, time.Now())
+}
+
+// NewMarketOrderAt is NewMarketOrder with an explicit creation timestamp.
+func NewMarketOrderAt(userID string, side Side, amount float64, ts time.Time) (*Order, error) {
 	if userID == "" {
 		return nil, errors.New("userID cannot be empty")
 	}
 	if side != Bid && side != Ask {
 		return nil, ErrInvalidSide
 	}
+	if !isFinite(amount) {
+		return nil, ErrNonFinite
+	}
 	if amount <= 0 {
 		return nil, ErrInvalidAmount
 	}
 
 	return &Order{
-		ID:           nextOrderID(),
+		ID:           NextOrderID(),
+		Seq:          nextSeq(),
 		UserID:       userID,
 		Side:         side,
 		Type:         OrderTypeMarket,
@@ -66,10 +94,16 @@ func NewMarketOrder(userID string, side Side, amount float64) (*Order, error) {
 		Amount:       amount,
 		FilledAmount: 0,
 		State:        OrderOpen,
-		Timestamp:    time.Now(),
+		Timestamp:    ts,
 	}, nil
 }
 
+// isFinite reports whether v is neither NaN nor ±Inf. NaN fails every
+// comparison, so "<= 0" alone would let it through.
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
 func (o *Order) IsFilled() bool {
 	return o.FilledAmount >= o.Amount
 }
@@ -78,6 +112,18 @@ func (o *Order) RemainingAmount() float64 {
 	return o.Amount - o.FilledAmount
 }
 
+// levelCapReached reports whether the order has matched against as many price
+// levels as MaxLevels allows.
+func (o *Order) levelCapReached(levels int) bool {
+	return o.MaxLevels > 0 && levels >= o.MaxLevels
+}
+
+// gapExceeded reports whether a market order that last matched at prev must
+// stop before the level at next, because of MaxGap.
+func (o *Order) gapExceeded(prev, next float64) bool {
+	return o.MaxGap > 0 && prev > 0 && math.Abs(next-prev)/prev > o.MaxGap
+}
+
 func (o *Order) String() string {
 	if o.Type == OrderTypeMarket {
 		return fmt.Sprintf("[ID:%d User:%s %s MARKET %.8f filled:%.8f state:%s]",
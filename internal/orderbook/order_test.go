@@ -1,6 +1,9 @@
 package orderbook
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestNewOrder_Valid(t *testing.T) {
 	order, err := NewOrder("1", Bid, 50_000, 1.0)
@@ -39,6 +42,22 @@ func TestNewOrder_InvalidAmount(t *testing.T) {
 	}
 }
 
+func TestNewOrder_NonFinite(t *testing.T) {
+	values := []float64{math.NaN(), math.Inf(1), math.Inf(-1)}
+
+	for _, v := range values {
+		if _, err := NewOrder("1", Bid, v, 1.0); err != ErrNonFinite {
+			t.Errorf("price %v: expected ErrNonFinite, got %v", v, err)
+		}
+		if _, err := NewOrder("1", Bid, 50_000, v); err != ErrNonFinite {
+			t.Errorf("amount %v: expected ErrNonFinite, got %v", v, err)
+		}
+		if _, err := NewMarketOrder("1", Bid, v); err != ErrNonFinite {
+			t.Errorf("market amount %v: expected ErrNonFinite, got %v", v, err)
+		}
+	}
+}
+
 func TestNewOrder_InvalidUserID(t *testing.T) {
 	_, err := NewOrder("", Bid, 50_000, 1.0)
 	if err == nil {
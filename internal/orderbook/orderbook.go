@@ -3,7 +3,9 @@ package orderbook
 import (
 	"sort"
 	"sync"
+	"time"
 
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
 	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
 )
 
@@ -15,109 +17,188 @@ type Orderbook struct {
 	AskLimits map[int64]*Limit
 	Orders    map[int64]*Order
 
+	userOrders map[string]map[int64]*Order // user ID -> that user's entries of Orders
+
 	mu sync.RWMutex
 
 	priceTick float64
+	clock     clock.Clock
 }
 
 func NewOrderbook() *Orderbook {
-	return &Orderbook{
-		bids:      []*Limit{},
-		asks:      []*Limit{},
-		BidLimits: make(map[int64]*Limit),
-		AskLimits: make(map[int64]*Limit),
-		Orders:    make(map[int64]*Order),
-		priceTick: 0.01,
+	ob, _ := NewOrderbookWithTick(0.01)
+	return ob
+}
+
+// NewOrderbookWithTick creates an orderbook whose price levels are priceTick
+// apart. priceTick must be a positive finite number: a zero tick would
+// collapse every price into a single level.
+func NewOrderbookWithTick(priceTick float64) (*Orderbook, error) {
+	return NewOrderbookWithClock(priceTick, clock.Real())
+}
+
+// NewOrderbookWithClock is NewOrderbookWithTick with clk stamping matches.
+func NewOrderbookWithClock(priceTick float64, clk clock.Clock) (*Orderbook, error) {
+	if !isFinite(priceTick) || priceTick <= 0 {
+		return nil, ErrInvalidTick
 	}
+
+	return &Orderbook{
+		bids:       []*Limit{},
+		asks:       []*Limit{},
+		BidLimits:  make(map[int64]*Limit),
+		AskLimits:  make(map[int64]*Limit),
+		Orders:     make(map[int64]*Order),
+		userOrders: make(map[string]map[int64]*Order),
+		priceTick:  priceTick,
+		clock:      clk,
+	}, nil
+}
+
+// PriceTick returns the price increment between levels of this book.
+func (ob *Orderbook) PriceTick() float64 {
+	return ob.priceTick
 }
 
-// PlaceLimitOrder places order in orderbook and tries to match
+// PlaceLimitOrder places order in orderbook and tries to match. When the
+// order's MaxLevels cap stops it while it still crosses the book, the
+// remainder cannot rest at its price without crossing the spread, so the order
+// ends cancelled instead.
 func (ob *Orderbook) PlaceLimitOrder(order *Order) []Match {
 	ob.mu.Lock()
 	defer ob.mu.Unlock()
 
 	orderPriceTicks := utils.PriceToTicks(order.Price, ob.priceTick)
+	now := ob.clock.Now()
 
 	var matches []Match
+	levels, capped := 0, false
 
+	// clearLimit removes emptied levels from the slice being walked, so the
+	// index only moves on when a level is left in place.
 	if order.Side == Bid {
-		for _, askLimit := range ob.asks {
+		for i := 0; i < len(ob.asks); {
+			askLimit := ob.asks[i]
 			if askLimit.PriceTicks > orderPriceTicks {
 				break
 			}
 			if order.IsFilled() {
 				break
 			}
+			if order.levelCapReached(levels) {
+				capped = true
+				break
+			}
 
-			limitMatches := askLimit.Fill(order, ob.priceTick)
+			limitMatches := askLimit.FillAt(order, ob.priceTick, now)
 			matches = append(matches, limitMatches...)
+			levels++
 
 			if len(askLimit.Orders) == 0 {
 				ob.clearLimit(false, askLimit)
+			} else {
+				i++
 			}
 		}
 	} else {
-		for _, bidLimit := range ob.bids {
+		for i := 0; i < len(ob.bids); {
+			bidLimit := ob.bids[i]
 			if bidLimit.PriceTicks < orderPriceTicks {
 				break
 			}
 			if order.IsFilled() {
 				break
 			}
+			if order.levelCapReached(levels) {
+				capped = true
+				break
+			}
 
-			limitMatches := bidLimit.Fill(order, ob.priceTick)
+			limitMatches := bidLimit.FillAt(order, ob.priceTick, now)
 			matches = append(matches, limitMatches...)
+			levels++
 
 			if len(bidLimit.Orders) == 0 {
 				ob.clearLimit(true, bidLimit)
+			} else {
+				i++
 			}
 		}
 	}
 
-	if !order.IsFilled() {
+	ob.removeFilledMakers(matches)
+
+	if capped {
+		order.State = OrderCancelled
+	} else if !order.IsFilled() {
 		ob.addOrderToBook(order, orderPriceTicks)
 	}
 
 	return matches
 }
 
-// PlaceMarketOrder executes immediately against the top of book.
+// PlaceMarketOrder executes immediately against the top of book, walking at
+// most order.MaxLevels price levels when that is set, and stops before a level
+// priced more than order.MaxGap away from the previous one.
 func (ob *Orderbook) PlaceMarketOrder(order *Order) []Match {
 	ob.mu.Lock()
 	defer ob.mu.Unlock()
 
+	now := ob.clock.Now()
 	var matches []Match
+	levels := 0
+	prevPrice := 0.0
 
 	if order.Side == Bid {
 		// BUY market: consume asks from best price (lowest)
-		for _, askLimit := range ob.asks {
-			if order.IsFilled() {
+		for i := 0; i < len(ob.asks); {
+			askLimit := ob.asks[i]
+			if order.IsFilled() || order.levelCapReached(levels) {
+				break
+			}
+			price := askLimit.Price(ob.priceTick)
+			if order.gapExceeded(prevPrice, price) {
 				break
 			}
 
-			limitMatches := askLimit.Fill(order, ob.priceTick)
+			limitMatches := askLimit.FillAt(order, ob.priceTick, now)
 			matches = append(matches, limitMatches...)
+			levels++
+			prevPrice = price
 
 			if len(askLimit.Orders) == 0 {
 				ob.clearLimit(false, askLimit)
+			} else {
+				i++
 			}
 		}
 	} else {
 		// SELL market: consume bids from best price (highest)
-		for _, bidLimit := range ob.bids {
-			if order.IsFilled() {
+		for i := 0; i < len(ob.bids); {
+			bidLimit := ob.bids[i]
+			if order.IsFilled() || order.levelCapReached(levels) {
+				break
+			}
+			price := bidLimit.Price(ob.priceTick)
+			if order.gapExceeded(prevPrice, price) {
 				break
 			}
 
-			limitMatches := bidLimit.Fill(order, ob.priceTick)
+			limitMatches := bidLimit.FillAt(order, ob.priceTick, now)
 			matches = append(matches, limitMatches...)
+			levels++
+			prevPrice = price
 
 			if len(bidLimit.Orders) == 0 {
 				ob.clearLimit(true, bidLimit)
+			} else {
+				i++
 			}
 		}
 	}
 
+	ob.removeFilledMakers(matches)
+
 	// Market order never goes to the book
 	if order.IsFilled() {
 		order.State = OrderFilled
@@ -148,11 +229,105 @@ func (ob *Orderbook) CancelOrder(orderID int64) (*Order, error) {
 		}
 	}
 
-	delete(ob.Orders, orderID)
+	ob.removeOrder(order)
 	order.State = OrderCancelled
 	return order, nil
 }
 
+// ReduceOrder lowers the amount of resting order orderID to newAmount. The
+// order keeps its place in its level's queue. newAmount must be below the
+// current amount and above what has already filled, so the order stays open.
+func (ob *Orderbook) ReduceOrder(orderID int64, newAmount float64) (*Order, error) {
+	if !isFinite(newAmount) {
+		return nil, ErrNonFinite
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	order, exists := ob.Orders[orderID]
+	if !exists {
+		return nil, ErrOrderNotFound
+	}
+	if newAmount >= order.Amount {
+		return nil, ErrAmendIncrease
+	}
+	if newAmount <= order.FilledAmount {
+		return nil, ErrAmendBelowFilled
+	}
+
+	order.Limit.reduce(order, order.Amount-newAmount)
+	order.Amount = newAmount
+	return order, nil
+}
+
+// OpenOrderCount returns how many orders userID has resting in the book.
+func (ob *Orderbook) OpenOrderCount(userID string) int {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return len(ob.userOrders[userID])
+}
+
+// OpenOrderTotal returns how many orders are resting in the book.
+func (ob *Orderbook) OpenOrderTotal() int {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return len(ob.Orders)
+}
+
+// UserOrders returns the orders userID has resting in the book, oldest first.
+// It reads the per-user index, so the cost follows the user's order count
+// rather than the size of the book.
+func (ob *Orderbook) UserOrders(userID string) []*Order {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	var orders []*Order
+	for _, o := range ob.userOrders[userID] {
+		orders = append(orders, o)
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].Seq < orders[j].Seq
+	})
+	return orders
+}
+
+// RestingOrders returns every resting order, oldest first by Seq.
+func (ob *Orderbook) RestingOrders() []*Order {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	orders := make([]*Order, 0, len(ob.Orders))
+	for _, o := range ob.Orders {
+		orders = append(orders, o)
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].Seq < orders[j].Seq
+	})
+	return orders
+}
+
+// OrdersPlacedBefore returns the resting orders whose Timestamp is before
+// cutoff, oldest first by Seq.
+func (ob *Orderbook) OrdersPlacedBefore(cutoff time.Time) []*Order {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	var orders []*Order
+	for _, o := range ob.Orders {
+		if o.Timestamp.Before(cutoff) {
+			orders = append(orders, o)
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].Seq < orders[j].Seq
+	})
+	return orders
+}
+
 func (ob *Orderbook) Bids() []*Limit {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
@@ -183,6 +358,57 @@ func (ob *Orderbook) BestAsk() (*Limit, bool) {
 	return ob.asks[0], true
 }
 
+// BestLimitExcluding returns the best level on side holding at least one
+// resting order skip does not reject, and false when there is none.
+func (ob *Orderbook) BestLimitExcluding(side Side, skip func(*Order) bool) (*Limit, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	limits := ob.asks
+	if side == Bid {
+		limits = ob.bids
+	}
+	for _, limit := range limits {
+		for _, o := range limit.Orders {
+			if !skip(o) {
+				return limit, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// SpreadBps returns the visible spread relative to the mid price, in basis
+// points: (ask - bid) / mid * 10000. It is computed from the integer tick
+// prices, so the only rounding is the final division. Levels holding only
+// hidden orders are skipped, as in the public depth. It returns 0 and false
+// unless both sides have visible orders; a crossed book gives a negative
+// value.
+func (ob *Orderbook) SpreadBps() (float64, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	bid, hasBid := bestVisible(ob.bids)
+	ask, hasAsk := bestVisible(ob.asks)
+	if !hasBid || !hasAsk {
+		return 0, false
+	}
+
+	// (ask - bid) / ((ask + bid) / 2) * 10000, with prices in ticks
+	return float64(20_000*(ask.PriceTicks-bid.PriceTicks)) / float64(ask.PriceTicks+bid.PriceTicks), true
+}
+
+// bestVisible returns the first level of limits with visible volume. Must be
+// called with ob.mu held.
+func bestVisible(limits []*Limit) (*Limit, bool) {
+	for _, limit := range limits {
+		if limit.VisibleVolume() > 0 {
+			return limit, true
+		}
+	}
+	return nil, false
+}
+
 func (ob *Orderbook) BidTotalVolume() float64 {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
@@ -205,6 +431,67 @@ func (ob *Orderbook) AskTotalVolume() float64 {
 	return total
 }
 
+// SelfCrossingOrders returns the resting orders of order's owner on the opposite
+// side whose price the order would trade through.
+func (ob *Orderbook) SelfCrossingOrders(order *Order) []*Order {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	orderPriceTicks := utils.PriceToTicks(order.Price, ob.priceTick)
+
+	var crossing []*Order
+	if order.Side == Bid {
+		for _, askLimit := range ob.asks {
+			if askLimit.PriceTicks > orderPriceTicks {
+				break
+			}
+			crossing = appendUserOrders(crossing, askLimit, order.UserID)
+		}
+	} else {
+		for _, bidLimit := range ob.bids {
+			if bidLimit.PriceTicks < orderPriceTicks {
+				break
+			}
+			crossing = appendUserOrders(crossing, bidLimit, order.UserID)
+		}
+	}
+
+	return crossing
+}
+
+func appendUserOrders(dst []*Order, limit *Limit, userID string) []*Order {
+	for _, o := range limit.Orders {
+		if o.UserID == userID {
+			dst = append(dst, o)
+		}
+	}
+	return dst
+}
+
+// BidVisibleVolume is BidTotalVolume without hidden orders.
+func (ob *Orderbook) BidVisibleVolume() float64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	total := 0.0
+	for _, l := range ob.bids {
+		total += l.VisibleVolume()
+	}
+	return total
+}
+
+// AskVisibleVolume is AskTotalVolume without hidden orders.
+func (ob *Orderbook) AskVisibleVolume() float64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	total := 0.0
+	for _, l := range ob.asks {
+		total += l.VisibleVolume()
+	}
+	return total
+}
+
 func (ob *Orderbook) GetOrder(orderID int64) (*Order, bool) {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
@@ -240,6 +527,64 @@ func (ob *Orderbook) addOrderToBook(order *Order, priceTicks int64) {
 
 	limit.AddOrder(order)
 	ob.Orders[order.ID] = order
+	if ob.userOrders[order.UserID] == nil {
+		ob.userOrders[order.UserID] = make(map[int64]*Order)
+	}
+	ob.userOrders[order.UserID][order.ID] = order
+}
+
+// removeOrder drops order from the order indexes once it no longer rests.
+func (ob *Orderbook) removeOrder(order *Order) {
+	if _, exists := ob.Orders[order.ID]; !exists {
+		return
+	}
+
+	delete(ob.Orders, order.ID)
+	delete(ob.userOrders[order.UserID], order.ID)
+	if len(ob.userOrders[order.UserID]) == 0 {
+		delete(ob.userOrders, order.UserID)
+	}
+}
+
+// removeFilledMakers drops resting orders that were completely filled by matches.
+func (ob *Orderbook) removeFilledMakers(matches []Match) {
+	for _, m := range matches {
+		if m.Bid.IsFilled() {
+			ob.removeOrder(m.Bid)
+		}
+		if m.Ask.IsFilled() {
+			ob.removeOrder(m.Ask)
+		}
+	}
+}
+
+// Reconcile recomputes the volume of every level from the orders resting
+// there and removes levels left without any. It returns how many levels it
+// corrected or removed; a consistent book returns 0.
+func (ob *Orderbook) Reconcile() int {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	fixed := 0
+	for _, side := range []struct {
+		isBid  bool
+		limits []*Limit
+	}{
+		{true, append([]*Limit(nil), ob.bids...)},
+		{false, append([]*Limit(nil), ob.asks...)},
+	} {
+		for _, limit := range side.limits {
+			if len(limit.Orders) == 0 {
+				ob.clearLimit(side.isBid, limit)
+				fixed++
+				continue
+			}
+			if limit.reconcile() {
+				fixed++
+			}
+		}
+	}
+	return fixed
 }
 
 func (ob *Orderbook) clearLimit(isBid bool, limit *Limit) {
@@ -142,6 +142,27 @@ func TestOrderbook_PlaceMarketOrder_Sell_PartialFill_WhenNotEnoughLiquidity(t *t
 	assertFloat(t, 0.0, ob.BidTotalVolume(), "Bid total volume should be 0")
 }
 
+func TestOrderbook_PlaceMarketOrder_Sell_SweepsConsecutiveLevels(t *testing.T) {
+	ob := NewOrderbook()
+
+	for _, price := range []float64{50_300, 50_200, 50_100} {
+		bid, err := NewOrder("buyer", Bid, price, 0.5)
+		assertNoError(t, err)
+		ob.PlaceLimitOrder(bid)
+	}
+
+	sell, err := NewMarketOrder("seller", Ask, 1.5)
+	assertNoError(t, err)
+	matches := ob.PlaceMarketOrder(sell)
+
+	assertEqual(t, 3, len(matches), "Should consume all three levels")
+	assertFloat(t, 50_300, matches[0].Price, "Best bid first")
+	assertFloat(t, 50_200, matches[1].Price, "Next level second")
+	assertFloat(t, 50_100, matches[2].Price, "Last level third")
+	assertEqual(t, OrderFilled, sell.State, "Market sell should be filled")
+	assertEqual(t, 0, len(ob.Bids()), "Bids should be empty")
+}
+
 func TestOrderbook_PlaceMarketOrder_EmptyBook(t *testing.T) {
 	ob := NewOrderbook()
 
@@ -159,3 +180,43 @@ func TestOrderbook_PlaceMarketOrder_EmptyBook(t *testing.T) {
 	_, exists := ob.GetOrder(buy.ID)
 	assertFalse(t, exists, "Market order should not be stored in Orders map")
 }
+
+func TestOrderbook_EstimateFill_DoesNotMutate(t *testing.T) {
+	ob := NewOrderbook()
+
+	ask1, _ := NewOrder("seller1", Ask, 50_000, 0.6)
+	ask2, _ := NewOrder("seller2", Ask, 50_100, 0.4)
+	ob.PlaceLimitOrder(ask1)
+	ob.PlaceLimitOrder(ask2)
+
+	est := ob.EstimateFill(Bid, 0.8)
+
+	assertFloat(t, 0.8, est.Filled, "Filled")
+	assertEqual(t, 2, est.Levels, "Levels touched")
+	assertFloat(t, 50_000, est.FirstPrice, "First price")
+	assertFloat(t, 50_100, est.LastPrice, "Last price")
+	assertFloat(t, 0.6*50_000+0.2*50_100, est.QuoteAmount, "Quote amount")
+
+	assertFloat(t, 0, ask1.FilledAmount, "Ask1 untouched")
+	assertFloat(t, 1.0, ob.AskTotalVolume(), "Ask volume untouched")
+}
+
+func TestOrderbook_PlaceMarketOrder_Sell_StopsAtGap(t *testing.T) {
+	ob := NewOrderbook()
+	for _, price := range []float64{50_000, 49_000, 40_000} {
+		bid, err := NewOrder("buyer", Bid, price, 0.5)
+		assertNoError(t, err)
+		ob.PlaceLimitOrder(bid)
+	}
+
+	sell, err := NewMarketOrder("seller", Ask, 1.5)
+	assertNoError(t, err)
+	sell.MaxGap = 0.1
+
+	// 49,000 is 2% below 50,000; 40,000 is 18% below 49,000
+	matches := ob.PlaceMarketOrder(sell)
+	assertEqual(t, 2, len(matches), "Stops before the gap")
+	assertFloat(t, 1, sell.FilledAmount, "Filled the first two levels")
+	assertEqual(t, OrderPartiallyFilled, sell.State, "Partial fill")
+	assertEqual(t, 1, len(ob.Bids()), "Level past the gap untouched")
+}
@@ -1,8 +1,11 @@
 package orderbook
 
 import (
+	"math"
 	"testing"
 	"time"
+
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
 )
 
 func TestNewOrderbook(t *testing.T) {
@@ -13,6 +16,18 @@ func TestNewOrderbook(t *testing.T) {
 	assertEqual(t, 0, len(ob.Orders), "Orders should be empty")
 }
 
+func TestNewOrderbookWithTick_InvalidTick(t *testing.T) {
+	for _, tick := range []float64{0, -0.01, math.Inf(1), math.NaN()} {
+		ob, err := NewOrderbookWithTick(tick)
+		assertEqual(t, ErrInvalidTick, err, "Degenerate tick rejected")
+		assertTrue(t, ob == nil, "No book built")
+	}
+
+	ob, err := NewOrderbookWithTick(0.5)
+	assertNoError(t, err)
+	assertFloat(t, 0.5, ob.PriceTick(), "Tick kept")
+}
+
 func TestOrderbook_PlaceLimitOrder_NoMatch(t *testing.T) {
 	ob := NewOrderbook()
 
@@ -123,15 +138,220 @@ func TestOrderbook_PlaceLimitOrder_MultipleMatches(t *testing.T) {
 	assertEqual(t, 0, len(ob.Asks()), "Asks should be empty")
 }
 
-func TestOrderbook_PlaceLimitOrder_PriceTimePriority(t *testing.T) {
+func TestOrderbook_PlaceLimitOrder_SweepsConsecutiveLevels(t *testing.T) {
 	ob := NewOrderbook()
 
-	ask1, err := NewOrder("1", Ask, 50_000, 1.0)
+	// Emptying a level must not skip the one behind it
+	for _, price := range []float64{50_000, 50_100, 50_200, 50_300} {
+		ask, err := NewOrder("1", Ask, price, 0.5)
+		assertNoError(t, err)
+		ob.PlaceLimitOrder(ask)
+	}
+
+	bid, err := NewOrder("2", Bid, 50_200, 2.0)
 	assertNoError(t, err)
+	matches := ob.PlaceLimitOrder(bid)
+
+	assertEqual(t, 3, len(matches), "Should match every level up to the limit")
+	assertFloat(t, 50_000, matches[0].Price, "First level")
+	assertFloat(t, 50_100, matches[1].Price, "Second level")
+	assertFloat(t, 50_200, matches[2].Price, "Third level")
+	assertFloat(t, 0.5, bid.RemainingAmount(), "Remainder rests")
+
+	best, _ := ob.BestAsk()
+	assertFloat(t, 50_300, best.Price(priceTick), "Untouched level left")
+}
 
-	time.Sleep(1 * time.Millisecond)
+func TestOrderbook_PlaceLimitOrder_MaxLevels(t *testing.T) {
+	ob := NewOrderbook()
+	for _, price := range []float64{50_000, 50_100, 50_200} {
+		ask, err := NewOrder("1", Ask, price, 0.5)
+		assertNoError(t, err)
+		ob.PlaceLimitOrder(ask)
+	}
 
-	ask2, err := NewOrder("2", Ask, 50_000, 1.0)
+	// Stopped while 50,200 still crosses: resting at 50,200 would cross the book
+	bid, err := NewOrder("2", Bid, 50_200, 2.0)
+	assertNoError(t, err)
+	bid.MaxLevels = 2
+	matches := ob.PlaceLimitOrder(bid)
+
+	assertEqual(t, 2, len(matches), "Stops at the cap")
+	assertEqual(t, OrderCancelled, bid.State, "Remainder cancelled")
+	assertFalse(t, bid.Limit != nil, "Not resting")
+	assertEqual(t, 0, len(ob.Bids()), "No bid left in the book")
+
+	// A cap the order never reaches leaves it resting as usual
+	bid, err = NewOrder("2", Bid, 50_200, 1.0)
+	assertNoError(t, err)
+	bid.MaxLevels = 5
+	ob.PlaceLimitOrder(bid)
+	assertEqual(t, OrderPartiallyFilled, bid.State, "Rests after the last crossing level")
+	assertEqual(t, 1, len(ob.Bids()), "Remainder rests")
+}
+
+func TestOrderbook_DepthInRange(t *testing.T) {
+	ob := NewOrderbook()
+	for _, ask := range []struct {
+		price, amount float64
+		hidden        bool
+	}{
+		{49_900, 1.0, false}, // below the range
+		{50_000, 0.5, false},
+		{50_000, 0.25, false},
+		{50_500, 0.3, false},
+		{50_500, 2.0, true}, // hidden, not counted
+		{51_000, 0.2, false},
+		{51_000.01, 4.0, false}, // one tick above the range
+	} {
+		order, err := NewOrder("1", Ask, ask.price, ask.amount)
+		assertNoError(t, err)
+		order.Hidden = ask.hidden
+		ob.PlaceLimitOrder(order)
+	}
+	bid, err := NewOrder("2", Bid, 49_000, 1.0)
+	assertNoError(t, err)
+	ob.PlaceLimitOrder(bid)
+
+	depth := ob.DepthInRange(Ask, 50_000, 51_000)
+	assertFloat(t, 1.25, depth.Volume, "Visible volume in range")
+	assertEqual(t, 4, depth.Orders, "Visible orders in range")
+	assertEqual(t, 3, depth.Levels, "Levels in range")
+
+	depth = ob.DepthInRange(Bid, 48_000, 49_500)
+	assertFloat(t, 1.0, depth.Volume, "Bid side")
+	assertEqual(t, 1, depth.Orders, "Bid orders")
+
+	assertEqual(t, RangeDepth{}, ob.DepthInRange(Ask, 52_000, 53_000), "Range with no levels")
+	assertEqual(t, RangeDepth{}, ob.DepthInRange(Ask, 51_000, 50_000), "Inverted range")
+}
+
+func TestOrderbook_WeightedMid(t *testing.T) {
+	ob := NewOrderbook()
+
+	_, ok := ob.WeightedMid(2)
+	assertFalse(t, ok, "Empty book")
+
+	for _, o := range []struct {
+		side          Side
+		price, amount float64
+		hidden        bool
+	}{
+		{Bid, 100, 1, false},
+		{Bid, 99.5, 5, true}, // hidden-only level, skipped
+		{Bid, 99, 1, false},
+		{Bid, 96, 6, false},
+		{Ask, 101, 1, false},
+		{Ask, 102, 3, false},
+		{Ask, 104, 4, false},
+	} {
+		order, err := NewOrder("1", o.side, o.price, o.amount)
+		assertNoError(t, err)
+		order.Hidden = o.hidden
+		ob.PlaceLimitOrder(order)
+	}
+
+	// 2 levels: bids 99.5 over 2, asks 101.75 over 4
+	// (99.5*4 + 101.75*2) / 6 = 100.25
+	mid, ok := ob.WeightedMid(2)
+	assertTrue(t, ok, "Two-sided book")
+	assertFloat(t, 100.25, mid, "Two levels")
+
+	// 3 levels: bids 775/8 = 96.875 over 8, asks 823/8 = 102.875 over 8
+	// (96.875*8 + 102.875*8) / 16 = 99.875
+	mid, _ = ob.WeightedMid(3)
+	assertFloat(t, 99.875, mid, "Three levels")
+
+	mid, _ = ob.WeightedMid(10)
+	assertFloat(t, 99.875, mid, "Fewer levels than asked uses them all")
+
+	// 1 level is the micro-price: (100*1 + 101*1) / 2
+	mid, _ = ob.WeightedMid(1)
+	assertFloat(t, 100.5, mid, "Micro-price")
+
+	_, ok = ob.WeightedMid(0)
+	assertFalse(t, ok, "Non-positive levels")
+
+	oneSided := NewOrderbook()
+	bid, err := NewOrder("1", Bid, 100, 1)
+	assertNoError(t, err)
+	oneSided.PlaceLimitOrder(bid)
+	_, ok = oneSided.WeightedMid(3)
+	assertFalse(t, ok, "One-sided book")
+}
+
+func TestOrderbook_Standing(t *testing.T) {
+	ob := NewOrderbook()
+	place := func(userID string, side Side, price, amount float64, hidden bool) *Order {
+		t.Helper()
+		order, err := NewOrder(userID, side, price, amount)
+		assertNoError(t, err)
+		order.Hidden = hidden
+		ob.PlaceLimitOrder(order)
+		return order
+	}
+
+	place("2", Bid, 50_100, 0.5, false)  // better price, ahead
+	place("3", Bid, 50_150, 0.4, true)   // hidden, not shown
+	place("2", Bid, 50_000, 0.25, false) // earlier at the same price, ahead
+	place("3", Bid, 50_000, 0.3, true)   // hidden yields to visible, behind
+	target := place("1", Bid, 50_000, 1, false)
+	place("3", Bid, 50_000, 0.2, false)  // later, behind
+	place("1", Ask, 50_200, 0.25, false) // own, skipped by matching
+	place("2", Ask, 50_200, 0.25, false)
+	place("3", Ask, 50_300, 0.4, true) // hidden, not shown
+	place("2", Ask, 50_400, 0.5, false)
+
+	standing, ok := ob.Standing(target.ID)
+	assertTrue(t, ok, "Resting order found")
+	assertEqual(t, Bid, standing.Side, "Side")
+	assertFloat(t, 50_000, standing.Price, "Price")
+	assertFloat(t, 1, standing.Remaining, "Remaining")
+	assertFloat(t, 0.75, standing.QueueAhead, "Queue ahead")
+	assertEqual(t, 2, standing.Position, "Behind the earlier visible order only")
+	assertFloat(t, 0.75, standing.Fillable, "Fillable without own or hidden asks")
+	assertFloat(t, 37_750, standing.FillableQuote, "0.25*50,200 + 0.5*50,400")
+
+	_, ok = ob.Standing(999)
+	assertFalse(t, ok, "Unknown order")
+}
+
+func TestOrderbook_ReduceOrder(t *testing.T) {
+	ob := NewOrderbook()
+
+	first, err := NewOrder("1", Bid, 50_000, 1.0)
+	assertNoError(t, err)
+	ob.PlaceLimitOrder(first)
+	hidden, err := NewOrder("2", Bid, 50_000, 1.0)
+	assertNoError(t, err)
+	hidden.Hidden = true
+	ob.PlaceLimitOrder(hidden)
+
+	_, err = ob.ReduceOrder(first.ID, 0.5)
+	assertNoError(t, err)
+	_, err = ob.ReduceOrder(hidden.ID, 0.25)
+	assertNoError(t, err)
+
+	limit := first.Limit
+	assertEqual(t, first.ID, limit.Orders[0].ID, "Queue order unchanged")
+	assertFloat(t, 0.75, limit.TotalVolume, "Total volume")
+	assertFloat(t, 0.25, limit.HiddenVolume, "Hidden volume")
+	assertFloat(t, 0.5, limit.VisibleVolume(), "Visible volume")
+
+	_, err = ob.ReduceOrder(first.ID, 0.5)
+	assertEqual(t, ErrAmendIncrease, err, "Same amount")
+	_, err = ob.ReduceOrder(999, 0.1)
+	assertEqual(t, ErrOrderNotFound, err, "Unknown order")
+}
+
+func TestOrderbook_PlaceLimitOrder_PriceTimePriority(t *testing.T) {
+	clk := newTestClock()
+	ob, err := NewOrderbookWithClock(0.01, clk)
+	assertNoError(t, err)
+
+	ask1, err := NewOrderAt("1", Ask, 50_000, 1.0, clk.Now())
+	assertNoError(t, err)
+	ask2, err := NewOrderAt("2", Ask, 50_000, 1.0, clk.Now())
 	assertNoError(t, err)
 
 	ob.PlaceLimitOrder(ask1)
@@ -255,6 +475,27 @@ func TestOrderbook_BestBid_BestAsk(t *testing.T) {
 	assertFloat(t, 50_500, bestAsk.Price(priceTick), "Best ask price")
 }
 
+func TestOrderbook_BestLimitExcluding(t *testing.T) {
+	ob := NewOrderbook()
+
+	skipped, err := NewOrder("1", Bid, 50_000, 1.0)
+	assertNoError(t, err)
+	ob.PlaceLimitOrder(skipped)
+
+	order2, err := NewOrder("2", Bid, 49_000, 1.0)
+	assertNoError(t, err)
+	ob.PlaceLimitOrder(order2)
+
+	skip := func(o *Order) bool { return o.ID == skipped.ID }
+
+	best, ok := ob.BestLimitExcluding(Bid, skip)
+	assertTrue(t, ok, "Should find a bid")
+	assertFloat(t, 49_000, best.Price(priceTick), "Skips the level holding only the skipped order")
+
+	_, ok = ob.BestLimitExcluding(Ask, skip)
+	assertFalse(t, ok, "No asks")
+}
+
 func TestOrderbook_TotalVolumes(t *testing.T) {
 	ob := NewOrderbook()
 
@@ -273,3 +514,203 @@ func TestOrderbook_TotalVolumes(t *testing.T) {
 	assertFloat(t, 3.0, ob.BidTotalVolume(), "Bid total volume")
 	assertFloat(t, 3.0, ob.AskTotalVolume(), "Ask total volume")
 }
+
+func TestOrderbook_Reconcile_AfterPartialFills(t *testing.T) {
+	ob := NewOrderbook()
+
+	amounts := []float64{0.7, 0.3, 1.1, 0.9}
+	for i, amount := range amounts {
+		ask, err := NewOrder("maker", Ask, 50_000, amount)
+		assertNoError(t, err)
+		ask.Hidden = i%2 == 1
+		ob.PlaceLimitOrder(ask)
+	}
+
+	// Odd-sized partial fills leave the running totals off by float noise
+	for i := 0; i < 150; i++ {
+		bid, err := NewOrder("taker", Bid, 50_000, []float64{0.01, 0.03, 0.007}[i%3])
+		assertNoError(t, err)
+		ob.PlaceLimitOrder(bid)
+	}
+
+	limit, ok := ob.BestAsk()
+	assertTrue(t, ok, "Level still rests")
+	assertTrue(t, ob.Reconcile() <= 1, "At most the one level corrected")
+
+	total, hidden := 0.0, 0.0
+	for _, o := range limit.Orders {
+		total += o.RemainingAmount()
+		if o.Hidden {
+			hidden += o.RemainingAmount()
+		}
+	}
+	assertFloat(t, total, limit.TotalVolume, "Total volume is the sum of the remainders")
+	assertFloat(t, hidden, limit.HiddenVolume, "Hidden volume is the sum of the hidden remainders")
+	assertEqual(t, 0, ob.Reconcile(), "Nothing left to correct")
+}
+
+func TestOrderbook_Reconcile_DriftAndEmptyLevels(t *testing.T) {
+	ob := NewOrderbook()
+
+	bid, err := NewOrder("1", Bid, 50_000, 1.0)
+	assertNoError(t, err)
+	ob.PlaceLimitOrder(bid)
+	ask, err := NewOrder("2", Ask, 51_000, 1.0)
+	assertNoError(t, err)
+	ob.PlaceLimitOrder(ask)
+
+	// A drifted total, and a level whose orders are gone
+	bid.Limit.TotalVolume += 1e-12
+	ask.Limit.Orders = nil
+
+	assertEqual(t, 2, ob.Reconcile(), "Both levels fixed")
+	assertFloat(t, 1.0, bid.Limit.TotalVolume, "Drift removed")
+	_, ok := ob.BestAsk()
+	assertFalse(t, ok, "Empty level removed")
+	assertEqual(t, 0, len(ob.AskLimits), "Empty level unindexed")
+}
+
+func TestOrderbook_UserIndexMatchesScan(t *testing.T) {
+	ob := NewOrderbook()
+
+	place := func(userID string, side Side, price, amount float64) *Order {
+		t.Helper()
+		order, err := NewOrder(userID, side, price, amount)
+		assertNoError(t, err)
+		ob.PlaceLimitOrder(order)
+		return order
+	}
+
+	place("1", Ask, 51_000, 1.0)
+	a2 := place("1", Ask, 51_100, 0.5)
+	b1 := place("2", Bid, 50_000, 2.0)
+	place("2", Bid, 49_900, 1.0)
+	place("3", Ask, 51_000, 0.4)
+
+	// Partial fill, full fill, cancel, reduce and a market order
+	place("4", Bid, 51_000, 0.6)
+	place("4", Bid, 51_000, 0.8)
+	_, err := ob.CancelOrder(b1.ID)
+	assertNoError(t, err)
+	_, err = ob.ReduceOrder(a2.ID, 0.45)
+	assertNoError(t, err)
+	market, err := NewMarketOrder("3", Bid, 0.3)
+	assertNoError(t, err)
+	ob.PlaceMarketOrder(market)
+	place("2", Ask, 52_000, 0.1)
+
+	scan := make(map[string]map[int64]*Order)
+	for id, o := range ob.Orders {
+		if scan[o.UserID] == nil {
+			scan[o.UserID] = make(map[int64]*Order)
+		}
+		scan[o.UserID][id] = o
+	}
+
+	assertEqual(t, len(scan), len(ob.userOrders), "Users in the index")
+	for userID, orders := range scan {
+		assertEqual(t, len(orders), len(ob.userOrders[userID]), "Orders of user "+userID)
+		assertEqual(t, len(orders), ob.OpenOrderCount(userID), "Open count of user "+userID)
+		for id, o := range orders {
+			assertEqual(t, o, ob.userOrders[userID][id], "Indexed order")
+		}
+	}
+	assertEqual(t, 0, len(ob.UserOrders("4")), "Filled taker holds nothing")
+}
+
+func TestOrderbook_SelfCrossingOrders(t *testing.T) {
+	ob := NewOrderbook()
+
+	own1, _ := NewOrder("1", Ask, 50_000, 1.0)
+	other, _ := NewOrder("2", Ask, 50_000, 1.0)
+	own2, _ := NewOrder("1", Ask, 52_000, 1.0)
+	ob.PlaceLimitOrder(own1)
+	ob.PlaceLimitOrder(other)
+	ob.PlaceLimitOrder(own2)
+
+	bid, _ := NewOrder("1", Bid, 51_000, 1.0)
+	crossing := ob.SelfCrossingOrders(bid)
+
+	assertEqual(t, 1, len(crossing), "Only own ask within the bid price crosses")
+	assertEqual(t, own1.ID, crossing[0].ID, "Crossing order")
+
+	passive, _ := NewOrder("1", Bid, 49_000, 1.0)
+	assertEqual(t, 0, len(ob.SelfCrossingOrders(passive)), "Passive bid crosses nothing")
+}
+
+func TestOrderbook_SpreadBps(t *testing.T) {
+	ob := NewOrderbook()
+
+	_, ok := ob.SpreadBps()
+	assertFalse(t, ok, "Empty book")
+
+	bid, _ := NewOrder("1", Bid, 49_990, 1.0)
+	ob.PlaceLimitOrder(bid)
+	bps, ok := ob.SpreadBps()
+	assertFalse(t, ok, "One-sided book")
+	assertFloat(t, 0, bps, "No spread without asks")
+
+	// mid 50,000, spread 20: 20 / 50,000 * 10,000 = 4 bps
+	ask, _ := NewOrder("2", Ask, 50_010, 1.0)
+	ob.PlaceLimitOrder(ask)
+	bps, ok = ob.SpreadBps()
+	assertTrue(t, ok, "Two-sided book")
+	assertFloat(t, 4, bps, "Spread in bps")
+
+	// Hidden-only levels are not the visible top
+	hidden, _ := NewOrder("2", Ask, 50_000, 1.0)
+	hidden.Hidden = true
+	ob.PlaceLimitOrder(hidden)
+	bps, _ = ob.SpreadBps()
+	assertFloat(t, 4, bps, "Hidden ask ignored")
+
+	// mid 100.50, spread 1: 1 / 100.5 * 10,000 = 20,000 / 201 bps
+	small := NewOrderbook()
+	smallBid, _ := NewOrder("1", Bid, 100, 1.0)
+	smallAsk, _ := NewOrder("2", Ask, 101, 1.0)
+	small.PlaceLimitOrder(smallBid)
+	small.PlaceLimitOrder(smallAsk)
+	bps, _ = small.SpreadBps()
+	assertFloat(t, 20_000.0/201, bps, "Non-integer bps")
+}
+
+func TestOrderbook_FilledMakerRemovedFromOrders(t *testing.T) {
+	ob := NewOrderbook()
+
+	ask, _ := NewOrder("1", Ask, 50_000, 1.0)
+	ob.PlaceLimitOrder(ask)
+	assertEqual(t, 1, ob.OpenOrderCount("1"), "Open orders after resting")
+
+	bid, _ := NewOrder("2", Bid, 50_000, 1.0)
+	ob.PlaceLimitOrder(bid)
+
+	_, exists := ob.GetOrder(ask.ID)
+	assertFalse(t, exists, "Filled maker should leave the Orders map")
+	assertEqual(t, 0, ob.OpenOrderCount("1"), "Open orders after fill")
+
+	_, err := ob.CancelOrder(ask.ID)
+	assertEqual(t, ErrOrderNotFound, err, "Filled order cannot be cancelled")
+	assertEqual(t, OrderFilled, ask.State, "State stays filled")
+}
+
+func TestOrderbook_FIFO_SameTimestamp(t *testing.T) {
+	// Zero step: every order and match gets the exact same timestamp
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+	ob, err := NewOrderbookWithClock(0.01, clk)
+	assertNoError(t, err)
+
+	for _, user := range []string{"1", "2", "3"} {
+		ask, err := NewOrderAt(user, Ask, 50_000, 1.0, clk.Now())
+		assertNoError(t, err)
+		ob.PlaceLimitOrder(ask)
+	}
+
+	bid, err := NewOrderAt("4", Bid, 50_000, 2.0, clk.Now())
+	assertNoError(t, err)
+	matches := ob.PlaceLimitOrder(bid)
+
+	assertEqual(t, 2, len(matches), "Should have 2 matches")
+	assertEqual(t, "1", matches[0].Ask.UserID, "First placed matches first")
+	assertEqual(t, "2", matches[1].Ask.UserID, "Second placed matches second")
+	assertEqual(t, "3", ob.Asks()[0].Orders[0].UserID, "Third still resting")
+}
@@ -0,0 +1,89 @@
+package orderbook
+
+import (
+	"fmt"
+
+	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
+)
+
+// Export returns a copy of every resting order in priority order: bids from
+// the best price down, then asks from the best price up, each level in queue
+// order. The copies carry no Limit and can be fed to Import on another book.
+func (ob *Orderbook) Export() []Order {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	orders := make([]Order, 0, len(ob.Orders))
+	for _, levels := range [][]*Limit{ob.bids, ob.asks} {
+		for _, limit := range levels {
+			for _, o := range limit.Orders {
+				snapshot := *o
+				snapshot.Limit = nil
+				orders = append(orders, snapshot)
+			}
+		}
+	}
+	return orders
+}
+
+// Import rests orders, as returned by Export, in an empty book. IDs, Seq
+// numbers and fill progress are kept, so FIFO priority within each level is
+// the same as where the orders came from. Every price must be aligned to
+// this book's tick and every ID must be unique. Nothing is imported unless
+// every order is valid. Afterwards new orders are numbered past the highest
+// imported ID and Seq.
+func (ob *Orderbook) Import(orders []Order) error {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	if len(ob.Orders) > 0 {
+		return ErrBookNotEmpty
+	}
+
+	seen := make(map[int64]bool, len(orders))
+	var maxID int64
+	var maxSeq uint64
+	for i := range orders {
+		o := &orders[i]
+		if seen[o.ID] {
+			return fmt.Errorf("order %d: %w", o.ID, ErrDuplicateID)
+		}
+		seen[o.ID] = true
+
+		if err := ob.validateImport(o); err != nil {
+			return fmt.Errorf("order %d: %w", o.ID, err)
+		}
+		maxID = max(maxID, o.ID)
+		maxSeq = max(maxSeq, o.Seq)
+	}
+	advanceCounters(maxID, maxSeq)
+
+	for i := range orders {
+		o := orders[i]
+		queuePos := o.QueuePos
+		ob.addOrderToBook(&o, utils.PriceToTicks(o.Price, ob.priceTick))
+		o.QueuePos = queuePos
+	}
+	return nil
+}
+
+// validateImport checks that o can rest in this book. Must be called with
+// ob.mu held.
+func (ob *Orderbook) validateImport(o *Order) error {
+	if o.Side != Bid && o.Side != Ask {
+		return ErrInvalidSide
+	}
+	if !isFinite(o.Price) || !isFinite(o.Amount) || !isFinite(o.FilledAmount) {
+		return ErrNonFinite
+	}
+	if o.Price <= 0 {
+		return ErrInvalidPrice
+	}
+	if o.RemainingAmount() <= 0 {
+		return ErrInvalidAmount
+	}
+	if !utils.IsValidTick(o.Price, ob.priceTick) {
+		return ErrPriceOffTick
+	}
+	return nil
+}
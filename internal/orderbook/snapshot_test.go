@@ -0,0 +1,137 @@
+package orderbook
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOrderbook_ExportImport_RoundTrip(t *testing.T) {
+	clk := newTestClock()
+	ob, err := NewOrderbookWithClock(priceTick, clk)
+	assertNoError(t, err)
+
+	place := func(user string, side Side, price, amount float64, hidden bool) *Order {
+		o, err := NewOrderAt(user, side, price, amount, clk.Now())
+		assertNoError(t, err)
+		o.Hidden = hidden
+		ob.PlaceLimitOrder(o)
+		return o
+	}
+	place("1", Bid, 49_000, 1, false)
+	place("2", Bid, 49_500, 0.5, false)
+	place("3", Bid, 49_500, 0.25, true)
+	place("4", Bid, 49_500, 0.75, false)
+	place("5", Ask, 50_000, 2, false)
+	place("6", Ask, 50_000, 1, false)
+	place("7", Ask, 51_000, 3, false)
+
+	// Partially fill the first ask so fill progress must survive too
+	taker := place("8", Bid, 50_000, 0.5, false)
+	assertTrue(t, taker.IsFilled(), "Taker filled")
+
+	exported := ob.Export()
+	assertEqual(t, 7, len(exported), "Exported resting orders")
+
+	restored, err := NewOrderbookWithClock(priceTick, clk)
+	assertNoError(t, err)
+	assertNoError(t, restored.Import(exported))
+
+	bestBid, _ := restored.BestBid()
+	bestAsk, _ := restored.BestAsk()
+	assertFloat(t, 49_500, bestBid.Price(priceTick), "Best bid")
+	assertFloat(t, 50_000, bestAsk.Price(priceTick), "Best ask")
+	assertEqual(t, len(ob.Bids()), len(restored.Bids()), "Bid levels")
+	assertEqual(t, len(ob.Asks()), len(restored.Asks()), "Ask levels")
+
+	for _, side := range []struct {
+		name          string
+		before, after []*Limit
+	}{
+		{"bids", ob.Bids(), restored.Bids()},
+		{"asks", ob.Asks(), restored.Asks()},
+	} {
+		for i := range side.before {
+			before, after := side.before[i], side.after[i]
+			assertEqual(t, before.PriceTicks, after.PriceTicks, side.name+" level price")
+			assertFloat(t, before.TotalVolume, after.TotalVolume, side.name+" level volume")
+			assertFloat(t, before.HiddenVolume, after.HiddenVolume, side.name+" level hidden volume")
+			assertEqual(t, len(before.Orders), len(after.Orders), side.name+" level orders")
+			for j := range before.Orders {
+				assertEqual(t, before.Orders[j].ID, after.Orders[j].ID, side.name+" queue order")
+				assertFloat(t, before.Orders[j].FilledAmount, after.Orders[j].FilledAmount, side.name+" filled amount")
+				assertEqual(t, before.Orders[j].QueuePos, after.Orders[j].QueuePos, side.name+" queue position")
+			}
+		}
+	}
+
+	assertEqual(t, 1, restored.OpenOrderCount("5"), "Open orders indexed by user")
+	_, exists := restored.GetOrder(exported[0].ID)
+	assertTrue(t, exists, "Orders indexed by ID")
+
+	// The restored book keeps matching in the original FIFO order
+	sell, err := NewOrderAt("9", Ask, 49_500, 1, clk.Now())
+	assertNoError(t, err)
+	matches := restored.PlaceLimitOrder(sell)
+	assertEqual(t, 2, len(matches), "Matches at best bid")
+	assertEqual(t, "2", matches[0].Bid.UserID, "First visible bid first")
+	assertEqual(t, "4", matches[1].Bid.UserID, "Hidden bid yields to visible")
+}
+
+func TestOrderbook_Import_NewTick(t *testing.T) {
+	ob := NewOrderbook()
+	o, _ := NewOrder("1", Bid, 49_000.5, 1)
+	ob.PlaceLimitOrder(o)
+
+	// 49,000.50 is not on a 1.00 tick
+	coarse, err := NewOrderbookWithTick(1)
+	assertNoError(t, err)
+	err = coarse.Import(ob.Export())
+	assertTrue(t, errors.Is(err, ErrPriceOffTick), "Misaligned price rejected")
+	assertEqual(t, 0, coarse.OpenOrderTotal(), "Nothing imported")
+
+	// A finer tick accepts it
+	fine, err := NewOrderbookWithTick(0.001)
+	assertNoError(t, err)
+	assertNoError(t, fine.Import(ob.Export()))
+	best, _ := fine.BestBid()
+	assertFloat(t, 49_000.5, best.Price(0.001), "Price kept under the new tick")
+}
+
+func TestOrderbook_Import_RequiresEmptyBook(t *testing.T) {
+	ob := NewOrderbook()
+	o, _ := NewOrder("1", Bid, 49_000, 1)
+	ob.PlaceLimitOrder(o)
+
+	err := ob.Import(ob.Export())
+	assertEqual(t, ErrBookNotEmpty, err, "Import into a non-empty book")
+}
+
+func TestOrderbook_Import_DuplicateID(t *testing.T) {
+	ob := NewOrderbook()
+	first, _ := NewOrder("1", Bid, 49_000, 1)
+	second, _ := NewOrder("2", Ask, 50_000, 1)
+	second.ID = first.ID
+
+	err := ob.Import([]Order{*first, *second})
+	assertTrue(t, errors.Is(err, ErrDuplicateID), "Duplicate ID rejected")
+	assertEqual(t, 0, ob.OpenOrderTotal(), "Nothing imported")
+}
+
+func TestOrderbook_Import_AdvancesCounters(t *testing.T) {
+	o, _ := NewOrder("1", Bid, 49_000, 1)
+	imported := *o
+	imported.ID = o.ID + 1_000
+	imported.Seq = o.Seq + 1_000
+
+	ob := NewOrderbook()
+	assertNoError(t, ob.Import([]Order{imported}))
+
+	next, _ := NewOrder("2", Bid, 49_000, 1)
+	assertEqual(t, imported.ID+1, next.ID, "Next ID past the imported one")
+	assertEqual(t, imported.Seq+1, next.Seq, "Next Seq past the imported one")
+
+	ob.PlaceLimitOrder(next)
+	best, _ := ob.BestBid()
+	assertEqual(t, imported.ID, best.Orders[0].ID, "Imported order keeps its priority")
+	assertEqual(t, 2, ob.OpenOrderTotal(), "Both orders resting")
+}
@@ -2,7 +2,9 @@ package orderbook
 
 import (
 	"testing"
+	"time"
 
+	"github.com/moura95/crypto-exchange-challenge/pkg/clock"
 	"github.com/moura95/crypto-exchange-challenge/pkg/utils"
 )
 
@@ -46,3 +48,8 @@ func assertFalse(t *testing.T, condition bool, msg string) {
 func priceToTicks(price float64) int64 {
 	return utils.PriceToTicks(price, priceTick)
 }
+
+// newTestClock returns a fake clock that moves 1ms forward on every read.
+func newTestClock() *clock.Fake {
+	return clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Millisecond)
+}
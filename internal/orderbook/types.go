@@ -18,6 +18,7 @@ const (
 	OrderPartiallyFilled OrderState = "partially_filled"
 	OrderFilled          OrderState = "filled"
 	OrderCancelled       OrderState = "cancelled"
+	OrderExpired         OrderState = "expired" // good-till-date order removed at its expiry
 )
 
 type OrderType string
@@ -29,6 +30,35 @@ const (
 
 var orderIDCounter int64
 
-func nextOrderID() int64 {
+// NextOrderID draws a process-wide order ID, as every new order does. IDs
+// are never handed out twice.
+func NextOrderID() int64 {
 	return atomic.AddInt64(&orderIDCounter, 1)
 }
+
+// seqCounter orders creation across the process. Queue priority within a
+// price level is by Seq, never by Timestamp, so it does not depend on clock
+// resolution.
+var seqCounter uint64
+
+func nextSeq() uint64 {
+	return atomic.AddUint64(&seqCounter, 1)
+}
+
+// advanceCounters moves the ID and Seq counters to at least id and seq, so
+// orders created after an import never reuse an imported ID or queue ahead of
+// an imported order.
+func advanceCounters(id int64, seq uint64) {
+	for {
+		cur := atomic.LoadInt64(&orderIDCounter)
+		if cur >= id || atomic.CompareAndSwapInt64(&orderIDCounter, cur, id) {
+			break
+		}
+	}
+	for {
+		cur := atomic.LoadUint64(&seqCounter)
+		if cur >= seq || atomic.CompareAndSwapUint64(&seqCounter, cur, seq) {
+			break
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	v1 "github.com/moura95/crypto-exchange-challenge/api/v1"
@@ -21,19 +24,66 @@ type Server struct {
 	orderHandler     *handler.OrderHandler
 	accountHandler   *handler.AccountHandler
 	orderbookHandler *handler.OrderbookHandler
+	pairHandler      *handler.PairHandler
+	sessionHandler   *handler.SessionHandler
+	streamHandler    *handler.StreamHandler
+	adminHandler     *handler.AdminHandler
+	feeHandler       *handler.FeeHandler
+	configHandler    *handler.ConfigHandler
 	startTime        time.Time
+	httpServer       *http.Server
+
+	mu    sync.Mutex
+	stops []func() // stop the background workers Start launched
 }
 
+// expirySweepInterval is how often good-till-date orders are checked for expiry.
+const expirySweepInterval = time.Second
+
 func NewServer(cfg *config.Config) (*Server, error) {
 	logger.Info("Initializing server...")
 
 	// Initialize engine
-	eng := engine.NewEngine()
+	engineCfg := engine.DefaultConfig()
+	engineCfg.TickPolicy = engine.TickPolicy(cfg.TickPolicy)
+	engineCfg.MaxOpenOrdersPerUser = cfg.MaxOpenOrdersPerUser
+	engineCfg.MaxLevelsPerOrder = cfg.MaxLevelsPerOrder
+	engineCfg.SweepDust = cfg.SweepDust
+	engineCfg.SpreadSampleInterval = cfg.SpreadSampleInterval
+	engineCfg.AllowUnlistedPairs = cfg.AllowUnlistedPairs
+	engineCfg.Deterministic = cfg.DeterministicMatching
+	engineCfg.MinRefund = cfg.MinRefund
+	engineCfg.FeeRounding = engine.FeeRounding(cfg.FeeRounding)
+	engineCfg.SkipDefaultPairs = cfg.Bootstrap != nil && len(cfg.Bootstrap.Pairs) > 0
+	engineCfg.TakerThrottle = engine.TakerThrottle{
+		Delay:  cfg.TakerDelay,
+		Limit:  cfg.TakerLimit,
+		Window: cfg.TakerWindow,
+	}
+	for _, tier := range cfg.FeeTiers {
+		engineCfg.FeeTiers = append(engineCfg.FeeTiers, engine.FeeTier{
+			MinVolume: tier.MinVolume,
+			MakerRate: tier.MakerRate,
+			TakerRate: tier.TakerRate,
+		})
+	}
+	eng := engine.NewEngineWithConfig(engineCfg)
+	if cfg.Bootstrap != nil {
+		if err := applyBootstrap(eng, cfg.Bootstrap); err != nil {
+			return nil, fmt.Errorf("bootstrap failed: %w", err)
+		}
+	}
 
 	// Initialize handlers
 	orderHandler := handler.NewOrderHandler(eng)
 	accountHandler := handler.NewAccountHandler(eng.GetAccountManager())
 	orderbookHandler := handler.NewOrderbookHandler(eng)
+	pairHandler := handler.NewPairHandler(eng)
+	sessionHandler := handler.NewSessionHandler(eng)
+	streamHandler := handler.NewStreamHandler(eng)
+	adminHandler := handler.NewAdminHandler(eng)
+	feeHandler := handler.NewFeeHandler(eng)
+	configHandler := handler.NewConfigHandler(eng, cfg.AdminToken != "")
 
 	return &Server{
 		config:           cfg,
@@ -41,15 +91,57 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		orderHandler:     orderHandler,
 		accountHandler:   accountHandler,
 		orderbookHandler: orderbookHandler,
+		pairHandler:      pairHandler,
+		sessionHandler:   sessionHandler,
+		streamHandler:    streamHandler,
+		adminHandler:     adminHandler,
+		feeHandler:       feeHandler,
+		configHandler:    configHandler,
 		startTime:        time.Now(),
+		httpServer: &http.Server{
+			Addr:    cfg.HTTPServerAddress,
+			Handler: handler.LimitBody(cfg.MaxBodyBytes, http.DefaultServeMux),
+		},
 	}, nil
 }
 
+// Start launches the background workers and serves HTTP until Shutdown, when
+// it returns http.ErrServerClosed.
 func (s *Server) Start() error {
 	s.registerRoutes()
 
+	s.mu.Lock()
+	s.stops = append(s.stops,
+		s.engine.StartExpirySweeper(expirySweepInterval),
+		s.engine.StartReconciler(s.config.ReconcileInterval),
+		s.engine.StartPegRepricer(),
+	)
+	// Samples and checksum events fire on their own schedule rather than on
+	// the input sequence, which has no place in a deterministic run
+	if !s.config.DeterministicMatching {
+		s.stops = append(s.stops,
+			s.engine.StartSpreadSampler(),
+			s.streamHandler.StartChecksums(s.config.ChecksumInterval),
+		)
+	}
+	s.mu.Unlock()
+
 	logger.Infof("Server starting on %s (version %s)", s.config.HTTPServerAddress, Version)
-	return http.ListenAndServe(s.config.HTTPServerAddress, nil)
+	return s.httpServer.ListenAndServe()
+}
+
+// Shutdown stops the background workers, then stops accepting requests and
+// waits for the ones in flight until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	stops := s.stops
+	s.stops = nil
+	s.mu.Unlock()
+
+	for _, stop := range stops {
+		stop()
+	}
+	return s.httpServer.Shutdown(ctx)
 }
 
 func (s *Server) registerRoutes() {
@@ -59,25 +151,97 @@ func (s *Server) registerRoutes() {
 
 	// Account routes
 	http.HandleFunc("/api/v1/accounts/credit", s.accountHandler.Credit)
+	http.HandleFunc("/api/v1/accounts/credit/batch", s.accountHandler.CreditBatch)
 	http.HandleFunc("/api/v1/accounts/debit", s.accountHandler.Debit)
 	http.HandleFunc("/api/v1/accounts/balance", s.accountHandler.GetBalance)
+	http.HandleFunc("/api/v1/accounts/fees", s.feeHandler.GetFeeStatus)
+	http.HandleFunc("/api/v1/accounts/position", s.orderHandler.GetPosition)
+	http.HandleFunc("/api/v1/accounts/pnl", s.orderHandler.GetPnL)
 
 	// Order routes
 	http.HandleFunc("/api/v1/orders", s.orderHandler.PlaceOrder)
 	http.HandleFunc("/api/v1/orders/cancel", s.orderHandler.CancelOrder)
+	http.HandleFunc("/api/v1/orders/amend", s.orderHandler.AmendOrder)
+	http.HandleFunc("/api/v1/orders/cancel-all-global", s.orderHandler.CancelAllOrdersGlobal)
+	http.HandleFunc("/api/v1/orders/history", s.orderHandler.GetOrderHistory)
+	http.HandleFunc("/api/v1/orders/detail", s.orderHandler.GetOrder)
+	http.HandleFunc("/api/v1/orders/{id}/fills", s.orderHandler.GetOrderFills)
+	http.HandleFunc("/api/v1/orders/standing", s.orderHandler.GetOrderStanding)
+	http.HandleFunc("/api/v1/orders/open", s.orderHandler.GetOpenOrders)
+	http.HandleFunc("/api/v1/orders/required-funds", s.orderHandler.GetRequiredFunds)
+	http.HandleFunc("/api/v1/orders/rejections", s.orderHandler.GetRejections)
+	http.HandleFunc("/api/v1/orders/reserve-id", s.orderHandler.ReserveOrderID)
+	http.HandleFunc("/api/v1/ws/orders", s.sessionHandler.OrderSession)
+	http.HandleFunc("/api/v1/ws/trades", s.streamHandler.TradeStream)
 
 	// Orderbook routes
 	http.HandleFunc("/api/v1/orderbook", s.orderbookHandler.GetOrderbook)
+	http.HandleFunc("/api/v1/top", s.orderbookHandler.GetTopOfBook)
+	http.HandleFunc("/api/v1/depth", s.orderbookHandler.GetDepth)
+	http.HandleFunc("/api/v1/depth-in-range", s.orderbookHandler.GetDepthInRange)
+	http.HandleFunc("/api/v1/auction", s.orderbookHandler.GetAuctionPreview)
+	http.HandleFunc("/api/v1/spread-history", s.orderbookHandler.GetSpreadHistory)
+
+	// Pair routes
+	http.HandleFunc("/api/v1/pairs", s.pairHandler.ListPairs)
+	http.HandleFunc("/api/v1/config", s.configHandler.GetConfig)
+	http.HandleFunc("/api/v1/capabilities", s.configHandler.GetCapabilities)
+
+	// Admin routes
+	http.HandleFunc("/api/v1/admin/orders/cancel", handler.RequireAdmin(s.config.AdminToken, s.adminHandler.CancelOrder))
+	http.HandleFunc("/api/v1/admin/orders/cancel-stale", handler.RequireAdmin(s.config.AdminToken, s.adminHandler.CancelStaleOrders))
+	http.HandleFunc("/api/v1/admin/halt", handler.RequireAdmin(s.config.AdminToken, s.adminHandler.Halt))
+	http.HandleFunc("/api/v1/admin/resume", handler.RequireAdmin(s.config.AdminToken, s.adminHandler.Resume))
+	http.HandleFunc("/api/v1/admin/pairs/mode", handler.RequireAdmin(s.config.AdminToken, s.adminHandler.SetPairMode))
+	http.HandleFunc("/api/v1/admin/pairs/auction", handler.RequireAdmin(s.config.AdminToken, s.adminHandler.RunAuction))
+	http.HandleFunc("/api/v1/admin/pairs/delist", handler.RequireAdmin(s.config.AdminToken, s.adminHandler.DelistPair))
+	http.HandleFunc("/api/v1/admin/assets", handler.RequireAdmin(s.config.AdminToken, s.adminHandler.AssetTotals))
+	http.HandleFunc("/api/v1/admin/stats", handler.RequireAdmin(s.config.AdminToken, s.adminHandler.Stats))
+	http.HandleFunc("/api/v1/admin/matches", handler.RequireAdmin(s.config.AdminToken, s.adminHandler.MatchesBetween))
 
 	logger.Info("Routes registered:")
 	logger.Info("  GET  /health")
 	logger.Info("  GET  /swagger/index.html")
 	logger.Info("  POST /api/v1/accounts/credit")
+	logger.Info("  POST /api/v1/accounts/credit/batch")
 	logger.Info("  POST /api/v1/accounts/debit")
 	logger.Info("  GET  /api/v1/accounts/balance?user_id={id}")
+	logger.Info("  GET  /api/v1/accounts/fees?user_id={id}")
+	logger.Info("  GET  /api/v1/accounts/position?user_id={id}&pair={pair}")
+	logger.Info("  GET  /api/v1/accounts/pnl?user_id={id}&pair={pair}")
 	logger.Info("  POST /api/v1/orders")
 	logger.Info("  POST /api/v1/orders/cancel")
+	logger.Info("  POST /api/v1/orders/amend")
+	logger.Info("  POST /api/v1/orders/cancel-all-global")
+	logger.Info("  GET  /api/v1/orders/history?user_id={id}&pair={pair}&limit={n}&offset={n}")
+	logger.Info("  GET  /api/v1/orders/detail?user_id={id}&pair={pair}&order_id={id}")
+	logger.Info("  GET  /api/v1/orders/{id}/fills?user_id={id}&pair={pair}&limit={n}&offset={n}")
+	logger.Info("  GET  /api/v1/orders/standing?user_id={id}&pair={pair}&order_id={id}")
+	logger.Info("  GET  /api/v1/orders/open?user_id={id}&pair={pair}")
+	logger.Info("  GET  /api/v1/orders/required-funds?pair={pair}&side={side}&type={type}&price={price}&amount={amount}")
+	logger.Info("  GET  /api/v1/orders/rejections?user_id={id}&limit={n}")
+	logger.Info("  POST /api/v1/orders/reserve-id")
+	logger.Info("  GET  /api/v1/ws/orders?user_id={id}&cancel_on_disconnect={bool} (WebSocket)")
+	logger.Info("  GET  /api/v1/ws/trades?pair={pair} (WebSocket)")
 	logger.Info("  GET  /api/v1/orderbook?pair={pair}")
+	logger.Info("  GET  /api/v1/top?pair={pair}&levels={n}")
+	logger.Info("  GET  /api/v1/depth?pair={pair}&depth={n}")
+	logger.Info("  GET  /api/v1/depth-in-range?pair={pair}&side={side}&from={price}&to={price}")
+	logger.Info("  GET  /api/v1/auction?pair={pair}")
+	logger.Info("  GET  /api/v1/spread-history?pair={pair}&limit={n}")
+	logger.Info("  GET  /api/v1/pairs")
+	logger.Info("  GET  /api/v1/config")
+	logger.Info("  GET  /api/v1/capabilities")
+	logger.Info("  POST /api/v1/admin/orders/cancel (admin)")
+	logger.Info("  POST /api/v1/admin/orders/cancel-stale (admin)")
+	logger.Info("  POST /api/v1/admin/halt (admin)")
+	logger.Info("  POST /api/v1/admin/resume (admin)")
+	logger.Info("  POST /api/v1/admin/pairs/mode (admin)")
+	logger.Info("  POST /api/v1/admin/pairs/auction (admin)")
+	logger.Info("  POST /api/v1/admin/pairs/delist (admin)")
+	logger.Info("  GET  /api/v1/admin/assets (admin)")
+	logger.Info("  GET  /api/v1/admin/stats (admin)")
+	logger.Info("  GET  /api/v1/admin/matches?userA={id}&userB={id}&limit={n}&offset={n} (admin)")
 }
 
 // handleHealth godoc
@@ -0,0 +1,254 @@
+// Package testutil holds helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/moura95/crypto-exchange-challenge/internal/account"
+	"github.com/moura95/crypto-exchange-challenge/internal/engine"
+	"github.com/moura95/crypto-exchange-challenge/internal/orderbook"
+)
+
+// Fixture is a scenario to replay on an engine, read from JSON:
+//
+//	{"ops": [
+//	  {"op": "credit", "user_id": "1", "asset": "BRL", "amount": 100000},
+//	  {"op": "place", "ref": "bid", "user_id": "1", "pair": "BTC/BRL", "side": "bid", "price": 50000, "amount": 0.5},
+//	  {"op": "cancel", "user_id": "1", "pair": "BTC/BRL", "ref": "bid"}
+//	]}
+type Fixture struct {
+	Ops []Op `json:"ops"`
+}
+
+// Op is one step of a fixture. Which fields apply depends on Op:
+//
+//   - credit: UserID, Asset, Amount
+//   - place: UserID, Pair, Side, Type (limit, the default, or market), Price
+//     for limits, Amount, and optionally Tag and Ref
+//   - cancel: UserID, Pair, Ref
+//
+// Ref names the order a place op created so later ops and the test can refer
+// to it. ExpectError makes the op pass only if it fails with that message.
+type Op struct {
+	Op          string  `json:"op"`
+	UserID      string  `json:"user_id"`
+	Asset       string  `json:"asset,omitempty"`
+	Pair        string  `json:"pair,omitempty"`
+	Side        string  `json:"side,omitempty"`
+	Type        string  `json:"type,omitempty"`
+	Price       float64 `json:"price,omitempty"`
+	Amount      float64 `json:"amount,omitempty"`
+	Tag         string  `json:"tag,omitempty"`
+	Ref         string  `json:"ref,omitempty"`
+	ExpectError string  `json:"expect_error,omitempty"`
+}
+
+// Result is what replaying a fixture produced. It holds copies, never the
+// engine's own orders, so it does not change with whatever the engine does
+// next: Orders and Books and Balances as they stood after the last op, and
+// each match with its two orders as they stood right after the op that made
+// it.
+type Result struct {
+	Orders   map[string]orderbook.Order            // by Ref
+	Matches  []orderbook.Match                     // every match, in the order they happened
+	Books    map[string]Book                       // by pair symbol, for every pair a place op used
+	Balances map[string]map[string]account.Balance // by user ID and asset, for every user an op named
+}
+
+// Book is a pair's resting orders, levels best price first.
+type Book struct {
+	Bids []Level
+	Asks []Level
+}
+
+// Level is one price level of a Book. Volume and Orders count hidden orders
+// too.
+type Level struct {
+	Price  float64
+	Volume float64
+	Orders int
+}
+
+// replay is the state of a fixture while it is applied.
+type replay struct {
+	orders  map[string]*orderbook.Order // by Ref, the engine's own
+	matches []orderbook.Match
+	pairs   []engine.Pair
+	users   []string
+}
+
+// LoadFixture reads the JSON fixture at path. Unknown fields are rejected so
+// a typo cannot silently drop a step.
+func LoadFixture(path string) (*Fixture, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+
+	var f Fixture
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Apply runs every op of f on e in order. It stops at the first op that fails
+// unexpectedly, or that was expected to fail and did not.
+func (f *Fixture) Apply(e *engine.Engine) (*Result, error) {
+	r := &replay{orders: make(map[string]*orderbook.Order)}
+
+	for i, op := range f.Ops {
+		err := r.apply(e, op)
+		switch {
+		case op.ExpectError == "" && err != nil:
+			return r.result(e), fmt.Errorf("ops[%d] (%s): %w", i, op.Op, err)
+		case op.ExpectError != "" && err == nil:
+			return r.result(e), fmt.Errorf("ops[%d] (%s): expected error %q, got none", i, op.Op, op.ExpectError)
+		case op.ExpectError != "" && err.Error() != op.ExpectError:
+			return r.result(e), fmt.Errorf("ops[%d] (%s): expected error %q, got %q", i, op.Op, op.ExpectError, err)
+		}
+	}
+	return r.result(e), nil
+}
+
+// Replay loads the fixture at path and applies it to e, failing the test on
+// any error.
+func Replay(t testing.TB, e *engine.Engine, path string) *Result {
+	t.Helper()
+
+	f, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	result, err := f.Apply(e)
+	if err != nil {
+		t.Fatalf("replay %s: %v", path, err)
+	}
+	return result
+}
+
+func (r *replay) apply(e *engine.Engine, op Op) error {
+	r.addUser(op.UserID)
+
+	switch op.Op {
+	case "credit":
+		return e.GetAccountManager().Credit(op.UserID, strings.ToUpper(op.Asset), op.Amount)
+
+	case "place":
+		if op.Ref != "" && r.orders[op.Ref] != nil {
+			return fmt.Errorf("ref %q used twice", op.Ref)
+		}
+
+		pair := parsePair(op.Pair)
+		r.addPair(pair)
+		side := orderbook.Side(op.Side)
+		opts := engine.OrderOptions{Tag: op.Tag}
+
+		var order *orderbook.Order
+		var matches []orderbook.Match
+		var err error
+		switch op.Type {
+		case "", string(orderbook.OrderTypeLimit):
+			order, matches, err = e.PlaceOrderWithOptions(op.UserID, pair, side, op.Price, op.Amount, opts)
+		case string(orderbook.OrderTypeMarket):
+			order, matches, err = e.PlaceMarketOrderWithOptions(op.UserID, pair, side, op.Amount, opts)
+		default:
+			return fmt.Errorf("unknown order type %q", op.Type)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, m := range matches {
+			m.Bid, m.Ask = copyOrder(m.Bid), copyOrder(m.Ask)
+			r.matches = append(r.matches, m)
+		}
+		if op.Ref != "" {
+			r.orders[op.Ref] = order
+		}
+		return nil
+
+	case "cancel":
+		order, ok := r.orders[op.Ref]
+		if !ok {
+			return fmt.Errorf("unknown ref %q", op.Ref)
+		}
+		_, err := e.CancelOrder(op.UserID, parsePair(op.Pair), order.ID)
+		return err
+
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+func (r *replay) addUser(userID string) {
+	if userID != "" && !slices.Contains(r.users, userID) {
+		r.users = append(r.users, userID)
+	}
+}
+
+func (r *replay) addPair(pair engine.Pair) {
+	if !slices.Contains(r.pairs, pair) {
+		r.pairs = append(r.pairs, pair)
+	}
+}
+
+// result copies out what the replay has produced so far.
+func (r *replay) result(e *engine.Engine) *Result {
+	result := &Result{
+		Orders:   make(map[string]orderbook.Order, len(r.orders)),
+		Matches:  r.matches,
+		Books:    make(map[string]Book, len(r.pairs)),
+		Balances: make(map[string]map[string]account.Balance, len(r.users)),
+	}
+	for ref, order := range r.orders {
+		result.Orders[ref] = *copyOrder(order)
+	}
+	for _, pair := range r.pairs {
+		if ob := e.GetOrderbook(pair); ob != nil {
+			result.Books[pair.String()] = Book{
+				Bids: levels(ob.Bids(), ob.PriceTick()),
+				Asks: levels(ob.Asks(), ob.PriceTick()),
+			}
+		}
+	}
+	for _, userID := range r.users {
+		balances := make(map[string]account.Balance)
+		for asset, balance := range e.GetAccountManager().GetAllBalances(userID) {
+			balances[asset] = *balance
+		}
+		result.Balances[userID] = balances
+	}
+	return result
+}
+
+func levels(limits []*orderbook.Limit, priceTick float64) []Level {
+	result := make([]Level, len(limits))
+	for i, limit := range limits {
+		result[i] = Level{Price: limit.Price(priceTick), Volume: limit.TotalVolume, Orders: len(limit.Orders)}
+	}
+	return result
+}
+
+// copyOrder returns a copy of order detached from its book level.
+func copyOrder(order *orderbook.Order) *orderbook.Order {
+	c := *order
+	c.Limit = nil
+	return &c
+}
+
+// parsePair reads "BASE/QUOTE". Malformed symbols yield a pair the engine
+// rejects.
+func parsePair(symbol string) engine.Pair {
+	base, quote, _ := strings.Cut(strings.ToUpper(symbol), "/")
+	return engine.Pair{Base: base, Quote: quote}
+}
@@ -0,0 +1,133 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source used to stamp orders and matches.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Real returns the wall clock.
+func Real() Clock {
+	return realClock{}
+}
+
+// Sleep waits d on c: the wall clock sleeps, a Fake moves forward by d and
+// returns at once. A Clock with no Sleep method of its own falls back to
+// the wall clock.
+func Sleep(c Clock, d time.Duration) {
+	if s, ok := c.(interface{ Sleep(time.Duration) }); ok {
+		s.Sleep(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+// After returns a channel that receives the time on c once d has passed on
+// it: the wall clock's after d of real time, a Fake's once it is moved at
+// least d forward. A Clock with no After method of its own falls back to
+// the wall clock.
+func After(c Clock, d time.Duration) <-chan time.Time {
+	if a, ok := c.(interface {
+		After(time.Duration) <-chan time.Time
+	}); ok {
+		return a.After(d)
+	}
+	return time.After(d)
+}
+
+// Fake is a deterministic clock for tests. Each call to Now returns the
+// current time and then moves it forward by step, so consecutive calls yield
+// strictly increasing timestamps when step > 0.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	step    time.Duration
+	waiters []waiter
+}
+
+// waiter is a pending After on a Fake.
+type waiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFake returns a fake clock starting at start that advances by step on
+// every call to Now.
+func NewFake(start time.Time, step time.Duration) *Fake {
+	return &Fake{now: start, step: step}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := f.now
+	f.now = f.now.Add(f.step)
+	f.fire()
+	return t
+}
+
+// Advance moves the clock forward by d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	f.fire()
+}
+
+// Sleep moves the clock forward by d without waiting.
+func (f *Fake) Sleep(d time.Duration) {
+	f.Advance(d)
+}
+
+// Set moves the clock to t.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+	f.fire()
+}
+
+// After returns a channel that receives the clock's time once it has been
+// moved at least d forward from now.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	f.waiters = append(f.waiters, waiter{deadline: f.now.Add(d), ch: ch})
+	f.fire()
+	return ch
+}
+
+// fire delivers the current time to every waiter whose deadline has been
+// reached. Must be called with f.mu held.
+func (f *Fake) fire() {
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.deadline.After(f.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- f.now
+	}
+	f.waiters = pending
+}
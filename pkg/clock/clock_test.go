@@ -0,0 +1,72 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake_StepsOnEveryCall(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start, time.Millisecond)
+
+	first := c.Now()
+	second := c.Now()
+
+	if !first.Equal(start) {
+		t.Errorf("first call: expected %v, got %v", start, first)
+	}
+	if !second.Equal(start.Add(time.Millisecond)) {
+		t.Errorf("second call: expected %v, got %v", start.Add(time.Millisecond), second)
+	}
+}
+
+func TestFake_AdvanceAndSet(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start, 0)
+
+	c.Advance(time.Hour)
+	if got := c.Now(); !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("after Advance: expected %v, got %v", start.Add(time.Hour), got)
+	}
+
+	c.Set(start)
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("after Set: expected %v, got %v", start, got)
+	}
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("zero step must not move the clock: got %v", got)
+	}
+}
+
+func TestSleep_FakeAdvances(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start, 0)
+
+	Sleep(c, time.Hour)
+	if got := c.Now(); !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("after Sleep: expected %v, got %v", start.Add(time.Hour), got)
+	}
+}
+
+func TestAfter_FakeFiresOnAdvance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start, 0)
+
+	ch := After(c, time.Minute)
+	c.Advance(30 * time.Second)
+	select {
+	case <-ch:
+		t.Fatal("fired before the deadline")
+	default:
+	}
+
+	c.Advance(30 * time.Second)
+	select {
+	case got := <-ch:
+		if !got.Equal(start.Add(time.Minute)) {
+			t.Errorf("expected %v, got %v", start.Add(time.Minute), got)
+		}
+	default:
+		t.Fatal("did not fire at the deadline")
+	}
+}
@@ -5,22 +5,25 @@ import (
 )
 
 func FloorToTick(val, tick float64) float64 {
-	if tick == 0.0 {
+	if tick <= 0 {
 		return val
 	}
 	return math.Floor((val/tick)+0.000000001) * tick
 }
 
 func IsValidTick(val, tick float64) bool {
-	if tick == 0.0 {
+	if tick <= 0 {
 		return true
 	}
 	normalized := FloorToTick(val, tick)
 	return math.Abs(val-normalized) < 0.0000000001
 }
 
+// PriceToTicks returns price as a whole number of ticks. A tick <= 0 is
+// degenerate and maps every price to 0, so books and pairs reject such ticks
+// before any price reaches this.
 func PriceToTicks(price, tick float64) int64 {
-	if tick == 0.0 {
+	if tick <= 0 {
 		return 0
 	}
 	return int64(math.Round(price / tick))
@@ -30,9 +33,30 @@ func TicksToPrice(ticks int64, tick float64) float64 {
 	return float64(ticks) * tick
 }
 
+// AmountToTicks returns amount as a whole number of amount ticks, like
+// PriceToTicks, so amounts can be added and compared without float noise.
+func AmountToTicks(amount, tick float64) int64 {
+	return PriceToTicks(amount, tick)
+}
+
+// TicksToAmount is the inverse of AmountToTicks.
+func TicksToAmount(ticks int64, tick float64) float64 {
+	return tickMultiple(float64(ticks), tick)
+}
+
 func RoundToTick(val, tick float64) float64 {
-	if tick == 0.0 {
+	if tick <= 0 {
 		return val
 	}
-	return math.Round(val/tick) * tick
+	return tickMultiple(math.Round(val/tick), tick)
+}
+
+// tickMultiple returns n*tick. Decimal ticks such as 0.01 have a whole-number
+// inverse, and dividing by it avoids the float noise of multiplying by tick.
+func tickMultiple(n, tick float64) float64 {
+	inverse := 1 / tick
+	if inverse == math.Trunc(inverse) {
+		return n / inverse
+	}
+	return n * tick
 }